@@ -44,12 +44,29 @@ func init() {
 	// Add descriptor
 	addDescriptorCmd.Flags().StringVar(&descriptorPath, "descriptorPath", "", "Application descriptor path containing a JSON spec")
 	addDescriptorCmd.Flags().MarkDeprecated("descriptorPath", "Use command argument instead")
+	addDescriptorCmd.Flags().StringVar(&idempotencyKey, "idempotencyKey", "", "Key to safely retry the request without adding the descriptor twice")
+	addDescriptorCmd.Flags().StringVar(&valuesPath, "values", "", "YAML or JSON file with the variables of the descriptor template")
 	descriptorCmd.AddCommand(addDescriptorCmd)
+	// Init descriptor
+	initDescriptorCmd.Flags().StringVar(&name, "name", "", "Name of the descriptor")
+	initDescriptorCmd.Flags().StringArrayVar(&serviceSpecs, "service", []string{}, "Service as group/name=image[,replicas=N][,ports=80;443][,expose=public|app|none][,storage=ephemeral|local|replica|cloud:/path], skips the questions")
+	initDescriptorCmd.Flags().StringVar(&outputPath, "outputPath", "", "Path of the resulting descriptor, <name>.json by default")
+	descriptorCmd.AddCommand(initDescriptorCmd)
+	// Import descriptor
+	importDescriptorCmd.Flags().StringVar(&importFormat, "from", "", "Format of the manifest: compose or k8s")
+	importDescriptorCmd.Flags().StringVar(&name, "name", "", "Name of the descriptor, the name of the manifest file by default")
+	importDescriptorCmd.Flags().StringVar(&outputPath, "outputPath", "", "Path of the resulting descriptor, <name>.json by default")
+	importDescriptorCmd.MarkFlagRequired("from")
+	descriptorCmd.AddCommand(importDescriptorCmd)
+	// Render descriptor
+	renderDescriptorCmd.Flags().StringVar(&valuesPath, "values", "", "YAML or JSON file with the variables of the descriptor template")
+	descriptorCmd.AddCommand(renderDescriptorCmd)
 	// Get descriptor
 	getDescriptorCmd.Flags().StringVar(&descriptorID, "descriptorID", "", "Application descriptor identifier")
 	getDescriptorCmd.Flags().MarkDeprecated("descriptorID", "Use command argument instead")
 	descriptorCmd.AddCommand(getDescriptorCmd)
 	// List descriptors
+	listDescriptorsCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the descriptors, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	descriptorCmd.AddCommand(listDescriptorsCmd)
 	// Help
 	addDescriptorHelpCmd.Flags().StringVar(&exampleName, "exampleName", "simple", "Example to show: simple or complex or pstorage")
@@ -67,10 +84,16 @@ func init() {
 	appDescLabelsCmd.AddCommand(removeLabelFromAppDescriptorCmd)
 	// List descriptor Parameters
 	descriptorCmd.AddCommand(getDescriptorParamsCmd)
+	// Descriptor history
+	descriptorCmd.AddCommand(descriptorHistoryCmd)
+	// Descriptor diff
+	diffDescriptorsCmd.Flags().StringVar(&name, "name", "", "Name of the descriptors, to compare versions instead of descriptor identifiers")
+	descriptorCmd.AddCommand(diffDescriptorsCmd)
 
 	// Instances
 	appsCmd.AddCommand(instanceCmd)
 	// List
+	listInstancesCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the instances, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	instanceCmd.AddCommand(listInstancesCmd)
 	// Deploy
 	deployInstanceCmd.Flags().StringVar(&name, "name", "", "Name of the application instance")
@@ -92,6 +115,9 @@ func init() {
 	instanceCmd.AddCommand(getInstanceCmd)
 	// List instance params
 	instanceCmd.AddCommand(getInstanceParamsCmd)
+	// Export descriptor
+	exportInstanceDescriptorCmd.Flags().StringVar(&outputPath, "outputPath", "", "Path of the file to store the descriptor, printed if not set")
+	instanceCmd.AddCommand(exportInstanceDescriptorCmd)
 }
 
 var descriptorCmd = &cobra.Command{
@@ -108,8 +134,10 @@ var descriptorCmd = &cobra.Command{
 var addDescriptorCmd = &cobra.Command{
 	Use:   "add [descriptorPath]",
 	Short: "Add a new application descriptor",
-	Long:  `Add a new application descriptor`,
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Add a new application descriptor written in YAML or JSON. Descriptors are templates that may use variables
+as ${NAME} or ${NAME:-default}, read from the values file or the environment, include fragments with
+{include: path} and extend them with {extends: path}`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		SetupLogging()
 		a := cli.NewApplications(
@@ -122,7 +150,7 @@ var addDescriptorCmd = &cobra.Command{
 			fmt.Println(err.Error())
 			cmd.Help()
 		} else {
-			a.AddDescriptor(cliOptions.Resolve("organizationID", organizationID), targetDescriptorPath[0])
+			a.AddDescriptor(cliOptions.Resolve("organizationID", organizationID), targetDescriptorPath[0], valuesPath, idempotencyKey)
 		}
 
 	},
@@ -155,7 +183,91 @@ var listDescriptorsCmd = &cobra.Command{
 			cliOptions.ResolveAsInt("port", nalejPort),
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
-		a.ListDescriptors(cliOptions.Resolve("organizationID", organizationID))
+		a.ListDescriptors(cliOptions.Resolve("organizationID", organizationID), labelSelector)
+	},
+}
+
+var descriptorHistoryCmd = &cobra.Command{
+	Use:   "history [name]",
+	Short: "List the versions of the application descriptors with a given name",
+	Long:  `List the versions of the application descriptors with a given name and the instances deployed from each of them`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.DescriptorHistory(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var diffDescriptorsCmd = &cobra.Command{
+	Use:   "diff [from] [to]",
+	Short: "Compare two application descriptors",
+	Long: `Compare two application descriptors showing the services, ports, environment variables and rules that changed.
+The descriptors are identified by their identifiers, or by their versions when --name is set, as in: diff 1 2 --name wordpress`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.DiffDescriptors(cliOptions.Resolve("organizationID", organizationID), args[0], args[1], name)
+	},
+}
+
+var initDescriptorCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a new application descriptor",
+	Long: `Create a new application descriptor asking for its service groups, services, images, ports, exposure, storage
+and replicas. The questions are skipped when the services are passed with --service, as in:
+init --name wordpress --service db/mysql=mysql:5.7,ports=3306,storage=local:/var/lib/mysql --service web/wordpress=wordpress:5,ports=80,expose=public`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.InitDescriptor(name, serviceSpecs, outputPath)
+	},
+}
+
+var importDescriptorCmd = &cobra.Command{
+	Use:   "import [manifestPath]",
+	Short: "Import an application descriptor from a docker-compose file or Kubernetes manifests",
+	Long: `Translate a docker-compose file or Kubernetes Deployment, StatefulSet and Service manifests into an application
+descriptor, reporting the elements that could not be translated. Review the descriptor before adding it`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.ImportDescriptor(args[0], importFormat, name, outputPath)
+	},
+}
+
+var renderDescriptorCmd = &cobra.Command{
+	Use:   "render [descriptorPath]",
+	Short: "Render an application descriptor template",
+	Long:  `Render an application descriptor template resolving its variables and fragments, and print the resulting descriptor`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.RenderDescriptor(cliOptions.Resolve("organizationID", organizationID), args[0], valuesPath)
 	},
 }
 
@@ -358,7 +470,7 @@ var listInstancesCmd = &cobra.Command{
 			cliOptions.ResolveAsInt("port", nalejPort),
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
-		a.ListInstances(cliOptions.Resolve("organizationID", organizationID))
+		a.ListInstances(cliOptions.Resolve("organizationID", organizationID), labelSelector)
 	},
 }
 
@@ -407,3 +519,20 @@ var getInstanceParamsCmd = &cobra.Command{
 		}
 	},
 }
+
+var exportInstanceDescriptorCmd = &cobra.Command{
+	Use:   "export-descriptor [instanceID]",
+	Short: "Export the descriptor of an application instance",
+	Long: `Rebuild an application descriptor that reproduces a running instance, including the values of its parameters.
+Image credentials are redacted and must be set before adding the descriptor in another organization`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		a := cli.NewApplications(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		a.ExportInstanceDescriptor(cliOptions.Resolve("organizationID", organizationID), args[0], outputPath)
+	},
+}
@@ -55,6 +55,7 @@ func init() {
 	listClustersCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for changes")
 	listClustersCmd.Flags().StringVar(&orderBy, "orderBy", "name", "field by which the clusters will be sorted (name, status or state)")
 	listClustersCmd.Flags().BoolVar(&desc, "desc", false, "Sort clusters in descending order")
+	listClustersCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the clusters, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	clustersCmd.AddCommand(listClustersCmd)
 
 	updateClusterCmd.Flags().StringVar(&clusterName, "name", "", "new name")
@@ -87,6 +88,7 @@ func init() {
 	provAndInstCmd.PersistentFlags().StringVar(&provisionTargetPlatform, "targetPlatform", "", "Target platform")
 	provAndInstCmd.PersistentFlags().StringVar(&provisionZone, "zone", "", "Deployment zone")
 	provAndInstCmd.PersistentFlags().StringVar(&provisionKubeConfigOutputPath, "kubeConfigOutputPath", "/tmp", "Path where the kubeconfig will be stored")
+	provAndInstCmd.PersistentFlags().StringVar(&idempotencyKey, "idempotencyKey", "", "Key to safely retry the request without provisioning the cluster twice")
 	clustersCmd.AddCommand(provAndInstCmd)
 
 	scaleClusterCmd.PersistentFlags().StringVar(&provisionClusterType, "clusterType", "KUBERNETES", "Cluster type")
@@ -158,7 +160,7 @@ var listClustersCmd = &cobra.Command{
 			cliOptions.ResolveAsInt("port", nalejPort),
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
-		c.List(cliOptions.Resolve("organizationID", organizationID), watch, orderBy, desc)
+		c.List(cliOptions.Resolve("organizationID", organizationID), watch, orderBy, desc, labelSelector)
 	},
 }
 
@@ -360,6 +362,7 @@ var provAndInstCmd = &cobra.Command{
 			int64(provisionNumNodes),
 			targetPlatform,
 			provisionZone,
+			idempotencyKey,
 		)
 	},
 }
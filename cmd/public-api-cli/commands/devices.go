@@ -69,6 +69,7 @@ func init() {
 	rootCmd.AddCommand(devicesCmd)
 	devicesCmd.PersistentFlags().StringVar(&deviceGroupID, "deviceGroupID", "", "Device group identifier")
 
+	listDevicesCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the devices, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	devicesCmd.AddCommand(listDevicesCmd)
 
 	devicesCmd.AddCommand(deviceInfoCmd)
@@ -199,7 +200,7 @@ var listDevicesCmd = &cobra.Command{
 			cmd.Help()
 		} else {
 			n.ListDevices(cliOptions.Resolve("organizationID", organizationID),
-				targetValues[0])
+				targetValues[0], labelSelector)
 		}
 
 	},
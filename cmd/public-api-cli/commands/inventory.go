@@ -49,16 +49,22 @@ func addAssetSelector(cmd *cobra.Command, selector *cli.AssetSelector) {
 }
 
 func addTimeRange(cmd *cobra.Command, timeRange *cli.TimeRange) {
-	cmd.Flags().StringVar(&timeRange.Timestamp, "timestamp", "", "Timestamp for point query")
+	cmd.Flags().StringVar(&timeRange.Timestamp, "timestamp", "", "Timestamp for point query, absolute or relative to now as in -15m or yesterday")
 	cmd.Flags().StringVar(&timeRange.Start, "start", "", "Start time for range query")
 	cmd.Flags().StringVar(&timeRange.End, "end", "", "End time for range query")
+	cmd.Flags().StringVar(&timeRange.Since, "since", "", "Duration before now of the start of a range query, as in 1h or 2d")
 	cmd.Flags().DurationVar(&timeRange.Resolution, "resolution", 0, "Range interval resolution - 0 to aggregate to single value")
 }
 
 func init() {
 	rootCmd.AddCommand(inventoryCmd)
+	inventoryListCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the devices, assets and edge controllers, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	inventoryCmd.AddCommand(inventoryListCmd)
 	inventoryCmd.AddCommand(inventorySummaryCmd)
+	inventoryCmd.AddCommand(inventoryExportCmd)
+	inventoryExportCmd.Flags().StringVar(&exportFormat, "format", cli.ExportCSV, "Format of the report: csv, json, ndjson or geojson")
+	inventoryExportCmd.Flags().StringVar(&exportPath, "outputPath", "", "Path of the report file; written to the standard output if empty")
+	inventoryExportCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the devices, assets and edge controllers, as in env=prod,tier!=db")
 	inventoryCmd.AddCommand(invControllerCommand)
 	inventoryCmd.AddCommand(invAssetCommand)
 	inventoryCmd.AddCommand(invDeviceCommand)
@@ -105,7 +111,24 @@ var inventoryListCmd = &cobra.Command{
 			cliOptions.ResolveAsInt("port", nalejPort),
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
-		ec.List(cliOptions.Resolve("organizationID", organizationID))
+		ec.List(cliOptions.Resolve("organizationID", organizationID), labelSelector)
+	},
+}
+
+var inventoryExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export a hardware report of the inventory",
+	Long: `Export the devices, assets and edge controllers with their operating system, hardware, storage and
+network details, labels, last-seen times and owning edge controller. The geojson format places each item on the
+coordinates of its location, set with update-location as in 40.4168,-3.7038, or of its edge controller`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		inv := cli.NewInventory(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		inv.Export(cliOptions.Resolve("organizationID", organizationID), labelSelector, exportFormat, exportPath)
 	},
 }
 
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"github.com/nalej/public-api/internal/app/cli"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+var bulkLabelsCmd = &cobra.Command{
+	Use:     "labels",
+	Aliases: []string{"bulk-label"},
+	Short:   "Manage the labels of several entities at once",
+	Long: `Add, remove or replace the labels of several clusters, nodes, devices, assets, edge controllers or
+application descriptors in one call. The entities are selected with --ids or with the labels in --match.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		_ = cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(bulkLabelsCmd)
+
+	bulkLabelsCmd.PersistentFlags().StringSliceVar(&bulkEntityIDs, "ids", []string{}, "Identifiers of the entities to update")
+	bulkLabelsCmd.PersistentFlags().StringVar(&bulkMatchLabels, "match", "", "Update the entities with these labels, separated by ; as in key1:value;key2:value")
+	bulkLabelsCmd.PersistentFlags().StringVar(&labelSelector, "selector", "", "Update the entities matching a label selector, as in env=prod,tier!=db,region in (eu,us),!deprecated")
+	bulkLabelsCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show the resulting labels without applying them")
+	bulkLabelsCmd.AddCommand(bulkAddLabelsCmd)
+	bulkLabelsCmd.AddCommand(bulkRemoveLabelsCmd)
+	bulkLabelsCmd.AddCommand(bulkReplaceLabelsCmd)
+}
+
+// stringToResourceType converts the resource type names accepted by the CLI into the ones of the API.
+func stringToResourceType(resourceType string) string {
+	switch strings.ToLower(resourceType) {
+	case "cluster", "clusters":
+		return "CLUSTER"
+	case "node", "nodes":
+		return "NODE"
+	case "device", "devices":
+		return "DEVICE"
+	case "asset", "assets":
+		return "ASSET"
+	case "ec", "edgecontroller", "edgecontrollers", "edge_controller":
+		return "EDGE_CONTROLLER"
+	case "descriptor", "descriptors", "desc":
+		return "DESCRIPTOR"
+	}
+	log.Fatal().Str("resourceType", resourceType).Msg("unknown resource type, expecting cluster, node, device, asset, ec or descriptor")
+	return ""
+}
+
+func bulkUpdateLabels(operation string, args []string) {
+	SetupLogging()
+	l := cli.NewLabels(
+		cliOptions.Resolve("nalejAddress", nalejAddress),
+		cliOptions.ResolveAsInt("port", nalejPort),
+		insecure, useTLS,
+		cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+	labels := ""
+	if len(args) > 1 {
+		labels = args[1]
+	}
+	l.BulkUpdate(cliOptions.Resolve("organizationID", organizationID), stringToResourceType(args[0]), operation,
+		bulkEntityIDs, bulkMatchLabels, labelSelector, labels, dryRun)
+}
+
+var bulkAddLabelsCmd = &cobra.Command{
+	Use:   "add <resourceType> <labels>",
+	Short: "Add a set of labels to several entities",
+	Long:  `Add a set of labels to several entities, overwriting the value of the existing keys`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		bulkUpdateLabels("ADD", args)
+	},
+}
+
+var bulkRemoveLabelsCmd = &cobra.Command{
+	Use:     "delete <resourceType> <labels>",
+	Aliases: []string{"remove", "del", "rm"},
+	Short:   "Remove a set of labels from several entities",
+	Long:    `Remove a set of labels from several entities`,
+	Args:    cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		bulkUpdateLabels("REMOVE", args)
+	},
+}
+
+var bulkReplaceLabelsCmd = &cobra.Command{
+	Use:   "replace <resourceType> [labels]",
+	Short: "Replace the labels of several entities",
+	Long:  `Replace all the labels of several entities with the given ones. All the labels are removed if none is given`,
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		bulkUpdateLabels("REPLACE", args)
+	},
+}
@@ -36,6 +36,7 @@ var nodesCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(nodesCmd)
 	listNodesCmd.Flags().StringVar(&clusterID, "clusterID", "", "Cluster identifier")
+	listNodesCmd.Flags().StringVar(&labelSelector, "selector", "", "Label selector to filter the nodes, as in env=prod,tier!=db,region in (eu,us),!deprecated")
 	nodesCmd.AddCommand(listNodesCmd)
 
 	nodeLabelsCmd.PersistentFlags().StringVar(&nodeID, "nodeID", "", "Node identifier")
@@ -64,7 +65,7 @@ var listNodesCmd = &cobra.Command{
 			cmd.Help()
 		} else {
 			n.List(cliOptions.Resolve("organizationID", organizationID),
-				cliOptions.Resolve("clusterID", targetValues[0]))
+				cliOptions.Resolve("clusterID", targetValues[0]), labelSelector)
 		}
 	},
 }
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"github.com/nalej/public-api/internal/app/cli"
+	"github.com/spf13/cobra"
+	"time"
+)
+
+var operationsCmd = &cobra.Command{
+	Use:     "operation",
+	Aliases: []string{"op", "operations"},
+	Short:   "Manage asynchronous operations",
+	Long:    `Track the asynchronous operations such as cluster installs, agent installs or log downloads`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		cmd.Help()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(operationsCmd)
+
+	listOperationsCmd.Flags().StringVar(&operationType, "type", "", "Filter by operation type (e.g. CLUSTER_INSTALL, LOG_DOWNLOAD)")
+	listOperationsCmd.Flags().StringVar(&operationStatus, "status", "", "Filter by status (IN_PROGRESS, SUCCESS, FAILED, CANCELLED)")
+	operationsCmd.AddCommand(listOperationsCmd)
+	operationsCmd.AddCommand(infoOperationCmd)
+	waitOperationCmd.Flags().DurationVar(&waitTimeout, "timeout", time.Minute*5, "Maximum time to wait for the operation")
+	operationsCmd.AddCommand(waitOperationCmd)
+	operationsCmd.AddCommand(cancelOperationCmd)
+}
+
+func newOperations() *cli.Operations {
+	return cli.NewOperations(
+		cliOptions.Resolve("nalejAddress", nalejAddress),
+		cliOptions.ResolveAsInt("port", nalejPort),
+		insecure, useTLS,
+		cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+}
+
+var listOperationsCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the operations",
+	Long:    `List the asynchronous operations of the organization, from the newest to the oldest`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		newOperations().List(cliOptions.Resolve("organizationID", organizationID), operationType, operationStatus)
+	},
+}
+
+var infoOperationCmd = &cobra.Command{
+	Use:     "info <operationID>",
+	Aliases: []string{"get"},
+	Short:   "Get the operation information",
+	Long:    `Get the operation information with its current status`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		newOperations().Info(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var waitOperationCmd = &cobra.Command{
+	Use:   "wait <operationID>",
+	Short: "Wait for an operation to finish",
+	Long:  `Wait for an operation to finish and show its final status. The last known status is shown if the timeout expires`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		newOperations().Wait(cliOptions.Resolve("organizationID", organizationID), args[0], waitTimeout)
+	},
+}
+
+var cancelOperationCmd = &cobra.Command{
+	Use:   "cancel <operationID>",
+	Short: "Cancel an operation",
+	Long:  `Cancel an operation in progress. Only some types of operations can be cancelled`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		newOperations().Cancel(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
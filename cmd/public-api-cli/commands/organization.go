@@ -57,6 +57,12 @@ func init() {
 	setCmd.AddCommand(listSetCmd)
 	listSetCmd.Flags().BoolVar(&desc, "desc", false, "Sort settings in descending order")
 
+	// Archive commands
+	orgCmd.AddCommand(exportOrgCmd)
+	importOrgCmd.Flags().StringVar(&onConflict, "onConflict", cli.ConflictSkip, "Policy for descriptors and device groups with existing names (skip, rename or fail)")
+	importOrgCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the actions without applying them")
+	orgCmd.AddCommand(importOrgCmd)
+
 }
 
 var infoCmd = &cobra.Command{
@@ -153,3 +159,41 @@ var listSetCmd = &cobra.Command{
 		o.ListSettings(cliOptions.Resolve("organizationID", organizationID), desc)
 	},
 }
+
+var exportOrgCmd = &cobra.Command{
+	Use:   "export [archivePath]",
+	Short: "Export the configuration of an organization",
+	Long: `Export the descriptors, device groups, settings, cluster, node and asset labels, application network
+connections and user roles of an organization into an archive. Secrets are not exported`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		ob := cli.NewOrganizationBackup(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath),
+			cliOptions.Resolve("output", output),
+			cliOptions.ResolveAsInt("labelLength", labelLength))
+		ob.Export(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var importOrgCmd = &cobra.Command{
+	Use:   "import [archivePath]",
+	Short: "Import the configuration of an organization",
+	Long: `Recreate the configuration stored in an archive in an organization, reporting the identifiers of the
+created entities`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		ob := cli.NewOrganizationBackup(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath),
+			cliOptions.Resolve("output", output),
+			cliOptions.ResolveAsInt("labelLength", labelLength))
+		ob.Import(cliOptions.Resolve("organizationID", organizationID), args[0], onConflict, dryRun)
+	},
+}
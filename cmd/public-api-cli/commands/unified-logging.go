@@ -21,6 +21,14 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var archiveOptions = &cli.ArchiveOptions{}
+
+func addArchiveOptions(cmd *cobra.Command, options *cli.ArchiveOptions) {
+	cmd.Flags().StringVar(&options.Checksum, "checksum", "", "Expected SHA-256 of the archive; the one sent by the server is used if empty")
+	cmd.Flags().BoolVar(&options.Extract, "extract", false, "Extract the archive into a directory per application and service group instance")
+	cmd.Flags().StringVar(&options.ExtractFormat, "extractFormat", cli.ExtractNDJSON, "Format of the extracted files: ndjson or text")
+}
+
 var logCmd = &cobra.Command{
 	Use:   "log",
 	Short: "Manage application logs",
@@ -44,13 +52,20 @@ func init() {
 	searchCmd.Flags().StringVar(&sgInstanceID, "sgInstanceID", "", "Service group instance identifier")
 	searchCmd.Flags().StringVar(&serviceID, "serviceID", "", "Service identifier")
 	searchCmd.Flags().StringVar(&serviceInstanceID, "serviceInstanceID", "", "Service instance identifier")
-	searchCmd.Flags().StringVar(&from, "from", "", "Start time of logs")
-	searchCmd.Flags().StringVar(&to, "to", "", "End time of logs")
+	searchCmd.Flags().StringVar(&from, "from", "", "Start time of logs, as in 2020-05-04T10:00:00Z, -15m, now-2h or yesterday")
+	searchCmd.Flags().StringVar(&to, "to", "", "End time of logs, absolute or relative to now")
+	searchCmd.Flags().StringVar(&since, "since", "", "Duration before now of the start of logs, as in 15m or 1d")
 	searchCmd.Flags().BoolVar(&desc, "desc", false, "Sort results in descending time order")
 	searchCmd.Flags().BoolVar(&redirectLog, "redirectResultAsLog", false, "Redirect the result to the CLI log")
 	searchCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Specify if the logs should be streamed")
 	searchCmd.Flags().BoolVar(&nFirst, "nFirst", false, "Specify if the user expects to receive the first n results or not")
 
+	logCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringVar(&from, "from", "", "Start time of logs, as in 2020-05-04T10:00:00Z, -15m, now-2h or yesterday")
+	queryCmd.Flags().StringVar(&to, "to", "", "End time of logs, absolute or relative to now")
+	queryCmd.Flags().StringVar(&since, "since", "", "Duration before now of the start of logs, as in 15m or 1d")
+	queryCmd.Flags().Int32Var(&queryLimit, "limit", 0, "Maximum number of entries returned by a query without aggregation")
+
 	logCmd.AddCommand(downloadCmd)
 	downloadCmd.AddCommand(downloadSearchCmd)
 	downloadSearchCmd.Flags().StringVar(&descriptorID, "descriptorID", "", "Application descriptor identifier")
@@ -59,15 +74,19 @@ func init() {
 	downloadSearchCmd.Flags().StringVar(&sgInstanceID, "sgInstanceID", "", "Service group instance identifier")
 	downloadSearchCmd.Flags().StringVar(&serviceID, "serviceID", "", "Service identifier")
 	downloadSearchCmd.Flags().StringVar(&serviceInstanceID, "serviceInstanceID", "", "Service instance identifier")
-	downloadSearchCmd.Flags().StringVar(&from, "from", "", "Start time of logs")
-	downloadSearchCmd.Flags().StringVar(&to, "to", "", "End time of logs")
+	downloadSearchCmd.Flags().StringVar(&from, "from", "", "Start time of logs, as in 2020-05-04T10:00:00Z, -15m, now-2h or yesterday")
+	downloadSearchCmd.Flags().StringVar(&to, "to", "", "End time of logs, absolute or relative to now")
+	downloadSearchCmd.Flags().StringVar(&since, "since", "", "Duration before now of the start of logs, as in 15m or 1d")
 	downloadSearchCmd.Flags().BoolVar(&desc, "desc", false, "Sort results in descending time order")
 	downloadSearchCmd.Flags().BoolVar(&metadata, "metadata", false, "Specify if the user expects to receive log entries metadata")
 	downloadSearchCmd.Flags().StringVar(&outputPath, "outputPath", "./", "Path to store the file")
+	downloadSearchCmd.Flags().DurationVar(&archiveOptions.Timeout, "timeout", cli.DefaultDownloadTimeout, "Maximum time to wait for the archive to be ready")
+	addArchiveOptions(downloadSearchCmd, archiveOptions)
 
 	downloadCmd.AddCommand(downloadGetCmd)
 	downloadGetCmd.Flags().StringVar(&requestId, "requestID", "", "request identifier")
 	downloadGetCmd.Flags().StringVar(&outputPath, "outputPath", "./", "Path to store the file")
+	addArchiveOptions(downloadGetCmd, archiveOptions)
 
 	downloadCmd.AddCommand(checkCmd)
 	checkCmd.Flags().StringVar(&requestId, "requestID", "", "request identifier")
@@ -75,6 +94,30 @@ func init() {
 	downloadCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolVarP(&watch, "watch", "w", false, "Watch for changes")
 
+	downloadCmd.AddCommand(cancelDownloadCmd)
+	downloadCmd.AddCommand(deleteDownloadCmd)
+	downloadCmd.AddCommand(pruneDownloadsCmd)
+	pruneDownloadsCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the expired download requests without removing them")
+
+	logCmd.AddCommand(savedSearchCmd)
+	savedSearchCmd.AddCommand(addSavedSearchCmd)
+	addSavedSearchCmd.Flags().StringVar(&since, "since", "", "Duration before now searched when the search is run, as in 15m or 1d. All the entries are searched if not set")
+	savedSearchCmd.AddCommand(listSavedSearchesCmd)
+	savedSearchCmd.AddCommand(runSavedSearchCmd)
+	savedSearchCmd.AddCommand(removeSavedSearchCmd)
+
+	logCmd.AddCommand(alertCmd)
+	alertCmd.AddCommand(addAlertRuleCmd)
+	addAlertRuleCmd.Flags().StringVar(&savedSearchID, "savedSearchID", "", "Saved search evaluated by the rule")
+	addAlertRuleCmd.Flags().StringVar(&alertInterval, "interval", "5m", "Time between two evaluations of the rule, at least 1m")
+	addAlertRuleCmd.Flags().Int64Var(&alertThreshold, "threshold", 1, "Number of matched entries that fires the alert")
+	addAlertRuleCmd.Flags().StringVar(&webhookURL, "webhookURL", "", "URL notified when the alert fires and when it is resolved")
+	addAlertRuleCmd.Flags().StringVar(&webhookSecret, "webhookSecret", "", "Secret used to sign the notifications in the X-Nalej-Signature header")
+	_ = addAlertRuleCmd.MarkFlagRequired("savedSearchID")
+	_ = addAlertRuleCmd.MarkFlagRequired("webhookURL")
+	alertCmd.AddCommand(listAlertRulesCmd)
+	alertCmd.AddCommand(removeAlertRuleCmd)
+
 }
 
 var searchCmd = &cobra.Command{
@@ -96,11 +139,29 @@ var searchCmd = &cobra.Command{
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
 
-		l.Search(cliOptions.Resolve("organizationID", organizationID), descriptorID, instanceID, sgID, sgInstanceID, serviceID, serviceInstanceID, message, from, to, desc, redirectLog, follow, nFirst)
+		l.Search(cliOptions.Resolve("organizationID", organizationID), descriptorID, instanceID, sgID, sgInstanceID, serviceID, serviceInstanceID, message, from, to, since, desc, redirectLog, follow, nFirst)
 
 	},
 }
 
+var queryCmd = &cobra.Command{
+	Use:   "query [query]",
+	Short: "Query application logs",
+	Long: `Query application logs with field filters, boolean operators and regular expressions, optionally aggregating
+the results. For example: 'service:web severity>=warn -health', 'severity:error | count by service every 5m' or
+'severity>=error | top 10'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+		l.Query(cliOptions.Resolve("organizationID", organizationID), args[0], from, to, since, queryLimit)
+	},
+}
+
 var downloadCmd = &cobra.Command{
 	Use:   "download",
 	Short: "Download application logs",
@@ -130,7 +191,7 @@ var downloadSearchCmd = &cobra.Command{
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
 
-		l.Download(cliOptions.Resolve("organizationID", organizationID), descriptorID, instanceID, sgID, sgInstanceID, serviceID, serviceInstanceID, message, from, to, desc, metadata, outputPath)
+		l.Download(cliOptions.Resolve("organizationID", organizationID), descriptorID, instanceID, sgID, sgInstanceID, serviceID, serviceInstanceID, message, from, to, since, desc, metadata, outputPath, archiveOptions)
 
 	},
 }
@@ -154,7 +215,7 @@ var downloadGetCmd = &cobra.Command{
 			insecure, useTLS,
 			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
 
-		l.Get(cliOptions.Resolve("organizationID", organizationID), requestId, outputPath)
+		l.Get(cliOptions.Resolve("organizationID", organizationID), requestId, outputPath, archiveOptions)
 
 	},
 }
@@ -200,3 +261,208 @@ var listCmd = &cobra.Command{
 
 	},
 }
+
+var cancelDownloadCmd = &cobra.Command{
+	Use:   "cancel requestID",
+	Short: "Cancel a download request in progress",
+	Long:  `Cancel a download request in progress`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.CancelDownload(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var deleteDownloadCmd = &cobra.Command{
+	Use:   "delete requestID",
+	Short: "Delete a finished download request",
+	Long:  `Delete a finished download request and its archive. The requests in progress must be cancelled first`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.DeleteDownload(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var pruneDownloadsCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete the expired download requests",
+	Long: `Delete the finished download requests older than the retention of the organization, set with the
+LOG_DOWNLOAD_RETENTION setting (7d by default)`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.PruneDownloads(cliOptions.Resolve("organizationID", organizationID), dryRun)
+	},
+}
+
+var savedSearchCmd = &cobra.Command{
+	Use:     "saved-search",
+	Aliases: []string{"saved-searches"},
+	Short:   "Manage saved log searches",
+	Long:    `Save log queries to run them again or to use them in alert rules`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		cmd.Help()
+	},
+}
+
+var addSavedSearchCmd = &cobra.Command{
+	Use:   "add name query",
+	Short: "Save a log query",
+	Long:  `Save a log query to run it later or to use it in alert rules. The query uses the same syntax as log query`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.AddSavedSearch(cliOptions.Resolve("organizationID", organizationID), args[0], args[1], since)
+	},
+}
+
+var listSavedSearchesCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the saved searches",
+	Long:    `List the saved searches of the organization`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.ListSavedSearches(cliOptions.Resolve("organizationID", organizationID))
+	},
+}
+
+var runSavedSearchCmd = &cobra.Command{
+	Use:   "run savedSearchID",
+	Short: "Run a saved search",
+	Long:  `Run a saved search over its time window up to now`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.RunSavedSearch(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var removeSavedSearchCmd = &cobra.Command{
+	Use:     "delete savedSearchID",
+	Aliases: []string{"remove", "del", "rm"},
+	Short:   "Delete a saved search",
+	Long:    `Delete a saved search that is not used by any alert rule`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.RemoveSavedSearch(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
+
+var alertCmd = &cobra.Command{
+	Use:     "alert",
+	Aliases: []string{"alerts"},
+	Short:   "Manage log alert rules",
+	Long:    `Manage the rules that notify a webhook when a saved search matches too many log entries`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		cmd.Help()
+	},
+}
+
+var addAlertRuleCmd = &cobra.Command{
+	Use:   "add name",
+	Short: "Add an alert rule",
+	Long: `Add a rule that runs a saved search on a schedule and notifies a webhook when the number of matched
+entries reaches the threshold, and again when it goes back below it`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.AddAlertRule(cliOptions.Resolve("organizationID", organizationID), savedSearchID, args[0], alertInterval, alertThreshold, webhookURL, webhookSecret)
+	},
+}
+
+var listAlertRulesCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the alert rules",
+	Long:    `List the alert rules of the organization with the result of their last evaluation`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.ListAlertRules(cliOptions.Resolve("organizationID", organizationID))
+	},
+}
+
+var removeAlertRuleCmd = &cobra.Command{
+	Use:     "delete alertRuleID",
+	Aliases: []string{"remove", "del", "rm"},
+	Short:   "Delete an alert rule",
+	Long:    `Delete an alert rule`,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+
+		l := cli.NewUnifiedLogging(
+			cliOptions.Resolve("nalejAddress", nalejAddress),
+			cliOptions.ResolveAsInt("port", nalejPort),
+			insecure, useTLS,
+			cliOptions.Resolve("cacert", caCertPath), cliOptions.Resolve("output", output), cliOptions.ResolveAsInt("labelLength", labelLength))
+
+		l.RemoveAlertRule(cliOptions.Resolve("organizationID", organizationID), args[0])
+	},
+}
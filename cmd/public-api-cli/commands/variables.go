@@ -18,6 +18,8 @@
 
 package commands
 
+import "time"
+
 var loginPort int
 var email string
 var password string
@@ -82,11 +84,19 @@ var nodeID string
 var message string
 var from string
 var to string
+var since string
 var redirectLog bool
 var desc bool
 var follow bool
 var nFirst bool
 var metadata bool
+var queryLimit int32
+
+var savedSearchID string
+var alertInterval string
+var alertThreshold int64
+var webhookURL string
+var webhookSecret string
 
 var rangeMinutes int32
 var clusterStatFields string
@@ -99,6 +109,9 @@ var clusterName string
 var orderBy string
 
 var outputPath string
+var valuesPath string
+var importFormat string
+var serviceSpecs []string
 var edgeControllerID string
 var assetID string
 var activate bool
@@ -129,3 +142,20 @@ var provisionNumNodes int
 var provisionTargetPlatform string
 var provisionZone string
 var provisionKubeConfigOutputPath string
+
+var operationType string
+var operationStatus string
+var waitTimeout time.Duration
+
+var idempotencyKey string
+
+var bulkEntityIDs []string
+var bulkMatchLabels string
+var dryRun bool
+
+var labelSelector string
+
+var exportFormat string
+var exportPath string
+
+var onConflict string
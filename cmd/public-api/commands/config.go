@@ -0,0 +1,192 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// EnvPrefix with the prefix of the environment variables that define configuration values.
+const EnvPrefix = "PUBLIC_API_"
+
+// configFileFlag with the name of the flag pointing to the configuration file.
+const configFileFlag = "configFile"
+
+var configFile string
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the server configuration",
+	Long:  `Inspect the server configuration`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		cmd.Help()
+	},
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration that results from applying the configuration file, the PUBLIC_API_* environment
+variables and the flags, in increasing order of precedence. Secrets are masked. Any validation problem is
+reported afterwards and the command fails.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := LoadConfiguration(cmd.Flags())
+		SetupLogging()
+		if err != nil {
+			log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load configuration")
+		}
+		config.Debug = debugLevel
+		content, err := config.ToYAML()
+		if err != nil {
+			log.Fatal().Str("trace", err.DebugReport()).Msg("cannot print configuration")
+		}
+		fmt.Print(string(content))
+		problems := config.ValidationReport()
+		if len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "\n%d configuration problems found:\n", len(problems))
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+			}
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	addConfigurationFlags(configPrintCmd)
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// EnvName returns the name of the environment variable associated with a flag. The camel case name of the flag is
+// converted to upper snake case, for example tlsClientCAPath is read from PUBLIC_API_TLS_CLIENT_CA_PATH.
+func EnvName(flagName string) string {
+	runes := []rune(flagName)
+	var name strings.Builder
+	name.WriteString(EnvPrefix)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			previousLower := unicode.IsLower(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if previousLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				name.WriteRune('_')
+			}
+		}
+		name.WriteRune(unicode.ToUpper(r))
+	}
+	return name.String()
+}
+
+// readConfigFile reads a YAML configuration file whose keys are the names of the flags. The values are returned
+// as strings so they can be parsed by the associated flag.
+func readConfigFile(path string, flags *pflag.FlagSet) (map[string]string, []string) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("cannot read configuration file %s: %s", path, err.Error())}
+	}
+	raw := make(map[string]interface{}, 0)
+	err = yaml.Unmarshal(content, &raw)
+	if err != nil {
+		return nil, []string{fmt.Sprintf("cannot parse configuration file %s: %s", path, err.Error())}
+	}
+	values := make(map[string]string, 0)
+	problems := make([]string, 0)
+	for key, value := range raw {
+		if key == configFileFlag || flags.Lookup(key) == nil {
+			problems = append(problems, fmt.Sprintf("unknown key %s in configuration file %s", key, path))
+			continue
+		}
+		values[key] = flagValue(flags.Lookup(key), value)
+	}
+	return values, problems
+}
+
+// flagValue converts a value of the configuration file to the text parsed by its flag. Lists are joined with commas,
+// and durations written in nanoseconds, as printed by config print, are converted to a duration.
+func flagValue(flag *pflag.Flag, value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case int:
+		if flag.Value.Type() == "duration" {
+			return time.Duration(typed).String()
+		}
+	}
+	return fmt.Sprint(value)
+}
+
+// LoadConfiguration completes the flags that have not been set in the command line with the values found in
+// the environment and in the configuration file, in that order. All the problems found are reported together.
+func LoadConfiguration(flags *pflag.FlagSet) derrors.Error {
+	path := configFile
+	if !flags.Changed(configFileFlag) {
+		if fromEnv, exists := os.LookupEnv(EnvName(configFileFlag)); exists {
+			path = fromEnv
+		}
+	}
+
+	problems := make([]string, 0)
+	fileValues := make(map[string]string, 0)
+	if path != "" {
+		values, fileProblems := readConfigFile(path, flags)
+		problems = append(problems, fileProblems...)
+		if values != nil {
+			fileValues = values
+		}
+	}
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed || flag.Name == configFileFlag || flag.Name == "help" {
+			return
+		}
+		source := "configuration file"
+		value, exists := os.LookupEnv(EnvName(flag.Name))
+		if exists {
+			source = EnvName(flag.Name)
+		} else {
+			value, exists = fileValues[flag.Name]
+		}
+		if !exists {
+			return
+		}
+		if err := flag.Value.Set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid value for %s from %s: %s", flag.Name, source, err.Error()))
+		}
+	})
+
+	if len(problems) > 0 {
+		return derrors.NewInvalidArgumentError(fmt.Sprintf("invalid configuration sources: %s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// configCase contains the sources of the configuration of a test case. The configuration file is written if
+// file is not empty, and passed with a flag or with the environment as set by fileFromEnv.
+type configCase struct {
+	name        string
+	file        string
+	fileFromEnv bool
+	args        []string
+	env         map[string]string
+}
+
+// configResult contains the values loaded in a test case.
+type configResult struct {
+	port    int
+	address string
+	err     error
+}
+
+var _ = ginkgo.Describe("Configuration", func() {
+
+	var dir string
+
+	// load loads the configuration of a test case with a new set of flags.
+	load := func(c configCase) configResult {
+		result := configResult{}
+		configFile = ""
+		flags := pflag.NewFlagSet("run", pflag.ContinueOnError)
+		flags.StringVar(&configFile, configFileFlag, "", "configuration file")
+		flags.IntVar(&result.port, "port", 8081, "port")
+		flags.StringVar(&result.address, "systemModelAddress", "localhost:8800", "address")
+
+		env := make(map[string]string, 0)
+		for name, value := range c.env {
+			env[name] = value
+		}
+		args := c.args
+		if c.file != "" {
+			path := filepath.Join(dir, "config.yaml")
+			gomega.Expect(ioutil.WriteFile(path, []byte(c.file), 0600)).To(gomega.Succeed())
+			if c.fileFromEnv {
+				env[EnvName(configFileFlag)] = path
+			} else {
+				args = append([]string{"--" + configFileFlag + "=" + path}, args...)
+			}
+		}
+		for name, value := range env {
+			gomega.Expect(os.Setenv(name, value)).To(gomega.Succeed())
+		}
+		defer func() {
+			for name := range env {
+				os.Unsetenv(name)
+			}
+		}()
+
+		gomega.Expect(flags.Parse(args)).To(gomega.Succeed(), c.name)
+		if err := LoadConfiguration(flags); err != nil {
+			result.err = err
+		}
+		return result
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "config")
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	ginkgo.It("should convert the flag names to environment variables", func() {
+		expected := map[string]string{
+			"port":                 "PUBLIC_API_PORT",
+			"httpPort":             "PUBLIC_API_HTTP_PORT",
+			"systemModelAddress":   "PUBLIC_API_SYSTEM_MODEL_ADDRESS",
+			"tlsClientCAPath":      "PUBLIC_API_TLS_CLIENT_CA_PATH",
+			"tlsRequireClientCert": "PUBLIC_API_TLS_REQUIRE_CLIENT_CERT",
+			"configFile":           "PUBLIC_API_CONFIG_FILE",
+		}
+		for flag, name := range expected {
+			gomega.Expect(EnvName(flag)).To(gomega.Equal(name), flag)
+		}
+	})
+
+	ginkgo.It("should apply the flags over the environment over the configuration file", func() {
+		file := "port: 9001\nsystemModelAddress: file:8800\n"
+		cases := []struct {
+			configCase
+			port    int
+			address string
+		}{
+			{configCase{name: "defaults"}, 8081, "localhost:8800"},
+			{configCase{name: "file", file: file}, 9001, "file:8800"},
+			{configCase{name: "file from the environment", file: file, fileFromEnv: true}, 9001, "file:8800"},
+			{configCase{name: "environment over file", file: file,
+				env: map[string]string{"PUBLIC_API_PORT": "9002"}}, 9002, "file:8800"},
+			{configCase{name: "empty environment over file", file: file,
+				env: map[string]string{"PUBLIC_API_SYSTEM_MODEL_ADDRESS": ""}}, 9001, ""},
+			{configCase{name: "flag over environment and file", file: file, args: []string{"--port=9003"},
+				env: map[string]string{"PUBLIC_API_PORT": "9002", "PUBLIC_API_SYSTEM_MODEL_ADDRESS": "env:8800"}},
+				9003, "env:8800"},
+			{configCase{name: "flag with the default value over file", file: file, args: []string{"--port=8081"}},
+				8081, "file:8800"},
+		}
+		for _, c := range cases {
+			result := load(c.configCase)
+			gomega.Expect(result.err).To(gomega.Succeed(), c.name)
+			gomega.Expect(result.port).To(gomega.Equal(c.port), c.name)
+			gomega.Expect(result.address).To(gomega.Equal(c.address), c.name)
+		}
+	})
+
+	ginkgo.It("should report the problems of every source", func() {
+		cases := []struct {
+			configCase
+			problems []string
+		}{
+			{configCase{name: "missing file", args: []string{"--configFile=/nonexistent/config.yaml"}},
+				[]string{"cannot read configuration file"}},
+			{configCase{name: "invalid file", file: "port: [9001"}, []string{"cannot parse configuration file"}},
+			{configCase{name: "unknown key", file: "prot: 9001\nport: 9001\n"}, []string{"unknown key prot"}},
+			{configCase{name: "invalid value in file", file: "port: high\n"},
+				[]string{"invalid value for port from configuration file"}},
+			{configCase{name: "invalid value in environment", file: "port: 9001\n",
+				env: map[string]string{"PUBLIC_API_PORT": "high"}}, []string{"invalid value for port from PUBLIC_API_PORT"}},
+			{configCase{name: "every source", file: "prot: 9001\n", env: map[string]string{"PUBLIC_API_PORT": "high"}},
+				[]string{"unknown key prot", "invalid value for port from PUBLIC_API_PORT"}},
+		}
+		for _, c := range cases {
+			result := load(c.configCase)
+			gomega.Expect(result.err).NotTo(gomega.Succeed(), c.name)
+			for _, problem := range c.problems {
+				gomega.Expect(result.err.Error()).To(gomega.ContainSubstring(problem), c.name)
+			}
+		}
+	})
+
+	ginkgo.It("should not override a flag set in the command line with an invalid environment", func() {
+		result := load(configCase{name: "flag", args: []string{"--port=9003"}, env: map[string]string{"PUBLIC_API_PORT": "high"}})
+		gomega.Expect(result.err).To(gomega.Succeed())
+		gomega.Expect(result.port).To(gomega.Equal(9003))
+	})
+
+	ginkgo.It("should load the output of config print", func() {
+		original := config
+		defer func() {
+			config = original
+			configFile = ""
+		}()
+		// newCommand returns a command with the configuration flags, as config print and run have.
+		newCommand := func() *cobra.Command {
+			root := &cobra.Command{Use: "root"}
+			root.PersistentFlags().BoolVar(&debugLevel, "debug", false, "debug")
+			command := &cobra.Command{Use: "print"}
+			addConfigurationFlags(command)
+			root.AddCommand(command)
+			return command
+		}
+
+		for _, hosts := range [][]string{{}, {"hooks.example.com", "alerts.example.com"}} {
+			configFile = ""
+			printed := newCommand()
+			gomega.Expect(printed.ParseFlags([]string{})).To(gomega.Succeed())
+			config.Port = 9100
+			config.CacheTTL = time.Second * 90
+			config.WebhookAllowedHosts = hosts
+			content, err := config.ToYAML()
+			gomega.Expect(err).To(gomega.Succeed())
+			expected := config.Masked()
+			path := filepath.Join(dir, "printed.yaml")
+			gomega.Expect(ioutil.WriteFile(path, content, 0600)).To(gomega.Succeed())
+
+			loaded := newCommand()
+			gomega.Expect(loaded.ParseFlags([]string{"--" + configFileFlag + "=" + path})).To(gomega.Succeed())
+			gomega.Expect(LoadConfiguration(loaded.Flags())).To(gomega.Succeed())
+			config.Debug = debugLevel
+			gomega.Expect(config).To(gomega.Equal(expected))
+			gomega.Expect(config.WebhookAllowedHosts).To(gomega.Equal(hosts))
+		}
+	})
+
+})
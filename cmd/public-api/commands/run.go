@@ -18,8 +18,12 @@ package commands
 
 import (
 	"github.com/nalej/public-api/internal/pkg/server"
+	"github.com/nalej/public-api/internal/pkg/server/idempotency"
+	"github.com/nalej/public-api/internal/pkg/server/tracing"
+	"github.com/nalej/public-api/internal/pkg/server/unified-logging"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 var config = server.Config{}
@@ -29,7 +33,11 @@ var runCmd = &cobra.Command{
 	Short: "Launch the server API",
 	Long:  `Launch the server API`,
 	Run: func(cmd *cobra.Command, args []string) {
+		err := LoadConfiguration(cmd.Flags())
 		SetupLogging()
+		if err != nil {
+			log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load configuration")
+		}
 		log.Info().Msg("Launching API!")
 		config.Debug = debugLevel
 		server := server.NewService(config)
@@ -38,6 +46,14 @@ var runCmd = &cobra.Command{
 }
 
 func init() {
+	addConfigurationFlags(runCmd)
+	rootCmd.AddCommand(runCmd)
+}
+
+// addConfigurationFlags registers the flags that define the server configuration on a given command.
+func addConfigurationFlags(runCmd *cobra.Command) {
+	runCmd.PersistentFlags().StringVar(&configFile, configFileFlag, "",
+		"Path of a YAML configuration file. Flags and PUBLIC_API_* environment variables take precedence over it")
 	runCmd.Flags().IntVar(&config.Port, "port", 8081, "Port to launch the Public gRPC API")
 	runCmd.Flags().IntVar(&config.HTTPPort, "httpPort", 8082, "Port to launch the Public HTTP API")
 	runCmd.PersistentFlags().StringVar(&config.SystemModelAddress, "systemModelAddress", "localhost:8800",
@@ -63,6 +79,38 @@ func init() {
 		"logDownload Manager address (host:port)")
 	runCmd.PersistentFlags().StringVar(&config.OrganizationManagerAddress, "organizationManagerAddress", "localhost:8950",
 		"Organization Manager address (host:port)")
-
-	rootCmd.AddCommand(runCmd)
+	runCmd.PersistentFlags().StringVar(&config.TLSCertPath, "tlsCertPath", "",
+		"Path of the PEM certificate used to serve TLS on the gRPC and HTTP ports")
+	runCmd.PersistentFlags().StringVar(&config.TLSKeyPath, "tlsKeyPath", "",
+		"Path of the PEM private key of the TLS certificate")
+	runCmd.PersistentFlags().StringVar(&config.TLSClientCAPath, "tlsClientCAPath", "",
+		"Path of the CA used to verify client certificates")
+	runCmd.PersistentFlags().BoolVar(&config.TLSRequireClientCert, "tlsRequireClientCert", false,
+		"Require gRPC clients to present a certificate signed by the client CA. The server certificate must be valid for client authentication")
+	runCmd.PersistentFlags().StringVar(&config.TracingExporter, "tracingExporter", tracing.NoneExporter,
+		"Destination of the trace spans: none, stdout or otlp")
+	runCmd.PersistentFlags().StringVar(&config.TracingEndpoint, "tracingEndpoint", "localhost:4318",
+		"OTLP/HTTP collector address (host:port)")
+	runCmd.PersistentFlags().BoolVar(&config.RateLimitEnabled, "rateLimitEnabled", true,
+		"Enforce per-organization and per-user request quotas")
+	runCmd.PersistentFlags().StringVar(&config.RateLimitConfigPath, "rateLimitConfigPath", "",
+		"Path of the YAML file with the request quotas. Default quotas are used if not set")
+	runCmd.PersistentFlags().DurationVar(&config.CacheTTL, "cacheTTL", time.Second*5,
+		"Time the cluster lists and resource summaries are cached. Use 0 to disable the cache")
+	runCmd.PersistentFlags().DurationVar(&config.IdempotencyWindow, "idempotencyWindow", idempotency.DefaultWindow,
+		"Time the responses of the requests sent with an Idempotency-Key are replayed. Use 0 to disable the idempotency keys")
+	runCmd.PersistentFlags().StringVar(&config.SavedSearchesPath, "savedSearchesPath", "",
+		"Path of the JSON file where the saved log searches and alert rules are kept. Required unless in mock mode")
+	runCmd.PersistentFlags().StringVar(&config.WebhookSecretKey, "webhookSecretKey", "",
+		"Key used to encrypt the webhook secrets of the alert rules. The authorization secret is used if not set")
+	runCmd.PersistentFlags().DurationVar(&config.AlertCheckInterval, "alertCheckInterval", unified_logging.DefaultAlertCheckInterval,
+		"Time between two checks of the log alert rules that are due. Use 0 to disable the alerts")
+	runCmd.PersistentFlags().StringSliceVar(&config.WebhookAllowedHosts, "webhookAllowedHosts", []string{},
+		"Comma separated list of the only hosts the alert webhooks can reach, a leading dot matches the subdomains. Any host with public addresses is allowed if not set")
+	runCmd.PersistentFlags().IntVar(&config.MetricsPort, "metricsPort", 0,
+		"Port to serve the internal metrics on /debug/vars. Disabled if 0")
+	runCmd.PersistentFlags().BoolVar(&config.Mock, "mock", false,
+		"Serve the API on top of in-memory fake components with fixture data. Do not use in production")
+	runCmd.PersistentFlags().IntVar(&config.MockLoginPort, "mockLoginPort", 8083,
+		"Port to launch the fake login service in mock mode")
 }
@@ -17,18 +17,22 @@
 package cli
 
 import (
-	"encoding/json"
+	"bytes"
 	"fmt"
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-application-go"
 	"github.com/nalej/grpc-application-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/descriptors"
 	"github.com/nalej/public-api/internal/pkg/entities"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -36,6 +40,15 @@ import (
 // WatchSleep with the time to sleep between watch calls.
 const WatchSleep = time.Second * 5
 
+// DescriptorImportReport with the result of translating a manifest into a descriptor.
+type DescriptorImportReport struct {
+	Name           string   `json:"name"`
+	DescriptorPath string   `json:"descriptor_path"`
+	Services       []string `json:"services"`
+	Rules          int      `json:"rules"`
+	Untranslated   []string `json:"untranslated"`
+}
+
 type Applications struct {
 	Connection
 	Credentials
@@ -64,23 +77,35 @@ func (a *Applications) getClient() (grpc_public_api_go.ApplicationsClient, *grpc
 	return appsClient, conn
 }
 
-func (a *Applications) createAddDescriptorRequest(organizationID string, descriptorPath string) (*grpc_application_go.AddAppDescriptorRequest, derrors.Error) {
+// createAddDescriptorRequest renders a YAML or JSON descriptor template. Variables are read from the values file if
+// set, and from the environment.
+func (a *Applications) createAddDescriptorRequest(organizationID string, descriptorPath string, valuesPath string) (*grpc_application_go.AddAppDescriptorRequest, derrors.Error) {
 
-	descPath := GetPath(descriptorPath)
-	content, err := ioutil.ReadFile(descPath)
-	if err != nil {
-		return nil, derrors.AsError(err, "cannot read descriptor")
+	values := make(descriptors.Values, 0)
+	if valuesPath != "" {
+		loaded, vErr := descriptors.LoadValues(GetPath(valuesPath))
+		if vErr != nil {
+			return nil, vErr
+		}
+		values = loaded
+	}
+
+	content, rErr := descriptors.Render(GetPath(descriptorPath), values)
+	if rErr != nil {
+		return nil, rErr
 	}
 
-	err = entities.ValidAppDescriptorFormat(content)
+	err := entities.ValidAppDescriptorFormat(content)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot validate descriptor")
 	}
 
+	// The enums of the descriptors may be written as names, as the descriptors created by the CLI do.
 	addDescriptorRequest := &grpc_application_go.AddAppDescriptorRequest{}
-	err = json.Unmarshal(content, &addDescriptorRequest)
-	if err != nil {
-		return nil, derrors.AsError(err, "cannot unmarshal structure")
+	unmarshaler := jsonpb.Unmarshaler{AllowUnknownFields: true}
+	uErr := unmarshaler.Unmarshal(bytes.NewReader(content), addDescriptorRequest)
+	if uErr != nil {
+		return nil, derrors.AsError(uErr, "cannot unmarshal structure")
 	}
 
 	addDescriptorRequest.OrganizationId = organizationID
@@ -140,7 +165,7 @@ func (a *Applications) GetStorageType(sType string) grpc_application_go.StorageT
 	return grpc_application_go.StorageType_EPHEMERAL
 }
 
-func (a *Applications) AddDescriptor(organizationID string, descriptorPath string) {
+func (a *Applications) AddDescriptor(organizationID string, descriptorPath string, valuesPath string, idempotencyKey string) {
 
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
@@ -152,14 +177,69 @@ func (a *Applications) AddDescriptor(organizationID string, descriptorPath strin
 	defer conn.Close()
 	defer cancel()
 
-	addDescriptorRequest, aErr := a.createAddDescriptorRequest(organizationID, descriptorPath)
+	addDescriptorRequest, aErr := a.createAddDescriptorRequest(organizationID, descriptorPath, valuesPath)
 	if aErr != nil {
 		log.Fatal().Str("trace", aErr.DebugReport()).Msg("cannot load application descriptor")
 	}
-	added, err := client.AddAppDescriptor(ctx, addDescriptorRequest)
+	added, err := client.AddAppDescriptor(WithIdempotencyKey(ctx, idempotencyKey), addDescriptorRequest)
 	a.PrintResultOrError(added, err, "cannot add a new application descriptor")
 }
 
+// ImportDescriptor translates a docker-compose file or Kubernetes manifests into a descriptor written in outputPath,
+// and reports the elements of the manifest that could not be translated.
+func (a *Applications) ImportDescriptor(manifestPath string, format string, name string, outputPath string) {
+	if format == "" {
+		log.Fatal().Msg("format cannot be empty, expecting compose or k8s")
+	}
+	content, err := ioutil.ReadFile(GetPath(manifestPath))
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot read manifest")
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(manifestPath), filepath.Ext(manifestPath))
+	}
+	if outputPath == "" {
+		outputPath = name + ".json"
+	}
+	result, iErr := descriptors.Import(format, content, name)
+	if iErr != nil {
+		log.Fatal().Str("trace", iErr.DebugReport()).Msg("cannot import manifest")
+	}
+	marshaler := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	marshaled, err := marshaler.MarshalToString(result.Descriptor)
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot marshal application descriptor")
+	}
+	descriptorPath := GetPath(outputPath)
+	if err := ioutil.WriteFile(descriptorPath, []byte(marshaled), 0600); err != nil {
+		log.Fatal().Err(err).Msg("cannot write application descriptor")
+	}
+	report := &DescriptorImportReport{
+		Name:           name,
+		DescriptorPath: descriptorPath,
+		Services:       make([]string, 0),
+		Rules:          len(result.Descriptor.Rules),
+		Untranslated:   result.Untranslated,
+	}
+	for _, group := range result.Descriptor.Groups {
+		for _, service := range group.Services {
+			report.Services = append(report.Services, service.Name)
+		}
+	}
+	a.PrintResultOrError(report, nil, "cannot import manifest")
+}
+
+// RenderDescriptor prints the descriptor resulting of rendering a template, without adding it.
+func (a *Applications) RenderDescriptor(organizationID string, descriptorPath string, valuesPath string) {
+	addDescriptorRequest, err := a.createAddDescriptorRequest(organizationID, descriptorPath, valuesPath)
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot render application descriptor")
+	}
+	if pErr := a.PrintResult(addDescriptorRequest); pErr != nil {
+		log.Fatal().Err(pErr).Msg("cannot print application descriptor")
+	}
+}
+
 func (a *Applications) GetDescriptor(organizationID string, descriptorID string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
@@ -229,7 +309,7 @@ func (a *Applications) GetDescriptorParameters(organizationID string, descriptor
 	a.PrintResultOrError(descriptor, err, "cannot obtain descriptor parameters")
 }
 
-func (a *Applications) ListDescriptors(organizationID string) {
+func (a *Applications) ListDescriptors(organizationID string, labelSelector string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
@@ -242,10 +322,81 @@ func (a *Applications) ListDescriptors(organizationID string) {
 	orgID := &grpc_organization_go.OrganizationId{
 		OrganizationId: organizationID,
 	}
-	list, err := client.ListAppDescriptors(ctx, orgID)
+	list, err := client.ListAppDescriptors(WithSelector(ctx, labelSelector), orgID)
 	a.PrintResultOrError(list, err, "cannot obtain descriptor list")
 }
 
+// DescriptorHistory shows the versions of the descriptors with a given name and the instances deployed from them.
+func (a *Applications) DescriptorHistory(organizationID string, name string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if name == "" {
+		log.Fatal().Msg("name cannot be empty")
+	}
+	a.load()
+	ctx, cancel := a.GetContext()
+	client, conn := a.getClient()
+	defer conn.Close()
+	defer cancel()
+
+	history, err := client.GetDescriptorHistory(ctx, &grpc_public_api_go.DescriptorHistoryRequest{
+		OrganizationId: organizationID,
+		Name:           name,
+	})
+	a.PrintResultOrError(history, err, "cannot obtain descriptor history")
+}
+
+// resolveVersion returns the identifier of the descriptor with a given version in the history.
+func (a *Applications) resolveVersion(history *grpc_public_api_go.DescriptorHistory, rawVersion string) string {
+	version, err := strconv.Atoi(strings.TrimPrefix(rawVersion, "v"))
+	if err != nil {
+		log.Fatal().Str("version", rawVersion).Msg("version must be a number")
+	}
+	for _, v := range history.Versions {
+		if v.Version == int32(version) {
+			return v.AppDescriptorId
+		}
+	}
+	log.Fatal().Str("name", history.Name).Int("version", version).Msg("version not found in the descriptor history")
+	return ""
+}
+
+// DiffDescriptors shows the changes between two descriptors. If a name is provided, from and to are the versions
+// of the descriptors with that name instead of their identifiers.
+func (a *Applications) DiffDescriptors(organizationID string, from string, to string, name string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if from == "" || to == "" {
+		log.Fatal().Msg("the descriptors to compare cannot be empty")
+	}
+	a.load()
+	ctx, cancel := a.GetContext()
+	client, conn := a.getClient()
+	defer conn.Close()
+	defer cancel()
+
+	if name != "" {
+		history, err := client.GetDescriptorHistory(ctx, &grpc_public_api_go.DescriptorHistoryRequest{
+			OrganizationId: organizationID,
+			Name:           name,
+		})
+		if err != nil {
+			a.PrintResultOrError(nil, err, "cannot obtain descriptor history")
+			return
+		}
+		from = a.resolveVersion(history, from)
+		to = a.resolveVersion(history, to)
+	}
+	diff, err := client.GetDescriptorDiff(ctx, &grpc_public_api_go.DescriptorDiffRequest{
+		OrganizationId:      organizationID,
+		FromAppDescriptorId: from,
+		ToAppDescriptorId:   to,
+	})
+	a.PrintResultOrError(diff, err, "cannot compare descriptors")
+}
+
 func (a *Applications) ModifyAppDescriptorLabels(organizationID string, descriptorID string, add bool, rawLabels string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
@@ -368,7 +519,7 @@ func (a *Applications) Undeploy(organizationID string, appInstanceID string, for
 	}
 }
 
-func (a *Applications) ListInstances(organizationID string) {
+func (a *Applications) ListInstances(organizationID string, labelSelector string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
@@ -382,7 +533,7 @@ func (a *Applications) ListInstances(organizationID string) {
 	orgID := &grpc_organization_go.OrganizationId{
 		OrganizationId: organizationID,
 	}
-	list, err := client.ListAppInstances(ctx, orgID)
+	list, err := client.ListAppInstances(WithSelector(ctx, labelSelector), orgID)
 	a.PrintResultOrError(list, err, "cannot list application instances")
 }
 
@@ -446,3 +597,45 @@ func (a *Applications) GetInstanceParameters(organizationID string, appInstanceI
 	descriptor, err := client.ListInstanceParameters(ctx, appDescriptorID)
 	a.PrintResultOrError(descriptor, err, "cannot obtain instance parameters")
 }
+
+// ExportInstanceDescriptor rebuilds the descriptor of a running instance. The descriptor is written as JSON into
+// outputPath if set, so it can be added with the descriptor add command, or printed otherwise.
+func (a *Applications) ExportInstanceDescriptor(organizationID string, appInstanceID string, outputPath string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if appInstanceID == "" {
+		log.Fatal().Msg("instanceID cannot be empty")
+	}
+	a.load()
+	ctx, cancel := a.GetContext()
+	client, conn := a.getClient()
+	defer conn.Close()
+	defer cancel()
+
+	instID := &grpc_application_go.AppInstanceId{
+		OrganizationId: organizationID,
+		AppInstanceId:  appInstanceID,
+	}
+	exported, err := client.ExportInstanceDescriptor(ctx, instID)
+	if err != nil {
+		a.PrintResultOrError(nil, err, "cannot export instance descriptor")
+		return
+	}
+	if outputPath == "" {
+		if pErr := a.PrintResult(exported); pErr != nil {
+			log.Fatal().Err(pErr).Msg("cannot print instance descriptor")
+		}
+		return
+	}
+	marshaler := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	marshaled, mErr := marshaler.MarshalToString(exported)
+	if mErr != nil {
+		log.Fatal().Err(mErr).Msg("cannot marshal instance descriptor")
+	}
+	outputFilePath := GetPath(outputPath)
+	if wErr := ioutil.WriteFile(outputFilePath, []byte(marshaled), 0600); wErr != nil {
+		log.Fatal().Err(wErr).Msg("cannot write instance descriptor")
+	}
+	fmt.Printf("Descriptor of instance %s written to %s\n", appInstanceID, outputFilePath)
+}
@@ -134,7 +134,7 @@ func (c *Clusters) Info(organizationID string, clusterID string) {
 	c.PrintResultOrError(retrieved, err, "cannot obtain cluster information")
 }
 
-func (c *Clusters) List(organizationID string, watch bool, orderBy string, desc bool) {
+func (c *Clusters) List(organizationID string, watch bool, orderBy string, desc bool, labelSelector string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
@@ -175,7 +175,7 @@ func (c *Clusters) List(organizationID string, watch bool, orderBy string, desc
 		OrganizationId: organizationID,
 		Order:          order,
 	}
-	previous, err := client.List(ctx, orgID)
+	previous, err := client.List(WithSelector(ctx, labelSelector), orgID)
 	c.PrintResultOrError(previous, err, "cannot obtain cluster list")
 	toCompare := make(map[string]*grpc_public_api_go.Cluster, 0)
 	if watch {
@@ -185,7 +185,7 @@ func (c *Clusters) List(organizationID string, watch bool, orderBy string, desc
 	}
 	for watch {
 		watchCtx, watchCancel := c.GetContext()
-		clusters, err := client.List(watchCtx, orgID)
+		clusters, err := client.List(WithSelector(watchCtx, labelSelector), orgID)
 		if err != nil {
 			c.PrintResultOrError(clusters, err, "cannot obtain cluster list information")
 		}
@@ -28,7 +28,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"io/ioutil"
+	"net/http"
 	"strings"
+	"time"
 )
 
 // Connection structure for the public API
@@ -64,16 +66,9 @@ func (c *Connection) GetSecureConnection() (*grpc.ClientConn, derrors.Error) {
 		creds = credentials.NewTLS(cfg)
 		log.Warn().Msg("CA validation will be skipped")
 	} else {
-		rootCAs := x509.NewCertPool()
-		caPath := GetPath(c.CACertPath)
-		log.Debug().Str("caCertPath", caPath).Msg("loading CA cert")
-		caCert, err := ioutil.ReadFile(caPath)
+		rootCAs, err := c.loadCACertPool()
 		if err != nil {
-			return nil, derrors.NewInternalError("Error loading CA certificate")
-		}
-		added := rootCAs.AppendCertsFromPEM(caCert)
-		if !added {
-			return nil, derrors.NewInternalError("cannot add CA certificate to the pool")
+			return nil, err
 		}
 
 		creds = credentials.NewClientTLSFromCert(rootCAs, "")
@@ -90,6 +85,46 @@ func (c *Connection) GetSecureConnection() (*grpc.ClientConn, derrors.Error) {
 	return sConn, nil
 }
 
+// loadCACertPool returns a pool with the CA certificate of the platform.
+func (c *Connection) loadCACertPool() (*x509.CertPool, derrors.Error) {
+	rootCAs := x509.NewCertPool()
+	caPath := GetPath(c.CACertPath)
+	log.Debug().Str("caCertPath", caPath).Msg("loading CA cert")
+	caCert, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, derrors.NewInternalError("Error loading CA certificate")
+	}
+	added := rootCAs.AppendCertsFromPEM(caCert)
+	if !added {
+		return nil, derrors.NewInternalError("cannot add CA certificate to the pool")
+	}
+	return rootCAs, nil
+}
+
+// GetHTTPClient returns a client for the HTTP endpoints of the platform, such as the log downloads. It applies the
+// same CA settings as the gRPC connections, using the system pool if no CA is given.
+func (c *Connection) GetHTTPClient() (*http.Client, derrors.Error) {
+	cfg := &tls.Config{}
+	if c.Insecure {
+		cfg.InsecureSkipVerify = true
+		log.Warn().Msg("CA validation will be skipped")
+	} else if c.CACertPath != "" {
+		rootCAs, err := c.loadCACertPool()
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = rootCAs
+	}
+	transport := &http.Transport{
+		Proxy:              http.ProxyFromEnvironment,
+		MaxIdleConns:       10,
+		IdleConnTimeout:    30 * time.Second,
+		DisableCompression: true,
+		TLSClientConfig:    cfg,
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
 // GetNoTLSConnection creates a connection to a non TLS based endpoint.
 func (c *Connection) GetNoTLSConnection() (*grpc.ClientConn, derrors.Error) {
 	log.Warn().Msg("Using insecure connection to a non TLS endpoint")
@@ -21,3 +21,6 @@ import "time"
 const DefaultTimeout = time.Minute
 
 const AuthHeader = "Authorization"
+
+// IdempotencyKeyHeader with the metadata entry used to send the idempotency key of a mutating request.
+const IdempotencyKeyHeader = "idempotency-key"
@@ -19,6 +19,7 @@ package cli
 import (
 	"context"
 	"github.com/nalej/derrors"
+	"github.com/nalej/public-api/internal/pkg/selector"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/metadata"
 	"io/ioutil"
@@ -123,3 +124,24 @@ func (c *Credentials) GetContext(timeout ...time.Duration) (context.Context, con
 	baseContext, cancel := context.WithTimeout(context.Background(), timeout[0])
 	return metadata.NewOutgoingContext(baseContext, md), cancel
 }
+
+// WithIdempotencyKey attaches an idempotency key to the outgoing metadata, so the server replays the original
+// response if the request is sent again with the same key.
+func WithIdempotencyKey(ctx context.Context, idempotencyKey string) context.Context {
+	if idempotencyKey == "" {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, IdempotencyKeyHeader, idempotencyKey)
+}
+
+// WithSelector attaches a label selector to the outgoing metadata, so the server only returns the entities whose
+// labels satisfy it. The expression is validated before sending the request.
+func WithSelector(ctx context.Context, expression string) context.Context {
+	if expression == "" {
+		return ctx
+	}
+	if _, err := selector.Parse(expression); err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid label selector")
+	}
+	return metadata.AppendToOutgoingContext(ctx, selector.MetadataKey, expression)
+}
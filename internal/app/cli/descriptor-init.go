@@ -0,0 +1,261 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/public-api/internal/pkg/descriptors"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/rs/zerolog/log"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// storageTypeNames with the storage types accepted by GetStorageType.
+var storageTypeNames = []string{"ephemeral", "local", "replica", "cloud"}
+
+// validStorageType checks that a storage type is known, as GetStorageType falls back to ephemeral storage.
+func validStorageType(name string) bool {
+	for _, known := range storageTypeNames {
+		if name == known {
+			return true
+		}
+	}
+	return false
+}
+
+// newStorage creates the storage of a scaffolded service.
+func (a *Applications) newStorage(storageType string, mountPath string) *grpc_application_go.Storage {
+	storage := &grpc_application_go.Storage{MountPath: mountPath, Type: a.GetStorageType(storageType)}
+	if storage.Type != grpc_application_go.StorageType_EPHEMERAL {
+		storage.Size = descriptors.DefaultVolumeSize
+	}
+	return storage
+}
+
+// parsePorts decodes a list of ports separated by commas or semicolons.
+func parsePorts(raw string) ([]int32, derrors.Error) {
+	result := make([]int32, 0)
+	for _, field := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ';' || r == ' ' }) {
+		port, err := strconv.ParseInt(field, 10, 32)
+		if err != nil {
+			return nil, derrors.NewInvalidArgumentError("invalid port").WithParams(field)
+		}
+		result = append(result, int32(port))
+	}
+	return result, nil
+}
+
+// parseServiceSpec decodes a service passed as a flag with the format
+// group/name=image[,replicas=N][,ports=80;443][,expose=public|app|none][,storage=type:/path].
+func (a *Applications) parseServiceSpec(raw string) (*descriptors.ServiceSpec, derrors.Error) {
+	fields := strings.Split(raw, ",")
+	header := strings.SplitN(fields[0], "=", 2)
+	names := strings.SplitN(header[0], "/", 2)
+	if len(header) != 2 || len(names) != 2 {
+		return nil, derrors.NewInvalidArgumentError("expecting services as group/name=image[,replicas=N][,ports=80;443][,expose=public|app|none][,storage=type:/path]").WithParams(raw)
+	}
+	spec := &descriptors.ServiceSpec{Group: names[0], Name: names[1], Image: header[1]}
+	for _, field := range fields[1:] {
+		option := strings.SplitN(field, "=", 2)
+		if len(option) != 2 {
+			return nil, derrors.NewInvalidArgumentError("expecting service options as key=value").WithParams(field)
+		}
+		switch option[0] {
+		case "replicas":
+			replicas, err := strconv.ParseInt(option[1], 10, 32)
+			if err != nil {
+				return nil, derrors.NewInvalidArgumentError("invalid replicas").WithParams(option[1])
+			}
+			spec.Replicas = int32(replicas)
+		case "ports":
+			ports, err := parsePorts(option[1])
+			if err != nil {
+				return nil, err
+			}
+			spec.Ports = ports
+		case "expose":
+			spec.Expose = option[1]
+		case "storage":
+			storage := strings.SplitN(option[1], ":", 2)
+			if len(storage) != 2 || !validStorageType(storage[0]) {
+				return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("expecting storage as type:/path with type %s", strings.Join(storageTypeNames, ", "))).WithParams(option[1])
+			}
+			spec.Storage = a.newStorage(storage[0], storage[1])
+		default:
+			return nil, derrors.NewInvalidArgumentError("unknown service option").WithParams(option[0])
+		}
+	}
+	return spec, nil
+}
+
+// prompter asks the questions of the descriptor wizard.
+type prompter struct {
+	reader *bufio.Reader
+	writer io.Writer
+}
+
+// ask asks a question returning the default value if the answer is empty.
+func (p *prompter) ask(question string, defaultValue string) string {
+	if defaultValue != "" {
+		fmt.Fprintf(p.writer, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.writer, "%s: ", question)
+	}
+	answer, err := p.reader.ReadString('\n')
+	if err != nil && answer == "" {
+		log.Fatal().Err(err).Msg("cannot read answer")
+	}
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return defaultValue
+	}
+	return answer
+}
+
+// askRequired asks a question until the answer is not empty.
+func (p *prompter) askRequired(question string, defaultValue string) string {
+	for {
+		if answer := p.ask(question, defaultValue); answer != "" {
+			return answer
+		}
+		fmt.Fprintln(p.writer, "A value is required")
+	}
+}
+
+// askOption asks a question until the answer is one of the options.
+func (p *prompter) askOption(question string, options []string, defaultValue string) string {
+	for {
+		answer := p.ask(fmt.Sprintf("%s (%s)", question, strings.Join(options, "/")), defaultValue)
+		for _, option := range options {
+			if answer == option {
+				return answer
+			}
+		}
+		fmt.Fprintf(p.writer, "Expecting one of %s\n", strings.Join(options, ", "))
+	}
+}
+
+// askYes asks a yes/no question.
+func (p *prompter) askYes(question string) bool {
+	return p.askOption(question, []string{"y", "n"}, "n") == "y"
+}
+
+// askService asks the questions describing a service of a group.
+func (a *Applications) askService(p *prompter, group string) descriptors.ServiceSpec {
+	spec := descriptors.ServiceSpec{Group: group}
+	spec.Name = p.askRequired("Service name", "")
+	spec.Image = p.askRequired("Image", "")
+	for {
+		replicas, err := strconv.ParseInt(p.ask("Replicas", "1"), 10, 32)
+		if err == nil && replicas > 0 {
+			spec.Replicas = int32(replicas)
+			break
+		}
+		fmt.Fprintln(p.writer, "Expecting a positive number")
+	}
+	for {
+		ports, err := parsePorts(p.ask("Ports separated by commas", ""))
+		if err == nil {
+			spec.Ports = ports
+			break
+		}
+		fmt.Fprintln(p.writer, err.Error())
+	}
+	if len(spec.Ports) > 0 {
+		spec.Expose = p.askOption("Expose the ports to", []string{descriptors.ExposePublic, descriptors.ExposeApp, descriptors.ExposeNone}, descriptors.ExposeApp)
+	}
+	storageType := p.askOption("Storage", append([]string{"none"}, storageTypeNames...), "none")
+	if storageType != "none" {
+		spec.Storage = a.newStorage(storageType, p.askRequired("Mount path", ""))
+	}
+	return spec
+}
+
+// askScaffoldSpec runs the interactive wizard.
+func (a *Applications) askScaffoldSpec(p *prompter, name string) descriptors.ScaffoldSpec {
+	spec := descriptors.ScaffoldSpec{Name: p.askRequired("Descriptor name", name)}
+	for {
+		group := p.askRequired("Service group name", descriptors.DefaultGroupName)
+		for {
+			spec.Services = append(spec.Services, a.askService(p, group))
+			if !p.askYes(fmt.Sprintf("Add another service to %s?", group)) {
+				break
+			}
+		}
+		if !p.askYes("Add another service group?") {
+			return spec
+		}
+	}
+}
+
+// validScaffold checks the new descriptor with the validations applied when it is added.
+func validScaffold(descriptor *grpc_application_go.AddAppDescriptorRequest) derrors.Error {
+	toValidate := proto.Clone(descriptor).(*grpc_application_go.AddAppDescriptorRequest)
+	toValidate.OrganizationId = "scaffold"
+	return entities.ValidAddAppDescriptor(toValidate)
+}
+
+// InitDescriptor creates a new descriptor file. The services are asked interactively unless they are passed as flags.
+func (a *Applications) InitDescriptor(name string, services []string, outputPath string) {
+	var spec descriptors.ScaffoldSpec
+	if len(services) > 0 {
+		spec.Name = name
+		for _, raw := range services {
+			service, err := a.parseServiceSpec(raw)
+			if err != nil {
+				log.Fatal().Str("trace", err.DebugReport()).Msg("invalid service")
+			}
+			spec.Services = append(spec.Services, *service)
+		}
+	} else {
+		spec = a.askScaffoldSpec(&prompter{reader: bufio.NewReader(os.Stdin), writer: os.Stdout}, name)
+	}
+
+	descriptor, err := descriptors.Scaffold(spec)
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot create application descriptor")
+	}
+	if err := validScaffold(descriptor); err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid application descriptor")
+	}
+
+	if outputPath == "" {
+		outputPath = spec.Name + ".json"
+	}
+	descriptorPath := GetPath(outputPath)
+	if _, sErr := os.Stat(descriptorPath); sErr == nil {
+		log.Fatal().Str("path", descriptorPath).Msg("descriptor file already exists")
+	}
+	marshaler := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	marshaled, mErr := marshaler.MarshalToString(descriptor)
+	if mErr != nil {
+		log.Fatal().Err(mErr).Msg("cannot marshal application descriptor")
+	}
+	if wErr := ioutil.WriteFile(descriptorPath, []byte(marshaled), 0600); wErr != nil {
+		log.Fatal().Err(wErr).Msg("cannot write application descriptor")
+	}
+	fmt.Printf("Descriptor %s written to %s\n", spec.Name, descriptorPath)
+}
@@ -176,7 +176,7 @@ func (d *Devices) ListDeviceGroups(organizationID string) {
 	d.PrintResultOrError(dgs, err, "cannot list device groups")
 }
 
-func (d *Devices) ListDevices(organizationID string, deviceGroupID string) {
+func (d *Devices) ListDevices(organizationID string, deviceGroupID string, labelSelector string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
@@ -194,7 +194,7 @@ func (d *Devices) ListDevices(organizationID string, deviceGroupID string) {
 		OrganizationId: organizationID,
 		DeviceGroupId:  deviceGroupID,
 	}
-	devices, err := client.ListDevices(ctx, dgID)
+	devices, err := client.ListDevices(WithSelector(ctx, labelSelector), dgID)
 	d.PrintResultOrError(devices, err, "cannot list devices")
 }
 
@@ -20,11 +20,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/araddon/dateparse"
-
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-monitoring-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
 
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
@@ -52,30 +51,33 @@ func (a *AssetSelector) ToGRPC() *grpc_inventory_go.AssetSelector {
 
 type TimeRange struct {
 	// Timestamps are strings to be parsed
-	Timestamp  string
-	Start      string
-	End        string
+	Timestamp string
+	Start     string
+	End       string
+	// Since is a duration that sets the start of the range before the current time
+	Since      string
 	Resolution time.Duration
 }
 
-func dateParse(in string) int64 {
-	if in == "" {
-		return 0
+func (t *TimeRange) ToGRPC() *grpc_monitoring_go.QueryMetricsRequest_TimeRange {
+	now := time.Now()
+	var timestamp int64
+	if t.Timestamp != "" {
+		point, err := timeexpr.Parse(t.Timestamp, now)
+		if err != nil {
+			log.Fatal().Str("trace", err.DebugReport()).Msg("invalid timestamp")
+		}
+		timestamp = timeexpr.Unix(point)
 	}
-
-	t, err := dateparse.ParseLocal(in)
+	interval, err := timeexpr.ParseRange(t.Start, t.End, t.Since, now)
 	if err != nil {
-		log.Fatal().Str("timestamp", in).Err(err).Msg("invalid timestamp")
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid time range")
 	}
 
-	return t.UTC().Unix()
-}
-
-func (t *TimeRange) ToGRPC() *grpc_monitoring_go.QueryMetricsRequest_TimeRange {
 	timeRange := &grpc_monitoring_go.QueryMetricsRequest_TimeRange{
-		Timestamp:  dateParse(t.Timestamp),
-		TimeStart:  dateParse(t.Start),
-		TimeEnd:    dateParse(t.End),
+		Timestamp:  timestamp,
+		TimeStart:  interval.FromUnix(),
+		TimeEnd:    interval.ToUnix(),
 		Resolution: int64(t.Resolution.Seconds()),
 	}
 
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-public-api-go"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the inventory export.
+const (
+	ExportCSV     = "csv"
+	ExportJSON    = "json"
+	ExportNDJSON  = "ndjson"
+	ExportGeoJSON = "geojson"
+)
+
+// Types of the items of the inventory export.
+const (
+	ExportDevice         = "DEVICE"
+	ExportAsset          = "ASSET"
+	ExportEdgeController = "EC"
+)
+
+// Sources of the location of an exported item.
+const (
+	LocationOwn            = "own"
+	LocationEdgeController = "edge_controller"
+)
+
+// ValidExportFormat checks whether an inventory export format is supported.
+func ValidExportFormat(format string) bool {
+	return format == ExportCSV || format == ExportJSON || format == ExportNDJSON || format == ExportGeoJSON
+}
+
+// ExportCPU with the description of a processor.
+type ExportCPU struct {
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Architecture string `json:"architecture,omitempty"`
+	NumCores     int32  `json:"num_cores"`
+}
+
+// ExportDisk with the description of a storage device.
+type ExportDisk struct {
+	Type          string `json:"type,omitempty"`
+	TotalCapacity int64  `json:"total_capacity"`
+}
+
+// ExportNetInterface with the description of a network interface.
+type ExportNetInterface struct {
+	Type         string `json:"type,omitempty"`
+	LinkCapacity int64  `json:"link_capacity"`
+}
+
+// ExportItem with the hardware report of a device, asset or edge controller. Times are in RFC3339 and empty if
+// unknown.
+type ExportItem struct {
+	Type               string               `json:"type"`
+	ID                 string               `json:"id"`
+	Name               string               `json:"name,omitempty"`
+	EdgeControllerID   string               `json:"edge_controller_id,omitempty"`
+	EdgeControllerName string               `json:"edge_controller_name,omitempty"`
+	DeviceGroupID      string               `json:"device_group_id,omitempty"`
+	AgentID            string               `json:"agent_id,omitempty"`
+	IP                 string               `json:"ip,omitempty"`
+	Status             string               `json:"status,omitempty"`
+	LastSeen           string               `json:"last_seen,omitempty"`
+	Registered         string               `json:"registered,omitempty"`
+	Labels             map[string]string    `json:"labels,omitempty"`
+	Geolocation        string               `json:"geolocation,omitempty"`
+	LocationSource     string               `json:"location_source,omitempty"`
+	OSName             string               `json:"os_name,omitempty"`
+	OSVersion          string               `json:"os_version,omitempty"`
+	OSArchitecture     string               `json:"os_architecture,omitempty"`
+	CPUs               []ExportCPU          `json:"cpus,omitempty"`
+	InstalledRAM       int64                `json:"installed_ram"`
+	Storage            []ExportDisk         `json:"storage,omitempty"`
+	NetInterfaces      []ExportNetInterface `json:"net_interfaces,omitempty"`
+}
+
+// Cores returns the total number of cores of the item.
+func (e *ExportItem) Cores() int64 {
+	total := int64(0)
+	for _, cpu := range e.CPUs {
+		total += int64(cpu.NumCores)
+	}
+	return total
+}
+
+// TotalStorage returns the total capacity of the storage devices of the item.
+func (e *ExportItem) TotalStorage() int64 {
+	total := int64(0)
+	for _, disk := range e.Storage {
+		total += disk.TotalCapacity
+	}
+	return total
+}
+
+// exportTime formats a timestamp in seconds, leaving it empty if unset.
+func exportTime(seconds int64) string {
+	if seconds == 0 {
+		return ""
+	}
+	return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+}
+
+// setAssetInfo fills in the operating system and hardware details of an item.
+func (e *ExportItem) setAssetInfo(os *grpc_public_api_go.OperatingSystemInfo, hardware *grpc_inventory_go.HardwareInfo,
+	storage []*grpc_inventory_go.StorageHardwareInfo) {
+	if os != nil {
+		e.OSName = os.Name
+		e.OSVersion = os.Version
+		e.OSArchitecture = os.Architecture
+	}
+	if hardware != nil {
+		e.InstalledRAM = hardware.InstalledRam
+		for _, cpu := range hardware.Cpus {
+			e.CPUs = append(e.CPUs, ExportCPU{Manufacturer: cpu.Manufacturer, Model: cpu.Model,
+				Architecture: cpu.Architecture, NumCores: cpu.NumCores})
+		}
+		for _, net := range hardware.NetInterfaces {
+			e.NetInterfaces = append(e.NetInterfaces, ExportNetInterface{Type: net.Type, LinkCapacity: net.LinkCapacity})
+		}
+	}
+	for _, disk := range storage {
+		e.Storage = append(e.Storage, ExportDisk{Type: disk.Type, TotalCapacity: disk.TotalCapacity})
+	}
+}
+
+// ToExportItems flattens an inventory list into the exported items: the edge controllers first, then their assets
+// and the devices. The assets without a location take the one of their edge controller.
+func ToExportItems(list *grpc_public_api_go.InventoryList) []*ExportItem {
+	controllers := make(map[string]*grpc_public_api_go.EdgeController, len(list.Controllers))
+	result := make([]*ExportItem, 0, len(list.Controllers)+len(list.Assets)+len(list.Devices))
+	for _, ec := range list.Controllers {
+		controllers[ec.EdgeControllerId] = ec
+		item := &ExportItem{
+			Type:     ExportEdgeController,
+			ID:       ec.EdgeControllerId,
+			Name:     ec.Name,
+			Status:   ec.StatusName,
+			LastSeen: exportTime(ec.LastAliveTimestamp),
+			Labels:   ec.Labels,
+		}
+		if ec.Location != nil && ec.Location.Geolocation != "" {
+			item.Geolocation, item.LocationSource = ec.Location.Geolocation, LocationOwn
+		}
+		if ec.AssetInfo != nil {
+			item.setAssetInfo(ec.AssetInfo.Os, ec.AssetInfo.Hardware, ec.AssetInfo.Storage)
+		}
+		result = append(result, item)
+	}
+	for _, asset := range list.Assets {
+		item := &ExportItem{
+			Type:             ExportAsset,
+			ID:               asset.AssetId,
+			EdgeControllerID: asset.EdgeControllerId,
+			AgentID:          asset.AgentId,
+			IP:               asset.EicNetIp,
+			Status:           asset.StatusName,
+			LastSeen:         exportTime(asset.LastAliveTimestamp),
+			Labels:           asset.Labels,
+		}
+		ec, found := controllers[asset.EdgeControllerId]
+		if found {
+			item.EdgeControllerName = ec.Name
+		}
+		if asset.Location != nil && asset.Location.Geolocation != "" {
+			item.Geolocation, item.LocationSource = asset.Location.Geolocation, LocationOwn
+		} else if found && ec.Location != nil && ec.Location.Geolocation != "" {
+			item.Geolocation, item.LocationSource = ec.Location.Geolocation, LocationEdgeController
+		}
+		item.setAssetInfo(asset.Os, asset.Hardware, asset.Storage)
+		result = append(result, item)
+	}
+	for _, device := range list.Devices {
+		id := device.AssetDeviceId
+		if id == "" {
+			id = device.DeviceId
+		}
+		item := &ExportItem{
+			Type:          ExportDevice,
+			ID:            id,
+			DeviceGroupID: device.DeviceGroupId,
+			Status:        device.DeviceStatusName,
+			Registered:    exportTime(device.RegisterSince),
+			Labels:        device.Labels,
+		}
+		if device.Location != nil && device.Location.Geolocation != "" {
+			item.Geolocation, item.LocationSource = device.Location.Geolocation, LocationOwn
+		}
+		if device.AssetInfo != nil {
+			item.setAssetInfo(device.AssetInfo.Os, device.AssetInfo.Hardware, device.AssetInfo.Storage)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// ParseCoordinates extracts the latitude and longitude of a geolocation written as "lat,lon" or "lat lon", as in
+// 40.4168,-3.7038. The rest of the geolocations, such as city names, have no coordinates.
+func ParseCoordinates(geolocation string) (float64, float64, bool) {
+	fields := strings.FieldsFunc(geolocation, func(r rune) bool {
+		return r == ',' || r == ' ' || r == ';'
+	})
+	if len(fields) != 2 {
+		return 0, 0, false
+	}
+	lat, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// exportHeader with the columns of the CSV export.
+var exportHeader = []string{"TYPE", "ID", "NAME", "EDGE_CONTROLLER_ID", "EDGE_CONTROLLER_NAME", "DEVICE_GROUP_ID",
+	"AGENT_ID", "IP", "STATUS", "LAST_SEEN", "REGISTERED", "LABELS", "GEOLOCATION", "LOCATION_SOURCE", "OS_NAME",
+	"OS_VERSION", "OS_ARCHITECTURE", "CPUS", "CPU_CORES", "CPU_MODELS", "INSTALLED_RAM", "STORAGE_TOTAL", "STORAGE",
+	"NET_INTERFACES"}
+
+// exportLabels joins the labels of an item in a stable order, as in env=prod;tier=db.
+func exportLabels(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", key, labels[key]))
+	}
+	return strings.Join(pairs, ";")
+}
+
+// csvFormulaPrefixes with the first characters that make a spreadsheet evaluate a cell as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// csvCell neutralises a user-controlled value so a spreadsheet does not evaluate it as a formula, prefixing it with
+// a single quote.
+func csvCell(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvRow returns the columns of an item in the CSV export. The lists are joined with semicolons.
+func (e *ExportItem) csvRow() []string {
+	models := make([]string, 0, len(e.CPUs))
+	for _, cpu := range e.CPUs {
+		models = append(models, strings.TrimSpace(fmt.Sprintf("%s %s", cpu.Manufacturer, cpu.Model)))
+	}
+	disks := make([]string, 0, len(e.Storage))
+	for _, disk := range e.Storage {
+		disks = append(disks, fmt.Sprintf("%s:%d", disk.Type, disk.TotalCapacity))
+	}
+	nets := make([]string, 0, len(e.NetInterfaces))
+	for _, net := range e.NetInterfaces {
+		nets = append(nets, fmt.Sprintf("%s:%d", net.Type, net.LinkCapacity))
+	}
+	row := []string{e.Type, e.ID, e.Name, e.EdgeControllerID, e.EdgeControllerName, e.DeviceGroupID, e.AgentID, e.IP,
+		e.Status, e.LastSeen, e.Registered, exportLabels(e.Labels), e.Geolocation, e.LocationSource, e.OSName,
+		e.OSVersion, e.OSArchitecture, strconv.Itoa(len(e.CPUs)), strconv.FormatInt(e.Cores(), 10),
+		strings.Join(models, ";"), strconv.FormatInt(e.InstalledRAM, 10), strconv.FormatInt(e.TotalStorage(), 10),
+		strings.Join(disks, ";"), strings.Join(nets, ";")}
+	for index, value := range row {
+		row[index] = csvCell(value)
+	}
+	return row
+}
+
+// geoJSONFeature with an item of the GeoJSON export. The geometry is null if the location has no coordinates.
+type geoJSONFeature struct {
+	Type       string           `json:"type"`
+	Geometry   *geoJSONGeometry `json:"geometry"`
+	Properties *ExportItem      `json:"properties"`
+}
+
+type geoJSONGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+type geoJSONCollection struct {
+	Type     string            `json:"type"`
+	Features []*geoJSONFeature `json:"features"`
+}
+
+// WriteInventoryExport writes the items in the given format.
+func WriteInventoryExport(w io.Writer, items []*ExportItem, format string) derrors.Error {
+	switch format {
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write(exportHeader)
+		for _, item := range items {
+			_ = writer.Write(item.csvRow())
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return derrors.NewInternalError("cannot write inventory export", err)
+		}
+	case ExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(items); err != nil {
+			return derrors.NewInternalError("cannot write inventory export", err)
+		}
+	case ExportNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, item := range items {
+			if err := encoder.Encode(item); err != nil {
+				return derrors.NewInternalError("cannot write inventory export", err)
+			}
+		}
+	case ExportGeoJSON:
+		collection := geoJSONCollection{Type: "FeatureCollection", Features: make([]*geoJSONFeature, 0, len(items))}
+		for _, item := range items {
+			feature := &geoJSONFeature{Type: "Feature", Properties: item}
+			if lat, lon, ok := ParseCoordinates(item.Geolocation); ok {
+				// GeoJSON positions are longitude first.
+				feature.Geometry = &geoJSONGeometry{Type: "Point", Coordinates: []float64{lon, lat}}
+			}
+			collection.Features = append(collection.Features, feature)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(collection); err != nil {
+			return derrors.NewInternalError("cannot write inventory export", err)
+		}
+	default:
+		return derrors.NewInvalidArgumentError("unsupported export format").WithParams(format)
+	}
+	return nil
+}
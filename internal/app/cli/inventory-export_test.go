@@ -0,0 +1,133 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func testInventoryList() *grpc_public_api_go.InventoryList {
+	return &grpc_public_api_go.InventoryList{
+		Controllers: []*grpc_public_api_go.EdgeController{{
+			EdgeControllerId:   "ec",
+			Name:               "=HYPERLINK(\"http://evil\")",
+			StatusName:         "ONLINE",
+			LastAliveTimestamp: 1600000000,
+			Labels:             map[string]string{"zone": "center", "env": "prod"},
+			Location:           &grpc_inventory_go.InventoryLocation{Geolocation: "40.4168,-3.7038"},
+		}},
+		Assets: []*grpc_public_api_go.Asset{{
+			AssetId:          "asset",
+			EdgeControllerId: "ec",
+			EicNetIp:         "10.0.0.2",
+			Os:               &grpc_public_api_go.OperatingSystemInfo{Name: "ubuntu", Version: "18.04"},
+			Hardware: &grpc_inventory_go.HardwareInfo{
+				InstalledRam:  4096,
+				Cpus:          []*grpc_inventory_go.CPUInfo{{Manufacturer: "Intel", Model: "i7, 4th gen", NumCores: 4}},
+				NetInterfaces: []*grpc_inventory_go.NetworkingHardwareInfo{{Type: "eth", LinkCapacity: 1000}},
+			},
+			Storage: []*grpc_inventory_go.StorageHardwareInfo{{Type: "ssd", TotalCapacity: 256}, {Type: "hdd", TotalCapacity: 1000}},
+		}},
+		Devices: []*grpc_public_api_go.Device{{
+			DeviceId:      "device",
+			DeviceGroupId: "group",
+			Location:      &grpc_inventory_go.InventoryLocation{Geolocation: "Madrid"},
+		}},
+	}
+}
+
+var _ = ginkgo.Describe("Inventory export", func() {
+
+	ginkgo.It("should parse the coordinates of a geolocation", func() {
+		lat, lon, ok := ParseCoordinates("40.4168,-3.7038")
+		gomega.Expect(ok).To(gomega.BeTrue())
+		gomega.Expect(lat).To(gomega.Equal(40.4168))
+		gomega.Expect(lon).To(gomega.Equal(-3.7038))
+		_, _, ok = ParseCoordinates("-33.86 151.2")
+		gomega.Expect(ok).To(gomega.BeTrue())
+		for _, invalid := range []string{"", "Madrid", "Madrid, Spain", "91,0", "0,181", "1,2,3"} {
+			_, _, ok = ParseCoordinates(invalid)
+			gomega.Expect(ok).To(gomega.BeFalse(), invalid)
+		}
+	})
+
+	ginkgo.It("should use the location of the edge controller for the assets without one", func() {
+		items := ToExportItems(testInventoryList())
+		gomega.Expect(len(items)).To(gomega.Equal(3))
+		gomega.Expect(items[0].Type).To(gomega.Equal(ExportEdgeController))
+		gomega.Expect(items[0].LocationSource).To(gomega.Equal(LocationOwn))
+		asset := items[1]
+		gomega.Expect(asset.EdgeControllerName).To(gomega.Equal(items[0].Name))
+		gomega.Expect(asset.Geolocation).To(gomega.Equal("40.4168,-3.7038"))
+		gomega.Expect(asset.LocationSource).To(gomega.Equal(LocationEdgeController))
+		gomega.Expect(asset.OSName).To(gomega.Equal("ubuntu"))
+		gomega.Expect(asset.Cores()).To(gomega.Equal(int64(4)))
+		gomega.Expect(asset.TotalStorage()).To(gomega.Equal(int64(1256)))
+		gomega.Expect(items[2].LocationSource).To(gomega.Equal(LocationOwn))
+	})
+
+	ginkgo.It("should quote and neutralise the CSV cells", func() {
+		buffer := &bytes.Buffer{}
+		gomega.Expect(WriteInventoryExport(buffer, ToExportItems(testInventoryList()), ExportCSV)).To(gomega.Succeed())
+		records, err := csv.NewReader(buffer).ReadAll()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(records)).To(gomega.Equal(4))
+		gomega.Expect(records[0]).To(gomega.Equal(exportHeader))
+		// The user-controlled names are not evaluated as formulas.
+		gomega.Expect(records[1][2]).To(gomega.Equal("'=HYPERLINK(\"http://evil\")"))
+		gomega.Expect(records[2][4]).To(gomega.Equal("'=HYPERLINK(\"http://evil\")"))
+		gomega.Expect(records[1][11]).To(gomega.Equal("env=prod;zone=center"))
+		gomega.Expect(records[1][12]).To(gomega.Equal("40.4168,-3.7038"))
+		gomega.Expect(records[2][19]).To(gomega.Equal("Intel i7, 4th gen"))
+		gomega.Expect(records[2][22]).To(gomega.Equal("ssd:256;hdd:1000"))
+		gomega.Expect(csvCell("-2+3")).To(gomega.Equal("'-2+3"))
+		gomega.Expect(csvCell("@SUM(A1)")).To(gomega.Equal("'@SUM(A1)"))
+		gomega.Expect(csvCell("plain")).To(gomega.Equal("plain"))
+	})
+
+	ginkgo.It("should place the GeoJSON features on their coordinates", func() {
+		buffer := &bytes.Buffer{}
+		gomega.Expect(WriteInventoryExport(buffer, ToExportItems(testInventoryList()), ExportGeoJSON)).To(gomega.Succeed())
+		collection := struct {
+			Type     string `json:"type"`
+			Features []struct {
+				Geometry *struct {
+					Type        string    `json:"type"`
+					Coordinates []float64 `json:"coordinates"`
+				} `json:"geometry"`
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"features"`
+		}{}
+		gomega.Expect(json.Unmarshal(buffer.Bytes(), &collection)).To(gomega.Succeed())
+		gomega.Expect(collection.Type).To(gomega.Equal("FeatureCollection"))
+		gomega.Expect(len(collection.Features)).To(gomega.Equal(3))
+		gomega.Expect(collection.Features[1].Geometry.Type).To(gomega.Equal("Point"))
+		gomega.Expect(collection.Features[1].Geometry.Coordinates).To(gomega.Equal([]float64{-3.7038, 40.4168}))
+		gomega.Expect(collection.Features[2].Geometry).To(gomega.BeNil())
+		gomega.Expect(collection.Features[2].Properties["geolocation"]).To(gomega.Equal("Madrid"))
+	})
+
+	ginkgo.It("should reject an unknown format", func() {
+		gomega.Expect(WriteInventoryExport(&bytes.Buffer{}, nil, "xml")).NotTo(gomega.Succeed())
+	})
+})
@@ -23,6 +23,8 @@ import (
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"io"
+	"os"
 )
 
 type Inventory struct {
@@ -53,7 +55,7 @@ func (i *Inventory) getClient() (grpc_public_api_go.InventoryClient, *grpc.Clien
 	return client, conn
 }
 
-func (i *Inventory) List(organizationID string) {
+func (i *Inventory) List(organizationID string, labelSelector string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
@@ -68,11 +70,51 @@ func (i *Inventory) List(organizationID string) {
 		OrganizationId: organizationID,
 	}
 
-	inventory, err := client.List(ctx, orgID)
+	inventory, err := client.List(WithSelector(ctx, labelSelector), orgID)
 	i.PrintResultOrError(inventory, err, "cannot retrieve inventory list")
 
 }
 
+// Export writes the hardware report of the inventory in CSV, JSON, NDJSON or GeoJSON to a file, or to the standard output
+// if the path is empty.
+func (i *Inventory) Export(organizationID string, labelSelector string, format string, outputPath string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if !ValidExportFormat(format) {
+		log.Fatal().Str("format", format).Msg("format must be csv, json, ndjson or geojson")
+	}
+
+	i.load()
+	ctx, cancel := i.GetContext()
+	client, conn := i.getClient()
+	defer conn.Close()
+	defer cancel()
+
+	inventory, err := client.List(WithSelector(ctx, labelSelector), &grpc_organization_go.OrganizationId{
+		OrganizationId: organizationID,
+	})
+	i.ExitOnError(err, "cannot retrieve inventory list")
+	items := ToExportItems(inventory)
+
+	var w io.Writer = os.Stdout
+	if outputPath != "" {
+		file, fErr := os.Create(outputPath)
+		if fErr != nil {
+			log.Fatal().Err(fErr).Str("path", outputPath).Msg("cannot create export file")
+		}
+		defer file.Close()
+		w = file
+	}
+	if wErr := WriteInventoryExport(w, items, format); wErr != nil {
+		log.Fatal().Str("trace", wErr.DebugReport()).Msg("cannot export inventory")
+	}
+	if outputPath != "" {
+		log.Info().Int("controllers", len(inventory.Controllers)).Int("assets", len(inventory.Assets)).
+			Int("devices", len(inventory.Devices)).Str("path", outputPath).Msg("inventory exported")
+	}
+}
+
 func (i *Inventory) Summary(organizationID string) {
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
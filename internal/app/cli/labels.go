@@ -0,0 +1,91 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"strings"
+)
+
+type Labels struct {
+	Connection
+	Credentials
+}
+
+func NewLabels(address string, port int, insecure bool, useTLS bool, caCertPath string, output string, labelLength int) *Labels {
+	return &Labels{
+		Connection:  *NewConnection(address, port, insecure, useTLS, caCertPath, output, labelLength),
+		Credentials: *NewEmptyCredentials(DefaultPath),
+	}
+}
+
+func (l *Labels) load() {
+	err := l.LoadCredentials()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load credentials, try login first")
+	}
+}
+
+func (l *Labels) getClient() (grpc_public_api_go.LabelsClient, *grpc.ClientConn) {
+	conn, err := l.GetConnection()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot create the connection with the Nalej platform")
+	}
+	client := grpc_public_api_go.NewLabelsClient(conn)
+	return client, conn
+}
+
+// BulkUpdate adds, removes or replaces the labels of the entities of a resource type selected by identifier or
+// by labels or by a label selector, and prints the result of each entity.
+func (l *Labels) BulkUpdate(organizationID string, resourceType string, operation string, entityIDs []string,
+	rawMatchLabels string, labelSelector string, rawLabels string, dryRun bool) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if len(entityIDs) == 0 && rawMatchLabels == "" && labelSelector == "" {
+		log.Fatal().Msg("either the entity identifiers, the labels to match or the selector must be set")
+	}
+	if labelSelector != "" {
+		if _, err := selector.Parse(labelSelector); err != nil {
+			log.Fatal().Str("trace", err.DebugReport()).Msg("invalid label selector")
+		}
+	}
+	request := &grpc_public_api_go.BulkLabelRequest{
+		OrganizationId: organizationID,
+		ResourceType:   strings.ToUpper(resourceType),
+		EntityIds:      entityIDs,
+		Selector:       labelSelector,
+		Operation:      operation,
+		DryRun:         dryRun,
+	}
+	if rawMatchLabels != "" {
+		request.MatchLabels = GetLabels(rawMatchLabels)
+	}
+	if rawLabels != "" {
+		request.Labels = GetLabels(rawLabels)
+	}
+	l.load()
+	ctx, cancel := l.GetContext()
+	client, conn := l.getClient()
+	defer conn.Close()
+	defer cancel()
+	response, err := client.BulkUpdate(ctx, request)
+	l.PrintResultOrError(response, err, "cannot update labels")
+}
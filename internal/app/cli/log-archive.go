@@ -0,0 +1,617 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nalej/derrors"
+	"github.com/rs/zerolog/log"
+)
+
+// DownloadRetries with the number of times a log archive download is resumed after a transfer error.
+const DownloadRetries = 5
+
+// DownloadRetrySleep with the base time between the attempts of a download. It grows with each attempt.
+const DownloadRetrySleep = time.Second * 2
+
+// DefaultDownloadTimeout with the maximum time to wait for a log archive to be ready.
+const DefaultDownloadTimeout = time.Minute * 10
+
+// ProgressInterval with the minimum time between two progress updates.
+const ProgressInterval = time.Second
+
+// PartialSuffix of the files with an incomplete download. Requesting the archive again resumes them.
+const PartialSuffix = ".part"
+
+// ValidatorSuffix of the file next to a partial download with the ETag or the Last-Modified date of the archive. It
+// is sent as If-Range when resuming, so the server sends the whole archive again if it changed.
+const ValidatorSuffix = ".part.validator"
+
+// ChecksumHeader with the hex SHA-256 of an archive, as an alternative to the Digest header.
+const ChecksumHeader = "X-Checksum-Sha256"
+
+const (
+	// ExtractNDJSON writes the extracted entries as JSON lines.
+	ExtractNDJSON = "ndjson"
+	// ExtractText writes the extracted entries as timestamp and message lines.
+	ExtractText = "text"
+)
+
+// tarMagic identifies the header of a tar archive, found at tarMagicOffset.
+const tarMagic = "ustar"
+const tarMagicOffset = 257
+
+// maxEntrySize with the maximum length of an entry in an archive.
+const maxEntrySize = 1024 * 1024
+
+// unknownComponent names the directories of the entries without instance, group or service.
+const unknownComponent = "unknown"
+
+// ArchiveOptions with the options of the log archive downloads.
+type ArchiveOptions struct {
+	// Checksum with the expected hex SHA-256 of the archive. The one sent by the server is used if empty.
+	Checksum string
+	// Extract the archive into a directory tree per service.
+	Extract bool
+	// ExtractFormat with the format of the extracted files, ndjson or text.
+	ExtractFormat string
+	// Timeout with the maximum time to wait for the archive to be ready.
+	Timeout time.Duration
+}
+
+// Validate the options.
+func (o *ArchiveOptions) Validate() derrors.Error {
+	if o.Extract && o.ExtractFormat != ExtractNDJSON && o.ExtractFormat != ExtractText {
+		return derrors.NewInvalidArgumentError("invalid extract format, expecting ndjson or text").WithParams(o.ExtractFormat)
+	}
+	if o.Checksum != "" {
+		if _, err := hex.DecodeString(o.Checksum); err != nil || len(o.Checksum) != sha256.Size*2 {
+			return derrors.NewInvalidArgumentError("invalid checksum, expecting a hex SHA-256").WithParams(o.Checksum)
+		}
+	}
+	return nil
+}
+
+// archiveDownload transfers a log archive to a local file, resuming the partial file of a previous attempt.
+type archiveDownload struct {
+	client *http.Client
+	url    string
+	token  string
+	path   string
+	// checksum with the expected hex SHA-256, set by the user or by the server.
+	checksum string
+}
+
+// headerChecksum returns the hex SHA-256 sent by the server in the Digest or the X-Checksum-Sha256 headers.
+func headerChecksum(header http.Header) string {
+	if checksum := header.Get(ChecksumHeader); checksum != "" {
+		return strings.ToLower(checksum)
+	}
+	for _, digest := range strings.Split(header.Get("Digest"), ",") {
+		parts := strings.SplitN(strings.TrimSpace(digest), "=", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "sha-256" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(parts[1])
+		if err == nil {
+			return hex.EncodeToString(raw)
+		}
+	}
+	return ""
+}
+
+// contentRange returns the start and the total size of a Content-Range header such as bytes 100-199/200 or
+// bytes */200. The total is -1 if unknown.
+func contentRange(value string) (int64, int64, bool) {
+	value = strings.TrimPrefix(value, "bytes ")
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+	total := int64(-1)
+	if parts[1] != "*" {
+		parsed, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = parsed
+	}
+	if parts[0] == "*" {
+		return 0, total, true
+	}
+	start, err := strconv.ParseInt(strings.SplitN(parts[0], "-", 2)[0], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// responseValidator returns the strong validator of a response, its ETag or its Last-Modified date, that can be
+// sent as If-Range.
+func responseValidator(header http.Header) string {
+	if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return header.Get("Last-Modified")
+}
+
+// readValidator returns the validator of the partial file, or an empty string if there is none.
+func (d *archiveDownload) readValidator() string {
+	content, err := ioutil.ReadFile(d.path + ValidatorSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// writeValidator records the validator of the archive being written to the partial file.
+func (d *archiveDownload) writeValidator(validator string) derrors.Error {
+	if validator == "" {
+		d.removeValidator()
+		return nil
+	}
+	if err := ioutil.WriteFile(d.path+ValidatorSuffix, []byte(validator), 0644); err != nil {
+		return derrors.AsError(err, "cannot write the archive validator")
+	}
+	return nil
+}
+
+// removeValidator removes the validator of the partial file.
+func (d *archiveDownload) removeValidator() {
+	_ = os.Remove(d.path + ValidatorSuffix)
+}
+
+// restart empties the partial file so the next attempt downloads the whole archive.
+func (d *archiveDownload) restart(file *os.File) derrors.Error {
+	d.removeValidator()
+	if err := file.Truncate(0); err != nil {
+		return derrors.AsError(err, "cannot truncate the archive file")
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return derrors.AsError(err, "cannot truncate the archive file")
+	}
+	return nil
+}
+
+// transfer requests the rest of the archive and appends it to the partial file. A partial file is only resumed if
+// its validator is known, and the validator is sent as If-Range so a changed archive is downloaded again. It
+// returns whether the error can be solved with a new attempt.
+func (d *archiveDownload) transfer() (derrors.Error, bool) {
+	partial := d.path + PartialSuffix
+	file, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return derrors.AsError(err, "cannot create the archive file"), false
+	}
+	defer file.Close()
+	offset, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return derrors.AsError(err, "cannot read the archive file"), false
+	}
+	validator := d.readValidator()
+	if offset > 0 && validator == "" {
+		log.Warn().Int64("offset", offset).Msg("the partial log archive cannot be validated, downloading it again")
+		if rErr := d.restart(file); rErr != nil {
+			return rErr, false
+		}
+		offset = 0
+	}
+
+	req, err := http.NewRequest(http.MethodGet, d.url, nil)
+	if err != nil {
+		return derrors.AsError(err, "invalid archive URL"), false
+	}
+	req.Header.Add("authorization", d.token)
+	if offset > 0 {
+		req.Header.Add("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Add("If-Range", validator)
+		log.Info().Int64("offset", offset).Msg("resuming log archive download")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return derrors.AsError(err, "cannot get the archive"), true
+	}
+	defer resp.Body.Close()
+
+	total := int64(-1)
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// The server sends the whole archive, either because no range was requested or because it changed
+		if rErr := d.restart(file); rErr != nil {
+			return rErr, false
+		}
+		if wErr := d.writeValidator(responseValidator(resp.Header)); wErr != nil {
+			return wErr, false
+		}
+		offset = 0
+		total = resp.ContentLength
+	case http.StatusPartialContent:
+		if current := responseValidator(resp.Header); current != "" && current != validator {
+			if rErr := d.restart(file); rErr != nil {
+				return rErr, false
+			}
+			return derrors.NewInternalError("the log archive changed in the server").WithParams(validator, current), true
+		}
+		start, size, ok := contentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return derrors.NewInternalError("unexpected range in the archive response").WithParams(resp.Header.Get("Content-Range")), false
+		}
+		total = size
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The partial file may be complete already
+		_, size, ok := contentRange(resp.Header.Get("Content-Range"))
+		current := responseValidator(resp.Header)
+		if ok && size == offset && (current == "" || current == validator) {
+			d.setChecksum(resp.Header)
+			return nil, false
+		}
+		if rErr := d.restart(file); rErr != nil {
+			return rErr, false
+		}
+		return derrors.NewOutOfRangeError("the partial archive does not match the one in the server"), true
+	default:
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		err := derrors.NewInternalError(resp.Status).WithParams(string(body))
+		return err, resp.StatusCode >= http.StatusInternalServerError
+	}
+	d.setChecksum(resp.Header)
+
+	progress := &progressWriter{written: offset, total: total}
+	_, err = io.Copy(io.MultiWriter(file, progress), resp.Body)
+	progress.finish()
+	if err != nil {
+		return derrors.AsError(err, "log archive transfer interrupted"), true
+	}
+	if total > 0 && progress.written != total {
+		return derrors.NewInternalError("log archive transfer incomplete").WithParams(progress.written, total), true
+	}
+	return nil, false
+}
+
+// setChecksum records the checksum sent by the server unless the user gave one.
+func (d *archiveDownload) setChecksum(header http.Header) {
+	if d.checksum == "" {
+		d.checksum = headerChecksum(header)
+	}
+}
+
+// run downloads the archive, verifies its checksum and moves it to its final path.
+func (d *archiveDownload) run() derrors.Error {
+	var err derrors.Error
+	for attempt := 0; attempt <= DownloadRetries; attempt++ {
+		if attempt > 0 {
+			log.Warn().Str("trace", err.DebugReport()).Int("attempt", attempt).Msg("log archive download failed, retrying")
+			time.Sleep(DownloadRetrySleep * time.Duration(attempt))
+		}
+		var retry bool
+		err, retry = d.transfer()
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return d.verify()
+}
+
+// verify compares the checksum of the partial file with the expected one. Without a checksum, the file must at
+// least open as an archive. A corrupted file is removed so the next attempt starts from scratch.
+func (d *archiveDownload) verify() derrors.Error {
+	partial := d.path + PartialSuffix
+	checksum, err := fileChecksum(partial)
+	if err != nil {
+		return err
+	}
+	if d.checksum == "" {
+		if aErr := checkArchive(partial); aErr != nil {
+			_ = os.Remove(partial)
+			d.removeValidator()
+			return aErr
+		}
+		log.Warn().Str("sha256", checksum).Msg("the server did not send a checksum, only the format of the log archive is verified")
+	} else if !strings.EqualFold(d.checksum, checksum) {
+		_ = os.Remove(partial)
+		d.removeValidator()
+		return derrors.NewInternalError("log archive checksum mismatch").WithParams(d.checksum, checksum)
+	} else {
+		log.Info().Str("sha256", checksum).Msg("log archive verified")
+	}
+	if err := os.Rename(partial, d.path); err != nil {
+		return derrors.AsError(err, "cannot move the archive file")
+	}
+	d.removeValidator()
+	return nil
+}
+
+// checkArchive checks that a file can be read entirely as a zip archive, or as a gzip file, such as a gzipped tar
+// archive.
+func checkArchive(path string) derrors.Error {
+	if reader, err := zip.OpenReader(path); err == nil {
+		defer reader.Close()
+		for _, file := range reader.File {
+			content, err := file.Open()
+			if err != nil {
+				return derrors.NewInternalError("the log archive is corrupted", err).WithParams(file.Name)
+			}
+			_, err = io.Copy(ioutil.Discard, content)
+			content.Close()
+			if err != nil {
+				return derrors.NewInternalError("the log archive is corrupted", err).WithParams(file.Name)
+			}
+		}
+		return nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return derrors.AsError(err, "cannot open the archive file")
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return derrors.NewInternalError("the log archive is not a zip or gzip file", err)
+	}
+	defer gzipReader.Close()
+	buffered := bufio.NewReader(gzipReader)
+	if header, err := buffered.Peek(tarMagicOffset + len(tarMagic)); err == nil && string(header[tarMagicOffset:]) == tarMagic {
+		tarReader := tar.NewReader(buffered)
+		for {
+			_, err := tarReader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return derrors.NewInternalError("the log archive is corrupted", err)
+			}
+			if _, err := io.Copy(ioutil.Discard, tarReader); err != nil {
+				return derrors.NewInternalError("the log archive is corrupted", err)
+			}
+		}
+	}
+	if _, err := io.Copy(ioutil.Discard, buffered); err != nil {
+		return derrors.NewInternalError("the log archive is corrupted", err)
+	}
+	return nil
+}
+
+// fileChecksum returns the hex SHA-256 of a file.
+func fileChecksum(path string) (string, derrors.Error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", derrors.AsError(err, "cannot open the archive file")
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", derrors.AsError(err, "cannot read the archive file")
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// progressWriter prints the progress of a transfer on the standard error.
+type progressWriter struct {
+	written int64
+	total   int64
+	last    time.Time
+}
+
+func (p *progressWriter) Write(data []byte) (int, error) {
+	p.written += int64(len(data))
+	if time.Since(p.last) >= ProgressInterval {
+		p.print()
+		p.last = time.Now()
+	}
+	return len(data), nil
+}
+
+func (p *progressWriter) print() {
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "\rdownloading log archive: %s of %s (%d%%)", byteSize(p.written), byteSize(p.total), p.written*100/p.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\rdownloading log archive: %s", byteSize(p.written))
+	}
+}
+
+// finish prints the final state of the transfer.
+func (p *progressWriter) finish() {
+	p.print()
+	fmt.Fprintln(os.Stderr)
+}
+
+// byteSize formats a number of bytes with binary units.
+func byteSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// archiveField returns a field of an archived entry, accepting both the proto and the JSON names, such as
+// app_instance_id and appInstanceId.
+func archiveField(entry map[string]interface{}, name string) string {
+	for key, value := range entry {
+		if strings.ToLower(strings.Replace(key, "_", "", -1)) != name {
+			continue
+		}
+		switch v := value.(type) {
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
+
+// safeComponent returns a path component that cannot escape the extraction directory.
+func safeComponent(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == os.PathSeparator {
+			return '_'
+		}
+		return r
+	}, name)
+	if name == "" || name == "." || name == ".." {
+		return unknownComponent
+	}
+	return name
+}
+
+// archiveExtractor writes the entries of an archive in a file per service, under the directories of their
+// application and service group instances.
+type archiveExtractor struct {
+	target  string
+	format  string
+	files   map[string]*bufio.Writer
+	handles []*os.File
+	entries int
+}
+
+// writer returns the writer of a file, creating it on first use.
+func (e *archiveExtractor) writer(components ...string) (*bufio.Writer, derrors.Error) {
+	path := e.target
+	for _, component := range components {
+		path = filepath.Join(path, safeComponent(component))
+	}
+	path = path + "." + map[string]string{ExtractNDJSON: "ndjson", ExtractText: "log"}[e.format]
+	if w, exists := e.files[path]; exists {
+		return w, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, derrors.AsError(err, "cannot create the extraction directory")
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create the extracted file")
+	}
+	e.handles = append(e.handles, file)
+	e.files[path] = bufio.NewWriter(file)
+	return e.files[path], nil
+}
+
+// line writes a line of an archived file. The lines that are not JSON entries are kept in a file named after the
+// archived one.
+func (e *archiveExtractor) line(source string, raw []byte) derrors.Error {
+	entry := make(map[string]interface{}, 0)
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&entry); err != nil || archiveField(entry, "msg") == "" {
+		w, derr := e.writer(strings.TrimSuffix(filepath.Base(source), filepath.Ext(source)))
+		if derr != nil {
+			return derr
+		}
+		_, _ = w.Write(raw)
+		_ = w.WriteByte('\n')
+		e.entries++
+		return nil
+	}
+	component := func(name string) string {
+		if value := archiveField(entry, name); value != "" {
+			return value
+		}
+		return unknownComponent
+	}
+	w, derr := e.writer(component("appinstanceid"), component("servicegroupinstanceid"), component("servicename"))
+	if derr != nil {
+		return derr
+	}
+	if e.format == ExtractText {
+		timestamp, _ := strconv.ParseInt(archiveField(entry, "timestamp"), 10, 64)
+		_, _ = fmt.Fprintf(w, "%s %s\n", time.Unix(0, timestamp).UTC().Format(time.RFC3339Nano), archiveField(entry, "msg"))
+	} else {
+		_, _ = w.Write(raw)
+		_ = w.WriteByte('\n')
+	}
+	e.entries++
+	return nil
+}
+
+// close flushes and closes the extracted files.
+func (e *archiveExtractor) close() derrors.Error {
+	var result derrors.Error
+	for _, w := range e.files {
+		if err := w.Flush(); err != nil && result == nil {
+			result = derrors.AsError(err, "cannot write the extracted file")
+		}
+	}
+	for _, file := range e.handles {
+		_ = file.Close()
+	}
+	return result
+}
+
+// extractArchive unpacks a log archive into a directory, returning the number of extracted entries.
+func extractArchive(archivePath string, target string, format string) (int, derrors.Error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return 0, derrors.AsError(err, "cannot open the log archive")
+	}
+	defer reader.Close()
+
+	extractor := &archiveExtractor{target: target, format: format, files: make(map[string]*bufio.Writer, 0)}
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		content, err := file.Open()
+		if err != nil {
+			extractor.close()
+			return 0, derrors.NewInternalError("cannot read the log archive", err).WithParams(file.Name)
+		}
+		scanner := bufio.NewScanner(content)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxEntrySize)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) == 0 {
+				continue
+			}
+			if derr := extractor.line(file.Name, scanner.Bytes()); derr != nil {
+				content.Close()
+				extractor.close()
+				return 0, derr
+			}
+		}
+		content.Close()
+		if err := scanner.Err(); err != nil {
+			extractor.close()
+			return 0, derrors.NewInternalError("cannot read the log archive", err).WithParams(file.Name)
+		}
+	}
+	return extractor.entries, extractor.close()
+}
@@ -0,0 +1,182 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// testLogArchive returns a zip log archive with the given files.
+func testLogArchive(files map[string]string) []byte {
+	buffer := new(bytes.Buffer)
+	writer := zip.NewWriter(buffer)
+	for name, content := range files {
+		file, err := writer.Create(name)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = file.Write([]byte(content))
+		gomega.Expect(err).To(gomega.Succeed())
+	}
+	gomega.Expect(writer.Close()).To(gomega.Succeed())
+	return buffer.Bytes()
+}
+
+var _ = ginkgo.Describe("Log archives", func() {
+
+	var dir string
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "log-archive")
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	ginkgo.It("should parse the content ranges", func() {
+		cases := []struct {
+			value string
+			start int64
+			total int64
+			ok    bool
+		}{
+			{"bytes 100-199/200", 100, 200, true},
+			{"bytes */200", 0, 200, true},
+			{"bytes 0-9/*", 0, -1, true},
+			{"bytes 0-9", 0, 0, false},
+			{"bytes a-9/10", 0, 0, false},
+			{"bytes 0-9/b", 0, 0, false},
+		}
+		for _, c := range cases {
+			start, total, ok := contentRange(c.value)
+			gomega.Expect(ok).To(gomega.Equal(c.ok), c.value)
+			gomega.Expect(start).To(gomega.Equal(c.start), c.value)
+			gomega.Expect(total).To(gomega.Equal(c.total), c.value)
+		}
+	})
+
+	ginkgo.Context("downloads", func() {
+
+		var content []byte
+		var etag string
+		var ranges []string
+		var server *httptest.Server
+		var path string
+
+		ginkgo.BeforeEach(func() {
+			content = testLogArchive(map[string]string{"logs.json": strings.Repeat(`{"msg":"entry"}`+"\n", 100)})
+			etag = `"v1"`
+			ranges = make([]string, 0)
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ranges = append(ranges, r.Header.Get("Range"))
+				w.Header().Set("ETag", etag)
+				http.ServeContent(w, r, "archive.zip", time.Time{}, bytes.NewReader(content))
+			}))
+			path = filepath.Join(dir, "request.zip")
+		})
+
+		ginkgo.AfterEach(func() {
+			server.Close()
+		})
+
+		download := func(checksum string) *archiveDownload {
+			return &archiveDownload{client: server.Client(), url: server.URL, token: "token", path: path, checksum: checksum}
+		}
+
+		ginkgo.It("should resume a partial download of the same archive", func() {
+			gomega.Expect(ioutil.WriteFile(path+PartialSuffix, content[:100], 0644)).To(gomega.Succeed())
+			gomega.Expect(ioutil.WriteFile(path+ValidatorSuffix, []byte(etag), 0644)).To(gomega.Succeed())
+			gomega.Expect(download("").run()).To(gomega.Succeed())
+			gomega.Expect(ranges).To(gomega.Equal([]string{"bytes=100-"}))
+			gomega.Expect(ioutil.ReadFile(path)).To(gomega.Equal(content))
+			gomega.Expect(path + PartialSuffix).NotTo(gomega.BeAnExistingFile())
+			gomega.Expect(path + ValidatorSuffix).NotTo(gomega.BeAnExistingFile())
+		})
+
+		ginkgo.It("should download again an archive that changed in the server", func() {
+			gomega.Expect(ioutil.WriteFile(path+PartialSuffix, []byte("stale partial content"), 0644)).To(gomega.Succeed())
+			gomega.Expect(ioutil.WriteFile(path+ValidatorSuffix, []byte(`"v0"`), 0644)).To(gomega.Succeed())
+			gomega.Expect(download("").run()).To(gomega.Succeed())
+			gomega.Expect(ioutil.ReadFile(path)).To(gomega.Equal(content))
+		})
+
+		ginkgo.It("should not resume a partial download without a validator", func() {
+			gomega.Expect(ioutil.WriteFile(path+PartialSuffix, content[:100], 0644)).To(gomega.Succeed())
+			gomega.Expect(download("").run()).To(gomega.Succeed())
+			gomega.Expect(ranges).To(gomega.Equal([]string{""}))
+			gomega.Expect(ioutil.ReadFile(path)).To(gomega.Equal(content))
+		})
+
+		ginkgo.It("should verify the checksum of the archive", func() {
+			sum := sha256.Sum256(content)
+			gomega.Expect(download(hex.EncodeToString(sum[:])).run()).To(gomega.Succeed())
+			gomega.Expect(path).To(gomega.BeAnExistingFile())
+
+			gomega.Expect(os.Remove(path)).To(gomega.Succeed())
+			gomega.Expect(download(strings.Repeat("0", sha256.Size*2)).run()).NotTo(gomega.Succeed())
+			gomega.Expect(path).NotTo(gomega.BeAnExistingFile())
+			gomega.Expect(path + PartialSuffix).NotTo(gomega.BeAnExistingFile())
+		})
+
+		ginkgo.It("should reject a file that is not an archive when there is no checksum", func() {
+			content = []byte("<html>not an archive</html>")
+			gomega.Expect(download("").run()).NotTo(gomega.Succeed())
+			gomega.Expect(path).NotTo(gomega.BeAnExistingFile())
+			gomega.Expect(path + PartialSuffix).NotTo(gomega.BeAnExistingFile())
+		})
+	})
+
+	ginkgo.It("should extract the entries of an archive per service", func() {
+		path := filepath.Join(dir, "request.zip")
+		gomega.Expect(ioutil.WriteFile(path, testLogArchive(map[string]string{
+			"logs.json": `{"app_instance_id":"app","service_group_instance_id":"group","service_name":"web","timestamp":"0","msg":"first"}` + "\n" +
+				`{"appInstanceId":"app","serviceGroupInstanceId":"group","serviceName":"web","timestamp":"1000000000","msg":"second"}` + "\n\n" +
+				`{"app_instance_id":"../escape","msg":"third"}` + "\n",
+			"notes.txt": "not an entry\n",
+		}), 0644)).To(gomega.Succeed())
+		target := filepath.Join(dir, "extracted")
+
+		entries, err := extractArchive(path, target, ExtractText)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(entries).To(gomega.Equal(4))
+		gomega.Expect(ioutil.ReadFile(filepath.Join(target, "app", "group", "web.log"))).To(gomega.Equal(
+			[]byte("1970-01-01T00:00:00Z first\n1970-01-01T00:00:01Z second\n")))
+		gomega.Expect(filepath.Join(target, ".._escape", unknownComponent, unknownComponent+".log")).To(gomega.BeAnExistingFile())
+		gomega.Expect(ioutil.ReadFile(filepath.Join(target, "notes.log"))).To(gomega.Equal([]byte("not an entry\n")))
+
+		ndjson := filepath.Join(dir, "ndjson")
+		entries, err = extractArchive(path, ndjson, ExtractNDJSON)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(entries).To(gomega.Equal(4))
+		lines, rErr := ioutil.ReadFile(filepath.Join(ndjson, "app", "group", "web.ndjson"))
+		gomega.Expect(rErr).To(gomega.Succeed())
+		gomega.Expect(strings.Count(string(lines), "\n")).To(gomega.Equal(2))
+	})
+})
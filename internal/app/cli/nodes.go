@@ -51,7 +51,7 @@ func (n *Nodes) getClient() (grpc_public_api_go.NodesClient, *grpc.ClientConn) {
 	return nodesClient, conn
 }
 
-func (n *Nodes) List(organizationID string, clusterID string) {
+func (n *Nodes) List(organizationID string, clusterID string, labelSelector string) {
 
 	if organizationID == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
@@ -70,7 +70,7 @@ func (n *Nodes) List(organizationID string, clusterID string) {
 		OrganizationId: organizationID,
 		ClusterId:      clusterID,
 	}
-	list, err := client.List(ctx, cID)
+	list, err := client.List(WithSelector(ctx, labelSelector), cID)
 	n.PrintResultOrError(list, err, "cannot list nodes")
 }
 
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"time"
+)
+
+type Operations struct {
+	Connection
+	Credentials
+}
+
+func NewOperations(address string, port int, insecure bool, useTLS bool, caCertPath string, output string, labelLength int) *Operations {
+	return &Operations{
+		Connection:  *NewConnection(address, port, insecure, useTLS, caCertPath, output, labelLength),
+		Credentials: *NewEmptyCredentials(DefaultPath),
+	}
+}
+
+func (o *Operations) load() {
+	err := o.LoadCredentials()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load credentials, try login first")
+	}
+}
+
+func (o *Operations) getClient() (grpc_public_api_go.OperationsClient, *grpc.ClientConn) {
+	conn, err := o.GetConnection()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot create the connection with the Nalej platform")
+	}
+	client := grpc_public_api_go.NewOperationsClient(conn)
+	return client, conn
+}
+
+// List the asynchronous operations of an organization, optionally filtered by type and status.
+func (o *Operations) List(organizationID string, operationType string, status string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	o.load()
+	ctx, cancel := o.GetContext()
+	client, conn := o.getClient()
+	defer conn.Close()
+	defer cancel()
+	list, err := client.ListOperations(ctx, &grpc_public_api_go.ListOperationsRequest{
+		OrganizationId: organizationID,
+		Type:           operationType,
+		Status:         status,
+	})
+	o.PrintResultOrError(list, err, "cannot obtain operation list")
+}
+
+// Info retrieves an operation with its current status.
+func (o *Operations) Info(organizationID string, operationID string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	o.load()
+	ctx, cancel := o.GetContext()
+	client, conn := o.getClient()
+	defer conn.Close()
+	defer cancel()
+	operation, err := client.GetOperation(ctx, &grpc_public_api_go.OperationId{
+		OrganizationId: organizationID,
+		OperationId:    operationID,
+	})
+	o.PrintResultOrError(operation, err, "cannot obtain operation information")
+}
+
+// Wait blocks until an operation finishes or the timeout expires, and prints its last status.
+func (o *Operations) Wait(organizationID string, operationID string, timeout time.Duration) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	o.load()
+	// The server answers once the timeout expires, so the call must last longer.
+	ctx, cancel := o.GetContext(timeout + DefaultTimeout)
+	client, conn := o.getClient()
+	defer conn.Close()
+	defer cancel()
+	operation, err := client.WaitOperation(ctx, &grpc_public_api_go.WaitOperationRequest{
+		OrganizationId: organizationID,
+		OperationId:    operationID,
+		TimeoutSeconds: int64(timeout.Seconds()),
+	})
+	o.PrintResultOrError(operation, err, "cannot wait for the operation")
+}
+
+// Cancel stops an operation in progress.
+func (o *Operations) Cancel(organizationID string, operationID string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	o.load()
+	ctx, cancel := o.GetContext()
+	client, conn := o.getClient()
+	defer conn.Close()
+	defer cancel()
+	operation, err := client.CancelOperation(ctx, &grpc_public_api_go.OperationId{
+		OrganizationId: organizationID,
+		OperationId:    operationID,
+	})
+	o.PrintResultOrError(operation, err, "cannot cancel the operation")
+}
@@ -0,0 +1,828 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-network-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// ArchiveVersion with the version of the format of the organization archives.
+const ArchiveVersion = 1
+
+// ArchiveTimeout with the maximum time to export or import an organization.
+const ArchiveTimeout = 5 * time.Minute
+
+// maxArchiveEntrySize with the maximum size of a file inside an archive.
+const maxArchiveEntrySize = 64 * 1024 * 1024
+
+// Conflict policies applied when an imported descriptor or device group has the same name as an existing one.
+const (
+	// ConflictSkip reuses the existing entity.
+	ConflictSkip = "skip"
+	// ConflictRename creates the entity with a new name.
+	ConflictRename = "rename"
+	// ConflictFail aborts the import before applying any change.
+	ConflictFail = "fail"
+)
+
+// renameSuffix appended to the name of the renamed entities.
+const renameSuffix = "-imported"
+
+// Import actions reported for each entity of the archive.
+const (
+	ActionCreate = "CREATE"
+	ActionRename = "RENAME"
+	ActionReuse  = "REUSE"
+	ActionUpdate = "UPDATE"
+	ActionSkip   = "SKIP"
+)
+
+// ArchiveManifest describes the content of an organization archive.
+type ArchiveManifest struct {
+	Version        int            `json:"version"`
+	OrganizationId string         `json:"organization_id"`
+	ExportedAt     string         `json:"exported_at"`
+	Entries        map[string]int `json:"entries"`
+}
+
+// ArchivedDescriptor with an application descriptor in the format accepted by AddAppDescriptor.
+type ArchivedDescriptor struct {
+	AppDescriptorId string                                       `json:"app_descriptor_id"`
+	Descriptor      *grpc_application_go.AddAppDescriptorRequest `json:"descriptor"`
+}
+
+// ArchivedDeviceGroup with the settings of a device group. The API key is not exported.
+type ArchivedDeviceGroup struct {
+	DeviceGroupId             string `json:"device_group_id"`
+	Name                      string `json:"name"`
+	Enabled                   bool   `json:"enabled"`
+	DefaultDeviceConnectivity bool   `json:"default_device_connectivity"`
+}
+
+// ArchivedSetting with an organization setting.
+type ArchivedSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// ArchivedLabels with the labels of a cluster, node or asset. Clusters are matched by name, nodes by cluster
+// name and IP, and assets by the identifier of their agent, as the asset identifiers change between organizations.
+type ArchivedLabels struct {
+	Id      string            `json:"id"`
+	Name    string            `json:"name"`
+	Parent  string            `json:"parent,omitempty"`
+	AgentId string            `json:"agent_id,omitempty"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// ArchivedConnection with an application network connection. Instances are matched by name.
+type ArchivedConnection struct {
+	SourceInstanceName string `json:"source_instance_name"`
+	OutboundName       string `json:"outbound_name"`
+	TargetInstanceName string `json:"target_instance_name"`
+	InboundName        string `json:"inbound_name"`
+}
+
+// ArchivedUser with the role assigned to a user. Passwords are not exported.
+type ArchivedUser struct {
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	RoleName string `json:"role_name"`
+}
+
+// OrganizationArchive with the configuration of an organization.
+type OrganizationArchive struct {
+	Manifest     ArchiveManifest
+	Descriptors  []ArchivedDescriptor
+	DeviceGroups []ArchivedDeviceGroup
+	Settings     []ArchivedSetting
+	Clusters     []ArchivedLabels
+	Nodes        []ArchivedLabels
+	Assets       []ArchivedLabels
+	Connections  []ArchivedConnection
+	Users        []ArchivedUser
+}
+
+// entries returns the files of the archive with their content.
+func (oa *OrganizationArchive) entries() map[string]interface{} {
+	return map[string]interface{}{
+		"descriptors.json":   &oa.Descriptors,
+		"device_groups.json": &oa.DeviceGroups,
+		"settings.json":      &oa.Settings,
+		"clusters.json":      &oa.Clusters,
+		"nodes.json":         &oa.Nodes,
+		"assets.json":        &oa.Assets,
+		"connections.json":   &oa.Connections,
+		"users.json":         &oa.Users,
+	}
+}
+
+// ImportAction with the result of importing an entity of the archive.
+type ImportAction struct {
+	Section  string `json:"section"`
+	Name     string `json:"name"`
+	SourceId string `json:"source_id,omitempty"`
+	TargetId string `json:"target_id,omitempty"`
+	Action   string `json:"action"`
+	Message  string `json:"message,omitempty"`
+	Failed   bool   `json:"failed,omitempty"`
+}
+
+// ImportReport with the actions applied when importing an archive into an organization.
+type ImportReport struct {
+	OrganizationId       string          `json:"organization_id"`
+	SourceOrganizationId string          `json:"source_organization_id"`
+	DryRun               bool            `json:"dry_run"`
+	Actions              []*ImportAction `json:"actions"`
+	Failed               int             `json:"failed"`
+}
+
+// OrganizationBackup exports and imports the configuration of an organization.
+type OrganizationBackup struct {
+	Connection
+	Credentials
+}
+
+func NewOrganizationBackup(address string, port int, insecure bool, useTLS bool, caCertPath string, output string, labelLength int) *OrganizationBackup {
+	return &OrganizationBackup{
+		Connection:  *NewConnection(address, port, insecure, useTLS, caCertPath, output, labelLength),
+		Credentials: *NewEmptyCredentials(DefaultPath),
+	}
+}
+
+func (ob *OrganizationBackup) load() {
+	err := ob.LoadCredentials()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load credentials, try login first")
+	}
+}
+
+func (ob *OrganizationBackup) getConnection() *grpc.ClientConn {
+	conn, err := ob.GetConnection()
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot create the connection with the Nalej platform")
+	}
+	return conn
+}
+
+// toArchivedDescriptor converts a descriptor into an add request without identifiers or image credentials. The
+// security rules keep the names of the device groups, which are resolved again in the target organization.
+func toArchivedDescriptor(descriptor *grpc_application_go.AppDescriptor) ArchivedDescriptor {
+	groups := make([]*grpc_application_go.ServiceGroup, 0, len(descriptor.Groups))
+	for _, source := range descriptor.Groups {
+		group := proto.Clone(source).(*grpc_application_go.ServiceGroup)
+		group.OrganizationId = ""
+		group.AppDescriptorId = ""
+		group.ServiceGroupId = ""
+		for _, service := range group.Services {
+			service.OrganizationId = ""
+			service.AppDescriptorId = ""
+			service.ServiceGroupId = ""
+			service.ServiceId = ""
+			service.Credentials = nil
+		}
+		groups = append(groups, group)
+	}
+	rules := make([]*grpc_application_go.SecurityRule, 0, len(descriptor.Rules))
+	for _, source := range descriptor.Rules {
+		rule := proto.Clone(source).(*grpc_application_go.SecurityRule)
+		rule.OrganizationId = ""
+		rule.AppDescriptorId = ""
+		rule.RuleId = ""
+		rule.DeviceGroupIds = nil
+		rules = append(rules, rule)
+	}
+	return ArchivedDescriptor{
+		AppDescriptorId: descriptor.AppDescriptorId,
+		Descriptor: &grpc_application_go.AddAppDescriptorRequest{
+			Name:                 descriptor.Name,
+			ConfigurationOptions: descriptor.ConfigurationOptions,
+			EnvironmentVariables: descriptor.EnvironmentVariables,
+			Labels:               descriptor.Labels,
+			Rules:                rules,
+			Groups:               groups,
+		},
+	}
+}
+
+// collect retrieves the configuration of an organization.
+func (ob *OrganizationBackup) collect(ctx context.Context, conn *grpc.ClientConn, organizationID string) (*OrganizationArchive, error) {
+	orgID := &grpc_organization_go.OrganizationId{OrganizationId: organizationID}
+	archive := &OrganizationArchive{}
+
+	descriptors, err := grpc_public_api_go.NewApplicationsClient(conn).ListAppDescriptors(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, descriptor := range descriptors.Descriptors {
+		archive.Descriptors = append(archive.Descriptors, toArchivedDescriptor(descriptor))
+	}
+
+	groups, err := grpc_public_api_go.NewDevicesClient(conn).ListDeviceGroups(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups.Groups {
+		archive.DeviceGroups = append(archive.DeviceGroups, ArchivedDeviceGroup{
+			DeviceGroupId:             group.DeviceGroupId,
+			Name:                      group.Name,
+			Enabled:                   group.Enabled,
+			DefaultDeviceConnectivity: group.DefaultDeviceConnectivity,
+		})
+	}
+
+	settings, err := grpc_public_api_go.NewOrganizationSettingsClient(conn).List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range settings.Settings {
+		archive.Settings = append(archive.Settings, ArchivedSetting{Key: setting.Key, Value: setting.Value})
+	}
+
+	clusters, err := grpc_public_api_go.NewClustersClient(conn).List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	nodesClient := grpc_public_api_go.NewNodesClient(conn)
+	for _, cluster := range clusters.Clusters {
+		archive.Clusters = append(archive.Clusters, ArchivedLabels{Id: cluster.ClusterId, Name: cluster.Name, Labels: cluster.Labels})
+		nodes, err := nodesClient.List(ctx, &grpc_infrastructure_go.ClusterId{OrganizationId: organizationID, ClusterId: cluster.ClusterId})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Nodes {
+			archive.Nodes = append(archive.Nodes, ArchivedLabels{Id: node.NodeId, Name: node.Ip, Parent: cluster.Name, Labels: node.Labels})
+		}
+	}
+
+	inventory, err := grpc_public_api_go.NewInventoryClient(conn).List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range inventory.Assets {
+		archive.Assets = append(archive.Assets, ArchivedLabels{Id: asset.AssetId, Name: asset.EicNetIp, AgentId: asset.AgentId, Labels: asset.Labels})
+	}
+
+	connections, err := grpc_public_api_go.NewApplicationNetworkClient(conn).ListConnections(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, connection := range connections.List {
+		archive.Connections = append(archive.Connections, ArchivedConnection{
+			SourceInstanceName: connection.SourceInstanceName,
+			OutboundName:       connection.OutboundName,
+			TargetInstanceName: connection.TargetInstanceName,
+			InboundName:        connection.InboundName,
+		})
+	}
+
+	users, err := grpc_public_api_go.NewUsersClient(conn).List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users.Users {
+		archive.Users = append(archive.Users, ArchivedUser{Email: user.Email, Name: user.Name, RoleName: user.RoleName})
+	}
+
+	archive.Manifest = ArchiveManifest{
+		Version:        ArchiveVersion,
+		OrganizationId: organizationID,
+		ExportedAt:     time.Now().UTC().Format(time.RFC3339),
+		Entries: map[string]int{
+			"descriptors":   len(archive.Descriptors),
+			"device_groups": len(archive.DeviceGroups),
+			"settings":      len(archive.Settings),
+			"clusters":      len(archive.Clusters),
+			"nodes":         len(archive.Nodes),
+			"assets":        len(archive.Assets),
+			"connections":   len(archive.Connections),
+			"users":         len(archive.Users),
+		},
+	}
+	return archive, nil
+}
+
+// writeArchive stores an archive as a gzipped tar file with a JSON document per section.
+func writeArchive(archive *OrganizationArchive, outputPath string) derrors.Error {
+	buffer := new(bytes.Buffer)
+	gzipWriter := gzip.NewWriter(buffer)
+	tarWriter := tar.NewWriter(gzipWriter)
+	files := archive.entries()
+	files["manifest.json"] = &archive.Manifest
+	names := []string{"manifest.json", "descriptors.json", "device_groups.json", "settings.json", "clusters.json",
+		"nodes.json", "assets.json", "connections.json", "users.json"}
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return derrors.AsError(err, "cannot marshal archive entry")
+		}
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(content)), ModTime: time.Now()}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return derrors.AsError(err, "cannot write archive entry")
+		}
+		if _, err := tarWriter.Write(content); err != nil {
+			return derrors.AsError(err, "cannot write archive entry")
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return derrors.AsError(err, "cannot close archive")
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return derrors.AsError(err, "cannot close archive")
+	}
+	if err := ioutil.WriteFile(outputPath, buffer.Bytes(), 0600); err != nil {
+		return derrors.AsError(err, "cannot write archive")
+	}
+	return nil
+}
+
+// readArchive loads an archive created by writeArchive.
+func readArchive(inputPath string) (*OrganizationArchive, derrors.Error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot open archive")
+	}
+	defer file.Close()
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read archive")
+	}
+	archive := &OrganizationArchive{}
+	files := archive.entries()
+	files["manifest.json"] = &archive.Manifest
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, derrors.AsError(err, "cannot read archive")
+		}
+		target, known := files[header.Name]
+		if !known {
+			log.Warn().Str("entry", header.Name).Msg("ignoring unknown archive entry")
+			continue
+		}
+		content, err := ioutil.ReadAll(io.LimitReader(tarReader, maxArchiveEntrySize))
+		if err != nil {
+			return nil, derrors.NewInternalError("cannot read archive entry", err).WithParams(header.Name)
+		}
+		if err := json.Unmarshal(content, target); err != nil {
+			return nil, derrors.NewInvalidArgumentError("cannot unmarshal archive entry", err).WithParams(header.Name)
+		}
+	}
+	if archive.Manifest.Version != ArchiveVersion {
+		return nil, derrors.NewInvalidArgumentError("unsupported archive version").WithParams(archive.Manifest.Version)
+	}
+	return archive, nil
+}
+
+// Export writes the configuration of an organization into an archive. Secrets such as passwords, device group API
+// keys and image credentials are not exported.
+func (ob *OrganizationBackup) Export(organizationID string, outputPath string) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if outputPath == "" {
+		log.Fatal().Msg("output path cannot be empty")
+	}
+	ob.load()
+	conn := ob.getConnection()
+	defer conn.Close()
+	ctx, cancel := ob.GetContext(ArchiveTimeout)
+	defer cancel()
+
+	archive, err := ob.collect(ctx, conn, organizationID)
+	if err != nil {
+		ob.PrintResultOrError(nil, err, "cannot export organization")
+	}
+	if wErr := writeArchive(archive, GetPath(outputPath)); wErr != nil {
+		log.Fatal().Str("trace", wErr.DebugReport()).Msg("cannot write organization archive")
+	}
+	ob.PrintResultOrError(&archive.Manifest, nil, "cannot export organization")
+}
+
+// importState with the entities of the target organization. The assets are indexed by the identifier of their agent.
+type importState struct {
+	descriptors  map[string]string
+	deviceGroups map[string]string
+	settings     map[string]string
+	clusters     map[string]*grpc_public_api_go.Cluster
+	nodes        map[string]*grpc_public_api_go.Node
+	assets       map[string]*grpc_public_api_go.Asset
+	instances    map[string]string
+	connections  map[ArchivedConnection]bool
+	users        map[string]*grpc_public_api_go.User
+	roles        map[string]string
+}
+
+// loadState retrieves the entities of the target organization used to match the archived ones.
+func (ob *OrganizationBackup) loadState(ctx context.Context, conn *grpc.ClientConn, organizationID string) (*importState, error) {
+	orgID := &grpc_organization_go.OrganizationId{OrganizationId: organizationID}
+	state := &importState{
+		descriptors:  make(map[string]string, 0),
+		deviceGroups: make(map[string]string, 0),
+		settings:     make(map[string]string, 0),
+		clusters:     make(map[string]*grpc_public_api_go.Cluster, 0),
+		nodes:        make(map[string]*grpc_public_api_go.Node, 0),
+		assets:       make(map[string]*grpc_public_api_go.Asset, 0),
+		instances:    make(map[string]string, 0),
+		connections:  make(map[ArchivedConnection]bool, 0),
+		users:        make(map[string]*grpc_public_api_go.User, 0),
+		roles:        make(map[string]string, 0),
+	}
+	appClient := grpc_public_api_go.NewApplicationsClient(conn)
+	descriptors, err := appClient.ListAppDescriptors(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, descriptor := range descriptors.Descriptors {
+		state.descriptors[descriptor.Name] = descriptor.AppDescriptorId
+	}
+	instances, err := appClient.ListAppInstances(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, instance := range instances.Instances {
+		state.instances[instance.Name] = instance.AppInstanceId
+	}
+	groups, err := grpc_public_api_go.NewDevicesClient(conn).ListDeviceGroups(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups.Groups {
+		state.deviceGroups[group.Name] = group.DeviceGroupId
+	}
+	settings, err := grpc_public_api_go.NewOrganizationSettingsClient(conn).List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	for _, setting := range settings.Settings {
+		state.settings[setting.Key] = setting.Value
+	}
+	clusters, err := grpc_public_api_go.NewClustersClient(conn).List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	nodesClient := grpc_public_api_go.NewNodesClient(conn)
+	for _, cluster := range clusters.Clusters {
+		state.clusters[cluster.Name] = cluster
+		nodes, err := nodesClient.List(ctx, &grpc_infrastructure_go.ClusterId{OrganizationId: organizationID, ClusterId: cluster.ClusterId})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Nodes {
+			state.nodes[cluster.Name+"/"+node.Ip] = node
+		}
+	}
+	inventory, err := grpc_public_api_go.NewInventoryClient(conn).List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range inventory.Assets {
+		if asset.AgentId != "" {
+			state.assets[asset.AgentId] = asset
+		}
+	}
+	connections, err := grpc_public_api_go.NewApplicationNetworkClient(conn).ListConnections(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, connection := range connections.List {
+		state.connections[ArchivedConnection{
+			SourceInstanceName: connection.SourceInstanceName,
+			OutboundName:       connection.OutboundName,
+			TargetInstanceName: connection.TargetInstanceName,
+			InboundName:        connection.InboundName,
+		}] = true
+	}
+	users, err := grpc_public_api_go.NewUsersClient(conn).List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users.Users {
+		state.users[user.Email] = user
+	}
+	roles, err := grpc_public_api_go.NewRolesClient(conn).List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range roles.Roles {
+		state.roles[role.Name] = role.RoleId
+	}
+	return state, nil
+}
+
+// missingLabels returns the archived labels that are not set with the same value.
+func missingLabels(current map[string]string, archived map[string]string) map[string]string {
+	result := make(map[string]string, 0)
+	for key, value := range archived {
+		if existing, exists := current[key]; !exists || existing != value {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// resolveName applies the conflict policy to a name already used in the target organization.
+func resolveName(name string, used map[string]string, onConflict string) (string, string) {
+	if _, exists := used[name]; !exists {
+		return name, ActionCreate
+	}
+	if onConflict != ConflictRename {
+		return name, ActionReuse
+	}
+	renamed := name + renameSuffix
+	for index := 2; ; index++ {
+		if _, exists := used[renamed]; !exists {
+			return renamed, ActionRename
+		}
+		renamed = fmt.Sprintf("%s%s-%d", name, renameSuffix, index)
+	}
+}
+
+// renameDeviceGroups replaces the names of the renamed device groups in the security rules of a descriptor.
+func renameDeviceGroups(descriptor *grpc_application_go.AddAppDescriptorRequest, renamed map[string]string) {
+	for _, rule := range descriptor.Rules {
+		for index, name := range rule.DeviceGroupNames {
+			if newName, exists := renamed[name]; exists {
+				rule.DeviceGroupNames[index] = newName
+			}
+		}
+	}
+}
+
+// plan computes the actions required to import an archive. The device groups are created before the descriptors,
+// whose security rules refer to them by name, and the rules of the archived descriptors are updated with the names
+// of the renamed groups. The conflicts are returned apart so the import can be aborted before applying any change.
+func plan(archive *OrganizationArchive, state *importState, onConflict string) ([]*ImportAction, []string) {
+	actions := make([]*ImportAction, 0)
+	conflicts := make([]string, 0)
+	renamedGroups := make(map[string]string, 0)
+	for _, group := range archive.DeviceGroups {
+		name, action := resolveName(group.Name, state.deviceGroups, onConflict)
+		if action != ActionCreate {
+			conflicts = append(conflicts, "device group "+group.Name)
+		}
+		if action == ActionRename {
+			renamedGroups[group.Name] = name
+		}
+		actions = append(actions, &ImportAction{Section: "device_groups", Name: name, SourceId: group.DeviceGroupId,
+			TargetId: state.deviceGroups[name], Action: action})
+	}
+	for _, descriptor := range archive.Descriptors {
+		renameDeviceGroups(descriptor.Descriptor, renamedGroups)
+		name, action := resolveName(descriptor.Descriptor.Name, state.descriptors, onConflict)
+		if action != ActionCreate {
+			conflicts = append(conflicts, "descriptor "+descriptor.Descriptor.Name)
+		}
+		actions = append(actions, &ImportAction{Section: "descriptors", Name: name, SourceId: descriptor.AppDescriptorId,
+			TargetId: state.descriptors[name], Action: action})
+	}
+	for _, setting := range archive.Settings {
+		action := ActionUpdate
+		if current, exists := state.settings[setting.Key]; exists && current == setting.Value {
+			action = ActionSkip
+		}
+		actions = append(actions, &ImportAction{Section: "settings", Name: setting.Key, Action: action})
+	}
+	for _, cluster := range archive.Clusters {
+		toAdd := &ImportAction{Section: "clusters", Name: cluster.Name, SourceId: cluster.Id, Action: ActionSkip}
+		if target, exists := state.clusters[cluster.Name]; !exists {
+			toAdd.Message = "cluster not found"
+		} else {
+			toAdd.TargetId = target.ClusterId
+			if len(missingLabels(target.Labels, cluster.Labels)) > 0 {
+				toAdd.Action = ActionUpdate
+			}
+		}
+		actions = append(actions, toAdd)
+	}
+	for _, node := range archive.Nodes {
+		toAdd := &ImportAction{Section: "nodes", Name: node.Parent + "/" + node.Name, SourceId: node.Id, Action: ActionSkip}
+		if target, exists := state.nodes[node.Parent+"/"+node.Name]; !exists {
+			toAdd.Message = "node not found"
+		} else {
+			toAdd.TargetId = target.NodeId
+			if len(missingLabels(target.Labels, node.Labels)) > 0 {
+				toAdd.Action = ActionUpdate
+			}
+		}
+		actions = append(actions, toAdd)
+	}
+	for _, asset := range archive.Assets {
+		toAdd := &ImportAction{Section: "assets", Name: asset.Name, SourceId: asset.Id, Action: ActionSkip}
+		if target, exists := state.assets[asset.AgentId]; asset.AgentId == "" {
+			toAdd.Message = "the archive does not contain the agent of the asset"
+		} else if !exists {
+			toAdd.Message = "asset not found"
+		} else {
+			toAdd.TargetId = target.AssetId
+			if len(missingLabels(target.Labels, asset.Labels)) > 0 {
+				toAdd.Action = ActionUpdate
+			}
+		}
+		actions = append(actions, toAdd)
+	}
+	for _, connection := range archive.Connections {
+		toAdd := &ImportAction{Section: "connections", Action: ActionCreate,
+			Name: fmt.Sprintf("%s:%s -> %s:%s", connection.SourceInstanceName, connection.OutboundName,
+				connection.TargetInstanceName, connection.InboundName)}
+		_, sourceExists := state.instances[connection.SourceInstanceName]
+		_, targetExists := state.instances[connection.TargetInstanceName]
+		if state.connections[connection] {
+			toAdd.Action = ActionSkip
+			toAdd.Message = "connection already exists"
+		} else if !sourceExists || !targetExists {
+			toAdd.Action = ActionSkip
+			toAdd.Message = "instance not found"
+		}
+		actions = append(actions, toAdd)
+	}
+	for _, user := range archive.Users {
+		toAdd := &ImportAction{Section: "users", Name: user.Email, Action: ActionSkip}
+		target, exists := state.users[user.Email]
+		roleID, roleExists := state.roles[user.RoleName]
+		if !exists {
+			toAdd.Message = "user not found, users must be created with their own password"
+		} else if !roleExists {
+			toAdd.Message = "role not found"
+		} else if target.RoleName != user.RoleName {
+			toAdd.Action = ActionUpdate
+			toAdd.TargetId = roleID
+		}
+		actions = append(actions, toAdd)
+	}
+	return actions, conflicts
+}
+
+// apply executes an action of the plan on the entity found in a position of its archive section. The identifier of
+// the created entities is stored in the action.
+func (ob *OrganizationBackup) apply(ctx context.Context, conn *grpc.ClientConn, organizationID string,
+	archive *OrganizationArchive, state *importState, position int, action *ImportAction) error {
+	if action.Action == ActionSkip || action.Action == ActionReuse {
+		return nil
+	}
+	switch action.Section {
+	case "descriptors":
+		request := proto.Clone(archive.Descriptors[position].Descriptor).(*grpc_application_go.AddAppDescriptorRequest)
+		request.OrganizationId = organizationID
+		request.Name = action.Name
+		added, err := grpc_public_api_go.NewApplicationsClient(conn).AddAppDescriptor(ctx, request)
+		if err != nil {
+			return err
+		}
+		action.TargetId = added.AppDescriptorId
+	case "device_groups":
+		group := archive.DeviceGroups[position]
+		added, err := grpc_public_api_go.NewDevicesClient(conn).AddDeviceGroup(ctx, &grpc_device_manager_go.AddDeviceGroupRequest{
+			OrganizationId:            organizationID,
+			Name:                      action.Name,
+			Enabled:                   group.Enabled,
+			DefaultDeviceConnectivity: group.DefaultDeviceConnectivity,
+		})
+		if err != nil {
+			return err
+		}
+		action.TargetId = added.DeviceGroupId
+	case "settings":
+		_, err := grpc_public_api_go.NewOrganizationSettingsClient(conn).Update(ctx, &grpc_public_api_go.UpdateSettingRequest{
+			OrganizationId: organizationID,
+			Key:            archive.Settings[position].Key,
+			Value:          archive.Settings[position].Value,
+		})
+		return err
+	case "clusters":
+		_, err := grpc_public_api_go.NewClustersClient(conn).Update(ctx, &grpc_public_api_go.UpdateClusterRequest{
+			OrganizationId: organizationID,
+			ClusterId:      action.TargetId,
+			AddLabels:      true,
+			Labels:         missingLabels(state.clusters[action.Name].Labels, archive.Clusters[position].Labels),
+		})
+		return err
+	case "nodes":
+		_, err := grpc_public_api_go.NewNodesClient(conn).UpdateNode(ctx, &grpc_public_api_go.UpdateNodeRequest{
+			OrganizationId: organizationID,
+			NodeId:         action.TargetId,
+			AddLabels:      true,
+			Labels:         missingLabels(state.nodes[action.Name].Labels, archive.Nodes[position].Labels),
+		})
+		return err
+	case "assets":
+		_, err := grpc_public_api_go.NewInventoryClient(conn).UpdateAsset(ctx, &grpc_inventory_go.UpdateAssetRequest{
+			OrganizationId: organizationID,
+			AssetId:        action.TargetId,
+			AddLabels:      true,
+			Labels:         missingLabels(state.assets[archive.Assets[position].AgentId].Labels, archive.Assets[position].Labels),
+		})
+		return err
+	case "connections":
+		connection := archive.Connections[position]
+		_, err := grpc_public_api_go.NewApplicationNetworkClient(conn).AddConnection(ctx, &grpc_application_network_go.AddConnectionRequest{
+			OrganizationId:   organizationID,
+			SourceInstanceId: state.instances[connection.SourceInstanceName],
+			OutboundName:     connection.OutboundName,
+			TargetInstanceId: state.instances[connection.TargetInstanceName],
+			InboundName:      connection.InboundName,
+		})
+		return err
+	case "users":
+		_, err := grpc_public_api_go.NewRolesClient(conn).AssignRole(ctx, &grpc_user_manager_go.AssignRoleRequest{
+			OrganizationId: organizationID,
+			Email:          action.Name,
+			RoleId:         action.TargetId,
+		})
+		return err
+	}
+	return nil
+}
+
+// Import recreates the configuration stored in an archive in an organization. Descriptors and device groups get new
+// identifiers, reported in the result, and the conflicts with existing names are solved with the onConflict policy.
+// Labels are merged into the clusters, nodes and assets found in the organization. Users are not created; the
+// archived roles are assigned to the existing ones.
+func (ob *OrganizationBackup) Import(organizationID string, inputPath string, onConflict string, dryRun bool) {
+	if organizationID == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if inputPath == "" {
+		log.Fatal().Msg("input path cannot be empty")
+	}
+	if onConflict != ConflictSkip && onConflict != ConflictRename && onConflict != ConflictFail {
+		log.Fatal().Str("onConflict", onConflict).Msg("conflict policy must be skip, rename or fail")
+	}
+	archive, aErr := readArchive(GetPath(inputPath))
+	if aErr != nil {
+		log.Fatal().Str("trace", aErr.DebugReport()).Msg("cannot read organization archive")
+	}
+
+	ob.load()
+	conn := ob.getConnection()
+	defer conn.Close()
+	ctx, cancel := ob.GetContext(ArchiveTimeout)
+	defer cancel()
+
+	state, err := ob.loadState(ctx, conn, organizationID)
+	if err != nil {
+		ob.PrintResultOrError(nil, err, "cannot retrieve the target organization")
+	}
+	actions, conflicts := plan(archive, state, onConflict)
+	if onConflict == ConflictFail && len(conflicts) > 0 {
+		log.Fatal().Strs("conflicts", conflicts).Msg("the archive conflicts with existing entities, no changes applied")
+	}
+	report := &ImportReport{
+		OrganizationId:       organizationID,
+		SourceOrganizationId: archive.Manifest.OrganizationId,
+		DryRun:               dryRun,
+		Actions:              actions,
+	}
+	if !dryRun {
+		index := make(map[string]int, 0)
+		for _, action := range actions {
+			position := index[action.Section]
+			index[action.Section]++
+			if err := ob.apply(ctx, conn, organizationID, archive, state, position, action); err != nil {
+				action.Failed = true
+				action.Message = err.Error()
+				report.Failed++
+			}
+		}
+	}
+	ob.PrintResultOrError(report, nil, "cannot import organization")
+}
@@ -0,0 +1,192 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cli
+
+import (
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func testArchive() *OrganizationArchive {
+	return &OrganizationArchive{
+		Manifest: ArchiveManifest{Version: ArchiveVersion, OrganizationId: "source", Entries: map[string]int{"assets": 3}},
+		Descriptors: []ArchivedDescriptor{{AppDescriptorId: "d1",
+			Descriptor: &grpc_application_go.AddAppDescriptorRequest{Name: "web", Labels: map[string]string{"app": "web"}}}},
+		DeviceGroups: []ArchivedDeviceGroup{{DeviceGroupId: "g1", Name: "sensors", Enabled: true}},
+		Settings:     []ArchivedSetting{{Key: "LOG_DOWNLOAD_RETENTION", Value: "7d"}, {Key: "OTHER", Value: "1"}},
+		Assets: []ArchivedLabels{
+			{Id: "source-asset", Name: "10.0.0.2", AgentId: "agent-1", Labels: map[string]string{"zone": "north", "env": "prod"}},
+			{Id: "unknown-agent", Name: "10.0.0.3", AgentId: "agent-2", Labels: map[string]string{"zone": "north"}},
+			{Id: "no-agent", Name: "10.0.0.4", Labels: map[string]string{"zone": "north"}},
+		},
+		Users: []ArchivedUser{{Email: "user@nalej.com", RoleName: "Operator"}},
+	}
+}
+
+func testImportState() *importState {
+	return &importState{
+		descriptors:  map[string]string{"web": "target-descriptor"},
+		deviceGroups: map[string]string{},
+		settings:     map[string]string{"LOG_DOWNLOAD_RETENTION": "7d", "OTHER": "2"},
+		clusters:     map[string]*grpc_public_api_go.Cluster{},
+		nodes:        map[string]*grpc_public_api_go.Node{},
+		assets: map[string]*grpc_public_api_go.Asset{
+			"agent-1": {AssetId: "target-asset", AgentId: "agent-1", Labels: map[string]string{"zone": "north"}},
+		},
+		instances:   map[string]string{},
+		connections: map[ArchivedConnection]bool{},
+		users:       map[string]*grpc_public_api_go.User{"user@nalej.com": {Email: "user@nalej.com", RoleName: "Developer"}},
+		roles:       map[string]string{"Operator": "role-operator"},
+	}
+}
+
+// actionsOf returns the actions of a section of the plan.
+func actionsOf(actions []*ImportAction, section string) []*ImportAction {
+	result := make([]*ImportAction, 0)
+	for _, action := range actions {
+		if action.Section == section {
+			result = append(result, action)
+		}
+	}
+	return result
+}
+
+var _ = ginkgo.Describe("Organization archive", func() {
+
+	ginkgo.It("should return the labels that are missing or different", func() {
+		missing := missingLabels(map[string]string{"a": "1", "b": "2"}, map[string]string{"a": "1", "b": "3", "c": "4"})
+		gomega.Expect(missing).To(gomega.Equal(map[string]string{"b": "3", "c": "4"}))
+		gomega.Expect(missingLabels(nil, nil)).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should apply the conflict policy to the names in use", func() {
+		used := map[string]string{"web": "1", "web-imported": "2"}
+		name, action := resolveName("api", used, ConflictRename)
+		gomega.Expect(name).To(gomega.Equal("api"))
+		gomega.Expect(action).To(gomega.Equal(ActionCreate))
+		name, action = resolveName("web", used, ConflictSkip)
+		gomega.Expect(name).To(gomega.Equal("web"))
+		gomega.Expect(action).To(gomega.Equal(ActionReuse))
+		name, action = resolveName("web", used, ConflictRename)
+		gomega.Expect(name).To(gomega.Equal("web-imported-2"))
+		gomega.Expect(action).To(gomega.Equal(ActionRename))
+	})
+
+	ginkgo.It("should archive the descriptors without identifiers", func() {
+		archived := toArchivedDescriptor(&grpc_application_go.AppDescriptor{
+			OrganizationId:  "source",
+			AppDescriptorId: "d1",
+			Name:            "web",
+			Rules: []*grpc_application_go.SecurityRule{{OrganizationId: "source", AppDescriptorId: "d1", RuleId: "r1",
+				DeviceGroupIds: []string{"g1"}, DeviceGroupNames: []string{"sensors"}}},
+			Groups: []*grpc_application_go.ServiceGroup{{OrganizationId: "source", ServiceGroupId: "sg1", Name: "group",
+				Services: []*grpc_application_go.Service{{ServiceId: "s1", Name: "nginx",
+					Credentials: &grpc_application_go.ImageCredentials{Username: "user", Password: "secret"}}}}},
+		})
+		gomega.Expect(archived.AppDescriptorId).To(gomega.Equal("d1"))
+		rule := archived.Descriptor.Rules[0]
+		gomega.Expect(rule.RuleId).To(gomega.BeEmpty())
+		gomega.Expect(rule.DeviceGroupIds).To(gomega.BeEmpty())
+		gomega.Expect(rule.DeviceGroupNames).To(gomega.Equal([]string{"sensors"}))
+		service := archived.Descriptor.Groups[0].Services[0]
+		gomega.Expect(service.ServiceId).To(gomega.BeEmpty())
+		gomega.Expect(service.Credentials).To(gomega.BeNil())
+		gomega.Expect(archived.Descriptor.Groups[0].ServiceGroupId).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should plan the import of an archive", func() {
+		actions, conflicts := plan(testArchive(), testImportState(), ConflictRename)
+		gomega.Expect(conflicts).To(gomega.Equal([]string{"descriptor web"}))
+
+		descriptors := actionsOf(actions, "descriptors")
+		gomega.Expect(descriptors[0].Action).To(gomega.Equal(ActionRename))
+		gomega.Expect(descriptors[0].Name).To(gomega.Equal("web-imported"))
+		gomega.Expect(actionsOf(actions, "device_groups")[0].Action).To(gomega.Equal(ActionCreate))
+
+		settings := actionsOf(actions, "settings")
+		gomega.Expect(settings[0].Action).To(gomega.Equal(ActionSkip))
+		gomega.Expect(settings[1].Action).To(gomega.Equal(ActionUpdate))
+
+		// The assets are matched by their agent, and the report maps the archived asset to the target one.
+		assets := actionsOf(actions, "assets")
+		gomega.Expect(assets[0].Action).To(gomega.Equal(ActionUpdate))
+		gomega.Expect(assets[0].SourceId).To(gomega.Equal("source-asset"))
+		gomega.Expect(assets[0].TargetId).To(gomega.Equal("target-asset"))
+		gomega.Expect(assets[1].Action).To(gomega.Equal(ActionSkip))
+		gomega.Expect(assets[1].Message).To(gomega.Equal("asset not found"))
+		gomega.Expect(assets[2].Action).To(gomega.Equal(ActionSkip))
+		gomega.Expect(assets[2].Message).NotTo(gomega.BeEmpty())
+
+		users := actionsOf(actions, "users")
+		gomega.Expect(users[0].Action).To(gomega.Equal(ActionUpdate))
+		gomega.Expect(users[0].TargetId).To(gomega.Equal("role-operator"))
+
+		_, conflicts = plan(testArchive(), testImportState(), ConflictSkip)
+		gomega.Expect(conflicts).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("should create the device groups before the descriptors that refer to their new names", func() {
+		archive := testArchive()
+		archive.Descriptors[0].Descriptor.Rules = []*grpc_application_go.SecurityRule{
+			{RuleId: "r1", DeviceGroupNames: []string{"sensors", "cameras"}}}
+		state := testImportState()
+		state.deviceGroups = map[string]string{"sensors": "target-group"}
+
+		actions, conflicts := plan(archive, state, ConflictRename)
+		gomega.Expect(conflicts).To(gomega.Equal([]string{"device group sensors", "descriptor web"}))
+		gomega.Expect(actions[0].Section).To(gomega.Equal("device_groups"))
+		gomega.Expect(actions[0].Name).To(gomega.Equal("sensors-imported"))
+		gomega.Expect(actions[1].Section).To(gomega.Equal("descriptors"))
+		gomega.Expect(archive.Descriptors[0].Descriptor.Rules[0].DeviceGroupNames).To(
+			gomega.Equal([]string{"sensors-imported", "cameras"}))
+
+		archive = testArchive()
+		archive.Descriptors[0].Descriptor.Rules = []*grpc_application_go.SecurityRule{
+			{RuleId: "r1", DeviceGroupNames: []string{"sensors"}}}
+		plan(archive, state, ConflictSkip)
+		gomega.Expect(archive.Descriptors[0].Descriptor.Rules[0].DeviceGroupNames).To(gomega.Equal([]string{"sensors"}))
+	})
+
+	ginkgo.It("should read the archives it writes", func() {
+		dir, err := ioutil.TempDir("", "archive")
+		gomega.Expect(err).To(gomega.Succeed())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "organization.tar.gz")
+
+		archive := testArchive()
+		gomega.Expect(writeArchive(archive, path)).To(gomega.Succeed())
+		loaded, dErr := readArchive(path)
+		gomega.Expect(dErr).To(gomega.Succeed())
+		gomega.Expect(loaded.Manifest).To(gomega.Equal(archive.Manifest))
+		gomega.Expect(loaded.Descriptors[0].Descriptor.Name).To(gomega.Equal("web"))
+		gomega.Expect(loaded.Descriptors[0].Descriptor.Labels).To(gomega.Equal(map[string]string{"app": "web"}))
+		gomega.Expect(loaded.DeviceGroups).To(gomega.Equal(archive.DeviceGroups))
+		gomega.Expect(loaded.Settings).To(gomega.Equal(archive.Settings))
+		gomega.Expect(loaded.Assets).To(gomega.Equal(archive.Assets))
+		gomega.Expect(loaded.Users).To(gomega.Equal(archive.Users))
+
+		archive.Manifest.Version = ArchiveVersion + 1
+		gomega.Expect(writeArchive(archive, path)).To(gomega.Succeed())
+		_, dErr = readArchive(path)
+		gomega.Expect(dErr).NotTo(gomega.Succeed())
+	})
+})
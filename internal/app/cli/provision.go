@@ -49,7 +49,7 @@ func NewProvision(address string, port int, insecure bool, useTLS bool, caCertPa
 func (p *Provision) ProvisionAndInstall(organizationId string, clusterName string, azureCredentialsPath string,
 	azureDnsZoneName string, azureResourceGroup string, clusterType grpc_infrastructure_go.ClusterType, isManagementCluster bool,
 	isProduction bool, kubernetesVersion string, nodeType string, numNodes int64, targetPlatform grpc_public_api_go.Platform,
-	zone string) {
+	zone string, idempotencyKey string) {
 	err := p.LoadCredentials()
 	if err != nil {
 		log.Fatal().Str("trace", err.DebugReport()).Msg("cannot load credentials, try login first")
@@ -92,7 +92,7 @@ func (p *Provision) ProvisionAndInstall(organizationId string, clusterName strin
 	ctx, cancel := p.GetContext()
 	defer cancel()
 
-	resp, errReq := provClient.ProvisionAndInstall(ctx, &request)
+	resp, errReq := provClient.ProvisionAndInstall(WithIdempotencyKey(ctx, idempotencyKey), &request)
 
 	p.PrintResultOrError(resp, errReq, "cannot provision cluster")
 }
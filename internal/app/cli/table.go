@@ -30,6 +30,7 @@ import (
 	"github.com/nalej/grpc-provisioner-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/public-api/internal/pkg/logquery"
 	"github.com/rs/zerolog/log"
 	"os"
 	"sort"
@@ -98,6 +99,10 @@ func AsTable(result interface{}, labelLength int) *ResultTable {
 		return FromAppDescriptor(result, labelLength)
 	case *grpc_public_api_go.AppParameterList:
 		return FromAppParameterList(result)
+	case *grpc_public_api_go.DescriptorHistory:
+		return FromDescriptorHistory(result, labelLength)
+	case *grpc_public_api_go.DescriptorDiff:
+		return FromDescriptorDiff(result)
 	case *grpc_device_manager_go.DeviceGroup:
 		return FromDeviceGroup(result)
 	case *grpc_device_manager_go.DeviceGroupList:
@@ -108,10 +113,26 @@ func AsTable(result interface{}, labelLength int) *ResultTable {
 		return FromDeviceList(result, labelLength)
 	case *grpc_application_manager_go.LogResponse:
 		return FromLogResponse(result)
+	case *grpc_public_api_go.LogQueryResponse:
+		return FromLogQueryResponse(result)
 	case *grpc_public_api_go.DownloadLogResponse:
 		return FromDownloadLogResponse(result)
 	case *grpc_public_api_go.DownloadLogResponseList:
 		return FromDownloadLogResponseList(result)
+	case *grpc_public_api_go.SavedSearch:
+		return FromSavedSearch(result)
+	case *grpc_public_api_go.SavedSearchList:
+		return FromSavedSearchList(result)
+	case *grpc_public_api_go.AlertRule:
+		return FromAlertRule(result)
+	case *grpc_public_api_go.AlertRuleList:
+		return FromAlertRuleList(result)
+	case *grpc_public_api_go.Operation:
+		return FromOperation(result)
+	case *grpc_public_api_go.OperationList:
+		return FromOperationList(result)
+	case *grpc_public_api_go.BulkLabelResponse:
+		return FromBulkLabelResponse(result, labelLength)
 	case *grpc_public_api_go.Node:
 		return FromNode(result, labelLength)
 	case *grpc_public_api_go.NodeList:
@@ -156,6 +177,12 @@ func AsTable(result interface{}, labelLength int) *ResultTable {
 		return FromOpResponse(result)
 	case *grpc_infrastructure_manager_go.ProvisionerResponse:
 		return FromProvisionerResponse(result)
+	case *ArchiveManifest:
+		return FromArchiveManifest(result)
+	case *ImportReport:
+		return FromImportReport(result)
+	case *DescriptorImportReport:
+		return FromDescriptorImportReport(result)
 	default:
 		log.Fatal().Str("type", fmt.Sprintf("%T", result)).Msg("unsupported")
 	}
@@ -288,6 +315,14 @@ func FromUserList(user *grpc_public_api_go.UserList) *ResultTable {
 // Clusters
 // ----
 
+// formatCount returns the text representation of a counter, taking into account that it may be unknown.
+func formatCount(count int64) string {
+	if count < 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d", count)
+}
+
 func FromCluster(result *grpc_public_api_go.Cluster, labelLength int) *ResultTable {
 	r := make([][]string, 0)
 	r = append(r, []string{"NAME", "ID", "STATE", "STATUS", "SEEN"})
@@ -297,7 +332,7 @@ func FromCluster(result *grpc_public_api_go.Cluster, labelLength int) *ResultTab
 	}
 	r = append(r, []string{result.Name, result.ClusterId, result.State.String(), result.Status.String(), seen})
 	r = append(r, []string{"NODES", "LABELS", "MCF"})
-	r = append(r, []string{fmt.Sprintf("%d", result.TotalNodes), TransformLabels(result.Labels, labelLength), fmt.Sprintf("%g", result.MillicoresConversionFactor)})
+	r = append(r, []string{formatCount(result.TotalNodes), TransformLabels(result.Labels, labelLength), fmt.Sprintf("%g", result.MillicoresConversionFactor)})
 	return &ResultTable{r}
 }
 
@@ -305,7 +340,7 @@ func FromClusterList(result *grpc_public_api_go.ClusterList, labelLength int) *R
 	r := make([][]string, 0)
 	r = append(r, []string{"NAME", "ID", "NODES", "LABELS", "STATE", "STATUS"})
 	for _, c := range result.Clusters {
-		r = append(r, []string{c.Name, c.ClusterId, fmt.Sprintf("%d", c.TotalNodes), TransformLabels(c.Labels, labelLength), c.State.String(), c.Status.String()})
+		r = append(r, []string{c.Name, c.ClusterId, formatCount(c.TotalNodes), TransformLabels(c.Labels, labelLength), c.State.String(), c.Status.String()})
 	}
 	return &ResultTable{r}
 }
@@ -441,6 +476,50 @@ func FromAppDescriptorList(result *grpc_application_go.AppDescriptorList, labelL
 	return &ResultTable{r}
 }
 
+func FromDescriptorImportReport(result *DescriptorImportReport) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"NAME", "DESCRIPTOR", "SERVICES", "RULES"})
+	r = append(r, []string{result.Name, result.DescriptorPath, strings.Join(result.Services, ", "), strconv.Itoa(result.Rules)})
+	if len(result.Untranslated) > 0 {
+		r = append(r, []string{"", "", "", ""})
+		r = append(r, []string{"NOT TRANSLATED", "", "", ""})
+		for _, element := range result.Untranslated {
+			r = append(r, []string{element, "", "", ""})
+		}
+	}
+	return &ResultTable{r}
+}
+
+func FromDescriptorHistory(result *grpc_public_api_go.DescriptorHistory, labelLength int) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"VERSION", "ID", "LABELS", "INSTANCES"})
+	for _, v := range result.Versions {
+		instances := make([]string, 0, len(v.Instances))
+		for _, i := range v.Instances {
+			instances = append(instances, fmt.Sprintf("%s (%s)", i.Name, i.StatusName))
+		}
+		r = append(r, []string{strconv.Itoa(int(v.Version)), v.AppDescriptorId, TransformLabels(v.Labels, labelLength), strings.Join(instances, ", ")})
+	}
+	return &ResultTable{r}
+}
+
+func FromDescriptorDiff(result *grpc_public_api_go.DescriptorDiff) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"FROM", "TO", "CHANGES", ""})
+	r = append(r, []string{
+		fmt.Sprintf("v%d %s", result.FromVersion, result.FromAppDescriptorId),
+		fmt.Sprintf("v%d %s", result.ToVersion, result.ToAppDescriptorId),
+		strconv.Itoa(len(result.Changes)), ""})
+	if len(result.Changes) > 0 {
+		r = append(r, []string{"", "", "", ""})
+		r = append(r, []string{"PATH", "CHANGE", "BEFORE", "AFTER"})
+		for _, c := range result.Changes {
+			r = append(r, []string{c.Path, c.Kind, c.Before, c.After})
+		}
+	}
+	return &ResultTable{r}
+}
+
 func FromAppDescriptor(result *grpc_application_go.AppDescriptor, labelLength int) *ResultTable {
 	r := make([][]string, 0)
 
@@ -562,6 +641,59 @@ func FromLogResponse(result *grpc_application_manager_go.LogResponse) *ResultTab
 	return &ResultTable{r}
 }
 
+// HistogramWidth with the number of characters of the longest bar of a histogram.
+const HistogramWidth = 40
+
+// histogramBar returns the bar of a count relative to the maximum one.
+func histogramBar(count int64, max int64) string {
+	if max == 0 || count == 0 {
+		return ""
+	}
+	return strings.Repeat("#", int((count*HistogramWidth+max-1)/max))
+}
+
+func FromLogQueryResponse(result *grpc_public_api_go.LogQueryResponse) *ResultTable {
+	r := make([][]string, 0)
+	switch {
+	case len(result.Buckets) > 0:
+		max := int64(0)
+		for _, b := range result.Buckets {
+			if b.Count > max {
+				max = b.Count
+			}
+		}
+		r = append(r, []string{strings.ToUpper(result.GroupBy), "BUCKET", "COUNT", "HISTOGRAM"})
+		for _, b := range result.Buckets {
+			key := b.Key
+			if key == "" {
+				key = "-"
+			}
+			r = append(r, []string{key, time.Unix(0, b.Start).Format(time.RFC3339), strconv.FormatInt(b.Count, 10), histogramBar(b.Count, max)})
+		}
+	case len(result.TopMessages) > 0:
+		r = append(r, []string{"COUNT", "LAST SEEN", "PATTERN"})
+		for _, m := range result.TopMessages {
+			r = append(r, []string{strconv.FormatInt(m.Count, 10), time.Unix(0, m.LastSeen).Format(time.RFC3339), m.Pattern})
+		}
+	case len(result.Entries) > 0:
+		r = append(r, []string{"TIMESTAMP", "SERVICE", "MSG"})
+		for _, e := range result.Entries {
+			r = append(r, []string{time.Unix(0, e.Timestamp).String(), e.ServiceName, e.Msg})
+		}
+	}
+	summary := fmt.Sprintf("%d of %d entries matched", result.Matched, result.Scanned)
+	if result.Truncated {
+		// The queries without aggregation read the newest entries first.
+		covered := "newest"
+		if query, err := logquery.Parse(result.Query); err == nil && query.Aggregation != nil {
+			covered = "oldest"
+		}
+		summary = fmt.Sprintf("%s, the time range was truncated to its %s entries", summary, covered)
+	}
+	r = append(r, []string{summary})
+	return &ResultTable{r}
+}
+
 func FromDownloadLogResponse(result *grpc_public_api_go.DownloadLogResponse) *ResultTable {
 
 	from := "NA"
@@ -591,18 +723,169 @@ func FromDownloadLogResponseList(result *grpc_public_api_go.DownloadLogResponseL
 
 	r := make([][]string, 0)
 
-	r = append(r, []string{"REQUEST_ID", "STATE", "INFO", "EXPIRATION"})
+	r = append(r, []string{"REQUEST_ID", "STATE", "INFO", "CREATED", "EXPIRATION"})
 	for _, response := range result.Responses {
+		created := ""
+		if response.Created != 0 {
+			created = time.Unix(0, response.Created).String()
+		}
 		exp := ""
 		if response.Expiration != 0 {
 			exp = time.Unix(0, response.Expiration).String()
 		}
-		r = append(r, []string{response.RequestId, response.StateName, response.Info, exp})
+		r = append(r, []string{response.RequestId, response.StateName, response.Info, created, exp})
 	}
 
 	return &ResultTable{r}
 }
 
+// ----
+// Saved searches and alert rules
+// ----
+
+func savedSearchRow(search *grpc_public_api_go.SavedSearch) []string {
+	since := search.Since
+	if since == "" {
+		since = "-"
+	}
+	return []string{search.SavedSearchId, search.Name, search.Query, since, time.Unix(search.Created, 0).String()}
+}
+
+func FromSavedSearch(result *grpc_public_api_go.SavedSearch) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "NAME", "QUERY", "SINCE", "CREATED"})
+	r = append(r, savedSearchRow(result))
+	return &ResultTable{r}
+}
+
+func FromSavedSearchList(result *grpc_public_api_go.SavedSearchList) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "NAME", "QUERY", "SINCE", "CREATED"})
+	for _, search := range result.SavedSearches {
+		r = append(r, savedSearchRow(search))
+	}
+	return &ResultTable{r}
+}
+
+func alertRuleRow(rule *grpc_public_api_go.AlertRule) []string {
+	lastRun := "-"
+	if rule.LastRun != 0 {
+		lastRun = time.Unix(rule.LastRun, 0).String()
+	}
+	return []string{rule.AlertRuleId, rule.Name, rule.SavedSearchId, rule.Interval, strconv.FormatInt(rule.Threshold, 10),
+		rule.State, strconv.FormatInt(rule.LastCount, 10), lastRun, rule.LastError}
+}
+
+func FromAlertRule(result *grpc_public_api_go.AlertRule) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "NAME", "SAVED_SEARCH_ID", "INTERVAL", "THRESHOLD", "STATE", "LAST_COUNT", "LAST_RUN", "LAST_ERROR"})
+	r = append(r, alertRuleRow(result))
+	r = append(r, []string{""})
+	r = append(r, []string{"WEBHOOK"})
+	r = append(r, []string{result.WebhookUrl})
+	return &ResultTable{r}
+}
+
+func FromAlertRuleList(result *grpc_public_api_go.AlertRuleList) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "NAME", "SAVED_SEARCH_ID", "INTERVAL", "THRESHOLD", "STATE", "LAST_COUNT", "LAST_RUN", "LAST_ERROR"})
+	for _, rule := range result.AlertRules {
+		r = append(r, alertRuleRow(rule))
+	}
+	return &ResultTable{r}
+}
+
+// ----
+// Operations
+// ----
+
+func operationRow(operation *grpc_public_api_go.Operation) []string {
+	return []string{operation.OperationId, operation.Type, operation.TargetId, operation.Status,
+		operation.RequestedBy, time.Unix(operation.Updated, 0).String(), operation.Info}
+}
+
+func FromOperation(result *grpc_public_api_go.Operation) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "TYPE", "TARGET", "STATUS", "REQUESTED_BY", "UPDATED", "INFO"})
+	r = append(r, operationRow(result))
+	return &ResultTable{r}
+}
+
+func FromOperationList(result *grpc_public_api_go.OperationList) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "TYPE", "TARGET", "STATUS", "REQUESTED_BY", "UPDATED", "INFO"})
+	for _, operation := range result.Operations {
+		r = append(r, operationRow(operation))
+	}
+	return &ResultTable{r}
+}
+
+// ----
+// Labels
+// ----
+
+func FromBulkLabelResponse(result *grpc_public_api_go.BulkLabelResponse, labelLength int) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"ID", "NAME", "CHANGED", "STATUS", "LABELS"})
+	for _, entity := range result.Results {
+		status := "OK"
+		if !entity.Success {
+			status = entity.Error
+		}
+		r = append(r, []string{entity.EntityId, entity.Name, strconv.FormatBool(entity.Changed), status,
+			TransformLabels(entity.Labels, labelLength)})
+	}
+	summary := fmt.Sprintf("%d succeeded, %d failed", result.Succeeded, result.Failed)
+	if result.DryRun {
+		summary = summary + " (dry run, no changes applied)"
+	}
+	r = append(r, []string{""})
+	r = append(r, []string{summary})
+	return &ResultTable{r}
+}
+
+// ----
+// Organization archives
+// ----
+
+func FromArchiveManifest(result *ArchiveManifest) *ResultTable {
+	sections := make([]string, 0, len(result.Entries))
+	for section := range result.Entries {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	r := make([][]string, 0)
+	r = append(r, []string{"SECTION", "ENTRIES"})
+	for _, section := range sections {
+		r = append(r, []string{section, strconv.Itoa(result.Entries[section])})
+	}
+	r = append(r, []string{""})
+	r = append(r, []string{fmt.Sprintf("organization %s exported at %s", result.OrganizationId, result.ExportedAt)})
+	return &ResultTable{r}
+}
+
+func FromImportReport(result *ImportReport) *ResultTable {
+	r := make([][]string, 0)
+	r = append(r, []string{"SECTION", "NAME", "SOURCE_ID", "TARGET_ID", "ACTION", "STATUS"})
+	for _, action := range result.Actions {
+		status := "OK"
+		if action.Failed {
+			status = "FAILED"
+		}
+		if action.Message != "" {
+			status = status + ": " + action.Message
+		}
+		r = append(r, []string{action.Section, action.Name, action.SourceId, action.TargetId, action.Action, status})
+	}
+	summary := fmt.Sprintf("%d actions, %d failed", len(result.Actions), result.Failed)
+	if result.DryRun {
+		summary = summary + " (dry run, no changes applied)"
+	}
+	r = append(r, []string{""})
+	r = append(r, []string{summary})
+	return &ResultTable{r}
+}
+
 // ----
 // Roles
 // ----
@@ -17,30 +17,32 @@
 package cli
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
-	"github.com/araddon/dateparse"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-manager-go"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-log-download-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"io"
-	"net/http"
-	"os"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 const OrderByField = "timestamp"
 const FollowSleep = time.Second * 5
 
+// FollowTimeout with the maximum life of a tail stream on the client side. The server ends the streams earlier.
+const FollowTimeout = time.Hour * 2
+
 type UnifiedLogging struct {
 	Connection
 	Credentials
@@ -70,9 +72,9 @@ func (u *UnifiedLogging) getClient() (grpc_public_api_go.UnifiedLoggingClient, *
 }
 
 func parseTime(timeString string) (*timestamp.Timestamp, error) {
-	t, err := dateparse.ParseLocal(timeString)
-	if err != nil {
-		return nil, err
+	t, derr := timeexpr.Parse(timeString, time.Now())
+	if derr != nil {
+		return nil, derr
 	}
 	timeProto, err := ptypes.TimestampProto(t)
 	if err != nil {
@@ -84,34 +86,16 @@ func parseTime(timeString string) (*timestamp.Timestamp, error) {
 
 //l.Search(cliOptions.Resolve("organizationID", organizationID), descriptorID, instanceID, sgID, sgInstanceID, serviceID, serviceInstanceID, message, from, to, desc, redirectLog)
 func (u *UnifiedLogging) Search(organizationId, descriptorId, instanceId, sgId, sgInstanceId, serviceId, serviceInstanceId,
-	msgFilter, from, to string, desc bool, redirectLog bool, follow bool, nFirst bool) {
+	msgFilter, from, to, since string, desc bool, redirectLog bool, follow bool, nFirst bool) {
 	// Validate options
 	if organizationId == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
 
 	// Parse and validate timestamps
-	var fromTime, toTime time.Time
-	var fromInt, toInt int64
-	fromInt = 0
-	toInt = 0
-	var err error
-	if from != "" {
-		fromTime, err = dateparse.ParseLocal(from)
-		if err != nil {
-			log.Fatal().Err(err).Msg("invalid from time")
-		}
-		fromInt = fromTime.UnixNano()
-	}
-	if to != "" {
-		toTime, err = dateparse.ParseLocal(to)
-		if err != nil {
-			log.Fatal().Err(err).Msg("invalid to time")
-		}
-		toInt = toTime.UnixNano()
-	}
+	timeRange := parseTimeRange(from, to, since)
 
-	if follow && (toInt != 0 || fromInt != 0) {
+	if follow && !timeRange.IsZero() {
 		log.Fatal().Msg("time range can not be informed with follow option")
 	}
 
@@ -134,60 +118,123 @@ func (u *UnifiedLogging) Search(organizationId, descriptorId, instanceId, sgId,
 		ServiceId:              serviceId,
 		ServiceInstanceId:      serviceInstanceId,
 		MsgQueryFilter:         msgFilter,
-		From:                   fromInt,
-		To:                     toInt,
+		From:                   timeRange.FromNano(),
+		To:                     timeRange.ToNano(),
 		Order:                  &order,
 		NFirst:                 nFirst,
 	}
 
-	toReturned := u.callSearch(searchRequest, redirectLog, client)
+	result := u.callSearch(searchRequest, redirectLog, client)
 
 	if follow {
-		ticker := time.NewTicker(FollowSleep)
-		done := make(chan bool)
+		u.follow(searchRequest, result, redirectLog, client)
+	}
+
+}
+
+// followCursor skips the entries already printed, as the initial search and the tail streams opened from its last
+// entry may return the same entries.
+type followCursor struct {
+	timestamp int64
+	seen      map[string]bool
+}
+
+// pending returns the entries that were not printed yet and records them.
+func (c *followCursor) pending(entries []*grpc_application_manager_go.LogEntryResponse) []*grpc_application_manager_go.LogEntryResponse {
+	result := make([]*grpc_application_manager_go.LogEntryResponse, 0)
+	for _, le := range entries {
+		identity := fmt.Sprintf("%s/%s/%s/%d/%s", le.AppInstanceId, le.ServiceGroupInstanceId, le.ServiceInstanceId, le.Timestamp, le.Msg)
+		if le.Timestamp < c.timestamp || (le.Timestamp == c.timestamp && c.seen[identity]) {
+			continue
+		}
+		if le.Timestamp > c.timestamp {
+			c.timestamp = le.Timestamp
+			c.seen = make(map[string]bool, 0)
+		}
+		c.seen[identity] = true
+		result = append(result, le)
+	}
+	return result
+}
+
+// follow prints the new entries sent by the Tail stream after the initial search. The server ends the stream
+// after a while, so it is opened again from the last cursor until the command is interrupted.
+func (u *UnifiedLogging) follow(searchRequest *grpc_public_api_go.SearchRequest, initial *grpc_application_manager_go.LogResponse,
+	redirectLog bool, client grpc_public_api_go.UnifiedLoggingClient) {
+	cursor := &followCursor{timestamp: time.Now().UnixNano(), seen: make(map[string]bool, 0)}
+	if initial != nil && len(initial.Entries) > 0 {
+		cursor.timestamp = 0
+		cursor.pending(initial.Entries)
+	}
+	tailRequest := *searchRequest
+	tailRequest.Order = nil
+	tailRequest.NFirst = false
+	for {
+		tailRequest.From = cursor.timestamp
+		ctx, cancel := u.GetContext(FollowTimeout)
+		stream, err := client.Tail(ctx, &tailRequest)
+		if err != nil {
+			cancel()
+			log.Fatal().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot follow logs")
+		}
 		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				if toReturned != 0 {
-					// next milisecond
-					searchRequest.From = toReturned + 1000000
-				}
-				toReturned = u.callSearch(searchRequest, redirectLog, client)
+			response, err := stream.Recv()
+			if err == io.EOF {
+				break
 			}
+			if err != nil {
+				cancel()
+				log.Fatal().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot follow logs")
+			}
+			entries := cursor.pending(response.Entries)
+			if len(entries) == 0 {
+				continue
+			}
+			u.printLogResponse(&grpc_application_manager_go.LogResponse{
+				OrganizationId: response.OrganizationId,
+				From:           entries[0].Timestamp,
+				To:             response.Cursor,
+				Entries:        entries,
+			}, nil, redirectLog)
 		}
+		cancel()
 	}
+}
 
+// Query evaluates a log query. The aggregations are shown as a histogram in the table output.
+func (u *UnifiedLogging) Query(organizationId, query, from, to, since string, limit int32) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	timeRange := parseTimeRange(from, to, since)
+
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	result, err := client.Query(ctx, &grpc_public_api_go.LogQueryRequest{
+		OrganizationId: organizationId,
+		Query:          query,
+		From:           timeRange.FromNano(),
+		To:             timeRange.ToNano(),
+		Limit:          limit,
+	})
+	u.PrintResultOrError(result, err, "cannot query logs")
 }
 
 func (u *UnifiedLogging) Download(organizationId, descriptorId, instanceId, sgId, sgInstanceId, serviceId, serviceInstanceId,
-	msgFilter, from, to string, desc bool, includeMetadata bool, outputPath string) {
+	msgFilter, from, to, since string, desc bool, includeMetadata bool, outputPath string, options *ArchiveOptions) {
 	// Validate options
 	if organizationId == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
 	}
+	if err := options.Validate(); err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid archive options")
+	}
 
 	// Parse and validate timestamps
-	var fromTime, toTime time.Time
-	var fromInt, toInt int64
-	fromInt = 0
-	toInt = 0
-	var err error
-	if from != "" {
-		fromTime, err = dateparse.ParseLocal(from)
-		if err != nil {
-			log.Fatal().Err(err).Msg("invalid from time")
-		}
-		fromInt = fromTime.UnixNano()
-	}
-	if to != "" {
-		toTime, err = dateparse.ParseLocal(to)
-		if err != nil {
-			log.Fatal().Err(err).Msg("invalid to time")
-		}
-		toInt = toTime.UnixNano()
-	}
+	timeRange := parseTimeRange(from, to, since)
 
 	u.load()
 
@@ -208,8 +255,8 @@ func (u *UnifiedLogging) Download(organizationId, descriptorId, instanceId, sgId
 		ServiceId:              serviceId,
 		ServiceInstanceId:      serviceInstanceId,
 		MsgQueryFilter:         msgFilter,
-		From:                   fromInt,
-		To:                     toInt,
+		From:                   timeRange.FromNano(),
+		To:                     timeRange.ToNano(),
 		Order:                  &order,
 		IncludeMetadata:        includeMetadata,
 	}
@@ -224,7 +271,11 @@ func (u *UnifiedLogging) Download(organizationId, descriptorId, instanceId, sgId
 		u.PrintResultOrError(response, err, "cannot download log entries")
 	} else {
 		// no error, check!
-		rCtx, rCancel := context.WithTimeout(context.Background(), time.Minute*10)
+		timeout := options.Timeout
+		if timeout <= 0 {
+			timeout = DefaultDownloadTimeout
+		}
+		rCtx, rCancel := context.WithTimeout(context.Background(), timeout)
 		defer rCancel()
 		ticker := time.NewTicker(FollowSleep)
 		done := make(chan bool)
@@ -244,13 +295,13 @@ func (u *UnifiedLogging) Download(organizationId, descriptorId, instanceId, sgId
 					return
 				}
 				if checkResponse.State == grpc_log_download_manager_go.DownloadLogState_READY {
-					u.callGet(checkResponse, outputPath)
+					u.callGet(checkResponse, outputPath, options)
 					return
 				}
 				// check
 			case <-rCtx.Done():
-				log.Info().Msg("error")
-				err = derrors.NewDeadlineExceededError("Download error. Try it manually")
+				err = derrors.NewDeadlineExceededError("the log archive is not ready yet").WithParams(response.RequestId)
+				u.PrintResultOrError(response, err, "try to get the archive later with: log download get "+response.RequestId)
 				return
 			}
 		}
@@ -270,7 +321,7 @@ func (u *UnifiedLogging) Check(organizationId, requestId string) {
 	u.PrintResultOrError(response, err, "cannot check the status of the request")
 }
 
-func (u *UnifiedLogging) Get(organizationId, requestId, outputPath string) {
+func (u *UnifiedLogging) Get(organizationId, requestId, outputPath string, options *ArchiveOptions) {
 	// Validate options
 	if organizationId == "" {
 		log.Fatal().Msg("organizationID cannot be empty")
@@ -278,17 +329,212 @@ func (u *UnifiedLogging) Get(organizationId, requestId, outputPath string) {
 	if requestId == "" {
 		log.Fatal().Msg("requestId cannot be empty")
 	}
+	if err := options.Validate(); err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid archive options")
+	}
 
 	// get the url
 	response, err := u.callCheck(organizationId, requestId)
 	if err != nil {
 		u.PrintResultOrError(response, err, "cannot check the status of the request")
 	} else {
-		u.callGet(response, outputPath)
+		u.callGet(response, outputPath, options)
 	}
 	return
 }
 
+// CancelDownload stops a download request in progress.
+func (u *UnifiedLogging) CancelDownload(organizationId, requestId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if requestId == "" {
+		log.Fatal().Msg("requestId cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	response, err := client.CancelDownload(ctx, &grpc_log_download_manager_go.DownloadRequestId{
+		OrganizationId: organizationId,
+		RequestId:      requestId,
+	})
+	u.PrintResultOrError(response, err, "cannot cancel the download request")
+}
+
+// DeleteDownload removes a finished download request and its archive.
+func (u *UnifiedLogging) DeleteDownload(organizationId, requestId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if requestId == "" {
+		log.Fatal().Msg("requestId cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	_, err := client.DeleteDownload(ctx, &grpc_log_download_manager_go.DownloadRequestId{
+		OrganizationId: organizationId,
+		RequestId:      requestId,
+	})
+	u.PrintSuccessOrError(err, "cannot delete the download request", "download request deleted")
+}
+
+// PruneDownloads removes the finished download requests older than the retention of the organization, printing
+// them. A dry run only prints them.
+func (u *UnifiedLogging) PruneDownloads(organizationId string, dryRun bool) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	response, err := client.PruneDownloads(ctx, &grpc_public_api_go.PruneDownloadsRequest{
+		OrganizationId: organizationId,
+		DryRun:         dryRun,
+	})
+	u.PrintResultOrError(response, err, "cannot prune the download requests")
+}
+
+// AddSavedSearch stores a log query to run it later or to use it in alert rules.
+func (u *UnifiedLogging) AddSavedSearch(organizationId, name, query, since string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if name == "" {
+		log.Fatal().Msg("name cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	search, err := client.AddSavedSearch(ctx, &grpc_public_api_go.AddSavedSearchRequest{
+		OrganizationId: organizationId,
+		Name:           name,
+		Query:          query,
+		Since:          since,
+	})
+	u.PrintResultOrError(search, err, "cannot save the search")
+}
+
+func (u *UnifiedLogging) ListSavedSearches(organizationId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	list, err := client.ListSavedSearches(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationId})
+	u.PrintResultOrError(list, err, "cannot list the saved searches")
+}
+
+// RunSavedSearch evaluates a saved search over its time window, printing the result as a query.
+func (u *UnifiedLogging) RunSavedSearch(organizationId, savedSearchId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if savedSearchId == "" {
+		log.Fatal().Msg("savedSearchID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	result, err := client.RunSavedSearch(ctx, &grpc_public_api_go.SavedSearchId{
+		OrganizationId: organizationId,
+		SavedSearchId:  savedSearchId,
+	})
+	u.PrintResultOrError(result, err, "cannot run the saved search")
+}
+
+func (u *UnifiedLogging) RemoveSavedSearch(organizationId, savedSearchId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if savedSearchId == "" {
+		log.Fatal().Msg("savedSearchID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	_, err := client.RemoveSavedSearch(ctx, &grpc_public_api_go.SavedSearchId{
+		OrganizationId: organizationId,
+		SavedSearchId:  savedSearchId,
+	})
+	u.PrintSuccessOrError(err, "cannot remove the saved search", "saved search removed")
+}
+
+// AddAlertRule creates a rule that notifies a webhook when a saved search matches at least threshold entries.
+func (u *UnifiedLogging) AddAlertRule(organizationId, savedSearchId, name, interval string, threshold int64, webhookURL, webhookSecret string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if savedSearchId == "" {
+		log.Fatal().Msg("savedSearchID cannot be empty")
+	}
+	if webhookURL == "" {
+		log.Fatal().Msg("webhookURL cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	rule, err := client.AddAlertRule(ctx, &grpc_public_api_go.AddAlertRuleRequest{
+		OrganizationId: organizationId,
+		SavedSearchId:  savedSearchId,
+		Name:           name,
+		Interval:       interval,
+		Threshold:      threshold,
+		WebhookUrl:     webhookURL,
+		WebhookSecret:  webhookSecret,
+	})
+	u.PrintResultOrError(rule, err, "cannot add the alert rule")
+}
+
+func (u *UnifiedLogging) ListAlertRules(organizationId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	list, err := client.ListAlertRules(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationId})
+	u.PrintResultOrError(list, err, "cannot list the alert rules")
+}
+
+func (u *UnifiedLogging) RemoveAlertRule(organizationId, alertRuleId string) {
+	if organizationId == "" {
+		log.Fatal().Msg("organizationID cannot be empty")
+	}
+	if alertRuleId == "" {
+		log.Fatal().Msg("alertRuleID cannot be empty")
+	}
+	u.load()
+	ctx, cancel := u.GetContext()
+	defer cancel()
+	client, conn := u.getClient()
+	defer conn.Close()
+	_, err := client.RemoveAlertRule(ctx, &grpc_public_api_go.AlertRuleId{
+		OrganizationId: organizationId,
+		AlertRuleId:    alertRuleId,
+	})
+	u.PrintSuccessOrError(err, "cannot remove the alert rule", "alert rule removed")
+}
+
 func (u *UnifiedLogging) List(organizationId string, watch bool) {
 	// Validate options
 	if organizationId == "" {
@@ -350,50 +596,37 @@ func (u *UnifiedLogging) List(organizationId string, watch bool) {
 	}
 }
 
-func (u *UnifiedLogging) callGet(checkResponse *grpc_public_api_go.DownloadLogResponse, outputPath string) {
-
-	tlsConfigInsecure := &tls.Config{InsecureSkipVerify: true}
-
-	tr := &http.Transport{
-		MaxIdleConns:       10,
-		IdleConnTimeout:    30 * time.Second,
-		DisableCompression: true,
-		TLSClientConfig:    tlsConfigInsecure,
-	}
-	client := &http.Client{
-		Transport: tr,
-	}
-	req, err := http.NewRequest("GET", checkResponse.Url, nil)
-	req.Header.Add("authorization", u.Token)
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
-
+// callGet downloads the archive of a ready request, resuming a previous partial download, and extracts it if
+// requested.
+func (u *UnifiedLogging) callGet(checkResponse *grpc_public_api_go.DownloadLogResponse, outputPath string, options *ArchiveOptions) {
+	client, err := u.GetHTTPClient()
 	if err != nil {
-		u.PrintResultOrError(req, err, "error getting the file")
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		buf := new(bytes.Buffer)
-		buf.ReadFrom(resp.Body)
-		u.PrintResultOrError(req, derrors.NewInternalError(resp.Status), buf.String())
+		u.PrintResultOrError(checkResponse, err, "cannot create the HTTP client")
 		return
 	}
-	// Create the file
-	outputFilePath := fmt.Sprintf("%s%s.zip", outputPath, checkResponse.RequestId)
-	out, err := os.Create(outputFilePath)
-	if err != nil {
-		u.PrintResultOrError(checkResponse, err, "error creating the file")
+	archivePath := filepath.Join(outputPath, fmt.Sprintf("%s.zip", checkResponse.RequestId))
+	download := &archiveDownload{
+		client:   client,
+		url:      checkResponse.Url,
+		token:    u.Token,
+		path:     archivePath,
+		checksum: strings.ToLower(options.Checksum),
+	}
+	if err := download.run(); err != nil {
+		u.PrintResultOrError(checkResponse, err, "cannot download the log archive")
 		return
 	}
-	defer out.Close()
+	fmt.Printf("\nLog Entries file: %s\n", archivePath)
 
-	// Write the body to file
-	_, err = io.Copy(out, resp.Body)
-	if err != nil {
-		u.PrintResultOrError(checkResponse, err, "error copying the file")
-		return
+	if options.Extract {
+		target := strings.TrimSuffix(archivePath, ".zip")
+		entries, err := extractArchive(archivePath, target, options.ExtractFormat)
+		if err != nil {
+			u.PrintResultOrError(checkResponse, err, "cannot extract the log archive")
+			return
+		}
+		fmt.Printf("%d log entries extracted in: %s\n", entries, target)
 	}
-	fmt.Printf("\nLog Entries file: %s\n", outputFilePath)
 }
 
 func (u *UnifiedLogging) callCheck(organizationId, requestId string) (*grpc_public_api_go.DownloadLogResponse, error) {
@@ -412,11 +645,17 @@ func (u *UnifiedLogging) callCheck(organizationId, requestId string) (*grpc_publ
 }
 
 // returns searchTo field (we need this value to update the next search)
-func (u *UnifiedLogging) callSearch(searchRequest *grpc_public_api_go.SearchRequest, redirectLog bool, client grpc_public_api_go.UnifiedLoggingClient) int64 {
+func (u *UnifiedLogging) callSearch(searchRequest *grpc_public_api_go.SearchRequest, redirectLog bool, client grpc_public_api_go.UnifiedLoggingClient) *grpc_application_manager_go.LogResponse {
+
+	searchCtx, searchCancel := u.GetContext()
+	defer searchCancel()
+	result, err := client.Search(searchCtx, searchRequest)
+	u.printLogResponse(result, err, redirectLog)
+	return result
+}
 
-	followCtx, followCancel := u.GetContext()
-	defer followCancel()
-	result, err := client.Search(followCtx, searchRequest)
+// printLogResponse prints the entries of a search, as log messages if redirectLog is set.
+func (u *UnifiedLogging) printLogResponse(result *grpc_application_manager_go.LogResponse, err error, redirectLog bool) {
 	if redirectLog {
 		if err != nil {
 			log.Fatal().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot search logs")
@@ -430,5 +669,4 @@ func (u *UnifiedLogging) callSearch(searchRequest *grpc_public_api_go.SearchRequ
 	} else {
 		u.PrintResultOrError(result, err, "cannot search logs")
 	}
-	return result.To
 }
@@ -19,12 +19,14 @@ package cli
 import (
 	"encoding/base64"
 	"github.com/nalej/derrors"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
 	"github.com/rs/zerolog/log"
 	"io/ioutil"
 	"os"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 func GetLabels(rawLabels string) map[string]string {
@@ -58,6 +60,15 @@ func GetPath(path string) string {
 	return path
 }
 
+// parseTimeRange parses the time flags of a command. The relative expressions are resolved against the current time.
+func parseTimeRange(from string, to string, since string) *timeexpr.Range {
+	timeRange, err := timeexpr.ParseRange(from, to, since, time.Now())
+	if err != nil {
+		log.Fatal().Str("trace", err.DebugReport()).Msg("invalid time range")
+	}
+	return timeRange
+}
+
 // PhotoPathToBase64 reads a image an convert the content to a base64 string
 func PhotoPathToBase64(path string) (string, derrors.Error) {
 	// if there is no path -> empty image
@@ -35,6 +35,8 @@ type RequestMetadata struct {
 	ResourcePrimitive      bool
 	ProfilePrimitive       bool
 	AppClusterOpsPrimitive bool
+	ResourceMngtPrimitive  bool
+	DevMngrPrimitive       bool
 }
 
 func GetRequestMetadata(ctx context.Context) (*RequestMetadata, derrors.Error) {
@@ -55,6 +57,8 @@ func GetRequestMetadata(ctx context.Context) (*RequestMetadata, derrors.Error) {
 	_, resourcePrimitive := md[strings.ToLower(grpc_authx_go.AccessPrimitive_RESOURCES.String())]
 	_, profilePrimitive := md[strings.ToLower(grpc_authx_go.AccessPrimitive_PROFILE.String())]
 	_, appClusterOpsPrimitive := md[strings.ToLower(grpc_authx_go.AccessPrimitive_APPCLUSTEROPS.String())]
+	_, resourceMngtPrimitive := md[strings.ToLower(grpc_authx_go.AccessPrimitive_RESOURCES_MNGT.String())]
+	_, devMngrPrimitive := md[strings.ToLower(grpc_authx_go.AccessPrimitive_DEVMNGR.String())]
 
 	return &RequestMetadata{
 		UserID:                 userID[0],
@@ -64,5 +68,7 @@ func GetRequestMetadata(ctx context.Context) (*RequestMetadata, derrors.Error) {
 		ResourcePrimitive:      resourcePrimitive,
 		ProfilePrimitive:       profilePrimitive,
 		AppClusterOpsPrimitive: appClusterOpsPrimitive,
+		ResourceMngtPrimitive:  resourceMngtPrimitive,
+		DevMngrPrimitive:       devMngrPrimitive,
 	}, nil
 }
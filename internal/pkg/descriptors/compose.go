@@ -0,0 +1,376 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"gopkg.in/yaml.v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// composeFile with the elements of a docker-compose file.
+type composeFile struct {
+	Version  string                    `yaml:"version"`
+	Services map[string]composeService `yaml:"services"`
+	Other    map[string]interface{}    `yaml:",inline"`
+}
+
+// composeDeploy with the deploy section of a compose service.
+type composeDeploy struct {
+	Replicas *int32                 `yaml:"replicas"`
+	Other    map[string]interface{} `yaml:",inline"`
+}
+
+// composeService with the elements of a compose service. Most of them accept several syntaxes, so they are decoded
+// as generic values.
+type composeService struct {
+	Image       string                 `yaml:"image"`
+	Environment interface{}            `yaml:"environment"`
+	Ports       []interface{}          `yaml:"ports"`
+	Expose      []interface{}          `yaml:"expose"`
+	Volumes     []interface{}          `yaml:"volumes"`
+	Tmpfs       interface{}            `yaml:"tmpfs"`
+	DependsOn   interface{}            `yaml:"depends_on"`
+	Deploy      *composeDeploy         `yaml:"deploy"`
+	Scale       *int32                 `yaml:"scale"`
+	Command     interface{}            `yaml:"command"`
+	Labels      interface{}            `yaml:"labels"`
+	Other       map[string]interface{} `yaml:",inline"`
+}
+
+// composeIgnored with the keys of a compose service that have no effect on the platform.
+var composeIgnored = map[string]bool{"container_name": true, "restart": true, "hostname": true}
+
+// keyValues decodes the list ([KEY=VALUE]) and map ({KEY: VALUE}) syntaxes of environment variables and labels.
+func keyValues(value interface{}) map[string]string {
+	result := make(map[string]string, 0)
+	switch v := value.(type) {
+	case []interface{}:
+		for _, item := range v {
+			entry := fmt.Sprint(item)
+			split := strings.SplitN(entry, "=", 2)
+			if len(split) == 2 {
+				result[split[0]] = split[1]
+			} else {
+				result[split[0]] = ""
+			}
+		}
+	case map[interface{}]interface{}:
+		for key, item := range v {
+			if item == nil {
+				result[fmt.Sprint(key)] = ""
+			} else {
+				result[fmt.Sprint(key)] = fmt.Sprint(item)
+			}
+		}
+	}
+	return result
+}
+
+// stringList decodes a value that may be a single string or a list of strings.
+func stringList(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			result = append(result, fmt.Sprint(item))
+		}
+		return result
+	case map[interface{}]interface{}:
+		result := make([]string, 0, len(v))
+		for key := range v {
+			result = append(result, fmt.Sprint(key))
+		}
+		sort.Strings(result)
+		return result
+	}
+	return nil
+}
+
+// shellWords splits a command line into its words as a POSIX shell does, honoring single quotes, double quotes and
+// backslash escapes. Variables and globs are not expanded.
+func shellWords(line string) ([]string, error) {
+	result := make([]string, 0)
+	var word strings.Builder
+	inWord, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped && r == '\n':
+			// An escaped new line continues the line
+			escaped = false
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' && r != '$' && r != '`' {
+				word.WriteRune('\\')
+			}
+			word.WriteRune(r)
+			escaped, inWord = false, true
+		case r == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				result = append(result, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if escaped || quote != 0 {
+		return nil, fmt.Errorf("unterminated quote or escape in %q", line)
+	}
+	if inWord {
+		result = append(result, word.String())
+	}
+	return result, nil
+}
+
+// commandList decodes a command that may be a list of arguments or a command line split as a shell does.
+func commandList(value interface{}) ([]string, error) {
+	if line, ok := value.(string); ok {
+		return shellWords(line)
+	}
+	return stringList(value), nil
+}
+
+// parsePort decodes a port number.
+func parsePort(value string) (int32, error) {
+	port, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return int32(port), nil
+}
+
+// composePort decodes the short (HOST:CONTAINER/PROTOCOL) and long syntaxes of a port. It returns the container
+// port, the published port if any, and the protocol.
+func composePort(value interface{}) (int32, int32, string, error) {
+	if long, ok := value.(map[interface{}]interface{}); ok {
+		target, err := parsePort(fmt.Sprint(long["target"]))
+		if err != nil {
+			return 0, 0, "", err
+		}
+		var published int32
+		if long["published"] != nil {
+			if published, err = parsePort(fmt.Sprint(long["published"])); err != nil {
+				return 0, 0, "", err
+			}
+		}
+		protocol := "tcp"
+		if long["protocol"] != nil {
+			protocol = fmt.Sprint(long["protocol"])
+		}
+		return target, published, protocol, nil
+	}
+	spec := fmt.Sprint(value)
+	protocol := "tcp"
+	if index := strings.Index(spec, "/"); index != -1 {
+		protocol = spec[index+1:]
+		spec = spec[:index]
+	}
+	parts := strings.Split(spec, ":")
+	target, err := parsePort(parts[len(parts)-1])
+	if err != nil {
+		return 0, 0, "", err
+	}
+	var published int32
+	if len(parts) > 1 {
+		if published, err = parsePort(parts[len(parts)-2]); err != nil {
+			return 0, 0, "", err
+		}
+	}
+	return target, published, protocol, nil
+}
+
+// composeVolume decodes the short (SOURCE:TARGET:MODE) and long syntaxes of a volume. It returns the storage of the
+// volume, or nil if it is a bind mount of the host that cannot be translated, and the access mode of the volume if
+// it is not the default read-write one, as it cannot be translated either.
+func composeVolume(value interface{}) (*grpc_application_go.Storage, string) {
+	var source, target, volumeType, mode string
+	if long, ok := value.(map[interface{}]interface{}); ok {
+		if long["source"] != nil {
+			source = fmt.Sprint(long["source"])
+		}
+		target = fmt.Sprint(long["target"])
+		volumeType = fmt.Sprint(long["type"])
+		if readOnly, _ := long["read_only"].(bool); readOnly {
+			mode = "ro"
+		}
+	} else {
+		parts := strings.Split(fmt.Sprint(value), ":")
+		if len(parts) == 1 {
+			target = parts[0]
+		} else {
+			source = parts[0]
+			target = parts[1]
+		}
+		if len(parts) > 2 && parts[2] != "rw" {
+			mode = parts[2]
+		}
+		switch {
+		case source == "":
+			volumeType = "volume"
+		case strings.HasPrefix(source, "/") || strings.HasPrefix(source, ".") || strings.HasPrefix(source, "~"):
+			volumeType = "bind"
+		default:
+			volumeType = "volume"
+		}
+	}
+	switch {
+	case volumeType == "tmpfs" || (volumeType == "volume" && source == ""):
+		return &grpc_application_go.Storage{MountPath: target, Type: grpc_application_go.StorageType_EPHEMERAL}, mode
+	case volumeType == "volume":
+		return &grpc_application_go.Storage{MountPath: target, Type: grpc_application_go.StorageType_CLUSTER_LOCAL, Size: DefaultVolumeSize}, mode
+	default:
+		return nil, mode
+	}
+}
+
+// FromCompose translates a docker-compose file into a descriptor. All the services are placed in the same group.
+// Named volumes become cluster local storage and anonymous ones ephemeral storage. Published ports are exposed
+// publicly with a web endpoint.
+func FromCompose(content []byte, name string) (*ImportResult, derrors.Error) {
+	compose := composeFile{}
+	if err := yaml.Unmarshal(content, &compose); err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot parse compose file", err)
+	}
+	if len(compose.Services) == 0 {
+		return nil, derrors.NewInvalidArgumentError("compose file does not contain services")
+	}
+	result := newImportResult(name)
+	for _, key := range sortedKeys(compose.Other) {
+		result.untranslated("top-level %s", key)
+	}
+
+	serviceNames := make([]string, 0, len(compose.Services))
+	for serviceName := range compose.Services {
+		serviceNames = append(serviceNames, serviceName)
+	}
+	sort.Strings(serviceNames)
+
+	public := make(map[string]map[int32]bool, 0)
+	for _, serviceName := range serviceNames {
+		cs := compose.Services[serviceName]
+		service := &grpc_application_go.Service{
+			Name:                 serviceName,
+			Type:                 grpc_application_go.ServiceType_DOCKER,
+			Image:                cs.Image,
+			Specs:                &grpc_application_go.DeploySpecs{Replicas: 1},
+			EnvironmentVariables: keyValues(cs.Environment),
+			Labels:               keyValues(cs.Labels),
+			DeployAfter:          stringList(cs.DependsOn),
+			Storage:              make([]*grpc_application_go.Storage, 0),
+			ExposedPorts:         make([]*grpc_application_go.Port, 0),
+		}
+		if cs.Image == "" {
+			result.untranslated("service %s: build, an image is required", serviceName)
+		}
+		if command, err := commandList(cs.Command); err != nil {
+			result.untranslated("service %s: command %v", serviceName, cs.Command)
+		} else {
+			service.RunArguments = command
+		}
+		if cs.Scale != nil {
+			service.Specs.Replicas = *cs.Scale
+		}
+		if cs.Deploy != nil {
+			if cs.Deploy.Replicas != nil {
+				service.Specs.Replicas = *cs.Deploy.Replicas
+			}
+			for _, key := range sortedKeys(cs.Deploy.Other) {
+				result.untranslated("service %s: deploy %s", serviceName, key)
+			}
+		}
+
+		public[serviceName] = make(map[int32]bool, 0)
+		ports := make(map[int32]bool, 0)
+		addPort := func(target int32, published bool) {
+			if !ports[target] {
+				ports[target] = true
+				service.ExposedPorts = append(service.ExposedPorts, &grpc_application_go.Port{
+					Name:         fmt.Sprintf("%s-%d", serviceName, target),
+					InternalPort: target,
+					ExposedPort:  target,
+				})
+			}
+			if published {
+				public[serviceName][target] = true
+			}
+		}
+		for _, port := range cs.Ports {
+			target, published, protocol, err := composePort(port)
+			if err != nil {
+				result.untranslated("service %s: port %v", serviceName, port)
+				continue
+			}
+			if protocol != "tcp" {
+				result.untranslated("service %s: %s port %d", serviceName, protocol, target)
+				continue
+			}
+			if published != 0 && published != target {
+				result.untranslated("service %s: port %d published on host port %d", serviceName, target, published)
+			}
+			addPort(target, published != 0)
+		}
+		for _, port := range cs.Expose {
+			target, _, protocol, err := composePort(port)
+			if err != nil || protocol != "tcp" {
+				result.untranslated("service %s: exposed port %v", serviceName, port)
+				continue
+			}
+			addPort(target, false)
+		}
+
+		for _, volume := range cs.Volumes {
+			storage, mode := composeVolume(volume)
+			if storage == nil {
+				result.untranslated("service %s: bind mount %v", serviceName, volume)
+				continue
+			}
+			if mode != "" {
+				result.untranslated("service %s: volume %s mode %s", serviceName, storage.MountPath, mode)
+			}
+			service.Storage = append(service.Storage, storage)
+		}
+		for _, path := range stringList(cs.Tmpfs) {
+			service.Storage = append(service.Storage, &grpc_application_go.Storage{MountPath: path, Type: grpc_application_go.StorageType_EPHEMERAL})
+		}
+
+		for _, key := range sortedKeys(cs.Other) {
+			if !composeIgnored[key] {
+				result.untranslated("service %s: %s", serviceName, key)
+			}
+		}
+		result.addService(service)
+	}
+	result.exposePorts(public)
+	return result, nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"sort"
+	"strings"
+)
+
+// Formats of the manifests that can be imported as descriptors.
+const (
+	// ComposeFormat for docker-compose files.
+	ComposeFormat = "compose"
+	// KubernetesFormat for Kubernetes Deployment, StatefulSet and Service manifests.
+	KubernetesFormat = "k8s"
+)
+
+// DefaultGroupName with the name of the service group containing the imported services.
+const DefaultGroupName = "application"
+
+// DefaultVolumeSize with the size of the storage created for the imported volumes.
+const DefaultVolumeSize = int64(1024 * 1024 * 1024)
+
+// ImportResult contains the descriptor translated from a manifest and the elements that could not be translated.
+type ImportResult struct {
+	Descriptor *grpc_application_go.AddAppDescriptorRequest
+	// Untranslated with a description of each element of the manifest that is not part of the descriptor.
+	Untranslated []string
+}
+
+// untranslated records an element of the manifest that is not part of the descriptor.
+func (ir *ImportResult) untranslated(format string, args ...interface{}) {
+	ir.Untranslated = append(ir.Untranslated, fmt.Sprintf(format, args...))
+}
+
+// Import translates a manifest into a descriptor with a given name.
+func Import(format string, content []byte, name string) (*ImportResult, derrors.Error) {
+	switch strings.ToLower(format) {
+	case ComposeFormat:
+		return FromCompose(content, name)
+	case KubernetesFormat, "kubernetes":
+		return FromKubernetes(content, name)
+	default:
+		return nil, derrors.NewInvalidArgumentError("unsupported manifest format, expecting compose or k8s").WithParams(format)
+	}
+}
+
+// newImportResult creates the result of an import with an empty descriptor.
+func newImportResult(name string) *ImportResult {
+	return &ImportResult{
+		Descriptor: &grpc_application_go.AddAppDescriptorRequest{
+			Name:   name,
+			Labels: map[string]string{},
+			Rules:  make([]*grpc_application_go.SecurityRule, 0),
+			Groups: []*grpc_application_go.ServiceGroup{{
+				Name:     DefaultGroupName,
+				Services: make([]*grpc_application_go.Service, 0),
+				Specs:    &grpc_application_go.ServiceGroupDeploymentSpecs{Replicas: 1},
+			}},
+		},
+		Untranslated: make([]string, 0),
+	}
+}
+
+// addService adds a service to the group of the descriptor.
+func (ir *ImportResult) addService(service *grpc_application_go.Service) {
+	group := ir.Descriptor.Groups[0]
+	group.Services = append(group.Services, service)
+}
+
+// exposePorts creates the security rules of the ports of the services. Public ports get a web endpoint and can be
+// accessed from outside the application, and the rest can be accessed by the other services of the group, as the
+// services of a compose project or a Kubernetes namespace can reach each other.
+func (ir *ImportResult) exposePorts(public map[string]map[int32]bool) {
+	group := ir.Descriptor.Groups[0]
+	for _, service := range group.Services {
+		others := make([]string, 0)
+		for _, other := range group.Services {
+			if other.Name != service.Name {
+				others = append(others, other.Name)
+			}
+		}
+		for _, port := range service.ExposedPorts {
+			if public[service.Name][port.ExposedPort] {
+				port.Endpoints = append(port.Endpoints, &grpc_application_go.Endpoint{
+					Type: grpc_application_go.EndpointType_WEB,
+					Path: "/",
+				})
+				ir.Descriptor.Rules = append(ir.Descriptor.Rules, &grpc_application_go.SecurityRule{
+					Name:                   fmt.Sprintf("allow public access to %s port %d", service.Name, port.ExposedPort),
+					TargetServiceGroupName: group.Name,
+					TargetServiceName:      service.Name,
+					TargetPort:             port.ExposedPort,
+					Access:                 grpc_application_go.PortAccess_PUBLIC,
+				})
+			} else if len(others) > 0 {
+				ir.Descriptor.Rules = append(ir.Descriptor.Rules, &grpc_application_go.SecurityRule{
+					Name:                   fmt.Sprintf("allow access to %s port %d", service.Name, port.ExposedPort),
+					TargetServiceGroupName: group.Name,
+					TargetServiceName:      service.Name,
+					TargetPort:             port.ExposedPort,
+					Access:                 grpc_application_go.PortAccess_APP_SERVICES,
+					AuthServiceGroupName:   group.Name,
+					AuthServices:           others,
+				})
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map sorted.
+func sortedKeys(m map[string]interface{}) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
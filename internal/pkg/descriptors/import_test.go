@@ -0,0 +1,231 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"github.com/nalej/grpc-application-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const composeManifest = `
+version: "3.7"
+services:
+  db:
+    image: mysql:5.7
+    environment:
+      MYSQL_ROOT_PASSWORD: root
+    volumes:
+    - db-data:/var/lib/mysql:ro
+    - ./init.sql:/docker-entrypoint-initdb.d/init.sql
+    expose:
+    - "3306"
+    restart: always
+  wordpress:
+    image: wordpress:5.0.0
+    command: sh -c "echo 'starting  wordpress' && exec apache2-foreground" --name\ with\ spaces
+    depends_on:
+    - db
+    environment:
+    - WORDPRESS_DB_HOST=db:3306
+    ports:
+    - "8080:80"
+    - "5000:5000/udp"
+    tmpfs: /run
+    deploy:
+      replicas: 2
+      resources:
+        limits:
+          cpus: "0.5"
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+volumes:
+  db-data: {}
+`
+
+const kubernetesManifest = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 3
+  template:
+    metadata:
+      labels:
+        app: web
+    spec:
+      containers:
+      - name: nginx
+        image: nginx:1.19
+        args: ["-g", "daemon off;"]
+        env:
+        - name: MODE
+          value: production
+        - name: TOKEN
+          valueFrom:
+            secretKeyRef:
+              name: web
+              key: token
+        ports:
+        - name: http
+          containerPort: 80
+        volumeMounts:
+        - name: cache
+          mountPath: /var/cache/nginx
+        - name: config
+          mountPath: /etc/nginx/conf.d
+      volumes:
+      - name: cache
+        emptyDir: {}
+      - name: config
+        configMap:
+          name: web
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  type: LoadBalancer
+  selector:
+    app: web
+  ports:
+  - port: 8080
+    targetPort: http
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: web
+`
+
+var _ = ginkgo.Describe("Descriptor import", func() {
+
+	serviceByName := func(result *ImportResult, name string) *grpc_application_go.Service {
+		for _, service := range result.Descriptor.Groups[0].Services {
+			if service.Name == name {
+				return service
+			}
+		}
+		return nil
+	}
+
+	ginkgo.It("should translate a compose file", func() {
+		result, err := Import(ComposeFormat, []byte(composeManifest), "wordpress")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Descriptor.Name).To(gomega.Equal("wordpress"))
+		gomega.Expect(result.Descriptor.Groups[0].Services).To(gomega.HaveLen(2))
+
+		db := serviceByName(result, "db")
+		gomega.Expect(db.Image).To(gomega.Equal("mysql:5.7"))
+		gomega.Expect(db.EnvironmentVariables).To(gomega.Equal(map[string]string{"MYSQL_ROOT_PASSWORD": "root"}))
+		gomega.Expect(db.Storage).To(gomega.HaveLen(1))
+		gomega.Expect(db.Storage[0].MountPath).To(gomega.Equal("/var/lib/mysql"))
+		gomega.Expect(db.Storage[0].Type).To(gomega.Equal(grpc_application_go.StorageType_CLUSTER_LOCAL))
+		gomega.Expect(db.ExposedPorts).To(gomega.HaveLen(1))
+		gomega.Expect(db.ExposedPorts[0].Endpoints).To(gomega.BeEmpty())
+
+		wordpress := serviceByName(result, "wordpress")
+		gomega.Expect(wordpress.Specs.Replicas).To(gomega.Equal(int32(2)))
+		gomega.Expect(wordpress.DeployAfter).To(gomega.Equal([]string{"db"}))
+		gomega.Expect(wordpress.RunArguments).To(gomega.Equal([]string{"sh", "-c",
+			"echo 'starting  wordpress' && exec apache2-foreground", "--name with spaces"}))
+		gomega.Expect(wordpress.EnvironmentVariables).To(gomega.HaveKeyWithValue("WORDPRESS_DB_HOST", "db:3306"))
+		gomega.Expect(wordpress.Storage).To(gomega.HaveLen(1))
+		gomega.Expect(wordpress.Storage[0].Type).To(gomega.Equal(grpc_application_go.StorageType_EPHEMERAL))
+		gomega.Expect(wordpress.ExposedPorts).To(gomega.HaveLen(1))
+		gomega.Expect(wordpress.ExposedPorts[0].InternalPort).To(gomega.Equal(int32(80)))
+		gomega.Expect(wordpress.ExposedPorts[0].Endpoints).To(gomega.HaveLen(1))
+
+		gomega.Expect(result.Descriptor.Rules).To(gomega.HaveLen(2))
+		for _, rule := range result.Descriptor.Rules {
+			if rule.TargetServiceName == "db" {
+				gomega.Expect(rule.Access).To(gomega.Equal(grpc_application_go.PortAccess_APP_SERVICES))
+				gomega.Expect(rule.AuthServices).To(gomega.Equal([]string{"wordpress"}))
+			} else {
+				gomega.Expect(rule.Access).To(gomega.Equal(grpc_application_go.PortAccess_PUBLIC))
+				gomega.Expect(rule.TargetPort).To(gomega.Equal(int32(80)))
+			}
+		}
+
+		gomega.Expect(result.Untranslated).To(gomega.ConsistOf(
+			"service db: bind mount ./init.sql:/docker-entrypoint-initdb.d/init.sql",
+			"service db: volume /var/lib/mysql mode ro",
+			"service wordpress: port 80 published on host port 8080",
+			"service wordpress: udp port 5000",
+			"service wordpress: deploy resources",
+			"service wordpress: healthcheck",
+			"top-level volumes",
+		))
+	})
+
+	ginkgo.It("should split compose commands as a shell does", func() {
+		cases := map[string][]string{
+			`nginx -g "daemon off;"`:      {"nginx", "-g", "daemon off;"},
+			`echo 'a "b"' "c \"d\"" e\ f`: {"echo", `a "b"`, `c "d"`, "e f"},
+			`printf "%s\n" ''`:            {"printf", `%s\n`, ""},
+			"run --flag \\\n  value":      {"run", "--flag", "value"},
+		}
+		for line, expected := range cases {
+			words, err := shellWords(line)
+			gomega.Expect(err).To(gomega.Succeed(), line)
+			gomega.Expect(words).To(gomega.Equal(expected), line)
+		}
+		_, err := shellWords(`echo "unterminated`)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should translate Kubernetes manifests", func() {
+		result, err := Import(KubernetesFormat, []byte(kubernetesManifest), "web")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Descriptor.Groups[0].Services).To(gomega.HaveLen(1))
+
+		web := serviceByName(result, "web")
+		gomega.Expect(web.Image).To(gomega.Equal("nginx:1.19"))
+		gomega.Expect(web.Specs.Replicas).To(gomega.Equal(int32(3)))
+		gomega.Expect(web.RunArguments).To(gomega.Equal([]string{"-g", "daemon off;"}))
+		gomega.Expect(web.EnvironmentVariables).To(gomega.Equal(map[string]string{"MODE": "production"}))
+		gomega.Expect(web.Storage).To(gomega.HaveLen(1))
+		gomega.Expect(web.Storage[0].Type).To(gomega.Equal(grpc_application_go.StorageType_EPHEMERAL))
+		gomega.Expect(web.ExposedPorts).To(gomega.HaveLen(1))
+		gomega.Expect(web.ExposedPorts[0].InternalPort).To(gomega.Equal(int32(80)))
+		gomega.Expect(web.ExposedPorts[0].ExposedPort).To(gomega.Equal(int32(8080)))
+		gomega.Expect(web.ExposedPorts[0].Endpoints).To(gomega.HaveLen(1))
+		gomega.Expect(result.Descriptor.Rules).To(gomega.HaveLen(1))
+		gomega.Expect(result.Descriptor.Rules[0].Access).To(gomega.Equal(grpc_application_go.PortAccess_PUBLIC))
+		gomega.Expect(result.Descriptor.Rules[0].TargetPort).To(gomega.Equal(int32(8080)))
+
+		gomega.Expect(result.Untranslated).To(gomega.ConsistOf(
+			"Deployment web: volume config",
+			"Deployment web: environment variable TOKEN from a reference",
+			"ConfigMap web",
+		))
+	})
+
+	ginkgo.It("should fail on unsupported formats", func() {
+		_, err := Import("helm", []byte(""), "app")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should fail on manifests without workloads", func() {
+		_, err := Import(ComposeFormat, []byte("version: '3'\n"), "app")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = Import(KubernetesFormat, []byte("kind: ConfigMap\nmetadata:\n  name: config\n"), "app")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
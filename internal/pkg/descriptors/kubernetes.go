@@ -0,0 +1,340 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"gopkg.in/yaml.v2"
+	"io"
+	"strconv"
+)
+
+// k8sMetadata with the metadata of a Kubernetes object.
+type k8sMetadata struct {
+	Name   string            `yaml:"name"`
+	Labels map[string]string `yaml:"labels"`
+}
+
+// k8sKind is used to decode the kind of an object before decoding the rest of it.
+type k8sKind struct {
+	Kind     string      `yaml:"kind"`
+	Metadata k8sMetadata `yaml:"metadata"`
+}
+
+// k8sEnvVar with an environment variable of a container.
+type k8sEnvVar struct {
+	Name      string      `yaml:"name"`
+	Value     string      `yaml:"value"`
+	ValueFrom interface{} `yaml:"valueFrom"`
+}
+
+// k8sContainerPort with a port of a container.
+type k8sContainerPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int32  `yaml:"containerPort"`
+	Protocol      string `yaml:"protocol"`
+}
+
+// k8sVolumeMount with a volume mounted in a container.
+type k8sVolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+}
+
+// k8sContainer with the elements of a container.
+type k8sContainer struct {
+	Name         string                 `yaml:"name"`
+	Image        string                 `yaml:"image"`
+	Args         []string               `yaml:"args"`
+	Env          []k8sEnvVar            `yaml:"env"`
+	Ports        []k8sContainerPort     `yaml:"ports"`
+	VolumeMounts []k8sVolumeMount       `yaml:"volumeMounts"`
+	Other        map[string]interface{} `yaml:",inline"`
+}
+
+// k8sVolume with a volume of a pod.
+type k8sVolume struct {
+	Name                  string      `yaml:"name"`
+	EmptyDir              interface{} `yaml:"emptyDir"`
+	PersistentVolumeClaim interface{} `yaml:"persistentVolumeClaim"`
+}
+
+// k8sPodSpec with the elements of a pod template.
+type k8sPodSpec struct {
+	Containers []k8sContainer         `yaml:"containers"`
+	Volumes    []k8sVolume            `yaml:"volumes"`
+	Other      map[string]interface{} `yaml:",inline"`
+}
+
+// k8sWorkload with the elements of a Deployment or StatefulSet.
+type k8sWorkload struct {
+	Metadata k8sMetadata `yaml:"metadata"`
+	Spec     struct {
+		Replicas *int32 `yaml:"replicas"`
+		Template struct {
+			Metadata k8sMetadata `yaml:"metadata"`
+			Spec     k8sPodSpec  `yaml:"spec"`
+		} `yaml:"template"`
+		VolumeClaimTemplates []struct {
+			Metadata k8sMetadata `yaml:"metadata"`
+		} `yaml:"volumeClaimTemplates"`
+	} `yaml:"spec"`
+}
+
+// k8sServicePort with a port of a Kubernetes service.
+type k8sServicePort struct {
+	Port       int32       `yaml:"port"`
+	TargetPort interface{} `yaml:"targetPort"`
+	Protocol   string      `yaml:"protocol"`
+}
+
+// k8sService with the elements of a Kubernetes service.
+type k8sService struct {
+	Metadata k8sMetadata `yaml:"metadata"`
+	Spec     struct {
+		Type     string            `yaml:"type"`
+		Selector map[string]string `yaml:"selector"`
+		Ports    []k8sServicePort  `yaml:"ports"`
+	} `yaml:"spec"`
+}
+
+// k8sImport contains the state of the translation of Kubernetes manifests.
+type k8sImport struct {
+	result *ImportResult
+	// podLabels with the labels of the pods of each service.
+	podLabels map[string]map[string]string
+	// containerPorts with the ports of the containers of each service by name.
+	containerPorts map[string]map[string]int32
+	public         map[string]map[int32]bool
+}
+
+// addWorkload translates the containers of a Deployment or StatefulSet into services. A pod with several containers
+// becomes several services as the platform does not share the network of a pod.
+func (ki *k8sImport) addWorkload(kind string, workload k8sWorkload) {
+	name := workload.Metadata.Name
+	pod := workload.Spec.Template.Spec
+	volumes := make(map[string]*grpc_application_go.StorageType, 0)
+	for _, volume := range pod.Volumes {
+		switch {
+		case volume.EmptyDir != nil:
+			storageType := grpc_application_go.StorageType_EPHEMERAL
+			volumes[volume.Name] = &storageType
+		case volume.PersistentVolumeClaim != nil:
+			storageType := grpc_application_go.StorageType_CLUSTER_LOCAL
+			volumes[volume.Name] = &storageType
+		default:
+			ki.result.untranslated("%s %s: volume %s", kind, name, volume.Name)
+		}
+	}
+	for _, claim := range workload.Spec.VolumeClaimTemplates {
+		storageType := grpc_application_go.StorageType_CLUSTER_LOCAL
+		volumes[claim.Metadata.Name] = &storageType
+	}
+	for _, key := range sortedKeys(pod.Other) {
+		ki.result.untranslated("%s %s: pod %s", kind, name, key)
+	}
+	if len(pod.Containers) > 1 {
+		ki.result.untranslated("%s %s: the containers of the pod are deployed as separate services", kind, name)
+	}
+
+	for _, container := range pod.Containers {
+		serviceName := name
+		if len(pod.Containers) > 1 {
+			serviceName = name + "-" + container.Name
+		}
+		service := &grpc_application_go.Service{
+			Name:                 serviceName,
+			Type:                 grpc_application_go.ServiceType_DOCKER,
+			Image:                container.Image,
+			Specs:                &grpc_application_go.DeploySpecs{Replicas: 1},
+			EnvironmentVariables: make(map[string]string, 0),
+			Labels:               workload.Spec.Template.Metadata.Labels,
+			RunArguments:         container.Args,
+			Storage:              make([]*grpc_application_go.Storage, 0),
+			ExposedPorts:         make([]*grpc_application_go.Port, 0),
+		}
+		if workload.Spec.Replicas != nil {
+			service.Specs.Replicas = *workload.Spec.Replicas
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil {
+				ki.result.untranslated("%s %s: environment variable %s from a reference", kind, name, env.Name)
+				continue
+			}
+			service.EnvironmentVariables[env.Name] = env.Value
+		}
+		ki.containerPorts[serviceName] = make(map[string]int32, 0)
+		for _, port := range container.Ports {
+			if port.Protocol != "" && port.Protocol != "TCP" {
+				ki.result.untranslated("%s %s: %s port %d", kind, name, port.Protocol, port.ContainerPort)
+				continue
+			}
+			portName := port.Name
+			if portName == "" {
+				portName = fmt.Sprintf("%s-%d", serviceName, port.ContainerPort)
+			}
+			ki.containerPorts[serviceName][portName] = port.ContainerPort
+			service.ExposedPorts = append(service.ExposedPorts, &grpc_application_go.Port{
+				Name:         portName,
+				InternalPort: port.ContainerPort,
+				ExposedPort:  port.ContainerPort,
+			})
+		}
+		for _, mount := range container.VolumeMounts {
+			storageType, exists := volumes[mount.Name]
+			if !exists {
+				continue
+			}
+			storage := &grpc_application_go.Storage{MountPath: mount.MountPath, Type: *storageType}
+			if *storageType == grpc_application_go.StorageType_CLUSTER_LOCAL {
+				storage.Size = DefaultVolumeSize
+			}
+			service.Storage = append(service.Storage, storage)
+		}
+		for _, key := range sortedKeys(container.Other) {
+			ki.result.untranslated("%s %s: container %s %s", kind, name, container.Name, key)
+		}
+		ki.podLabels[serviceName] = workload.Spec.Template.Metadata.Labels
+		ki.result.addService(service)
+	}
+}
+
+// selects checks if a selector matches the labels of a pod.
+func selects(selector map[string]string, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// addService applies a Kubernetes service to the ports of the services whose pods it selects. LoadBalancer and
+// NodePort services expose the ports publicly.
+func (ki *k8sImport) addService(service k8sService) {
+	public := service.Spec.Type == "LoadBalancer" || service.Spec.Type == "NodePort"
+	matched := false
+	for _, target := range ki.result.Descriptor.Groups[0].Services {
+		if !selects(service.Spec.Selector, ki.podLabels[target.Name]) {
+			continue
+		}
+		matched = true
+		for _, servicePort := range service.Spec.Ports {
+			if servicePort.Protocol != "" && servicePort.Protocol != "TCP" {
+				ki.result.untranslated("Service %s: %s port %d", service.Metadata.Name, servicePort.Protocol, servicePort.Port)
+				continue
+			}
+			containerPort := servicePort.Port
+			if servicePort.TargetPort != nil {
+				targetPort := fmt.Sprint(servicePort.TargetPort)
+				if byName, exists := ki.containerPorts[target.Name][targetPort]; exists {
+					containerPort = byName
+				} else if number, err := strconv.ParseInt(targetPort, 10, 32); err == nil {
+					containerPort = int32(number)
+				}
+			}
+			found := false
+			for _, port := range target.ExposedPorts {
+				if port.InternalPort == containerPort {
+					port.ExposedPort = servicePort.Port
+					found = true
+				}
+			}
+			if !found {
+				target.ExposedPorts = append(target.ExposedPorts, &grpc_application_go.Port{
+					Name:         fmt.Sprintf("%s-%d", target.Name, containerPort),
+					InternalPort: containerPort,
+					ExposedPort:  servicePort.Port,
+				})
+			}
+			if public {
+				if ki.public[target.Name] == nil {
+					ki.public[target.Name] = make(map[int32]bool, 0)
+				}
+				ki.public[target.Name][servicePort.Port] = true
+			}
+		}
+	}
+	if !matched {
+		ki.result.untranslated("Service %s: does not select any imported workload", service.Metadata.Name)
+	}
+}
+
+// FromKubernetes translates Kubernetes manifests into a descriptor. Deployments and StatefulSets become services of
+// the same group, and Services set the exposed ports of the pods they select. Empty dirs become ephemeral storage and
+// persistent volume claims cluster local storage.
+func FromKubernetes(content []byte, name string) (*ImportResult, derrors.Error) {
+	ki := &k8sImport{
+		result:         newImportResult(name),
+		podLabels:      make(map[string]map[string]string, 0),
+		containerPorts: make(map[string]map[string]int32, 0),
+		public:         make(map[string]map[int32]bool, 0),
+	}
+	services := make([]k8sService, 0)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var document interface{}
+		err := decoder.Decode(&document)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, derrors.NewInvalidArgumentError("cannot parse Kubernetes manifest", err)
+		}
+		if document == nil {
+			continue
+		}
+		raw, err := yaml.Marshal(document)
+		if err != nil {
+			return nil, derrors.NewInternalError("cannot process Kubernetes manifest", err)
+		}
+		kind := k8sKind{}
+		if err := yaml.Unmarshal(raw, &kind); err != nil {
+			return nil, derrors.NewInvalidArgumentError("cannot parse Kubernetes manifest", err)
+		}
+		switch kind.Kind {
+		case "Deployment", "StatefulSet":
+			workload := k8sWorkload{}
+			if err := yaml.Unmarshal(raw, &workload); err != nil {
+				return nil, derrors.NewInvalidArgumentError("cannot parse Kubernetes workload", err).WithParams(kind.Metadata.Name)
+			}
+			ki.addWorkload(kind.Kind, workload)
+		case "Service":
+			service := k8sService{}
+			if err := yaml.Unmarshal(raw, &service); err != nil {
+				return nil, derrors.NewInvalidArgumentError("cannot parse Kubernetes service", err).WithParams(kind.Metadata.Name)
+			}
+			services = append(services, service)
+		default:
+			ki.result.untranslated("%s %s", kind.Kind, kind.Metadata.Name)
+		}
+	}
+	if len(ki.result.Descriptor.Groups[0].Services) == 0 {
+		return nil, derrors.NewInvalidArgumentError("Kubernetes manifests do not contain Deployments or StatefulSets")
+	}
+	for _, service := range services {
+		ki.addService(service)
+	}
+	ki.result.exposePorts(ki.public)
+	return ki.result, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+)
+
+// Exposures of the ports of a scaffolded service.
+const (
+	// ExposePublic exposes the ports outside the application with a web endpoint.
+	ExposePublic = "public"
+	// ExposeApp exposes the ports to all the services of the application.
+	ExposeApp = "app"
+	// ExposeNone does not create security rules for the ports.
+	ExposeNone = "none"
+)
+
+// ServiceSpec with the answers describing a service of a new descriptor.
+type ServiceSpec struct {
+	Group    string
+	Name     string
+	Image    string
+	Replicas int32
+	Ports    []int32
+	Expose   string
+	// Storage of the service, if any.
+	Storage *grpc_application_go.Storage
+}
+
+// ScaffoldSpec with the answers describing a new descriptor.
+type ScaffoldSpec struct {
+	Name     string
+	Services []ServiceSpec
+}
+
+// validate checks the answers describing a service.
+func (ss ServiceSpec) validate() derrors.Error {
+	if ss.Group == "" || ss.Name == "" {
+		return derrors.NewInvalidArgumentError("services require a group and a name").WithParams(ss.Group, ss.Name)
+	}
+	if ss.Image == "" {
+		return derrors.NewInvalidArgumentError("services require an image").WithParams(ss.Name)
+	}
+	if ss.Replicas < 0 {
+		return derrors.NewInvalidArgumentError("replicas cannot be negative").WithParams(ss.Name)
+	}
+	seen := make(map[int32]bool, 0)
+	for _, port := range ss.Ports {
+		if port <= 0 || port > 65535 {
+			return derrors.NewInvalidArgumentError("invalid port").WithParams(ss.Name, port)
+		}
+		if seen[port] {
+			return derrors.NewInvalidArgumentError("duplicated port").WithParams(ss.Name, port)
+		}
+		seen[port] = true
+	}
+	switch ss.Expose {
+	case "", ExposePublic, ExposeApp, ExposeNone:
+	default:
+		return derrors.NewInvalidArgumentError("exposure must be public, app or none").WithParams(ss.Name, ss.Expose)
+	}
+	if ss.Storage != nil && ss.Storage.MountPath == "" {
+		return derrors.NewInvalidArgumentError("storage requires a mount path").WithParams(ss.Name)
+	}
+	return nil
+}
+
+// Scaffold builds a new descriptor from the answers of the descriptor wizard. Groups are created in the order their
+// services appear. Ports are exposed to the application services unless set otherwise.
+func Scaffold(spec ScaffoldSpec) (*grpc_application_go.AddAppDescriptorRequest, derrors.Error) {
+	if spec.Name == "" {
+		return nil, derrors.NewInvalidArgumentError("descriptor name cannot be empty")
+	}
+	if len(spec.Services) == 0 {
+		return nil, derrors.NewInvalidArgumentError("expecting at least one service")
+	}
+	result := &grpc_application_go.AddAppDescriptorRequest{
+		Name:   spec.Name,
+		Labels: map[string]string{"app": spec.Name},
+		Rules:  make([]*grpc_application_go.SecurityRule, 0),
+		Groups: make([]*grpc_application_go.ServiceGroup, 0),
+	}
+	groups := make(map[string]*grpc_application_go.ServiceGroup, 0)
+	names := make(map[string]bool, 0)
+	for _, ss := range spec.Services {
+		if err := ss.validate(); err != nil {
+			return nil, err
+		}
+		if names[ss.Group+"/"+ss.Name] {
+			return nil, derrors.NewInvalidArgumentError("duplicated service").WithParams(ss.Group, ss.Name)
+		}
+		names[ss.Group+"/"+ss.Name] = true
+
+		group, exists := groups[ss.Group]
+		if !exists {
+			group = &grpc_application_go.ServiceGroup{
+				Name:     ss.Group,
+				Services: make([]*grpc_application_go.Service, 0),
+				Specs:    &grpc_application_go.ServiceGroupDeploymentSpecs{Replicas: 1},
+			}
+			groups[ss.Group] = group
+			result.Groups = append(result.Groups, group)
+		}
+
+		replicas := ss.Replicas
+		if replicas == 0 {
+			replicas = 1
+		}
+		service := &grpc_application_go.Service{
+			Name:         ss.Name,
+			Type:         grpc_application_go.ServiceType_DOCKER,
+			Image:        ss.Image,
+			Specs:        &grpc_application_go.DeploySpecs{Replicas: replicas},
+			Labels:       map[string]string{"app": spec.Name, "component": ss.Name},
+			Storage:      make([]*grpc_application_go.Storage, 0),
+			ExposedPorts: make([]*grpc_application_go.Port, 0),
+		}
+		if ss.Storage != nil {
+			service.Storage = append(service.Storage, ss.Storage)
+		}
+		for _, number := range ss.Ports {
+			port := &grpc_application_go.Port{
+				Name:         fmt.Sprintf("%s-%d", ss.Name, number),
+				InternalPort: number,
+				ExposedPort:  number,
+			}
+			rule := &grpc_application_go.SecurityRule{
+				TargetServiceGroupName: ss.Group,
+				TargetServiceName:      ss.Name,
+				TargetPort:             number,
+			}
+			switch ss.Expose {
+			case ExposePublic:
+				port.Endpoints = []*grpc_application_go.Endpoint{{Type: grpc_application_go.EndpointType_WEB, Path: "/"}}
+				rule.Name = fmt.Sprintf("allow public access to %s port %d", ss.Name, number)
+				rule.Access = grpc_application_go.PortAccess_PUBLIC
+			case ExposeNone:
+				rule = nil
+			default:
+				rule.Name = fmt.Sprintf("allow access to %s port %d", ss.Name, number)
+				rule.Access = grpc_application_go.PortAccess_ALL_APP_SERVICES
+			}
+			service.ExposedPorts = append(service.ExposedPorts, port)
+			if rule != nil {
+				result.Rules = append(result.Rules, rule)
+			}
+		}
+		group.Services = append(group.Services, service)
+	}
+	return result, nil
+}
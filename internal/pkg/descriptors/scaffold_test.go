@@ -0,0 +1,73 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"github.com/nalej/grpc-application-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Descriptor scaffolding", func() {
+
+	ginkgo.It("should build the groups, services and rules", func() {
+		descriptor, err := Scaffold(ScaffoldSpec{
+			Name: "wordpress",
+			Services: []ServiceSpec{
+				{Group: "db", Name: "mysql", Image: "mysql:5.7", Ports: []int32{3306},
+					Storage: &grpc_application_go.Storage{MountPath: "/var/lib/mysql", Type: grpc_application_go.StorageType_CLUSTER_LOCAL}},
+				{Group: "web", Name: "wordpress", Image: "wordpress:5", Replicas: 2, Ports: []int32{80}, Expose: ExposePublic},
+				{Group: "web", Name: "worker", Image: "worker:1", Ports: []int32{9000}, Expose: ExposeNone},
+			},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(descriptor.Groups).To(gomega.HaveLen(2))
+		gomega.Expect(descriptor.Groups[0].Name).To(gomega.Equal("db"))
+		gomega.Expect(descriptor.Groups[1].Services).To(gomega.HaveLen(2))
+
+		mysql := descriptor.Groups[0].Services[0]
+		gomega.Expect(mysql.Specs.Replicas).To(gomega.Equal(int32(1)))
+		gomega.Expect(mysql.Storage).To(gomega.HaveLen(1))
+		wordpress := descriptor.Groups[1].Services[0]
+		gomega.Expect(wordpress.Specs.Replicas).To(gomega.Equal(int32(2)))
+		gomega.Expect(wordpress.ExposedPorts[0].Endpoints).To(gomega.HaveLen(1))
+
+		gomega.Expect(descriptor.Rules).To(gomega.HaveLen(2))
+		gomega.Expect(descriptor.Rules[0].Access).To(gomega.Equal(grpc_application_go.PortAccess_ALL_APP_SERVICES))
+		gomega.Expect(descriptor.Rules[0].TargetServiceGroupName).To(gomega.Equal("db"))
+		gomega.Expect(descriptor.Rules[1].Access).To(gomega.Equal(grpc_application_go.PortAccess_PUBLIC))
+		gomega.Expect(descriptor.Rules[1].TargetPort).To(gomega.Equal(int32(80)))
+	})
+
+	ginkgo.It("should reject invalid answers", func() {
+		valid := ServiceSpec{Group: "g1", Name: "s1", Image: "nginx"}
+		invalid := []ScaffoldSpec{
+			{Services: []ServiceSpec{valid}},
+			{Name: "app"},
+			{Name: "app", Services: []ServiceSpec{{Group: "g1", Name: "s1"}}},
+			{Name: "app", Services: []ServiceSpec{valid, valid}},
+			{Name: "app", Services: []ServiceSpec{{Group: "g1", Name: "s1", Image: "nginx", Ports: []int32{80, 80}}}},
+			{Name: "app", Services: []ServiceSpec{{Group: "g1", Name: "s1", Image: "nginx", Ports: []int32{70000}}}},
+			{Name: "app", Services: []ServiceSpec{{Group: "g1", Name: "s1", Image: "nginx", Expose: "everyone"}}},
+			{Name: "app", Services: []ServiceSpec{{Group: "g1", Name: "s1", Image: "nginx", Storage: &grpc_application_go.Storage{}}}},
+		}
+		for _, spec := range invalid {
+			_, err := Scaffold(spec)
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		}
+	})
+})
@@ -0,0 +1,384 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package descriptors renders application descriptors written as YAML or JSON templates. Templates may use variables
+// as ${NAME} or ${NAME:-default}, include shared fragments with {include: path} and extend a base fragment with
+// {extends: path}. Variables are substituted inside the string values and keys of the parsed template, and a variable
+// that is a whole unquoted value keeps the type of a number or boolean. The result is the JSON of an
+// AddAppDescriptorRequest.
+package descriptors
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/nalej/derrors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// IncludeKey with the key of the elements that are replaced by the content of a fragment. An include found in a list
+// whose fragment is a list is expanded into the elements of the fragment.
+const IncludeKey = "include"
+
+// ExtendsKey with the key of the elements that are merged on top of a base fragment. Nested objects are merged, and
+// any other value replaces the one of the fragment.
+const ExtendsKey = "extends"
+
+// MaxDepth with the maximum depth of nested fragments.
+const MaxDepth = 16
+
+// variablePattern matches the variables of a template, and the escaped $${ sequence producing a literal ${.
+var variablePattern = regexp.MustCompile(`\$\$\{|\$\{([A-Za-z_][A-Za-z0-9_.]*)(?::-([^}]*))?\}`)
+
+// Values with the variables available to the templates. Variables not found are read from the environment.
+type Values map[string]string
+
+// LoadValues reads the variables of a YAML or JSON values file. Nested objects are flattened joining their keys
+// with dots, so {db: {user: root}} defines db.user.
+func LoadValues(path string) (Values, derrors.Error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read values file")
+	}
+	raw := make(map[interface{}]interface{}, 0)
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot parse values file", err).WithParams(path)
+	}
+	result := make(Values, 0)
+	flatten("", normalize(raw).(map[string]interface{}), result)
+	return result, nil
+}
+
+// flatten adds the values of a nested object with their keys joined by dots.
+func flatten(prefix string, source map[string]interface{}, result Values) {
+	for key, value := range source {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]interface{}:
+			flatten(key, v, result)
+		case nil:
+			result[key] = ""
+		default:
+			result[key] = fmt.Sprint(v)
+		}
+	}
+}
+
+// lookup returns the value of a variable.
+func (v Values) lookup(name string) (string, bool) {
+	if value, exists := v[name]; exists {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// replaceVariables replaces the variables of a template with the text returned by a function receiving the position
+// of the variable and its value. All the undefined variables without a default value are reported in the error.
+func (v Values) replaceVariables(content []byte, replacement func(start int, end int, value string) string) ([]byte, derrors.Error) {
+	missing := make(map[string]bool, 0)
+	var result bytes.Buffer
+	last := 0
+	for _, loc := range variablePattern.FindAllSubmatchIndex(content, -1) {
+		result.Write(content[last:loc[0]])
+		last = loc[1]
+		if string(content[loc[0]:loc[1]]) == "$${" {
+			result.WriteString("${")
+			continue
+		}
+		name := string(content[loc[2]:loc[3]])
+		value, exists := v.lookup(name)
+		if !exists && loc[4] >= 0 {
+			value, exists = string(content[loc[4]:loc[5]]), true
+		}
+		if !exists {
+			missing[name] = true
+			continue
+		}
+		result.WriteString(replacement(loc[0], loc[1], value))
+	}
+	result.Write(content[last:])
+	if len(missing) > 0 {
+		names := make([]string, 0, len(missing))
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, derrors.NewInvalidArgumentError("undefined template variables").WithParams(names)
+	}
+	return result.Bytes(), nil
+}
+
+// Substitute replaces the variables of a text. All the undefined variables without a default value are reported in
+// the error.
+func (v Values) Substitute(content []byte) ([]byte, derrors.Error) {
+	return v.replaceVariables(content, func(_ int, _ int, value string) string {
+		return value
+	})
+}
+
+// placeholderFormat with the text that stands for a variable while a template is parsed. Placeholders are plain
+// words, so the value of a variable cannot change the structure of the document.
+const placeholderFormat = "__template_variable_%d__"
+
+// placeholderPattern matches the placeholders of a parsed template.
+var placeholderPattern = regexp.MustCompile(`__template_variable_(\d+)__`)
+
+// variable with the value that replaces a placeholder.
+type variable struct {
+	value string
+	// quoted is set when the variable is the whole content of a quoted scalar.
+	quoted bool
+}
+
+// placeholders replaces the variables of a template with placeholders, returning the values that replace them.
+func (v Values) placeholders(content []byte) ([]byte, []variable, derrors.Error) {
+	variables := make([]variable, 0)
+	result, err := v.replaceVariables(content, func(start int, end int, value string) string {
+		quoted := start > 0 && end < len(content) && content[start-1] == content[end] &&
+			(content[end] == '"' || content[end] == '\'')
+		variables = append(variables, variable{value: value, quoted: quoted})
+		return fmt.Sprintf(placeholderFormat, len(variables)-1)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, variables, nil
+}
+
+// typedValue returns the value of a variable that is the whole content of a plain scalar. Numbers and booleans
+// keep their type, and any other value is a string.
+func typedValue(value string) interface{} {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(value), &decoded); err == nil {
+		switch decoded.(type) {
+		case int, int64, uint64, float64, bool:
+			return decoded
+		}
+	}
+	return value
+}
+
+// expand replaces the placeholders found in the strings and keys of a parsed template with the values of the
+// variables.
+func expand(value interface{}, variables []variable) interface{} {
+	replace := func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			index, _ := strconv.Atoi(placeholderPattern.FindStringSubmatch(match)[1])
+			return variables[index].value
+		})
+	}
+	switch v := value.(type) {
+	case string:
+		if groups := placeholderPattern.FindStringSubmatch(v); groups != nil && groups[0] == v {
+			index, _ := strconv.Atoi(groups[1])
+			if !variables[index].quoted {
+				return typedValue(variables[index].value)
+			}
+		}
+		return replace(v)
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[replace(key)] = expand(item, variables)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = expand(item, variables)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// normalize converts the objects decoded by the YAML parser into objects with string keys that can be encoded as JSON.
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalize(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalize(item)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// merge returns the result of merging an object on top of a base one.
+func merge(base map[string]interface{}, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for key, value := range base {
+		result[key] = value
+	}
+	for key, value := range override {
+		baseMap, baseIsMap := result[key].(map[string]interface{})
+		overrideMap, overrideIsMap := value.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			result[key] = merge(baseMap, overrideMap)
+		} else {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// renderer resolves the fragments of a template.
+type renderer struct {
+	values Values
+	// stack with the absolute paths of the files being rendered, to detect cycles.
+	stack []string
+}
+
+// load reads, substitutes and resolves a file. Variables are replaced inside the strings of the parsed file, so their
+// values cannot add elements to the descriptor. Relative paths are resolved from the directory of the file
+// referencing them.
+func (r *renderer) load(path string, dir string) (interface{}, derrors.Error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot resolve template path")
+	}
+	for _, previous := range r.stack {
+		if previous == absPath {
+			return nil, derrors.NewInvalidArgumentError("template fragments include themselves").WithParams(append(r.stack, absPath))
+		}
+	}
+	if len(r.stack) >= MaxDepth {
+		return nil, derrors.NewInvalidArgumentError("too many nested template fragments").WithParams(absPath)
+	}
+	content, err := ioutil.ReadFile(absPath)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read template")
+	}
+	parsed, variables, sErr := r.values.placeholders(content)
+	if sErr != nil {
+		return nil, sErr
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(parsed, &raw); err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot parse template", err).WithParams(absPath)
+	}
+	r.stack = append(r.stack, absPath)
+	defer func() { r.stack = r.stack[:len(r.stack)-1] }()
+	return r.resolve(expand(normalize(raw), variables), filepath.Dir(absPath))
+}
+
+// fragmentPath returns the path of a fragment referenced by a key.
+func fragmentPath(object map[string]interface{}, key string) (string, derrors.Error) {
+	path, ok := object[key].(string)
+	if !ok || path == "" {
+		return "", derrors.NewInvalidArgumentError(fmt.Sprintf("%s must be the path of a template fragment", key))
+	}
+	return path, nil
+}
+
+// resolve replaces the includes and extends of a decoded template.
+func (r *renderer) resolve(value interface{}, dir string) (interface{}, derrors.Error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, exists := v[IncludeKey]; exists {
+			if len(v) != 1 {
+				return nil, derrors.NewInvalidArgumentError("include cannot be combined with other keys, use extends instead")
+			}
+			path, err := fragmentPath(v, IncludeKey)
+			if err != nil {
+				return nil, err
+			}
+			return r.load(path, dir)
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if key == ExtendsKey {
+				continue
+			}
+			resolved, err := r.resolve(item, dir)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = resolved
+		}
+		if _, exists := v[ExtendsKey]; !exists {
+			return result, nil
+		}
+		path, err := fragmentPath(v, ExtendsKey)
+		if err != nil {
+			return nil, err
+		}
+		base, err := r.load(path, dir)
+		if err != nil {
+			return nil, err
+		}
+		baseObject, ok := base.(map[string]interface{})
+		if !ok {
+			return nil, derrors.NewInvalidArgumentError("extended template fragments must be objects").WithParams(path)
+		}
+		return merge(baseObject, result), nil
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			object, isObject := item.(map[string]interface{})
+			_, isInclude := object[IncludeKey]
+			resolved, err := r.resolve(item, dir)
+			if err != nil {
+				return nil, err
+			}
+			if list, isList := resolved.([]interface{}); isObject && isInclude && isList {
+				result = append(result, list...)
+			} else {
+				result = append(result, resolved)
+			}
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
+// Render renders the template of a descriptor into JSON.
+func Render(path string, values Values) ([]byte, derrors.Error) {
+	r := &renderer{values: values, stack: make([]string, 0)}
+	rendered, err := r.load(path, "")
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := rendered.(map[string]interface{}); !ok {
+		return nil, derrors.NewInvalidArgumentError("descriptor must be an object").WithParams(path)
+	}
+	result, jErr := json.Marshal(rendered)
+	if jErr != nil {
+		return nil, derrors.AsError(jErr, "cannot encode rendered descriptor")
+	}
+	return result, nil
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package descriptors
+
+import (
+	"encoding/json"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+var _ = ginkgo.Describe("Descriptor templates", func() {
+
+	var dir string
+
+	write := func(name string, content string) string {
+		path := filepath.Join(dir, name)
+		gomega.Expect(os.MkdirAll(filepath.Dir(path), 0700)).To(gomega.Succeed())
+		gomega.Expect(ioutil.WriteFile(path, []byte(content), 0600)).To(gomega.Succeed())
+		return path
+	}
+
+	render := func(path string, values Values) map[string]interface{} {
+		rendered, err := Render(path, values)
+		gomega.Expect(err).To(gomega.Succeed())
+		result := make(map[string]interface{}, 0)
+		gomega.Expect(json.Unmarshal(rendered, &result)).To(gomega.Succeed())
+		return result
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "descriptors")
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	ginkgo.It("should substitute the variables from the values, the environment and the defaults", func() {
+		os.Setenv("DESCRIPTORS_TEST_TAG", "5.7")
+		defer os.Unsetenv("DESCRIPTORS_TEST_TAG")
+		result, err := Values{"name": "wordpress", "db.replicas": "2"}.Substitute(
+			[]byte("${name} mysql:${DESCRIPTORS_TEST_TAG} ${db.replicas} ${port:-3306} $${literal}"))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(string(result)).To(gomega.Equal("wordpress mysql:5.7 2 3306 ${literal}"))
+	})
+
+	ginkgo.It("should report all the undefined variables", func() {
+		_, err := Values{}.Substitute([]byte("${first} ${second} ${first} ${third:-}"))
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(err.DebugReport()).To(gomega.ContainSubstring("first"))
+		gomega.Expect(err.DebugReport()).To(gomega.ContainSubstring("second"))
+		gomega.Expect(err.DebugReport()).NotTo(gomega.ContainSubstring("third"))
+	})
+
+	ginkgo.It("should flatten the values files", func() {
+		path := write("values.yaml", "name: wordpress\ndb:\n  replicas: 2\n  image: mysql\n")
+		values, err := LoadValues(path)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(values).To(gomega.Equal(Values{"name": "wordpress", "db.replicas": "2", "db.image": "mysql"}))
+	})
+
+	ginkgo.It("should render a YAML descriptor with includes and extends", func() {
+		write("fragments/service.yaml", "type: 0\nspecs:\n  replicas: 1\nlabels:\n  tier: backend\n")
+		write("fragments/cache.yaml", "- name: cache\n  services:\n  - name: redis\n    image: redis:5\n")
+		path := write("app.yaml", `
+name: ${name}
+labels:
+  app: ${name}
+groups:
+- name: db
+  services:
+  - extends: fragments/service.yaml
+    name: mysql
+    image: mysql:${tag:-5.6}
+    specs:
+      replicas: ${replicas}
+- include: fragments/cache.yaml
+`)
+		result := render(path, Values{"name": "wordpress", "replicas": "3"})
+		gomega.Expect(result["name"]).To(gomega.Equal("wordpress"))
+		groups := result["groups"].([]interface{})
+		gomega.Expect(groups).To(gomega.HaveLen(2))
+		service := groups[0].(map[string]interface{})["services"].([]interface{})[0].(map[string]interface{})
+		gomega.Expect(service["image"]).To(gomega.Equal("mysql:5.6"))
+		gomega.Expect(service["type"]).To(gomega.BeNumerically("==", 0))
+		gomega.Expect(service["specs"]).To(gomega.Equal(map[string]interface{}{"replicas": float64(3)}))
+		gomega.Expect(service["labels"]).To(gomega.Equal(map[string]interface{}{"tier": "backend"}))
+		gomega.Expect(service).NotTo(gomega.HaveKey(ExtendsKey))
+		gomega.Expect(groups[1].(map[string]interface{})["name"]).To(gomega.Equal("cache"))
+	})
+
+	ginkgo.It("should render JSON descriptors", func() {
+		path := write("app.json", `{"name": "${name}", "groups": []}`)
+		result := render(path, Values{"name": "wordpress"})
+		gomega.Expect(result["name"]).To(gomega.Equal("wordpress"))
+	})
+
+	ginkgo.It("should keep values with newlines and colons inside their strings", func() {
+		path := write("app.yaml", `
+name: ${name}
+labels:
+  description: ${description}
+  quoted: "${replicas}"
+  ${key}: value
+groups: []
+`)
+		result := render(path, Values{
+			"name":        "wordpress\ngroups:\n- name: injected",
+			"description": "first line\nkey: value\n- item",
+			"replicas":    "3",
+			"key":         "a: b",
+		})
+		gomega.Expect(result["name"]).To(gomega.Equal("wordpress\ngroups:\n- name: injected"))
+		gomega.Expect(result["groups"]).To(gomega.BeEmpty())
+		gomega.Expect(result["labels"]).To(gomega.Equal(map[string]interface{}{
+			"description": "first line\nkey: value\n- item",
+			"quoted":      "3",
+			"a: b":        "value",
+		}))
+	})
+
+	ginkgo.It("should keep values with quotes inside the strings of JSON descriptors", func() {
+		path := write("app.json", `{"name": "${name}", "labels": {"image": "mysql:${tag}"}, "groups": []}`)
+		result := render(path, Values{"name": `word", "groups": [{"name": "injected"}], "x": "`, "tag": "5.7"})
+		gomega.Expect(result["name"]).To(gomega.Equal(`word", "groups": [{"name": "injected"}], "x": "`))
+		gomega.Expect(result["labels"]).To(gomega.Equal(map[string]interface{}{"image": "mysql:5.7"}))
+		gomega.Expect(result["groups"]).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should fail on fragments that include themselves", func() {
+		write("a.yaml", "include: b.yaml\n")
+		write("b.yaml", "include: a.yaml\n")
+		path := write("app.yaml", "name: app\ngroups:\n- include: a.yaml\n")
+		_, err := Render(path, Values{})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should fail on includes combined with other keys", func() {
+		write("group.yaml", "name: g1\n")
+		path := write("app.yaml", "name: app\ngroups:\n- include: group.yaml\n  name: other\n")
+		_, err := Render(path, Values{})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
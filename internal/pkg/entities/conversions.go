@@ -17,6 +17,7 @@
 package entities
 
 import (
+	"github.com/golang/protobuf/proto"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-application-go"
 	"github.com/nalej/grpc-application-manager-go"
@@ -30,6 +31,7 @@ import (
 	"github.com/nalej/grpc-log-download-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/logquery"
 	"github.com/rs/zerolog/log"
 )
 
@@ -51,6 +53,10 @@ func ToInfraClusterUpdate(update grpc_public_api_go.UpdateClusterRequest) *grpc_
 	return result
 }
 
+// UnknownCount is returned in the counters that could not be computed because an upstream component did not
+// answer in time. The rest of the response is still valid.
+const UnknownCount int64 = -1
+
 func ToPublicAPICluster(source *grpc_infrastructure_go.Cluster, totalNodes int64, runningNodes int64) *grpc_public_api_go.Cluster {
 	return &grpc_public_api_go.Cluster{
 		OrganizationId:             source.OrganizationId,
@@ -558,6 +564,33 @@ func NewSearchRequest(request *grpc_public_api_go.SearchRequest) *grpc_applicati
 	}
 }
 
+// ToLogCountBuckets transforms the buckets of a log query count.
+func ToLogCountBuckets(buckets []logquery.Bucket) []*grpc_public_api_go.LogCountBucket {
+	result := make([]*grpc_public_api_go.LogCountBucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, &grpc_public_api_go.LogCountBucket{
+			Key:   bucket.Key,
+			Start: bucket.Start,
+			Count: bucket.Count,
+		})
+	}
+	return result
+}
+
+// ToLogMessageCounts transforms the most frequent messages of a log query.
+func ToLogMessageCounts(counts []logquery.MessageCount) []*grpc_public_api_go.LogMessageCount {
+	result := make([]*grpc_public_api_go.LogMessageCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, &grpc_public_api_go.LogMessageCount{
+			Pattern:  count.Pattern,
+			Example:  count.Example,
+			Count:    count.Count,
+			LastSeen: count.LastSeen,
+		})
+	}
+	return result
+}
+
 func ToPublicAPIDownloadLogReponse(response *grpc_log_download_manager_go.DownloadLogResponse) *grpc_public_api_go.DownloadLogResponse {
 	return &grpc_public_api_go.DownloadLogResponse{
 		OrganizationId: response.OrganizationId,
@@ -569,6 +602,7 @@ func ToPublicAPIDownloadLogReponse(response *grpc_log_download_manager_go.Downlo
 		Url:            response.Url,
 		Expiration:     response.Expiration,
 		Info:           response.Info,
+		Created:        response.Created,
 	}
 }
 
@@ -594,3 +628,89 @@ func ToUpdateSettingRequest(updateRequest *grpc_public_api_go.UpdateSettingReque
 		Value:          updateRequest.Value,
 	}
 }
+
+// toServices rebuilds the services of a descriptor from the service instances of a group.
+func toServices(source []*grpc_application_go.ServiceInstance) []*grpc_application_go.Service {
+	result := make([]*grpc_application_go.Service, 0, len(source))
+	for _, si := range source {
+		credentials := si.Credentials
+		if credentials != nil {
+			credentials = hideCredentials(credentials)
+		}
+		result = append(result, &grpc_application_go.Service{
+			Name:                 si.Name,
+			Type:                 si.Type,
+			Image:                si.Image,
+			Credentials:          credentials,
+			Specs:                si.Specs,
+			Storage:              si.Storage,
+			ExposedPorts:         si.ExposedPorts,
+			EnvironmentVariables: si.EnvironmentVariables,
+			Configs:              si.Configs,
+			Labels:               si.Labels,
+			DeployAfter:          si.DeployAfter,
+			RunArguments:         si.RunArguments,
+		})
+	}
+	return result
+}
+
+// ToAddAppDescriptorRequest rebuilds a descriptor that reproduces a running instance. The parameters of the original
+// descriptor take the values the instance was deployed with as default values, and the credentials of the images
+// are redacted. Identifiers are not copied so the descriptor can be added in any organization.
+func ToAddAppDescriptorRequest(instance *grpc_application_manager_go.AppInstance, parameters []*grpc_application_go.AppParameter, values *grpc_application_go.InstanceParameterList) *grpc_application_go.AddAppDescriptorRequest {
+	deployed := make(map[string]string, 0)
+	if values != nil {
+		for _, value := range values.Parameters {
+			deployed[value.ParameterName] = value.Value
+		}
+	}
+	params := make([]*grpc_application_go.AppParameter, 0, len(parameters))
+	for _, p := range parameters {
+		param := proto.Clone(p).(*grpc_application_go.AppParameter)
+		if value, exists := deployed[p.Name]; exists {
+			param.DefaultValue = value
+		}
+		params = append(params, param)
+	}
+
+	groups := make([]*grpc_application_go.ServiceGroup, 0, len(instance.Groups))
+	for _, sgi := range instance.Groups {
+		groups = append(groups, &grpc_application_go.ServiceGroup{
+			Name:     sgi.Name,
+			Services: toServices(sgi.ServiceInstances),
+			Policy:   sgi.Policy,
+			Specs:    sgi.Specs,
+			Labels:   sgi.Labels,
+		})
+	}
+
+	rules := make([]*grpc_application_go.SecurityRule, 0, len(instance.Rules))
+	for _, sr := range instance.Rules {
+		rules = append(rules, &grpc_application_go.SecurityRule{
+			Name:                   sr.Name,
+			TargetServiceGroupName: sr.TargetServiceGroupName,
+			TargetServiceName:      sr.TargetServiceName,
+			TargetPort:             sr.TargetPort,
+			Access:                 sr.Access,
+			AuthServiceGroupName:   sr.AuthServiceGroupName,
+			AuthServices:           sr.AuthServices,
+			DeviceGroupNames:       sr.DeviceGroupNames,
+			InboundNetInterface:    sr.InboundNetInterface,
+			OutboundNetInterface:   sr.OutboundNetInterface,
+		})
+	}
+
+	return &grpc_application_go.AddAppDescriptorRequest{
+		OrganizationId:        instance.OrganizationId,
+		Name:                  instance.Name,
+		ConfigurationOptions:  instance.ConfigurationOptions,
+		EnvironmentVariables:  instance.EnvironmentVariables,
+		Labels:                instance.Labels,
+		Rules:                 rules,
+		Groups:                groups,
+		Parameters:            params,
+		InboundNetInterfaces:  instance.InboundNetInterfaces,
+		OutboundNetInterfaces: instance.OutboundNetInterfaces,
+	}
+}
@@ -34,10 +34,14 @@ import (
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-user-go"
 	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
 	"github.com/rs/zerolog/log"
 	"github.com/santhosh-tekuri/jsonschema"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 )
 
 const emptyOrganizationId = "organization_id cannot be empty"
@@ -76,6 +80,12 @@ const emptyRequestId = "request_id cannot be empty"
 
 const emptyKey = "key cannot be empty"
 
+const emptyOperationId = "operation_id cannot be empty"
+
+const emptySavedSearchId = "saved_search_id cannot be empty"
+
+const emptyAlertRuleId = "alert_rule_id cannot be empty"
+
 // --------- Application descriptor JSON Schema
 type AppJSONSchema struct {
 	// Singleton object used to validate application descriptors
@@ -546,6 +556,37 @@ func ValidSearchRequest(request *grpc_public_api_go.SearchRequest) derrors.Error
 	return nil
 }
 
+// ValidTailRequest checks a search used to tail the logs. The stream has no end time and always sends the entries
+// in ascending order.
+func ValidTailRequest(request *grpc_public_api_go.SearchRequest) derrors.Error {
+	if err := ValidSearchRequest(request); err != nil {
+		return err
+	}
+	if request.To != 0 {
+		return derrors.NewInvalidArgumentError("to cannot be set when tailing the logs")
+	}
+	if request.NFirst {
+		return derrors.NewInvalidArgumentError("n_first cannot be set when tailing the logs")
+	}
+	if request.Order != nil && request.Order.Order != grpc_public_api_go.Order_ASC {
+		return derrors.NewInvalidArgumentError("log entries are always tailed in ascending order")
+	}
+	return nil
+}
+
+func ValidLogQueryRequest(request *grpc_public_api_go.LogQueryRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.From != 0 && request.To != 0 && request.From > request.To {
+		return derrors.NewInvalidArgumentError("from cannot be after to")
+	}
+	if request.Limit < 0 {
+		return derrors.NewInvalidArgumentError("limit cannot be negative")
+	}
+	return nil
+}
+
 func ValidDownloadRequestId(request *grpc_log_download_manager_go.DownloadRequestId) derrors.Error {
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError(emptyOrganizationId)
@@ -557,6 +598,82 @@ func ValidDownloadRequestId(request *grpc_log_download_manager_go.DownloadReques
 	return nil
 }
 
+// ValidPruneDownloadsRequest checks a request to remove the expired log downloads.
+func ValidPruneDownloadsRequest(request *grpc_public_api_go.PruneDownloadsRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	return nil
+}
+
+// ValidAddSavedSearchRequest checks a request to save a log query. The query itself is parsed by the manager.
+func ValidAddSavedSearchRequest(request *grpc_public_api_go.AddSavedSearchRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.Name == "" {
+		return derrors.NewInvalidArgumentError(emptyName)
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		return derrors.NewInvalidArgumentError("query cannot be empty")
+	}
+	if request.Since != "" {
+		since, err := timeexpr.ParseDuration(request.Since)
+		if err != nil || since <= 0 {
+			return derrors.NewInvalidArgumentError("since must be a positive duration, as in 1h").WithParams(request.Since)
+		}
+	}
+	return nil
+}
+
+func ValidSavedSearchId(request *grpc_public_api_go.SavedSearchId) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.SavedSearchId == "" {
+		return derrors.NewInvalidArgumentError(emptySavedSearchId)
+	}
+	return nil
+}
+
+// MinAlertRuleInterval with the minimum time between two evaluations of an alert rule.
+const MinAlertRuleInterval = time.Minute
+
+// ValidAddAlertRuleRequest checks a request to create an alert rule on a saved search.
+func ValidAddAlertRuleRequest(request *grpc_public_api_go.AddAlertRuleRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.SavedSearchId == "" {
+		return derrors.NewInvalidArgumentError(emptySavedSearchId)
+	}
+	if request.Name == "" {
+		return derrors.NewInvalidArgumentError(emptyName)
+	}
+	interval, err := timeexpr.ParseDuration(request.Interval)
+	if err != nil || interval < MinAlertRuleInterval {
+		return derrors.NewInvalidArgumentError(fmt.Sprintf("interval must be a duration of at least %s", MinAlertRuleInterval)).WithParams(request.Interval)
+	}
+	if request.Threshold <= 0 {
+		return derrors.NewInvalidArgumentError("threshold must be greater than zero")
+	}
+	webhook, uErr := url.Parse(request.WebhookUrl)
+	if uErr != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+		return derrors.NewInvalidArgumentError("webhook_url must be an http or https URL").WithParams(request.WebhookUrl)
+	}
+	return nil
+}
+
+func ValidAlertRuleId(request *grpc_public_api_go.AlertRuleId) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.AlertRuleId == "" {
+		return derrors.NewInvalidArgumentError(emptyAlertRuleId)
+	}
+	return nil
+}
+
 func ValidDownloadLogRequest(request *grpc_log_download_manager_go.DownloadLogRequest) derrors.Error {
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError(emptyOrganizationId)
@@ -805,6 +922,10 @@ func ValidRemoveConnectionRequest(conn *grpc_application_network_go.RemoveConnec
 	return nil
 }
 
+// LogDownloadRetentionSetting with the key of the organization setting that sets how long the finished log downloads
+// are kept, as a duration such as 72h or 7d. A retention of 0 keeps them.
+const LogDownloadRetentionSetting = "LOG_DOWNLOAD_RETENTION"
+
 func ValidUpdateSettingRequest(updateRequest *grpc_public_api_go.UpdateSettingRequest) derrors.Error {
 	if updateRequest.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError(emptyOrganizationId)
@@ -812,6 +933,12 @@ func ValidUpdateSettingRequest(updateRequest *grpc_public_api_go.UpdateSettingRe
 	if updateRequest.Key == "" {
 		return derrors.NewInvalidArgumentError(emptyKey)
 	}
+	if updateRequest.Key == LogDownloadRetentionSetting {
+		retention, err := timeexpr.ParseDuration(updateRequest.Value)
+		if err != nil || retention < 0 {
+			return derrors.NewInvalidArgumentError("the log download retention must be a positive duration, as in 7d, or 0").WithParams(updateRequest.Value)
+		}
+	}
 	return nil
 }
 
@@ -822,3 +949,80 @@ func ValidListRequest(request *grpc_public_api_go.ListRequest) derrors.Error {
 
 	return nil
 }
+
+func ValidOperationId(operationID *grpc_public_api_go.OperationId) derrors.Error {
+	if operationID.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if operationID.OperationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOperationId)
+	}
+	return nil
+}
+
+func ValidListOperationsRequest(request *grpc_public_api_go.ListOperationsRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	return nil
+}
+
+func ValidWaitOperationRequest(request *grpc_public_api_go.WaitOperationRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.OperationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOperationId)
+	}
+	if request.TimeoutSeconds < 0 {
+		return derrors.NewInvalidArgumentError("timeout_seconds cannot be negative")
+	}
+	return nil
+}
+
+func ValidBulkLabelRequest(request *grpc_public_api_go.BulkLabelRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.ResourceType == "" {
+		return derrors.NewInvalidArgumentError("resource_type cannot be empty")
+	}
+	criteria := 0
+	for _, set := range []bool{len(request.EntityIds) > 0, len(request.MatchLabels) > 0, request.Selector != ""} {
+		if set {
+			criteria++
+		}
+	}
+	if criteria == 0 {
+		return derrors.NewInvalidArgumentError("entity_ids, match_labels or selector must be set")
+	}
+	if criteria > 1 {
+		return derrors.NewInvalidArgumentError("entity_ids, match_labels and selector cannot be used together")
+	}
+	if request.Selector != "" {
+		if _, err := selector.Parse(request.Selector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ValidDescriptorHistoryRequest(request *grpc_public_api_go.DescriptorHistoryRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.Name == "" {
+		return derrors.NewInvalidArgumentError(emptyName)
+	}
+	return nil
+}
+
+func ValidDescriptorDiffRequest(request *grpc_public_api_go.DescriptorDiffRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError(emptyOrganizationId)
+	}
+	if request.FromAppDescriptorId == "" || request.ToAppDescriptorId == "" {
+		return derrors.NewInvalidArgumentError("from_app_descriptor_id and to_app_descriptor_id cannot be empty")
+	}
+	return nil
+}
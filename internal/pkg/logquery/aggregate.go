@@ -0,0 +1,166 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logquery
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultBuckets with the approximate number of buckets of a count if the size is not set in the query.
+const DefaultBuckets = 30
+
+// bucketSizes contains the sizes chosen for the counts without an explicit one.
+var bucketSizes = []time.Duration{
+	time.Second, time.Second * 5, time.Second * 10, time.Second * 30,
+	time.Minute, time.Minute * 5, time.Minute * 10, time.Minute * 15, time.Minute * 30,
+	time.Hour, time.Hour * 3, time.Hour * 6, time.Hour * 12, time.Hour * 24,
+}
+
+// Bucket with the number of entries of a group in a time interval.
+type Bucket struct {
+	// Key with the value of the field the entries are grouped by.
+	Key string
+	// Start of the interval in nanoseconds.
+	Start int64
+	Count int64
+}
+
+// MessageCount with the number of entries sharing a message pattern.
+type MessageCount struct {
+	// Pattern of the message with the variable parts, such as numbers and identifiers, masked.
+	Pattern string
+	// Example contains the last message with the pattern.
+	Example  string
+	Count    int64
+	LastSeen int64
+}
+
+// BucketSize returns the size of the buckets of a count: the one in the query, or the one that splits the time range
+// in about DefaultBuckets intervals. The range of the entries is used if from or to are not set.
+func BucketSize(aggregation *Aggregation, entries []*Entry, from int64, to int64) time.Duration {
+	if aggregation.Every > 0 {
+		return aggregation.Every
+	}
+	for _, entry := range entries {
+		if from == 0 || entry.Timestamp < from {
+			from = entry.Timestamp
+		}
+		if to == 0 || entry.Timestamp > to {
+			to = entry.Timestamp
+		}
+	}
+	span := time.Duration(to - from)
+	for _, size := range bucketSizes {
+		if span/size <= DefaultBuckets {
+			return size
+		}
+	}
+	return bucketSizes[len(bucketSizes)-1]
+}
+
+// Count groups the entries by a field and by time buckets of the given size. The buckets are aligned to multiples
+// of the size and sorted by key and start; the empty ones are not returned.
+func Count(entries []*Entry, by string, size time.Duration) []Bucket {
+	type bucketKey struct {
+		key   string
+		start int64
+	}
+	counts := make(map[bucketKey]int64, 0)
+	for _, entry := range entries {
+		start := entry.Timestamp - entry.Timestamp%int64(size)
+		counts[bucketKey{entry.Field(by), start}]++
+	}
+	result := make([]Bucket, 0, len(counts))
+	for key, count := range counts {
+		result = append(result, Bucket{Key: key.key, Start: key.start, Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Key != result[j].Key {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Start < result[j].Start
+	})
+	return result
+}
+
+// variableParts matches the parts of a message that change between occurrences of the same event.
+var variableParts = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>"},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>"},
+}
+
+// hexWord matches the words that may be hexadecimal identifiers, such as hashes.
+var hexWord = regexp.MustCompile(`\b[0-9a-fA-F]{6,}\b`)
+
+// number matches integer and decimal numbers.
+var number = regexp.MustCompile(`\d+(\.\d+)?`)
+
+// Pattern masks the variable parts of a message so the occurrences of an event are counted together.
+func Pattern(msg string) string {
+	for _, part := range variableParts {
+		msg = part.pattern.ReplaceAllString(msg, part.replacement)
+	}
+	msg = hexWord.ReplaceAllStringFunc(msg, func(word string) string {
+		if strings.IndexAny(word, "0123456789") >= 0 && strings.IndexAny(word, "abcdefABCDEF") >= 0 {
+			return "<hex>"
+		}
+		return word
+	})
+	return number.ReplaceAllString(msg, "<n>")
+}
+
+// Top returns the most frequent message patterns, sorted by count and, in case of a tie, by the last occurrence.
+func Top(entries []*Entry, limit int) []MessageCount {
+	counts := make(map[string]*MessageCount, 0)
+	for _, entry := range entries {
+		pattern := Pattern(entry.Msg)
+		count, found := counts[pattern]
+		if !found {
+			count = &MessageCount{Pattern: pattern}
+			counts[pattern] = count
+		}
+		count.Count++
+		if entry.Timestamp >= count.LastSeen {
+			count.LastSeen = entry.Timestamp
+			count.Example = entry.Msg
+		}
+	}
+	result := make([]MessageCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].LastSeen != result[j].LastSeen {
+			return result[i].LastSeen > result[j].LastSeen
+		}
+		return result[i].Pattern < result[j].Pattern
+	})
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logquery
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Log aggregations", func() {
+
+	minute := time.Minute.Nanoseconds()
+
+	ginkgo.It("should count the entries per service and bucket", func() {
+		entries := []*Entry{
+			newEntry("web", minute*10+1, "a"),
+			newEntry("web", minute*14, "b"),
+			newEntry("db", minute*12, "c"),
+			newEntry("web", minute*16, "d"),
+		}
+		gomega.Expect(Count(entries, ServiceField, time.Minute*5)).To(gomega.Equal([]Bucket{
+			{Key: "db", Start: minute * 10, Count: 1},
+			{Key: "web", Start: minute * 10, Count: 2},
+			{Key: "web", Start: minute * 15, Count: 1},
+		}))
+	})
+
+	ginkgo.It("should choose the bucket size from the time range", func() {
+		count := &Aggregation{Type: CountAggregation, By: ServiceField}
+		gomega.Expect(BucketSize(count, nil, 0, time.Hour.Nanoseconds())).To(gomega.Equal(time.Minute * 5))
+		gomega.Expect(BucketSize(count, nil, 0, time.Minute.Nanoseconds()*10)).To(gomega.Equal(time.Second * 30))
+		entries := []*Entry{newEntry("web", minute, "a"), newEntry("web", minute*3, "b")}
+		gomega.Expect(BucketSize(count, entries, 0, 0)).To(gomega.Equal(time.Second * 5))
+		count.Every = time.Hour
+		gomega.Expect(BucketSize(count, entries, 0, 0)).To(gomega.Equal(time.Hour))
+	})
+
+	ginkgo.It("should mask the variable parts of the messages", func() {
+		gomega.Expect(Pattern("request 5f0e2c1a-3b4d-4e5f-8a9b-0c1d2e3f4a5b failed after 1.5s")).
+			To(gomega.Equal("request <uuid> failed after <n>s"))
+		gomega.Expect(Pattern("cannot connect to 10.0.0.12:3306")).To(gomega.Equal("cannot connect to <ip>"))
+		gomega.Expect(Pattern("commit a3f9c01 in 0x1f")).To(gomega.Equal("commit <hex> in <hex>"))
+		gomega.Expect(Pattern("deadline exceeded")).To(gomega.Equal("deadline exceeded"))
+	})
+
+	ginkgo.It("should return the most frequent messages", func() {
+		entries := []*Entry{
+			newEntry("web", 1, "timeout after 30s"),
+			newEntry("web", 2, "disk full"),
+			newEntry("web", 3, "timeout after 12s"),
+			newEntry("db", 4, "connection refused"),
+		}
+		top := Top(entries, 2)
+		gomega.Expect(top).To(gomega.Equal([]MessageCount{
+			{Pattern: "timeout after <n>s", Example: "timeout after 12s", Count: 2, LastSeen: 3},
+			{Pattern: "connection refused", Example: "connection refused", Count: 1, LastSeen: 4},
+		}))
+	})
+})
@@ -0,0 +1,277 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logquery
+
+import (
+	"github.com/nalej/grpc-application-manager-go"
+	"regexp"
+	"strings"
+)
+
+// Severity of a log entry, detected from its message.
+type Severity int
+
+const (
+	UnknownSeverity Severity = iota
+	TraceSeverity
+	DebugSeverity
+	InfoSeverity
+	WarningSeverity
+	ErrorSeverity
+	FatalSeverity
+)
+
+var severityNames = map[Severity]string{
+	UnknownSeverity: "unknown",
+	TraceSeverity:   "trace",
+	DebugSeverity:   "debug",
+	InfoSeverity:    "info",
+	WarningSeverity: "warn",
+	ErrorSeverity:   "error",
+	FatalSeverity:   "fatal",
+}
+
+// severityAliases contains the names used by the common logging libraries.
+var severityAliases = map[string]Severity{
+	"unknown": UnknownSeverity, "trace": TraceSeverity, "debug": DebugSeverity, "dbg": DebugSeverity,
+	"info": InfoSeverity, "information": InfoSeverity, "notice": InfoSeverity, "warn": WarningSeverity,
+	"warning": WarningSeverity, "error": ErrorSeverity, "err": ErrorSeverity, "fatal": FatalSeverity,
+	"panic": FatalSeverity, "critical": FatalSeverity, "crit": FatalSeverity,
+}
+
+// String returns the name of a severity.
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// ParseSeverity returns the severity given its name or an alias, regardless of the case.
+func ParseSeverity(name string) (Severity, bool) {
+	severity, found := severityAliases[strings.ToLower(name)]
+	return severity, found
+}
+
+// levelField finds the severity of structured messages, such as level=error or "level":"warn".
+var levelField = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`)
+
+// levelPrefix finds the severity at the beginning of plain messages, such as "ERROR: ..." or "[warn] ...".
+var levelPrefix = regexp.MustCompile(`^\W*([A-Za-z]+)\b`)
+
+// DetectSeverity returns the severity of a message, or UnknownSeverity if it does not contain one.
+func DetectSeverity(msg string) Severity {
+	for _, pattern := range []*regexp.Regexp{levelField, levelPrefix} {
+		if match := pattern.FindStringSubmatch(msg); match != nil {
+			if severity, found := ParseSeverity(match[1]); found {
+				return severity
+			}
+		}
+	}
+	return UnknownSeverity
+}
+
+// Entry with a log entry and the information used to evaluate the queries.
+type Entry struct {
+	*grpc_application_manager_go.LogEntryResponse
+	// Labels of the application instance that produced the entry.
+	Labels map[string]string
+	// Severity detected from the message.
+	Severity Severity
+}
+
+// NewEntry creates the entry of a log entry and the labels of its instance.
+func NewEntry(entry *grpc_application_manager_go.LogEntryResponse, labels map[string]string) *Entry {
+	return &Entry{LogEntryResponse: entry, Labels: labels, Severity: DetectSeverity(entry.Msg)}
+}
+
+// Field returns the value of a field of the entry.
+func (e *Entry) Field(name string) string {
+	switch name {
+	case MsgField:
+		return e.Msg
+	case SeverityField:
+		return e.Severity.String()
+	case ServiceField:
+		return e.ServiceName
+	case GroupField:
+		return e.ServiceGroupName
+	case AppField:
+		return e.AppInstanceName
+	case InstanceField:
+		return e.AppInstanceId
+	case DescriptorField:
+		return e.AppDescriptorId
+	}
+	if strings.HasPrefix(name, LabelPrefix) {
+		return e.Labels[strings.TrimPrefix(name, LabelPrefix)]
+	}
+	return ""
+}
+
+// Expr is a filter of log entries.
+type Expr interface {
+	Match(entry *Entry) bool
+}
+
+// andExpr matches the entries matched by all its expressions. An empty one matches all the entries.
+type andExpr []Expr
+
+func (e andExpr) Match(entry *Entry) bool {
+	for _, expr := range e {
+		if !expr.Match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// orExpr matches the entries matched by any of its expressions.
+type orExpr []Expr
+
+func (e orExpr) Match(entry *Entry) bool {
+	for _, expr := range e {
+		if expr.Match(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct {
+	Expr
+}
+
+func (e notExpr) Match(entry *Entry) bool {
+	return !e.Expr.Match(entry)
+}
+
+// termExpr matches the messages containing the value, or the fields equal to it. Both are case insensitive.
+type termExpr struct {
+	field string
+	value string
+	// raw contains the value as written in the query.
+	raw string
+}
+
+func (e termExpr) Match(entry *Entry) bool {
+	if e.field == MsgField {
+		return strings.Contains(strings.ToLower(entry.Msg), e.value)
+	}
+	return strings.ToLower(entry.Field(e.field)) == e.value
+}
+
+type regexExpr struct {
+	field string
+	re    *regexp.Regexp
+}
+
+func (e regexExpr) Match(entry *Entry) bool {
+	return e.re.MatchString(entry.Field(e.field))
+}
+
+// severityExpr compares the severity of the entries.
+type severityExpr struct {
+	op       string
+	severity Severity
+}
+
+func (e severityExpr) Match(entry *Entry) bool {
+	switch e.op {
+	case ">=":
+		return entry.Severity >= e.severity
+	case ">":
+		return entry.Severity > e.severity
+	case "<=":
+		return entry.Severity <= e.severity
+	case "<":
+		return entry.Severity < e.severity
+	}
+	return entry.Severity == e.severity
+}
+
+// Pushdown contains the filters of a query that the unified logging backend can apply.
+type Pushdown struct {
+	AppInstanceId   string
+	AppDescriptorId string
+	// MsgQueryFilter contains a text required in the messages. The backend matches it regardless of the case.
+	MsgQueryFilter string
+}
+
+// Pushdown returns the instance, descriptor and message text required by the whole query, so the backend only
+// returns their entries. The backend accepts a single message text, so the longest one is chosen. The query must
+// still be evaluated on the returned entries.
+func (q *Query) Pushdown() Pushdown {
+	result := Pushdown{}
+	required := andExpr{q.Filter}
+	if and, ok := q.Filter.(andExpr); ok {
+		required = and
+	}
+	for _, expr := range required {
+		if term, ok := expr.(termExpr); ok {
+			// The identifiers are case sensitive in the backend.
+			switch term.field {
+			case InstanceField:
+				result.AppInstanceId = term.raw
+			case DescriptorField:
+				result.AppDescriptorId = term.raw
+			case MsgField:
+				if len(term.raw) > len(result.MsgQueryFilter) {
+					result.MsgQueryFilter = term.raw
+				}
+			}
+		}
+	}
+	return result
+}
+
+// UsesLabels checks if the query filters by label, so the labels of the instances must be retrieved.
+func (q *Query) UsesLabels() bool {
+	return usesLabels(q.Filter)
+}
+
+func usesLabels(expr Expr) bool {
+	switch e := expr.(type) {
+	case andExpr:
+		for _, inner := range e {
+			if usesLabels(inner) {
+				return true
+			}
+		}
+	case orExpr:
+		for _, inner := range e {
+			if usesLabels(inner) {
+				return true
+			}
+		}
+	case notExpr:
+		return usesLabels(e.Expr)
+	case termExpr:
+		return strings.HasPrefix(e.field, LabelPrefix)
+	case regexExpr:
+		return strings.HasPrefix(e.field, LabelPrefix)
+	}
+	return false
+}
+
+// Apply returns the entries matching the filter of the query.
+func (q *Query) Apply(entries []*Entry) []*Entry {
+	result := make([]*Entry, 0)
+	for _, entry := range entries {
+		if q.Filter.Match(entry) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
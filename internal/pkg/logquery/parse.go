@@ -0,0 +1,439 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logquery
+
+import (
+	"github.com/nalej/derrors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields that can be used in the filters of a query. The label values are filtered with LabelPrefix and the key.
+const (
+	MsgField        = "msg"
+	SeverityField   = "severity"
+	ServiceField    = "service"
+	GroupField      = "group"
+	AppField        = "app"
+	InstanceField   = "instance"
+	DescriptorField = "descriptor"
+	LabelPrefix     = "label."
+)
+
+// Aggregations supported after the pipe of a query.
+const (
+	CountAggregation = "count"
+	TopAggregation   = "top"
+)
+
+// DefaultTopLimit with the number of messages returned by the top aggregation if not set.
+const DefaultTopLimit = 10
+
+// groupableFields contains the fields the count aggregation can group by.
+var groupableFields = map[string]bool{ServiceField: true, GroupField: true, AppField: true, SeverityField: true}
+
+// Aggregation computed on the entries matching the filter of a query.
+type Aggregation struct {
+	// Type is CountAggregation or TopAggregation.
+	Type string
+	// By contains the field the entries are grouped by in a count.
+	By string
+	// Every contains the size of the time buckets of a count. It is chosen from the time range if not set.
+	Every time.Duration
+	// Limit contains the number of messages of a top aggregation.
+	Limit int
+}
+
+// Query with a parsed log query.
+type Query struct {
+	// Filter matching the entries. It matches all of them if the query has no filters.
+	Filter Expr
+	// Aggregation is nil if the matching entries are returned.
+	Aggregation *Aggregation
+}
+
+const (
+	tokWord = iota
+	tokFilter
+	tokPhrase
+	tokRegex
+	tokOpen
+	tokClose
+	tokPipe
+	tokNot
+	tokAnd
+	tokOr
+)
+
+// token of a query. Field filters are read as a single token.
+type token struct {
+	kind  int
+	field string
+	op    string
+	value string
+	regex bool
+}
+
+// isDelimiter checks if a character ends a word.
+func isDelimiter(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '(' || c == ')' || c == '|' || c == '"'
+}
+
+// isField checks if a name can be used in a field filter.
+func isField(name string) bool {
+	switch name {
+	case MsgField, SeverityField, ServiceField, GroupField, AppField, InstanceField, DescriptorField:
+		return true
+	}
+	return strings.HasPrefix(name, LabelPrefix) && len(name) > len(LabelPrefix)
+}
+
+// lexer splits a query into tokens.
+type lexer struct {
+	input string
+	pos   int
+}
+
+// delimited reads a quoted phrase or a regular expression starting at the current position, handling the escaped
+// delimiters.
+func (l *lexer) delimited(delimiter byte) (string, derrors.Error) {
+	start := l.pos
+	l.pos++
+	var value strings.Builder
+	for l.pos < len(l.input) {
+		c := l.input[l.pos]
+		if c == '\\' && l.pos+1 < len(l.input) && l.input[l.pos+1] == delimiter {
+			if delimiter == '/' {
+				value.WriteByte('\\')
+			}
+			value.WriteByte(delimiter)
+			l.pos += 2
+			continue
+		}
+		if c == delimiter {
+			l.pos++
+			return value.String(), nil
+		}
+		value.WriteByte(c)
+		l.pos++
+	}
+	return "", derrors.NewInvalidArgumentError("unterminated expression in query").WithParams(l.input[start:])
+}
+
+// word reads the characters until the next delimiter.
+func (l *lexer) word() string {
+	start := l.pos
+	for l.pos < len(l.input) && !isDelimiter(l.input[l.pos]) {
+		l.pos++
+	}
+	return l.input[start:l.pos]
+}
+
+// value reads the value of a field filter, that may be quoted or a regular expression.
+func (l *lexer) value(t *token) derrors.Error {
+	if l.pos < len(l.input) && (l.input[l.pos] == '"' || l.input[l.pos] == '/') {
+		t.regex = l.input[l.pos] == '/'
+		value, err := l.delimited(l.input[l.pos])
+		if err != nil {
+			return err
+		}
+		t.value = value
+		return nil
+	}
+	t.value = l.word()
+	if t.value == "" {
+		return derrors.NewInvalidArgumentError("missing value in field filter").WithParams(t.field)
+	}
+	return nil
+}
+
+// next returns the next token, or nil at the end of the input.
+func (l *lexer) next() (*token, derrors.Error) {
+	for l.pos < len(l.input) && (l.input[l.pos] == ' ' || l.input[l.pos] == '\t' || l.input[l.pos] == '\n') {
+		l.pos++
+	}
+	if l.pos >= len(l.input) {
+		return nil, nil
+	}
+	switch c := l.input[l.pos]; c {
+	case '(':
+		l.pos++
+		return &token{kind: tokOpen}, nil
+	case ')':
+		l.pos++
+		return &token{kind: tokClose}, nil
+	case '|':
+		l.pos++
+		return &token{kind: tokPipe}, nil
+	case '"', '/':
+		value, err := l.delimited(c)
+		if err != nil {
+			return nil, err
+		}
+		if c == '/' {
+			return &token{kind: tokRegex, field: MsgField, value: value, regex: true}, nil
+		}
+		return &token{kind: tokPhrase, field: MsgField, value: value}, nil
+	case '-':
+		if l.pos+1 < len(l.input) && !isDelimiter(l.input[l.pos+1]) {
+			l.pos++
+			return &token{kind: tokNot}, nil
+		}
+	}
+
+	start := l.pos
+	for l.pos < len(l.input) && !isDelimiter(l.input[l.pos]) && !strings.ContainsRune(":<>=", rune(l.input[l.pos])) {
+		l.pos++
+	}
+	name := l.input[start:l.pos]
+	if l.pos < len(l.input) && strings.ContainsRune(":<>=", rune(l.input[l.pos])) && isField(name) {
+		t := &token{kind: tokFilter, field: name}
+		for _, op := range []string{">=", "<=", ":", "=", ">", "<"} {
+			if strings.HasPrefix(l.input[l.pos:], op) {
+				t.op = op
+				l.pos += len(op)
+				break
+			}
+		}
+		if err := l.value(t); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	l.pos = start
+	word := l.word()
+	switch word {
+	case "AND":
+		return &token{kind: tokAnd}, nil
+	case "OR":
+		return &token{kind: tokOr}, nil
+	case "NOT":
+		return &token{kind: tokNot}, nil
+	}
+	return &token{kind: tokWord, field: MsgField, value: word}, nil
+}
+
+// parser builds the expression of a query with the usual precedence: NOT, AND and OR. Consecutive filters without
+// operator are joined with AND.
+type parser struct {
+	tokens []*token
+	pos    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) or() (Expr, derrors.Error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	result := orExpr{left}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, right)
+	}
+	if len(result) == 1 {
+		return left, nil
+	}
+	return result, nil
+}
+
+func (p *parser) and() (Expr, derrors.Error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	result := andExpr{left}
+	for t := p.peek(); t != nil && t.kind != tokOr && t.kind != tokClose && t.kind != tokPipe; t = p.peek() {
+		if t.kind == tokAnd {
+			p.pos++
+		}
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, right)
+	}
+	if len(result) == 1 {
+		return left, nil
+	}
+	return result, nil
+}
+
+func (p *parser) unary() (Expr, derrors.Error) {
+	t := p.peek()
+	if t == nil {
+		return nil, derrors.NewInvalidArgumentError("unexpected end of query")
+	}
+	p.pos++
+	switch t.kind {
+	case tokNot:
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{inner}, nil
+	case tokOpen:
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.peek(); closing == nil || closing.kind != tokClose {
+			return nil, derrors.NewInvalidArgumentError("missing closing parenthesis in query")
+		}
+		p.pos++
+		return inner, nil
+	case tokWord, tokPhrase, tokRegex, tokFilter:
+		return newFilter(t)
+	}
+	return nil, derrors.NewInvalidArgumentError("unexpected operator in query")
+}
+
+// newFilter creates the expression of a term or field filter.
+func newFilter(t *token) (Expr, derrors.Error) {
+	if t.regex {
+		re, err := regexp.Compile(t.value)
+		if err != nil {
+			return nil, derrors.NewInvalidArgumentError("invalid regular expression in query", err).WithParams(t.value)
+		}
+		return regexExpr{field: t.field, re: re}, nil
+	}
+	if t.field == SeverityField {
+		severity, found := ParseSeverity(t.value)
+		if !found {
+			return nil, derrors.NewInvalidArgumentError("unknown severity in query").WithParams(t.value)
+		}
+		return severityExpr{op: t.op, severity: severity}, nil
+	}
+	if t.op != "" && t.op != ":" && t.op != "=" {
+		return nil, derrors.NewInvalidArgumentError("comparisons are only supported on severity").WithParams(t.field)
+	}
+	return termExpr{field: t.field, value: strings.ToLower(t.value), raw: t.value}, nil
+}
+
+// parseAggregation reads the words after the pipe: count [by <field>] [every <duration>] or top [<limit>].
+func parseAggregation(tokens []*token) (*Aggregation, derrors.Error) {
+	words := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if t.kind != tokWord {
+			return nil, derrors.NewInvalidArgumentError("invalid aggregation in query")
+		}
+		words = append(words, t.value)
+	}
+	if len(words) == 0 {
+		return nil, derrors.NewInvalidArgumentError("missing aggregation after pipe in query")
+	}
+	switch strings.ToLower(words[0]) {
+	case CountAggregation:
+		aggregation := &Aggregation{Type: CountAggregation, By: ServiceField}
+		for i := 1; i < len(words); i += 2 {
+			if i+1 >= len(words) {
+				return nil, derrors.NewInvalidArgumentError("missing value in count aggregation").WithParams(words[i])
+			}
+			switch strings.ToLower(words[i]) {
+			case "by":
+				if !groupableFields[words[i+1]] {
+					return nil, derrors.NewInvalidArgumentError("cannot count by field").WithParams(words[i+1])
+				}
+				aggregation.By = words[i+1]
+			case "every":
+				every, err := time.ParseDuration(words[i+1])
+				if err != nil || every <= 0 {
+					return nil, derrors.NewInvalidArgumentError("invalid bucket size in count aggregation").WithParams(words[i+1])
+				}
+				aggregation.Every = every
+			default:
+				return nil, derrors.NewInvalidArgumentError("unknown option in count aggregation").WithParams(words[i])
+			}
+		}
+		return aggregation, nil
+	case TopAggregation:
+		aggregation := &Aggregation{Type: TopAggregation, Limit: DefaultTopLimit}
+		if len(words) > 2 {
+			return nil, derrors.NewInvalidArgumentError("top aggregation only accepts a limit")
+		}
+		if len(words) == 2 {
+			limit, err := strconv.Atoi(words[1])
+			if err != nil || limit <= 0 {
+				return nil, derrors.NewInvalidArgumentError("invalid limit in top aggregation").WithParams(words[1])
+			}
+			aggregation.Limit = limit
+		}
+		return aggregation, nil
+	}
+	return nil, derrors.NewInvalidArgumentError("unknown aggregation in query").WithParams(words[0])
+}
+
+// Parse reads a query. The filters are combined with AND, OR, NOT or a leading dash, and grouped with parentheses;
+// a word or quoted phrase matches the messages containing it, /regex/ the messages matching it, and field:value the
+// entries with that field. Severities can also be compared, as in severity>=warn. An aggregation can follow a
+// pipe, as in "severity:error | count by service every 5m" or "severity>=error | top 10".
+func Parse(query string) (*Query, derrors.Error) {
+	l := &lexer{input: query}
+	filter := make([]*token, 0)
+	var aggregation []*token
+	for {
+		t, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			break
+		}
+		if aggregation != nil {
+			aggregation = append(aggregation, t)
+			continue
+		}
+		if t.kind == tokPipe {
+			aggregation = make([]*token, 0)
+			continue
+		}
+		filter = append(filter, t)
+	}
+
+	result := &Query{Filter: andExpr{}}
+	if len(filter) > 0 {
+		p := &parser{tokens: filter}
+		expr, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos < len(p.tokens) {
+			return nil, derrors.NewInvalidArgumentError("unexpected closing parenthesis in query")
+		}
+		result.Filter = expr
+	}
+	if aggregation != nil {
+		parsed, err := parseAggregation(aggregation)
+		if err != nil {
+			return nil, err
+		}
+		result.Aggregation = parsed
+	}
+	return result, nil
+}
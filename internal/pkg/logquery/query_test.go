@@ -0,0 +1,122 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package logquery
+
+import (
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+// newEntry creates an entry of a service with the given message.
+func newEntry(service string, timestamp int64, msg string) *Entry {
+	return NewEntry(&grpc_application_manager_go.LogEntryResponse{
+		AppInstanceId:   "instance",
+		AppInstanceName: "wordpress",
+		ServiceName:     service,
+		Timestamp:       timestamp,
+		Msg:             msg,
+	}, map[string]string{"env": "prod"})
+}
+
+var _ = ginkgo.Describe("Log queries", func() {
+
+	entries := []*Entry{
+		newEntry("web", 1, "GET /index.php 200"),
+		newEntry("web", 2, "level=error msg=\"GET /missing 404\""),
+		newEntry("db", 3, "[Warning] Aborted connection 12 to db"),
+		newEntry("db", 4, "ERROR: connection refused"),
+		newEntry("web", 5, "level=debug msg=\"cache hit\""),
+	}
+
+	match := func(query string) []int64 {
+		parsed, err := Parse(query)
+		gomega.Expect(err).To(gomega.Succeed())
+		result := make([]int64, 0)
+		for _, entry := range parsed.Apply(entries) {
+			result = append(result, entry.Timestamp)
+		}
+		return result
+	}
+
+	ginkgo.It("should detect the severity of the messages", func() {
+		gomega.Expect(entries[0].Severity).To(gomega.Equal(UnknownSeverity))
+		gomega.Expect(entries[1].Severity).To(gomega.Equal(ErrorSeverity))
+		gomega.Expect(entries[2].Severity).To(gomega.Equal(WarningSeverity))
+		gomega.Expect(entries[3].Severity).To(gomega.Equal(ErrorSeverity))
+		gomega.Expect(DetectSeverity(`{"level":"fatal","msg":"exit"}`)).To(gomega.Equal(FatalSeverity))
+	})
+
+	ginkgo.It("should match terms, phrases and regular expressions", func() {
+		gomega.Expect(match("")).To(gomega.Equal([]int64{1, 2, 3, 4, 5}))
+		gomega.Expect(match("connection")).To(gomega.Equal([]int64{3, 4}))
+		gomega.Expect(match(`"connection refused"`)).To(gomega.Equal([]int64{4}))
+		gomega.Expect(match(`/GET \/\w+ 40\d/`)).To(gomega.Equal([]int64{2}))
+		gomega.Expect(match("get index")).To(gomega.Equal([]int64{1}))
+	})
+
+	ginkgo.It("should combine filters with boolean operators", func() {
+		gomega.Expect(match("service:db OR cache")).To(gomega.Equal([]int64{3, 4, 5}))
+		gomega.Expect(match("service:web AND NOT severity:debug")).To(gomega.Equal([]int64{1, 2}))
+		gomega.Expect(match("service:web -GET")).To(gomega.Equal([]int64{5}))
+		gomega.Expect(match("(service:db OR severity:error) connection")).To(gomega.Equal([]int64{3, 4}))
+	})
+
+	ginkgo.It("should filter by fields and labels", func() {
+		gomega.Expect(match("severity>=warn")).To(gomega.Equal([]int64{2, 3, 4}))
+		gomega.Expect(match("severity:err")).To(gomega.Equal([]int64{2, 4}))
+		gomega.Expect(match("service:/^d/")).To(gomega.Equal([]int64{3, 4}))
+		gomega.Expect(match(`app:"WordPress" label.env:prod service:db`)).To(gomega.Equal([]int64{3, 4}))
+		gomega.Expect(match("label.env:dev")).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should reject invalid queries", func() {
+		for _, query := range []string{"(error", "error)", "severity:loud", "/[/", "service>web", `"open`,
+			"error |", "error | sum", "| count by msg", "| count every 0s", "| top ten", "OR error"} {
+			_, err := Parse(query)
+			gomega.Expect(err).NotTo(gomega.Succeed(), query)
+		}
+	})
+
+	ginkgo.It("should push down the required identifiers", func() {
+		parsed, err := Parse("instance:Abc-1 descriptor:D1 error")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Pushdown()).To(gomega.Equal(Pushdown{AppInstanceId: "Abc-1", AppDescriptorId: "D1", MsgQueryFilter: "error"}))
+		gomega.Expect(parsed.UsesLabels()).To(gomega.BeFalse())
+
+		parsed, err = Parse(`"Disk Full" timeout -retry`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Pushdown()).To(gomega.Equal(Pushdown{MsgQueryFilter: "Disk Full"}))
+
+		parsed, err = Parse("instance:a1 OR label.env:prod")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Pushdown()).To(gomega.Equal(Pushdown{}))
+		gomega.Expect(parsed.UsesLabels()).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should parse the aggregations", func() {
+		parsed, err := Parse("severity:error | count by service every 5m")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(*parsed.Aggregation).To(gomega.Equal(Aggregation{Type: CountAggregation, By: ServiceField, Every: time.Minute * 5}))
+
+		parsed, err = Parse("| top 3")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(*parsed.Aggregation).To(gomega.Equal(Aggregation{Type: TopAggregation, Limit: 3}))
+		gomega.Expect(parsed.Apply(entries)).To(gomega.HaveLen(len(entries)))
+	})
+})
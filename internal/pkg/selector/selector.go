@@ -0,0 +1,287 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package selector contains the parser of the label selector expressions used to filter the lists of entities,
+// such as env=prod,tier!=db,region in (eu,us),!deprecated. The requirements are separated by commas and all of
+// them must be satisfied.
+package selector
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"google.golang.org/grpc/metadata"
+	"sort"
+	"strings"
+)
+
+// MetadataKey with the name of the metadata entry and HTTP header containing the selector of a list request.
+const MetadataKey = "label-selector"
+
+// Operator of a requirement.
+type Operator string
+
+const (
+	// Equals requires the label to have a given value.
+	Equals Operator = "="
+	// NotEquals requires the label not to have a given value. Entities without the label match.
+	NotEquals Operator = "!="
+	// In requires the label to have one of a set of values.
+	In Operator = "in"
+	// NotIn requires the label not to have any of a set of values. Entities without the label match.
+	NotIn Operator = "notin"
+	// Exists requires the label to be set.
+	Exists Operator = "exists"
+	// DoesNotExist requires the label not to be set.
+	DoesNotExist Operator = "!"
+)
+
+// Requirement on a label key.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Matches checks whether a set of labels satisfies the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	value, exists := labels[r.Key]
+	switch r.Operator {
+	case Equals:
+		return exists && value == r.Values[0]
+	case NotEquals:
+		return !exists || value != r.Values[0]
+	case In:
+		return exists && contains(r.Values, value)
+	case NotIn:
+		return !exists || !contains(r.Values, value)
+	case Exists:
+		return exists
+	case DoesNotExist:
+		return !exists
+	}
+	return false
+}
+
+// String returns the expression of the requirement.
+func (r Requirement) String() string {
+	switch r.Operator {
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	case In, NotIn:
+		return r.Key + " " + string(r.Operator) + " (" + strings.Join(r.Values, ",") + ")"
+	}
+	return r.Key + string(r.Operator) + r.Values[0]
+}
+
+// contains checks whether a value is in a list.
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Selector is a list of requirements that must be satisfied at the same time. The empty selector matches
+// everything.
+type Selector []Requirement
+
+// Empty checks whether the selector has no requirements.
+func (s Selector) Empty() bool {
+	return len(s) == 0
+}
+
+// Matches checks whether a set of labels satisfies all the requirements.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, requirement := range s {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the expression of the selector.
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for index, requirement := range s {
+		parts[index] = requirement.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// FromLabels creates a selector that requires all the given labels.
+func FromLabels(labels map[string]string) Selector {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make(Selector, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, Requirement{Key: key, Operator: Equals, Values: []string{labels[key]}})
+	}
+	return result
+}
+
+// validToken checks that a key or a value only contains the characters allowed in the labels.
+func validToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	for _, c := range token {
+		isLetter := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		isDigit := c >= '0' && c <= '9'
+		if !isLetter && !isDigit && !strings.ContainsRune("-_./", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// split separates the requirements of an expression by the commas that are not enclosed in parentheses.
+func split(expression string) ([]string, derrors.Error) {
+	parts := make([]string, 0)
+	depth := 0
+	start := 0
+	for index, c := range expression {
+		switch c {
+		case '(':
+			depth++
+			if depth > 1 {
+				return nil, derrors.NewInvalidArgumentError("nested parentheses in label selector").WithParams(expression)
+			}
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, derrors.NewInvalidArgumentError("unbalanced parentheses in label selector").WithParams(expression)
+			}
+		case ',':
+			if depth == 0 {
+				parts = append(parts, expression[start:index])
+				start = index + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, derrors.NewInvalidArgumentError("unbalanced parentheses in label selector").WithParams(expression)
+	}
+	return append(parts, expression[start:]), nil
+}
+
+// parseSet parses a set of values such as (eu,us).
+func parseSet(raw string) ([]string, bool) {
+	raw = strings.TrimSpace(raw)
+	if !strings.HasPrefix(raw, "(") || !strings.HasSuffix(raw, ")") {
+		return nil, false
+	}
+	values := make([]string, 0)
+	for _, value := range strings.Split(raw[1:len(raw)-1], ",") {
+		value = strings.TrimSpace(value)
+		if !validToken(value) {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
+
+// parseRequirement parses a single requirement.
+func parseRequirement(raw string) (*Requirement, derrors.Error) {
+	raw = strings.TrimSpace(raw)
+	invalid := derrors.NewInvalidArgumentError("invalid label selector requirement").WithParams(raw)
+
+	if strings.HasPrefix(raw, "!") && !strings.Contains(raw, "=") {
+		key := strings.TrimSpace(raw[1:])
+		if !validToken(key) {
+			return nil, invalid
+		}
+		return &Requirement{Key: key, Operator: DoesNotExist}, nil
+	}
+	for _, op := range []string{"!=", "==", "="} {
+		if pos := strings.Index(raw, op); pos >= 0 {
+			key := strings.TrimSpace(raw[:pos])
+			value := strings.TrimSpace(raw[pos+len(op):])
+			if !validToken(key) || !validToken(value) {
+				return nil, invalid
+			}
+			operator := Equals
+			if op == "!=" {
+				operator = NotEquals
+			}
+			return &Requirement{Key: key, Operator: operator, Values: []string{value}}, nil
+		}
+	}
+	fields := strings.Fields(raw)
+	if len(fields) == 1 {
+		if !validToken(fields[0]) {
+			return nil, invalid
+		}
+		return &Requirement{Key: fields[0], Operator: Exists}, nil
+	}
+	if len(fields) >= 3 && (fields[1] == string(In) || fields[1] == string(NotIn)) {
+		if !validToken(fields[0]) {
+			return nil, invalid
+		}
+		setStart := strings.Index(raw, "(")
+		if setStart < 0 {
+			return nil, invalid
+		}
+		values, ok := parseSet(raw[setStart:])
+		if !ok {
+			return nil, invalid
+		}
+		return &Requirement{Key: fields[0], Operator: Operator(fields[1]), Values: values}, nil
+	}
+	return nil, invalid
+}
+
+// Parse parses a selector expression. The empty expression returns the empty selector.
+func Parse(expression string) (Selector, derrors.Error) {
+	if strings.TrimSpace(expression) == "" {
+		return Selector{}, nil
+	}
+	parts, err := split(expression)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Selector, 0, len(parts))
+	for _, part := range parts {
+		requirement, err := parseRequirement(part)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *requirement)
+	}
+	return result, nil
+}
+
+// FromIncomingContext parses the selector sent in the metadata of a request. The empty selector is returned if
+// the request does not have one.
+func FromIncomingContext(ctx context.Context) (Selector, derrors.Error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return Selector{}, nil
+	}
+	values := md.Get(MetadataKey)
+	if len(values) == 0 {
+		return Selector{}, nil
+	}
+	return Parse(values[0])
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package selector
+
+import (
+	"context"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc/metadata"
+)
+
+var _ = ginkgo.Describe("Label selector", func() {
+
+	labels := map[string]string{"env": "prod", "tier": "web", "region": "eu"}
+
+	ginkgo.It("should parse all the operators", func() {
+		sel, err := Parse("env=prod, tier!=db,region in (eu, us),zone notin (a),owner,!deprecated,app==web")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(sel).To(gomega.HaveLen(7))
+		gomega.Expect(sel[0]).To(gomega.Equal(Requirement{Key: "env", Operator: Equals, Values: []string{"prod"}}))
+		gomega.Expect(sel[1].Operator).To(gomega.Equal(NotEquals))
+		gomega.Expect(sel[2]).To(gomega.Equal(Requirement{Key: "region", Operator: In, Values: []string{"eu", "us"}}))
+		gomega.Expect(sel[3].Operator).To(gomega.Equal(NotIn))
+		gomega.Expect(sel[4]).To(gomega.Equal(Requirement{Key: "owner", Operator: Exists}))
+		gomega.Expect(sel[5]).To(gomega.Equal(Requirement{Key: "deprecated", Operator: DoesNotExist}))
+		gomega.Expect(sel[6].Operator).To(gomega.Equal(Equals))
+		gomega.Expect(sel.String()).To(gomega.Equal("env=prod,tier!=db,region in (eu,us),zone notin (a),owner,!deprecated,app=web"))
+	})
+
+	ginkgo.It("should match the labels", func() {
+		matching := []string{"", "env=prod", "tier!=db", "region in (eu,us)", "zone notin (a)", "env", "!deprecated",
+			"env=prod,tier!=db,region in (eu,us),!deprecated"}
+		for _, expression := range matching {
+			sel, err := Parse(expression)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(sel.Matches(labels)).To(gomega.BeTrue(), expression)
+		}
+		notMatching := []string{"env=dev", "tier!=web", "region in (us)", "region notin (eu)", "owner", "!env",
+			"env=prod,tier=db"}
+		for _, expression := range notMatching {
+			sel, err := Parse(expression)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(sel.Matches(labels)).To(gomega.BeFalse(), expression)
+		}
+	})
+
+	ginkgo.It("should reject invalid expressions", func() {
+		invalid := []string{"env=", "=prod", "region in (eu", "region in eu,us)", "region in ()", "a b",
+			"region in ((eu))", "env=pr od", ",", "!"}
+		for _, expression := range invalid {
+			_, err := Parse(expression)
+			gomega.Expect(err).NotTo(gomega.Succeed(), expression)
+		}
+	})
+
+	ginkgo.It("should build a selector from a set of labels", func() {
+		sel := FromLabels(map[string]string{"tier": "web", "env": "prod"})
+		gomega.Expect(sel.String()).To(gomega.Equal("env=prod,tier=web"))
+		gomega.Expect(sel.Matches(labels)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should read the selector from the request metadata", func() {
+		sel, err := FromIncomingContext(context.Background())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(sel.Empty()).To(gomega.BeTrue())
+
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "env=prod"))
+		sel, err = FromIncomingContext(ctx)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(sel.String()).To(gomega.Equal("env=prod"))
+
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "env in"))
+		_, err = FromIncomingContext(ctx)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
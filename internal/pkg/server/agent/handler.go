@@ -50,7 +50,7 @@ func (h *Handler) CreateAgentJoinToken(ctx context.Context, edgeController *grpc
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.CreateAgentJoinToken(edgeController)
+	return h.Manager.CreateAgentJoinToken(ctx, edgeController)
 }
 
 func (h *Handler) ActivateMonitoring(ctx context.Context, assetRequest *grpc_public_api_go.AssetMonitoringRequest) (*grpc_public_api_go.AgentOpResponse, error) {
@@ -65,7 +65,7 @@ func (h *Handler) ActivateMonitoring(ctx context.Context, assetRequest *grpc_pub
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ActivateMonitoring(assetRequest)
+	return h.Manager.ActivateMonitoring(ctx, assetRequest)
 }
 
 // UninstallAgent operation to uninstall an agent
@@ -82,6 +82,6 @@ func (h *Handler) UninstallAgent(ctx context.Context, request *grpc_inventory_ma
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UninstallAgent(request)
+	return h.Manager.UninstallAgent(ctx, request)
 
 }
@@ -17,6 +17,7 @@
 package agent
 
 import (
+	"context"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
 	"github.com/nalej/grpc-public-api-go"
@@ -43,15 +44,15 @@ func NewManager(agentClient grpc_inventory_manager_go.AgentClient) Manager {
 	}
 }
 
-func (m *Manager) CreateAgentJoinToken(edgeController *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.AgentJoinToken, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) CreateAgentJoinToken(ctx context.Context, edgeController *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.AgentJoinToken, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.agentClient.CreateAgentJoinToken(ctx, edgeController)
 }
 
-func (m *Manager) ActivateMonitoring(assetRequest *grpc_public_api_go.AssetMonitoringRequest) (*grpc_public_api_go.AgentOpResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ActivateMonitoring(ctx context.Context, assetRequest *grpc_public_api_go.AssetMonitoringRequest) (*grpc_public_api_go.AgentOpResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	op := ""
 	if assetRequest.Activate {
@@ -76,8 +77,8 @@ func (m *Manager) ActivateMonitoring(assetRequest *grpc_public_api_go.AssetMonit
 
 }
 
-func (m *Manager) UninstallAgent(request *grpc_inventory_manager_go.UninstallAgentRequest) (*grpc_public_api_go.ECOpResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UninstallAgent(ctx context.Context, request *grpc_inventory_manager_go.UninstallAgentRequest) (*grpc_public_api_go.ECOpResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	response, err := m.agentClient.UninstallAgent(ctx, request)
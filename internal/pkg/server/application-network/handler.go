@@ -53,7 +53,7 @@ func (h *Handler) AddConnection(ctx context.Context, connRequest *grpc_applicati
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.AddConnection(connRequest)
+	return h.Manager.AddConnection(ctx, connRequest)
 }
 
 // RemoveConnection removes a connection
@@ -70,7 +70,7 @@ func (h *Handler) RemoveConnection(ctx context.Context, request *grpc_applicatio
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.RemoveConnection(request)
+	return h.Manager.RemoveConnection(ctx, request)
 }
 
 // ListConnections retrieves a list all the established connections of an organization
@@ -86,7 +86,7 @@ func (h *Handler) ListConnections(ctx context.Context, organizationID *grpc_orga
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	connections, mErr := h.Manager.ListConnections(organizationID)
+	connections, mErr := h.Manager.ListConnections(ctx, organizationID)
 	if mErr != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
@@ -107,7 +107,7 @@ func (h *Handler) ListAvailableInstanceInbounds(ctx context.Context, organizatio
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.ListAvailableInstanceInbounds(organizationID)
+	return h.Manager.ListAvailableInstanceInbounds(ctx, organizationID)
 }
 
 // ListAvailableInstanceOutbounds retrieves a list of available outbounds of an organization
@@ -124,5 +124,5 @@ func (h *Handler) ListAvailableInstanceOutbounds(ctx context.Context, organizati
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.ListAvailableInstanceOutbounds(organizationID)
+	return h.Manager.ListAvailableInstanceOutbounds(ctx, organizationID)
 }
@@ -17,6 +17,7 @@
 package application_network
 
 import (
+	"context"
 	"github.com/nalej/grpc-application-manager-go"
 	"github.com/nalej/grpc-application-network-go"
 	"github.com/nalej/grpc-organization-go"
@@ -38,8 +39,8 @@ func NewManager(
 }
 
 // AddConnection adds a new connection between one outbound and one inbound
-func (m *Manager) AddConnection(connRequest *grpc_application_network_go.AddConnectionRequest) (*grpc_public_api_go.OpResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) AddConnection(ctx context.Context, connRequest *grpc_application_network_go.AddConnectionRequest) (*grpc_public_api_go.OpResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	appNetResponse, err := m.appNetClient.AddConnection(ctx, connRequest)
@@ -50,8 +51,8 @@ func (m *Manager) AddConnection(connRequest *grpc_application_network_go.AddConn
 }
 
 // RemoveConnection removes a connection
-func (m *Manager) RemoveConnection(connRequest *grpc_application_network_go.RemoveConnectionRequest) (*grpc_public_api_go.OpResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) RemoveConnection(ctx context.Context, connRequest *grpc_application_network_go.RemoveConnectionRequest) (*grpc_public_api_go.OpResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	appNetResponse, err := m.appNetClient.RemoveConnection(ctx, connRequest)
@@ -62,22 +63,22 @@ func (m *Manager) RemoveConnection(connRequest *grpc_application_network_go.Remo
 }
 
 // ListConnections retrieves a list all the established connections of an organization
-func (m *Manager) ListConnections(organizationID *grpc_organization_go.OrganizationId) (*grpc_application_network_go.ConnectionInstanceList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListConnections(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_network_go.ConnectionInstanceList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.appNetClient.ListConnections(ctx, organizationID)
 }
 
-func (m *Manager) ListAvailableInstanceInbounds(organizationID *grpc_organization_go.OrganizationId) (*grpc_application_manager_go.AvailableInstanceInboundList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListAvailableInstanceInbounds(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_manager_go.AvailableInstanceInboundList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.appClient.ListAvailableInstanceInbounds(ctx, organizationID)
 }
 
-func (m *Manager) ListAvailableInstanceOutbounds(organizationID *grpc_organization_go.OrganizationId) (*grpc_application_manager_go.AvailableInstanceOutboundList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListAvailableInstanceOutbounds(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_manager_go.AvailableInstanceOutboundList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.appClient.ListAvailableInstanceOutbounds(ctx, organizationID)
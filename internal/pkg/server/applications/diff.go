@@ -0,0 +1,248 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package applications
+
+import (
+	"fmt"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-public-api-go"
+	"sort"
+	"strings"
+)
+
+// Kinds of the changes between two descriptors.
+const (
+	// ChangeAdded is reported for elements that only exist in the newer descriptor.
+	ChangeAdded = "ADDED"
+	// ChangeRemoved is reported for elements that only exist in the older descriptor.
+	ChangeRemoved = "REMOVED"
+	// ChangeModified is reported for elements whose value changed.
+	ChangeModified = "CHANGED"
+)
+
+// changeSet accumulates the changes found comparing two descriptors.
+type changeSet struct {
+	changes []*grpc_public_api_go.DescriptorChange
+}
+
+// value records the change of a single value. Empty values are considered as not set.
+func (cs *changeSet) value(path string, before string, after string) {
+	if before == after {
+		return
+	}
+	kind := ChangeModified
+	if before == "" {
+		kind = ChangeAdded
+	} else if after == "" {
+		kind = ChangeRemoved
+	}
+	cs.changes = append(cs.changes, &grpc_public_api_go.DescriptorChange{
+		Path:   path,
+		Kind:   kind,
+		Before: before,
+		After:  after,
+	})
+}
+
+// values records the changes between two maps of values.
+func (cs *changeSet) values(path string, before map[string]string, after map[string]string) {
+	for _, key := range unionKeys(before, after) {
+		cs.value(path+"/"+key, before[key], after[key])
+	}
+}
+
+// unionKeys returns the sorted keys found in any of the maps.
+func unionKeys(maps ...map[string]string) []string {
+	keys := make(map[string]bool, 0)
+	for _, m := range maps {
+		for key := range m {
+			keys[key] = true
+		}
+	}
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// withoutVersion returns the labels of a descriptor without the version label set by the public API.
+func withoutVersion(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		if key != VersionLabel {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// serviceSpecs summarizes the resources of a service.
+func serviceSpecs(specs *grpc_application_go.DeploySpecs) map[string]string {
+	if specs == nil {
+		return nil
+	}
+	return map[string]string{
+		"cpu":      fmt.Sprintf("%d", specs.Cpu),
+		"memory":   fmt.Sprintf("%d", specs.Memory),
+		"replicas": fmt.Sprintf("%d", specs.Replicas),
+	}
+}
+
+// groupSpecs summarizes the deployment specs of a service group.
+func groupSpecs(specs *grpc_application_go.ServiceGroupDeploymentSpecs) map[string]string {
+	if specs == nil {
+		return nil
+	}
+	result := map[string]string{
+		"replicas":              fmt.Sprintf("%d", specs.Replicas),
+		"multi_cluster_replica": fmt.Sprintf("%t", specs.MultiClusterReplica),
+	}
+	for key, value := range specs.DeploymentSelectors {
+		result["deployment_selectors."+key] = value
+	}
+	return result
+}
+
+// ports summarizes the exposed ports of a service by name.
+func ports(exposed []*grpc_application_go.Port) map[string]string {
+	result := make(map[string]string, len(exposed))
+	for _, port := range exposed {
+		endpoints := make([]string, 0, len(port.Endpoints))
+		for _, endpoint := range port.Endpoints {
+			endpoints = append(endpoints, endpoint.Type.String()+":"+endpoint.Path)
+		}
+		value := fmt.Sprintf("%d->%d", port.InternalPort, port.ExposedPort)
+		if len(endpoints) > 0 {
+			value = value + " [" + strings.Join(endpoints, ",") + "]"
+		}
+		result[port.Name] = value
+	}
+	return result
+}
+
+// storage summarizes the storage of a service by mount path.
+func storage(volumes []*grpc_application_go.Storage) map[string]string {
+	result := make(map[string]string, len(volumes))
+	for _, volume := range volumes {
+		result[volume.MountPath] = fmt.Sprintf("%s %d", volume.Type.String(), volume.Size)
+	}
+	return result
+}
+
+// rule summarizes a security rule.
+func rule(r *grpc_application_go.SecurityRule) string {
+	result := fmt.Sprintf("%s/%s:%d %s", r.TargetServiceGroupName, r.TargetServiceName, r.TargetPort, r.Access.String())
+	if r.AuthServiceGroupName != "" || len(r.AuthServices) > 0 {
+		result = result + " auth=" + r.AuthServiceGroupName + "/" + strings.Join(r.AuthServices, ",")
+	}
+	if len(r.DeviceGroupNames) > 0 {
+		result = result + " devices=" + strings.Join(r.DeviceGroupNames, ",")
+	}
+	return result
+}
+
+// diffService records the changes between two versions of a service.
+func (cs *changeSet) diffService(path string, before *grpc_application_go.Service, after *grpc_application_go.Service) {
+	cs.value(path+"/image", before.Image, after.Image)
+	cs.value(path+"/type", before.Type.String(), after.Type.String())
+	cs.values(path+"/specs", serviceSpecs(before.Specs), serviceSpecs(after.Specs))
+	cs.values(path+"/ports", ports(before.ExposedPorts), ports(after.ExposedPorts))
+	cs.values(path+"/environment_variables", before.EnvironmentVariables, after.EnvironmentVariables)
+	cs.values(path+"/labels", before.Labels, after.Labels)
+	cs.values(path+"/storage", storage(before.Storage), storage(after.Storage))
+	cs.value(path+"/deploy_after", strings.Join(before.DeployAfter, ","), strings.Join(after.DeployAfter, ","))
+	cs.value(path+"/run_arguments", strings.Join(before.RunArguments, " "), strings.Join(after.RunArguments, " "))
+}
+
+// diffGroup records the changes between two versions of a service group.
+func (cs *changeSet) diffGroup(path string, before *grpc_application_go.ServiceGroup, after *grpc_application_go.ServiceGroup) {
+	cs.values(path+"/specs", groupSpecs(before.Specs), groupSpecs(after.Specs))
+	beforeServices := make(map[string]*grpc_application_go.Service, len(before.Services))
+	names := make(map[string]string, 0)
+	for _, service := range before.Services {
+		beforeServices[service.Name] = service
+		names[service.Name] = service.Image
+	}
+	afterServices := make(map[string]*grpc_application_go.Service, len(after.Services))
+	afterNames := make(map[string]string, 0)
+	for _, service := range after.Services {
+		afterServices[service.Name] = service
+		afterNames[service.Name] = service.Image
+	}
+	for _, name := range unionKeys(names, afterNames) {
+		servicePath := path + "/services/" + name
+		previous, inBefore := beforeServices[name]
+		current, inAfter := afterServices[name]
+		switch {
+		case !inBefore:
+			cs.changes = append(cs.changes, &grpc_public_api_go.DescriptorChange{Path: servicePath, Kind: ChangeAdded, After: current.Image})
+		case !inAfter:
+			cs.changes = append(cs.changes, &grpc_public_api_go.DescriptorChange{Path: servicePath, Kind: ChangeRemoved, Before: previous.Image})
+		default:
+			cs.diffService(servicePath, previous, current)
+		}
+	}
+}
+
+// diffDescriptors returns the semantic changes between two descriptors. Groups, services, ports and rules are matched
+// by name, so the identifiers generated for each descriptor are ignored.
+func diffDescriptors(before *grpc_application_go.AppDescriptor, after *grpc_application_go.AppDescriptor) []*grpc_public_api_go.DescriptorChange {
+	cs := &changeSet{changes: make([]*grpc_public_api_go.DescriptorChange, 0)}
+	cs.value("name", before.Name, after.Name)
+	cs.values("labels", withoutVersion(before.Labels), withoutVersion(after.Labels))
+	cs.values("environment_variables", before.EnvironmentVariables, after.EnvironmentVariables)
+	cs.values("configuration_options", before.ConfigurationOptions, after.ConfigurationOptions)
+
+	beforeGroups := make(map[string]*grpc_application_go.ServiceGroup, len(before.Groups))
+	groupNames := make(map[string]string, 0)
+	for _, group := range before.Groups {
+		beforeGroups[group.Name] = group
+		groupNames[group.Name] = group.Name
+	}
+	afterGroups := make(map[string]*grpc_application_go.ServiceGroup, len(after.Groups))
+	afterGroupNames := make(map[string]string, 0)
+	for _, group := range after.Groups {
+		afterGroups[group.Name] = group
+		afterGroupNames[group.Name] = group.Name
+	}
+	for _, name := range unionKeys(groupNames, afterGroupNames) {
+		groupPath := "groups/" + name
+		previous, inBefore := beforeGroups[name]
+		current, inAfter := afterGroups[name]
+		switch {
+		case !inBefore:
+			cs.changes = append(cs.changes, &grpc_public_api_go.DescriptorChange{Path: groupPath, Kind: ChangeAdded, After: name})
+		case !inAfter:
+			cs.changes = append(cs.changes, &grpc_public_api_go.DescriptorChange{Path: groupPath, Kind: ChangeRemoved, Before: name})
+		default:
+			cs.diffGroup(groupPath, previous, current)
+		}
+	}
+
+	beforeRules := make(map[string]string, len(before.Rules))
+	for _, r := range before.Rules {
+		beforeRules[r.Name] = rule(r)
+	}
+	afterRules := make(map[string]string, len(after.Rules))
+	for _, r := range after.Rules {
+		afterRules[r.Name] = rule(r)
+	}
+	cs.values("rules", beforeRules, afterRules)
+	return cs.changes
+}
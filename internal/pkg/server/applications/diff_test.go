@@ -0,0 +1,83 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package applications
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Descriptor diff", func() {
+
+	var before *grpc_application_go.AppDescriptor
+
+	ginkgo.BeforeEach(func() {
+		request := ithelpers.GetAddDescriptorRequest("org")
+		before = &grpc_application_go.AppDescriptor{
+			AppDescriptorId: "v1",
+			Name:            request.Name,
+			Labels:          map[string]string{"app": "simple-app", VersionLabel: "1"},
+			Rules:           request.Rules,
+			Groups:          request.Groups,
+		}
+	})
+
+	changesByPath := func(changes []*grpc_public_api_go.DescriptorChange) map[string]*grpc_public_api_go.DescriptorChange {
+		result := make(map[string]*grpc_public_api_go.DescriptorChange, len(changes))
+		for _, change := range changes {
+			result[change.Path] = change
+		}
+		return result
+	}
+
+	ginkgo.It("should not report changes between equivalent descriptors", func() {
+		after := proto.Clone(before).(*grpc_application_go.AppDescriptor)
+		after.AppDescriptorId = "v2"
+		after.Labels[VersionLabel] = "2"
+		after.Groups[0].ServiceGroupId = "other"
+		gomega.Expect(diffDescriptors(before, after)).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should report the changes of services, ports, environment and rules", func() {
+		after := proto.Clone(before).(*grpc_application_go.AppDescriptor)
+		service := after.Groups[0].Services[0]
+		service.Image = "mysql:5.7"
+		service.ExposedPorts[0].ExposedPort = 3307
+		service.EnvironmentVariables["MYSQL_DATABASE"] = "wordpress"
+		delete(service.EnvironmentVariables, "MYSQL_ROOT_PASSWORD")
+		service.Specs.Replicas = 3
+		after.Groups[0].Services = append(after.Groups[0].Services, &grpc_application_go.Service{Name: "cache", Image: "redis:5"})
+		after.Groups = append(after.Groups, &grpc_application_go.ServiceGroup{Name: "g2"})
+		after.Rules[0].Access = grpc_application_go.PortAccess_APP_SERVICES
+
+		changes := changesByPath(diffDescriptors(before, after))
+		gomega.Expect(changes).To(gomega.HaveLen(8))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/image"].Kind).To(gomega.Equal(ChangeModified))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/image"].After).To(gomega.Equal("mysql:5.7"))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/ports/mysqlport"].After).To(gomega.Equal("3306->3307"))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/environment_variables/MYSQL_DATABASE"].Kind).To(gomega.Equal(ChangeAdded))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/environment_variables/MYSQL_ROOT_PASSWORD"].Kind).To(gomega.Equal(ChangeRemoved))
+		gomega.Expect(changes["groups/g1/services/simple-mysql-service/specs/replicas"].After).To(gomega.Equal("3"))
+		gomega.Expect(changes["groups/g1/services/cache"].Kind).To(gomega.Equal(ChangeAdded))
+		gomega.Expect(changes["groups/g2"].Kind).To(gomega.Equal(ChangeAdded))
+		gomega.Expect(changes["rules/allow access to mysql"].Kind).To(gomega.Equal(ChangeModified))
+	})
+})
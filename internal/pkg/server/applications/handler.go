@@ -54,7 +54,7 @@ func (h *Handler) AddAppDescriptor(ctx context.Context, addRequest *grpc_applica
 		return nil, conversions.ToGRPCError(err)
 	}
 	addRequest.RequestId = uuid.New().String()
-	return h.Manager.AddAppDescriptor(addRequest)
+	return h.Manager.AddAppDescriptor(ctx, addRequest)
 }
 
 // ListAppDescriptors retrieves a list of application descriptors.
@@ -70,7 +70,7 @@ func (h *Handler) ListAppDescriptors(ctx context.Context, organizationID *grpc_o
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ListAppDescriptors(organizationID)
+	return h.Manager.ListAppDescriptors(ctx, organizationID)
 }
 
 // GetAppDescriptor retrieves a given application descriptor.
@@ -86,7 +86,7 @@ func (h *Handler) GetAppDescriptor(ctx context.Context, appDescriptorID *grpc_ap
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.GetAppDescriptor(appDescriptorID)
+	return h.Manager.GetAppDescriptor(ctx, appDescriptorID)
 }
 
 // UpdateAppDescriptor allows the user to update the information of a registered descriptor.
@@ -102,7 +102,10 @@ func (h *Handler) UpdateAppDescriptor(ctx context.Context, request *grpc_applica
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateAppDescriptor(request)
+	if _, exists := request.Labels[VersionLabel]; exists {
+		return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("the version label cannot be modified").WithParams(VersionLabel))
+	}
+	return h.Manager.UpdateAppDescriptor(ctx, request)
 }
 
 // GetAppDescriptor retrieves a given application descriptor.
@@ -118,7 +121,7 @@ func (h *Handler) DeleteAppDescriptor(ctx context.Context, appDescriptorID *grpc
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.DeleteAppDescriptor(appDescriptorID)
+	return h.Manager.DeleteAppDescriptor(ctx, appDescriptorID)
 }
 
 // Deploy an application descriptor.
@@ -134,7 +137,7 @@ func (h *Handler) Deploy(ctx context.Context, deployRequest *grpc_application_ma
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Deploy(deployRequest)
+	return h.Manager.Deploy(ctx, deployRequest)
 }
 
 // Undeploy a running application instance.
@@ -150,7 +153,7 @@ func (h *Handler) Undeploy(ctx context.Context, undeployRequest *grpc_applicatio
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Undeploy(undeployRequest)
+	return h.Manager.Undeploy(ctx, undeployRequest)
 }
 
 // ListAppInstances retrieves a list of application descriptors.
@@ -166,7 +169,7 @@ func (h *Handler) ListAppInstances(ctx context.Context, organizationID *grpc_org
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ListAppInstances(organizationID)
+	return h.Manager.ListAppInstances(ctx, organizationID)
 }
 
 // GetAppDescriptor retrieves a given application descriptor.
@@ -182,7 +185,23 @@ func (h *Handler) GetAppInstance(ctx context.Context, appInstanceID *grpc_applic
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.GetAppInstance(appInstanceID)
+	return h.Manager.GetAppInstance(ctx, appInstanceID)
+}
+
+// ExportInstanceDescriptor rebuilds a descriptor that reproduces a running instance.
+func (h *Handler) ExportInstanceDescriptor(ctx context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_go.AddAppDescriptorRequest, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if appInstanceID.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidAppInstanceID(appInstanceID)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.ExportInstanceDescriptor(ctx, appInstanceID)
 }
 
 // ListDescriptorAppParameters retrieves a list of parameters of an application
@@ -199,7 +218,7 @@ func (h *Handler) ListDescriptorAppParameters(ctx context.Context, appDescriptor
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.ListDescriptorAppParameters(appDescriptorID)
+	return h.Manager.ListDescriptorAppParameters(ctx, appDescriptorID)
 }
 
 // ListInstanceParameters retrieves a list of instance parameters
@@ -216,5 +235,37 @@ func (h *Handler) ListInstanceParameters(ctx context.Context, appInstanceID *grp
 		return nil, conversions.ToGRPCError(err)
 	}
 
-	return h.Manager.ListInstanceParameters(appInstanceID)
+	return h.Manager.ListInstanceParameters(ctx, appInstanceID)
+}
+
+// GetDescriptorHistory retrieves the versions of the descriptors with a given name.
+func (h *Handler) GetDescriptorHistory(ctx context.Context, request *grpc_public_api_go.DescriptorHistoryRequest) (*grpc_public_api_go.DescriptorHistory, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidDescriptorHistoryRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.GetDescriptorHistory(ctx, request)
+}
+
+// GetDescriptorDiff retrieves the changes between two application descriptors.
+func (h *Handler) GetDescriptorDiff(ctx context.Context, request *grpc_public_api_go.DescriptorDiffRequest) (*grpc_public_api_go.DescriptorDiff, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidDescriptorDiffRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.GetDescriptorDiff(ctx, request)
 }
@@ -0,0 +1,285 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package applications
+
+import (
+	"context"
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"sync"
+)
+
+// racingClient adds a descriptor with the same version before the first descriptor added through it, as another
+// replica of the public API receiving a request at the same time would do.
+type racingClient struct {
+	grpc_application_manager_go.ApplicationManagerClient
+	raced bool
+}
+
+func (rc *racingClient) AddAppDescriptor(ctx context.Context, in *grpc_application_go.AddAppDescriptorRequest, opts ...grpc.CallOption) (*grpc_application_go.AppDescriptor, error) {
+	if !rc.raced {
+		rc.raced = true
+		if _, err := rc.ApplicationManagerClient.AddAppDescriptor(ctx, in, opts...); err != nil {
+			return nil, err
+		}
+	}
+	return rc.ApplicationManagerClient.AddAppDescriptor(ctx, in, opts...)
+}
+
+var _ = ginkgo.Describe("Applications handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.ApplicationsClient
+
+	var organizationID string
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+		organizationID = ithelpers.GenerateUUID()
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(grpc_application_manager_go.NewApplicationManagerClient(platform.Conn()))
+		grpc_public_api_go.RegisterApplicationsServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewApplicationsClient(conn)
+
+		token = ithelpers.GenerateToken("dev@nalej.com", organizationID, "Developer", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_APPS})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	ginkgo.It("should deploy an application descriptor", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		descriptor, err := client.AddAppDescriptor(ctx, ithelpers.GetAddDescriptorRequest(organizationID))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		deployed, err := client.Deploy(ctx, ithelpers.GenerateDeploy(organizationID, descriptor.AppDescriptorId))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		instances, err := client.ListAppInstances(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(instances.Instances)).To(gomega.Equal(1))
+		gomega.Expect(instances.Instances[0].AppInstanceId).To(gomega.Equal(deployed.AppInstanceId))
+		gomega.Expect(instances.Instances[0].AppDescriptorId).To(gomega.Equal(descriptor.AppDescriptorId))
+	})
+
+	ginkgo.It("should not delete a descriptor with running instances", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		descriptor, err := client.AddAppDescriptor(ctx, ithelpers.GetAddDescriptorRequest(organizationID))
+		gomega.Expect(err).To(gomega.Succeed())
+		deployed, err := client.Deploy(ctx, ithelpers.GenerateDeploy(organizationID, descriptor.AppDescriptorId))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		descriptorID := &grpc_application_go.AppDescriptorId{
+			OrganizationId:  organizationID,
+			AppDescriptorId: descriptor.AppDescriptorId,
+		}
+		_, err = client.DeleteAppDescriptor(ctx, descriptorID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.FailedPrecondition))
+
+		_, err = client.Undeploy(ctx, &grpc_application_manager_go.UndeployRequest{
+			OrganizationId: organizationID,
+			AppInstanceId:  deployed.AppInstanceId,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.DeleteAppDescriptor(ctx, descriptorID)
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should export the descriptor of a running instance", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		toAdd := ithelpers.GetAddDescriptorRequest(organizationID)
+		toAdd.Parameters = []*grpc_application_go.AppParameter{{
+			Name:         "replicas",
+			Path:         "groups.0.services.0.specs.replicas",
+			DefaultValue: "1",
+		}}
+		descriptor, err := client.AddAppDescriptor(ctx, toAdd)
+		gomega.Expect(err).To(gomega.Succeed())
+		deployRequest := ithelpers.GenerateDeploy(organizationID, descriptor.AppDescriptorId)
+		deployRequest.Parameters = &grpc_application_go.InstanceParameterList{
+			Parameters: []*grpc_application_go.InstanceParameter{{ParameterName: "replicas", Value: "2"}},
+		}
+		deployed, err := client.Deploy(ctx, deployRequest)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		exported, err := client.ExportInstanceDescriptor(ctx, &grpc_application_go.AppInstanceId{
+			OrganizationId: organizationID,
+			AppInstanceId:  deployed.AppInstanceId,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(exported.Name).To(gomega.Equal(deployRequest.Name))
+		gomega.Expect(exported.Groups).To(gomega.HaveLen(1))
+		gomega.Expect(exported.Groups[0].Name).To(gomega.Equal("g1"))
+		service := exported.Groups[0].Services[0]
+		gomega.Expect(service.ServiceId).To(gomega.BeEmpty())
+		gomega.Expect(service.Image).To(gomega.Equal("mysql:5.6"))
+		gomega.Expect(service.Credentials.Password).To(gomega.Equal("redacted"))
+		gomega.Expect(exported.Rules).To(gomega.HaveLen(1))
+		gomega.Expect(exported.Rules[0].RuleId).To(gomega.BeEmpty())
+		gomega.Expect(exported.Parameters).To(gomega.HaveLen(1))
+		gomega.Expect(exported.Parameters[0].DefaultValue).To(gomega.Equal("2"))
+
+		_, err = client.AddAppDescriptor(ctx, exported)
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should not export the descriptor of an instance of another organization", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.ExportInstanceDescriptor(ctx, &grpc_application_go.AppInstanceId{
+			OrganizationId: ithelpers.GenerateUUID(),
+			AppInstanceId:  ithelpers.GenerateUUID(),
+		})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should number the versions of the descriptors with the same name", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		first, err := client.AddAppDescriptor(ctx, ithelpers.GetAddDescriptorRequest(organizationID))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(first.Labels).To(gomega.HaveKeyWithValue(VersionLabel, "1"))
+		updated := ithelpers.GetAddDescriptorRequest(organizationID)
+		updated.Groups[0].Services[0].Image = "mysql:5.7"
+		second, err := client.AddAppDescriptor(ctx, updated)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(second.Labels).To(gomega.HaveKeyWithValue(VersionLabel, "2"))
+		deployed, err := client.Deploy(ctx, ithelpers.GenerateDeploy(organizationID, first.AppDescriptorId))
+		gomega.Expect(err).To(gomega.Succeed())
+
+		history, err := client.GetDescriptorHistory(ctx, &grpc_public_api_go.DescriptorHistoryRequest{
+			OrganizationId: organizationID,
+			Name:           first.Name,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(history.Versions).To(gomega.HaveLen(2))
+		gomega.Expect(history.Versions[0].AppDescriptorId).To(gomega.Equal(first.AppDescriptorId))
+		gomega.Expect(history.Versions[0].Instances).To(gomega.HaveLen(1))
+		gomega.Expect(history.Versions[0].Instances[0].AppInstanceId).To(gomega.Equal(deployed.AppInstanceId))
+		gomega.Expect(history.Versions[1].Version).To(gomega.Equal(int32(2)))
+		gomega.Expect(history.Versions[1].Instances).To(gomega.BeEmpty())
+
+		diff, err := client.GetDescriptorDiff(ctx, &grpc_public_api_go.DescriptorDiffRequest{
+			OrganizationId:      organizationID,
+			FromAppDescriptorId: first.AppDescriptorId,
+			ToAppDescriptorId:   second.AppDescriptorId,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(diff.FromVersion).To(gomega.Equal(int32(1)))
+		gomega.Expect(diff.ToVersion).To(gomega.Equal(int32(2)))
+		gomega.Expect(diff.Changes).To(gomega.HaveLen(1))
+		gomega.Expect(diff.Changes[0].Path).To(gomega.Equal("groups/g1/services/simple-mysql-service/image"))
+	})
+
+	ginkgo.It("should not modify the version label of a descriptor", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		descriptor, err := client.AddAppDescriptor(ctx, ithelpers.GetAddDescriptorRequest(organizationID))
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.UpdateAppDescriptor(ctx, &grpc_application_go.UpdateAppDescriptorRequest{
+			OrganizationId:  organizationID,
+			AppDescriptorId: descriptor.AppDescriptorId,
+			RemoveLabels:    true,
+			Labels:          map[string]string{VersionLabel: "1"},
+		})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+
+	ginkgo.It("should not repeat the version of concurrent descriptors", func() {
+		const requests = 5
+		results := make(chan *grpc_application_go.AppDescriptor, requests)
+		var wg sync.WaitGroup
+		for i := 0; i < requests; i++ {
+			wg.Add(1)
+			go func() {
+				defer ginkgo.GinkgoRecover()
+				defer wg.Done()
+				ctx, cancel := ithelpers.GetContext(token)
+				defer cancel()
+				added, err := client.AddAppDescriptor(ctx, ithelpers.GetAddDescriptorRequest(organizationID))
+				gomega.Expect(err).To(gomega.Succeed())
+				results <- added
+			}()
+		}
+		wg.Wait()
+		close(results)
+		versions := make(map[string]bool, 0)
+		for added := range results {
+			versions[added.Labels[VersionLabel]] = true
+		}
+		gomega.Expect(versions).To(gomega.HaveLen(requests))
+	})
+
+	ginkgo.It("should add again a descriptor whose version was taken by another replica", func() {
+		appClient := grpc_application_manager_go.NewApplicationManagerClient(platform.Conn())
+		replica := NewManager(&racingClient{ApplicationManagerClient: appClient})
+		added, err := replica.AddAppDescriptor(context.Background(), ithelpers.GetAddDescriptorRequest(organizationID))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(added.Labels).To(gomega.HaveKeyWithValue(VersionLabel, "2"))
+
+		descriptors, err := appClient.ListAppDescriptors(context.Background(), &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(descriptors.Descriptors).To(gomega.HaveLen(2))
+		versions := make(map[string]bool, 0)
+		for _, descriptor := range descriptors.Descriptors {
+			versions[descriptor.Labels[VersionLabel]] = true
+		}
+		gomega.Expect(versions).To(gomega.Equal(map[string]bool{"1": true, "2": true}))
+	})
+
+	ginkgo.It("should return the error of the application manager", func() {
+		platform.Faults.FailOn("ApplicationManager/ListAppDescriptors", status.Error(codes.Unavailable, "application manager unavailable"))
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.ListAppDescriptors(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.Unavailable))
+	})
+
+})
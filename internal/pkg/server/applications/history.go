@@ -0,0 +1,213 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package applications
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// VersionLabel with the label set on the descriptors to number the versions of the descriptors with the same name.
+// Descriptors added before versioning was available do not have it and are reported as version 0.
+const VersionLabel = "nalej-descriptor-version"
+
+// descriptorVersion returns the version of a descriptor.
+func descriptorVersion(descriptor *grpc_application_go.AppDescriptor) int32 {
+	version, err := strconv.Atoi(descriptor.Labels[VersionLabel])
+	if err != nil || version < 0 {
+		return 0
+	}
+	return int32(version)
+}
+
+// nextVersion returns the version of a new descriptor with a given name.
+func nextVersion(descriptors []*grpc_application_go.AppDescriptor, name string) int32 {
+	var last int32
+	for _, descriptor := range descriptors {
+		if descriptor.Name == name {
+			if version := descriptorVersion(descriptor); version > last {
+				last = version
+			}
+		}
+	}
+	return last + 1
+}
+
+// MaxVersionAttempts with the number of times a descriptor is added before giving up when its version is taken by
+// concurrent requests.
+const MaxVersionAttempts = 5
+
+// VersionRetryDelay with the base time to wait before adding again a descriptor whose version was taken.
+const VersionRetryDelay = time.Millisecond * 50
+
+// versionRetryDelay returns the time to wait before an attempt. It is randomized, so the requests of several
+// replicas that took the same version do not collide again.
+func versionRetryDelay(attempt int) time.Duration {
+	base := VersionRetryDelay * time.Duration(attempt)
+	return base + time.Duration(rand.Int63n(int64(base)))
+}
+
+// versionTaken checks whether another descriptor with the same name has the version of an added descriptor. The
+// added descriptor always yields, as the other one may have been checked before it existed.
+func versionTaken(descriptors []*grpc_application_go.AppDescriptor, added *grpc_application_go.AppDescriptor) bool {
+	version := descriptorVersion(added)
+	for _, descriptor := range descriptors {
+		if descriptor.AppDescriptorId != added.AppDescriptorId && descriptor.Name == added.Name &&
+			descriptorVersion(descriptor) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// versionLocks serializes the versioning of the descriptors with the same name in an organization, so two
+// requests received by the same process are never given the same version.
+type versionLocks struct {
+	mutex sync.Mutex
+	locks map[string]*versionLock
+}
+
+// versionLock with the lock of a name and the number of requests holding or waiting for it.
+type versionLock struct {
+	sync.Mutex
+	users int
+}
+
+func newVersionLocks() *versionLocks {
+	return &versionLocks{locks: make(map[string]*versionLock, 0)}
+}
+
+// lock waits for the lock of a descriptor name and returns the function that releases it. The lock is discarded
+// once no request uses it.
+func (vl *versionLocks) lock(organizationID string, name string) func() {
+	key := organizationID + "/" + name
+	vl.mutex.Lock()
+	entry, exists := vl.locks[key]
+	if !exists {
+		entry = &versionLock{}
+		vl.locks[key] = entry
+	}
+	entry.users++
+	vl.mutex.Unlock()
+
+	entry.Lock()
+	return func() {
+		entry.Unlock()
+		vl.mutex.Lock()
+		entry.users--
+		if entry.users == 0 {
+			delete(vl.locks, key)
+		}
+		vl.mutex.Unlock()
+	}
+}
+
+// versionedRequest returns a copy of an add request with the version label set.
+func versionedRequest(addRequest *grpc_application_go.AddAppDescriptorRequest, version int32) *grpc_application_go.AddAppDescriptorRequest {
+	labels := make(map[string]string, len(addRequest.Labels)+1)
+	for key, value := range addRequest.Labels {
+		labels[key] = value
+	}
+	labels[VersionLabel] = strconv.Itoa(int(version))
+	result := proto.Clone(addRequest).(*grpc_application_go.AddAppDescriptorRequest)
+	result.Labels = labels
+	return result
+}
+
+// GetDescriptorHistory retrieves the versions of the descriptors with a given name and the instances deployed
+// from each of them.
+func (m *Manager) GetDescriptorHistory(ctx context.Context, request *grpc_public_api_go.DescriptorHistoryRequest) (*grpc_public_api_go.DescriptorHistory, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	orgID := &grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId}
+	descriptors, err := m.appClient.ListAppDescriptors(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := m.appClient.ListAppInstances(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	byDescriptor := make(map[string][]*grpc_public_api_go.DescriptorInstance, 0)
+	for _, instance := range instances.Instances {
+		byDescriptor[instance.AppDescriptorId] = append(byDescriptor[instance.AppDescriptorId], &grpc_public_api_go.DescriptorInstance{
+			AppInstanceId: instance.AppInstanceId,
+			Name:          instance.Name,
+			StatusName:    instance.Status.String(),
+		})
+	}
+	versions := make([]*grpc_public_api_go.DescriptorVersion, 0)
+	for _, descriptor := range descriptors.Descriptors {
+		if descriptor.Name != request.Name {
+			continue
+		}
+		versions = append(versions, &grpc_public_api_go.DescriptorVersion{
+			AppDescriptorId: descriptor.AppDescriptorId,
+			Version:         descriptorVersion(descriptor),
+			Labels:          withoutVersion(descriptor.Labels),
+			Instances:       byDescriptor[descriptor.AppDescriptorId],
+		})
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		if versions[i].Version == versions[j].Version {
+			return versions[i].AppDescriptorId < versions[j].AppDescriptorId
+		}
+		return versions[i].Version < versions[j].Version
+	})
+	return &grpc_public_api_go.DescriptorHistory{
+		OrganizationId: request.OrganizationId,
+		Name:           request.Name,
+		Versions:       versions,
+	}, nil
+}
+
+// GetDescriptorDiff retrieves the semantic changes between two descriptors.
+func (m *Manager) GetDescriptorDiff(ctx context.Context, request *grpc_public_api_go.DescriptorDiffRequest) (*grpc_public_api_go.DescriptorDiff, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	from, err := m.appClient.GetAppDescriptor(ctx, &grpc_application_go.AppDescriptorId{
+		OrganizationId:  request.OrganizationId,
+		AppDescriptorId: request.FromAppDescriptorId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	to, err := m.appClient.GetAppDescriptor(ctx, &grpc_application_go.AppDescriptorId{
+		OrganizationId:  request.OrganizationId,
+		AppDescriptorId: request.ToAppDescriptorId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &grpc_public_api_go.DescriptorDiff{
+		OrganizationId:      request.OrganizationId,
+		FromAppDescriptorId: from.AppDescriptorId,
+		FromVersion:         descriptorVersion(from),
+		ToAppDescriptorId:   to.AppDescriptorId,
+		ToVersion:           descriptorVersion(to),
+		Changes:             diffDescriptors(from, to),
+	}, nil
+}
@@ -17,76 +17,142 @@
 package applications
 
 import (
+	"context"
+	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-application-go"
 	"github.com/nalej/grpc-application-manager-go"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/selector"
 	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/nalej/public-api/internal/pkg/server/decorators"
+	"github.com/rs/zerolog/log"
+	"time"
 )
 
 type Manager struct {
 	appClient grpc_application_manager_go.ApplicationManagerClient
+	// versions serializes the versioning of the descriptors with the same name.
+	versions *versionLocks
 }
 
 func NewManager(appClient grpc_application_manager_go.ApplicationManagerClient) Manager {
-	return Manager{appClient}
+	return Manager{appClient: appClient, versions: newVersionLocks()}
 }
 
-// AddAppDescriptor adds a new application descriptor to a given organization.
-func (m *Manager) AddAppDescriptor(addRequest *grpc_application_go.AddAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
-	ctx, cancel := common.GetContext()
+// AddAppDescriptor adds a new application descriptor to a given organization. The descriptor is labeled with the
+// next version of the descriptors with the same name. The descriptors with the same name are added one at a time by
+// this process, and a descriptor whose version has been taken meanwhile by another replica is removed and added
+// again with the next version.
+func (m *Manager) AddAppDescriptor(ctx context.Context, addRequest *grpc_application_go.AddAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
+	unlock := m.versions.lock(addRequest.OrganizationId, addRequest.Name)
+	defer unlock()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
-	return m.appClient.AddAppDescriptor(ctx, addRequest)
+	orgID := &grpc_organization_go.OrganizationId{OrganizationId: addRequest.OrganizationId}
+	for attempt := 1; ; attempt++ {
+		existing, err := m.appClient.ListAppDescriptors(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		added, err := m.appClient.AddAppDescriptor(ctx, versionedRequest(addRequest, nextVersion(existing.Descriptors, addRequest.Name)))
+		if err != nil {
+			return nil, err
+		}
+		current, err := m.appClient.ListAppDescriptors(ctx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		if !versionTaken(current.Descriptors, added) {
+			return added, nil
+		}
+		log.Warn().Str("organizationID", added.OrganizationId).Str("name", added.Name).
+			Str("version", added.Labels[VersionLabel]).Msg("descriptor version taken by a concurrent request, retrying")
+		_, err = m.appClient.RemoveAppDescriptor(ctx, &grpc_application_go.AppDescriptorId{
+			OrganizationId:  added.OrganizationId,
+			AppDescriptorId: added.AppDescriptorId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if attempt == MaxVersionAttempts {
+			return nil, conversions.ToGRPCError(derrors.NewAbortedError("cannot assign a version to the application descriptor").WithParams(addRequest.Name))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, conversions.ToGRPCError(derrors.NewDeadlineExceededError("cannot assign a version to the application descriptor", ctx.Err()))
+		case <-time.After(versionRetryDelay(attempt)):
+		}
+	}
 }
 
-// ListAppDescriptors retrieves a list of application descriptors.
-func (m *Manager) ListAppDescriptors(organizationID *grpc_organization_go.OrganizationId) (*grpc_application_go.AppDescriptorList, error) {
-	ctx, cancel := common.GetContext()
+// ListAppDescriptors retrieves a list of application descriptors filtered by the label selector of the request.
+func (m *Manager) ListAppDescriptors(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_go.AppDescriptorList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
-	return m.appClient.ListAppDescriptors(ctx, organizationID)
+	list, err := m.appClient.ListAppDescriptors(ctx, organizationID)
+	if err != nil || sel.Empty() {
+		return list, err
+	}
+	filtered := decorators.ApplyDecorator(list.Descriptors, decorators.NewSelectorDecorator(sel))
+	if filtered.Error != nil {
+		return nil, conversions.ToGRPCError(filtered.Error)
+	}
+	return &grpc_application_go.AppDescriptorList{
+		Descriptors: filtered.AppDescriptorList,
+	}, nil
 }
 
 // GetAppDescriptor retrieves a given application descriptor.
-func (m *Manager) GetAppDescriptor(appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_application_go.AppDescriptor, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetAppDescriptor(ctx context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_application_go.AppDescriptor, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.GetAppDescriptor(ctx, appDescriptorID)
 }
 
 // UpdateAppDescriptor allows the user to update the information of a registered descriptor.
-func (m *Manager) UpdateAppDescriptor(request *grpc_application_go.UpdateAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateAppDescriptor(ctx context.Context, request *grpc_application_go.UpdateAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.UpdateAppDescriptor(ctx, request)
 }
 
 // DeleteAppDescriptor deletes a given application descriptor.
-func (m *Manager) DeleteAppDescriptor(appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) DeleteAppDescriptor(ctx context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.RemoveAppDescriptor(ctx, appDescriptorID)
 }
 
 // Deploy an application descriptor.
-func (m *Manager) Deploy(deployRequest *grpc_application_manager_go.DeployRequest) (*grpc_application_manager_go.DeploymentResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Deploy(ctx context.Context, deployRequest *grpc_application_manager_go.DeployRequest) (*grpc_application_manager_go.DeploymentResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.Deploy(ctx, deployRequest)
 }
 
 // Undeploy a running application instance.
-func (m *Manager) Undeploy(undeployRequest *grpc_application_manager_go.UndeployRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Undeploy(ctx context.Context, undeployRequest *grpc_application_manager_go.UndeployRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.Undeploy(ctx, undeployRequest)
 }
 
-// ListAppInstances retrieves a list of application descriptors.
-func (m *Manager) ListAppInstances(organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.AppInstanceList, error) {
-	ctx, cancel := common.GetContext()
+// ListAppInstances retrieves a list of application instances filtered by the label selector of the request.
+func (m *Manager) ListAppInstances(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.AppInstanceList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	apps, err := m.appClient.ListAppInstances(ctx, organizationID)
 	if err != nil {
@@ -96,14 +162,21 @@ func (m *Manager) ListAppInstances(organizationID *grpc_organization_go.Organiza
 	for _, app := range apps.Instances {
 		result = append(result, entities.ToPublicAPIAppInstance(app))
 	}
+	if !sel.Empty() {
+		filtered := decorators.ApplyDecorator(result, decorators.NewSelectorDecorator(sel))
+		if filtered.Error != nil {
+			return nil, conversions.ToGRPCError(filtered.Error)
+		}
+		result = filtered.AppInstanceList
+	}
 	return &grpc_public_api_go.AppInstanceList{
 		Instances: result,
 	}, nil
 }
 
 // GetAppDescriptor retrieves a given application descriptor.
-func (m *Manager) GetAppInstance(appInstanceID *grpc_application_go.AppInstanceId) (*grpc_public_api_go.AppInstance, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetAppInstance(ctx context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_public_api_go.AppInstance, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	inst, err := m.appClient.GetAppInstance(ctx, appInstanceID)
 	if err != nil {
@@ -112,16 +185,38 @@ func (m *Manager) GetAppInstance(appInstanceID *grpc_application_go.AppInstanceI
 	return entities.ToPublicAPIAppInstance(inst), nil
 }
 
+// ExportInstanceDescriptor rebuilds a descriptor that reproduces a running instance.
+func (m *Manager) ExportInstanceDescriptor(ctx context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_go.AddAppDescriptorRequest, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	inst, err := m.appClient.GetAppInstance(ctx, appInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := m.appClient.ListInstanceParameters(ctx, appInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	params, err := m.appClient.ListDescriptorAppParameters(ctx, &grpc_application_go.AppDescriptorId{
+		OrganizationId:  inst.OrganizationId,
+		AppDescriptorId: inst.AppDescriptorId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities.ToAddAppDescriptorRequest(inst, params.Parameters, values), nil
+}
+
 // ListInstanceParameters retrieves a list of instance parameters
-func (m *Manager) ListInstanceParameters(appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_go.InstanceParameterList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListInstanceParameters(ctx context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_go.InstanceParameterList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.appClient.ListInstanceParameters(ctx, appInstanceID)
 }
 
 // ListDescriptorAppParameters retrieves a list of parameters of an application
-func (m *Manager) ListDescriptorAppParameters(appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_public_api_go.AppParameterList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListDescriptorAppParameters(ctx context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_public_api_go.AppParameterList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	params, err := m.appClient.ListDescriptorAppParameters(ctx, appDescriptorID)
 	if err != nil {
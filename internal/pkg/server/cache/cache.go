@@ -0,0 +1,168 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"expvar"
+	"github.com/golang/protobuf/proto"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// metrics publishes the hit, miss and invalidation counters of all the caches through expvar.
+var metrics = expvar.NewMap("cache")
+
+// entry with a cached response.
+type entry struct {
+	value      proto.Message
+	expiration time.Time
+}
+
+// Stats with the counters of a cache.
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Invalidations uint64
+}
+
+// Cache is a read-through cache of responses partitioned by organization. The entries of an organization are
+// removed when the public API performs a mutation on it, otherwise they expire after the TTL.
+type Cache struct {
+	name  string
+	ttl   time.Duration
+	mutex sync.Mutex
+	// entries per organization and key.
+	entries map[string]map[string]entry
+	// generations per organization, incremented on each invalidation. A response is only stored if no
+	// invalidation happened while it was being loaded.
+	generations   map[string]uint64
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+	// now returns the current time. It can be replaced in tests.
+	now func() time.Time
+}
+
+// NewCache creates a cache with a given TTL. A TTL of zero or less disables the cache.
+func NewCache(name string, ttl time.Duration) *Cache {
+	return &Cache{
+		name:        name,
+		ttl:         ttl,
+		entries:     make(map[string]map[string]entry, 0),
+		generations: make(map[string]uint64, 0),
+		now:         time.Now,
+	}
+}
+
+// Key builds the key of a request to a given method.
+func Key(method string, request proto.Message) string {
+	return method + "/" + proto.CompactTextString(request)
+}
+
+// enabled checks whether responses must be cached.
+func (c *Cache) enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+// get returns a valid cached response and the current generation of the organization.
+func (c *Cache) get(organizationID string, key string) (proto.Message, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	generation := c.generations[organizationID]
+	cached, exists := c.entries[organizationID][key]
+	if !exists {
+		return nil, generation
+	}
+	if c.now().After(cached.expiration) {
+		delete(c.entries[organizationID], key)
+		return nil, generation
+	}
+	return cached.value, generation
+}
+
+// put stores a response if the organization has not been invalidated since the given generation.
+func (c *Cache) put(organizationID string, key string, value proto.Message, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.generations[organizationID] != generation {
+		return
+	}
+	orgEntries, exists := c.entries[organizationID]
+	if !exists {
+		orgEntries = make(map[string]entry, 0)
+		c.entries[organizationID] = orgEntries
+	}
+	now := c.now()
+	for k, e := range orgEntries {
+		if now.After(e.expiration) {
+			delete(orgEntries, k)
+		}
+	}
+	orgEntries[key] = entry{value: value, expiration: now.Add(c.ttl)}
+}
+
+// GetOrLoad returns the cached response of a request, or loads it and stores it if it is not found. The loader
+// reports whether the response can be cached, so partial responses are not served to later requests. The
+// returned message is a copy that can be modified by the caller.
+func (c *Cache) GetOrLoad(organizationID string, key string, loader func() (proto.Message, bool, error)) (proto.Message, error) {
+	if !c.enabled() {
+		loaded, _, err := loader()
+		return loaded, err
+	}
+	cached, generation := c.get(organizationID, key)
+	if cached != nil {
+		atomic.AddUint64(&c.hits, 1)
+		metrics.Add(c.name+".hits", 1)
+		log.Debug().Str("cache", c.name).Str("key", key).Msg("cache hit")
+		return proto.Clone(cached), nil
+	}
+	atomic.AddUint64(&c.misses, 1)
+	metrics.Add(c.name+".misses", 1)
+	loaded, cacheable, err := loader()
+	if err != nil {
+		return nil, err
+	}
+	if cacheable {
+		c.put(organizationID, key, proto.Clone(loaded), generation)
+	}
+	return loaded, nil
+}
+
+// Invalidate removes the cached responses of an organization.
+func (c *Cache) Invalidate(organizationID string) {
+	if !c.enabled() {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, organizationID)
+	c.generations[organizationID]++
+	atomic.AddUint64(&c.invalidations, 1)
+	metrics.Add(c.name+".invalidations", 1)
+	log.Debug().Str("cache", c.name).Str("organizationID", organizationID).Msg("cache invalidated")
+}
+
+// Stats returns the counters of the cache.
+func (c *Cache) Stats() Stats {
+	return Stats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Invalidations: atomic.LoadUint64(&c.invalidations),
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Response cache", func() {
+
+	const organizationID = "org"
+
+	var cache *Cache
+	var now time.Time
+	var loads int
+
+	loader := func() (proto.Message, bool, error) {
+		loads++
+		return &wrappers.StringValue{Value: "response"}, true, nil
+	}
+
+	ginkgo.BeforeEach(func() {
+		now = time.Now()
+		loads = 0
+		cache = NewCache("test", time.Second*5)
+		cache.now = func() time.Time { return now }
+	})
+
+	ginkgo.It("should serve repeated requests from the cache", func() {
+		key := Key("test.Get", &wrappers.StringValue{Value: "request"})
+		for i := 0; i < 3; i++ {
+			result, err := cache.GetOrLoad(organizationID, key, loader)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(result.(*wrappers.StringValue).Value).Should(gomega.Equal("response"))
+		}
+		gomega.Expect(loads).Should(gomega.Equal(1))
+		gomega.Expect(cache.Stats()).Should(gomega.Equal(Stats{Hits: 2, Misses: 1}))
+	})
+
+	ginkgo.It("should use different entries for different requests", func() {
+		cache.GetOrLoad(organizationID, Key("test.Get", &wrappers.StringValue{Value: "a"}), loader)
+		cache.GetOrLoad(organizationID, Key("test.Get", &wrappers.StringValue{Value: "b"}), loader)
+		gomega.Expect(loads).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should return copies of the cached responses", func() {
+		first, _ := cache.GetOrLoad(organizationID, "key", loader)
+		first.(*wrappers.StringValue).Value = "modified"
+		second, _ := cache.GetOrLoad(organizationID, "key", loader)
+		gomega.Expect(second.(*wrappers.StringValue).Value).Should(gomega.Equal("response"))
+	})
+
+	ginkgo.It("should expire the entries after the TTL", func() {
+		cache.GetOrLoad(organizationID, "key", loader)
+		now = now.Add(time.Second * 6)
+		cache.GetOrLoad(organizationID, "key", loader)
+		gomega.Expect(loads).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should invalidate the entries of an organization", func() {
+		cache.GetOrLoad(organizationID, "key", loader)
+		cache.GetOrLoad("other", "key", loader)
+		cache.Invalidate(organizationID)
+		cache.GetOrLoad(organizationID, "key", loader)
+		cache.GetOrLoad("other", "key", loader)
+		gomega.Expect(loads).Should(gomega.Equal(3))
+		gomega.Expect(cache.Stats().Invalidations).Should(gomega.Equal(uint64(1)))
+	})
+
+	ginkgo.It("should not store responses loaded during an invalidation", func() {
+		cache.GetOrLoad(organizationID, "key", func() (proto.Message, bool, error) {
+			cache.Invalidate(organizationID)
+			return loader()
+		})
+		cache.GetOrLoad(organizationID, "key", loader)
+		gomega.Expect(loads).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should not store partial responses", func() {
+		partialLoader := func() (proto.Message, bool, error) {
+			loads++
+			return &wrappers.StringValue{Value: "partial"}, false, nil
+		}
+		cache.GetOrLoad(organizationID, "key", partialLoader)
+		cache.GetOrLoad(organizationID, "key", partialLoader)
+		gomega.Expect(loads).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should always load if disabled", func() {
+		disabled := NewCache("disabled", 0)
+		disabled.GetOrLoad(organizationID, "key", loader)
+		disabled.GetOrLoad(organizationID, "key", loader)
+		gomega.Expect(loads).Should(gomega.Equal(2))
+	})
+})
@@ -54,7 +54,7 @@ func (h *Handler) Install(ctx context.Context, request *grpc_public_api_go.Insta
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	response, opErr := h.Manager.Install(request)
+	response, opErr := h.Manager.Install(ctx, request)
 	if opErr != nil {
 		return nil, opErr
 	}
@@ -71,7 +71,7 @@ func (h *Handler) ProvisionAndInstall(ctx context.Context, request *grpc_provisi
 	if request.OrganizationId != rm.OrganizationID {
 		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
 	}
-	return h.Manager.ProvisionAndInstall(request)
+	return h.Manager.ProvisionAndInstall(ctx, request)
 }
 
 // Scale the number of nodes in the cluster.
@@ -89,7 +89,7 @@ func (h *Handler) Scale(ctx context.Context, request *grpc_provisioner_go.ScaleC
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Scale(request)
+	return h.Manager.Scale(ctx, request)
 }
 
 // Uninstall a existing cluster. This process will uninstall the nalej platform and
@@ -108,7 +108,7 @@ func (h *Handler) Uninstall(ctx context.Context, request *grpc_public_api_go.Uni
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	response, opErr := h.Manager.Uninstall(request)
+	response, opErr := h.Manager.Uninstall(ctx, request)
 	if opErr != nil {
 		return nil, opErr
 	}
@@ -131,7 +131,7 @@ func (h *Handler) Decommission(ctx context.Context, request *grpc_public_api_go.
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	response, opErr := h.Manager.Decommission(request)
+	response, opErr := h.Manager.Decommission(ctx, request)
 	if opErr != nil {
 		return nil, opErr
 	}
@@ -151,7 +151,7 @@ func (h *Handler) Info(ctx context.Context, clusterID *grpc_infrastructure_go.Cl
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Info(clusterID)
+	return h.Manager.Info(ctx, clusterID)
 }
 
 // List all the clusters in an organization.
@@ -167,7 +167,7 @@ func (h *Handler) List(ctx context.Context, request *grpc_public_api_go.ListRequ
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.List(request)
+	return h.Manager.List(ctx, request)
 }
 
 // Update the cluster information.
@@ -183,7 +183,7 @@ func (h *Handler) Update(ctx context.Context, updateClusterRequest *grpc_public_
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Update(updateClusterRequest)
+	return h.Manager.Update(ctx, updateClusterRequest)
 }
 
 func (h *Handler) Cordon(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
@@ -191,7 +191,7 @@ func (h *Handler) Cordon(ctx context.Context, clusterID *grpc_infrastructure_go.
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Cordon(clusterID)
+	return h.Manager.Cordon(ctx, clusterID)
 }
 
 func (h *Handler) Uncordon(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
@@ -199,7 +199,7 @@ func (h *Handler) Uncordon(ctx context.Context, clusterID *grpc_infrastructure_g
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Uncordon(clusterID)
+	return h.Manager.Uncordon(ctx, clusterID)
 }
 
 func (h *Handler) Drain(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
@@ -207,5 +207,5 @@ func (h *Handler) Drain(ctx context.Context, clusterID *grpc_infrastructure_go.C
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.DrainCluster(clusterID)
+	return h.Manager.DrainCluster(ctx, clusterID)
 }
@@ -34,6 +34,7 @@ import (
 	"github.com/nalej/grpc-organization-manager-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
 	"github.com/nalej/public-api/internal/pkg/utils"
 	"github.com/onsi/ginkgo"
@@ -101,7 +102,7 @@ var _ = ginkgo.Describe("Clusters", func() {
 		conn, err := test.GetConn(*listener)
 		gomega.Expect(err).To(gomega.Succeed())
 
-		manager := NewManager(clustClient, nodeClient, infraClient)
+		manager := NewManager(clustClient, nodeClient, infraClient, cache.NewCache("clusters", 0))
 		handler := NewHandler(manager)
 		grpc_public_api_go.RegisterClustersServer(server, handler)
 		test.LaunchServer(server, listener)
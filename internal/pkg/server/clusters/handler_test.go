@@ -0,0 +1,160 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package clusters
+
+import (
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"time"
+)
+
+var _ = ginkgo.Describe("Clusters handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.ClustersClient
+
+	var organizationID string
+	var targetCluster *grpc_infrastructure_go.Cluster
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+
+		organizationID = ithelpers.GenerateUUID()
+		targetCluster = platform.Store.AddCluster(&grpc_infrastructure_go.Cluster{
+			OrganizationId: organizationID,
+			Name:           "cluster",
+		})
+		platform.Store.AddNode(&grpc_infrastructure_go.Node{
+			OrganizationId: organizationID,
+			ClusterId:      targetCluster.ClusterId,
+			Status:         grpc_infrastructure_go.InfraStatus_RUNNING,
+		})
+		platform.Store.AddNode(&grpc_infrastructure_go.Node{
+			OrganizationId: organizationID,
+			ClusterId:      targetCluster.ClusterId,
+		})
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(grpc_infrastructure_go.NewClustersClient(platform.Conn()),
+			grpc_infrastructure_go.NewNodesClient(platform.Conn()),
+			grpc_infrastructure_manager_go.NewInfrastructureManagerClient(platform.Conn()),
+			cache.NewCache("clusters", time.Minute))
+		grpc_public_api_go.RegisterClustersServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewClustersClient(conn)
+
+		token = ithelpers.GenerateToken("email@nalej.com", organizationID, "Owner", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_ORG})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	ginkgo.It("should list the clusters with their node statistics", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		list, err := client.List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Clusters)).To(gomega.Equal(1))
+		gomega.Expect(list.Clusters[0].ClusterId).To(gomega.Equal(targetCluster.ClusterId))
+		gomega.Expect(list.Clusters[0].TotalNodes).To(gomega.Equal(int64(2)))
+		gomega.Expect(list.Clusters[0].RunningNodes).To(gomega.Equal(int64(1)))
+	})
+
+	ginkgo.It("should report unknown node statistics if the nodes cannot be retrieved", func() {
+		platform.Faults.FailOn("Nodes/ListNodes", status.Error(codes.Unavailable, "system model unavailable"))
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		list, err := client.List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Clusters)).To(gomega.Equal(1))
+		gomega.Expect(list.Clusters[0].TotalNodes).To(gomega.Equal(entities.UnknownCount))
+		gomega.Expect(list.Clusters[0].RunningNodes).To(gomega.Equal(entities.UnknownCount))
+	})
+
+	ginkgo.It("should serve repeated lists from the cache until a cluster is updated", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		request := &grpc_public_api_go.ListRequest{OrganizationId: organizationID}
+		_, err := client.List(ctx, request)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.List(ctx, request)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(platform.Faults.Calls("InfrastructureManager/ListClusters")).To(gomega.Equal(1))
+
+		updated, err := client.Update(ctx, &grpc_public_api_go.UpdateClusterRequest{
+			OrganizationId: organizationID,
+			ClusterId:      targetCluster.ClusterId,
+			UpdateName:     true,
+			Name:           "renamed",
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(updated.Name).To(gomega.Equal("renamed"))
+
+		list, err := client.List(ctx, request)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Clusters[0].Name).To(gomega.Equal("renamed"))
+		gomega.Expect(platform.Faults.Calls("InfrastructureManager/ListClusters")).To(gomega.Equal(2))
+	})
+
+	ginkgo.It("should return the upstream error when a cluster does not exist", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.Info(ctx, &grpc_infrastructure_go.ClusterId{
+			OrganizationId: organizationID,
+			ClusterId:      ithelpers.GenerateUUID(),
+		})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.NotFound))
+	})
+
+	ginkgo.It("should not access the clusters of another organization", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.List(ctx, &grpc_public_api_go.ListRequest{OrganizationId: ithelpers.GenerateUUID()})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(platform.Faults.Calls("InfrastructureManager/ListClusters")).To(gomega.Equal(0))
+	})
+
+})
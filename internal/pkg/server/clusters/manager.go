@@ -17,6 +17,8 @@
 package clusters
 
 import (
+	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-infrastructure-go"
 	"github.com/nalej/grpc-infrastructure-manager-go"
@@ -26,6 +28,8 @@ import (
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/common"
 	"github.com/nalej/public-api/internal/pkg/server/decorators"
 	"github.com/rs/zerolog/log"
@@ -37,28 +41,32 @@ type Manager struct {
 	clustClient grpc_infrastructure_go.ClustersClient
 	nodeClient  grpc_infrastructure_go.NodesClient
 	infraClient grpc_infrastructure_manager_go.InfrastructureManagerClient
+	// cache with the cluster lists. Every operation that modifies a cluster invalidates its organization.
+	cache *cache.Cache
 }
 
 // NewManager creates a Manager using a set of clients.
 func NewManager(clustClient grpc_infrastructure_go.ClustersClient,
 	nodeClient grpc_infrastructure_go.NodesClient,
-	infraClient grpc_infrastructure_manager_go.InfrastructureManagerClient) Manager {
+	infraClient grpc_infrastructure_manager_go.InfrastructureManagerClient,
+	responseCache *cache.Cache) Manager {
 	return Manager{
 		clustClient: clustClient,
 		nodeClient:  nodeClient,
 		infraClient: infraClient,
+		cache:       responseCache,
 	}
 }
 
 // clusterNodeStats determines the number of total and running nodes in a cluster.
-func (m *Manager) clusterNodesStats(organizationID string, clusterID string) (int64, int64, error) {
+func (m *Manager) clusterNodesStats(ctx context.Context, organizationID string, clusterID string) (int64, int64, error) {
 	runningNodes := 0
 
 	cID := &grpc_infrastructure_go.ClusterId{
 		OrganizationId: organizationID,
 		ClusterId:      clusterID,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	clusterNodes, err := m.nodeClient.ListNodes(ctx, cID)
 	if err != nil {
@@ -73,7 +81,8 @@ func (m *Manager) clusterNodesStats(organizationID string, clusterID string) (in
 }
 
 // Install a new cluster adding it to the system.
-func (m *Manager) Install(request *grpc_public_api_go.InstallRequest) (*grpc_common_go.OpResponse, error) {
+func (m *Manager) Install(ctx context.Context, request *grpc_public_api_go.InstallRequest) (*grpc_common_go.OpResponse, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
 	installRequest := &grpc_installer_go.InstallRequest{
 		OrganizationId:    request.OrganizationId,
 		ClusterId:         request.ClusterId,
@@ -87,29 +96,32 @@ func (m *Manager) Install(request *grpc_public_api_go.InstallRequest) (*grpc_com
 		TargetPlatform:    grpc_installer_go.Platform(grpc_installer_go.Platform_value[request.TargetPlatform.String()]),
 		StaticIpAddresses: request.StaticIpAddresses,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.InstallCluster(ctx, installRequest)
 }
 
 // Provision and install a new cluster adding it to the system.
-func (m *Manager) ProvisionAndInstall(request *grpc_provisioner_go.ProvisionClusterRequest) (*grpc_infrastructure_manager_go.ProvisionerResponse, error) {
+func (m *Manager) ProvisionAndInstall(ctx context.Context, request *grpc_provisioner_go.ProvisionClusterRequest) (*grpc_infrastructure_manager_go.ProvisionerResponse, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
 	request.RequestId = uuid.NewV4().String()
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.ProvisionAndInstallCluster(ctx, request)
 }
 
 // Scale the number of nodes in the cluster.
-func (m *Manager) Scale(request *grpc_provisioner_go.ScaleClusterRequest) (*grpc_infrastructure_manager_go.ProvisionerResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Scale(ctx context.Context, request *grpc_provisioner_go.ScaleClusterRequest) (*grpc_infrastructure_manager_go.ProvisionerResponse, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.Scale(ctx, request)
 }
 
 // Uninstall a existing cluster. This process will uninstall the nalej platform and
 // remove the cluster from the list.
-func (m *Manager) Uninstall(request *grpc_public_api_go.UninstallClusterRequest) (*grpc_common_go.OpResponse, error) {
+func (m *Manager) Uninstall(ctx context.Context, request *grpc_public_api_go.UninstallClusterRequest) (*grpc_common_go.OpResponse, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
 	imPlatform, err := entities.ToInstallerTargetPlatform(request.TargetPlatform)
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
@@ -121,19 +133,20 @@ func (m *Manager) Uninstall(request *grpc_public_api_go.UninstallClusterRequest)
 		KubeConfigRaw:  request.KubeConfigRaw,
 		TargetPlatform: *imPlatform,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.Uninstall(ctx, imRequest)
 }
 
 // Decommission an application cluster. This process will uninstall the nalej platform,
 // decommission the cluster from the infrastructure provider, and remove the cluster from the list.
-func (m *Manager) Decommission(request *grpc_public_api_go.DecommissionClusterRequest) (*grpc_common_go.OpResponse, error) {
+func (m *Manager) Decommission(ctx context.Context, request *grpc_public_api_go.DecommissionClusterRequest) (*grpc_common_go.OpResponse, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
 	imPlatform, err := entities.ToInstallerTargetPlatform(request.TargetPlatform)
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	dRequest := &grpc_provisioner_go.DecommissionClusterRequest{
 		OrganizationId:      request.OrganizationId,
@@ -147,88 +160,129 @@ func (m *Manager) Decommission(request *grpc_public_api_go.DecommissionClusterRe
 	return m.infraClient.DecommissionCluster(ctx, dRequest)
 }
 
-func (m *Manager) extendInfo(source *grpc_infrastructure_go.Cluster) (*grpc_public_api_go.Cluster, error) {
-	totalNodes, runningNodes, err := m.clusterNodesStats(source.OrganizationId, source.ClusterId)
+func (m *Manager) extendInfo(ctx context.Context, source *grpc_infrastructure_go.Cluster) (*grpc_public_api_go.Cluster, error) {
+	totalNodes, runningNodes, err := m.clusterNodesStats(ctx, source.OrganizationId, source.ClusterId)
 	if err != nil {
 		return nil, err
 	}
 	return entities.ToPublicAPICluster(source, totalNodes, runningNodes), nil
 }
 
-func (m *Manager) Info(clusterID *grpc_infrastructure_go.ClusterId) (*grpc_public_api_go.Cluster, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Info(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_public_api_go.Cluster, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	retrieved, err := m.infraClient.GetCluster(ctx, clusterID)
 	if err != nil {
 		return nil, err
 	}
-	return m.extendInfo(retrieved)
+	return m.extendInfo(ctx, retrieved)
 }
 
-// List all the clusters in an organization.
-func (m *Manager) List(request *grpc_public_api_go.ListRequest) (*grpc_public_api_go.ClusterList, error) {
-	ctx, cancel := common.GetContext()
+// List all the clusters in an organization. The cached list is filtered by the label selector of the request.
+func (m *Manager) List(ctx context.Context, request *grpc_public_api_go.ListRequest) (*grpc_public_api_go.ClusterList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	list, err := m.cache.GetOrLoad(request.OrganizationId, cache.Key("clusters.List", request), func() (proto.Message, bool, error) {
+		return m.list(ctx, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+	clusters := list.(*grpc_public_api_go.ClusterList)
+	if sel.Empty() {
+		return clusters, nil
+	}
+	filtered := decorators.ApplyDecorator(clusters.Clusters, decorators.NewSelectorDecorator(sel))
+	if filtered.Error != nil {
+		return nil, conversions.ToGRPCError(filtered.Error)
+	}
+	return &grpc_public_api_go.ClusterList{
+		Clusters: filtered.ClusterList,
+	}, nil
+}
+
+// list retrieves the clusters of an organization from the infrastructure manager. The node statistics of the
+// clusters are retrieved in parallel. If they cannot be retrieved for a cluster, its node counters are set to
+// entities.UnknownCount and the list is reported as partial.
+func (m *Manager) list(ctx context.Context, request *grpc_public_api_go.ListRequest) (*grpc_public_api_go.ClusterList, bool, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	list, err := m.infraClient.ListClusters(ctx, &grpc_organization_go.OrganizationId{
 		OrganizationId: request.OrganizationId,
 	})
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
-	clusters := make([]*grpc_public_api_go.Cluster, 0)
-	for _, c := range list.Clusters {
-		toAdd, err := m.extendInfo(c)
+	clusters := make([]*grpc_public_api_go.Cluster, len(list.Clusters))
+	errs := common.FanOut(ctx, len(list.Clusters), func(callCtx context.Context, index int) error {
+		toAdd, err := m.extendInfo(callCtx, list.Clusters[index])
 		if err != nil {
-			return nil, err
+			return err
+		}
+		clusters[index] = toAdd
+		return nil
+	})
+	complete := true
+	for index, err := range errs {
+		if err != nil {
+			source := list.Clusters[index]
+			log.Warn().Str("clusterID", source.ClusterId).Err(err).Msg("cannot retrieve node statistics, reporting them as unknown")
+			clusters[index] = entities.ToPublicAPICluster(source, entities.UnknownCount, entities.UnknownCount)
+			complete = false
 		}
-		clusters = append(clusters, toAdd)
 	}
 
 	if request.Order != nil {
 		sortOptions := decorators.NewOrderOptions(*request.Order)
 		sortedClusters := decorators.ApplyDecorator(clusters, decorators.NewOrderDecorator(sortOptions))
 		if sortedClusters.Error != nil {
-			return nil, conversions.ToGRPCError(sortedClusters.Error)
+			return nil, false, conversions.ToGRPCError(sortedClusters.Error)
 		}
 		clusters = sortedClusters.ClusterList
 	}
 
 	return &grpc_public_api_go.ClusterList{
 		Clusters: clusters,
-	}, nil
+	}, complete, nil
 }
 
 // Update the cluster information.
-func (m *Manager) Update(updateClusterRequest *grpc_public_api_go.UpdateClusterRequest) (*grpc_public_api_go.Cluster, error) {
+func (m *Manager) Update(ctx context.Context, updateClusterRequest *grpc_public_api_go.UpdateClusterRequest) (*grpc_public_api_go.Cluster, error) {
+	defer m.cache.Invalidate(updateClusterRequest.OrganizationId)
 	log.Debug().Interface("request", updateClusterRequest).Msg("update cluster request")
 	toSend := entities.ToInfraClusterUpdate(*updateClusterRequest)
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	updated, err := m.infraClient.UpdateCluster(ctx, toSend)
 	if err != nil {
 		return nil, err
 	}
-	result, err := m.extendInfo(updated)
+	result, err := m.extendInfo(ctx, updated)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
 
-func (m *Manager) Cordon(clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Cordon(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	defer m.cache.Invalidate(clusterID.OrganizationId)
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.CordonCluster(ctx, clusterID)
 }
 
-func (m *Manager) Uncordon(clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Uncordon(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	defer m.cache.Invalidate(clusterID.OrganizationId)
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.UncordonCluster(ctx, clusterID)
 }
 
-func (m *Manager) DrainCluster(clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) DrainCluster(ctx context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	defer m.cache.Invalidate(clusterID.OrganizationId)
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.infraClient.DrainCluster(ctx, clusterID)
 
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	// FanOutParallelism with the maximum number of concurrent calls issued by a fan-out.
+	FanOutParallelism = 8
+	// FanOutCallTimeout with the deadline of each call issued by a fan-out.
+	FanOutCallTimeout = time.Second * 10
+)
+
+// FanOut runs a task for each index in [0, count) with at most FanOutParallelism tasks running at the same time.
+// Each task receives a context that expires after FanOutCallTimeout so a slow call does not delay the rest. The
+// error of each task is returned in the position of its index.
+func FanOut(ctx context.Context, count int, task func(ctx context.Context, index int) error) []error {
+	errs := make([]error, count)
+	semaphore := make(chan struct{}, FanOutParallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(index int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			callCtx, cancel := context.WithTimeout(ctx, FanOutCallTimeout)
+			defer cancel()
+			errs[index] = task(callCtx, index)
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package common
+
+import (
+	"context"
+	"fmt"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"sync/atomic"
+	"time"
+)
+
+var _ = ginkgo.Describe("Fan-out", func() {
+
+	ginkgo.It("should bound the number of concurrent tasks", func() {
+		var running, maxRunning int32
+		errs := FanOut(context.Background(), FanOutParallelism*3, func(ctx context.Context, index int) error {
+			current := atomic.AddInt32(&running, 1)
+			for {
+				observed := atomic.LoadInt32(&maxRunning)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxRunning, observed, current) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond * 10)
+			atomic.AddInt32(&running, -1)
+			return nil
+		})
+		gomega.Expect(errs).Should(gomega.HaveLen(FanOutParallelism * 3))
+		gomega.Expect(maxRunning).Should(gomega.BeNumerically("<=", FanOutParallelism))
+		gomega.Expect(maxRunning).Should(gomega.BeNumerically(">", 1))
+	})
+
+	ginkgo.It("should report the error of each task in its position", func() {
+		errs := FanOut(context.Background(), 4, func(ctx context.Context, index int) error {
+			if index%2 == 1 {
+				return fmt.Errorf("task %d failed", index)
+			}
+			return nil
+		})
+		gomega.Expect(errs[0]).To(gomega.Succeed())
+		gomega.Expect(errs[1]).ShouldNot(gomega.Succeed())
+		gomega.Expect(errs[2]).To(gomega.Succeed())
+		gomega.Expect(errs[3]).ShouldNot(gomega.Succeed())
+	})
+
+	ginkgo.It("should set a deadline on each task", func() {
+		var deadline time.Time
+		var hasDeadline bool
+		FanOut(context.Background(), 1, func(ctx context.Context, index int) error {
+			deadline, hasDeadline = ctx.Deadline()
+			return nil
+		})
+		gomega.Expect(hasDeadline).Should(gomega.BeTrue())
+		gomega.Expect(time.Until(deadline)).Should(gomega.BeNumerically("<=", FanOutCallTimeout))
+	})
+
+	ginkgo.It("should keep the deadline of the parent in internal contexts", func() {
+		parent, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		ctx, cancelInternal := GetContext(parent)
+		defer cancelInternal()
+		deadline, _ := ctx.Deadline()
+		gomega.Expect(time.Until(deadline)).Should(gomega.BeNumerically("<=", time.Second))
+	})
+})
@@ -19,6 +19,7 @@ package common
 import (
 	"context"
 	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/metadata"
 	"time"
 )
@@ -29,14 +30,29 @@ const (
 )
 
 // GetContext returns a context with a default timeout for internal communications. Notice that the context does not
-// have any security related information attached to it.
-func GetContext() (context.Context, context.CancelFunc) {
-	return context.WithTimeout(context.Background(), DefaultTimeout)
+// have any security related information attached to it. Only the tracing span of the parent context is kept so that
+// outgoing calls are linked to the incoming request, and its deadline if it expires before the default timeout.
+func GetContext(parent context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(detach(parent), parent)
 }
 
-func GetContextWithUser(userId string) (context.Context, context.CancelFunc) {
+// GetContextWithUser returns a context for internal communications that carries the user identifier as metadata.
+func GetContextWithUser(parent context.Context, userId string) (context.Context, context.CancelFunc) {
 	md := metadata.New(map[string]string{UserID: userId})
 	log.Debug().Interface("md", md).Msg("metadata has been created")
-	baseContext, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	baseContext, cancel := withTimeout(detach(parent), parent)
 	return metadata.NewOutgoingContext(baseContext, md), cancel
 }
+
+// detach returns a background context that only keeps the tracing span of the parent.
+func detach(parent context.Context) context.Context {
+	return trace.ContextWithSpan(context.Background(), trace.SpanFromContext(parent))
+}
+
+// withTimeout applies the default timeout to a context, or the deadline of the parent if it is earlier.
+func withTimeout(ctx context.Context, parent context.Context) (context.Context, context.CancelFunc) {
+	if deadline, ok := parent.Deadline(); ok && time.Until(deadline) < DefaultTimeout {
+		return context.WithDeadline(ctx, deadline)
+	}
+	return context.WithTimeout(ctx, DefaultTimeout)
+}
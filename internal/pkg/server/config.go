@@ -17,114 +17,266 @@
 package server
 
 import (
+	"fmt"
 	"github.com/nalej/authx/pkg/interceptor"
 	"github.com/nalej/derrors"
+	"github.com/nalej/public-api/internal/pkg/server/ratelimit"
+	"github.com/nalej/public-api/internal/pkg/server/tracing"
 	"github.com/nalej/public-api/version"
 	"github.com/rs/zerolog/log"
+	"gopkg.in/yaml.v2"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	// Debug level is active.
-	Debug bool
+	Debug bool `yaml:"debug"`
 	// Port where the gRPC API service will listen requests.
-	Port int
+	Port int `yaml:"port"`
 	// HTTPPort where the HTTP gRPC gateway will be listening.
-	HTTPPort int
+	HTTPPort int `yaml:"httpPort"`
 	// SystemModelAddress with the host:port to connect to System Model
-	SystemModelAddress string
+	SystemModelAddress string `yaml:"systemModelAddress"`
 	// InfrastructureManagerAddress with the host:port to connect to the Infrastructure Manager.
-	InfrastructureManagerAddress string
+	InfrastructureManagerAddress string `yaml:"infrastructureManagerAddress"`
 	// ApplicationsManagerAddress with the host:port to connect to the Applications manager.
-	ApplicationsManagerAddress string
+	ApplicationsManagerAddress string `yaml:"applicationsManagerAddress"`
 	// UserManagerAddress with the host:port to connect to the Access manager.
-	UserManagerAddress string
+	UserManagerAddress string `yaml:"userManagerAddress"`
 	// DeviceManagerAddress with the host:port to connect to the Device Manager component.
-	DeviceManagerAddress string
+	DeviceManagerAddress string `yaml:"deviceManagerAddress"`
 	// MonitoringManagerAddress with the host:port to connect to the Monitoring Manager component.
-	MonitoringManagerAddress string
+	MonitoringManagerAddress string `yaml:"monitoringManagerAddress"`
 	// InventoryManagerAddress with the host:port to connect to the Inventory Manager component.
-	InventoryManagerAddress string
+	InventoryManagerAddress string `yaml:"inventoryManagerAddress"`
 	// ProvisionerManagerAddress with the host:port to connect to the Provisioner Manager component.
-	ProvisionerManagerAddress string
+	ProvisionerManagerAddress string `yaml:"provisionerManagerAddress"`
 	// LogDownloadManagerAddress with the host:port to connect to the Log-Download Manager component.
-	LogDownloadManagerAddress string
+	LogDownloadManagerAddress string `yaml:"logDownloadManagerAddress"`
 	// OrganizationManagerAddress with the host:port to connect to the Organization Manager component.
-	OrganizationManagerAddress string
+	OrganizationManagerAddress string `yaml:"organizationManagerAddress"`
 	// AuthSecret contains the shared authx secret.
-	AuthSecret string
+	AuthSecret string `yaml:"authSecret"`
 	// AuthHeader contains the name of the target header.
-	AuthHeader string
+	AuthHeader string `yaml:"authHeader"`
 	// AuthConfigPath contains the path of the file with the authentication configuration.
-	AuthConfigPath string
+	AuthConfigPath string `yaml:"authConfigPath"`
+	// TLSCertPath contains the path of the PEM certificate used to terminate TLS. TLS is disabled if empty.
+	TLSCertPath string `yaml:"tlsCertPath"`
+	// TLSKeyPath contains the path of the PEM private key associated with TLSCertPath.
+	TLSKeyPath string `yaml:"tlsKeyPath"`
+	// TLSClientCAPath contains the path of the CA used to verify client certificates.
+	TLSClientCAPath string `yaml:"tlsClientCAPath"`
+	// TLSRequireClientCert determines whether gRPC clients must present a certificate signed by TLSClientCAPath.
+	TLSRequireClientCert bool `yaml:"tlsRequireClientCert"`
+	// TracingExporter with the destination of the trace spans: none, stdout or otlp.
+	TracingExporter string `yaml:"tracingExporter"`
+	// TracingEndpoint with the host:port of the OTLP/HTTP collector.
+	TracingEndpoint string `yaml:"tracingEndpoint"`
+	// RateLimitEnabled determines whether the per-organization and per-user quotas are enforced.
+	RateLimitEnabled bool `yaml:"rateLimitEnabled"`
+	// RateLimitConfigPath contains the path of the YAML file with the quotas. The default quotas are used if empty.
+	RateLimitConfigPath string `yaml:"rateLimitConfigPath"`
+	// CacheTTL with the time the cluster lists and resource summaries are cached. Zero disables the cache.
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// IdempotencyWindow with the time the responses of the requests sent with an idempotency key are replayed.
+	// Zero disables the idempotency keys.
+	IdempotencyWindow time.Duration `yaml:"idempotencyWindow"`
+	// SavedSearchesPath contains the path of the JSON file where the saved log searches and the alert rules are kept.
+	// It is required unless in mock mode, where they are only kept in memory if empty.
+	SavedSearchesPath string `yaml:"savedSearchesPath"`
+	// WebhookSecretKey contains the key used to encrypt the webhook secrets of the alert rules. The authorization
+	// secret is used if empty, in which case rotating it makes the stored webhook secrets unreadable.
+	WebhookSecretKey string `yaml:"webhookSecretKey"`
+	// AlertCheckInterval with the time between two checks of the alert rules that are due. Zero disables the alerts.
+	AlertCheckInterval time.Duration `yaml:"alertCheckInterval"`
+	// WebhookAllowedHosts with the only hosts the alert webhooks can reach. Any host with public addresses can be
+	// reached if empty.
+	WebhookAllowedHosts []string `yaml:"webhookAllowedHosts"`
+	// MetricsPort where the internal metrics are served. Zero disables the metrics listener.
+	MetricsPort int `yaml:"metricsPort"`
+	// Mock determines whether the upstream components are replaced by in-memory fakes with fixture data.
+	Mock bool `yaml:"mock"`
+	// MockLoginPort where the fake login service listens in mock mode.
+	MockLoginPort int `yaml:"mockLoginPort"`
 }
 
-func (conf *Config) Validate() derrors.Error {
+// MockAuthHeader with the authorization header used in mock mode if none is set.
+const MockAuthHeader = "authorization"
+
+// MockAuthSecret with the secret used to sign the mock tokens if none is set.
+const MockAuthSecret = "mock-secret"
+
+// ApplyMockDefaults fills in the authorization settings that are optional in mock mode.
+func (conf *Config) ApplyMockDefaults() {
+	if conf.AuthHeader == "" {
+		conf.AuthHeader = MockAuthHeader
+	}
+	if conf.AuthSecret == "" {
+		conf.AuthSecret = MockAuthSecret
+	}
+}
+
+// ValidationReport checks the configuration and returns the list of problems found. The list is empty if the
+// configuration is valid.
+func (conf *Config) ValidationReport() []string {
+	problems := make([]string, 0)
 
 	if conf.Port <= 0 || conf.HTTPPort <= 0 {
-		return derrors.NewInvalidArgumentError("ports must be valid")
+		problems = append(problems, "ports must be valid")
 	}
 
-	if conf.SystemModelAddress == "" {
-		return derrors.NewInvalidArgumentError("systemModelAddress must be set")
+	requiredAddresses := []struct {
+		name  string
+		value string
+	}{
+		{"systemModelAddress", conf.SystemModelAddress},
+		{"infrastructureManagerAddress", conf.InfrastructureManagerAddress},
+		{"applicationsManagerAddress", conf.ApplicationsManagerAddress},
+		{"userManagerAddress", conf.UserManagerAddress},
+		{"deviceManagerAddress", conf.DeviceManagerAddress},
+		{"monitoringManagerAddress", conf.MonitoringManagerAddress},
+		{"inventoryManagerAddress", conf.InventoryManagerAddress},
+		{"provisionerManagerAddress", conf.ProvisionerManagerAddress},
+		{"logDownloadManagerAddress", conf.LogDownloadManagerAddress},
+		{"organizationManagerAddress", conf.OrganizationManagerAddress},
+	}
+	// The upstream components and the permissions are not used in mock mode.
+	if !conf.Mock {
+		for _, address := range requiredAddresses {
+			if address.value == "" {
+				problems = append(problems, fmt.Sprintf("%s must be set", address.name))
+			}
+		}
+		if conf.AuthConfigPath == "" {
+			problems = append(problems, "authConfigPath must be set")
+		}
+	} else if conf.MockLoginPort <= 0 {
+		problems = append(problems, "mockLoginPort must be valid")
 	}
 
-	if conf.InfrastructureManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("infrastructureManagerAddress must be set")
+	if conf.AuthHeader == "" || conf.AuthSecret == "" {
+		problems = append(problems, "Authorization header and secret must be set")
 	}
 
-	if conf.ApplicationsManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("applicationsManagerAddress must be set")
+	if (conf.TLSCertPath == "") != (conf.TLSKeyPath == "") {
+		problems = append(problems, "tlsCertPath and tlsKeyPath must be set together")
 	}
 
-	if conf.UserManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("userManagerAddress must be set")
+	if conf.TLSClientCAPath != "" && !conf.UseTLS() {
+		problems = append(problems, "tlsClientCAPath requires tlsCertPath and tlsKeyPath")
 	}
 
-	if conf.DeviceManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("deviceManagerAddress must be set")
+	if conf.TLSRequireClientCert && conf.TLSClientCAPath == "" {
+		problems = append(problems, "tlsRequireClientCert requires tlsClientCAPath")
 	}
 
-	if conf.MonitoringManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("monitoringManagerAddress must be set")
+	if conf.CacheTTL < 0 {
+		problems = append(problems, "cacheTTL cannot be negative")
 	}
 
-	if conf.InventoryManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("inventoryManagerAddress must be set")
+	if conf.IdempotencyWindow < 0 {
+		problems = append(problems, "idempotencyWindow cannot be negative")
 	}
 
-	if conf.ProvisionerManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("provisionerManagerAddress must be set")
+	if conf.SavedSearchesPath == "" && !conf.Mock {
+		problems = append(problems, "savedSearchesPath must be set")
 	}
 
-	if conf.LogDownloadManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("LogDownloadManagerAddress must be set")
+	if conf.AlertCheckInterval < 0 {
+		problems = append(problems, "alertCheckInterval cannot be negative")
 	}
 
-	if conf.OrganizationManagerAddress == "" {
-		return derrors.NewInvalidArgumentError("OrganizationManagerAddress must be set")
+	if conf.MetricsPort < 0 {
+		problems = append(problems, "metricsPort must be valid")
 	}
 
-	if conf.AuthHeader == "" || conf.AuthSecret == "" {
-		return derrors.NewInvalidArgumentError("Authorization header and secret must be set")
+	if !tracing.ValidExporter(conf.TracingExporter) {
+		problems = append(problems, fmt.Sprintf("tracingExporter must be one of %s, %s or %s",
+			tracing.NoneExporter, tracing.StdoutExporter, tracing.OTLPExporter))
 	}
 
-	if conf.AuthConfigPath == "" {
-		return derrors.NewInvalidArgumentError("authConfigPath must be set")
+	if conf.TracingExporter == tracing.OTLPExporter && conf.TracingEndpoint == "" {
+		problems = append(problems, "tracingEndpoint must be set to use the otlp exporter")
 	}
 
+	return problems
+}
+
+// Validate checks the configuration reporting all the problems found in a single error.
+func (conf *Config) Validate() derrors.Error {
+	problems := conf.ValidationReport()
+	if len(problems) > 0 {
+		return derrors.NewInvalidArgumentError(fmt.Sprintf("invalid configuration: %s", strings.Join(problems, "; ")))
+	}
 	return nil
 }
 
-// LoadAuthConfig loads the security configuration.
+// UseTLS checks whether the listeners must terminate TLS.
+func (conf *Config) UseTLS() bool {
+	return conf.TLSCertPath != ""
+}
+
+// LoadRateLimitConfig loads the quotas enforced on the gRPC API.
+func (conf *Config) LoadRateLimitConfig() (*ratelimit.Config, derrors.Error) {
+	if conf.RateLimitConfigPath == "" {
+		return ratelimit.NewDefaultConfig(), nil
+	}
+	return ratelimit.LoadConfig(conf.RateLimitConfigPath)
+}
+
+// LoadAuthConfig loads the security configuration. In mock mode all the methods are allowed, the permissions
+// are filled in once the services are registered.
 func (conf *Config) LoadAuthConfig() (*interceptor.AuthorizationConfig, derrors.Error) {
+	if conf.Mock {
+		return &interceptor.AuthorizationConfig{
+			AllowsAll:   true,
+			Permissions: make(map[string]interceptor.Permission, 0),
+		}, nil
+	}
 	return interceptor.LoadAuthorizationConfig(conf.AuthConfigPath)
 }
 
+// WebhookKey returns the key used to encrypt the webhook secrets of the alert rules.
+func (conf *Config) WebhookKey() string {
+	if conf.WebhookSecretKey != "" {
+		return conf.WebhookSecretKey
+	}
+	return conf.AuthSecret
+}
+
+// maskSecret hides the content of a secret value keeping its length.
+func maskSecret(secret string) string {
+	return strings.Repeat("*", len(secret))
+}
+
+// Masked returns a copy of the configuration with the secrets hidden.
+func (conf *Config) Masked() Config {
+	masked := *conf
+	masked.AuthSecret = maskSecret(conf.AuthSecret)
+	masked.WebhookSecretKey = maskSecret(conf.WebhookSecretKey)
+	return masked
+}
+
+// ToYAML returns the YAML representation of the configuration with the secrets hidden. The result can be used
+// as a configuration file once the secrets are filled in.
+func (conf *Config) ToYAML() ([]byte, derrors.Error) {
+	masked := conf.Masked()
+	content, err := yaml.Marshal(&masked)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot marshal configuration")
+	}
+	return content, nil
+}
+
 func (conf *Config) Print() {
 	log.Info().Str("app", version.AppVersion).Str("commit", version.Commit).Msg("Version")
 	log.Info().Int("port", conf.Port).Msg("gRPC port")
 	log.Info().Int("port", conf.HTTPPort).Msg("HTTP port")
+	if conf.Mock {
+		log.Warn().Int("loginPort", conf.MockLoginPort).Msg("Mock mode: upstream components replaced by in-memory fakes")
+	}
 	log.Info().Str("URL", conf.SystemModelAddress).Msg("System Model")
 	log.Info().Str("URL", conf.InfrastructureManagerAddress).Msg("Infrastructure Manager")
 	log.Info().Str("URL", conf.ApplicationsManagerAddress).Msg("Applications Manager")
@@ -136,7 +288,39 @@ func (conf *Config) Print() {
 	log.Info().Str("URL", conf.LogDownloadManagerAddress).Msg("LogDownload Manager service")
 	log.Info().Str("URL", conf.OrganizationManagerAddress).Msg("Organization Manager service")
 
-	log.Info().Str("header", conf.AuthHeader).Str("secret", strings.Repeat("*", len(conf.AuthSecret))).Msg("Authorization")
+	log.Info().Str("header", conf.AuthHeader).Str("secret", maskSecret(conf.AuthSecret)).Msg("Authorization")
 	log.Info().Str("path", conf.AuthConfigPath).Msg("Permissions file")
+	if conf.UseTLS() {
+		log.Info().Str("cert", conf.TLSCertPath).Str("key", conf.TLSKeyPath).Msg("TLS enabled")
+		if conf.TLSClientCAPath != "" {
+			log.Info().Str("clientCA", conf.TLSClientCAPath).Bool("required", conf.TLSRequireClientCert).Msg("Client certificates")
+		}
+	} else {
+		log.Warn().Msg("TLS disabled")
+	}
+	if conf.RateLimitEnabled {
+		log.Info().Str("config", conf.RateLimitConfigPath).Msg("Rate limiting enabled")
+	} else {
+		log.Warn().Msg("Rate limiting disabled")
+	}
+	log.Info().Str("TTL", conf.CacheTTL.String()).Msg("Response cache")
+	log.Info().Str("window", conf.IdempotencyWindow.String()).Msg("Idempotency keys")
+	if conf.SavedSearchesPath == "" {
+		log.Warn().Msg("Saved searches are only kept in memory")
+	} else {
+		log.Info().Str("path", conf.SavedSearchesPath).Msg("Saved searches")
+	}
+	if conf.WebhookSecretKey == "" {
+		log.Warn().Msg("Webhook secrets encrypted with the authorization secret, rotating it makes them unreadable")
+	}
+	if conf.AlertCheckInterval > 0 {
+		log.Info().Str("interval", conf.AlertCheckInterval.String()).Strs("webhookAllowedHosts", conf.WebhookAllowedHosts).Msg("Alert rules")
+	} else {
+		log.Warn().Msg("Alert rules disabled")
+	}
+	if conf.MetricsPort > 0 {
+		log.Info().Int("port", conf.MetricsPort).Msg("Metrics port")
+	}
+	log.Info().Str("exporter", conf.TracingExporter).Str("endpoint", conf.TracingEndpoint).Msg("Tracing")
 
 }
@@ -34,6 +34,10 @@ type DecoratorResponse struct {
 	LogResponseList   []*grpc_application_manager_go.LogEntryResponse
 	SettingList       []*grpc_organization_manager_go.Setting
 	ClusterList       []*grpc_public_api_go.Cluster
+	NodeList          []*grpc_public_api_go.Node
+	DeviceList        []*grpc_public_api_go.Device
+	AssetList         []*grpc_public_api_go.Asset
+	ControllerList    []*grpc_public_api_go.EdgeController
 	Error             derrors.Error
 }
 
@@ -60,16 +64,45 @@ func ApplyDecorator(result interface{}, decorator Decorator) *DecoratorResponse
 		return FromSetting(result, decorator)
 	case []*grpc_public_api_go.Cluster:
 		return FromClusterList(result, decorator)
+	case []*grpc_public_api_go.Node:
+		return FromNodeList(result, decorator)
+	case []*grpc_public_api_go.Device:
+		return FromDeviceList(result, decorator)
+	case []*grpc_public_api_go.Asset:
+		return FromAssetList(result, decorator)
+	case []*grpc_public_api_go.EdgeController:
+		return FromControllerList(result, decorator)
 	}
 	return &DecoratorResponse{
 		Error: derrors.NewInvalidArgumentError("unable to apply decorator"),
 	}
 }
 
-// FromAppInstanceList not implemented yet
+// FromAppInstanceList applies decorator to a AppInstanceList
 func FromAppInstanceList(result []*grpc_public_api_go.AppInstance, decorator Decorator) *DecoratorResponse {
+	// convert to []interface{}
+	toGenericList := make([]interface{}, len(result))
+	for i, d := range result {
+		toGenericList[i] = *d
+	}
+
+	// call to apply
+	ordered, err := decorator.Apply(toGenericList)
+	if err != nil {
+		return &DecoratorResponse{
+			Error: err,
+		}
+	}
+
+	// reconvert to grpc_public_api_go.AppInstance
+	orderedResult := make([]*grpc_public_api_go.AppInstance, len(ordered))
+	for i, d := range ordered {
+		aux := d.(grpc_public_api_go.AppInstance)
+		orderedResult[i] = &aux
+	}
+
 	return &DecoratorResponse{
-		Error: derrors.NewUnimplementedError("not implemented yet"),
+		AppInstanceList: orderedResult,
 	}
 }
 
@@ -90,7 +123,7 @@ func FromAppDescriptorList(result []*grpc_application_go.AppDescriptor, decorato
 	}
 
 	// reconvert to grpc_application_go.AppDescriptor
-	orderedResult := make([]*grpc_application_go.AppDescriptor, len(result))
+	orderedResult := make([]*grpc_application_go.AppDescriptor, len(ordered))
 	for i, d := range ordered {
 		aux := d.(grpc_application_go.AppDescriptor)
 		orderedResult[i] = &aux
@@ -117,7 +150,7 @@ func FromClusterList(result []*grpc_public_api_go.Cluster, decorator Decorator)
 	}
 
 	// reconvert to grpc_public_api_go.Cluster
-	orderedResult := make([]*grpc_public_api_go.Cluster, len(result))
+	orderedResult := make([]*grpc_public_api_go.Cluster, len(ordered))
 	for i, d := range ordered {
 		aux := d.(grpc_public_api_go.Cluster)
 		orderedResult[i] = &aux
@@ -145,7 +178,7 @@ func FromLogEntryResponse(result []*grpc_application_manager_go.LogEntryResponse
 	}
 
 	// reconvert to grpc_public_api_go.LogEntryResponse
-	orderedResult := make([]*grpc_application_manager_go.LogEntryResponse, len(result))
+	orderedResult := make([]*grpc_application_manager_go.LogEntryResponse, len(ordered))
 	for i, d := range ordered {
 		aux := d.(grpc_application_manager_go.LogEntryResponse)
 		orderedResult[i] = &aux
@@ -173,7 +206,7 @@ func FromSetting(result []*grpc_organization_manager_go.Setting, decorator Decor
 	}
 
 	// reconvert to grpc_public_api_go.LogEntryResponse
-	orderedResult := make([]*grpc_organization_manager_go.Setting, len(result))
+	orderedResult := make([]*grpc_organization_manager_go.Setting, len(ordered))
 	for i, d := range ordered {
 		aux := d.(grpc_organization_manager_go.Setting)
 		orderedResult[i] = &aux
@@ -183,3 +216,115 @@ func FromSetting(result []*grpc_organization_manager_go.Setting, decorator Decor
 		SettingList: orderedResult,
 	}
 }
+
+// FromNodeList applies decorator to a list of nodes
+func FromNodeList(result []*grpc_public_api_go.Node, decorator Decorator) *DecoratorResponse {
+	// convert to []interface{}
+	toGenericList := make([]interface{}, len(result))
+	for i, d := range result {
+		toGenericList[i] = *d
+	}
+
+	// call to apply
+	ordered, err := decorator.Apply(toGenericList)
+	if err != nil {
+		return &DecoratorResponse{
+			Error: err,
+		}
+	}
+
+	// reconvert to grpc_public_api_go.Node
+	orderedResult := make([]*grpc_public_api_go.Node, len(ordered))
+	for i, d := range ordered {
+		aux := d.(grpc_public_api_go.Node)
+		orderedResult[i] = &aux
+	}
+
+	return &DecoratorResponse{
+		NodeList: orderedResult,
+	}
+}
+
+// FromDeviceList applies decorator to a list of devices
+func FromDeviceList(result []*grpc_public_api_go.Device, decorator Decorator) *DecoratorResponse {
+	// convert to []interface{}
+	toGenericList := make([]interface{}, len(result))
+	for i, d := range result {
+		toGenericList[i] = *d
+	}
+
+	// call to apply
+	ordered, err := decorator.Apply(toGenericList)
+	if err != nil {
+		return &DecoratorResponse{
+			Error: err,
+		}
+	}
+
+	// reconvert to grpc_public_api_go.Device
+	orderedResult := make([]*grpc_public_api_go.Device, len(ordered))
+	for i, d := range ordered {
+		aux := d.(grpc_public_api_go.Device)
+		orderedResult[i] = &aux
+	}
+
+	return &DecoratorResponse{
+		DeviceList: orderedResult,
+	}
+}
+
+// FromAssetList applies decorator to a list of assets
+func FromAssetList(result []*grpc_public_api_go.Asset, decorator Decorator) *DecoratorResponse {
+	// convert to []interface{}
+	toGenericList := make([]interface{}, len(result))
+	for i, d := range result {
+		toGenericList[i] = *d
+	}
+
+	// call to apply
+	ordered, err := decorator.Apply(toGenericList)
+	if err != nil {
+		return &DecoratorResponse{
+			Error: err,
+		}
+	}
+
+	// reconvert to grpc_public_api_go.Asset
+	orderedResult := make([]*grpc_public_api_go.Asset, len(ordered))
+	for i, d := range ordered {
+		aux := d.(grpc_public_api_go.Asset)
+		orderedResult[i] = &aux
+	}
+
+	return &DecoratorResponse{
+		AssetList: orderedResult,
+	}
+}
+
+// FromControllerList applies decorator to a list of edge controllers
+func FromControllerList(result []*grpc_public_api_go.EdgeController, decorator Decorator) *DecoratorResponse {
+	// convert to []interface{}
+	toGenericList := make([]interface{}, len(result))
+	for i, d := range result {
+		toGenericList[i] = *d
+	}
+
+	// call to apply
+	ordered, err := decorator.Apply(toGenericList)
+	if err != nil {
+		return &DecoratorResponse{
+			Error: err,
+		}
+	}
+
+	// reconvert to grpc_public_api_go.EdgeController
+	orderedResult := make([]*grpc_public_api_go.EdgeController, len(ordered))
+	for i, d := range ordered {
+		aux := d.(grpc_public_api_go.EdgeController)
+		orderedResult[i] = &aux
+	}
+
+	return &DecoratorResponse{
+		ControllerList: orderedResult,
+	}
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decorators
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	grpc_public_api_go "github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"reflect"
+)
+
+// labelsField is the name of the field that contains the labels of the elements to be filtered.
+const labelsField = "Labels"
+
+// SelectorDecorator implements Decorator interface filtering the elements whose labels do not satisfy
+// a label selector.
+type SelectorDecorator struct {
+	Selector selector.Selector
+}
+
+func NewSelectorDecorator(sel selector.Selector) Decorator {
+	selectorDecorator := SelectorDecorator{sel}
+	return &selectorDecorator
+}
+
+// Validate checks if the result is a list of elements with labels
+func (sd *SelectorDecorator) Validate(result interface{}) derrors.Error {
+
+	switch result.(type) {
+	case []*grpc_application_go.AppDescriptor,
+		[]*grpc_public_api_go.AppInstance,
+		[]*grpc_public_api_go.Cluster,
+		[]*grpc_public_api_go.Node,
+		[]*grpc_public_api_go.Device,
+		[]*grpc_public_api_go.Asset,
+		[]*grpc_public_api_go.EdgeController:
+		return nil
+	}
+
+	return derrors.NewInvalidArgumentError("selector decorator not allowed")
+}
+
+func (sd *SelectorDecorator) Apply(elements []interface{}) ([]interface{}, derrors.Error) {
+
+	if sd.Selector.Empty() {
+		return elements, nil
+	}
+
+	result := make([]interface{}, 0, len(elements))
+	for _, element := range elements {
+		field := reflect.ValueOf(element).FieldByName(labelsField)
+		if !field.IsValid() {
+			return nil, derrors.NewInvalidArgumentError("unable to apply decorator, field not found").WithParams(labelsField)
+		}
+		labels, _ := field.Interface().(map[string]string)
+		if sd.Selector.Matches(labels) {
+			result = append(result, element)
+		}
+	}
+
+	return result, nil
+}
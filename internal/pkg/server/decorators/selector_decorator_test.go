@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package decorators
+
+import (
+	"github.com/google/uuid"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Selector decorator", func() {
+
+	ginkgo.It("should filter a list of appDescriptor by labels", func() {
+		list := make([]*grpc_application_go.AppDescriptor, 0)
+		for _, env := range []string{"prod", "dev", "prod"} {
+			descriptor := CreateApplicationDescriptor(uuid.New().String())
+			descriptor.Labels = map[string]string{"env": env}
+			list = append(list, descriptor)
+		}
+		list = append(list, CreateApplicationDescriptor(uuid.New().String()))
+
+		sel, err := selector.Parse("env=prod")
+		gomega.Expect(err).Should(gomega.BeNil())
+		res := ApplyDecorator(list, NewSelectorDecorator(sel))
+		gomega.Expect(res.Error).Should(gomega.BeNil())
+		gomega.Expect(res.AppDescriptorList).Should(gomega.HaveLen(2))
+		gomega.Expect(res.AppDescriptorList[0].AppDescriptorId).Should(gomega.Equal(list[0].AppDescriptorId))
+		gomega.Expect(res.AppDescriptorList[1].AppDescriptorId).Should(gomega.Equal(list[2].AppDescriptorId))
+
+		sel, err = selector.Parse("env notin (prod)")
+		gomega.Expect(err).Should(gomega.BeNil())
+		res = ApplyDecorator(list, NewSelectorDecorator(sel))
+		gomega.Expect(res.Error).Should(gomega.BeNil())
+		gomega.Expect(res.AppDescriptorList).Should(gomega.HaveLen(2))
+	})
+
+	ginkgo.It("should filter a list of nodes by labels", func() {
+		list := []*grpc_public_api_go.Node{
+			{NodeId: "n1", Labels: map[string]string{"tier": "db"}},
+			{NodeId: "n2", Labels: map[string]string{"tier": "web", "deprecated": "true"}},
+			{NodeId: "n3", Labels: map[string]string{"tier": "web"}},
+		}
+		sel, err := selector.Parse("tier in (web,cache),!deprecated")
+		gomega.Expect(err).Should(gomega.BeNil())
+		res := ApplyDecorator(list, NewSelectorDecorator(sel))
+		gomega.Expect(res.Error).Should(gomega.BeNil())
+		gomega.Expect(res.NodeList).Should(gomega.HaveLen(1))
+		gomega.Expect(res.NodeList[0].NodeId).Should(gomega.Equal("n3"))
+	})
+
+	ginkgo.It("should not be able to filter a list without labels", func() {
+		sel, err := selector.Parse("env=prod")
+		gomega.Expect(err).Should(gomega.BeNil())
+		res := ApplyDecorator([]*grpc_application_go.AppInstance{CreateApplicationInstance("app")}, NewSelectorDecorator(sel))
+		gomega.Expect(res.Error).ShouldNot(gomega.BeNil())
+	})
+})
@@ -46,7 +46,7 @@ func (h *Handler) GetDevice(ctx context.Context, deviceID *grpc_device_go.Device
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.GetDevice(deviceID)
+	return h.Manager.GetDevice(ctx, deviceID)
 }
 
 // NewHandler creates a new Handler with a linked manager.
@@ -66,7 +66,7 @@ func (h *Handler) AddDeviceGroup(ctx context.Context, request *grpc_device_manag
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.AddDeviceGroup(request)
+	return h.Manager.AddDeviceGroup(ctx, request)
 }
 
 func (h *Handler) UpdateDeviceGroup(ctx context.Context, request *grpc_device_manager_go.UpdateDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
@@ -81,7 +81,7 @@ func (h *Handler) UpdateDeviceGroup(ctx context.Context, request *grpc_device_ma
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateDeviceGroup(request)
+	return h.Manager.UpdateDeviceGroup(ctx, request)
 }
 
 func (h *Handler) RemoveDeviceGroup(ctx context.Context, request *grpc_device_go.DeviceGroupId) (*grpc_common_go.Success, error) {
@@ -96,7 +96,7 @@ func (h *Handler) RemoveDeviceGroup(ctx context.Context, request *grpc_device_go
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.RemoveDeviceGroup(request)
+	return h.Manager.RemoveDeviceGroup(ctx, request)
 }
 
 func (h *Handler) ListDeviceGroups(ctx context.Context, request *grpc_organization_go.OrganizationId) (*grpc_device_manager_go.DeviceGroupList, error) {
@@ -111,7 +111,7 @@ func (h *Handler) ListDeviceGroups(ctx context.Context, request *grpc_organizati
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ListDeviceGroups(request)
+	return h.Manager.ListDeviceGroups(ctx, request)
 }
 
 func (h *Handler) ListDevices(ctx context.Context, request *grpc_device_go.DeviceGroupId) (*grpc_public_api_go.DeviceList, error) {
@@ -126,7 +126,7 @@ func (h *Handler) ListDevices(ctx context.Context, request *grpc_device_go.Devic
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ListDevices(request)
+	return h.Manager.ListDevices(ctx, request)
 }
 
 func (h *Handler) AddLabelToDevice(ctx context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
@@ -141,7 +141,7 @@ func (h *Handler) AddLabelToDevice(ctx context.Context, request *grpc_device_man
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.AddLabelToDevice(request)
+	return h.Manager.AddLabelToDevice(ctx, request)
 }
 
 func (h *Handler) RemoveLabelFromDevice(ctx context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
@@ -156,7 +156,7 @@ func (h *Handler) RemoveLabelFromDevice(ctx context.Context, request *grpc_devic
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.RemoveLabelFromDevice(request)
+	return h.Manager.RemoveLabelFromDevice(ctx, request)
 }
 
 func (h *Handler) UpdateDevice(ctx context.Context, request *grpc_device_manager_go.UpdateDeviceRequest) (*grpc_public_api_go.Device, error) {
@@ -171,7 +171,7 @@ func (h *Handler) UpdateDevice(ctx context.Context, request *grpc_device_manager
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateDevice(request)
+	return h.Manager.UpdateDevice(ctx, request)
 }
 
 func (h *Handler) RemoveDevice(ctx context.Context, deviceID *grpc_device_go.DeviceId) (*grpc_common_go.Success, error) {
@@ -179,5 +179,5 @@ func (h *Handler) RemoveDevice(ctx context.Context, deviceID *grpc_device_go.Dev
 	if vErr != nil {
 		return nil, conversions.ToGRPCError(vErr)
 	}
-	return h.Manager.RemoveDevice(deviceID)
+	return h.Manager.RemoveDevice(ctx, deviceID)
 }
@@ -0,0 +1,120 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package devices
+
+import (
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var _ = ginkgo.Describe("Devices handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.DevicesClient
+
+	var organizationID string
+	var group *grpc_device_manager_go.DeviceGroup
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+
+		organizationID = ithelpers.GenerateUUID()
+		group = platform.Store.AddDeviceGroup(&grpc_device_manager_go.DeviceGroup{OrganizationId: organizationID, Name: "group"})
+		platform.Store.AddDevice(&grpc_device_manager_go.Device{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+			DeviceId:       "device",
+			Labels:         map[string]string{"k1": "v1"},
+		})
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(grpc_device_manager_go.NewDevicesClient(platform.Conn()))
+		grpc_public_api_go.RegisterDevicesServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewDevicesClient(conn)
+
+		token = ithelpers.GenerateToken("email@nalej.com", organizationID, "Owner", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_ORG})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	ginkgo.It("should add and remove the labels of a device", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		labelRequest := &grpc_device_manager_go.DeviceLabelRequest{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+			DeviceId:       "device",
+			Labels:         map[string]string{"k2": "v2"},
+		}
+		_, err := client.AddLabelToDevice(ctx, labelRequest)
+		gomega.Expect(err).To(gomega.Succeed())
+		labelRequest.Labels = map[string]string{"k1": "v1"}
+		_, err = client.RemoveLabelFromDevice(ctx, labelRequest)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		list, err := client.ListDevices(ctx, &grpc_device_go.DeviceGroupId{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Devices)).To(gomega.Equal(1))
+		gomega.Expect(list.Devices[0].Labels).To(gomega.Equal(map[string]string{"k2": "v2"}))
+	})
+
+	ginkgo.It("should remove a device group with its devices", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		groupID := &grpc_device_go.DeviceGroupId{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+		}
+		_, err := client.RemoveDeviceGroup(ctx, groupID)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.ListDevices(ctx, groupID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.NotFound))
+	})
+
+})
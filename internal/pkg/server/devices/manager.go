@@ -17,13 +17,17 @@
 package devices
 
 import (
+	"context"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-device-go"
 	"github.com/nalej/grpc-device-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/selector"
 	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/nalej/public-api/internal/pkg/server/decorators"
 )
 
 // Manager structure with the required clients for node operations.
@@ -38,56 +42,69 @@ func NewManager(deviceClient grpc_device_manager_go.DevicesClient) Manager {
 	}
 }
 
-func (m *Manager) AddDeviceGroup(request *grpc_device_manager_go.AddDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) AddDeviceGroup(ctx context.Context, request *grpc_device_manager_go.AddDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.AddDeviceGroup(ctx, request)
 }
 
-func (m *Manager) UpdateDeviceGroup(request *grpc_device_manager_go.UpdateDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateDeviceGroup(ctx context.Context, request *grpc_device_manager_go.UpdateDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.UpdateDeviceGroup(ctx, request)
 }
 
-func (m *Manager) RemoveDeviceGroup(request *grpc_device_go.DeviceGroupId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) RemoveDeviceGroup(ctx context.Context, request *grpc_device_go.DeviceGroupId) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.RemoveDeviceGroup(ctx, request)
 }
 
-func (m *Manager) ListDeviceGroups(request *grpc_organization_go.OrganizationId) (*grpc_device_manager_go.DeviceGroupList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListDeviceGroups(ctx context.Context, request *grpc_organization_go.OrganizationId) (*grpc_device_manager_go.DeviceGroupList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.ListDeviceGroups(ctx, request)
 }
 
-func (m *Manager) ListDevices(request *grpc_device_go.DeviceGroupId) (*grpc_public_api_go.DeviceList, error) {
-	ctx, cancel := common.GetContext()
+// ListDevices retrieves the devices of a group filtered by the label selector of the request.
+func (m *Manager) ListDevices(ctx context.Context, request *grpc_device_go.DeviceGroupId) (*grpc_public_api_go.DeviceList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	list, err := m.deviceClient.ListDevices(ctx, request)
 	if err != nil {
 		return nil, err
 	}
 
-	return entities.ToPublicAPIDeviceList(list), nil
+	result := entities.ToPublicAPIDeviceList(list)
+	if !sel.Empty() {
+		filtered := decorators.ApplyDecorator(result.Devices, decorators.NewSelectorDecorator(sel))
+		if filtered.Error != nil {
+			return nil, conversions.ToGRPCError(filtered.Error)
+		}
+		result.Devices = filtered.DeviceList
+	}
+	return result, nil
 
 }
 
-func (m *Manager) AddLabelToDevice(request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) AddLabelToDevice(ctx context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.AddLabelToDevice(ctx, request)
 }
 
-func (m *Manager) RemoveLabelFromDevice(request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) RemoveLabelFromDevice(ctx context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.RemoveLabelFromDevice(ctx, request)
 }
 
-func (m *Manager) UpdateDevice(request *grpc_device_manager_go.UpdateDeviceRequest) (*grpc_public_api_go.Device, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateDevice(ctx context.Context, request *grpc_device_manager_go.UpdateDeviceRequest) (*grpc_public_api_go.Device, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	device, err := m.deviceClient.UpdateDevice(ctx, request)
 	if err != nil {
@@ -96,14 +113,14 @@ func (m *Manager) UpdateDevice(request *grpc_device_manager_go.UpdateDeviceReque
 	return entities.ToPublicAPIDevice(device), nil
 }
 
-func (m *Manager) RemoveDevice(deviceID *grpc_device_go.DeviceId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) RemoveDevice(ctx context.Context, deviceID *grpc_device_go.DeviceId) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.deviceClient.RemoveDevice(ctx, deviceID)
 }
 
-func (m *Manager) GetDevice(deviceID *grpc_device_go.DeviceId) (*grpc_public_api_go.Device, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetDevice(ctx context.Context, deviceID *grpc_device_go.DeviceId) (*grpc_public_api_go.Device, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	dmDevice, err := m.deviceClient.GetDevice(ctx, deviceID)
 	if err != nil {
@@ -51,7 +51,7 @@ func (h *Handler) CreateEICToken(ctx context.Context, organizationID *grpc_organ
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.CreateEICToken(organizationID)
+	return h.Manager.CreateEICToken(ctx, organizationID)
 }
 
 func (h *Handler) UnlinkEIC(ctx context.Context, request *grpc_inventory_manager_go.UnlinkECRequest) (*grpc_common_go.Success, error) {
@@ -66,7 +66,7 @@ func (h *Handler) UnlinkEIC(ctx context.Context, request *grpc_inventory_manager
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UnlinkEIC(request)
+	return h.Manager.UnlinkEIC(ctx, request)
 }
 
 func (h *Handler) InstallAgent(ctx context.Context, request *grpc_inventory_manager_go.InstallAgentRequest) (*grpc_public_api_go.ECOpResponse, error) {
@@ -81,7 +81,7 @@ func (h *Handler) InstallAgent(ctx context.Context, request *grpc_inventory_mana
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.InstallAgent(request)
+	return h.Manager.InstallAgent(ctx, request)
 }
 
 func (h *Handler) UpdateGeolocation(ctx context.Context, updateRequest *grpc_inventory_manager_go.UpdateGeolocationRequest) (*grpc_inventory_go.EdgeController, error) {
@@ -97,6 +97,6 @@ func (h *Handler) UpdateGeolocation(ctx context.Context, updateRequest *grpc_inv
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateGeolocation(updateRequest)
+	return h.Manager.UpdateGeolocation(ctx, updateRequest)
 
 }
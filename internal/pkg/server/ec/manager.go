@@ -17,6 +17,7 @@
 package ec
 
 import (
+	"context"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
@@ -40,20 +41,20 @@ func NewManager(eicClient grpc_inventory_manager_go.EICClient, agentClient grpc_
 	}
 }
 
-func (m *Manager) CreateEICToken(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.EICJoinToken, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) CreateEICToken(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.EICJoinToken, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.eicClient.CreateEICToken(ctx, organizationID)
 }
 
-func (m *Manager) UnlinkEIC(edgeControllerID *grpc_inventory_manager_go.UnlinkECRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UnlinkEIC(ctx context.Context, edgeControllerID *grpc_inventory_manager_go.UnlinkECRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.eicClient.UnlinkEIC(ctx, edgeControllerID)
 }
 
-func (m *Manager) InstallAgent(request *grpc_inventory_manager_go.InstallAgentRequest) (*grpc_public_api_go.ECOpResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) InstallAgent(ctx context.Context, request *grpc_inventory_manager_go.InstallAgentRequest) (*grpc_public_api_go.ECOpResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	response, err := m.agentClient.InstallAgent(ctx, request)
 
@@ -64,8 +65,8 @@ func (m *Manager) InstallAgent(request *grpc_inventory_manager_go.InstallAgentRe
 
 }
 
-func (m *Manager) UpdateGeolocation(updateRequest *grpc_inventory_manager_go.UpdateGeolocationRequest) (*grpc_inventory_go.EdgeController, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateGeolocation(ctx context.Context, updateRequest *grpc_inventory_manager_go.UpdateGeolocationRequest) (*grpc_inventory_go.EdgeController, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.eicClient.UpdateECGeolocation(ctx, updateRequest)
 }
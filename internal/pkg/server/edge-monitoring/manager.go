@@ -17,6 +17,7 @@
 package edge_monitoring
 
 import (
+	"context"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-monitoring-go"
 
@@ -34,15 +35,15 @@ func NewManager(client grpc_monitoring_go.AssetMonitoringClient) Manager {
 	}
 }
 
-func (m *Manager) ListMetrics(selector *grpc_inventory_go.AssetSelector) (*grpc_monitoring_go.MetricsList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ListMetrics(ctx context.Context, selector *grpc_inventory_go.AssetSelector) (*grpc_monitoring_go.MetricsList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.client.ListMetrics(ctx, selector)
 }
 
-func (m *Manager) QueryMetrics(request *grpc_monitoring_go.QueryMetricsRequest) (*grpc_monitoring_go.QueryMetricsResult, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) QueryMetrics(ctx context.Context, request *grpc_monitoring_go.QueryMetricsRequest) (*grpc_monitoring_go.QueryMetricsResult, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.client.QueryMetrics(ctx, request)
@@ -0,0 +1,286 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"sort"
+	"strings"
+)
+
+// ApplicationManager is a fake of the application manager. Deployments create the instance immediately.
+type ApplicationManager struct {
+	grpc_application_manager_go.UnimplementedApplicationManagerServer
+	store *Store
+}
+
+// NewApplicationManager creates the fake on top of a store.
+func NewApplicationManager(store *Store) *ApplicationManager {
+	return &ApplicationManager{store: store}
+}
+
+// AddAppDescriptor adds a new application descriptor.
+func (am *ApplicationManager) AddAppDescriptor(_ context.Context, request *grpc_application_go.AddAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
+	return am.store.AddDescriptor(&grpc_application_go.AppDescriptor{
+		OrganizationId:       request.OrganizationId,
+		Name:                 request.Name,
+		ConfigurationOptions: request.ConfigurationOptions,
+		EnvironmentVariables: request.EnvironmentVariables,
+		Labels:               request.Labels,
+		Rules:                request.Rules,
+		Groups:               request.Groups,
+		Parameters:           request.Parameters,
+	}), nil
+}
+
+// ListAppDescriptors returns the descriptors of an organization sorted by identifier.
+func (am *ApplicationManager) ListAppDescriptors(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_go.AppDescriptorList, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	result := make([]*grpc_application_go.AppDescriptor, 0)
+	for _, descriptor := range am.store.descriptors {
+		if descriptor.OrganizationId == organizationID.OrganizationId {
+			result = append(result, proto.Clone(descriptor).(*grpc_application_go.AppDescriptor))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AppDescriptorId < result[j].AppDescriptorId })
+	return &grpc_application_go.AppDescriptorList{Descriptors: result}, nil
+}
+
+// getDescriptor returns a descriptor. The store must be locked by the caller.
+func (am *ApplicationManager) getDescriptor(organizationID string, appDescriptorID string) (*grpc_application_go.AppDescriptor, error) {
+	descriptor, exists := am.store.descriptors[appDescriptorID]
+	if !exists || descriptor.OrganizationId != organizationID {
+		return nil, notFound("application descriptor", appDescriptorID)
+	}
+	return descriptor, nil
+}
+
+// GetAppDescriptor returns a descriptor.
+func (am *ApplicationManager) GetAppDescriptor(_ context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_application_go.AppDescriptor, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	descriptor, err := am.getDescriptor(appDescriptorID.OrganizationId, appDescriptorID.AppDescriptorId)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Clone(descriptor).(*grpc_application_go.AppDescriptor), nil
+}
+
+// UpdateAppDescriptor updates the labels of a descriptor.
+func (am *ApplicationManager) UpdateAppDescriptor(_ context.Context, request *grpc_application_go.UpdateAppDescriptorRequest) (*grpc_application_go.AppDescriptor, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	descriptor, err := am.getDescriptor(request.OrganizationId, request.AppDescriptorId)
+	if err != nil {
+		return nil, err
+	}
+	descriptor.Labels = updateLabels(descriptor.Labels, request.AddLabels, request.RemoveLabels, request.Labels)
+	return proto.Clone(descriptor).(*grpc_application_go.AppDescriptor), nil
+}
+
+// RemoveAppDescriptor removes a descriptor that has no running instances.
+func (am *ApplicationManager) RemoveAppDescriptor(_ context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_common_go.Success, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	if _, err := am.getDescriptor(appDescriptorID.OrganizationId, appDescriptorID.AppDescriptorId); err != nil {
+		return nil, err
+	}
+	for _, instance := range am.store.instances {
+		if instance.AppDescriptorId == appDescriptorID.AppDescriptorId {
+			return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("application descriptor has running instances").WithParams(appDescriptorID.AppDescriptorId))
+		}
+	}
+	delete(am.store.descriptors, appDescriptorID.AppDescriptorId)
+	return &grpc_common_go.Success{}, nil
+}
+
+// toGroupInstances creates the group and service instances of a descriptor.
+func toGroupInstances(descriptor *grpc_application_go.AppDescriptor, appInstanceID string) []*grpc_application_go.ServiceGroupInstance {
+	result := make([]*grpc_application_go.ServiceGroupInstance, 0, len(descriptor.Groups))
+	for _, group := range descriptor.Groups {
+		services := make([]*grpc_application_go.ServiceInstance, 0, len(group.Services))
+		for _, service := range group.Services {
+			services = append(services, &grpc_application_go.ServiceInstance{
+				OrganizationId:       descriptor.OrganizationId,
+				AppDescriptorId:      descriptor.AppDescriptorId,
+				AppInstanceId:        appInstanceID,
+				ServiceGroupId:       group.ServiceGroupId,
+				ServiceId:            service.ServiceId,
+				ServiceInstanceId:    newID(),
+				Name:                 service.Name,
+				Type:                 service.Type,
+				Image:                service.Image,
+				Credentials:          service.Credentials,
+				Specs:                service.Specs,
+				Storage:              service.Storage,
+				ExposedPorts:         service.ExposedPorts,
+				EnvironmentVariables: service.EnvironmentVariables,
+				Configs:              service.Configs,
+				Labels:               service.Labels,
+				DeployAfter:          service.DeployAfter,
+				RunArguments:         service.RunArguments,
+			})
+		}
+		result = append(result, &grpc_application_go.ServiceGroupInstance{
+			OrganizationId:         descriptor.OrganizationId,
+			AppDescriptorId:        descriptor.AppDescriptorId,
+			AppInstanceId:          appInstanceID,
+			ServiceGroupId:         group.ServiceGroupId,
+			ServiceGroupInstanceId: newID(),
+			Name:                   group.Name,
+			ServiceInstances:       services,
+			Policy:                 group.Policy,
+			Specs:                  group.Specs,
+			Labels:                 group.Labels,
+		})
+	}
+	return result
+}
+
+// Deploy creates an instance of a descriptor.
+func (am *ApplicationManager) Deploy(_ context.Context, request *grpc_application_manager_go.DeployRequest) (*grpc_application_manager_go.DeploymentResponse, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	descriptor, err := am.getDescriptor(request.OrganizationId, request.AppDescriptorId)
+	if err != nil {
+		return nil, err
+	}
+	appInstanceID := newID()
+	instance := &grpc_application_manager_go.AppInstance{
+		OrganizationId:       request.OrganizationId,
+		AppDescriptorId:      request.AppDescriptorId,
+		AppInstanceId:        appInstanceID,
+		Name:                 request.Name,
+		ConfigurationOptions: descriptor.ConfigurationOptions,
+		EnvironmentVariables: descriptor.EnvironmentVariables,
+		Labels:               copyLabels(descriptor.Labels),
+		Rules:                descriptor.Rules,
+		Groups:               toGroupInstances(descriptor, appInstanceID),
+	}
+	am.store.instances[instance.AppInstanceId] = proto.Clone(instance).(*grpc_application_manager_go.AppInstance)
+	if request.Parameters != nil {
+		am.store.instanceParams[instance.AppInstanceId] = proto.Clone(request.Parameters).(*grpc_application_go.InstanceParameterList)
+	}
+	return &grpc_application_manager_go.DeploymentResponse{
+		RequestId:     newID(),
+		AppInstanceId: instance.AppInstanceId,
+	}, nil
+}
+
+// Undeploy removes an instance.
+func (am *ApplicationManager) Undeploy(_ context.Context, request *grpc_application_manager_go.UndeployRequest) (*grpc_common_go.Success, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	instance, exists := am.store.instances[request.AppInstanceId]
+	if !exists || instance.OrganizationId != request.OrganizationId {
+		return nil, notFound("application instance", request.AppInstanceId)
+	}
+	delete(am.store.instances, request.AppInstanceId)
+	delete(am.store.instanceParams, request.AppInstanceId)
+	return &grpc_common_go.Success{}, nil
+}
+
+// ListAppInstances returns the instances of an organization sorted by identifier.
+func (am *ApplicationManager) ListAppInstances(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_application_manager_go.AppInstanceList, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	result := make([]*grpc_application_manager_go.AppInstance, 0)
+	for _, instance := range am.store.instances {
+		if instance.OrganizationId == organizationID.OrganizationId {
+			result = append(result, proto.Clone(instance).(*grpc_application_manager_go.AppInstance))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].AppInstanceId < result[j].AppInstanceId })
+	return &grpc_application_manager_go.AppInstanceList{Instances: result}, nil
+}
+
+// GetAppInstance returns an instance.
+func (am *ApplicationManager) GetAppInstance(_ context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_manager_go.AppInstance, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	instance, exists := am.store.instances[appInstanceID.AppInstanceId]
+	if !exists || instance.OrganizationId != appInstanceID.OrganizationId {
+		return nil, notFound("application instance", appInstanceID.AppInstanceId)
+	}
+	return proto.Clone(instance).(*grpc_application_manager_go.AppInstance), nil
+}
+
+// ListInstanceParameters returns the parameters an instance was deployed with.
+func (am *ApplicationManager) ListInstanceParameters(_ context.Context, appInstanceID *grpc_application_go.AppInstanceId) (*grpc_application_go.InstanceParameterList, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	instance, exists := am.store.instances[appInstanceID.AppInstanceId]
+	if !exists || instance.OrganizationId != appInstanceID.OrganizationId {
+		return nil, notFound("application instance", appInstanceID.AppInstanceId)
+	}
+	params, exists := am.store.instanceParams[appInstanceID.AppInstanceId]
+	if !exists {
+		return &grpc_application_go.InstanceParameterList{}, nil
+	}
+	return proto.Clone(params).(*grpc_application_go.InstanceParameterList), nil
+}
+
+// ListDescriptorAppParameters returns the parameters of a descriptor.
+func (am *ApplicationManager) ListDescriptorAppParameters(_ context.Context, appDescriptorID *grpc_application_go.AppDescriptorId) (*grpc_application_go.AppParameterList, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	descriptor, err := am.getDescriptor(appDescriptorID.OrganizationId, appDescriptorID.AppDescriptorId)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Clone(&grpc_application_go.AppParameterList{Parameters: descriptor.Parameters}).(*grpc_application_go.AppParameterList), nil
+}
+
+// UnifiedLogging is a fake of the unified logging service of the application manager.
+type UnifiedLogging struct {
+	grpc_application_manager_go.UnimplementedUnifiedLoggingServer
+	store *Store
+}
+
+// NewUnifiedLogging creates the fake on top of a store.
+func NewUnifiedLogging(store *Store) *UnifiedLogging {
+	return &UnifiedLogging{store: store}
+}
+
+// Search returns the log entries of the organization that contain the message filter in the requested time range.
+// As in the upstream component both ends are inclusive and the message filter is case insensitive.
+func (ul *UnifiedLogging) Search(_ context.Context, request *grpc_application_manager_go.SearchRequest) (*grpc_application_manager_go.LogResponse, error) {
+	ul.store.Lock()
+	defer ul.store.Unlock()
+	result := make([]*grpc_application_manager_go.LogEntryResponse, 0)
+	msgFilter := strings.ToLower(request.MsgQueryFilter)
+	for _, entry := range ul.store.logEntries[request.OrganizationId] {
+		if request.From != 0 && entry.Timestamp < request.From {
+			continue
+		}
+		if request.To != 0 && entry.Timestamp > request.To {
+			continue
+		}
+		if strings.Contains(strings.ToLower(entry.Msg), msgFilter) {
+			result = append(result, proto.Clone(entry).(*grpc_application_manager_go.LogEntryResponse))
+		}
+	}
+	return &grpc_application_manager_go.LogResponse{Entries: result}, nil
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"sort"
+)
+
+// Devices is a fake of the device manager.
+type Devices struct {
+	grpc_device_manager_go.UnimplementedDevicesServer
+	store *Store
+}
+
+// NewDevices creates the fake on top of a store.
+func NewDevices(store *Store) *Devices {
+	return &Devices{store: store}
+}
+
+// getGroup returns a device group. The store must be locked by the caller.
+func (d *Devices) getGroup(organizationID string, deviceGroupID string) (*grpc_device_manager_go.DeviceGroup, error) {
+	group, exists := d.store.deviceGroups[deviceGroupID]
+	if !exists || group.OrganizationId != organizationID {
+		return nil, notFound("device group", deviceGroupID)
+	}
+	return group, nil
+}
+
+// getDevice returns a device. The store must be locked by the caller.
+func (d *Devices) getDevice(organizationID string, deviceGroupID string, deviceID string) (*grpc_device_manager_go.Device, error) {
+	device, exists := d.store.devices[deviceKey(organizationID, deviceGroupID, deviceID)]
+	if !exists {
+		return nil, notFound("device", deviceID)
+	}
+	return device, nil
+}
+
+// AddDeviceGroup adds a new device group.
+func (d *Devices) AddDeviceGroup(_ context.Context, request *grpc_device_manager_go.AddDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
+	return d.store.AddDeviceGroup(&grpc_device_manager_go.DeviceGroup{
+		OrganizationId:            request.OrganizationId,
+		Name:                      request.Name,
+		Enabled:                   request.Enabled,
+		DefaultDeviceConnectivity: request.DefaultDeviceConnectivity,
+		DeviceGroupApiKey:         newID(),
+	}), nil
+}
+
+// UpdateDeviceGroup updates the flags of a device group.
+func (d *Devices) UpdateDeviceGroup(_ context.Context, request *grpc_device_manager_go.UpdateDeviceGroupRequest) (*grpc_device_manager_go.DeviceGroup, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	group, err := d.getGroup(request.OrganizationId, request.DeviceGroupId)
+	if err != nil {
+		return nil, err
+	}
+	if request.UpdateEnabled {
+		group.Enabled = request.Enabled
+	}
+	if request.UpdateDeviceConnectivity {
+		group.DefaultDeviceConnectivity = request.DefaultDeviceConnectivity
+	}
+	return proto.Clone(group).(*grpc_device_manager_go.DeviceGroup), nil
+}
+
+// RemoveDeviceGroup removes a device group and its devices.
+func (d *Devices) RemoveDeviceGroup(_ context.Context, deviceGroupID *grpc_device_go.DeviceGroupId) (*grpc_common_go.Success, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	if _, err := d.getGroup(deviceGroupID.OrganizationId, deviceGroupID.DeviceGroupId); err != nil {
+		return nil, err
+	}
+	delete(d.store.deviceGroups, deviceGroupID.DeviceGroupId)
+	for key, device := range d.store.devices {
+		if device.DeviceGroupId == deviceGroupID.DeviceGroupId {
+			delete(d.store.devices, key)
+		}
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// ListDeviceGroups returns the device groups of an organization sorted by identifier.
+func (d *Devices) ListDeviceGroups(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_device_manager_go.DeviceGroupList, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	result := make([]*grpc_device_manager_go.DeviceGroup, 0)
+	for _, group := range d.store.deviceGroups {
+		if group.OrganizationId == organizationID.OrganizationId {
+			result = append(result, proto.Clone(group).(*grpc_device_manager_go.DeviceGroup))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DeviceGroupId < result[j].DeviceGroupId })
+	return &grpc_device_manager_go.DeviceGroupList{Groups: result}, nil
+}
+
+// ListDevices returns the devices of a group sorted by identifier.
+func (d *Devices) ListDevices(_ context.Context, deviceGroupID *grpc_device_go.DeviceGroupId) (*grpc_device_manager_go.DeviceList, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	if _, err := d.getGroup(deviceGroupID.OrganizationId, deviceGroupID.DeviceGroupId); err != nil {
+		return nil, err
+	}
+	result := make([]*grpc_device_manager_go.Device, 0)
+	for _, device := range d.store.devices {
+		if device.OrganizationId == deviceGroupID.OrganizationId && device.DeviceGroupId == deviceGroupID.DeviceGroupId {
+			result = append(result, proto.Clone(device).(*grpc_device_manager_go.Device))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].DeviceId < result[j].DeviceId })
+	return &grpc_device_manager_go.DeviceList{Devices: result}, nil
+}
+
+// AddLabelToDevice adds labels to a device.
+func (d *Devices) AddLabelToDevice(_ context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	device, err := d.getDevice(request.OrganizationId, request.DeviceGroupId, request.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	device.Labels = updateLabels(device.Labels, true, false, request.Labels)
+	return &grpc_common_go.Success{}, nil
+}
+
+// RemoveLabelFromDevice removes labels from a device.
+func (d *Devices) RemoveLabelFromDevice(_ context.Context, request *grpc_device_manager_go.DeviceLabelRequest) (*grpc_common_go.Success, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	device, err := d.getDevice(request.OrganizationId, request.DeviceGroupId, request.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	device.Labels = updateLabels(device.Labels, false, true, request.Labels)
+	return &grpc_common_go.Success{}, nil
+}
+
+// UpdateDevice enables or disables a device.
+func (d *Devices) UpdateDevice(_ context.Context, request *grpc_device_manager_go.UpdateDeviceRequest) (*grpc_device_manager_go.Device, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	device, err := d.getDevice(request.OrganizationId, request.DeviceGroupId, request.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	device.Enabled = request.Enabled
+	return proto.Clone(device).(*grpc_device_manager_go.Device), nil
+}
+
+// RemoveDevice removes a device.
+func (d *Devices) RemoveDevice(_ context.Context, deviceID *grpc_device_go.DeviceId) (*grpc_common_go.Success, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	if _, err := d.getDevice(deviceID.OrganizationId, deviceID.DeviceGroupId, deviceID.DeviceId); err != nil {
+		return nil, err
+	}
+	delete(d.store.devices, deviceKey(deviceID.OrganizationId, deviceID.DeviceGroupId, deviceID.DeviceId))
+	return &grpc_common_go.Success{}, nil
+}
+
+// GetDevice returns a device.
+func (d *Devices) GetDevice(_ context.Context, deviceID *grpc_device_go.DeviceId) (*grpc_device_manager_go.Device, error) {
+	d.store.Lock()
+	defer d.store.Unlock()
+	device, err := d.getDevice(deviceID.OrganizationId, deviceID.DeviceGroupId, deviceID.DeviceId)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Clone(device).(*grpc_device_manager_go.Device), nil
+}
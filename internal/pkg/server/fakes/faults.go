@@ -0,0 +1,142 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fault describes the abnormal behaviour of a method.
+type fault struct {
+	// err returned instead of calling the fake. A nil error only applies the delay.
+	err error
+	// delay before answering the call.
+	delay time.Duration
+	// remaining number of calls affected by the fault. Negative values apply to every call.
+	remaining int
+}
+
+// Faults contains the errors and delays injected in the fake components, and counts the calls received by each
+// method. Methods are identified by the end of their full gRPC name, so "ListNodes", "Nodes/ListNodes" and
+// "/infrastructure.Nodes/ListNodes" match the same method.
+type Faults struct {
+	sync.Mutex
+	faults map[string]*fault
+	calls  map[string]int
+}
+
+// NewFaults creates an empty set of faults.
+func NewFaults() *Faults {
+	return &Faults{
+		faults: make(map[string]*fault, 0),
+		calls:  make(map[string]int, 0),
+	}
+}
+
+// FailOn makes every call to a method return the given error.
+func (f *Faults) FailOn(method string, err error) {
+	f.inject(method, &fault{err: err, remaining: -1})
+}
+
+// FailTimes makes the next calls to a method return the given error.
+func (f *Faults) FailTimes(method string, err error, times int) {
+	f.inject(method, &fault{err: err, remaining: times})
+}
+
+// Delay makes every call to a method wait before answering. The wait ends early if the call is cancelled.
+func (f *Faults) Delay(method string, delay time.Duration) {
+	f.inject(method, &fault{delay: delay, remaining: -1})
+}
+
+// Clear removes the faults and resets the call counters.
+func (f *Faults) Clear() {
+	f.Lock()
+	defer f.Unlock()
+	f.faults = make(map[string]*fault, 0)
+	f.calls = make(map[string]int, 0)
+}
+
+// Calls returns the number of calls received by a method.
+func (f *Faults) Calls(method string) int {
+	f.Lock()
+	defer f.Unlock()
+	total := 0
+	for fullMethod, count := range f.calls {
+		if matches(fullMethod, method) {
+			total += count
+		}
+	}
+	return total
+}
+
+func (f *Faults) inject(method string, toAdd *fault) {
+	f.Lock()
+	defer f.Unlock()
+	f.faults[method] = toAdd
+}
+
+// matches checks whether a full gRPC method name corresponds to a method identifier.
+func matches(fullMethod string, method string) bool {
+	return fullMethod == method || strings.HasSuffix(fullMethod, "/"+strings.TrimPrefix(method, "/")) ||
+		strings.HasSuffix(fullMethod, "."+strings.TrimPrefix(method, "/"))
+}
+
+// take registers a call and returns the fault that applies to it, if any.
+func (f *Faults) take(fullMethod string) *fault {
+	f.Lock()
+	defer f.Unlock()
+	f.calls[fullMethod]++
+	for method, current := range f.faults {
+		if !matches(fullMethod, method) {
+			continue
+		}
+		if current.remaining == 0 {
+			continue
+		}
+		if current.remaining > 0 {
+			current.remaining--
+		}
+		return current
+	}
+	return nil
+}
+
+// UnaryServerInterceptor applies the faults to the calls received by the fake components.
+func (f *Faults) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		current := f.take(info.FullMethod)
+		if current != nil {
+			if current.delay > 0 {
+				select {
+				case <-time.After(current.delay):
+				case <-ctx.Done():
+					return nil, status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+				}
+			}
+			if current.err != nil {
+				return nil, current.err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var _ = ginkgo.Describe("Faults", func() {
+
+	const fullMethod = "/infrastructure.Nodes/ListNodes"
+
+	var faults *Faults
+	var interceptor grpc.UnaryServerInterceptor
+	var handled int
+
+	call := func(ctx context.Context, method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, interface{}) (interface{}, error) {
+				handled++
+				return nil, nil
+			})
+		return err
+	}
+
+	ginkgo.BeforeEach(func() {
+		faults = NewFaults()
+		interceptor = faults.UnaryServerInterceptor()
+		handled = 0
+	})
+
+	ginkgo.It("should match methods by the end of their name", func() {
+		gomega.Expect(matches(fullMethod, "ListNodes")).To(gomega.BeTrue())
+		gomega.Expect(matches(fullMethod, "Nodes/ListNodes")).To(gomega.BeTrue())
+		gomega.Expect(matches(fullMethod, fullMethod)).To(gomega.BeTrue())
+		gomega.Expect(matches(fullMethod, "Nodes")).To(gomega.BeFalse())
+		gomega.Expect(matches(fullMethod, "Clusters/ListNodes")).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should fail every call to a method", func() {
+		faults.FailOn("Nodes/ListNodes", status.Error(codes.Unavailable, "unavailable"))
+		for i := 0; i < 3; i++ {
+			gomega.Expect(status.Code(call(context.Background(), fullMethod))).To(gomega.Equal(codes.Unavailable))
+		}
+		gomega.Expect(call(context.Background(), "/infrastructure.Nodes/UpdateNode")).To(gomega.Succeed())
+		gomega.Expect(handled).To(gomega.Equal(1))
+		gomega.Expect(faults.Calls("ListNodes")).To(gomega.Equal(3))
+	})
+
+	ginkgo.It("should fail a limited number of calls", func() {
+		faults.FailTimes("ListNodes", status.Error(codes.Internal, "internal"), 2)
+		gomega.Expect(call(context.Background(), fullMethod)).NotTo(gomega.Succeed())
+		gomega.Expect(call(context.Background(), fullMethod)).NotTo(gomega.Succeed())
+		gomega.Expect(call(context.Background(), fullMethod)).To(gomega.Succeed())
+		gomega.Expect(handled).To(gomega.Equal(1))
+	})
+
+	ginkgo.It("should stop waiting when the call is cancelled", func() {
+		faults.Delay("ListNodes", time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+		defer cancel()
+		gomega.Expect(status.Code(call(ctx, fullMethod))).To(gomega.Equal(codes.DeadlineExceeded))
+		gomega.Expect(handled).To(gomega.Equal(0))
+	})
+
+	ginkgo.It("should clear the faults and the counters", func() {
+		faults.FailOn("ListNodes", status.Error(codes.Internal, "internal"))
+		gomega.Expect(call(context.Background(), fullMethod)).NotTo(gomega.Succeed())
+		faults.Clear()
+		gomega.Expect(faults.Calls("ListNodes")).To(gomega.Equal(0))
+		gomega.Expect(call(context.Background(), fullMethod)).To(gomega.Succeed())
+	})
+
+})
@@ -0,0 +1,172 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"strings"
+	"time"
+)
+
+// FixtureOrganizationID with the identifier of the organization seeded by SeedFixtures.
+const FixtureOrganizationID = "00000000-0000-0000-0000-000000000001"
+
+// FixtureRoles contains the primitives of the roles seeded by SeedFixtures.
+var FixtureRoles = map[string][]grpc_authx_go.AccessPrimitive{
+	"Owner":     {grpc_authx_go.AccessPrimitive_ORG},
+	"Operator":  {grpc_authx_go.AccessPrimitive_PROFILE, grpc_authx_go.AccessPrimitive_RESOURCES},
+	"Developer": {grpc_authx_go.AccessPrimitive_PROFILE, grpc_authx_go.AccessPrimitive_APPS},
+}
+
+// fixtureUsers contains the email and role of the users seeded by SeedFixtures.
+var fixtureUsers = []struct {
+	email string
+	name  string
+	role  string
+}{
+	{"admin@nalej.com", "Admin", "Owner"},
+	{"operator@nalej.com", "Operator", "Operator"},
+	{"developer@nalej.com", "Developer", "Developer"},
+}
+
+// SeedFixtures fills the store with a realistic organization: users of each role, two clusters with their
+// nodes, application descriptors and instances, devices, an inventory and some log entries.
+func (s *Store) SeedFixtures() {
+	organizationID := FixtureOrganizationID
+	s.AddOrganization(&grpc_organization_manager_go.Organization{
+		OrganizationId: organizationID,
+		Name:           "Nalej Mock",
+	})
+	s.AddSetting(&grpc_organization_manager_go.Setting{
+		OrganizationId: organizationID,
+		Key:            "ALLOW_AUTO_NODE_SCALING",
+		Value:          "false",
+	})
+	s.AddSetting(&grpc_organization_manager_go.Setting{
+		OrganizationId: organizationID,
+		Key:            "LOG_DOWNLOAD_RETENTION",
+		Value:          "7d",
+	})
+
+	roleIDs := make(map[string]string, 0)
+	for name := range FixtureRoles {
+		role := s.AddRole(&grpc_authx_go.Role{OrganizationId: organizationID, Name: name})
+		roleIDs[name] = role.RoleId
+	}
+	for _, user := range fixtureUsers {
+		s.AddUser(&grpc_user_manager_go.User{
+			OrganizationId: organizationID,
+			Email:          user.email,
+			Name:           user.name,
+			RoleId:         roleIDs[user.role],
+			RoleName:       user.role,
+		})
+	}
+
+	for c, name := range []string{"edge-madrid", "cloud-frankfurt"} {
+		cluster := s.AddCluster(&grpc_infrastructure_go.Cluster{
+			OrganizationId: organizationID,
+			Name:           name,
+			Labels:         map[string]string{"env": "mock", "region": name[strings.Index(name, "-")+1:]},
+		})
+		for n := 0; n < 3; n++ {
+			status := grpc_infrastructure_go.InfraStatus_RUNNING
+			if c == 1 && n == 2 {
+				status = grpc_infrastructure_go.InfraStatus_ERROR
+			}
+			s.AddNode(&grpc_infrastructure_go.Node{
+				OrganizationId: organizationID,
+				ClusterId:      cluster.ClusterId,
+				Status:         status,
+				Labels:         map[string]string{"role": "worker"},
+			})
+		}
+	}
+
+	var instance *grpc_application_manager_go.AppInstance
+	for _, name := range []string{"wordpress", "mongodb"} {
+		descriptor := s.AddDescriptor(&grpc_application_go.AppDescriptor{
+			OrganizationId: organizationID,
+			Name:           name,
+			Labels:         map[string]string{"app": name},
+		})
+		instance = s.AddInstance(&grpc_application_manager_go.AppInstance{
+			OrganizationId:  organizationID,
+			AppDescriptorId: descriptor.AppDescriptorId,
+			Name:            fmt.Sprintf("%s-instance", name),
+			Labels:          map[string]string{"app": name},
+		})
+	}
+
+	group := s.AddDeviceGroup(&grpc_device_manager_go.DeviceGroup{OrganizationId: organizationID, Name: "sensors"})
+	for d := 0; d < 3; d++ {
+		s.AddDevice(&grpc_device_manager_go.Device{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+			DeviceId:       fmt.Sprintf("sensor-%d", d),
+			Labels:         map[string]string{"type": "temperature"},
+		})
+	}
+
+	now := time.Now()
+	controller := s.AddController(&grpc_inventory_manager_go.EdgeController{
+		OrganizationId:     organizationID,
+		Name:               "controller-madrid",
+		Created:            now.Add(-time.Hour * 24).Unix(),
+		LastAliveTimestamp: now.Unix(),
+		Labels:             map[string]string{"env": "mock"},
+	})
+	for a := 0; a < 2; a++ {
+		s.AddAsset(&grpc_inventory_manager_go.Asset{
+			OrganizationId:   organizationID,
+			EdgeControllerId: controller.EdgeControllerId,
+			AgentId:          newID(),
+			Created:          now.Add(-time.Hour).Unix(),
+			Labels:           map[string]string{"env": "mock"},
+		})
+	}
+
+	for e, msg := range []string{"starting application", "listening on port 80", "GET /index.php 200", "GET /missing 404"} {
+		s.AddLogEntries(organizationID, &grpc_application_manager_go.LogEntryResponse{
+			AppDescriptorId: instance.AppDescriptorId,
+			AppInstanceId:   instance.AppInstanceId,
+			Timestamp:       now.Add(time.Duration(e-4) * time.Minute).UnixNano(),
+			Msg:             msg,
+		})
+	}
+}
+
+// findUser returns a copy of a user given the email, regardless of the organization.
+func (s *Store) findUser(email string) *grpc_user_manager_go.User {
+	s.Lock()
+	defer s.Unlock()
+	for _, user := range s.users {
+		if user.Email == email {
+			return proto.Clone(user).(*grpc_user_manager_go.User)
+		}
+	}
+	return nil
+}
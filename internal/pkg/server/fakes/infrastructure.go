@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-installer-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-provisioner-go"
+	"sort"
+	"time"
+)
+
+// listClusters returns the clusters of an organization sorted by identifier.
+func (s *Store) listClusters(organizationID string) *grpc_infrastructure_go.ClusterList {
+	s.Lock()
+	defer s.Unlock()
+	result := make([]*grpc_infrastructure_go.Cluster, 0)
+	for _, cluster := range s.clusters {
+		if cluster.OrganizationId == organizationID {
+			result = append(result, proto.Clone(cluster).(*grpc_infrastructure_go.Cluster))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ClusterId < result[j].ClusterId })
+	return &grpc_infrastructure_go.ClusterList{Clusters: result}
+}
+
+// getCluster returns a copy of a cluster.
+func (s *Store) getCluster(clusterID *grpc_infrastructure_go.ClusterId) (*grpc_infrastructure_go.Cluster, error) {
+	s.Lock()
+	defer s.Unlock()
+	cluster, exists := s.clusters[clusterID.ClusterId]
+	if !exists || cluster.OrganizationId != clusterID.OrganizationId {
+		return nil, notFound("cluster", clusterID.ClusterId)
+	}
+	return proto.Clone(cluster).(*grpc_infrastructure_go.Cluster), nil
+}
+
+// removeCluster removes a cluster and its nodes.
+func (s *Store) removeCluster(organizationID string, clusterID string) error {
+	s.Lock()
+	defer s.Unlock()
+	cluster, exists := s.clusters[clusterID]
+	if !exists || cluster.OrganizationId != organizationID {
+		return notFound("cluster", clusterID)
+	}
+	delete(s.clusters, clusterID)
+	for nodeID, node := range s.nodes {
+		if node.ClusterId == clusterID {
+			delete(s.nodes, nodeID)
+		}
+	}
+	return nil
+}
+
+// opResponse creates the response of an asynchronous operation.
+func opResponse(organizationID string, operation string) *grpc_common_go.OpResponse {
+	return &grpc_common_go.OpResponse{
+		OrganizationId: organizationID,
+		RequestId:      newID(),
+		OperationName:  operation,
+		Timestamp:      time.Now().Unix(),
+	}
+}
+
+// Clusters is a fake of the cluster service of the system model.
+type Clusters struct {
+	grpc_infrastructure_go.UnimplementedClustersServer
+	store *Store
+}
+
+// NewClusters creates the fake on top of a store.
+func NewClusters(store *Store) *Clusters {
+	return &Clusters{store: store}
+}
+
+// ListClusters returns the clusters of an organization.
+func (c *Clusters) ListClusters(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_infrastructure_go.ClusterList, error) {
+	return c.store.listClusters(organizationID.OrganizationId), nil
+}
+
+// GetCluster returns a cluster.
+func (c *Clusters) GetCluster(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_infrastructure_go.Cluster, error) {
+	return c.store.getCluster(clusterID)
+}
+
+// Nodes is a fake of the node service of the system model.
+type Nodes struct {
+	grpc_infrastructure_go.UnimplementedNodesServer
+	store *Store
+}
+
+// NewNodes creates the fake on top of a store.
+func NewNodes(store *Store) *Nodes {
+	return &Nodes{store: store}
+}
+
+// ListNodes returns the nodes of a cluster sorted by identifier.
+func (n *Nodes) ListNodes(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_infrastructure_go.NodeList, error) {
+	n.store.Lock()
+	defer n.store.Unlock()
+	result := make([]*grpc_infrastructure_go.Node, 0)
+	for _, node := range n.store.nodes {
+		if node.OrganizationId == clusterID.OrganizationId && node.ClusterId == clusterID.ClusterId {
+			result = append(result, proto.Clone(node).(*grpc_infrastructure_go.Node))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NodeId < result[j].NodeId })
+	return &grpc_infrastructure_go.NodeList{Nodes: result}, nil
+}
+
+// UpdateNode updates the labels of a node.
+func (n *Nodes) UpdateNode(_ context.Context, request *grpc_infrastructure_go.UpdateNodeRequest) (*grpc_infrastructure_go.Node, error) {
+	n.store.Lock()
+	defer n.store.Unlock()
+	node, exists := n.store.nodes[request.NodeId]
+	if !exists || node.OrganizationId != request.OrganizationId {
+		return nil, notFound("node", request.NodeId)
+	}
+	node.Labels = updateLabels(node.Labels, request.AddLabels, request.RemoveLabels, request.Labels)
+	return proto.Clone(node).(*grpc_infrastructure_go.Node), nil
+}
+
+// InfrastructureManager is a fake of the infrastructure manager. Install, uninstall and decommission
+// operations are applied immediately.
+type InfrastructureManager struct {
+	grpc_infrastructure_manager_go.UnimplementedInfrastructureManagerServer
+	store *Store
+}
+
+// NewInfrastructureManager creates the fake on top of a store.
+func NewInfrastructureManager(store *Store) *InfrastructureManager {
+	return &InfrastructureManager{store: store}
+}
+
+// InstallCluster registers the cluster being installed.
+func (im *InfrastructureManager) InstallCluster(_ context.Context, request *grpc_installer_go.InstallRequest) (*grpc_common_go.OpResponse, error) {
+	clusterID := request.ClusterId
+	if clusterID == "" {
+		clusterID = newID()
+	}
+	im.store.AddCluster(&grpc_infrastructure_go.Cluster{
+		OrganizationId: request.OrganizationId,
+		ClusterId:      clusterID,
+		Name:           request.Hostname,
+	})
+	return opResponse(request.OrganizationId, "install"), nil
+}
+
+// Uninstall removes the cluster and its nodes.
+func (im *InfrastructureManager) Uninstall(_ context.Context, request *grpc_installer_go.UninstallClusterRequest) (*grpc_common_go.OpResponse, error) {
+	if err := im.store.removeCluster(request.OrganizationId, request.ClusterId); err != nil {
+		return nil, err
+	}
+	return opResponse(request.OrganizationId, "uninstall"), nil
+}
+
+// DecommissionCluster removes the cluster and its nodes.
+func (im *InfrastructureManager) DecommissionCluster(_ context.Context, request *grpc_provisioner_go.DecommissionClusterRequest) (*grpc_common_go.OpResponse, error) {
+	if err := im.store.removeCluster(request.OrganizationId, request.ClusterId); err != nil {
+		return nil, err
+	}
+	return opResponse(request.OrganizationId, "decommission"), nil
+}
+
+// ListClusters returns the clusters of an organization.
+func (im *InfrastructureManager) ListClusters(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_infrastructure_go.ClusterList, error) {
+	return im.store.listClusters(organizationID.OrganizationId), nil
+}
+
+// GetCluster returns a cluster.
+func (im *InfrastructureManager) GetCluster(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_infrastructure_go.Cluster, error) {
+	return im.store.getCluster(clusterID)
+}
+
+// UpdateCluster updates the name, labels and conversion factor of a cluster.
+func (im *InfrastructureManager) UpdateCluster(_ context.Context, request *grpc_infrastructure_go.UpdateClusterRequest) (*grpc_infrastructure_go.Cluster, error) {
+	im.store.Lock()
+	defer im.store.Unlock()
+	cluster, exists := im.store.clusters[request.ClusterId]
+	if !exists || cluster.OrganizationId != request.OrganizationId {
+		return nil, notFound("cluster", request.ClusterId)
+	}
+	if request.UpdateName {
+		cluster.Name = request.Name
+	}
+	if request.UpdateMillicoresConversionFactor {
+		cluster.MillicoresConversionFactor = request.MillicoresConversionFactor
+	}
+	cluster.Labels = updateLabels(cluster.Labels, request.AddLabels, request.RemoveLabels, request.Labels)
+	return proto.Clone(cluster).(*grpc_infrastructure_go.Cluster), nil
+}
+
+// CordonCluster checks that the cluster exists.
+func (im *InfrastructureManager) CordonCluster(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	if _, err := im.store.getCluster(clusterID); err != nil {
+		return nil, err
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// UncordonCluster checks that the cluster exists.
+func (im *InfrastructureManager) UncordonCluster(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	if _, err := im.store.getCluster(clusterID); err != nil {
+		return nil, err
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// DrainCluster checks that the cluster exists.
+func (im *InfrastructureManager) DrainCluster(_ context.Context, clusterID *grpc_infrastructure_go.ClusterId) (*grpc_common_go.Success, error) {
+	if _, err := im.store.getCluster(clusterID); err != nil {
+		return nil, err
+	}
+	return &grpc_common_go.Success{}, nil
+}
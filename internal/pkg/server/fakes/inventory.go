@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"sort"
+)
+
+// Inventory is a fake of the inventory service of the inventory manager.
+type Inventory struct {
+	grpc_inventory_manager_go.UnimplementedInventoryServer
+	store *Store
+}
+
+// NewInventory creates the fake on top of a store.
+func NewInventory(store *Store) *Inventory {
+	return &Inventory{store: store}
+}
+
+// listAssets returns the assets of an organization that match a filter. The store must be locked by the caller.
+func (i *Inventory) listAssets(organizationID string, filter func(asset *grpc_inventory_manager_go.Asset) bool) []*grpc_inventory_manager_go.Asset {
+	result := make([]*grpc_inventory_manager_go.Asset, 0)
+	for _, asset := range i.store.assets {
+		if asset.OrganizationId == organizationID && filter(asset) {
+			result = append(result, proto.Clone(asset).(*grpc_inventory_manager_go.Asset))
+		}
+	}
+	sort.Slice(result, func(a, b int) bool { return result[a].AssetId < result[b].AssetId })
+	return result
+}
+
+// List returns the assets and edge controllers of an organization.
+func (i *Inventory) List(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventoryList, error) {
+	i.store.Lock()
+	defer i.store.Unlock()
+	controllers := make([]*grpc_inventory_manager_go.EdgeController, 0)
+	for _, controller := range i.store.controllers {
+		if controller.OrganizationId == organizationID.OrganizationId {
+			controllers = append(controllers, proto.Clone(controller).(*grpc_inventory_manager_go.EdgeController))
+		}
+	}
+	sort.Slice(controllers, func(a, b int) bool { return controllers[a].EdgeControllerId < controllers[b].EdgeControllerId })
+	return &grpc_inventory_manager_go.InventoryList{
+		Assets: i.listAssets(organizationID.OrganizationId, func(*grpc_inventory_manager_go.Asset) bool {
+			return true
+		}),
+		Controllers: controllers,
+		Devices:     make([]*grpc_inventory_manager_go.Device, 0),
+	}, nil
+}
+
+// GetControllerExtendedInfo returns an edge controller with its managed assets.
+func (i *Inventory) GetControllerExtendedInfo(_ context.Context, edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.EdgeControllerExtendedInfo, error) {
+	i.store.Lock()
+	defer i.store.Unlock()
+	controller, exists := i.store.controllers[edgeControllerID.EdgeControllerId]
+	if !exists || controller.OrganizationId != edgeControllerID.OrganizationId {
+		return nil, notFound("edge controller", edgeControllerID.EdgeControllerId)
+	}
+	return &grpc_inventory_manager_go.EdgeControllerExtendedInfo{
+		Controller: proto.Clone(controller).(*grpc_inventory_manager_go.EdgeController),
+		ManagedAssets: i.listAssets(edgeControllerID.OrganizationId, func(asset *grpc_inventory_manager_go.Asset) bool {
+			return asset.EdgeControllerId == edgeControllerID.EdgeControllerId
+		}),
+	}, nil
+}
+
+// GetAssetInfo returns an asset.
+func (i *Inventory) GetAssetInfo(_ context.Context, assetID *grpc_inventory_go.AssetId) (*grpc_inventory_manager_go.Asset, error) {
+	i.store.Lock()
+	defer i.store.Unlock()
+	asset, exists := i.store.assets[assetID.AssetId]
+	if !exists || asset.OrganizationId != assetID.OrganizationId {
+		return nil, notFound("asset", assetID.AssetId)
+	}
+	return proto.Clone(asset).(*grpc_inventory_manager_go.Asset), nil
+}
+
+// UpdateAsset updates the labels of an asset.
+func (i *Inventory) UpdateAsset(_ context.Context, request *grpc_inventory_go.UpdateAssetRequest) (*grpc_inventory_go.Asset, error) {
+	i.store.Lock()
+	defer i.store.Unlock()
+	asset, exists := i.store.assets[request.AssetId]
+	if !exists || asset.OrganizationId != request.OrganizationId {
+		return nil, notFound("asset", request.AssetId)
+	}
+	asset.Labels = updateLabels(asset.Labels, request.AddLabels, request.RemoveLabels, request.Labels)
+	return &grpc_inventory_go.Asset{
+		OrganizationId:   asset.OrganizationId,
+		EdgeControllerId: asset.EdgeControllerId,
+		AssetId:          asset.AssetId,
+		Labels:           copyLabels(asset.Labels),
+	}, nil
+}
+
+// EIC is a fake of the edge controller service of the inventory manager.
+type EIC struct {
+	grpc_inventory_manager_go.UnimplementedEICServer
+	store *Store
+}
+
+// NewEIC creates the fake on top of a store.
+func NewEIC(store *Store) *EIC {
+	return &EIC{store: store}
+}
+
+// UpdateEC updates the labels of an edge controller.
+func (e *EIC) UpdateEC(_ context.Context, request *grpc_inventory_go.UpdateEdgeControllerRequest) (*grpc_inventory_go.EdgeController, error) {
+	e.store.Lock()
+	defer e.store.Unlock()
+	controller, exists := e.store.controllers[request.EdgeControllerId]
+	if !exists || controller.OrganizationId != request.OrganizationId {
+		return nil, notFound("edge controller", request.EdgeControllerId)
+	}
+	controller.Labels = updateLabels(controller.Labels, request.AddLabels, request.RemoveLabels, request.Labels)
+	return &grpc_inventory_go.EdgeController{
+		OrganizationId:   controller.OrganizationId,
+		EdgeControllerId: controller.EdgeControllerId,
+		Name:             controller.Name,
+		Labels:           copyLabels(controller.Labels),
+	}, nil
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"sort"
+	"time"
+)
+
+// LogDownload is a fake of the log download manager. Requests are ready as soon as they are created.
+type LogDownload struct {
+	grpc_log_download_manager_go.UnimplementedLogDownloadManagerServer
+	store *Store
+}
+
+// NewLogDownload creates the fake on top of a store.
+func NewLogDownload(store *Store) *LogDownload {
+	return &LogDownload{store: store}
+}
+
+// DownloadLog creates a download request.
+func (ld *LogDownload) DownloadLog(_ context.Context, request *grpc_log_download_manager_go.DownloadLogRequest) (*grpc_log_download_manager_go.DownloadLogResponse, error) {
+	ld.store.Lock()
+	defer ld.store.Unlock()
+	requestID := newID()
+	response := &grpc_log_download_manager_go.DownloadLogResponse{
+		OrganizationId: request.OrganizationId,
+		RequestId:      requestID,
+		From:           request.From,
+		To:             request.To,
+		State:          grpc_log_download_manager_go.DownloadLogState_READY,
+		Url:            fmt.Sprintf("/v1/logs/download/%s/%s", request.OrganizationId, requestID),
+		Created:        time.Now().UnixNano(),
+	}
+	ld.store.downloads[requestID] = response
+	return proto.Clone(response).(*grpc_log_download_manager_go.DownloadLogResponse), nil
+}
+
+// Check returns the state of a download request.
+func (ld *LogDownload) Check(_ context.Context, requestID *grpc_log_download_manager_go.DownloadRequestId) (*grpc_log_download_manager_go.DownloadLogResponse, error) {
+	ld.store.Lock()
+	defer ld.store.Unlock()
+	response, exists := ld.store.downloads[requestID.RequestId]
+	if !exists || response.OrganizationId != requestID.OrganizationId {
+		return nil, notFound("download request", requestID.RequestId)
+	}
+	return proto.Clone(response).(*grpc_log_download_manager_go.DownloadLogResponse), nil
+}
+
+// Cancel stops a download request in progress.
+func (ld *LogDownload) Cancel(_ context.Context, requestID *grpc_log_download_manager_go.DownloadRequestId) (*grpc_common_go.Success, error) {
+	ld.store.Lock()
+	defer ld.store.Unlock()
+	response, exists := ld.store.downloads[requestID.RequestId]
+	if !exists || response.OrganizationId != requestID.OrganizationId {
+		return nil, notFound("download request", requestID.RequestId)
+	}
+	if response.State != grpc_log_download_manager_go.DownloadLogState_PENDING {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("download request already finished").WithParams(requestID.RequestId))
+	}
+	response.State = grpc_log_download_manager_go.DownloadLogState_CANCELLED
+	return &grpc_common_go.Success{}, nil
+}
+
+// Delete removes a finished download request.
+func (ld *LogDownload) Delete(_ context.Context, requestID *grpc_log_download_manager_go.DownloadRequestId) (*grpc_common_go.Success, error) {
+	ld.store.Lock()
+	defer ld.store.Unlock()
+	response, exists := ld.store.downloads[requestID.RequestId]
+	if !exists || response.OrganizationId != requestID.OrganizationId {
+		return nil, notFound("download request", requestID.RequestId)
+	}
+	if response.State == grpc_log_download_manager_go.DownloadLogState_PENDING {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("download request in progress").WithParams(requestID.RequestId))
+	}
+	delete(ld.store.downloads, requestID.RequestId)
+	return &grpc_common_go.Success{}, nil
+}
+
+// List returns the download requests of an organization sorted by identifier.
+func (ld *LogDownload) List(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_log_download_manager_go.DownloadLogResponseList, error) {
+	ld.store.Lock()
+	defer ld.store.Unlock()
+	result := make([]*grpc_log_download_manager_go.DownloadLogResponse, 0)
+	for _, response := range ld.store.downloads {
+		if response.OrganizationId == organizationID.OrganizationId {
+			result = append(result, proto.Clone(response).(*grpc_log_download_manager_go.DownloadLogResponse))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].RequestId < result[j].RequestId })
+	return &grpc_log_download_manager_go.DownloadLogResponseList{Responses: result}, nil
+}
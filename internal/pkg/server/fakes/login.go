@@ -0,0 +1,100 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/nalej/authx/pkg/token"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-login-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/status"
+	"net/http"
+	"time"
+)
+
+// LoginPath with the HTTP path where the fake login is served.
+const LoginPath = "/v1/login"
+
+// Login is a fake of the login API. Any non-empty password is accepted for the users in the store, and the
+// issued tokens carry the primitives defined in FixtureRoles.
+type Login struct {
+	grpc_login_api_go.UnimplementedLoginServer
+	store      *Store
+	secret     string
+	expiration time.Duration
+}
+
+// NewLogin creates the fake on top of a store. The tokens are signed with the given secret.
+func NewLogin(store *Store, secret string, expiration time.Duration) *Login {
+	return &Login{store: store, secret: secret, expiration: expiration}
+}
+
+// LoginWithBasicCredentials issues a token for a user of the store.
+func (l *Login) LoginWithBasicCredentials(_ context.Context, request *grpc_authx_go.LoginWithBasicCredentialsRequest) (*grpc_authx_go.LoginResponse, error) {
+	if request.Username == "" || request.Password == "" {
+		return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("username and password must be set"))
+	}
+	user := l.store.findUser(request.Username)
+	if user == nil {
+		return nil, conversions.ToGRPCError(derrors.NewUnauthenticatedError("invalid credentials").WithParams(request.Username))
+	}
+	primitives := make([]string, 0)
+	for _, primitive := range FixtureRoles[user.RoleName] {
+		primitives = append(primitives, primitive.String())
+	}
+	claim := token.NewClaim(token.PersonalClaim{
+		UserID:         user.Email,
+		Primitives:     primitives,
+		RoleName:       user.RoleName,
+		OrganizationID: user.OrganizationId,
+	}, "mock", time.Now(), l.expiration)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claim).SignedString([]byte(l.secret))
+	if err != nil {
+		return nil, conversions.ToGRPCError(derrors.AsError(err, "cannot sign token"))
+	}
+	log.Debug().Str("email", user.Email).Str("role", user.RoleName).Msg("mock login")
+	return &grpc_authx_go.LoginResponse{Token: signed, RefreshToken: newID()}, nil
+}
+
+// ServeHTTP serves the login on LoginPath for the web clients. The body is the JSON representation of the
+// gRPC request.
+func (l *Login) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	request := &grpc_authx_go.LoginWithBasicCredentialsRequest{}
+	if err := jsonpb.Unmarshal(r.Body, request); err != nil {
+		http.Error(w, "invalid login request", http.StatusBadRequest)
+		return
+	}
+	response, err := l.LoginWithBasicCredentials(r.Context(), request)
+	if err != nil {
+		http.Error(w, status.Convert(err).Message(), runtime.HTTPStatusFromCode(status.Code(err)))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := (&jsonpb.Marshaler{}).Marshal(w, response); err != nil {
+		log.Warn().Err(err).Msg("cannot write login response")
+	}
+}
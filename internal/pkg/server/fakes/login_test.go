@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/nalej/authx/pkg/token"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+var _ = ginkgo.Describe("Login", func() {
+
+	const secret = "secret"
+
+	var login *Login
+
+	parse := func(signed string) *token.Claim {
+		tk, err := jwt.ParseWithClaims(signed, &token.Claim{}, func(*jwt.Token) (interface{}, error) {
+			return []byte(secret), nil
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		return tk.Claims.(*token.Claim)
+	}
+
+	ginkgo.BeforeEach(func() {
+		store := NewStore()
+		store.SeedFixtures()
+		login = NewLogin(store, secret, time.Minute)
+	})
+
+	ginkgo.It("should issue a token with the primitives of the role", func() {
+		response, err := login.LoginWithBasicCredentials(context.Background(),
+			&grpc_authx_go.LoginWithBasicCredentialsRequest{Username: "developer@nalej.com", Password: "any"})
+		gomega.Expect(err).To(gomega.Succeed())
+		claim := parse(response.Token)
+		gomega.Expect(claim.UserID).To(gomega.Equal("developer@nalej.com"))
+		gomega.Expect(claim.OrganizationID).To(gomega.Equal(FixtureOrganizationID))
+		gomega.Expect(claim.RoleName).To(gomega.Equal("Developer"))
+		gomega.Expect(claim.Primitives).To(gomega.ConsistOf(
+			grpc_authx_go.AccessPrimitive_PROFILE.String(), grpc_authx_go.AccessPrimitive_APPS.String()))
+	})
+
+	ginkgo.It("should reject unknown users and empty passwords", func() {
+		_, err := login.LoginWithBasicCredentials(context.Background(),
+			&grpc_authx_go.LoginWithBasicCredentialsRequest{Username: "unknown@nalej.com", Password: "any"})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = login.LoginWithBasicCredentials(context.Background(),
+			&grpc_authx_go.LoginWithBasicCredentialsRequest{Username: "admin@nalej.com"})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should serve the login over HTTP", func() {
+		recorder := httptest.NewRecorder()
+		body := strings.NewReader(`{"username": "admin@nalej.com", "password": "any"}`)
+		login.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, LoginPath, body))
+		gomega.Expect(recorder.Code).To(gomega.Equal(http.StatusOK))
+		response := &grpc_authx_go.LoginResponse{}
+		gomega.Expect(jsonpb.Unmarshal(recorder.Body, response)).To(gomega.Succeed())
+		gomega.Expect(parse(response.Token).RoleName).To(gomega.Equal("Owner"))
+
+		recorder = httptest.NewRecorder()
+		login.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, LoginPath, nil))
+		gomega.Expect(recorder.Code).To(gomega.Equal(http.StatusMethodNotAllowed))
+	})
+})
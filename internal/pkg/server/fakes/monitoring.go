@@ -0,0 +1,79 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-monitoring-go"
+)
+
+// MonitoringManager is a fake of the monitoring manager that returns the seeded cluster metrics.
+type MonitoringManager struct {
+	grpc_monitoring_go.UnimplementedMonitoringManagerServer
+	store *Store
+}
+
+// NewMonitoringManager creates the fake on top of a store.
+func NewMonitoringManager(store *Store) *MonitoringManager {
+	return &MonitoringManager{store: store}
+}
+
+// GetClusterSummary returns the seeded summary of a cluster.
+func (mm *MonitoringManager) GetClusterSummary(_ context.Context, request *grpc_monitoring_go.ClusterSummaryRequest) (*grpc_monitoring_go.ClusterSummary, error) {
+	mm.store.Lock()
+	defer mm.store.Unlock()
+	summary, exists := mm.store.clusterSummary[request.ClusterId]
+	if !exists {
+		return nil, notFound("cluster summary", request.ClusterId)
+	}
+	return proto.Clone(summary).(*grpc_monitoring_go.ClusterSummary), nil
+}
+
+// GetClusterStats returns the seeded statistics of a cluster.
+func (mm *MonitoringManager) GetClusterStats(_ context.Context, request *grpc_monitoring_go.ClusterStatsRequest) (*grpc_monitoring_go.ClusterStats, error) {
+	mm.store.Lock()
+	defer mm.store.Unlock()
+	stats, exists := mm.store.clusterStats[request.ClusterId]
+	if !exists {
+		return nil, notFound("cluster stats", request.ClusterId)
+	}
+	return proto.Clone(stats).(*grpc_monitoring_go.ClusterStats), nil
+}
+
+// AssetMonitoring is a fake of the asset monitoring service that returns the seeded metrics.
+type AssetMonitoring struct {
+	grpc_monitoring_go.UnimplementedAssetMonitoringServer
+	store *Store
+}
+
+// NewAssetMonitoring creates the fake on top of a store.
+func NewAssetMonitoring(store *Store) *AssetMonitoring {
+	return &AssetMonitoring{store: store}
+}
+
+// ListMetrics returns the seeded metrics of the organization.
+func (am *AssetMonitoring) ListMetrics(_ context.Context, selector *grpc_inventory_go.AssetSelector) (*grpc_monitoring_go.MetricsList, error) {
+	am.store.Lock()
+	defer am.store.Unlock()
+	metrics, exists := am.store.metrics[selector.OrganizationId]
+	if !exists {
+		return &grpc_monitoring_go.MetricsList{}, nil
+	}
+	return proto.Clone(metrics).(*grpc_monitoring_go.MetricsList), nil
+}
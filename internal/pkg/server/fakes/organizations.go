@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-organization-manager-go"
+)
+
+// Organizations is a fake of the organization manager.
+type Organizations struct {
+	grpc_organization_manager_go.UnimplementedOrganizationsServer
+	store *Store
+}
+
+// NewOrganizations creates the fake on top of a store.
+func NewOrganizations(store *Store) *Organizations {
+	return &Organizations{store: store}
+}
+
+// GetOrganization returns an organization.
+func (o *Organizations) GetOrganization(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_organization_manager_go.Organization, error) {
+	o.store.Lock()
+	defer o.store.Unlock()
+	organization, exists := o.store.organizations[organizationID.OrganizationId]
+	if !exists {
+		return nil, notFound("organization", organizationID.OrganizationId)
+	}
+	return proto.Clone(organization).(*grpc_organization_manager_go.Organization), nil
+}
+
+// UpdateOrganization updates the name of an organization.
+func (o *Organizations) UpdateOrganization(_ context.Context, request *grpc_organization_go.UpdateOrganizationRequest) (*grpc_common_go.Success, error) {
+	o.store.Lock()
+	defer o.store.Unlock()
+	organization, exists := o.store.organizations[request.OrganizationId]
+	if !exists {
+		return nil, notFound("organization", request.OrganizationId)
+	}
+	if request.UpdateName {
+		organization.Name = request.Name
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// ListSettings returns the settings of an organization.
+func (o *Organizations) ListSettings(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_organization_manager_go.SettingList, error) {
+	o.store.Lock()
+	defer o.store.Unlock()
+	result := make([]*grpc_organization_manager_go.Setting, 0)
+	for _, setting := range o.store.settings[organizationID.OrganizationId] {
+		result = append(result, proto.Clone(setting).(*grpc_organization_manager_go.Setting))
+	}
+	return &grpc_organization_manager_go.SettingList{Settings: result}, nil
+}
+
+// UpdateSettings updates the value of an existing setting.
+func (o *Organizations) UpdateSettings(_ context.Context, request *grpc_organization_go.UpdateSettingRequest) (*grpc_common_go.Success, error) {
+	o.store.Lock()
+	defer o.store.Unlock()
+	for _, setting := range o.store.settings[request.OrganizationId] {
+		if setting.Key == request.Key {
+			if request.UpdateValue {
+				setting.Value = request.Value
+			}
+			return &grpc_common_go.Success{}, nil
+		}
+	}
+	return nil, notFound("setting", request.Key)
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fakes contains in-memory implementations of the upstream components used by the public API. All
+// of them are served by a single in-process gRPC server, so the handlers can be tested without a running
+// platform. The services that are not faked answer with codes.Unimplemented.
+package fakes
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-monitoring-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+)
+
+// BufferSize with the size of the in-memory connection buffer.
+const BufferSize = 1024 * 1024
+
+// Platform serves the fake upstream components.
+type Platform struct {
+	// Store with the state of the components.
+	Store *Store
+	// Faults injected in the components.
+	Faults   *Faults
+	server   *grpc.Server
+	listener *bufconn.Listener
+	conn     *grpc.ClientConn
+}
+
+// NewPlatform creates a platform with an empty store.
+func NewPlatform() *Platform {
+	return &Platform{
+		Store:  NewStore(),
+		Faults: NewFaults(),
+	}
+}
+
+// Start launches the in-process server and connects to it.
+func (p *Platform) Start() derrors.Error {
+	p.listener = bufconn.Listen(BufferSize)
+	p.server = grpc.NewServer(grpc.UnaryInterceptor(p.Faults.UnaryServerInterceptor()))
+
+	grpc_infrastructure_go.RegisterClustersServer(p.server, NewClusters(p.Store))
+	grpc_infrastructure_go.RegisterNodesServer(p.server, NewNodes(p.Store))
+	grpc_infrastructure_manager_go.RegisterInfrastructureManagerServer(p.server, NewInfrastructureManager(p.Store))
+	grpc_organization_manager_go.RegisterOrganizationsServer(p.server, NewOrganizations(p.Store))
+	grpc_user_manager_go.RegisterUserManagerServer(p.server, NewUserManager(p.Store))
+	grpc_application_manager_go.RegisterApplicationManagerServer(p.server, NewApplicationManager(p.Store))
+	grpc_application_manager_go.RegisterUnifiedLoggingServer(p.server, NewUnifiedLogging(p.Store))
+	grpc_device_manager_go.RegisterDevicesServer(p.server, NewDevices(p.Store))
+	grpc_inventory_manager_go.RegisterInventoryServer(p.server, NewInventory(p.Store))
+	grpc_inventory_manager_go.RegisterEICServer(p.server, NewEIC(p.Store))
+	grpc_monitoring_go.RegisterMonitoringManagerServer(p.server, NewMonitoringManager(p.Store))
+	grpc_monitoring_go.RegisterAssetMonitoringServer(p.server, NewAssetMonitoring(p.Store))
+	grpc_log_download_manager_go.RegisterLogDownloadManagerServer(p.server, NewLogDownload(p.Store))
+
+	go func() {
+		if err := p.server.Serve(p.listener); err != nil {
+			log.Warn().Err(err).Msg("fake platform stopped")
+		}
+	}()
+
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return p.listener.Dial()
+		}))
+	if err != nil {
+		p.server.Stop()
+		return derrors.AsError(err, "cannot connect with the fake platform")
+	}
+	p.conn = conn
+	return nil
+}
+
+// Conn returns the connection with the fake components. All the upstream clients can share it.
+func (p *Platform) Conn() *grpc.ClientConn {
+	return p.conn
+}
+
+// Stop closes the connection and stops the server.
+func (p *Platform) Stop() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+	if p.server != nil {
+		p.server.Stop()
+	}
+}
@@ -0,0 +1,312 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-monitoring-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/satori/go.uuid"
+	"sync"
+	"time"
+)
+
+// Store contains the state shared by the fake upstream components. The entities are kept by value, so the
+// callers never receive a pointer to the stored entity.
+type Store struct {
+	sync.Mutex
+	organizations  map[string]*grpc_organization_manager_go.Organization
+	settings       map[string][]*grpc_organization_manager_go.Setting
+	clusters       map[string]*grpc_infrastructure_go.Cluster
+	nodes          map[string]*grpc_infrastructure_go.Node
+	roles          map[string][]*grpc_authx_go.Role
+	users          map[string]*grpc_user_manager_go.User
+	descriptors    map[string]*grpc_application_go.AppDescriptor
+	instances      map[string]*grpc_application_manager_go.AppInstance
+	instanceParams map[string]*grpc_application_go.InstanceParameterList
+	deviceGroups   map[string]*grpc_device_manager_go.DeviceGroup
+	devices        map[string]*grpc_device_manager_go.Device
+	assets         map[string]*grpc_inventory_manager_go.Asset
+	controllers    map[string]*grpc_inventory_manager_go.EdgeController
+	logEntries     map[string][]*grpc_application_manager_go.LogEntryResponse
+	downloads      map[string]*grpc_log_download_manager_go.DownloadLogResponse
+	clusterSummary map[string]*grpc_monitoring_go.ClusterSummary
+	clusterStats   map[string]*grpc_monitoring_go.ClusterStats
+	metrics        map[string]*grpc_monitoring_go.MetricsList
+}
+
+// NewStore creates an empty store.
+func NewStore() *Store {
+	return &Store{
+		organizations:  make(map[string]*grpc_organization_manager_go.Organization, 0),
+		settings:       make(map[string][]*grpc_organization_manager_go.Setting, 0),
+		clusters:       make(map[string]*grpc_infrastructure_go.Cluster, 0),
+		nodes:          make(map[string]*grpc_infrastructure_go.Node, 0),
+		roles:          make(map[string][]*grpc_authx_go.Role, 0),
+		users:          make(map[string]*grpc_user_manager_go.User, 0),
+		descriptors:    make(map[string]*grpc_application_go.AppDescriptor, 0),
+		instances:      make(map[string]*grpc_application_manager_go.AppInstance, 0),
+		instanceParams: make(map[string]*grpc_application_go.InstanceParameterList, 0),
+		deviceGroups:   make(map[string]*grpc_device_manager_go.DeviceGroup, 0),
+		devices:        make(map[string]*grpc_device_manager_go.Device, 0),
+		assets:         make(map[string]*grpc_inventory_manager_go.Asset, 0),
+		controllers:    make(map[string]*grpc_inventory_manager_go.EdgeController, 0),
+		logEntries:     make(map[string][]*grpc_application_manager_go.LogEntryResponse, 0),
+		downloads:      make(map[string]*grpc_log_download_manager_go.DownloadLogResponse, 0),
+		clusterSummary: make(map[string]*grpc_monitoring_go.ClusterSummary, 0),
+		clusterStats:   make(map[string]*grpc_monitoring_go.ClusterStats, 0),
+		metrics:        make(map[string]*grpc_monitoring_go.MetricsList, 0),
+	}
+}
+
+// newID generates the identifier of a new entity.
+func newID() string {
+	return uuid.NewV4().String()
+}
+
+// notFound returns the error sent by the upstream components when an entity does not exist.
+func notFound(entity string, params ...string) error {
+	return conversions.ToGRPCError(derrors.NewNotFoundError(fmt.Sprintf("%s not found", entity)).WithParams(params))
+}
+
+// userKey returns the key of a user in the store.
+func userKey(organizationID string, email string) string {
+	return organizationID + "/" + email
+}
+
+// deviceKey returns the key of a device in the store.
+func deviceKey(organizationID string, deviceGroupID string, deviceID string) string {
+	return organizationID + "/" + deviceGroupID + "/" + deviceID
+}
+
+// copyLabels returns a copy of a set of labels.
+func copyLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		result[key] = value
+	}
+	return result
+}
+
+// updateLabels applies a label update request to a set of labels.
+func updateLabels(current map[string]string, add bool, remove bool, labels map[string]string) map[string]string {
+	if current == nil {
+		current = make(map[string]string, 0)
+	}
+	for key, value := range labels {
+		if add {
+			current[key] = value
+		}
+		if remove {
+			delete(current, key)
+		}
+	}
+	return current
+}
+
+// AddOrganization seeds an organization. The identifier is generated if not set.
+func (s *Store) AddOrganization(organization *grpc_organization_manager_go.Organization) *grpc_organization_manager_go.Organization {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(organization).(*grpc_organization_manager_go.Organization)
+	if toAdd.OrganizationId == "" {
+		toAdd.OrganizationId = newID()
+	}
+	s.organizations[toAdd.OrganizationId] = toAdd
+	return proto.Clone(toAdd).(*grpc_organization_manager_go.Organization)
+}
+
+// AddSetting seeds a setting of an organization.
+func (s *Store) AddSetting(setting *grpc_organization_manager_go.Setting) {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(setting).(*grpc_organization_manager_go.Setting)
+	s.settings[toAdd.OrganizationId] = append(s.settings[toAdd.OrganizationId], toAdd)
+}
+
+// AddDownload seeds a log download request. The identifier is generated if not set.
+func (s *Store) AddDownload(response *grpc_log_download_manager_go.DownloadLogResponse) *grpc_log_download_manager_go.DownloadLogResponse {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(response).(*grpc_log_download_manager_go.DownloadLogResponse)
+	if toAdd.RequestId == "" {
+		toAdd.RequestId = newID()
+	}
+	s.downloads[toAdd.RequestId] = toAdd
+	return proto.Clone(toAdd).(*grpc_log_download_manager_go.DownloadLogResponse)
+}
+
+// AddCluster seeds a cluster. The identifier is generated if not set.
+func (s *Store) AddCluster(cluster *grpc_infrastructure_go.Cluster) *grpc_infrastructure_go.Cluster {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(cluster).(*grpc_infrastructure_go.Cluster)
+	if toAdd.ClusterId == "" {
+		toAdd.ClusterId = newID()
+	}
+	s.clusters[toAdd.ClusterId] = toAdd
+	return proto.Clone(toAdd).(*grpc_infrastructure_go.Cluster)
+}
+
+// AddNode seeds a node of a cluster. The identifier is generated if not set.
+func (s *Store) AddNode(node *grpc_infrastructure_go.Node) *grpc_infrastructure_go.Node {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(node).(*grpc_infrastructure_go.Node)
+	if toAdd.NodeId == "" {
+		toAdd.NodeId = newID()
+	}
+	s.nodes[toAdd.NodeId] = toAdd
+	return proto.Clone(toAdd).(*grpc_infrastructure_go.Node)
+}
+
+// AddRole seeds a role of an organization. The identifier is generated if not set.
+func (s *Store) AddRole(role *grpc_authx_go.Role) *grpc_authx_go.Role {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(role).(*grpc_authx_go.Role)
+	if toAdd.RoleId == "" {
+		toAdd.RoleId = newID()
+	}
+	s.roles[toAdd.OrganizationId] = append(s.roles[toAdd.OrganizationId], toAdd)
+	return proto.Clone(toAdd).(*grpc_authx_go.Role)
+}
+
+// AddUser seeds a user of an organization.
+func (s *Store) AddUser(user *grpc_user_manager_go.User) *grpc_user_manager_go.User {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(user).(*grpc_user_manager_go.User)
+	if toAdd.MemberSince == 0 {
+		toAdd.MemberSince = time.Now().Unix()
+	}
+	s.users[userKey(toAdd.OrganizationId, toAdd.Email)] = toAdd
+	return proto.Clone(toAdd).(*grpc_user_manager_go.User)
+}
+
+// AddDescriptor seeds an application descriptor. The identifier is generated if not set.
+func (s *Store) AddDescriptor(descriptor *grpc_application_go.AppDescriptor) *grpc_application_go.AppDescriptor {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(descriptor).(*grpc_application_go.AppDescriptor)
+	if toAdd.AppDescriptorId == "" {
+		toAdd.AppDescriptorId = newID()
+	}
+	s.descriptors[toAdd.AppDescriptorId] = toAdd
+	return proto.Clone(toAdd).(*grpc_application_go.AppDescriptor)
+}
+
+// AddInstance seeds an application instance. The identifier is generated if not set.
+func (s *Store) AddInstance(instance *grpc_application_manager_go.AppInstance) *grpc_application_manager_go.AppInstance {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(instance).(*grpc_application_manager_go.AppInstance)
+	if toAdd.AppInstanceId == "" {
+		toAdd.AppInstanceId = newID()
+	}
+	s.instances[toAdd.AppInstanceId] = toAdd
+	return proto.Clone(toAdd).(*grpc_application_manager_go.AppInstance)
+}
+
+// AddDeviceGroup seeds a device group. The identifier is generated if not set.
+func (s *Store) AddDeviceGroup(group *grpc_device_manager_go.DeviceGroup) *grpc_device_manager_go.DeviceGroup {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(group).(*grpc_device_manager_go.DeviceGroup)
+	if toAdd.DeviceGroupId == "" {
+		toAdd.DeviceGroupId = newID()
+	}
+	s.deviceGroups[toAdd.DeviceGroupId] = toAdd
+	return proto.Clone(toAdd).(*grpc_device_manager_go.DeviceGroup)
+}
+
+// AddDevice seeds a device of a device group.
+func (s *Store) AddDevice(device *grpc_device_manager_go.Device) *grpc_device_manager_go.Device {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(device).(*grpc_device_manager_go.Device)
+	if toAdd.RegisterSince == 0 {
+		toAdd.RegisterSince = time.Now().Unix()
+	}
+	s.devices[deviceKey(toAdd.OrganizationId, toAdd.DeviceGroupId, toAdd.DeviceId)] = toAdd
+	return proto.Clone(toAdd).(*grpc_device_manager_go.Device)
+}
+
+// AddAsset seeds an asset of the inventory. The identifier is generated if not set.
+func (s *Store) AddAsset(asset *grpc_inventory_manager_go.Asset) *grpc_inventory_manager_go.Asset {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(asset).(*grpc_inventory_manager_go.Asset)
+	if toAdd.AssetId == "" {
+		toAdd.AssetId = newID()
+	}
+	s.assets[toAdd.AssetId] = toAdd
+	return proto.Clone(toAdd).(*grpc_inventory_manager_go.Asset)
+}
+
+// AddController seeds an edge controller of the inventory. The identifier is generated if not set.
+func (s *Store) AddController(controller *grpc_inventory_manager_go.EdgeController) *grpc_inventory_manager_go.EdgeController {
+	s.Lock()
+	defer s.Unlock()
+	toAdd := proto.Clone(controller).(*grpc_inventory_manager_go.EdgeController)
+	if toAdd.EdgeControllerId == "" {
+		toAdd.EdgeControllerId = newID()
+	}
+	s.controllers[toAdd.EdgeControllerId] = toAdd
+	return proto.Clone(toAdd).(*grpc_inventory_manager_go.EdgeController)
+}
+
+// AddLogEntries seeds the log entries returned by the searches of an organization.
+func (s *Store) AddLogEntries(organizationID string, entries ...*grpc_application_manager_go.LogEntryResponse) {
+	s.Lock()
+	defer s.Unlock()
+	for _, entry := range entries {
+		s.logEntries[organizationID] = append(s.logEntries[organizationID], proto.Clone(entry).(*grpc_application_manager_go.LogEntryResponse))
+	}
+}
+
+// SetClusterSummary seeds the monitoring summary of a cluster.
+func (s *Store) SetClusterSummary(clusterID string, summary *grpc_monitoring_go.ClusterSummary) {
+	s.Lock()
+	defer s.Unlock()
+	s.clusterSummary[clusterID] = proto.Clone(summary).(*grpc_monitoring_go.ClusterSummary)
+}
+
+// SetClusterStats seeds the monitoring statistics of a cluster.
+func (s *Store) SetClusterStats(clusterID string, stats *grpc_monitoring_go.ClusterStats) {
+	s.Lock()
+	defer s.Unlock()
+	s.clusterStats[clusterID] = proto.Clone(stats).(*grpc_monitoring_go.ClusterStats)
+}
+
+// SetMetrics seeds the asset metrics available in an organization.
+func (s *Store) SetMetrics(organizationID string, metrics *grpc_monitoring_go.MetricsList) {
+	s.Lock()
+	defer s.Unlock()
+	s.metrics[organizationID] = proto.Clone(metrics).(*grpc_monitoring_go.MetricsList)
+}
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fakes
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-user-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"sort"
+	"time"
+)
+
+// findRole returns the role of an organization with a given identifier.
+func (s *Store) findRole(organizationID string, roleID string) *grpc_authx_go.Role {
+	for _, role := range s.roles[organizationID] {
+		if role.RoleId == roleID {
+			return role
+		}
+	}
+	return nil
+}
+
+// UserManager is a fake of the user manager. Passwords are not stored.
+type UserManager struct {
+	grpc_user_manager_go.UnimplementedUserManagerServer
+	store *Store
+}
+
+// NewUserManager creates the fake on top of a store.
+func NewUserManager(store *Store) *UserManager {
+	return &UserManager{store: store}
+}
+
+// ListRoles returns the roles of an organization.
+func (um *UserManager) ListRoles(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_authx_go.RoleList, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	result := make([]*grpc_authx_go.Role, 0)
+	for _, role := range um.store.roles[organizationID.OrganizationId] {
+		result = append(result, proto.Clone(role).(*grpc_authx_go.Role))
+	}
+	return &grpc_authx_go.RoleList{Roles: result}, nil
+}
+
+// AddUser adds a user with an existing role.
+func (um *UserManager) AddUser(_ context.Context, request *grpc_user_manager_go.AddUserRequest) (*grpc_user_manager_go.User, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	role := um.store.findRole(request.OrganizationId, request.RoleId)
+	if role == nil {
+		return nil, notFound("role", request.RoleId)
+	}
+	key := userKey(request.OrganizationId, request.Email)
+	if _, exists := um.store.users[key]; exists {
+		return nil, conversions.ToGRPCError(derrors.NewAlreadyExistsError("user already exists").WithParams(request.Email))
+	}
+	added := &grpc_user_manager_go.User{
+		OrganizationId: request.OrganizationId,
+		Email:          request.Email,
+		Name:           request.Name,
+		PhotoBase64:    request.PhotoBase64,
+		MemberSince:    time.Now().Unix(),
+		RoleId:         role.RoleId,
+		RoleName:       role.Name,
+		LastName:       request.LastName,
+		Title:          request.Title,
+		Phone:          request.Phone,
+		Location:       request.Location,
+	}
+	um.store.users[key] = added
+	return proto.Clone(added).(*grpc_user_manager_go.User), nil
+}
+
+// GetUser returns a user.
+func (um *UserManager) GetUser(_ context.Context, userID *grpc_user_go.UserId) (*grpc_user_manager_go.User, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	user, exists := um.store.users[userKey(userID.OrganizationId, userID.Email)]
+	if !exists {
+		return nil, notFound("user", userID.Email)
+	}
+	return proto.Clone(user).(*grpc_user_manager_go.User), nil
+}
+
+// ListUsers returns the users of an organization sorted by email.
+func (um *UserManager) ListUsers(_ context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_user_manager_go.UserList, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	result := make([]*grpc_user_manager_go.User, 0)
+	for _, user := range um.store.users {
+		if user.OrganizationId == organizationID.OrganizationId {
+			result = append(result, proto.Clone(user).(*grpc_user_manager_go.User))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Email < result[j].Email })
+	return &grpc_user_manager_go.UserList{Users: result}, nil
+}
+
+// RemoveUser removes a user.
+func (um *UserManager) RemoveUser(_ context.Context, userID *grpc_user_go.UserId) (*grpc_common_go.Success, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	key := userKey(userID.OrganizationId, userID.Email)
+	if _, exists := um.store.users[key]; !exists {
+		return nil, notFound("user", userID.Email)
+	}
+	delete(um.store.users, key)
+	return &grpc_common_go.Success{}, nil
+}
+
+// Update updates the name of a user.
+func (um *UserManager) Update(_ context.Context, request *grpc_user_go.UpdateUserRequest) (*grpc_common_go.Success, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	user, exists := um.store.users[userKey(request.OrganizationId, request.Email)]
+	if !exists {
+		return nil, notFound("user", request.Email)
+	}
+	if request.UpdateName {
+		user.Name = request.Name
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// ChangePassword checks that the user exists.
+func (um *UserManager) ChangePassword(_ context.Context, request *grpc_user_manager_go.ChangePasswordRequest) (*grpc_common_go.Success, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	if _, exists := um.store.users[userKey(request.OrganizationId, request.Email)]; !exists {
+		return nil, notFound("user", request.Email)
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// AssignRole changes the role of a user.
+func (um *UserManager) AssignRole(_ context.Context, request *grpc_user_manager_go.AssignRoleRequest) (*grpc_user_manager_go.User, error) {
+	um.store.Lock()
+	defer um.store.Unlock()
+	user, exists := um.store.users[userKey(request.OrganizationId, request.Email)]
+	if !exists {
+		return nil, notFound("user", request.Email)
+	}
+	role := um.store.findRole(request.OrganizationId, request.RoleId)
+	if role == nil {
+		return nil, notFound("role", request.RoleId)
+	}
+	user.RoleId = role.RoleId
+	user.RoleName = role.Name
+	return proto.Clone(user).(*grpc_user_manager_go.User), nil
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// KeyHeader with the name of the metadata entry and HTTP header containing the idempotency key.
+const KeyHeader = "idempotency-key"
+
+// ReplayedHeader with the name of the metadata entry set on the responses replayed from the store.
+const ReplayedHeader = "idempotent-replayed"
+
+// readOnlyPrefixes with the prefixes of the methods that do not modify the platform. The idempotency key is
+// ignored on them as the client expects fresh results.
+var readOnlyPrefixes = []string{"Get", "List", "Search", "Summary", "Info", "Check", "Monitor", "Query", "Wait", "Export", "Run"}
+
+// isMutation checks whether a full gRPC method name may modify the platform.
+func isMutation(fullMethod string) bool {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range readOnlyPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+// keyFromContext retrieves the idempotency key sent by the client, if any.
+func keyFromContext(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	values := md.Get(KeyHeader)
+	if len(values) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(values[0])
+}
+
+// UnaryServerInterceptor executes once the mutating requests sent with an idempotency key. Retries with the same
+// key and payload get the original response, and retries with a different payload are rejected. The keys are
+// scoped to the user and organization found in the authx metadata, so it must run after the authx interceptor.
+func UnaryServerInterceptor(store *Store) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := keyFromContext(ctx)
+		request, isProto := req.(proto.Message)
+		if key == "" || !isProto || !store.Enabled() || !isMutation(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		if len(key) > MaxKeyLength {
+			return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("idempotency key is too long"))
+		}
+		rm, err := authhelper.GetRequestMetadata(ctx)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		hash, err := Hash(info.FullMethod, request)
+		if err != nil {
+			return nil, conversions.ToGRPCError(err)
+		}
+		executed := false
+		response, replayed, callErr := store.Do(ctx, rm.OrganizationID+"/"+rm.UserID+"/"+key, hash, func() (proto.Message, error) {
+			executed = true
+			result, hErr := handler(ctx, req)
+			if hErr != nil {
+				return nil, hErr
+			}
+			message, _ := result.(proto.Message)
+			return message, nil
+		})
+		if callErr != nil {
+			if dErr, isStoreErr := callErr.(derrors.Error); isStoreErr && !executed {
+				return nil, conversions.ToGRPCError(dErr)
+			}
+			return nil, callErr
+		}
+		if replayed {
+			log.Debug().Str("organizationID", rm.OrganizationID).Str("userID", rm.UserID).
+				Str("method", info.FullMethod).Str("key", key).Msg("response replayed from idempotency key")
+			if hErr := grpc.SetHeader(ctx, metadata.Pairs(ReplayedHeader, "true")); hErr != nil {
+				log.Warn().Err(hErr).Msg("cannot set idempotent-replayed header")
+			}
+		}
+		return response, nil
+	}
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var _ = ginkgo.Describe("Idempotency interceptor", func() {
+
+	var interceptor grpc.UnaryServerInterceptor
+	var calls int
+
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return &wrappers.StringValue{Value: req.(*wrappers.StringValue).Value + "-created"}, nil
+	}
+
+	contextWith := func(user string, key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+			authhelper.UserIdField:         user,
+			authhelper.OrganizationIdField: "org",
+			KeyHeader:                      key,
+		}))
+	}
+
+	call := func(ctx context.Context, method string, value string) (interface{}, error) {
+		return interceptor(ctx, &wrappers.StringValue{Value: value}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+	}
+
+	ginkgo.BeforeEach(func() {
+		calls = 0
+		interceptor = UnaryServerInterceptor(NewStore(time.Minute))
+	})
+
+	ginkgo.It("should execute a mutation once per key", func() {
+		ctx := contextWith("user", "key")
+		first, err := call(ctx, "/public_api.Applications/AddAppDescriptor", "app")
+		gomega.Expect(err).To(gomega.Succeed())
+		second, err := call(ctx, "/public_api.Applications/AddAppDescriptor", "app")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(second.(*wrappers.StringValue).Value).Should(gomega.Equal(first.(*wrappers.StringValue).Value))
+		gomega.Expect(calls).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should reject a key reused with a different payload", func() {
+		ctx := contextWith("user", "key")
+		_, err := call(ctx, "/public_api.Clusters/ProvisionAndInstall", "a")
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = call(ctx, "/public_api.Clusters/ProvisionAndInstall", "b")
+		gomega.Expect(status.Code(err)).Should(gomega.Equal(codes.InvalidArgument))
+		gomega.Expect(calls).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should scope the keys to the user", func() {
+		call(contextWith("user", "key"), "/public_api.Applications/AddAppDescriptor", "app")
+		call(contextWith("other", "key"), "/public_api.Applications/AddAppDescriptor", "app")
+		gomega.Expect(calls).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should ignore the key on read-only methods", func() {
+		ctx := contextWith("user", "key")
+		call(ctx, "/public_api.Applications/ListAppDescriptors", "org")
+		call(ctx, "/public_api.Applications/ListAppDescriptors", "org")
+		gomega.Expect(calls).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should execute every request without a key", func() {
+		ctx := contextWith("user", "")
+		call(ctx, "/public_api.Applications/AddAppDescriptor", "app")
+		call(ctx, "/public_api.Applications/AddAppDescriptor", "app")
+		gomega.Expect(calls).Should(gomega.Equal(2))
+	})
+})
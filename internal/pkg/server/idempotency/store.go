@@ -0,0 +1,151 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"sync"
+	"time"
+)
+
+// DefaultWindow with the time the responses are kept to be replayed.
+const DefaultWindow = time.Hour * 24
+
+// MaxKeyLength with the maximum length accepted for an idempotency key.
+const MaxKeyLength = 255
+
+// entry with the state of a request sent with an idempotency key.
+type entry struct {
+	// hash of the method and the payload of the original request.
+	hash string
+	// response of the original request. It is nil while the request is in progress.
+	response proto.Message
+	// done is closed when the original request finishes.
+	done       chan struct{}
+	expiration time.Time
+}
+
+// Store keeps the successful responses of the requests sent with an idempotency key during a window, so retries
+// of the same request get the original response instead of executing it again.
+type Store struct {
+	window  time.Duration
+	mutex   sync.Mutex
+	entries map[string]*entry
+	// now returns the current time. It can be replaced in tests.
+	now func() time.Time
+}
+
+// NewStore creates a store that keeps the responses for a given window. A window of zero or less disables the
+// idempotency keys.
+func NewStore(window time.Duration) *Store {
+	return &Store{
+		window:  window,
+		entries: make(map[string]*entry, 0),
+		now:     time.Now,
+	}
+}
+
+// Enabled checks whether the store keeps any response.
+func (s *Store) Enabled() bool {
+	return s.window > 0
+}
+
+// Hash computes the fingerprint of a request to a given method.
+func Hash(method string, request proto.Message) (string, derrors.Error) {
+	buffer := proto.NewBuffer(nil)
+	buffer.SetDeterministic(true)
+	if err := buffer.Marshal(request); err != nil {
+		return "", derrors.AsError(err, "cannot marshal request")
+	}
+	digest := sha256.New()
+	digest.Write([]byte(method))
+	digest.Write([]byte{0})
+	digest.Write(buffer.Bytes())
+	return hex.EncodeToString(digest.Sum(nil)), nil
+}
+
+// purge removes the expired responses. The caller must hold the lock.
+func (s *Store) purge() {
+	now := s.now()
+	for key, e := range s.entries {
+		if e.response != nil && now.After(e.expiration) {
+			delete(s.entries, key)
+		}
+	}
+}
+
+// Do executes a call once per key and hash. A call with a key already used returns a copy of the original
+// response if the hash matches, or an error if it does not. Concurrent calls with the same key wait for the first
+// one to finish. Failed calls are not stored so the client can retry them. The errors of the call are returned
+// unchanged. The returned flag indicates whether the response is a replay.
+func (s *Store) Do(ctx context.Context, key string, hash string, call func() (proto.Message, error)) (proto.Message, bool, error) {
+	for {
+		s.mutex.Lock()
+		s.purge()
+		current, exists := s.entries[key]
+		if !exists {
+			current = &entry{hash: hash, done: make(chan struct{})}
+			s.entries[key] = current
+			s.mutex.Unlock()
+			return s.execute(key, current, call)
+		}
+		if current.hash != hash {
+			s.mutex.Unlock()
+			return nil, false, derrors.NewInvalidArgumentError("idempotency key already used with a different request").WithParams(key)
+		}
+		if current.response != nil {
+			response := proto.Clone(current.response)
+			s.mutex.Unlock()
+			return response, true, nil
+		}
+		done := current.done
+		s.mutex.Unlock()
+		select {
+		case <-done:
+			// The original request finished, check again whether its response was stored.
+		case <-ctx.Done():
+			return nil, false, derrors.NewDeadlineExceededError("waiting for the request with the same idempotency key", ctx.Err())
+		}
+	}
+}
+
+// execute runs the original call of a key and stores its response if it succeeds.
+func (s *Store) execute(key string, current *entry, call func() (proto.Message, error)) (proto.Message, bool, error) {
+	response, err := call()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err != nil || response == nil {
+		delete(s.entries, key)
+	} else {
+		current.response = proto.Clone(response)
+		current.expiration = s.now().Add(s.window)
+	}
+	close(current.done)
+	return response, false, err
+}
+
+// Len returns the number of keys in the store, including the ones of requests in progress.
+func (s *Store) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.purge()
+	return len(s.entries)
+}
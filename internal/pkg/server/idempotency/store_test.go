@@ -0,0 +1,116 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package idempotency
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/nalej/derrors"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Idempotency store", func() {
+
+	var store *Store
+	var now time.Time
+	var calls int
+
+	call := func() (proto.Message, error) {
+		calls++
+		return &wrappers.StringValue{Value: "response"}, nil
+	}
+
+	ginkgo.BeforeEach(func() {
+		now = time.Now()
+		calls = 0
+		store = NewStore(time.Minute)
+		store.now = func() time.Time { return now }
+	})
+
+	ginkgo.It("should replay the response of a repeated request", func() {
+		first, replayed, err := store.Do(context.Background(), "key", "hash", call)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(replayed).To(gomega.BeFalse())
+		second, replayed, err := store.Do(context.Background(), "key", "hash", call)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(replayed).To(gomega.BeTrue())
+		gomega.Expect(proto.Equal(first, second)).To(gomega.BeTrue())
+		gomega.Expect(calls).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should reject a key reused with a different request", func() {
+		_, _, err := store.Do(context.Background(), "key", "hash", call)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, _, err = store.Do(context.Background(), "key", "other", call)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(err.(derrors.Error).Type()).Should(gomega.Equal(derrors.InvalidArgument))
+		gomega.Expect(calls).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should not store failed requests", func() {
+		_, _, err := store.Do(context.Background(), "key", "hash", func() (proto.Message, error) {
+			return nil, derrors.NewUnavailableError("upstream down")
+		})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, replayed, err := store.Do(context.Background(), "key", "hash", call)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(replayed).To(gomega.BeFalse())
+		gomega.Expect(calls).Should(gomega.Equal(1))
+	})
+
+	ginkgo.It("should expire the responses after the window", func() {
+		store.Do(context.Background(), "key", "hash", call)
+		now = now.Add(time.Minute * 2)
+		gomega.Expect(store.Len()).Should(gomega.Equal(0))
+		_, replayed, _ := store.Do(context.Background(), "key", "other", call)
+		gomega.Expect(replayed).To(gomega.BeFalse())
+		gomega.Expect(calls).Should(gomega.Equal(2))
+	})
+
+	ginkgo.It("should wait for a request in progress with the same key", func() {
+		release := make(chan struct{})
+		started := make(chan struct{})
+		go func() {
+			store.Do(context.Background(), "key", "hash", func() (proto.Message, error) {
+				close(started)
+				<-release
+				return &wrappers.StringValue{Value: "slow"}, nil
+			})
+		}()
+		<-started
+		go func() {
+			time.Sleep(time.Millisecond * 50)
+			close(release)
+		}()
+		response, replayed, err := store.Do(context.Background(), "key", "hash", call)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(replayed).To(gomega.BeTrue())
+		gomega.Expect(response.(*wrappers.StringValue).Value).Should(gomega.Equal("slow"))
+	})
+
+	ginkgo.It("should compute the same hash for the same request", func() {
+		first, err := Hash("/test/Add", &wrappers.StringValue{Value: "a"})
+		gomega.Expect(err).To(gomega.Succeed())
+		second, _ := Hash("/test/Add", &wrappers.StringValue{Value: "a"})
+		other, _ := Hash("/test/Update", &wrappers.StringValue{Value: "a"})
+		gomega.Expect(first).Should(gomega.Equal(second))
+		gomega.Expect(first).ShouldNot(gomega.Equal(other))
+	})
+})
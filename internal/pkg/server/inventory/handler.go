@@ -50,7 +50,7 @@ func (h *Handler) List(ctx context.Context, orgID *grpc_organization_go.Organiza
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.List(orgID)
+	return h.Manager.List(ctx, orgID)
 }
 
 func (h *Handler) GetControllerExtendedInfo(ctx context.Context, edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_public_api_go.EdgeControllerExtendedInfo, error) {
@@ -65,7 +65,7 @@ func (h *Handler) GetControllerExtendedInfo(ctx context.Context, edgeControllerI
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.GetControllerExtendedInfo(edgeControllerID)
+	return h.Manager.GetControllerExtendedInfo(ctx, edgeControllerID)
 }
 
 func (h *Handler) GetAssetInfo(ctx context.Context, assetID *grpc_inventory_go.AssetId) (*grpc_public_api_go.Asset, error) {
@@ -80,7 +80,7 @@ func (h *Handler) GetAssetInfo(ctx context.Context, assetID *grpc_inventory_go.A
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.GetAssetInfo(assetID)
+	return h.Manager.GetAssetInfo(ctx, assetID)
 }
 
 func (h *Handler) GetDeviceInfo(ctx context.Context, deviceID *grpc_inventory_manager_go.DeviceId) (*grpc_public_api_go.Device, error) {
@@ -95,7 +95,7 @@ func (h *Handler) GetDeviceInfo(ctx context.Context, deviceID *grpc_inventory_ma
 	if vErr != nil {
 		return nil, conversions.ToGRPCError(vErr)
 	}
-	return h.Manager.GetDeviceInfo(deviceID)
+	return h.Manager.GetDeviceInfo(ctx, deviceID)
 }
 
 func (h *Handler) UpdateAsset(ctx context.Context, updateRequest *grpc_inventory_go.UpdateAssetRequest) (*grpc_inventory_go.Asset, error) {
@@ -111,7 +111,7 @@ func (h *Handler) UpdateAsset(ctx context.Context, updateRequest *grpc_inventory
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateAsset(updateRequest)
+	return h.Manager.UpdateAsset(ctx, updateRequest)
 
 }
 
@@ -130,7 +130,7 @@ func (h *Handler) UpdateDeviceLocation(ctx context.Context, request *grpc_invent
 	if vErr != nil {
 		return nil, conversions.ToGRPCError(vErr)
 	}
-	return h.Manager.UpdateDeviceLocation(request)
+	return h.Manager.UpdateDeviceLocation(ctx, request)
 }
 
 func (h *Handler) UpdateEdgeController(ctx context.Context, request *grpc_inventory_go.UpdateEdgeControllerRequest) (*grpc_inventory_go.EdgeController, error) {
@@ -145,7 +145,7 @@ func (h *Handler) UpdateEdgeController(ctx context.Context, request *grpc_invent
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateEdgeController(request)
+	return h.Manager.UpdateEdgeController(ctx, request)
 }
 
 func (h *Handler) Summary(ctx context.Context, orgId *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySummary, error) {
@@ -160,5 +160,5 @@ func (h *Handler) Summary(ctx context.Context, orgId *grpc_organization_go.Organ
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Summary(orgId)
+	return h.Manager.Summary(ctx, orgId)
 }
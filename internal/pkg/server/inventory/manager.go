@@ -17,12 +17,16 @@
 package inventory
 
 import (
+	"context"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/selector"
 	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/nalej/public-api/internal/pkg/server/decorators"
 )
 
 // Manager structure with the required clients for node operations.
@@ -39,8 +43,14 @@ func NewManager(invManagerClient grpc_inventory_manager_go.InventoryClient, ecCl
 	}
 }
 
-func (m *Manager) List(orgID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.InventoryList, error) {
-	ctx, cancel := common.GetContext()
+// List retrieves the devices, assets and edge controllers of an organization filtered by the label selector
+// of the request.
+func (m *Manager) List(ctx context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.InventoryList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	list, err := m.invManagerClient.List(ctx, orgID)
 	if err != nil {
@@ -50,6 +60,24 @@ func (m *Manager) List(orgID *grpc_organization_go.OrganizationId) (*grpc_public
 	devices := entities.ToPublicAPIDeviceArray(list.Devices)
 	assets := entities.ToPublicAPIAssetArray(list.Assets)
 	controllers := entities.ToPublicAPIControllerArray(list.Controllers)
+	if !sel.Empty() {
+		decorator := decorators.NewSelectorDecorator(sel)
+		filteredDevices := decorators.ApplyDecorator(devices, decorator)
+		if filteredDevices.Error != nil {
+			return nil, conversions.ToGRPCError(filteredDevices.Error)
+		}
+		filteredAssets := decorators.ApplyDecorator(assets, decorator)
+		if filteredAssets.Error != nil {
+			return nil, conversions.ToGRPCError(filteredAssets.Error)
+		}
+		filteredControllers := decorators.ApplyDecorator(controllers, decorator)
+		if filteredControllers.Error != nil {
+			return nil, conversions.ToGRPCError(filteredControllers.Error)
+		}
+		devices = filteredDevices.DeviceList
+		assets = filteredAssets.AssetList
+		controllers = filteredControllers.ControllerList
+	}
 
 	return &grpc_public_api_go.InventoryList{
 		Devices:     devices,
@@ -58,8 +86,8 @@ func (m *Manager) List(orgID *grpc_organization_go.OrganizationId) (*grpc_public
 	}, nil
 }
 
-func (m *Manager) GetControllerExtendedInfo(edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_public_api_go.EdgeControllerExtendedInfo, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetControllerExtendedInfo(ctx context.Context, edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_public_api_go.EdgeControllerExtendedInfo, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	info, err := m.invManagerClient.GetControllerExtendedInfo(ctx, edgeControllerID)
 	if err != nil {
@@ -71,8 +99,8 @@ func (m *Manager) GetControllerExtendedInfo(edgeControllerID *grpc_inventory_go.
 	}, nil
 }
 
-func (m *Manager) GetAssetInfo(assetID *grpc_inventory_go.AssetId) (*grpc_public_api_go.Asset, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetAssetInfo(ctx context.Context, assetID *grpc_inventory_go.AssetId) (*grpc_public_api_go.Asset, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	info, err := m.invManagerClient.GetAssetInfo(ctx, assetID)
 	if err != nil {
@@ -81,14 +109,14 @@ func (m *Manager) GetAssetInfo(assetID *grpc_inventory_go.AssetId) (*grpc_public
 	return entities.ToPublicAPIAsset(info), nil
 }
 
-func (m *Manager) UpdateAsset(updateRequest *grpc_inventory_go.UpdateAssetRequest) (*grpc_inventory_go.Asset, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateAsset(ctx context.Context, updateRequest *grpc_inventory_go.UpdateAssetRequest) (*grpc_inventory_go.Asset, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.invManagerClient.UpdateAsset(ctx, updateRequest)
 }
 
-func (m *Manager) UpdateDeviceLocation(udlr *grpc_inventory_manager_go.UpdateDeviceLocationRequest) (*grpc_public_api_go.Device, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateDeviceLocation(ctx context.Context, udlr *grpc_inventory_manager_go.UpdateDeviceLocationRequest) (*grpc_public_api_go.Device, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	deviceUpdate, err := m.invManagerClient.UpdateDevice(ctx, udlr)
@@ -98,8 +126,8 @@ func (m *Manager) UpdateDeviceLocation(udlr *grpc_inventory_manager_go.UpdateDev
 	return entities.InventoryDeviceToPublicAPIDevice(deviceUpdate), nil
 }
 
-func (m *Manager) GetDeviceInfo(deviceID *grpc_inventory_manager_go.DeviceId) (*grpc_public_api_go.Device, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetDeviceInfo(ctx context.Context, deviceID *grpc_inventory_manager_go.DeviceId) (*grpc_public_api_go.Device, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	info, err := m.invManagerClient.GetDeviceInfo(ctx, deviceID)
 	if err != nil {
@@ -108,14 +136,14 @@ func (m *Manager) GetDeviceInfo(deviceID *grpc_inventory_manager_go.DeviceId) (*
 	return entities.InventoryDeviceToPublicAPIDevice(info), nil
 }
 
-func (m *Manager) UpdateEdgeController(updateRequest *grpc_inventory_go.UpdateEdgeControllerRequest) (*grpc_inventory_go.EdgeController, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) UpdateEdgeController(ctx context.Context, updateRequest *grpc_inventory_go.UpdateEdgeControllerRequest) (*grpc_inventory_go.EdgeController, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.ecClient.UpdateEC(ctx, updateRequest)
 }
 
-func (m *Manager) Summary(orgId *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySummary, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Summary(ctx context.Context, orgId *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySummary, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.invManagerClient.Summary(ctx, orgId)
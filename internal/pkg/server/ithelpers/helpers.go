@@ -280,6 +280,12 @@ func GetAllAuthConfig() *interceptor.AuthorizationConfig {
 	permissions["/public_api.Applications/GetAppDescriptor"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
 	}
+	permissions["/public_api.Applications/GetDescriptorHistory"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.Applications/GetDescriptorDiff"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
 	permissions["/public_api.Applications/DeleteAppDescriptor"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
 	}
@@ -295,9 +301,57 @@ func GetAllAuthConfig() *interceptor.AuthorizationConfig {
 	permissions["/public_api.Applications/GetAppInstance"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
 	}
+	permissions["/public_api.Applications/ExportInstanceDescriptor"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
 	permissions["/public_api.UnifiedLogging/Search"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
 	}
+	permissions["/public_api.UnifiedLogging/Tail"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/Query"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/DownloadLog"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/Check"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/List"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/CancelDownload"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/DeleteDownload"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/PruneDownloads"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/AddSavedSearch"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/ListSavedSearches"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/RunSavedSearch"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/RemoveSavedSearch"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/AddAlertRule"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/ListAlertRules"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
+	permissions["/public_api.UnifiedLogging/RemoveAlertRule"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
 	permissions["/public_api.Devices/AddDeviceGroup"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_DEVMNGR.String()},
 	}
@@ -328,6 +382,11 @@ func GetAllAuthConfig() *interceptor.AuthorizationConfig {
 	permissions["/public_api.Devices/RemoveDevice"] = interceptor.Permission{
 		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_DEVMNGR.String()},
 	}
+	permissions["/public_api.Labels/BulkUpdate"] = interceptor.Permission{
+		Should: []string{grpc_authx_go.AccessPrimitive_ORG.String(), grpc_authx_go.AccessPrimitive_RESOURCES.String(),
+			grpc_authx_go.AccessPrimitive_RESOURCES_MNGT.String(), grpc_authx_go.AccessPrimitive_DEVMNGR.String(),
+			grpc_authx_go.AccessPrimitive_APPS.String()},
+	}
 	return &interceptor.AuthorizationConfig{
 		AllowsAll:   false,
 		Permissions: permissions,
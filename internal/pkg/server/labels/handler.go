@@ -0,0 +1,101 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package labels
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/nalej/public-api/internal/pkg/entities"
+)
+
+// Handler structure for the bulk label requests.
+type Handler struct {
+	Manager Manager
+}
+
+// NewHandler creates a new Handler with a linked manager.
+func NewHandler(manager Manager) *Handler {
+	return &Handler{manager}
+}
+
+// validOperation checks the operation and the labels of a bulk request. Only a replace accepts an empty set of
+// labels, removing all the existing ones.
+func validOperation(request *grpc_public_api_go.BulkLabelRequest) derrors.Error {
+	switch request.Operation {
+	case Add, Remove:
+		if len(request.Labels) == 0 {
+			return derrors.NewInvalidArgumentError("labels cannot be empty")
+		}
+		return nil
+	case Replace:
+		return nil
+	}
+	return derrors.NewInvalidArgumentError("invalid label operation").WithParams(request.Operation)
+}
+
+// updatePrimitives returns whether a user can update the entities of each resource type. Each resource type
+// requires the same primitives as the public API method that updates a single entity.
+var updatePrimitives = map[string]func(rm *authhelper.RequestMetadata) bool{
+	// Clusters/Update
+	Cluster: func(rm *authhelper.RequestMetadata) bool { return rm.OrgPrimitive || rm.ResourcePrimitive },
+	// Nodes/UpdateNode
+	Node: func(rm *authhelper.RequestMetadata) bool { return rm.ResourceMngtPrimitive },
+	// Devices/UpdateDevice
+	Device: func(rm *authhelper.RequestMetadata) bool { return rm.OrgPrimitive || rm.DevMngrPrimitive },
+	// Inventory/AddLabelToAsset and Inventory/RemoveLabelFromAsset
+	Asset: func(rm *authhelper.RequestMetadata) bool { return rm.OrgPrimitive || rm.ResourcePrimitive },
+	// Inventory/UpdateEdgeController
+	EdgeController: func(rm *authhelper.RequestMetadata) bool { return rm.ResourceMngtPrimitive },
+	// Applications/UpdateAppDescriptor
+	Descriptor: func(rm *authhelper.RequestMetadata) bool { return rm.OrgPrimitive || rm.AppsPrimitive },
+}
+
+// checkPrimitives verifies that the user can update the labels of a resource type.
+func checkPrimitives(rm *authhelper.RequestMetadata, resourceType string) derrors.Error {
+	allowed, exists := updatePrimitives[resourceType]
+	if !exists || !allowed(rm) {
+		return derrors.NewPermissionDeniedError("cannot update the labels of the requested resource type").WithParams(resourceType)
+	}
+	return nil
+}
+
+// BulkUpdate adds, removes or replaces the labels of a set of entities selected by identifier or labels.
+func (h *Handler) BulkUpdate(ctx context.Context, request *grpc_public_api_go.BulkLabelRequest) (*grpc_public_api_go.BulkLabelResponse, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidBulkLabelRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	err = validOperation(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	err = checkPrimitives(rm, request.ResourceType)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.BulkUpdate(ctx, request)
+}
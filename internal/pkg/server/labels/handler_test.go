@@ -0,0 +1,279 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package labels
+
+import (
+	"context"
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"time"
+)
+
+var _ = ginkgo.Describe("Labels handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.LabelsClient
+
+	var organizationID string
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+
+		organizationID = ithelpers.GenerateUUID()
+		platform.Store.AddCluster(&grpc_infrastructure_go.Cluster{
+			OrganizationId: organizationID, ClusterId: "madrid", Name: "madrid",
+			Labels: map[string]string{"env": "prod", "region": "eu"},
+		})
+		platform.Store.AddCluster(&grpc_infrastructure_go.Cluster{
+			OrganizationId: organizationID, ClusterId: "oregon", Name: "oregon",
+			Labels: map[string]string{"env": "dev", "region": "us"},
+		})
+		for _, assetID := range []string{"a1", "a2"} {
+			platform.Store.AddAsset(&grpc_inventory_manager_go.Asset{
+				OrganizationId: organizationID, AssetId: assetID,
+				Labels: map[string]string{"floor": "1", "old": "true"},
+			})
+		}
+		platform.Store.AddDescriptor(&grpc_application_go.AppDescriptor{
+			OrganizationId: organizationID, AppDescriptorId: "wordpress", Name: "wordpress",
+		})
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(
+			grpc_infrastructure_go.NewClustersClient(platform.Conn()),
+			grpc_infrastructure_go.NewNodesClient(platform.Conn()),
+			grpc_infrastructure_manager_go.NewInfrastructureManagerClient(platform.Conn()),
+			grpc_device_manager_go.NewDevicesClient(platform.Conn()),
+			grpc_inventory_manager_go.NewInventoryClient(platform.Conn()),
+			grpc_inventory_manager_go.NewEICClient(platform.Conn()),
+			grpc_application_manager_go.NewApplicationManagerClient(platform.Conn()),
+			cache.NewCache("test", time.Minute))
+		grpc_public_api_go.RegisterLabelsServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewLabelsClient(conn)
+
+		token = ithelpers.GenerateToken("email@nalej.com", organizationID, "Operator", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_RESOURCES})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	clusterLabels := func(clusterID string) map[string]string {
+		cluster, err := grpc_infrastructure_go.NewClustersClient(platform.Conn()).GetCluster(
+			context.Background(), &grpc_infrastructure_go.ClusterId{OrganizationId: organizationID, ClusterId: clusterID})
+		gomega.Expect(err).To(gomega.Succeed())
+		return cluster.Labels
+	}
+
+	ginkgo.It("should add labels to the entities matching the selector", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			MatchLabels:    map[string]string{"env": "prod"},
+			Operation:      Add,
+			Labels:         map[string]string{"tier": "gold"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Succeeded).To(gomega.Equal(int32(1)))
+		gomega.Expect(response.Results[0].EntityId).To(gomega.Equal("madrid"))
+		gomega.Expect(response.Results[0].Changed).To(gomega.BeTrue())
+		gomega.Expect(clusterLabels("madrid")).To(gomega.HaveKeyWithValue("tier", "gold"))
+		gomega.Expect(clusterLabels("oregon")).NotTo(gomega.HaveKey("tier"))
+	})
+
+	ginkgo.It("should replace the labels of the entities matching a selector expression", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			Selector:       "env!=prod,region in (us,asia)",
+			Operation:      Replace,
+			Labels:         map[string]string{"env": "staging"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Results).To(gomega.HaveLen(1))
+		gomega.Expect(response.Results[0].EntityId).To(gomega.Equal("oregon"))
+		gomega.Expect(clusterLabels("oregon")).To(gomega.Equal(map[string]string{"env": "staging"}))
+		gomega.Expect(clusterLabels("madrid")).To(gomega.HaveKeyWithValue("env", "prod"))
+	})
+
+	ginkgo.It("should reject an invalid selector expression", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			Selector:       "region in (us",
+			Operation:      Add,
+			Labels:         map[string]string{"tier": "gold"},
+		})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+
+	ginkgo.It("should not apply the changes in dry run mode", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			EntityIds:      []string{"madrid", "oregon"},
+			Operation:      Remove,
+			Labels:         map[string]string{"region": ""},
+			DryRun:         true,
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Results).To(gomega.HaveLen(2))
+		for _, result := range response.Results {
+			gomega.Expect(result.Changed).To(gomega.BeTrue())
+			gomega.Expect(result.Labels).NotTo(gomega.HaveKey("region"))
+		}
+		gomega.Expect(clusterLabels("madrid")).To(gomega.HaveKey("region"))
+	})
+
+	ginkgo.It("should replace the labels of a list of assets", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Asset,
+			EntityIds:      []string{"a1", "a2"},
+			Operation:      Replace,
+			Labels:         map[string]string{"floor": "2"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Succeeded).To(gomega.Equal(int32(2)))
+		asset, err := grpc_inventory_manager_go.NewInventoryClient(platform.Conn()).GetAssetInfo(ctx,
+			&grpc_inventory_go.AssetId{OrganizationId: organizationID, AssetId: "a2"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(asset.Labels).To(gomega.Equal(map[string]string{"floor": "2"}))
+	})
+
+	ginkgo.It("should report the entities that cannot be updated", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		platform.Faults.FailOn("InfrastructureManager/UpdateCluster", status.Error(codes.Unavailable, "down"))
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			EntityIds:      []string{"madrid", "unknown"},
+			Operation:      Add,
+			Labels:         map[string]string{"tier": "gold"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Failed).To(gomega.Equal(int32(2)))
+		gomega.Expect(response.Results[0].Error).To(gomega.Equal("down"))
+		gomega.Expect(response.Results[1].EntityId).To(gomega.Equal("unknown"))
+	})
+
+	ginkgo.It("should require the apps primitive to update descriptors", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Descriptor,
+			EntityIds:      []string{"wordpress"},
+			Operation:      Add,
+			Labels:         map[string]string{"team": "web"},
+		})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.PermissionDenied))
+	})
+
+	ginkgo.It("should require the primitives of the single entity update of each resource type", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		for _, resourceType := range []string{Node, EdgeController, Device} {
+			_, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+				OrganizationId: organizationID,
+				ResourceType:   resourceType,
+				EntityIds:      []string{"entity"},
+				Operation:      Add,
+				Labels:         map[string]string{"tier": "gold"},
+			})
+			gomega.Expect(status.Code(err)).To(gomega.Equal(codes.PermissionDenied), resourceType)
+		}
+	})
+
+	ginkgo.It("should let the device managers update the labels of the devices", func() {
+		group := platform.Store.AddDeviceGroup(&grpc_device_manager_go.DeviceGroup{OrganizationId: organizationID, Name: "sensors"})
+		platform.Store.AddDevice(&grpc_device_manager_go.Device{
+			OrganizationId: organizationID, DeviceGroupId: group.DeviceGroupId, DeviceId: "d1",
+		})
+		deviceToken := ithelpers.GenerateToken("email@nalej.com", organizationID, "DeviceManager", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_DEVMNGR})
+		ctx, cancel := ithelpers.GetContext(deviceToken)
+		defer cancel()
+		response, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Device,
+			EntityIds:      []string{"d1"},
+			Operation:      Add,
+			Labels:         map[string]string{"tier": "gold"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(response.Succeeded).To(gomega.Equal(int32(1)))
+	})
+
+	ginkgo.It("should reject unknown operations", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.BulkUpdate(ctx, &grpc_public_api_go.BulkLabelRequest{
+			OrganizationId: organizationID,
+			ResourceType:   Cluster,
+			EntityIds:      []string{"madrid"},
+			Operation:      "MERGE",
+			Labels:         map[string]string{"tier": "gold"},
+		})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+})
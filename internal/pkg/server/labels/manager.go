@@ -0,0 +1,262 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package labels
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/status"
+)
+
+// Operations that can be applied on the labels.
+const (
+	// Add the labels, overwriting the value of the existing keys.
+	Add = "ADD"
+	// Remove the label keys.
+	Remove = "REMOVE"
+	// Replace all the labels with the given ones.
+	Replace = "REPLACE"
+)
+
+// Manager structure with the targets of each resource type.
+type Manager struct {
+	targets map[string]target
+	// cache with the cluster lists and summaries, invalidated when the labels of clusters or nodes change.
+	cache *cache.Cache
+}
+
+// NewManager creates a Manager using a set of clients.
+func NewManager(clustClient grpc_infrastructure_go.ClustersClient,
+	nodeClient grpc_infrastructure_go.NodesClient,
+	infraClient grpc_infrastructure_manager_go.InfrastructureManagerClient,
+	deviceClient grpc_device_manager_go.DevicesClient,
+	invClient grpc_inventory_manager_go.InventoryClient,
+	eicClient grpc_inventory_manager_go.EICClient,
+	appClient grpc_application_manager_go.ApplicationManagerClient,
+	responseCache *cache.Cache) Manager {
+	return Manager{
+		targets: map[string]target{
+			Cluster:        &clusterTarget{clustClient: clustClient, infraClient: infraClient},
+			Node:           &nodeTarget{clustClient: clustClient, nodeClient: nodeClient},
+			Device:         &deviceTarget{deviceClient: deviceClient},
+			Asset:          &assetTarget{invClient: invClient},
+			EdgeController: &edgeControllerTarget{invClient: invClient, eicClient: eicClient},
+			Descriptor:     &descriptorTarget{appClient: appClient},
+		},
+		cache: responseCache,
+	}
+}
+
+// requestSelector returns the label selector of a request. The match labels are converted into a selector
+// requiring all of them.
+func requestSelector(request *grpc_public_api_go.BulkLabelRequest) (selector.Selector, derrors.Error) {
+	if request.Selector != "" {
+		return selector.Parse(request.Selector)
+	}
+	return selector.FromLabels(request.MatchLabels), nil
+}
+
+// changes computes the labels to be removed and added to apply an operation, and the resulting labels.
+func changes(current map[string]string, operation string, labels map[string]string) (map[string]string, map[string]string, map[string]string) {
+	toRemove := make(map[string]string, 0)
+	toAdd := make(map[string]string, 0)
+	result := make(map[string]string, len(current))
+	for key, value := range current {
+		result[key] = value
+	}
+	switch operation {
+	case Add:
+		for key, value := range labels {
+			if current[key] != value {
+				toAdd[key] = value
+				result[key] = value
+			}
+		}
+	case Remove:
+		for key := range labels {
+			if value, exists := current[key]; exists {
+				toRemove[key] = value
+				delete(result, key)
+			}
+		}
+	case Replace:
+		for key, value := range current {
+			if _, kept := labels[key]; !kept {
+				toRemove[key] = value
+				delete(result, key)
+			}
+		}
+		for key, value := range labels {
+			if current[key] != value {
+				toAdd[key] = value
+				result[key] = value
+			}
+		}
+	}
+	return toRemove, toAdd, result
+}
+
+// selectEntities returns the entities targeted by a request. The identifiers that do not exist are returned apart.
+func selectEntities(all []entity, request *grpc_public_api_go.BulkLabelRequest, sel selector.Selector) ([]entity, []string) {
+	selected := make([]entity, 0)
+	if len(request.EntityIds) == 0 {
+		for _, candidate := range all {
+			if sel.Matches(candidate.labels) {
+				selected = append(selected, candidate)
+			}
+		}
+		return selected, nil
+	}
+	byID := make(map[string]entity, len(all))
+	for _, candidate := range all {
+		byID[candidate.id] = candidate
+	}
+	missing := make([]string, 0)
+	seen := make(map[string]bool, len(request.EntityIds))
+	for _, entityID := range request.EntityIds {
+		if seen[entityID] {
+			continue
+		}
+		seen[entityID] = true
+		if found, exists := byID[entityID]; exists {
+			selected = append(selected, found)
+		} else {
+			missing = append(missing, entityID)
+		}
+	}
+	return selected, missing
+}
+
+// apply updates the labels of an entity. Labels are removed first so a replace does not lose the new values.
+func apply(ctx context.Context, toApply target, organizationID string, toUpdate entity, toRemove map[string]string, toAdd map[string]string) error {
+	if len(toRemove) > 0 {
+		if err := toApply.update(ctx, organizationID, toUpdate, false, toRemove); err != nil {
+			return err
+		}
+	}
+	if len(toAdd) > 0 {
+		if err := toApply.update(ctx, organizationID, toUpdate, true, toAdd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errorMessage extracts the message to be reported for a failed update.
+func errorMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return st.Message()
+	}
+	return err.Error()
+}
+
+// BulkUpdate adds, removes or replaces the labels of a set of entities of the same type. The result of each entity
+// is reported apart, so a failure does not stop the rest of updates. In dry run mode, the resulting labels are
+// computed but not applied.
+func (m *Manager) BulkUpdate(ctx context.Context, request *grpc_public_api_go.BulkLabelRequest) (*grpc_public_api_go.BulkLabelResponse, error) {
+	toApply, exists := m.targets[request.ResourceType]
+	if !exists {
+		return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("invalid resource type").WithParams(request.ResourceType))
+	}
+	sel, sErr := requestSelector(request)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	all, err := toApply.list(ctx, request.OrganizationId)
+	if err != nil {
+		return nil, err
+	}
+	selected, missing := selectEntities(all, request, sel)
+
+	results := make([]*grpc_public_api_go.BulkLabelResult, len(selected))
+	pending := make([]int, 0)
+	removals := make([]map[string]string, len(selected))
+	additions := make([]map[string]string, len(selected))
+	for index, toUpdate := range selected {
+		toRemove, toAdd, result := changes(toUpdate.labels, request.Operation, request.Labels)
+		changed := len(toRemove) > 0 || len(toAdd) > 0
+		results[index] = &grpc_public_api_go.BulkLabelResult{
+			EntityId: toUpdate.id,
+			Name:     toUpdate.name,
+			Success:  true,
+			Changed:  changed,
+			Labels:   result,
+		}
+		if changed && !request.DryRun {
+			pending = append(pending, index)
+			removals[index] = toRemove
+			additions[index] = toAdd
+		}
+	}
+
+	if len(pending) > 0 {
+		if request.ResourceType == Cluster || request.ResourceType == Node {
+			defer m.cache.Invalidate(request.OrganizationId)
+		}
+		errs := common.FanOut(ctx, len(pending), func(callCtx context.Context, i int) error {
+			index := pending[i]
+			return apply(callCtx, toApply, request.OrganizationId, selected[index], removals[index], additions[index])
+		})
+		for i, updateErr := range errs {
+			if updateErr != nil {
+				index := pending[i]
+				log.Warn().Str("resourceType", request.ResourceType).Str("entityID", selected[index].id).
+					Str("err", updateErr.Error()).Msg("cannot update labels")
+				results[index].Success = false
+				results[index].Error = errorMessage(updateErr)
+				results[index].Labels = selected[index].labels
+			}
+		}
+	}
+
+	for _, entityID := range missing {
+		results = append(results, &grpc_public_api_go.BulkLabelResult{
+			EntityId: entityID,
+			Success:  false,
+			Error:    "entity not found",
+		})
+	}
+
+	response := &grpc_public_api_go.BulkLabelResponse{
+		OrganizationId: request.OrganizationId,
+		ResourceType:   request.ResourceType,
+		Operation:      request.Operation,
+		DryRun:         request.DryRun,
+		Results:        results,
+	}
+	for _, result := range results {
+		if result.Success {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	return response, nil
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package labels
+
+import (
+	"context"
+	"github.com/nalej/grpc-application-go"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+)
+
+// Resource types whose labels can be updated in bulk.
+const (
+	Cluster        = "CLUSTER"
+	Node           = "NODE"
+	Device         = "DEVICE"
+	Asset          = "ASSET"
+	EdgeController = "EDGE_CONTROLLER"
+	Descriptor     = "DESCRIPTOR"
+)
+
+// entity with the labels of an element of any resource type.
+type entity struct {
+	id   string
+	name string
+	// parentID with the identifier of the cluster of a node, or the device group of a device.
+	parentID string
+	labels   map[string]string
+}
+
+// target with the upstream operations used to update the labels of a resource type.
+type target interface {
+	// list retrieves the entities of an organization.
+	list(ctx context.Context, organizationID string) ([]entity, error)
+	// update adds or removes a set of labels from an entity.
+	update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error
+}
+
+// clusterTarget updates the labels of the clusters.
+type clusterTarget struct {
+	clustClient grpc_infrastructure_go.ClustersClient
+	infraClient grpc_infrastructure_manager_go.InfrastructureManagerClient
+}
+
+func (t *clusterTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	list, err := t.clustClient.ListClusters(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0, len(list.Clusters))
+	for _, cluster := range list.Clusters {
+		result = append(result, entity{id: cluster.ClusterId, name: cluster.Name, labels: cluster.Labels})
+	}
+	return result, nil
+}
+
+func (t *clusterTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	_, err := t.infraClient.UpdateCluster(ctx, &grpc_infrastructure_go.UpdateClusterRequest{
+		OrganizationId: organizationID,
+		ClusterId:      toUpdate.id,
+		AddLabels:      add,
+		RemoveLabels:   !add,
+		Labels:         labels,
+	})
+	return err
+}
+
+// nodeTarget updates the labels of the nodes of all the clusters.
+type nodeTarget struct {
+	clustClient grpc_infrastructure_go.ClustersClient
+	nodeClient  grpc_infrastructure_go.NodesClient
+}
+
+func (t *nodeTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	clusters, err := t.clustClient.ListClusters(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0)
+	for _, cluster := range clusters.Clusters {
+		nodes, err := t.nodeClient.ListNodes(ctx, &grpc_infrastructure_go.ClusterId{
+			OrganizationId: organizationID,
+			ClusterId:      cluster.ClusterId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Nodes {
+			result = append(result, entity{id: node.NodeId, name: node.Ip, parentID: node.ClusterId, labels: node.Labels})
+		}
+	}
+	return result, nil
+}
+
+func (t *nodeTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	_, err := t.nodeClient.UpdateNode(ctx, &grpc_infrastructure_go.UpdateNodeRequest{
+		OrganizationId: organizationID,
+		NodeId:         toUpdate.id,
+		AddLabels:      add,
+		RemoveLabels:   !add,
+		Labels:         labels,
+	})
+	return err
+}
+
+// deviceTarget updates the labels of the devices of all the device groups.
+type deviceTarget struct {
+	deviceClient grpc_device_manager_go.DevicesClient
+}
+
+func (t *deviceTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	groups, err := t.deviceClient.ListDeviceGroups(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0)
+	for _, group := range groups.Groups {
+		devices, err := t.deviceClient.ListDevices(ctx, &grpc_device_go.DeviceGroupId{
+			OrganizationId: organizationID,
+			DeviceGroupId:  group.DeviceGroupId,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, device := range devices.Devices {
+			result = append(result, entity{id: device.DeviceId, name: group.Name, parentID: device.DeviceGroupId, labels: device.Labels})
+		}
+	}
+	return result, nil
+}
+
+func (t *deviceTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	request := &grpc_device_manager_go.DeviceLabelRequest{
+		OrganizationId: organizationID,
+		DeviceGroupId:  toUpdate.parentID,
+		DeviceId:       toUpdate.id,
+		Labels:         labels,
+	}
+	var err error
+	if add {
+		_, err = t.deviceClient.AddLabelToDevice(ctx, request)
+	} else {
+		_, err = t.deviceClient.RemoveLabelFromDevice(ctx, request)
+	}
+	return err
+}
+
+// assetTarget updates the labels of the inventory assets.
+type assetTarget struct {
+	invClient grpc_inventory_manager_go.InventoryClient
+}
+
+func (t *assetTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	inventory, err := t.invClient.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0, len(inventory.Assets))
+	for _, asset := range inventory.Assets {
+		result = append(result, entity{id: asset.AssetId, name: asset.EicNetIp, parentID: asset.EdgeControllerId, labels: asset.Labels})
+	}
+	return result, nil
+}
+
+func (t *assetTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	_, err := t.invClient.UpdateAsset(ctx, &grpc_inventory_go.UpdateAssetRequest{
+		OrganizationId: organizationID,
+		AssetId:        toUpdate.id,
+		AddLabels:      add,
+		RemoveLabels:   !add,
+		Labels:         labels,
+	})
+	return err
+}
+
+// edgeControllerTarget updates the labels of the edge controllers.
+type edgeControllerTarget struct {
+	invClient grpc_inventory_manager_go.InventoryClient
+	eicClient grpc_inventory_manager_go.EICClient
+}
+
+func (t *edgeControllerTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	inventory, err := t.invClient.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0, len(inventory.Controllers))
+	for _, controller := range inventory.Controllers {
+		result = append(result, entity{id: controller.EdgeControllerId, name: controller.Name, labels: controller.Labels})
+	}
+	return result, nil
+}
+
+func (t *edgeControllerTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	_, err := t.eicClient.UpdateEC(ctx, &grpc_inventory_go.UpdateEdgeControllerRequest{
+		OrganizationId:   organizationID,
+		EdgeControllerId: toUpdate.id,
+		AddLabels:        add,
+		RemoveLabels:     !add,
+		Labels:           labels,
+	})
+	return err
+}
+
+// descriptorTarget updates the labels of the application descriptors.
+type descriptorTarget struct {
+	appClient grpc_application_manager_go.ApplicationManagerClient
+}
+
+func (t *descriptorTarget) list(ctx context.Context, organizationID string) ([]entity, error) {
+	descriptors, err := t.appClient.ListAppDescriptors(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entity, 0, len(descriptors.Descriptors))
+	for _, descriptor := range descriptors.Descriptors {
+		result = append(result, entity{id: descriptor.AppDescriptorId, name: descriptor.Name, labels: descriptor.Labels})
+	}
+	return result, nil
+}
+
+func (t *descriptorTarget) update(ctx context.Context, organizationID string, toUpdate entity, add bool, labels map[string]string) error {
+	_, err := t.appClient.UpdateAppDescriptor(ctx, &grpc_application_go.UpdateAppDescriptorRequest{
+		OrganizationId:  organizationID,
+		AppDescriptorId: toUpdate.id,
+		AddLabels:       add,
+		RemoveLabels:    !add,
+		Labels:          labels,
+	})
+	return err
+}
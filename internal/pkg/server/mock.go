@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"fmt"
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-login-api-go"
+	"github.com/nalej/grpc-monitoring-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-provisioner-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"net"
+	"net/http"
+	"time"
+)
+
+// MockTokenExpiration with the validity of the tokens issued by the fake login.
+const MockTokenExpiration = time.Hour * 24
+
+// StartMock launches the fake upstream components seeded with the fixture data, and the fake login service.
+func (s *Service) StartMock() derrors.Error {
+	platform := fakes.NewPlatform()
+	if err := platform.Start(); err != nil {
+		return err
+	}
+	platform.Store.SeedFixtures()
+	s.mock = platform
+	s.mockLogin = fakes.NewLogin(platform.Store, s.Configuration.AuthSecret, MockTokenExpiration)
+	log.Info().Str("organizationID", fakes.FixtureOrganizationID).
+		Msg("Mock platform ready. Login as admin@nalej.com, operator@nalej.com or developer@nalej.com with any password")
+	go s.LaunchMockLogin()
+	return nil
+}
+
+// mockClients creates the clients of the upstream components on top of the fake platform.
+func (s *Service) mockClients() *Clients {
+	conn := s.mock.Conn()
+	return &Clients{
+		orgClient:         grpc_organization_manager_go.NewOrganizationsClient(conn),
+		clusClient:        grpc_infrastructure_go.NewClustersClient(conn),
+		nodeClient:        grpc_infrastructure_go.NewNodesClient(conn),
+		infraClient:       grpc_infrastructure_manager_go.NewInfrastructureManagerClient(conn),
+		umClient:          grpc_user_manager_go.NewUserManagerClient(conn),
+		appClient:         grpc_application_manager_go.NewApplicationManagerClient(conn),
+		deviceClient:      grpc_device_manager_go.NewDevicesClient(conn),
+		unifLoggClient:    grpc_application_manager_go.NewUnifiedLoggingClient(conn),
+		mmClient:          grpc_monitoring_go.NewMonitoringManagerClient(conn),
+		amClient:          grpc_monitoring_go.NewAssetMonitoringClient(conn),
+		eicClient:         grpc_inventory_manager_go.NewEICClient(conn),
+		invClient:         grpc_inventory_manager_go.NewInventoryClient(conn),
+		agentClient:       grpc_inventory_manager_go.NewAgentClient(conn),
+		appNetClient:      grpc_application_manager_go.NewApplicationNetworkClient(conn),
+		provisionerClient: grpc_provisioner_go.NewProvisionClient(conn),
+		logDownloadClient: grpc_log_download_manager_go.NewLogDownloadManagerClient(conn),
+	}
+}
+
+// allowRegisteredMethods adds a permission without requirements for each method registered in the server. The
+// authx interceptor still validates the token of those methods and fills in the request metadata.
+func allowRegisteredMethods(authConfig *interceptor.AuthorizationConfig, grpcServer *grpc.Server) {
+	for serviceName, info := range grpcServer.GetServiceInfo() {
+		for _, method := range info.Methods {
+			authConfig.Permissions[fmt.Sprintf("/%s/%s", serviceName, method.Name)] = interceptor.Permission{}
+		}
+	}
+}
+
+// withMockLogin serves the fake login on fakes.LoginPath next to the gateway.
+func (s *Service) withMockLogin(gateway http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(fakes.LoginPath, s.mockLogin)
+	mux.Handle("/", gateway)
+	return mux
+}
+
+// LaunchMockLogin serves the gRPC login API used by the CLI.
+func (s *Service) LaunchMockLogin() {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", s.Configuration.MockLoginPort))
+	if err != nil {
+		log.Fatal().Errs("failed to listen: %v", []error{err})
+	}
+	grpcServer := grpc.NewServer()
+	grpc_login_api_go.RegisterLoginServer(grpcServer, s.mockLogin)
+	log.Info().Int("port", s.Configuration.MockLoginPort).Msg("Launching mock login server")
+	if err := grpcServer.Serve(lis); err != nil {
+		log.Fatal().Errs("failed to serve: %v", []error{err})
+	}
+}
@@ -39,8 +39,8 @@ func NewHandler(manager Manager) *Handler {
 	}
 }
 
-func (h *Handler) GetClusterStats(context context.Context, request *grpc_monitoring_go.ClusterStatsRequest) (*grpc_monitoring_go.ClusterStats, error) {
-	rm, err := authhelper.GetRequestMetadata(context)
+func (h *Handler) GetClusterStats(ctx context.Context, request *grpc_monitoring_go.ClusterStatsRequest) (*grpc_monitoring_go.ClusterStats, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
@@ -55,8 +55,8 @@ func (h *Handler) GetClusterStats(context context.Context, request *grpc_monitor
 	return h.manager.GetClusterStats(request)
 }
 
-func (h *Handler) GetClusterSummary(context context.Context, request *grpc_monitoring_go.ClusterSummaryRequest) (*grpc_monitoring_go.ClusterSummary, error) {
-	rm, err := authhelper.GetRequestMetadata(context)
+func (h *Handler) GetClusterSummary(ctx context.Context, request *grpc_monitoring_go.ClusterSummaryRequest) (*grpc_monitoring_go.ClusterSummary, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
@@ -71,8 +71,8 @@ func (h *Handler) GetClusterSummary(context context.Context, request *grpc_monit
 	return h.manager.GetClusterSummary(request)
 }
 
-func (h *Handler) GetOrganizationApplicationStats(context context.Context, request *grpc_monitoring_go.OrganizationApplicationStatsRequest) (*grpc_monitoring_go.OrganizationApplicationStatsResponse, error) {
-	rm, err := authhelper.GetRequestMetadata(context)
+func (h *Handler) GetOrganizationApplicationStats(ctx context.Context, request *grpc_monitoring_go.OrganizationApplicationStatsRequest) (*grpc_monitoring_go.OrganizationApplicationStatsResponse, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
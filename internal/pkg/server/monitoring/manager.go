@@ -17,6 +17,7 @@
 package monitoring
 
 import (
+	"context"
 	"github.com/nalej/grpc-monitoring-go"
 	"github.com/nalej/public-api/internal/pkg/server/common"
 )
@@ -30,22 +31,22 @@ func NewManager(client grpc_monitoring_go.MonitoringManagerClient) Manager {
 	return Manager{client: client}
 }
 
-func (m *Manager) GetClusterStats(request *grpc_monitoring_go.ClusterStatsRequest) (*grpc_monitoring_go.ClusterStats, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetClusterStats(ctx context.Context, request *grpc_monitoring_go.ClusterStatsRequest) (*grpc_monitoring_go.ClusterStats, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.client.GetClusterStats(ctx, request)
 }
 
-func (m *Manager) GetClusterSummary(request *grpc_monitoring_go.ClusterSummaryRequest) (*grpc_monitoring_go.ClusterSummary, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetClusterSummary(ctx context.Context, request *grpc_monitoring_go.ClusterSummaryRequest) (*grpc_monitoring_go.ClusterSummary, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.client.GetClusterSummary(ctx, request)
 }
 
-func (m *Manager) GetOrganizationApplicationStats(request *grpc_monitoring_go.OrganizationApplicationStatsRequest) (*grpc_monitoring_go.OrganizationApplicationStatsResponse, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) GetOrganizationApplicationStats(ctx context.Context, request *grpc_monitoring_go.OrganizationApplicationStatsRequest) (*grpc_monitoring_go.OrganizationApplicationStatsResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.client.GetOrganizationApplicationStats(ctx, request)
@@ -49,7 +49,7 @@ func (h *Handler) List(ctx context.Context, clusterId *grpc_infrastructure_go.Cl
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.List(clusterId)
+	return h.Manager.List(ctx, clusterId)
 }
 
 // UpdateNode allows the user to update the information of a node.
@@ -65,5 +65,5 @@ func (h *Handler) UpdateNode(ctx context.Context, request *grpc_public_api_go.Up
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.UpdateNode(request)
+	return h.Manager.UpdateNode(ctx, request)
 }
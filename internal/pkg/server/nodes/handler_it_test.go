@@ -30,6 +30,7 @@ import (
 	"github.com/nalej/grpc-organization-manager-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
 	"github.com/nalej/public-api/internal/pkg/utils"
 	"github.com/onsi/ginkgo"
@@ -92,7 +93,7 @@ var _ = ginkgo.Describe("Nodes", func() {
 		conn, err := test.GetConn(*listener)
 		gomega.Expect(err).To(gomega.Succeed())
 
-		manager := NewManager(nodeClient)
+		manager := NewManager(nodeClient, cache.NewCache("nodes", 0))
 		handler := NewHandler(manager)
 		grpc_public_api_go.RegisterNodesServer(server, handler)
 		test.LaunchServer(server, listener)
@@ -17,27 +17,39 @@
 package nodes
 
 import (
+	"context"
 	"github.com/nalej/grpc-infrastructure-go"
 	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/selector"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/nalej/public-api/internal/pkg/server/decorators"
 )
 
 // Manager structure with the required clients for node operations.
 type Manager struct {
 	nodeClient grpc_infrastructure_go.NodesClient
+	// cache with the cluster lists and summaries, invalidated when a node is updated.
+	cache *cache.Cache
 }
 
 // NewManager creates a Manager using a set of clients.
-func NewManager(nodeClient grpc_infrastructure_go.NodesClient) Manager {
+func NewManager(nodeClient grpc_infrastructure_go.NodesClient, responseCache *cache.Cache) Manager {
 	return Manager{
 		nodeClient: nodeClient,
+		cache:      responseCache,
 	}
 }
 
-// List retrieves information about the nodes of a cluster.
-func (m *Manager) List(clusterId *grpc_infrastructure_go.ClusterId) (*grpc_public_api_go.NodeList, error) {
-	ctx, cancel := common.GetContext()
+// List retrieves information about the nodes of a cluster. The nodes are filtered by the label selector of the request.
+func (m *Manager) List(ctx context.Context, clusterId *grpc_infrastructure_go.ClusterId) (*grpc_public_api_go.NodeList, error) {
+	sel, sErr := selector.FromIncomingContext(ctx)
+	if sErr != nil {
+		return nil, conversions.ToGRPCError(sErr)
+	}
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	nodes, err := m.nodeClient.ListNodes(ctx, clusterId)
 	if err != nil {
@@ -47,13 +59,21 @@ func (m *Manager) List(clusterId *grpc_infrastructure_go.ClusterId) (*grpc_publi
 	for _, n := range nodes.Nodes {
 		result = append(result, entities.ToPublicAPINode(n))
 	}
+	if !sel.Empty() {
+		filtered := decorators.ApplyDecorator(result, decorators.NewSelectorDecorator(sel))
+		if filtered.Error != nil {
+			return nil, conversions.ToGRPCError(filtered.Error)
+		}
+		result = filtered.NodeList
+	}
 	return &grpc_public_api_go.NodeList{
 		Nodes: result,
 	}, nil
 }
 
 // UpdateNode allows the user to update the information of a node.
-func (m *Manager) UpdateNode(request *grpc_public_api_go.UpdateNodeRequest) (*grpc_public_api_go.Node, error) {
+func (m *Manager) UpdateNode(ctx context.Context, request *grpc_public_api_go.UpdateNodeRequest) (*grpc_public_api_go.Node, error) {
+	defer m.cache.Invalidate(request.OrganizationId)
 	updateRequest := &grpc_infrastructure_go.UpdateNodeRequest{
 		OrganizationId: request.OrganizationId,
 		NodeId:         request.NodeId,
@@ -61,7 +81,7 @@ func (m *Manager) UpdateNode(request *grpc_public_api_go.UpdateNodeRequest) (*gr
 		RemoveLabels:   request.RemoveLabels,
 		Labels:         request.Labels,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	updated, err := m.nodeClient.UpdateNode(ctx, updateRequest)
 	if err != nil {
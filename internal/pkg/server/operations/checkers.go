@@ -0,0 +1,159 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-provisioner-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// clusterRemoved is the state reported once the cluster of an uninstall or decommission no longer exists.
+const clusterRemoved = "REMOVED_SUCCESS"
+
+// NewClusterChecker checks the cluster operations using the state of the target cluster. The removal operations
+// finish once the cluster no longer exists.
+func NewClusterChecker(client grpc_infrastructure_go.ClustersClient, removal bool) Checker {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		ctx, cancel := common.GetContext(ctx)
+		defer cancel()
+		cluster, err := client.GetCluster(ctx, &grpc_infrastructure_go.ClusterId{
+			OrganizationId: operation.OrganizationId,
+			ClusterId:      operation.TargetId,
+		})
+		if err != nil {
+			if removal && status.Code(err) == codes.NotFound {
+				return clusterRemoved, "", nil
+			}
+			return "", "", conversions.ToDerror(err)
+		}
+		state := cluster.State.String()
+		// The cluster keeps its installed state until the removal starts.
+		if removal && StatusFromName(state) == StatusSuccess && state != "UNINSTALLED" {
+			return StatusInProgress, "", nil
+		}
+		return state, "", nil
+	}
+}
+
+// NewProvisionChecker checks the provisioning and scaling operations with the provisioner.
+func NewProvisionChecker(client grpc_provisioner_go.ProvisionClient) Checker {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		ctx, cancel := common.GetContext(ctx)
+		defer cancel()
+		progress, err := client.CheckProgress(ctx, &grpc_common_go.RequestId{RequestId: operation.OperationId})
+		if err != nil {
+			return "", "", conversions.ToDerror(err)
+		}
+		return progress.State.String(), progress.Error, nil
+	}
+}
+
+// NewProvisionCanceller removes a pending provisioning request from the provisioner.
+func NewProvisionCanceller(client grpc_provisioner_go.ProvisionClient) Canceller {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) derrors.Error {
+		ctx, cancel := common.GetContext(ctx)
+		defer cancel()
+		if _, err := client.RemoveProvision(ctx, &grpc_common_go.RequestId{RequestId: operation.OperationId}); err != nil {
+			return conversions.ToDerror(err)
+		}
+		return nil
+	}
+}
+
+// NewControllerOpChecker checks the agent installations and uninstallations with the last operation reported by
+// the edge controller that runs them. The operation stays in progress until the controller reports it.
+func NewControllerOpChecker(client grpc_inventory_manager_go.InventoryClient) Checker {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		ctx, cancel := common.GetContext(ctx)
+		defer cancel()
+		info, err := client.GetControllerExtendedInfo(ctx, &grpc_inventory_go.EdgeControllerId{
+			OrganizationId:   operation.OrganizationId,
+			EdgeControllerId: operation.TargetId,
+		})
+		if err != nil {
+			return "", "", conversions.ToDerror(err)
+		}
+		if info.Controller == nil || info.Controller.LastOpResult == nil ||
+			info.Controller.LastOpResult.OperationId != operation.OperationId {
+			return StatusInProgress, "", nil
+		}
+		return info.Controller.LastOpResult.Status.String(), info.Controller.LastOpResult.Info, nil
+	}
+}
+
+// NewAssetOpChecker checks the monitoring activations with the last operation reported by the agent of the
+// target asset. The operation stays in progress until the agent reports it.
+func NewAssetOpChecker(client grpc_inventory_manager_go.InventoryClient) Checker {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		ctx, cancel := common.GetContext(ctx)
+		defer cancel()
+		asset, err := client.GetAssetInfo(ctx, &grpc_inventory_go.AssetId{
+			OrganizationId: operation.OrganizationId,
+			AssetId:        operation.TargetId,
+		})
+		if err != nil {
+			return "", "", conversions.ToDerror(err)
+		}
+		if asset.LastOpSummary == nil || asset.LastOpSummary.OperationId != operation.OperationId {
+			return StatusInProgress, "", nil
+		}
+		return asset.LastOpSummary.Status.String(), asset.LastOpSummary.Info, nil
+	}
+}
+
+// NewLogDownloadChecker checks the log downloads with the log-download manager on behalf of their requester.
+func NewLogDownloadChecker(client grpc_log_download_manager_go.LogDownloadManagerClient) Checker {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		ctx, cancel := common.GetContextWithUser(ctx, operation.RequestedBy)
+		defer cancel()
+		response, err := client.Check(ctx, &grpc_log_download_manager_go.DownloadRequestId{
+			OrganizationId: operation.OrganizationId,
+			RequestId:      operation.OperationId,
+		})
+		if err != nil {
+			return "", "", conversions.ToDerror(err)
+		}
+		return response.State.String(), response.Info, nil
+	}
+}
+
+// NewLogDownloadCanceller stops a log download in progress on behalf of its requester.
+func NewLogDownloadCanceller(client grpc_log_download_manager_go.LogDownloadManagerClient) Canceller {
+	return func(ctx context.Context, operation *grpc_public_api_go.Operation) derrors.Error {
+		ctx, cancel := common.GetContextWithUser(ctx, operation.RequestedBy)
+		defer cancel()
+		_, err := client.Cancel(ctx, &grpc_log_download_manager_go.DownloadRequestId{
+			OrganizationId: operation.OrganizationId,
+			RequestId:      operation.OperationId,
+		})
+		if err != nil {
+			return conversions.ToDerror(err)
+		}
+		return nil
+	}
+}
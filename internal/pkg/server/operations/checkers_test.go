@@ -0,0 +1,100 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+)
+
+// inventoryClient returns the last operation of a controller and an asset.
+type inventoryClient struct {
+	grpc_inventory_manager_go.InventoryClient
+	controllerOp *grpc_inventory_go.ECOpSummary
+	assetOp      *grpc_inventory_go.AgentOpSummary
+}
+
+func (ic *inventoryClient) GetControllerExtendedInfo(_ context.Context, in *grpc_inventory_go.EdgeControllerId, _ ...grpc.CallOption) (*grpc_inventory_manager_go.EdgeControllerExtendedInfo, error) {
+	return &grpc_inventory_manager_go.EdgeControllerExtendedInfo{
+		Controller: &grpc_inventory_manager_go.EdgeController{
+			OrganizationId:   in.OrganizationId,
+			EdgeControllerId: in.EdgeControllerId,
+			LastOpResult:     ic.controllerOp,
+		},
+	}, nil
+}
+
+func (ic *inventoryClient) GetAssetInfo(_ context.Context, in *grpc_inventory_go.AssetId, _ ...grpc.CallOption) (*grpc_inventory_manager_go.Asset, error) {
+	return &grpc_inventory_manager_go.Asset{
+		OrganizationId: in.OrganizationId,
+		AssetId:        in.AssetId,
+		LastOpSummary:  ic.assetOp,
+	}, nil
+}
+
+var _ = ginkgo.Describe("Operation checkers", func() {
+
+	var client *inventoryClient
+	var operation *grpc_public_api_go.Operation
+
+	ginkgo.BeforeEach(func() {
+		client = &inventoryClient{}
+		operation = &grpc_public_api_go.Operation{
+			OrganizationId: "org",
+			OperationId:    "op1",
+			TargetId:       "target",
+			Status:         StatusInProgress,
+		}
+	})
+
+	ginkgo.It("should follow the agent operations with the last operation of the controller", func() {
+		checker := NewControllerOpChecker(client)
+		state, _, err := checker(context.Background(), operation)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(state).To(gomega.Equal(StatusInProgress))
+
+		client.controllerOp = &grpc_inventory_go.ECOpSummary{OperationId: "other", Info: "other operation"}
+		state, info, err := checker(context.Background(), operation)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(state).To(gomega.Equal(StatusInProgress))
+		gomega.Expect(info).To(gomega.BeEmpty())
+
+		client.controllerOp = &grpc_inventory_go.ECOpSummary{OperationId: "op1", Info: "done"}
+		state, info, err = checker(context.Background(), operation)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(state).To(gomega.Equal(client.controllerOp.Status.String()))
+		gomega.Expect(info).To(gomega.Equal("done"))
+	})
+
+	ginkgo.It("should follow the monitoring activations with the last operation of the asset", func() {
+		checker := NewAssetOpChecker(client)
+		state, _, err := checker(context.Background(), operation)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(state).To(gomega.Equal(StatusInProgress))
+
+		client.assetOp = &grpc_inventory_go.AgentOpSummary{OperationId: "op1", Info: "activated"}
+		state, info, err := checker(context.Background(), operation)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(state).To(gomega.Equal(client.assetOp.Status.String()))
+		gomega.Expect(info).To(gomega.Equal("activated"))
+	})
+})
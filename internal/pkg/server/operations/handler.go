@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/nalej/public-api/internal/pkg/entities"
+)
+
+// Handler structure for the operations requests.
+type Handler struct {
+	Manager Manager
+}
+
+// NewHandler creates a new Handler with a linked manager.
+func NewHandler(manager Manager) *Handler {
+	return &Handler{manager}
+}
+
+// validStatus checks the status used to filter the operations.
+func validStatus(status string) derrors.Error {
+	switch status {
+	case "", StatusInProgress, StatusSuccess, StatusFailed, StatusCancelled:
+		return nil
+	}
+	return derrors.NewInvalidArgumentError("invalid operation status").WithParams(status)
+}
+
+// ListOperations retrieves the asynchronous operations of an organization.
+func (h *Handler) ListOperations(ctx context.Context, request *grpc_public_api_go.ListOperationsRequest) (*grpc_public_api_go.OperationList, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidListOperationsRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	err = validStatus(request.Status)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.List(ctx, request)
+}
+
+// GetOperation retrieves an operation with its current status.
+func (h *Handler) GetOperation(ctx context.Context, operationID *grpc_public_api_go.OperationId) (*grpc_public_api_go.Operation, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if operationID.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidOperationId(operationID)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.Get(ctx, operationID)
+}
+
+// WaitOperation blocks until an operation finishes or the timeout expires.
+func (h *Handler) WaitOperation(ctx context.Context, request *grpc_public_api_go.WaitOperationRequest) (*grpc_public_api_go.Operation, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidWaitOperationRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.Wait(ctx, request)
+}
+
+// CancelOperation stops an operation in progress if the upstream component supports it.
+func (h *Handler) CancelOperation(ctx context.Context, operationID *grpc_public_api_go.OperationId) (*grpc_public_api_go.Operation, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if operationID.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidOperationId(operationID)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.Cancel(ctx, operationID)
+}
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"google.golang.org/grpc"
+)
+
+// recorder builds the operation described by the response of an asynchronous method. It returns nil if the
+// response does not identify an operation.
+type recorder func(req interface{}, resp interface{}) *grpc_public_api_go.Operation
+
+// fromOpResponse records the operations answered with an OpResponse.
+func fromOpResponse(operationType string, target func(req interface{}) string) recorder {
+	return func(req interface{}, resp interface{}) *grpc_public_api_go.Operation {
+		response, ok := resp.(*grpc_public_api_go.OpResponse)
+		if !ok || response.RequestId == "" {
+			return nil
+		}
+		info := response.Info
+		if response.Error != "" {
+			info = response.Error
+		}
+		return &grpc_public_api_go.Operation{
+			OperationId: response.RequestId,
+			Type:        operationType,
+			TargetId:    target(req),
+			Status:      StatusFromName(response.StatusName),
+			Info:        info,
+		}
+	}
+}
+
+// fromProvisionerResponse records the operations answered with a ProvisionerResponse.
+func fromProvisionerResponse(operationType string) recorder {
+	return func(_ interface{}, resp interface{}) *grpc_public_api_go.Operation {
+		response, ok := resp.(*grpc_infrastructure_manager_go.ProvisionerResponse)
+		if !ok || response.RequestId == "" {
+			return nil
+		}
+		return &grpc_public_api_go.Operation{
+			OperationId: response.RequestId,
+			Type:        operationType,
+			TargetId:    response.ClusterId,
+			Status:      StatusFromName(response.State.String()),
+			Info:        response.Error,
+		}
+	}
+}
+
+// fromECOpResponse records the operations answered with an ECOpResponse.
+func fromECOpResponse(operationType string) recorder {
+	return func(_ interface{}, resp interface{}) *grpc_public_api_go.Operation {
+		response, ok := resp.(*grpc_public_api_go.ECOpResponse)
+		if !ok || response.OperationId == "" {
+			return nil
+		}
+		return &grpc_public_api_go.Operation{
+			OperationId: response.OperationId,
+			Type:        operationType,
+			TargetId:    response.EdgeControllerId,
+			Status:      StatusFromName(response.Status),
+			Info:        response.Info,
+		}
+	}
+}
+
+// fromAgentOpResponse records the operations answered with an AgentOpResponse.
+func fromAgentOpResponse(operationType string) recorder {
+	return func(_ interface{}, resp interface{}) *grpc_public_api_go.Operation {
+		response, ok := resp.(*grpc_public_api_go.AgentOpResponse)
+		if !ok || response.OperationId == "" {
+			return nil
+		}
+		return &grpc_public_api_go.Operation{
+			OperationId: response.OperationId,
+			Type:        operationType,
+			TargetId:    response.AssetId,
+			Status:      StatusFromName(response.Status),
+			Info:        response.Info,
+		}
+	}
+}
+
+// fromDownloadLogResponse records the operations answered with a DownloadLogResponse.
+func fromDownloadLogResponse(operationType string) recorder {
+	return func(_ interface{}, resp interface{}) *grpc_public_api_go.Operation {
+		response, ok := resp.(*grpc_public_api_go.DownloadLogResponse)
+		if !ok || response.RequestId == "" {
+			return nil
+		}
+		return &grpc_public_api_go.Operation{
+			OperationId: response.RequestId,
+			Type:        operationType,
+			Status:      StatusFromName(response.StateName),
+			Info:        response.Info,
+		}
+	}
+}
+
+// recorders contains the asynchronous methods of the public API indexed by their full name.
+var recorders = map[string]recorder{
+	"/public_api.Clusters/Install": fromOpResponse(ClusterInstall, func(req interface{}) string {
+		return req.(*grpc_public_api_go.InstallRequest).ClusterId
+	}),
+	"/public_api.Clusters/ProvisionAndInstall": fromProvisionerResponse(ClusterProvision),
+	"/public_api.Clusters/Scale":               fromProvisionerResponse(ClusterScale),
+	"/public_api.Clusters/Uninstall": fromOpResponse(ClusterUninstall, func(req interface{}) string {
+		return req.(*grpc_public_api_go.UninstallClusterRequest).ClusterId
+	}),
+	"/public_api.Clusters/Decommission": fromOpResponse(ClusterDecommission, func(req interface{}) string {
+		return req.(*grpc_public_api_go.DecommissionClusterRequest).ClusterId
+	}),
+	"/public_api.EdgeControllers/InstallAgent": fromECOpResponse(AgentInstall),
+	"/public_api.Agent/UninstallAgent":         fromECOpResponse(AgentUninstall),
+	"/public_api.Agent/ActivateMonitoring":     fromAgentOpResponse(MonitoringActivation),
+	"/public_api.UnifiedLogging/DownloadLog":   fromDownloadLogResponse(LogDownload),
+}
+
+// UnaryServerInterceptor records the operations started by the asynchronous methods once they succeed. It must
+// run after the authx interceptor so the requester is known.
+func UnaryServerInterceptor(registry *Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		record, async := recorders[info.FullMethod]
+		if err != nil || !async {
+			return resp, err
+		}
+		rm, mErr := authhelper.GetRequestMetadata(ctx)
+		if mErr != nil {
+			return resp, err
+		}
+		if operation := record(req, resp); operation != nil {
+			operation.OrganizationId = rm.OrganizationID
+			operation.RequestedBy = rm.UserID
+			registry.Record(operation)
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var _ = ginkgo.Describe("Operations interceptor", func() {
+
+	const organizationID = "org"
+	const userID = "user@nalej.com"
+
+	var registry *Registry
+	var interceptor grpc.UnaryServerInterceptor
+	var ctx context.Context
+
+	call := func(method string, resp interface{}, err error) {
+		_, _ = interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method},
+			func(context.Context, interface{}) (interface{}, error) {
+				return resp, err
+			})
+	}
+
+	ginkgo.BeforeEach(func() {
+		registry = NewRegistry(time.Hour, time.Hour, time.Millisecond)
+		interceptor = UnaryServerInterceptor(registry)
+		ctx = metadata.NewIncomingContext(context.Background(), metadata.New(map[string]string{
+			authhelper.UserIdField:         userID,
+			authhelper.OrganizationIdField: organizationID,
+		}))
+	})
+
+	ginkgo.It("should record the asynchronous operations", func() {
+		call("/public_api.UnifiedLogging/DownloadLog", &grpc_public_api_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			RequestId:      "request",
+			StateName:      "ON_GOING",
+		}, nil)
+		operations := registry.List(organizationID, "", "")
+		gomega.Expect(operations).To(gomega.HaveLen(1))
+		gomega.Expect(operations[0].OperationId).To(gomega.Equal("request"))
+		gomega.Expect(operations[0].Type).To(gomega.Equal(LogDownload))
+		gomega.Expect(operations[0].RequestedBy).To(gomega.Equal(userID))
+		gomega.Expect(operations[0].Status).To(gomega.Equal(StatusInProgress))
+	})
+
+	ginkgo.It("should ignore failed requests and other methods", func() {
+		call("/public_api.UnifiedLogging/DownloadLog", nil, status.Error(codes.Unavailable, "unavailable"))
+		call("/public_api.UnifiedLogging/Check", &grpc_public_api_go.DownloadLogResponse{RequestId: "request"}, nil)
+		gomega.Expect(registry.List(organizationID, "", "")).To(gomega.BeEmpty())
+	})
+})
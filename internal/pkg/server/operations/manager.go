@@ -0,0 +1,69 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"time"
+)
+
+// Manager structure with the registry of operations.
+type Manager struct {
+	registry *Registry
+}
+
+// NewManager creates a Manager on top of a registry.
+func NewManager(registry *Registry) Manager {
+	return Manager{registry}
+}
+
+// List the operations of an organization.
+func (m *Manager) List(_ context.Context, request *grpc_public_api_go.ListOperationsRequest) (*grpc_public_api_go.OperationList, error) {
+	return &grpc_public_api_go.OperationList{
+		Operations: m.registry.List(request.OrganizationId, request.Type, request.Status),
+	}, nil
+}
+
+// Get an operation refreshing its status.
+func (m *Manager) Get(ctx context.Context, operationID *grpc_public_api_go.OperationId) (*grpc_public_api_go.Operation, error) {
+	operation, err := m.registry.Get(ctx, operationID.OrganizationId, operationID.OperationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return operation, nil
+}
+
+// Wait for an operation to finish.
+func (m *Manager) Wait(ctx context.Context, request *grpc_public_api_go.WaitOperationRequest) (*grpc_public_api_go.Operation, error) {
+	timeout := time.Duration(request.TimeoutSeconds) * time.Second
+	operation, err := m.registry.Wait(ctx, request.OrganizationId, request.OperationId, timeout)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return operation, nil
+}
+
+// Cancel an operation in progress.
+func (m *Manager) Cancel(ctx context.Context, operationID *grpc_public_api_go.OperationId) (*grpc_public_api_go.Operation, error) {
+	operation, err := m.registry.Cancel(ctx, operationID.OrganizationId, operationID.OperationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return operation, nil
+}
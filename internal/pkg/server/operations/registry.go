@@ -0,0 +1,330 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package operations keeps track of the asynchronous operations requested through the public API. The
+// upstream components answer those requests with different responses, so the registry records them in a
+// single shape and refreshes their status on demand.
+package operations
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the operations recorded by the registry.
+const (
+	ClusterInstall       = "CLUSTER_INSTALL"
+	ClusterProvision     = "CLUSTER_PROVISION"
+	ClusterScale         = "CLUSTER_SCALE"
+	ClusterUninstall     = "CLUSTER_UNINSTALL"
+	ClusterDecommission  = "CLUSTER_DECOMMISSION"
+	AgentInstall         = "AGENT_INSTALL"
+	AgentUninstall       = "AGENT_UNINSTALL"
+	MonitoringActivation = "MONITORING_ACTIVATION"
+	LogDownload          = "LOG_DOWNLOAD"
+)
+
+// Status of the operations.
+const (
+	StatusInProgress = "IN_PROGRESS"
+	StatusSuccess    = "SUCCESS"
+	StatusFailed     = "FAILED"
+	StatusCancelled  = "CANCELLED"
+)
+
+// DefaultRetention with the time a finished operation is kept.
+const DefaultRetention = time.Hour * 24
+
+// DefaultMaxAge with the time after which an operation that has not reached a final status is considered failed.
+const DefaultMaxAge = time.Hour * 24
+
+// expiredInfo with the information of the operations that expire before reaching a final status.
+const expiredInfo = "the operation did not reach a final status in time"
+
+// DefaultPollInterval with the time between status checks while waiting for an operation.
+const DefaultPollInterval = time.Second * 2
+
+// MaxWaitTimeout with the maximum time a client can wait for an operation in a single call.
+const MaxWaitTimeout = time.Minute * 5
+
+// upstreamStatus maps the exact names of the states reported by the upstream components to the status of an
+// operation. The names come from the common and inventory operation status, the cluster state, the provisioning
+// progress and the log download state.
+var upstreamStatus = map[string]string{
+	// Operation status of the common and inventory responses.
+	"SCHEDULED":  StatusInProgress,
+	"INPROGRESS": StatusInProgress,
+	"SUCCESS":    StatusSuccess,
+	"FAIL":       StatusFailed,
+	"FAILED":     StatusFailed,
+	"CANCELED":   StatusCancelled,
+	// Cluster state.
+	"PROVISIONING":          StatusInProgress,
+	"PROVISIONED":           StatusSuccess,
+	"INSTALL_IN_PROGRESS":   StatusInProgress,
+	"INSTALLED":             StatusSuccess,
+	"SCALING":               StatusInProgress,
+	"UNINSTALLING":          StatusInProgress,
+	"UNINSTALL_IN_PROGRESS": StatusInProgress,
+	"UNINSTALLED":           StatusSuccess,
+	"DECOMMISSIONING":       StatusInProgress,
+	"FAILURE":               StatusFailed,
+	"UNKNOWN":               StatusInProgress,
+	clusterRemoved:          StatusSuccess,
+	// Provisioning progress.
+	"INIT":        StatusInProgress,
+	"REGISTERING": StatusInProgress,
+	"IN_PROGRESS": StatusInProgress,
+	"FINISHED":    StatusSuccess,
+	"ERROR":       StatusFailed,
+	// Log download state. An expired download no longer offers its file.
+	"PENDING":    StatusInProgress,
+	"GENERATING": StatusInProgress,
+	"READY":      StatusSuccess,
+	"EXPIRED":    StatusFailed,
+	"CANCELLED":  StatusCancelled,
+}
+
+// StatusFromName translates the state reported by an upstream component into the status of an operation. Each
+// component uses its own enumeration, so the translation relies on the exact name of the state. Unknown states
+// keep the operation in progress.
+func StatusFromName(name string) string {
+	if status, exists := upstreamStatus[strings.ToUpper(name)]; exists {
+		return status
+	}
+	return StatusInProgress
+}
+
+// Finished checks whether an operation has reached a final status.
+func Finished(operation *grpc_public_api_go.Operation) bool {
+	return operation.Status != StatusInProgress
+}
+
+// Checker retrieves the state of an operation from the upstream component. It returns the name of the state,
+// translated with StatusFromName, and any additional information.
+type Checker func(ctx context.Context, operation *grpc_public_api_go.Operation) (string, string, derrors.Error)
+
+// Canceller asks the upstream component to stop an operation.
+type Canceller func(ctx context.Context, operation *grpc_public_api_go.Operation) derrors.Error
+
+// Registry contains the operations of all the organizations.
+type Registry struct {
+	sync.Mutex
+	operations map[string]*grpc_public_api_go.Operation
+	checkers   map[string]Checker
+	cancellers map[string]Canceller
+	// retention with the time a finished operation is kept.
+	retention time.Duration
+	// maxAge with the time after which an operation in progress expires.
+	maxAge time.Duration
+	// pollInterval with the time between status checks while waiting for an operation.
+	pollInterval time.Duration
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry(retention time.Duration, maxAge time.Duration, pollInterval time.Duration) *Registry {
+	return &Registry{
+		operations:   make(map[string]*grpc_public_api_go.Operation, 0),
+		checkers:     make(map[string]Checker, 0),
+		cancellers:   make(map[string]Canceller, 0),
+		retention:    retention,
+		maxAge:       maxAge,
+		pollInterval: pollInterval,
+	}
+}
+
+// operationKey returns the key of an operation in the registry.
+func operationKey(organizationID string, operationID string) string {
+	return organizationID + "/" + operationID
+}
+
+// RegisterChecker sets the checker used to refresh the operations of a given type.
+func (r *Registry) RegisterChecker(operationType string, checker Checker) {
+	r.Lock()
+	defer r.Unlock()
+	r.checkers[operationType] = checker
+}
+
+// RegisterCanceller sets the canceller of the operations of a given type. The operations without a canceller
+// cannot be cancelled.
+func (r *Registry) RegisterCanceller(operationType string, canceller Canceller) {
+	r.Lock()
+	defer r.Unlock()
+	r.cancellers[operationType] = canceller
+}
+
+// purge marks as failed the operations in progress older than the maximum age, so the operations whose upstream
+// never reports a final status finish, and removes the finished operations older than the retention time. The
+// registry must be locked by the caller.
+func (r *Registry) purge(now time.Time) {
+	expired := now.Add(-r.maxAge).Unix()
+	limit := now.Add(-r.retention).Unix()
+	for key, operation := range r.operations {
+		if !Finished(operation) && operation.Created < expired {
+			operation.Status = StatusFailed
+			operation.Info = expiredInfo
+			operation.Updated = now.Unix()
+		}
+		if Finished(operation) && operation.Updated < limit {
+			delete(r.operations, key)
+		}
+	}
+}
+
+// Record adds a new operation. The creation time is set by the registry.
+func (r *Registry) Record(operation *grpc_public_api_go.Operation) {
+	r.Lock()
+	defer r.Unlock()
+	now := time.Now()
+	r.purge(now)
+	toAdd := proto.Clone(operation).(*grpc_public_api_go.Operation)
+	toAdd.Created = now.Unix()
+	toAdd.Updated = toAdd.Created
+	r.operations[operationKey(toAdd.OrganizationId, toAdd.OperationId)] = toAdd
+	log.Debug().Str("organizationID", toAdd.OrganizationId).Str("operationID", toAdd.OperationId).
+		Str("type", toAdd.Type).Str("status", toAdd.Status).Msg("operation recorded")
+}
+
+// List returns the operations of an organization, optionally filtered by type and status, from the newest to
+// the oldest.
+func (r *Registry) List(organizationID string, operationType string, status string) []*grpc_public_api_go.Operation {
+	r.Lock()
+	defer r.Unlock()
+	r.purge(time.Now())
+	result := make([]*grpc_public_api_go.Operation, 0)
+	for _, operation := range r.operations {
+		if operation.OrganizationId != organizationID {
+			continue
+		}
+		if operationType != "" && operation.Type != operationType {
+			continue
+		}
+		if status != "" && operation.Status != status {
+			continue
+		}
+		result = append(result, proto.Clone(operation).(*grpc_public_api_go.Operation))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Created != result[j].Created {
+			return result[i].Created > result[j].Created
+		}
+		return result[i].OperationId < result[j].OperationId
+	})
+	return result
+}
+
+// find returns a copy of an operation and the checker of its type.
+func (r *Registry) find(organizationID string, operationID string) (*grpc_public_api_go.Operation, Checker, derrors.Error) {
+	r.Lock()
+	defer r.Unlock()
+	r.purge(time.Now())
+	operation, exists := r.operations[operationKey(organizationID, operationID)]
+	if !exists {
+		return nil, nil, derrors.NewNotFoundError("operation not found").WithParams(organizationID, operationID)
+	}
+	return proto.Clone(operation).(*grpc_public_api_go.Operation), r.checkers[operation.Type], nil
+}
+
+// update stores the new status of an operation unless it already finished, and returns the stored operation.
+func (r *Registry) update(organizationID string, operationID string, status string, info string) *grpc_public_api_go.Operation {
+	r.Lock()
+	defer r.Unlock()
+	operation, exists := r.operations[operationKey(organizationID, operationID)]
+	if !exists {
+		return nil
+	}
+	if !Finished(operation) {
+		operation.Status = status
+		if info != "" {
+			operation.Info = info
+		}
+		operation.Updated = time.Now().Unix()
+	}
+	return proto.Clone(operation).(*grpc_public_api_go.Operation)
+}
+
+// Get returns an operation. The status of the operations in progress is refreshed from the upstream component
+// if a checker is available.
+func (r *Registry) Get(ctx context.Context, organizationID string, operationID string) (*grpc_public_api_go.Operation, derrors.Error) {
+	operation, checker, err := r.find(organizationID, operationID)
+	if err != nil {
+		return nil, err
+	}
+	if Finished(operation) || checker == nil {
+		return operation, nil
+	}
+	state, info, cErr := checker(ctx, operation)
+	if cErr != nil {
+		log.Warn().Str("operationID", operationID).Str("type", operation.Type).Str("err", cErr.DebugReport()).
+			Msg("cannot check the operation, returning the last known status")
+		return operation, nil
+	}
+	if updated := r.update(organizationID, operationID, StatusFromName(state), info); updated != nil {
+		return updated, nil
+	}
+	return operation, nil
+}
+
+// Wait returns an operation once it finishes or the timeout expires. The operation is returned in its last
+// known status if the timeout expires first.
+func (r *Registry) Wait(ctx context.Context, organizationID string, operationID string, timeout time.Duration) (*grpc_public_api_go.Operation, derrors.Error) {
+	if timeout <= 0 || timeout > MaxWaitTimeout {
+		timeout = MaxWaitTimeout
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for {
+		operation, err := r.Get(ctx, organizationID, operationID)
+		if err != nil || Finished(operation) {
+			return operation, err
+		}
+		select {
+		case <-ctx.Done():
+			return operation, nil
+		case <-deadline.C:
+			return operation, nil
+		case <-time.After(r.pollInterval):
+		}
+	}
+}
+
+// Cancel stops an operation in progress if its upstream component supports it.
+func (r *Registry) Cancel(ctx context.Context, organizationID string, operationID string) (*grpc_public_api_go.Operation, derrors.Error) {
+	operation, _, err := r.find(organizationID, operationID)
+	if err != nil {
+		return nil, err
+	}
+	r.Lock()
+	canceller, supported := r.cancellers[operation.Type]
+	r.Unlock()
+	if !supported {
+		return nil, derrors.NewUnimplementedError("operations of this type cannot be cancelled").WithParams(operation.Type)
+	}
+	if Finished(operation) {
+		return nil, derrors.NewFailedPreconditionError("operation already finished").WithParams(operationID, operation.Status)
+	}
+	if cErr := canceller(ctx, operation); cErr != nil {
+		return nil, cErr
+	}
+	return r.update(organizationID, operationID, StatusCancelled, ""), nil
+}
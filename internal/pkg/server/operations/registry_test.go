@@ -0,0 +1,222 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operations
+
+import (
+	"context"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-infrastructure-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-provisioner-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// maxStateValue with the highest value probed on the upstream enumerations.
+const maxStateValue = 64
+
+// stateNames returns the names of the values of an upstream enumeration. The generated String method returns
+// the number for the values without a name.
+func stateNames(enumeration fmt.Stringer) []string {
+	names := make([]string, 0)
+	value := reflect.New(reflect.TypeOf(enumeration)).Elem()
+	for i := int64(0); i < maxStateValue; i++ {
+		value.SetInt(i)
+		name := value.Interface().(fmt.Stringer).String()
+		if name != strconv.FormatInt(i, 10) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+var _ = ginkgo.Describe("Operations registry", func() {
+
+	const organizationID = "org"
+
+	var registry *Registry
+	var state string
+	var checks int
+
+	checker := func(context.Context, *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+		checks++
+		return state, "checked", nil
+	}
+
+	record := func(operationID string, operationType string) {
+		registry.Record(&grpc_public_api_go.Operation{
+			OrganizationId: organizationID,
+			OperationId:    operationID,
+			Type:           operationType,
+			Status:         StatusInProgress,
+		})
+	}
+
+	ginkgo.BeforeEach(func() {
+		registry = NewRegistry(time.Hour, time.Hour, time.Millisecond*10)
+		registry.RegisterChecker(LogDownload, checker)
+		state = "ON_GOING"
+		checks = 0
+	})
+
+	ginkgo.It("should translate the upstream states", func() {
+		expected := map[string]string{
+			"SCHEDULED":           StatusInProgress,
+			"INPROGRESS":          StatusInProgress,
+			"INSTALL_IN_PROGRESS": StatusInProgress,
+			"PENDING":             StatusInProgress,
+			"UNKNOWN":             StatusInProgress,
+			"SUCCESS":             StatusSuccess,
+			"INSTALLED":           StatusSuccess,
+			"UNINSTALLED":         StatusSuccess,
+			"FINISHED":            StatusSuccess,
+			"READY":               StatusSuccess,
+			clusterRemoved:        StatusSuccess,
+			"FAIL":                StatusFailed,
+			"FAILED":              StatusFailed,
+			"FAILURE":             StatusFailed,
+			"ERROR":               StatusFailed,
+			"EXPIRED":             StatusFailed,
+			"CANCELED":            StatusCancelled,
+			"CANCELLED":           StatusCancelled,
+			"ON_GOING":            StatusInProgress,
+		}
+		for name, status := range expected {
+			gomega.Expect(StatusFromName(name)).To(gomega.Equal(status), name)
+		}
+	})
+
+	ginkgo.It("should map every upstream state", func() {
+		enumerations := map[string]fmt.Stringer{
+			"operation":    grpc_common_go.OpResponse{}.Status,
+			"controller":   grpc_inventory_manager_go.EdgeControllerOpResponse{}.Status,
+			"agent":        grpc_inventory_manager_go.AgentOpResponse{}.Status,
+			"ec op":        grpc_inventory_go.ECOpSummary{}.Status,
+			"asset op":     grpc_inventory_go.AgentOpSummary{}.Status,
+			"cluster":      grpc_infrastructure_go.Cluster{}.State,
+			"provisioner":  grpc_infrastructure_manager_go.ProvisionerResponse{}.State,
+			"provisioning": grpc_provisioner_go.ProvisionClusterResponse{}.State,
+			"log download": grpc_log_download_manager_go.DownloadLogResponse{}.State,
+		}
+		for enumeration, value := range enumerations {
+			names := stateNames(value)
+			gomega.Expect(names).ShouldNot(gomega.BeEmpty(), enumeration)
+			for _, name := range names {
+				_, exists := upstreamStatus[name]
+				gomega.Expect(exists).To(gomega.BeTrue(), "%s state %s", enumeration, name)
+			}
+		}
+	})
+
+	ginkgo.It("should list the operations of an organization", func() {
+		record("op1", LogDownload)
+		record("op2", AgentInstall)
+		registry.Record(&grpc_public_api_go.Operation{OrganizationId: "other", OperationId: "op3", Type: LogDownload})
+
+		gomega.Expect(registry.List(organizationID, "", "")).To(gomega.HaveLen(2))
+		filtered := registry.List(organizationID, AgentInstall, StatusInProgress)
+		gomega.Expect(filtered).To(gomega.HaveLen(1))
+		gomega.Expect(filtered[0].OperationId).To(gomega.Equal("op2"))
+		gomega.Expect(filtered[0].Created).ShouldNot(gomega.BeZero())
+	})
+
+	ginkgo.It("should refresh the status of the operations in progress", func() {
+		record("op1", LogDownload)
+		state = "READY"
+		operation, err := registry.Get(context.Background(), organizationID, "op1")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(operation.Status).To(gomega.Equal(StatusSuccess))
+		gomega.Expect(operation.Info).To(gomega.Equal("checked"))
+
+		_, err = registry.Get(context.Background(), organizationID, "op1")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(checks).To(gomega.Equal(1))
+	})
+
+	ginkgo.It("should not find the operations of other organizations", func() {
+		record("op1", LogDownload)
+		_, err := registry.Get(context.Background(), "other", "op1")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(err.Type()).To(gomega.Equal(derrors.NotFound))
+	})
+
+	ginkgo.It("should wait until the operation finishes", func() {
+		finishAt := time.Now().Add(time.Millisecond * 30)
+		registry.RegisterChecker(AgentInstall, func(context.Context, *grpc_public_api_go.Operation) (string, string, derrors.Error) {
+			if time.Now().After(finishAt) {
+				return "SUCCESS", "", nil
+			}
+			return "IN_PROGRESS", "", nil
+		})
+		record("op1", AgentInstall)
+		operation, err := registry.Wait(context.Background(), organizationID, "op1", time.Second)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(operation.Status).To(gomega.Equal(StatusSuccess))
+	})
+
+	ginkgo.It("should return the last known status when the timeout expires", func() {
+		record("op1", AgentInstall)
+		operation, err := registry.Wait(context.Background(), organizationID, "op1", time.Millisecond*30)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(operation.Status).To(gomega.Equal(StatusInProgress))
+	})
+
+	ginkgo.It("should expire the operations that do not finish", func() {
+		record("op1", AgentInstall)
+		record("op2", AgentInstall)
+		registry.operations[operationKey(organizationID, "op1")].Created = time.Now().Add(-time.Hour * 2).Unix()
+
+		operation, err := registry.Get(context.Background(), organizationID, "op1")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(operation.Status).To(gomega.Equal(StatusFailed))
+		gomega.Expect(operation.Info).To(gomega.Equal(expiredInfo))
+
+		inProgress := registry.List(organizationID, "", StatusInProgress)
+		gomega.Expect(inProgress).To(gomega.HaveLen(1))
+		gomega.Expect(inProgress[0].OperationId).To(gomega.Equal("op2"))
+	})
+
+	ginkgo.It("should only cancel the operations supported by the upstream", func() {
+		record("op1", LogDownload)
+		_, err := registry.Cancel(context.Background(), organizationID, "op1")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(err.Type()).To(gomega.Equal(derrors.Unimplemented))
+
+		cancelled := 0
+		registry.RegisterCanceller(LogDownload, func(context.Context, *grpc_public_api_go.Operation) derrors.Error {
+			cancelled++
+			return nil
+		})
+		operation, err := registry.Cancel(context.Background(), organizationID, "op1")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(operation.Status).To(gomega.Equal(StatusCancelled))
+		gomega.Expect(cancelled).To(gomega.Equal(1))
+
+		_, err = registry.Cancel(context.Background(), organizationID, "op1")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(err.Type()).To(gomega.Equal(derrors.FailedPrecondition))
+	})
+})
@@ -53,7 +53,7 @@ func (h *Handler) Update(ctx context.Context, updateRequest *grpc_public_api_go.
 		return nil, conversions.ToGRPCError(vErr)
 	}
 
-	return h.Manager.Update(updateRequest)
+	return h.Manager.Update(ctx, updateRequest)
 }
 func (h *Handler) List(ctx context.Context, orgID *grpc_public_api_go.ListRequest) (*grpc_organization_manager_go.SettingList, error) {
 	rm, err := authhelper.GetRequestMetadata(ctx)
@@ -69,5 +69,5 @@ func (h *Handler) List(ctx context.Context, orgID *grpc_public_api_go.ListReques
 		return nil, conversions.ToGRPCError(vErr)
 	}
 
-	return h.Manager.List(orgID)
+	return h.Manager.List(ctx, orgID)
 }
@@ -19,6 +19,7 @@
 package organization_settings
 
 import (
+	"context"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-organization-manager-go"
@@ -37,18 +38,18 @@ func NewManager(settingClient grpc_organization_manager_go.OrganizationsClient)
 	return Manager{settingClient: settingClient}
 }
 
-func (m *Manager) Update(updateRequest *grpc_public_api_go.UpdateSettingRequest) (*grpc_common_go.Success, error) {
+func (m *Manager) Update(ctx context.Context, updateRequest *grpc_public_api_go.UpdateSettingRequest) (*grpc_common_go.Success, error) {
 
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	return m.settingClient.UpdateSettings(ctx, entities.ToUpdateSettingRequest(updateRequest))
 
 }
 
-func (m *Manager) List(organizationID *grpc_public_api_go.ListRequest) (*grpc_organization_manager_go.SettingList, error) {
+func (m *Manager) List(ctx context.Context, organizationID *grpc_public_api_go.ListRequest) (*grpc_organization_manager_go.SettingList, error) {
 
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 
 	list, err := m.settingClient.ListSettings(ctx, &grpc_organization_go.OrganizationId{
@@ -49,7 +49,7 @@ func (h *Handler) Info(ctx context.Context, organizationID *grpc_organization_go
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Info(organizationID)
+	return h.Manager.Info(ctx, organizationID)
 }
 
 func (h *Handler) Update(ctx context.Context, updateRequest *grpc_organization_go.UpdateOrganizationRequest) (*grpc_common_go.Success, error) {
@@ -65,6 +65,6 @@ func (h *Handler) Update(ctx context.Context, updateRequest *grpc_organization_g
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Update(updateRequest)
+	return h.Manager.Update(ctx, updateRequest)
 
 }
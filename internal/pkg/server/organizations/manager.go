@@ -17,6 +17,7 @@
 package organizations
 
 import (
+	"context"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-organization-manager-go"
@@ -33,15 +34,15 @@ func NewManager(orgClient grpc_organization_manager_go.OrganizationsClient) Mana
 	return Manager{orgClient: orgClient}
 }
 
-func (m *Manager) ToOrganizationInfo(organization *grpc_organization_manager_go.Organization) *grpc_organization_manager_go.Organization {
+func (m *Manager) ToOrganizationInfo(ctx context.Context, organization *grpc_organization_manager_go.Organization) *grpc_organization_manager_go.Organization {
 	return &grpc_organization_manager_go.Organization{
 		OrganizationId: organization.OrganizationId,
 		Name:           organization.Name,
 	}
 }
 
-func (m *Manager) Info(organizationID *grpc_organization_go.OrganizationId) (*grpc_organization_manager_go.Organization, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Info(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_organization_manager_go.Organization, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.orgClient.GetOrganization(ctx, organizationID)
 	//retrieved, err := m.orgClient.GetOrganization(ctx, organizationID)
@@ -49,11 +50,11 @@ func (m *Manager) Info(organizationID *grpc_organization_go.OrganizationId) (*gr
 	//	log.Debug().Interface("error", err).Msg("error retrieving info")
 	//	return nil, err
 	//}
-	//return m.ToOrganizationInfo(retrieved), nil
+	//return m.ToOrganizationInfo(ctx, retrieved), nil
 }
 
-func (m *Manager) Update(updateRequest *grpc_organization_go.UpdateOrganizationRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Update(ctx context.Context, updateRequest *grpc_organization_go.UpdateOrganizationRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.orgClient.UpdateOrganization(ctx, updateRequest)
 }
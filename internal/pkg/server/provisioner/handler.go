@@ -33,15 +33,15 @@ func NewHandler(manager Manager) *Handler {
 
 func (h *Handler) ProvisionCluster(ctx context.Context, request *grpc_provisioner_go.ProvisionClusterRequest) (
 	*grpc_provisioner_go.ProvisionClusterResponse, error) {
-	return h.Manager.ProvisionCluster(request)
+	return h.Manager.ProvisionCluster(ctx, request)
 }
 
 func (h *Handler) CheckProgress(ctx context.Context, request *grpc_common_go.RequestId) (
 	*grpc_provisioner_go.ProvisionClusterResponse, error) {
 	log.Debug().Msg("incoming check progress request")
-	return h.Manager.CheckProgress(request)
+	return h.Manager.CheckProgress(ctx, request)
 }
 
 func (h *Handler) RemoveProvision(ctx context.Context, request *grpc_common_go.RequestId) (*grpc_common_go.Success, error) {
-	return h.Manager.RemoveProvision(request)
+	return h.Manager.RemoveProvision(ctx, request)
 }
@@ -30,15 +30,15 @@ func NewManager(provClient grpc_provisioner_go.ProvisionClient) Manager {
 	return Manager{provClient}
 }
 
-func (m *Manager) ProvisionCluster(request *grpc_provisioner_go.ProvisionClusterRequest) (
+func (m *Manager) ProvisionCluster(ctx context.Context, request *grpc_provisioner_go.ProvisionClusterRequest) (
 	*grpc_provisioner_go.ProvisionClusterResponse, error) {
 	return m.ProvisionerClient.ProvisionCluster(context.Background(), request)
 }
 
-func (m *Manager) CheckProgress(request *grpc_common_go.RequestId) (*grpc_provisioner_go.ProvisionClusterResponse, error) {
+func (m *Manager) CheckProgress(ctx context.Context, request *grpc_common_go.RequestId) (*grpc_provisioner_go.ProvisionClusterResponse, error) {
 	return m.ProvisionerClient.CheckProgress(context.Background(), request)
 }
 
-func (m *Manager) RemoveProvision(request *grpc_common_go.RequestId) (*grpc_common_go.Success, error) {
+func (m *Manager) RemoveProvision(ctx context.Context, request *grpc_common_go.RequestId) (*grpc_common_go.Success, error) {
 	return m.ProvisionerClient.RemoveProvision(context.Background(), request)
 }
@@ -0,0 +1,208 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// ReadClass groups the methods that retrieve information.
+	ReadClass = "read"
+	// WriteClass groups the methods that modify the system.
+	WriteClass = "write"
+	// HeavyClass groups the methods that are expensive for the upstream components.
+	HeavyClass = "heavy"
+	// StreamClass groups the methods that keep a stream open, whose concurrency limits the open streams.
+	StreamClass = "stream"
+)
+
+// readPrefixes with the method name prefixes that identify read operations.
+var readPrefixes = []string{"Get", "List", "Info", "Search", "Summary", "Check", "Query", "Monitor"}
+
+// defaultHeavyMethods with the methods that fan out to several components or scan large amounts of data.
+var defaultHeavyMethods = []string{
+	"/public_api.Clusters/List",
+	"/public_api.Resources/Summary",
+	"/public_api.UnifiedLogging/Search",
+	"/public_api.UnifiedLogging/Query",
+	"/public_api.UnifiedLogging/DownloadLog",
+	"/public_api.Inventory/List",
+	"/public_api.Inventory/Summary",
+	"/public_api.InventoryMonitoring/QueryMetrics",
+	"/public_api.Monitoring/GetOrganizationApplicationStats",
+}
+
+// defaultStreamMethods with the methods that stream responses until the client disconnects.
+var defaultStreamMethods = []string{
+	"/public_api.UnifiedLogging/Tail",
+}
+
+// Limit defines the quota of a user or an organization for a class of methods.
+type Limit struct {
+	// Rate with the number of requests per second that refill the bucket. Zero or less disables the rate limit.
+	Rate float64 `yaml:"rate"`
+	// Burst with the capacity of the bucket.
+	Burst int `yaml:"burst"`
+	// Concurrency with the maximum number of requests being served at the same time. Zero or less disables it.
+	Concurrency int `yaml:"concurrency"`
+}
+
+// ClassLimits contains the default limits of a class of methods.
+type ClassLimits struct {
+	// User limits apply to each user.
+	User Limit `yaml:"user"`
+	// Organization limits apply to the requests of all the users of an organization.
+	Organization Limit `yaml:"organization"`
+}
+
+// Config with the rate limiting configuration. A class defined in the configuration file replaces its default
+// limits entirely.
+type Config struct {
+	// Classes with the default limits per class of method.
+	Classes map[string]ClassLimits `yaml:"classes"`
+	// Methods assigns a class to a full method name, overriding the default classification.
+	Methods map[string]string `yaml:"methods"`
+	// Organizations with the limits per class that replace the defaults for a given organization.
+	Organizations map[string]map[string]Limit `yaml:"organizations"`
+	// Users with the limits per class that replace the defaults for a given user.
+	Users map[string]map[string]Limit `yaml:"users"`
+}
+
+// NewDefaultConfig returns the limits applied when no configuration file is provided.
+func NewDefaultConfig() *Config {
+	methods := make(map[string]string, len(defaultHeavyMethods)+len(defaultStreamMethods))
+	for _, method := range defaultHeavyMethods {
+		methods[method] = HeavyClass
+	}
+	for _, method := range defaultStreamMethods {
+		methods[method] = StreamClass
+	}
+	return &Config{
+		Classes: map[string]ClassLimits{
+			ReadClass: {
+				User:         Limit{Rate: 10, Burst: 20, Concurrency: 10},
+				Organization: Limit{Rate: 50, Burst: 100, Concurrency: 40},
+			},
+			WriteClass: {
+				User:         Limit{Rate: 2, Burst: 10, Concurrency: 5},
+				Organization: Limit{Rate: 10, Burst: 30, Concurrency: 20},
+			},
+			HeavyClass: {
+				User:         Limit{Rate: 0.5, Burst: 5, Concurrency: 2},
+				Organization: Limit{Rate: 2, Burst: 10, Concurrency: 8},
+			},
+			StreamClass: {
+				User:         Limit{Rate: 1, Burst: 5, Concurrency: 3},
+				Organization: Limit{Rate: 5, Burst: 20, Concurrency: 20},
+			},
+		},
+		Methods:       methods,
+		Organizations: make(map[string]map[string]Limit, 0),
+		Users:         make(map[string]map[string]Limit, 0),
+	}
+}
+
+// LoadConfig reads a YAML file on top of the default configuration.
+func LoadConfig(path string) (*Config, derrors.Error) {
+	config := NewDefaultConfig()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read rate limit configuration")
+	}
+	err = yaml.Unmarshal(content, config)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot parse rate limit configuration")
+	}
+	vErr := config.Validate()
+	if vErr != nil {
+		return nil, vErr
+	}
+	return config, nil
+}
+
+// validLimit checks that a rate limited bucket can hold at least one request.
+func validLimit(limit Limit) bool {
+	return limit.Rate <= 0 || limit.Burst >= 1
+}
+
+// Validate checks that all the classes referenced by the configuration are defined.
+func (c *Config) Validate() derrors.Error {
+	problems := make([]string, 0)
+	for class, limits := range c.Classes {
+		if !validLimit(limits.User) || !validLimit(limits.Organization) {
+			problems = append(problems, fmt.Sprintf("class %s requires a burst of at least 1", class))
+		}
+	}
+	for method, class := range c.Methods {
+		if _, exists := c.Classes[class]; !exists {
+			problems = append(problems, fmt.Sprintf("method %s uses undefined class %s", method, class))
+		}
+	}
+	overrides := map[string]map[string]map[string]Limit{"organization": c.Organizations, "user": c.Users}
+	for scope, entries := range overrides {
+		for id, classes := range entries {
+			for class, limit := range classes {
+				if _, exists := c.Classes[class]; !exists {
+					problems = append(problems, fmt.Sprintf("%s %s uses undefined class %s", scope, id, class))
+				}
+				if !validLimit(limit) {
+					problems = append(problems, fmt.Sprintf("%s %s requires a burst of at least 1 for class %s", scope, id, class))
+				}
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return derrors.NewInvalidArgumentError(fmt.Sprintf("invalid rate limit configuration: %s", strings.Join(problems, "; ")))
+	}
+	return nil
+}
+
+// ClassOf returns the class of a full method name. Methods not explicitly classified are considered read
+// operations if their name starts with a read prefix, and write operations otherwise.
+func (c *Config) ClassOf(fullMethod string) string {
+	if class, exists := c.Methods[fullMethod]; exists {
+		return class
+	}
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, prefix := range readPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return ReadClass
+		}
+	}
+	return WriteClass
+}
+
+// UserLimit returns the limit of a user for a class of methods.
+func (c *Config) UserLimit(userID string, class string) Limit {
+	if limit, exists := c.Users[userID][class]; exists {
+		return limit
+	}
+	return c.Classes[class].User
+}
+
+// OrganizationLimit returns the limit of an organization for a class of methods.
+func (c *Config) OrganizationLimit(organizationID string, class string) Limit {
+	if limit, exists := c.Organizations[organizationID][class]; exists {
+		return limit
+	}
+	return c.Classes[class].Organization
+}
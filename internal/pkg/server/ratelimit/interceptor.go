@@ -0,0 +1,113 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfterHeader with the name of the metadata entry and HTTP header containing the seconds to wait.
+const RetryAfterHeader = "retry-after"
+
+// retrySeconds rounds up a retry delay to whole seconds as expected by the Retry-After HTTP header.
+func retrySeconds(retryAfter time.Duration) int {
+	return int(math.Ceil(retryAfter.Seconds()))
+}
+
+// exhaustedError builds the RESOURCE_EXHAUSTED error returned to the client, including the retry delay as a
+// RetryInfo detail and as response metadata sent with the given function.
+func exhaustedError(setHeader func(metadata.MD) error, fullMethod string, retryAfter time.Duration) error {
+	seconds := strconv.Itoa(retrySeconds(retryAfter))
+	if err := setHeader(metadata.Pairs(RetryAfterHeader, seconds)); err != nil {
+		log.Warn().Err(err).Msg("cannot set retry-after header")
+	}
+	st := status.New(codes.ResourceExhausted, "request quota exceeded for "+fullMethod+", retry after "+seconds+"s")
+	detailed, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: ptypes.DurationProto(retryAfter)})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// UnaryServerInterceptor enforces the quotas of the user and organization found in the authx metadata. It must
+// run after the authx interceptor. Requests without authx metadata are not limited as they are rejected by the
+// handlers.
+func UnaryServerInterceptor(limiter *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		rm, err := authhelper.GetRequestMetadata(ctx)
+		if err != nil {
+			return handler(ctx, req)
+		}
+		release, retryAfter, allowed := limiter.Acquire(rm.OrganizationID, rm.UserID, info.FullMethod)
+		if !allowed {
+			log.Debug().Str("organizationID", rm.OrganizationID).Str("userID", rm.UserID).
+				Str("method", info.FullMethod).Dur("retryAfter", retryAfter).Msg("request rejected by rate limit")
+			return nil, exhaustedError(func(md metadata.MD) error {
+				return grpc.SetHeader(ctx, md)
+			}, info.FullMethod, retryAfter)
+		}
+		defer release()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor enforces the quotas of the user and organization on the streams, so the concurrency of
+// a class limits the streams kept open at the same time. It must run after the stream authorization interceptor.
+func StreamServerInterceptor(limiter *Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		rm, err := authhelper.GetRequestMetadata(ss.Context())
+		if err != nil {
+			return handler(srv, ss)
+		}
+		release, retryAfter, allowed := limiter.Acquire(rm.OrganizationID, rm.UserID, info.FullMethod)
+		if !allowed {
+			log.Debug().Str("organizationID", rm.OrganizationID).Str("userID", rm.UserID).
+				Str("method", info.FullMethod).Dur("retryAfter", retryAfter).Msg("stream rejected by rate limit")
+			return exhaustedError(ss.SetHeader, info.FullMethod, retryAfter)
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+// GatewayHTTPError extends the default error handler of the HTTP gateway adding the Retry-After header to the
+// responses of rejected requests. RESOURCE_EXHAUSTED is already translated into 429 Too Many Requests.
+func GatewayHTTPError(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if st, ok := status.FromError(err); ok && st.Code() == codes.ResourceExhausted {
+		for _, detail := range st.Details() {
+			if info, isRetryInfo := detail.(*errdetails.RetryInfo); isRetryInfo {
+				if retryAfter, dErr := ptypes.Duration(info.RetryDelay); dErr == nil {
+					w.Header().Set(http.CanonicalHeaderKey(RetryAfterHeader), strconv.Itoa(retrySeconds(retryAfter)))
+				}
+			}
+		}
+	}
+	runtime.DefaultHTTPError(ctx, mux, marshaler, w, r, err)
+}
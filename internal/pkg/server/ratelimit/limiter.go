@@ -0,0 +1,165 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ConcurrencyRetryAfter with the time suggested to the client when the concurrency quota is exhausted.
+const ConcurrencyRetryAfter = time.Second
+
+// PurgeInterval with the minimum time between two purges of idle entries.
+const PurgeInterval = time.Minute
+
+// bucket implements a token bucket.
+type bucket struct {
+	limit      Limit
+	tokens     float64
+	lastRefill time.Time
+}
+
+// refill adds the tokens accumulated since the last refill.
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+	b.lastRefill = now
+}
+
+// full checks whether the bucket is at its capacity, in which case it is equivalent to a new one.
+func (b *bucket) full() bool {
+	return b.tokens >= float64(b.limit.Burst)
+}
+
+// wait returns the time until the bucket holds a token. It is zero if a token is available.
+func (b *bucket) wait() time.Duration {
+	if b.tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// quota identifies the limit to apply to a key.
+type quota struct {
+	key   string
+	limit Limit
+}
+
+// Limiter keeps the token buckets and in-flight counters of users and organizations.
+type Limiter struct {
+	config    *Config
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	inFlight  map[string]int
+	lastPurge time.Time
+	// now returns the current time. It can be replaced in tests.
+	now func() time.Time
+}
+
+// NewLimiter creates a Limiter with a given configuration.
+func NewLimiter(config *Config) *Limiter {
+	return &Limiter{
+		config:   config,
+		buckets:  make(map[string]*bucket, 0),
+		inFlight: make(map[string]int, 0),
+		now:      time.Now,
+	}
+}
+
+// quotas returns the user and organization quotas that apply to a request.
+func (l *Limiter) quotas(organizationID string, userID string, class string) []quota {
+	return []quota{
+		{fmt.Sprintf("user/%s/%s", userID, class), l.config.UserLimit(userID, class)},
+		{fmt.Sprintf("organization/%s/%s", organizationID, class), l.config.OrganizationLimit(organizationID, class)},
+	}
+}
+
+// getBucket returns the bucket of a key, creating a full one if it does not exist.
+func (l *Limiter) getBucket(key string, limit Limit, now time.Time) *bucket {
+	b, exists := l.buckets[key]
+	if !exists || b.limit != limit {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), lastRefill: now}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	return b
+}
+
+// Acquire checks the user and organization quotas of a request to a given method. If the request is allowed,
+// the returned function must be called once the request finishes. Otherwise, the time after which the client
+// may retry is returned.
+func (l *Limiter) Acquire(organizationID string, userID string, fullMethod string) (func(), time.Duration, bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := l.now()
+	l.purge(now)
+
+	quotas := l.quotas(organizationID, userID, l.config.ClassOf(fullMethod))
+	var retryAfter time.Duration
+	for _, q := range quotas {
+		if q.limit.Rate > 0 {
+			if wait := l.getBucket(q.key, q.limit, now).wait(); wait > retryAfter {
+				retryAfter = wait
+			}
+		}
+		if q.limit.Concurrency > 0 && l.inFlight[q.key] >= q.limit.Concurrency && retryAfter < ConcurrencyRetryAfter {
+			retryAfter = ConcurrencyRetryAfter
+		}
+	}
+	if retryAfter > 0 {
+		return nil, retryAfter, false
+	}
+
+	for _, q := range quotas {
+		if q.limit.Rate > 0 {
+			l.buckets[q.key].tokens--
+		}
+		l.inFlight[q.key]++
+	}
+	released := false
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		if released {
+			return
+		}
+		released = true
+		for _, q := range quotas {
+			l.inFlight[q.key]--
+			if l.inFlight[q.key] <= 0 {
+				delete(l.inFlight, q.key)
+			}
+		}
+	}, 0, true
+}
+
+// purge removes the buckets that are full so idle users and organizations do not accumulate in memory.
+func (l *Limiter) purge(now time.Time) {
+	if now.Sub(l.lastPurge) < PurgeInterval {
+		return
+	}
+	l.lastPurge = now
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.full() {
+			delete(l.buckets, key)
+		}
+	}
+}
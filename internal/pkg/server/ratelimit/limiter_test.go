@@ -0,0 +1,141 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ratelimit
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Rate limiter", func() {
+
+	const listMethod = "/public_api.Nodes/List"
+	const updateMethod = "/public_api.Nodes/UpdateNode"
+
+	var config *Config
+	var limiter *Limiter
+	var now time.Time
+
+	ginkgo.BeforeEach(func() {
+		config = NewDefaultConfig()
+		config.Classes[ReadClass] = ClassLimits{
+			User:         Limit{Rate: 1, Burst: 2},
+			Organization: Limit{Rate: 10, Burst: 3},
+		}
+		now = time.Now()
+		limiter = NewLimiter(config)
+		limiter.now = func() time.Time { return now }
+	})
+
+	ginkgo.Context("classification", func() {
+		ginkgo.It("should classify methods by name", func() {
+			gomega.Expect(config.ClassOf(listMethod)).Should(gomega.Equal(ReadClass))
+			gomega.Expect(config.ClassOf(updateMethod)).Should(gomega.Equal(WriteClass))
+			gomega.Expect(config.ClassOf("/public_api.UnifiedLogging/Search")).Should(gomega.Equal(HeavyClass))
+			gomega.Expect(config.ClassOf("/public_api.UnifiedLogging/Tail")).Should(gomega.Equal(StreamClass))
+		})
+		ginkgo.It("should reject undefined classes", func() {
+			config.Methods[updateMethod] = "unknown"
+			gomega.Expect(config.Validate()).ShouldNot(gomega.Succeed())
+		})
+	})
+
+	ginkgo.It("should reject requests once the user bucket is empty", func() {
+		for i := 0; i < 2; i++ {
+			_, _, allowed := limiter.Acquire("org", "user", listMethod)
+			gomega.Expect(allowed).Should(gomega.BeTrue())
+		}
+		_, retryAfter, allowed := limiter.Acquire("org", "user", listMethod)
+		gomega.Expect(allowed).Should(gomega.BeFalse())
+		gomega.Expect(retryAfter).Should(gomega.Equal(time.Second))
+
+		now = now.Add(time.Second)
+		_, _, allowed = limiter.Acquire("org", "user", listMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should not consume tokens of other classes", func() {
+		for i := 0; i < 2; i++ {
+			limiter.Acquire("org", "user", listMethod)
+		}
+		_, _, allowed := limiter.Acquire("org", "user", updateMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should share the organization bucket between users", func() {
+		for _, user := range []string{"user1", "user2", "user3"} {
+			_, _, allowed := limiter.Acquire("org", user, listMethod)
+			gomega.Expect(allowed).Should(gomega.BeTrue())
+		}
+		_, _, allowed := limiter.Acquire("org", "user4", listMethod)
+		gomega.Expect(allowed).Should(gomega.BeFalse())
+		_, _, allowed = limiter.Acquire("other", "user4", listMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should apply user overrides", func() {
+		config.Users["vip"] = map[string]Limit{ReadClass: {Rate: 100, Burst: 100}}
+		config.Organizations["org"] = map[string]Limit{ReadClass: {Rate: 100, Burst: 100}}
+		for i := 0; i < 10; i++ {
+			_, _, allowed := limiter.Acquire("org", "vip", listMethod)
+			gomega.Expect(allowed).Should(gomega.BeTrue())
+		}
+	})
+
+	ginkgo.It("should limit concurrent requests until they are released", func() {
+		config.Classes[WriteClass] = ClassLimits{User: Limit{Concurrency: 1}}
+		release, _, allowed := limiter.Acquire("org", "user", updateMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+		_, retryAfter, allowed := limiter.Acquire("org", "user", updateMethod)
+		gomega.Expect(allowed).Should(gomega.BeFalse())
+		gomega.Expect(retryAfter).Should(gomega.Equal(ConcurrencyRetryAfter))
+		release()
+		release()
+		_, _, allowed = limiter.Acquire("org", "user", updateMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should limit the open streams of a user and an organization", func() {
+		const tailMethod = "/public_api.UnifiedLogging/Tail"
+		config.Classes[StreamClass] = ClassLimits{User: Limit{Concurrency: 2}, Organization: Limit{Concurrency: 3}}
+		releases := make([]func(), 0)
+		for i := 0; i < 2; i++ {
+			release, _, allowed := limiter.Acquire("org", "user", tailMethod)
+			gomega.Expect(allowed).Should(gomega.BeTrue())
+			releases = append(releases, release)
+		}
+		_, _, allowed := limiter.Acquire("org", "user", tailMethod)
+		gomega.Expect(allowed).Should(gomega.BeFalse())
+		release, _, allowed := limiter.Acquire("org", "other", tailMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+		_, _, allowed = limiter.Acquire("org", "third", tailMethod)
+		gomega.Expect(allowed).Should(gomega.BeFalse())
+		release()
+		releases[0]()
+		_, _, allowed = limiter.Acquire("org", "user", tailMethod)
+		gomega.Expect(allowed).Should(gomega.BeTrue())
+	})
+
+	ginkgo.It("should purge idle buckets", func() {
+		limiter.Acquire("org", "user", listMethod)
+		gomega.Expect(limiter.buckets).ShouldNot(gomega.BeEmpty())
+		now = now.Add(PurgeInterval * 2)
+		limiter.purge(now)
+		gomega.Expect(limiter.buckets).Should(gomega.BeEmpty())
+	})
+})
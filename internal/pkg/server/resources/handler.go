@@ -48,5 +48,5 @@ func (h *Handler) Summary(ctx context.Context, organizationID *grpc_organization
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Summary(organizationID)
+	return h.Manager.Summary(ctx, organizationID)
 }
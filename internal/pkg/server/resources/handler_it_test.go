@@ -31,6 +31,7 @@ import (
 	"github.com/nalej/grpc-organization-manager-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
 	"github.com/nalej/public-api/internal/pkg/utils"
 	"github.com/onsi/ginkgo"
@@ -107,7 +108,7 @@ var _ = ginkgo.Describe("Resources", func() {
 		conn, err := test.GetConn(*listener)
 		gomega.Expect(err).To(gomega.Succeed())
 
-		manager := NewManager(clustClient, nodeClient)
+		manager := NewManager(clustClient, nodeClient, cache.NewCache("resources", 0))
 		handler := NewHandler(manager)
 		grpc_public_api_go.RegisterResourcesServer(server, handler)
 		test.LaunchServer(server, listener)
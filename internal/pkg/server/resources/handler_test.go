@@ -0,0 +1,117 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resources
+
+import (
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-infrastructure-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var _ = ginkgo.Describe("Resources handler", func() {
+
+	const NumClusters = 3
+	const NumNodes = 2
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.ResourcesClient
+
+	var organizationID *grpc_organization_go.OrganizationId
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+
+		organizationID = &grpc_organization_go.OrganizationId{OrganizationId: ithelpers.GenerateUUID()}
+		for c := 0; c < NumClusters; c++ {
+			cluster := platform.Store.AddCluster(&grpc_infrastructure_go.Cluster{OrganizationId: organizationID.OrganizationId})
+			for n := 0; n < NumNodes; n++ {
+				platform.Store.AddNode(&grpc_infrastructure_go.Node{
+					OrganizationId: organizationID.OrganizationId,
+					ClusterId:      cluster.ClusterId,
+				})
+			}
+		}
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(grpc_infrastructure_go.NewClustersClient(platform.Conn()),
+			grpc_infrastructure_go.NewNodesClient(platform.Conn()), cache.NewCache("resources", 0))
+		grpc_public_api_go.RegisterResourcesServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewResourcesClient(conn)
+
+		token = ithelpers.GenerateToken("email@nalej.com", organizationID.OrganizationId, "Operator", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_RESOURCES})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	ginkgo.It("should summarize the clusters and nodes of the organization", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		summary, err := client.Summary(ctx, organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(summary.TotalClusters).To(gomega.Equal(int64(NumClusters)))
+		gomega.Expect(summary.TotalNodes).To(gomega.Equal(int64(NumClusters * NumNodes)))
+	})
+
+	ginkgo.It("should report an unknown number of nodes if a cluster does not answer", func() {
+		platform.Faults.FailTimes("Nodes/ListNodes", status.Error(codes.Unavailable, "system model unavailable"), 1)
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		summary, err := client.Summary(ctx, organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(summary.TotalClusters).To(gomega.Equal(int64(NumClusters)))
+		gomega.Expect(summary.TotalNodes).To(gomega.Equal(entities.UnknownCount))
+	})
+
+	ginkgo.It("should fail if the clusters cannot be listed", func() {
+		platform.Faults.FailOn("Clusters/ListClusters", status.Error(codes.Unavailable, "system model unavailable"))
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.Summary(ctx, organizationID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.Unavailable))
+	})
+
+})
@@ -17,35 +17,42 @@
 package resources
 
 import (
+	"context"
+	"github.com/golang/protobuf/proto"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-infrastructure-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/rs/zerolog/log"
 )
 
 // Manager structure with the required clients for resources operations.
 type Manager struct {
 	clustClient grpc_infrastructure_go.ClustersClient
 	nodeClient  grpc_infrastructure_go.NodesClient
+	// cache with the summaries, shared with the cluster and node managers that invalidate it.
+	cache *cache.Cache
 }
 
 // NewManager creates a Manager using a set of clients.
 func NewManager(clustClient grpc_infrastructure_go.ClustersClient,
-	nodeClient grpc_infrastructure_go.NodesClient) Manager {
+	nodeClient grpc_infrastructure_go.NodesClient, responseCache *cache.Cache) Manager {
 	return Manager{
-		clustClient: clustClient, nodeClient: nodeClient,
+		clustClient: clustClient, nodeClient: nodeClient, cache: responseCache,
 	}
 }
 
-func (m *Manager) getNumNodes(organizationID string, clusterID string) (int, derrors.Error) {
+func (m *Manager) getNumNodes(ctx context.Context, organizationID string, clusterID string) (int, derrors.Error) {
 	// Return number of nodes in a cluster
 	cID := &grpc_infrastructure_go.ClusterId{
 		OrganizationId: organizationID,
 		ClusterId:      clusterID,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	clusterNodes, err := m.nodeClient.ListNodes(ctx, cID)
 	if err != nil {
@@ -54,33 +61,62 @@ func (m *Manager) getNumNodes(organizationID string, clusterID string) (int, der
 	return len(clusterNodes.Nodes), nil
 }
 
-func (m *Manager) getSummary(organizationID *grpc_organization_go.OrganizationId) (int, int, derrors.Error) {
+// getSummary returns the number of clusters and nodes of an organization. The nodes of the clusters are counted
+// in parallel, and the summary is reported as partial if any of them cannot be retrieved.
+func (m *Manager) getSummary(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (int, int, bool, derrors.Error) {
 	// Obtain list of clusters
 	totalNodes := 0
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	list, err := m.clustClient.ListClusters(ctx, organizationID)
 	if err != nil {
-		return 0, 0, conversions.ToDerror(err)
+		return 0, 0, false, conversions.ToDerror(err)
 	}
-	for _, c := range list.Clusters {
-		n, err := m.getNumNodes(c.OrganizationId, c.ClusterId)
+	numNodes := make([]int, len(list.Clusters))
+	errs := common.FanOut(ctx, len(list.Clusters), func(callCtx context.Context, index int) error {
+		n, err := m.getNumNodes(callCtx, list.Clusters[index].OrganizationId, list.Clusters[index].ClusterId)
 		if err != nil {
-			return 0, 0, err
+			return err
 		}
-		totalNodes += n
+		numNodes[index] = n
+		return nil
+	})
+	complete := true
+	for index, err := range errs {
+		if err != nil {
+			log.Warn().Str("clusterID", list.Clusters[index].ClusterId).Err(err).Msg("cannot count the nodes of the cluster")
+			complete = false
+		}
+		totalNodes += numNodes[index]
+	}
+	return len(list.Clusters), totalNodes, complete, nil
+}
+
+// Summary returns the number of clusters and nodes of an organization.
+func (m *Manager) Summary(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.ResourceSummary, error) {
+	summary, err := m.cache.GetOrLoad(organizationID.OrganizationId, cache.Key("resources.Summary", organizationID), func() (proto.Message, bool, error) {
+		return m.summary(ctx, organizationID)
+	})
+	if err != nil {
+		return nil, err
 	}
-	return len(list.Clusters), totalNodes, nil
+	return summary.(*grpc_public_api_go.ResourceSummary), nil
 }
 
-func (m *Manager) Summary(organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.ResourceSummary, error) {
-	totalClusters, totalNodes, err := m.getSummary(organizationID)
+// summary computes the resource summary of an organization. The number of nodes is set to entities.UnknownCount
+// if the nodes of any cluster cannot be counted.
+func (m *Manager) summary(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.ResourceSummary, bool, error) {
+	totalClusters, totalNodes, complete, err := m.getSummary(ctx, organizationID)
 	if err != nil {
-		return nil, conversions.ToGRPCError(err)
+		return nil, false, conversions.ToGRPCError(err)
 	}
-	return &grpc_public_api_go.ResourceSummary{
+	summary := &grpc_public_api_go.ResourceSummary{
 		OrganizationId: organizationID.OrganizationId,
 		TotalClusters:  int64(totalClusters),
 		TotalNodes:     int64(totalNodes),
-	}, nil
+	}
+	if !complete {
+		summary.TotalNodes = entities.UnknownCount
+	}
+	return summary, complete, nil
 }
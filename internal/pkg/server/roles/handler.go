@@ -78,7 +78,7 @@ func (h *Handler) List(ctx context.Context, organizationID *grpc_organization_go
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	roles, lErr := h.Manager.List(organizationID)
+	roles, lErr := h.Manager.List(ctx, organizationID)
 	if lErr != nil {
 		return nil, lErr
 	}
@@ -98,7 +98,7 @@ func (h *Handler) ListInternal(ctx context.Context, organizationID *grpc_organiz
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	roles, lErr := h.Manager.List(organizationID)
+	roles, lErr := h.Manager.List(ctx, organizationID)
 	if lErr != nil {
 		return nil, lErr
 	}
@@ -118,7 +118,7 @@ func (h *Handler) AssignRole(ctx context.Context, request *grpc_user_manager_go.
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	user, lErr := h.Manager.AssignRole(request)
+	user, lErr := h.Manager.AssignRole(ctx, request)
 	if lErr != nil {
 		return nil, lErr
 	}
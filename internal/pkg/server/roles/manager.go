@@ -17,6 +17,7 @@
 package roles
 
 import (
+	"context"
 	"github.com/nalej/grpc-authx-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-user-manager-go"
@@ -33,14 +34,14 @@ func NewManager(client grpc_user_manager_go.UserManagerClient) Manager {
 	return Manager{client}
 }
 
-func (m *Manager) List(organizationID *grpc_organization_go.OrganizationId) (*grpc_authx_go.RoleList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) List(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_authx_go.RoleList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.client.ListRoles(ctx, organizationID)
 }
 
-func (m *Manager) AssignRole(request *grpc_user_manager_go.AssignRoleRequest) (*grpc_user_manager_go.User, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) AssignRole(ctx context.Context, request *grpc_user_manager_go.AssignRoleRequest) (*grpc_user_manager_go.User, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.client.AssignRole(ctx, request)
 }
@@ -18,6 +18,7 @@ package server
 
 import (
 	"context"
+	"expvar"
 	"fmt"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/nalej/authx/pkg/interceptor"
@@ -33,25 +34,35 @@ import (
 	"github.com/nalej/grpc-provisioner-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/public-api/internal/pkg/selector"
 	"github.com/nalej/public-api/internal/pkg/server/agent"
 	"github.com/nalej/public-api/internal/pkg/server/application-network"
 	"github.com/nalej/public-api/internal/pkg/server/applications"
+	"github.com/nalej/public-api/internal/pkg/server/cache"
 	"github.com/nalej/public-api/internal/pkg/server/clusters"
 	"github.com/nalej/public-api/internal/pkg/server/devices"
 	"github.com/nalej/public-api/internal/pkg/server/ec"
 	"github.com/nalej/public-api/internal/pkg/server/edge-monitoring"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/idempotency"
 	"github.com/nalej/public-api/internal/pkg/server/inventory"
+	"github.com/nalej/public-api/internal/pkg/server/labels"
 	"github.com/nalej/public-api/internal/pkg/server/monitoring"
 	"github.com/nalej/public-api/internal/pkg/server/nodes"
+	"github.com/nalej/public-api/internal/pkg/server/operations"
 	"github.com/nalej/public-api/internal/pkg/server/organization-settings"
 	"github.com/nalej/public-api/internal/pkg/server/organizations"
 	"github.com/nalej/public-api/internal/pkg/server/provisioner"
+	"github.com/nalej/public-api/internal/pkg/server/ratelimit"
 	"github.com/nalej/public-api/internal/pkg/server/resources"
 	"github.com/nalej/public-api/internal/pkg/server/roles"
+	"github.com/nalej/public-api/internal/pkg/server/streamauth"
+	"github.com/nalej/public-api/internal/pkg/server/tracing"
 	"github.com/nalej/public-api/internal/pkg/server/unified-logging"
 	"github.com/nalej/public-api/internal/pkg/server/users"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 	"net"
 	"net/http"
@@ -60,12 +71,21 @@ import (
 
 type Service struct {
 	Configuration Config
+	// certLoader serves the TLS certificate of both listeners. It is nil if TLS is disabled.
+	certLoader *CertificateLoader
+	// gatewayListener is the local gRPC listener dialed by the HTTP gateway, exempt from client certificates. It is
+	// nil if TLS is disabled.
+	gatewayListener net.Listener
+	// mock contains the fake upstream components used in mock mode. It is nil otherwise.
+	mock *fakes.Platform
+	// mockLogin issues the tokens in mock mode.
+	mockLogin *fakes.Login
 }
 
 // NewService creates a new system model service.
 func NewService(conf Config) *Service {
 	return &Service{
-		conf,
+		Configuration: conf,
 	}
 }
 
@@ -89,43 +109,48 @@ type Clients struct {
 }
 
 func (s *Service) GetClients() (*Clients, derrors.Error) {
-	smConn, err := grpc.Dial(s.Configuration.SystemModelAddress, grpc.WithInsecure())
+	if s.mock != nil {
+		return s.mockClients(), nil
+	}
+	// Outgoing calls continue the trace of the request being served.
+	dialOpts := []grpc.DialOption{grpc.WithInsecure(), grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor())}
+	smConn, err := grpc.Dial(s.Configuration.SystemModelAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with the system model")
 	}
-	infraConn, err := grpc.Dial(s.Configuration.InfrastructureManagerAddress, grpc.WithInsecure())
+	infraConn, err := grpc.Dial(s.Configuration.InfrastructureManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with the applications manager")
 	}
-	umConn, err := grpc.Dial(s.Configuration.UserManagerAddress, grpc.WithInsecure())
+	umConn, err := grpc.Dial(s.Configuration.UserManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with the user manager")
 	}
-	appConn, err := grpc.Dial(s.Configuration.ApplicationsManagerAddress, grpc.WithInsecure())
+	appConn, err := grpc.Dial(s.Configuration.ApplicationsManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with the applications manager")
 	}
-	devConn, err := grpc.Dial(s.Configuration.DeviceManagerAddress, grpc.WithInsecure())
+	devConn, err := grpc.Dial(s.Configuration.DeviceManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with the device manager")
 	}
-	mmConn, err := grpc.Dial(s.Configuration.MonitoringManagerAddress, grpc.WithInsecure())
+	mmConn, err := grpc.Dial(s.Configuration.MonitoringManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with infrastructure monitor coordinator")
 	}
-	invManagerConn, err := grpc.Dial(s.Configuration.InventoryManagerAddress, grpc.WithInsecure())
+	invManagerConn, err := grpc.Dial(s.Configuration.InventoryManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with inventory manager coordinator")
 	}
-	provConn, err := grpc.Dial(s.Configuration.ProvisionerManagerAddress, grpc.WithInsecure())
+	provConn, err := grpc.Dial(s.Configuration.ProvisionerManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with provisioner manager address")
 	}
-	logDownConn, err := grpc.Dial(s.Configuration.LogDownloadManagerAddress, grpc.WithInsecure())
+	logDownConn, err := grpc.Dial(s.Configuration.LogDownloadManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with log-download manager address")
 	}
-	orgConn, err := grpc.Dial(s.Configuration.OrganizationManagerAddress, grpc.WithInsecure())
+	orgConn, err := grpc.Dial(s.Configuration.OrganizationManagerAddress, dialOpts...)
 	if err != nil {
 		return nil, derrors.AsError(err, "cannot create connection with organization manager address")
 	}
@@ -156,6 +181,9 @@ func (s *Service) GetClients() (*Clients, derrors.Error) {
 
 // Run the service, launch the REST service handler.
 func (s *Service) Run() error {
+	if s.Configuration.Mock {
+		s.Configuration.ApplyMockDefaults()
+	}
 	vErr := s.Configuration.Validate()
 	if vErr != nil {
 		log.Fatal().Str("err", vErr.DebugReport()).Msg("invalid configuration")
@@ -168,8 +196,38 @@ func (s *Service) Run() error {
 		log.Fatal().Str("err", authErr.DebugReport()).Msg("cannot load authx config")
 	}
 
+	shutdownTracing, tErr := tracing.Setup(s.Configuration.TracingExporter, s.Configuration.TracingEndpoint)
+	if tErr != nil {
+		log.Fatal().Str("err", tErr.DebugReport()).Msg("cannot setup tracing")
+	}
+	defer shutdownTracing()
+
 	log.Info().Bool("AllowsAll", authConfig.AllowsAll).Int("permissions", len(authConfig.Permissions)).Msg("Auth config")
 
+	if s.Configuration.UseTLS() {
+		loader, tlsErr := NewCertificateLoader(s.Configuration.TLSCertPath, s.Configuration.TLSKeyPath)
+		if tlsErr != nil {
+			log.Fatal().Str("err", tlsErr.DebugReport()).Msg("cannot load TLS certificate")
+		}
+		s.certLoader = loader
+		gatewayListener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot listen for the HTTP gateway")
+		}
+		s.gatewayListener = gatewayListener
+	}
+
+	if s.Configuration.Mock {
+		if mErr := s.StartMock(); mErr != nil {
+			log.Fatal().Str("err", mErr.DebugReport()).Msg("cannot start mock platform")
+		}
+		defer s.mock.Stop()
+	}
+
+	if s.Configuration.MetricsPort > 0 {
+		go s.LaunchMetrics()
+	}
+
 	go s.LaunchGRPC(authConfig)
 	return s.LaunchHTTP()
 }
@@ -190,17 +248,55 @@ func (s *Service) allowCORS(h http.Handler) http.Handler {
 }
 
 func preflightHandler(w http.ResponseWriter, r *http.Request) {
-	headers := []string{"Content-Type", "Accept", "Authorization"}
+	headers := []string{"Content-Type", "Accept", "Authorization", "Idempotency-Key", "Label-Selector", "Last-Event-ID"}
 	w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ","))
 	methods := []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
 	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ","))
 }
 
+// gatewayHeaderMatcher forwards the idempotency key, the label selector and the trace context headers received
+// by the HTTP gateway as gRPC metadata.
+func gatewayHeaderMatcher(key string) (string, bool) {
+	if strings.EqualFold(key, idempotency.KeyHeader) {
+		return idempotency.KeyHeader, true
+	}
+	if strings.EqualFold(key, selector.MetadataKey) {
+		return selector.MetadataKey, true
+	}
+	return tracing.GatewayHeaderMatcher(key)
+}
+
+// withTailEvents serves the log tail stream as Server-Sent Events on unified_logging.TailEventsPath, as the
+// generated gateway does not support them.
+func (s *Service) withTailEvents(gateway http.Handler, client grpc_public_api_go.UnifiedLoggingClient) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(unified_logging.TailEventsPath, unified_logging.NewTailEventHandler(client, s.Configuration.AuthHeader))
+	mux.Handle("/", gateway)
+	return mux
+}
+
+// LaunchMetrics serves the internal metrics, such as the cache counters, in expvar format on /debug/vars.
+func (s *Service) LaunchMetrics() {
+	addr := fmt.Sprintf(":%d", s.Configuration.MetricsPort)
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	log.Info().Str("address", addr).Msg("Metrics listening")
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Error().Err(err).Msg("metrics listener stopped")
+	}
+}
+
 func (s *Service) LaunchHTTP() error {
 	addr := fmt.Sprintf(":%d", s.Configuration.HTTPPort)
 	clientAddr := fmt.Sprintf(":%d", s.Configuration.Port)
 	opts := []grpc.DialOption{grpc.WithInsecure()}
-	mux := runtime.NewServeMux()
+	if s.certLoader != nil {
+		clientAddr = s.gatewayListener.Addr().String()
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(credentials.NewTLS(s.GetGatewayTLSConfig(s.certLoader)))}
+	}
+	// Rejected requests are answered with 429 and a Retry-After header.
+	runtime.HTTPError = ratelimit.GatewayHTTPError
+	mux := runtime.NewServeMux(runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher))
 
 	if err := grpc_public_api_go.RegisterApplicationsHandlerFromEndpoint(context.Background(), mux, clientAddr, opts); err != nil {
 		log.Fatal().Err(err).Msg("failed to start applications handler")
@@ -253,9 +349,33 @@ func (s *Service) LaunchHTTP() error {
 	if err := grpc_public_api_go.RegisterOrganizationSettingsHandlerFromEndpoint(context.Background(), mux, clientAddr, opts); err != nil {
 		log.Fatal().Err(err).Msg("failed to start organization settings handler")
 	}
+	if err := grpc_public_api_go.RegisterOperationsHandlerFromEndpoint(context.Background(), mux, clientAddr, opts); err != nil {
+		log.Fatal().Err(err).Msg("failed to start operations handler")
+	}
+	if err := grpc_public_api_go.RegisterLabelsHandlerFromEndpoint(context.Background(), mux, clientAddr, opts); err != nil {
+		log.Fatal().Err(err).Msg("failed to start labels handler")
+	}
+	tailConn, err := grpc.Dial(clientAddr, opts...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to start unified logging tail handler")
+	}
+	var handler http.Handler = s.withTailEvents(mux, grpc_public_api_go.NewUnifiedLoggingClient(tailConn))
+	if s.mockLogin != nil {
+		handler = s.withMockLogin(handler)
+	}
 	server := &http.Server{
 		Addr:    addr,
-		Handler: s.allowCORS(mux),
+		Handler: s.allowCORS(handler),
+	}
+	if s.certLoader != nil {
+		tlsConfig, err := s.GetServerTLSConfig(s.certLoader, false)
+		if err != nil {
+			log.Fatal().Str("err", err.DebugReport()).Msg("cannot create HTTP TLS configuration")
+			return err
+		}
+		server.TLSConfig = tlsConfig
+		log.Info().Str("address", addr).Msg("HTTPS Listening")
+		return server.ListenAndServeTLS("", "")
 	}
 	log.Info().Str("address", addr).Msg("HTTP Listening")
 	return server.ListenAndServe()
@@ -276,13 +396,16 @@ func (s *Service) LaunchGRPC(authConfig *interceptor.AuthorizationConfig) error
 	orgManager := organizations.NewManager(clients.orgClient)
 	orgHandler := organizations.NewHandler(orgManager)
 
-	clusManager := clusters.NewManager(clients.clusClient, clients.nodeClient, clients.infraClient)
+	// The cluster lists and resource summaries are shared by the managers that read and invalidate them.
+	resourceCache := cache.NewCache("resources", s.Configuration.CacheTTL)
+
+	clusManager := clusters.NewManager(clients.clusClient, clients.nodeClient, clients.infraClient, resourceCache)
 	clusHandler := clusters.NewHandler(clusManager)
 
-	nodesManager := nodes.NewManager(clients.nodeClient)
+	nodesManager := nodes.NewManager(clients.nodeClient, resourceCache)
 	nodesHandler := nodes.NewHandler(nodesManager)
 
-	resManager := resources.NewManager(clients.clusClient, clients.nodeClient)
+	resManager := resources.NewManager(clients.clusClient, clients.nodeClient, resourceCache)
 	resHandler := resources.NewHandler(resManager)
 
 	userManager := users.NewManager(clients.umClient)
@@ -297,8 +420,20 @@ func (s *Service) LaunchGRPC(authConfig *interceptor.AuthorizationConfig) error
 	devManager := devices.NewManager(clients.deviceClient)
 	devHandler := devices.NewHandler(devManager)
 
-	ulManager := unified_logging.NewManager(clients.unifLoggClient, clients.logDownloadClient)
+	searchStore, sErr := unified_logging.NewSearchStore(s.Configuration.SavedSearchesPath, s.Configuration.WebhookKey())
+	if sErr != nil {
+		log.Fatal().Str("err", sErr.DebugReport()).Msg("cannot load saved searches")
+		return sErr
+	}
+	webhookGuard := unified_logging.NewWebhookGuard(s.Configuration.WebhookAllowedHosts)
+	ulManager := unified_logging.NewManager(clients.unifLoggClient, clients.logDownloadClient, clients.appClient, clients.orgClient,
+		searchStore, webhookGuard)
 	ulHandler := unified_logging.NewHandler(ulManager)
+	if s.Configuration.AlertCheckInterval > 0 {
+		alertWorker := unified_logging.NewAlertWorker(ulManager, unified_logging.NewWebhookNotifier(webhookGuard), s.Configuration.AlertCheckInterval)
+		alertWorker.Start()
+		defer alertWorker.Stop()
+	}
 
 	ecManager := ec.NewManager(clients.eicClient, clients.agentClient)
 	ecHandler := ec.NewHandler(ecManager)
@@ -324,8 +459,61 @@ func (s *Service) LaunchGRPC(authConfig *interceptor.AuthorizationConfig) error
 	settingsManager := organization_settings.NewManager(clients.orgClient)
 	settingsHandler := organization_settings.NewHandler(settingsManager)
 
-	grpcServer := grpc.NewServer(interceptor.WithServerAuthxInterceptor(
-		interceptor.NewConfig(authConfig, s.Configuration.AuthSecret, s.Configuration.AuthHeader)))
+	// The asynchronous operations are recorded by an interceptor and refreshed with the upstream components.
+	opRegistry := operations.NewRegistry(operations.DefaultRetention, operations.DefaultMaxAge,
+		operations.DefaultPollInterval)
+	opRegistry.RegisterChecker(operations.ClusterInstall, operations.NewClusterChecker(clients.clusClient, false))
+	opRegistry.RegisterChecker(operations.ClusterUninstall, operations.NewClusterChecker(clients.clusClient, true))
+	opRegistry.RegisterChecker(operations.ClusterDecommission, operations.NewClusterChecker(clients.clusClient, true))
+	opRegistry.RegisterChecker(operations.ClusterProvision, operations.NewProvisionChecker(clients.provisionerClient))
+	opRegistry.RegisterChecker(operations.ClusterScale, operations.NewProvisionChecker(clients.provisionerClient))
+	opRegistry.RegisterCanceller(operations.ClusterProvision, operations.NewProvisionCanceller(clients.provisionerClient))
+	opRegistry.RegisterChecker(operations.AgentInstall, operations.NewControllerOpChecker(clients.invClient))
+	opRegistry.RegisterChecker(operations.AgentUninstall, operations.NewControllerOpChecker(clients.invClient))
+	opRegistry.RegisterChecker(operations.MonitoringActivation, operations.NewAssetOpChecker(clients.invClient))
+	opRegistry.RegisterChecker(operations.LogDownload, operations.NewLogDownloadChecker(clients.logDownloadClient))
+	opRegistry.RegisterCanceller(operations.LogDownload, operations.NewLogDownloadCanceller(clients.logDownloadClient))
+	labelsManager := labels.NewManager(clients.clusClient, clients.nodeClient, clients.infraClient, clients.deviceClient,
+		clients.invClient, clients.eicClient, clients.appClient, resourceCache)
+	labelsHandler := labels.NewHandler(labelsManager)
+
+	opManager := operations.NewManager(opRegistry)
+	opHandler := operations.NewHandler(opManager)
+
+	authxConfig := interceptor.NewConfig(authConfig, s.Configuration.AuthSecret, s.Configuration.AuthHeader)
+	interceptors := []grpc.UnaryServerInterceptor{tracing.UnaryServerInterceptor()}
+	// The authx interceptor only covers unary calls, the streams are authorized by their own interceptor.
+	streamInterceptors := []grpc.StreamServerInterceptor{streamauth.StreamServerInterceptor(authxConfig)}
+	if s.Configuration.RateLimitEnabled {
+		rateLimitConfig, rlErr := s.Configuration.LoadRateLimitConfig()
+		if rlErr != nil {
+			log.Fatal().Str("err", rlErr.DebugReport()).Msg("cannot load rate limit config")
+			return rlErr
+		}
+		limiter := ratelimit.NewLimiter(rateLimitConfig)
+		interceptors = append(interceptors, ratelimit.UnaryServerInterceptor(limiter))
+		streamInterceptors = append(streamInterceptors, ratelimit.StreamServerInterceptor(limiter))
+	}
+	// Replayed responses skip the operations interceptor so the operation is not recorded twice.
+	interceptors = append(interceptors, idempotency.UnaryServerInterceptor(idempotency.NewStore(s.Configuration.IdempotencyWindow)))
+	interceptors = append(interceptors, operations.UnaryServerInterceptor(opRegistry))
+
+	// The authx interceptor always runs first, the chained interceptors run afterwards in order.
+	serverOpts := []grpc.ServerOption{
+		interceptor.WithServerAuthxInterceptor(authxConfig),
+		grpc.ChainUnaryInterceptor(interceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	}
+	if s.certLoader != nil {
+		tlsConfig, tErr := s.GetServerTLSConfig(s.certLoader, s.Configuration.TLSRequireClientCert)
+		if tErr != nil {
+			log.Fatal().Str("err", tErr.DebugReport()).Msg("cannot create gRPC TLS configuration")
+			return tErr
+		}
+		serverOpts = append(serverOpts, grpc.Creds(credentials.NewTLS(WithGatewayExemption(tlsConfig, s.gatewayListener.Addr()))))
+	}
+
+	grpcServer := grpc.NewServer(serverOpts...)
 	grpc_public_api_go.RegisterOrganizationsServer(grpcServer, orgHandler)
 	grpc_public_api_go.RegisterClustersServer(grpcServer, clusHandler)
 	grpc_public_api_go.RegisterNodesServer(grpcServer, nodesHandler)
@@ -343,12 +531,25 @@ func (s *Service) LaunchGRPC(authConfig *interceptor.AuthorizationConfig) error
 	grpc_public_api_go.RegisterApplicationNetworkServer(grpcServer, appNetHandler)
 	grpc_public_api_go.RegisterProvisionServer(grpcServer, provHandler)
 	grpc_public_api_go.RegisterOrganizationSettingsServer(grpcServer, settingsHandler)
+	grpc_public_api_go.RegisterOperationsServer(grpcServer, opHandler)
+	grpc_public_api_go.RegisterLabelsServer(grpcServer, labelsHandler)
+
+	if s.mock != nil {
+		allowRegisteredMethods(authConfig, grpcServer)
+	}
 
 	if s.Configuration.Debug {
 		log.Info().Msg("Enabling gRPC server reflection")
 		// Register reflection service on gRPC server.
 		reflection.Register(grpcServer)
 	}
+	if s.gatewayListener != nil {
+		go func() {
+			if err := grpcServer.Serve(s.gatewayListener); err != nil {
+				log.Fatal().Errs("failed to serve the HTTP gateway: %v", []error{err})
+			}
+		}()
+	}
 	log.Info().Int("port", s.Configuration.Port).Msg("Launching gRPC server")
 	if err := grpcServer.Serve(lis); err != nil {
 		log.Fatal().Errs("failed to serve: %v", []error{err})
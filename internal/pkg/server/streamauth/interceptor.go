@@ -0,0 +1,123 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package streamauth
+
+import (
+	"context"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/authx/pkg/token"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/authhelper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strings"
+)
+
+// authorizedStream replaces the context of a server stream with the one containing the authx metadata.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the context with the metadata extracted from the token.
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor validates the JWT of the streaming calls. The authx interceptor only covers unary calls,
+// so the token is checked here with the configuration given to the authx interceptor, and the metadata expected by
+// authhelper.GetRequestMetadata is rebuilt from the claims. Any user or organization sent by the client is
+// discarded.
+func StreamServerInterceptor(config *interceptor.Config) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(ss.Context(), config, info.FullMethod)
+		if err != nil {
+			return conversions.ToGRPCError(err)
+		}
+		return handler(srv, &authorizedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authorize checks the token and the permissions of a method and returns the context with the authx metadata.
+func authorize(ctx context.Context, config *interceptor.Config, method string) (context.Context, derrors.Error) {
+	permission, found := config.Authorization.Permissions[method]
+	if !found && !config.Authorization.AllowsAll {
+		return nil, derrors.NewPermissionDeniedError("unauthorized method").WithParams(method)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	raw := md.Get(config.Header)
+	if len(raw) == 0 || raw[0] == "" {
+		return nil, derrors.NewUnauthenticatedError("token is not supplied")
+	}
+	claim := &token.Claim{}
+	parsed, err := jwt.ParseWithClaims(raw[0], claim, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, derrors.NewUnauthenticatedError("unexpected signing method").WithParams(t.Header["alg"])
+		}
+		return []byte(config.Secret), nil
+	})
+	if err != nil {
+		return nil, derrors.NewUnauthenticatedError("token is not valid", err)
+	}
+	if !parsed.Valid {
+		return nil, derrors.NewUnauthenticatedError("token is not valid")
+	}
+	if found && !allowed(permission, claim.Primitives) {
+		return nil, derrors.NewPermissionDeniedError("unauthorized method").WithParams(method)
+	}
+
+	authorized := metadata.MD{}
+	for key, values := range md {
+		authorized[key] = values
+	}
+	delete(authorized, authhelper.UserIdField)
+	delete(authorized, authhelper.OrganizationIdField)
+	for name := range grpc_authx_go.AccessPrimitive_value {
+		delete(authorized, strings.ToLower(name))
+	}
+	authorized.Set(authhelper.UserIdField, claim.UserID)
+	authorized.Set(authhelper.OrganizationIdField, claim.OrganizationID)
+	for _, primitive := range claim.Primitives {
+		authorized.Set(strings.ToLower(primitive), "true")
+	}
+	return metadata.NewIncomingContext(ctx, authorized), nil
+}
+
+// allowed checks that the primitives contain all the required ones and at least one of the optional ones.
+func allowed(permission interceptor.Permission, primitives []string) bool {
+	granted := make(map[string]bool, len(primitives))
+	for _, primitive := range primitives {
+		granted[primitive] = true
+	}
+	for _, must := range permission.Must {
+		if !granted[must] {
+			return false
+		}
+	}
+	if len(permission.Should) == 0 {
+		return true
+	}
+	for _, should := range permission.Should {
+		if granted[should] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/nalej/derrors"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertificateCheckInterval with the minimum time between two checks of the certificate files on disk.
+const CertificateCheckInterval = time.Second * 30
+
+// CertificateLoader keeps the server certificate in memory and reloads it when the files on disk are rotated.
+type CertificateLoader struct {
+	certPath    string
+	keyPath     string
+	mutex       sync.RWMutex
+	certificate *tls.Certificate
+	certModTime time.Time
+	keyModTime  time.Time
+	lastCheck   time.Time
+}
+
+// NewCertificateLoader creates a CertificateLoader and performs the initial load of the certificate.
+func NewCertificateLoader(certPath string, keyPath string) (*CertificateLoader, derrors.Error) {
+	loader := &CertificateLoader{
+		certPath: certPath,
+		keyPath:  keyPath,
+	}
+	err := loader.reload()
+	if err != nil {
+		return nil, err
+	}
+	return loader, nil
+}
+
+// reload reads the certificate and key files and replaces the in-memory certificate.
+func (cl *CertificateLoader) reload() derrors.Error {
+	certInfo, err := os.Stat(cl.certPath)
+	if err != nil {
+		return derrors.AsError(err, "cannot access TLS certificate")
+	}
+	keyInfo, err := os.Stat(cl.keyPath)
+	if err != nil {
+		return derrors.AsError(err, "cannot access TLS private key")
+	}
+	certificate, err := tls.LoadX509KeyPair(cl.certPath, cl.keyPath)
+	if err != nil {
+		return derrors.AsError(err, "cannot load TLS certificate")
+	}
+	cl.mutex.Lock()
+	cl.certificate = &certificate
+	cl.certModTime = certInfo.ModTime()
+	cl.keyModTime = keyInfo.ModTime()
+	cl.lastCheck = time.Now()
+	cl.mutex.Unlock()
+	log.Info().Str("certPath", cl.certPath).Msg("TLS certificate loaded")
+	return nil
+}
+
+// modified checks whether the certificate files have changed since the last load.
+func (cl *CertificateLoader) modified() bool {
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	if time.Since(cl.lastCheck) < CertificateCheckInterval {
+		return false
+	}
+	certInfo, certErr := os.Stat(cl.certPath)
+	keyInfo, keyErr := os.Stat(cl.keyPath)
+	if certErr != nil || keyErr != nil {
+		// Files may be temporarily missing while they are being rotated.
+		return false
+	}
+	return !certInfo.ModTime().Equal(cl.certModTime) || !keyInfo.ModTime().Equal(cl.keyModTime)
+}
+
+// Current returns the certificate being served, reloading it first if the files have been rotated. If the new
+// files cannot be loaded, the previous certificate is kept.
+func (cl *CertificateLoader) Current() *tls.Certificate {
+	if cl.modified() {
+		err := cl.reload()
+		if err != nil {
+			log.Warn().Str("trace", err.DebugReport()).Msg("cannot reload TLS certificate, keeping the previous one")
+			cl.mutex.Lock()
+			cl.lastCheck = time.Now()
+			cl.mutex.Unlock()
+		}
+	}
+	cl.mutex.RLock()
+	defer cl.mutex.RUnlock()
+	return cl.certificate
+}
+
+// GetCertificate implements the tls.Config callback for servers.
+func (cl *CertificateLoader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cl.Current(), nil
+}
+
+// VerifySelf checks that the certificate presented by the peer is the one currently served by this process. The
+// HTTP gateway uses it on the internal hop as it always connects to its own gRPC listener.
+func (cl *CertificateLoader) VerifySelf(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	current := cl.Current()
+	if len(rawCerts) == 0 || len(current.Certificate) == 0 || !bytes.Equal(rawCerts[0], current.Certificate[0]) {
+		return derrors.NewPermissionDeniedError("unexpected certificate on the internal gateway connection")
+	}
+	return nil
+}
+
+// loadCertPool reads a PEM encoded CA file into a certificate pool.
+func loadCertPool(caPath string) (*x509.CertPool, derrors.Error) {
+	content, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read client CA certificate")
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(content) {
+		return nil, derrors.NewInvalidArgumentError("cannot add client CA certificate to the pool").WithParams(caPath)
+	}
+	return pool, nil
+}
+
+// GetServerTLSConfig returns the TLS configuration of a listener. Client certificates are required if
+// requireClientCert is set, otherwise they are verified only when the client presents them.
+func (s *Service) GetServerTLSConfig(loader *CertificateLoader, requireClientCert bool) (*tls.Config, derrors.Error) {
+	config := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: loader.GetCertificate,
+	}
+	if s.Configuration.TLSClientCAPath != "" {
+		pool, err := loadCertPool(s.Configuration.TLSClientCAPath)
+		if err != nil {
+			return nil, err
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+		if requireClientCert {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	}
+	return config, nil
+}
+
+// WithGatewayExemption returns a copy of the TLS configuration of the gRPC listener that does not ask for client
+// certificates on the connections accepted by the internal listener of the HTTP gateway. The server certificate
+// is not signed by the client CA, so the gateway cannot authenticate itself as a client. The internal listener
+// only accepts local connections, and the requests it receives are the ones the HTTP listener already accepts
+// without a client certificate.
+func WithGatewayExemption(config *tls.Config, gatewayAddr net.Addr) *tls.Config {
+	exempt := config.Clone()
+	exempt.ClientAuth = tls.NoClientCert
+	exempt.ClientCAs = nil
+	// gRPC negotiates HTTP/2 with ALPN, and only adds it to the configuration it receives.
+	exempt.NextProtos = []string{"h2"}
+	result := config.Clone()
+	result.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		if hello.Conn != nil && hello.Conn.LocalAddr().String() == gatewayAddr.String() {
+			return exempt, nil
+		}
+		return nil, nil
+	}
+	return result
+}
+
+// GetGatewayTLSConfig returns the TLS configuration used by the HTTP gateway to reach the internal gRPC listener.
+func (s *Service) GetGatewayTLSConfig(loader *CertificateLoader) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// The standard chain verification is replaced by VerifySelf as the gateway always dials the local
+		// listener, and the certificate is not required to be valid for localhost.
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: loader.VerifySelf,
+	}
+}
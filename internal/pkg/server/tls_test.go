@@ -0,0 +1,234 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// testAuthority signs the certificates used by the tests.
+type testAuthority struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+	pem         []byte
+}
+
+func newTestAuthority(name string) *testAuthority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	gomega.Expect(err).To(gomega.Succeed())
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	gomega.Expect(err).To(gomega.Succeed())
+	certificate, err := x509.ParseCertificate(raw)
+	gomega.Expect(err).To(gomega.Succeed())
+	return &testAuthority{
+		certificate: certificate,
+		key:         key,
+		pem:         pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+	}
+}
+
+// issue returns the PEM certificate and key of a server and client certificate signed by the authority.
+func (ta *testAuthority) issue(serial int64) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	gomega.Expect(err).To(gomega.Succeed())
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "public-api"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ta.certificate, &key.PublicKey, ta.key)
+	gomega.Expect(err).To(gomega.Succeed())
+	rawKey, err := x509.MarshalECPrivateKey(key)
+	gomega.Expect(err).To(gomega.Succeed())
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: rawKey})
+}
+
+// handshake connects a client to a TLS listener and returns the errors of both ends. The configuration of the
+// listener is created once its address is known.
+func handshake(serverConfig func(addr net.Addr) *tls.Config, clientConfig *tls.Config) (error, error) {
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	gomega.Expect(err).To(gomega.Succeed())
+	listener := tls.NewListener(tcpListener, serverConfig(tcpListener.Addr()))
+	defer listener.Close()
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- conn.(*tls.Conn).Handshake()
+	}()
+	conn, clientErr := tls.Dial("tcp", listener.Addr().String(), clientConfig)
+	if clientErr == nil {
+		// The client certificate is verified by the server after the client completes its handshake.
+		_, clientErr = conn.Read(make([]byte, 1))
+		if clientErr == io.EOF {
+			clientErr = nil
+		}
+		conn.Close()
+	}
+	return <-serverErr, clientErr
+}
+
+var _ = ginkgo.Describe("TLS", func() {
+
+	var dir string
+	var certPath string
+	var keyPath string
+	var authority *testAuthority
+
+	write := func(cert []byte, key []byte, modTime time.Time) {
+		gomega.Expect(ioutil.WriteFile(certPath, cert, 0600)).To(gomega.Succeed())
+		gomega.Expect(ioutil.WriteFile(keyPath, key, 0600)).To(gomega.Succeed())
+		gomega.Expect(os.Chtimes(certPath, modTime, modTime)).To(gomega.Succeed())
+		gomega.Expect(os.Chtimes(keyPath, modTime, modTime)).To(gomega.Succeed())
+	}
+
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "tls")
+		gomega.Expect(err).To(gomega.Succeed())
+		certPath = filepath.Join(dir, "tls.crt")
+		keyPath = filepath.Join(dir, "tls.key")
+		authority = newTestAuthority("public-api CA")
+	})
+
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	ginkgo.It("should reload the certificate after a rotation", func() {
+		cert, key := authority.issue(2)
+		write(cert, key, time.Now().Add(-time.Minute))
+		loader, err := NewCertificateLoader(certPath, keyPath)
+		gomega.Expect(err).To(gomega.Succeed())
+		initial := loader.Current()
+
+		rotatedCert, rotatedKey := authority.issue(3)
+		write(rotatedCert, rotatedKey, time.Now())
+		// The files are not checked again until the interval has passed.
+		gomega.Expect(loader.Current()).To(gomega.Equal(initial))
+		loader.lastCheck = time.Time{}
+		rotated := loader.Current()
+		gomega.Expect(rotated.Certificate[0]).NotTo(gomega.Equal(initial.Certificate[0]))
+		block, _ := pem.Decode(rotatedCert)
+		gomega.Expect(rotated.Certificate[0]).To(gomega.Equal(block.Bytes))
+
+		// A rotation in progress keeps the previous certificate.
+		write(rotatedCert, key, time.Now().Add(time.Minute))
+		loader.lastCheck = time.Time{}
+		gomega.Expect(loader.Current()).To(gomega.Equal(rotated))
+	})
+
+	// mutualTLS returns a service requiring client certificates signed by a private client CA, which did not sign
+	// the server certificate.
+	mutualTLS := func() (*Service, *CertificateLoader, *testAuthority) {
+		cert, key := authority.issue(2)
+		write(cert, key, time.Now())
+		clientAuthority := newTestAuthority("client CA")
+		caPath := filepath.Join(dir, "client-ca.crt")
+		gomega.Expect(ioutil.WriteFile(caPath, clientAuthority.pem, 0600)).To(gomega.Succeed())
+		service := &Service{Configuration: Config{TLSCertPath: certPath, TLSKeyPath: keyPath, TLSClientCAPath: caPath,
+			TLSRequireClientCert: true}}
+		loader, err := NewCertificateLoader(certPath, keyPath)
+		gomega.Expect(err).To(gomega.Succeed())
+		return service, loader, clientAuthority
+	}
+
+	ginkgo.It("should accept the gateway on its listener when client certificates are required", func() {
+		service, loader, _ := mutualTLS()
+		serverConfig, err := service.GetServerTLSConfig(loader, true)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		serverErr, clientErr := handshake(func(addr net.Addr) *tls.Config {
+			return WithGatewayExemption(serverConfig, addr)
+		}, service.GetGatewayTLSConfig(loader))
+		gomega.Expect(serverErr).To(gomega.Succeed())
+		gomega.Expect(clientErr).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should require client certificates outside the gateway listener", func() {
+		service, loader, clientAuthority := mutualTLS()
+		serverConfig, err := service.GetServerTLSConfig(loader, true)
+		gomega.Expect(err).To(gomega.Succeed())
+		gatewayAddr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+		exempted := func(net.Addr) *tls.Config {
+			return WithGatewayExemption(serverConfig, gatewayAddr)
+		}
+
+		serverErr, _ := handshake(exempted, service.GetGatewayTLSConfig(loader))
+		gomega.Expect(serverErr).NotTo(gomega.Succeed())
+
+		clientCert, clientKey := clientAuthority.issue(3)
+		certificate, tlsErr := tls.X509KeyPair(clientCert, clientKey)
+		gomega.Expect(tlsErr).To(gomega.Succeed())
+		clientConfig := service.GetGatewayTLSConfig(loader)
+		clientConfig.Certificates = []tls.Certificate{certificate}
+		serverErr, clientErr := handshake(exempted, clientConfig)
+		gomega.Expect(serverErr).To(gomega.Succeed())
+		gomega.Expect(clientErr).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject a listener with a certificate of another CA on the gateway hop", func() {
+		cert, key := authority.issue(2)
+		write(cert, key, time.Now())
+		loader, err := NewCertificateLoader(certPath, keyPath)
+		gomega.Expect(err).To(gomega.Succeed())
+		service := &Service{Configuration: Config{TLSCertPath: certPath, TLSKeyPath: keyPath}}
+
+		otherCert, otherKey := newTestAuthority("other CA").issue(2)
+		other, tlsErr := tls.X509KeyPair(otherCert, otherKey)
+		gomega.Expect(tlsErr).To(gomega.Succeed())
+		serverConfig := &tls.Config{MinVersion: tls.VersionTLS12, Certificates: []tls.Certificate{other}}
+
+		_, clientErr := handshake(func(net.Addr) *tls.Config { return serverConfig }, service.GetGatewayTLSConfig(loader))
+		gomega.Expect(clientErr).NotTo(gomega.Succeed())
+		gomega.Expect(clientErr.Error()).To(gomega.ContainSubstring("unexpected certificate"))
+	})
+
+})
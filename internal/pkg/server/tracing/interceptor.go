@@ -0,0 +1,135 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+)
+
+// MetadataCarrier adapts gRPC metadata to the OpenTelemetry propagation API.
+type MetadataCarrier struct {
+	md metadata.MD
+}
+
+// Get returns the first value associated with a key.
+func (mc MetadataCarrier) Get(key string) string {
+	values := mc.md.Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set replaces the values associated with a key.
+func (mc MetadataCarrier) Set(key string, value string) {
+	mc.md.Set(key, value)
+}
+
+// Keys returns the keys stored in the metadata.
+func (mc MetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(mc.md))
+	for key := range mc.md {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// GatewayHeaderMatcher forwards the W3C trace context headers received by the HTTP gateway as gRPC metadata, so
+// HTTP clients can join the trace. The rest of headers follow the default gateway rules.
+func GatewayHeaderMatcher(key string) (string, bool) {
+	switch strings.ToLower(key) {
+	case "traceparent", "tracestate", "baggage":
+		return strings.ToLower(key), true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// splitMethod extracts the service and method names from a full gRPC method name.
+func splitMethod(fullMethod string) (string, string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	pos := strings.LastIndex(name, "/")
+	if pos < 0 {
+		return "", name
+	}
+	return name[:pos], name[pos+1:]
+}
+
+// methodAttributes returns the standard RPC attributes of a span.
+func methodAttributes(fullMethod string) []attribute.KeyValue {
+	service, method := splitMethod(fullMethod)
+	return []attribute.KeyValue{
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	}
+}
+
+// endSpan records the result of the call and ends the span.
+func endSpan(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int64("rpc.grpc.status_code", int64(code)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+	span.End()
+}
+
+// UnaryServerInterceptor starts a server span for each incoming request, continuing the trace found in the
+// request metadata if any. The span is available in the context received by the handler.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, ok := metadata.FromIncomingContext(ctx)
+		if !ok {
+			md = metadata.MD{}
+		}
+		ctx = otel.GetTextMapPropagator().Extract(ctx, MetadataCarrier{md})
+		ctx, span := Tracer().Start(ctx, strings.TrimPrefix(info.FullMethod, "/"),
+			trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(methodAttributes(info.FullMethod)...))
+		resp, err := handler(ctx, req)
+		endSpan(span, err)
+		return resp, err
+	}
+}
+
+// UnaryClientInterceptor starts a client span for each outgoing call and injects the trace context in the
+// outgoing metadata so the upstream component can continue the trace.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := Tracer().Start(ctx, strings.TrimPrefix(method, "/"),
+			trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(methodAttributes(method)...))
+		md, ok := metadata.FromOutgoingContext(ctx)
+		if ok {
+			md = md.Copy()
+		} else {
+			md = metadata.MD{}
+		}
+		otel.GetTextMapPropagator().Inject(ctx, MetadataCarrier{md})
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		endSpan(span, err)
+		return err
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var _ = ginkgo.Describe("Tracing interceptors", func() {
+
+	var recorder *tracetest.SpanRecorder
+
+	ginkgo.BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	ginkgo.It("should propagate the trace context from the client to the server", func() {
+		var outgoing metadata.MD
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			outgoing, _ = metadata.FromOutgoingContext(ctx)
+			return nil
+		}
+		clientCtx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("userid", "user"))
+		err := UnaryClientInterceptor()(clientCtx, "/public_api.Clusters/List", nil, nil, nil, invoker)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(outgoing.Get("traceparent")).ShouldNot(gomega.BeEmpty())
+		gomega.Expect(outgoing.Get("userid")).Should(gomega.Equal([]string{"user"}))
+
+		var handlerSpan trace.SpanContext
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerSpan = trace.SpanContextFromContext(ctx)
+			return nil, nil
+		}
+		serverCtx := metadata.NewIncomingContext(context.Background(), outgoing)
+		info := &grpc.UnaryServerInfo{FullMethod: "/public_api.Clusters/List"}
+		_, err = UnaryServerInterceptor()(serverCtx, nil, info, handler)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		spans := recorder.Ended()
+		gomega.Expect(spans).Should(gomega.HaveLen(2))
+		clientSpan, serverSpan := spans[0], spans[1]
+		gomega.Expect(clientSpan.SpanKind()).Should(gomega.Equal(trace.SpanKindClient))
+		gomega.Expect(serverSpan.SpanKind()).Should(gomega.Equal(trace.SpanKindServer))
+		gomega.Expect(serverSpan.Parent().SpanID()).Should(gomega.Equal(clientSpan.SpanContext().SpanID()))
+		gomega.Expect(handlerSpan.TraceID()).Should(gomega.Equal(clientSpan.SpanContext().TraceID()))
+	})
+
+	ginkgo.It("should record failed calls", func() {
+		invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return status.Error(codes.Unavailable, "upstream not available")
+		}
+		err := UnaryClientInterceptor()(context.Background(), "/infrastructure.Nodes/ListNodes", nil, nil, nil, invoker)
+		gomega.Expect(status.Code(err)).Should(gomega.Equal(codes.Unavailable))
+		spans := recorder.Ended()
+		gomega.Expect(spans).Should(gomega.HaveLen(1))
+		gomega.Expect(spans[0].Name()).Should(gomega.Equal("infrastructure.Nodes/ListNodes"))
+		gomega.Expect(spans[0].Events()).ShouldNot(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should forward trace headers on the gateway", func() {
+		key, ok := GatewayHeaderMatcher("Traceparent")
+		gomega.Expect(ok).Should(gomega.BeTrue())
+		gomega.Expect(key).Should(gomega.Equal("traceparent"))
+	})
+})
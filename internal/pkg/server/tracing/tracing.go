@@ -0,0 +1,101 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+const (
+	// NoneExporter disables the export of spans. Trace context is still propagated.
+	NoneExporter = "none"
+	// StdoutExporter writes the spans to the standard output.
+	StdoutExporter = "stdout"
+	// OTLPExporter sends the spans to an OTLP/HTTP collector.
+	OTLPExporter = "otlp"
+)
+
+// ServiceName with the name used to identify the spans of this component.
+const ServiceName = "public-api"
+
+// InstrumentationName with the name of the tracer.
+const InstrumentationName = "github.com/nalej/public-api"
+
+// ShutdownTimeout with the maximum time to flush the pending spans when the service stops.
+const ShutdownTimeout = time.Second * 5
+
+// ValidExporter checks whether an exporter name is supported.
+func ValidExporter(exporter string) bool {
+	return exporter == NoneExporter || exporter == StdoutExporter || exporter == OTLPExporter
+}
+
+// Tracer returns the tracer used by the public API.
+func Tracer() trace.Tracer {
+	return otel.Tracer(InstrumentationName)
+}
+
+// Setup registers the global tracer provider and the W3C trace context propagator. The endpoint is only used by
+// the OTLP exporter. The returned function flushes the pending spans and must be called on shutdown.
+func Setup(exporter string, endpoint string) (func(), derrors.Error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case NoneExporter:
+		return func() {}, nil
+	case StdoutExporter:
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, derrors.AsError(err, "cannot create stdout trace exporter")
+		}
+		spanExporter = stdoutExporter
+	case OTLPExporter:
+		otlpExporter, err := otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpoint(endpoint), otlptracehttp.WithInsecure())
+		if err != nil {
+			return nil, derrors.AsError(err, "cannot create OTLP trace exporter")
+		}
+		spanExporter = otlpExporter
+	default:
+		return nil, derrors.NewInvalidArgumentError("unsupported trace exporter").WithParams(exporter)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", ServiceName))))
+	otel.SetTracerProvider(provider)
+	log.Info().Str("exporter", exporter).Msg("tracing enabled")
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warn().Err(err).Msg("cannot flush pending spans")
+		}
+	}, nil
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// DefaultAlertCheckInterval with the time between two checks of the alert rules that are due.
+const DefaultAlertCheckInterval = time.Second * 30
+
+// DefaultAlertEvaluationTimeout with the deadline of an evaluation, including the query and the notification.
+const DefaultAlertEvaluationTimeout = time.Minute
+
+// DefaultAlertConcurrency with the number of rules evaluated at the same time.
+const DefaultAlertConcurrency = 8
+
+// AlertWorker evaluates the alert rules on their schedule. Each evaluation runs the saved search of the rule, and
+// the webhook is notified when the number of matched entries crosses the threshold in either direction.
+type AlertWorker struct {
+	manager  Manager
+	notifier *WebhookNotifier
+	// interval with the time between two checks of the rules that are due.
+	interval time.Duration
+	// timeout with the deadline of each evaluation.
+	timeout time.Duration
+	// concurrency with the number of rules evaluated at the same time.
+	concurrency int
+	// ctx is cancelled when the worker is stopped, aborting the evaluations in progress.
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewAlertWorker creates a worker that evaluates the alert rules kept by a manager.
+func NewAlertWorker(manager Manager, notifier *WebhookNotifier, interval time.Duration) *AlertWorker {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AlertWorker{
+		manager:     manager,
+		notifier:    notifier,
+		interval:    interval,
+		timeout:     DefaultAlertEvaluationTimeout,
+		concurrency: DefaultAlertConcurrency,
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+}
+
+// Start launches the evaluation loop in the background.
+func (w *AlertWorker) Start() {
+	go w.run()
+}
+
+// Stop ends the evaluation loop, cancelling the evaluations in progress, and waits for them to finish.
+func (w *AlertWorker) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *AlertWorker) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case now := <-ticker.C:
+			w.checkRules(now)
+		}
+	}
+}
+
+// checkRules evaluates the rules that are due, a limited number of them at the same time, and waits for all of
+// them to finish. Each evaluation has its own deadline so a slow backend or webhook does not delay the rest.
+func (w *AlertWorker) checkRules(now time.Time) {
+	slots := make(chan struct{}, w.concurrency)
+	var wg sync.WaitGroup
+	for _, rule := range w.manager.searches.dueRules(now) {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(rule storedRule) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			ctx, cancel := context.WithTimeout(w.ctx, w.timeout)
+			defer cancel()
+			w.evaluate(ctx, rule, now)
+		}(rule)
+	}
+	wg.Wait()
+}
+
+// truncatedEvaluation with the error recorded when the window of a rule has more entries than a query can scan.
+const truncatedEvaluation = "the window has more log entries than a query can scan, narrow the saved search or the interval"
+
+// evaluate runs the saved search of a rule over its window and records the result. If the notification fails the
+// state is kept, so it is sent again on the next evaluation.
+func (w *AlertWorker) evaluate(ctx context.Context, stored storedRule, now time.Time) {
+	rule := stored.Rule
+	rule.LastRun = now.Unix()
+	search, err := w.manager.searches.GetSearch(&grpc_public_api_go.SavedSearchId{
+		OrganizationId: rule.OrganizationId,
+		SavedSearchId:  rule.SavedSearchId,
+	})
+	if err != nil {
+		w.record(rule, err.Error())
+		return
+	}
+	window := search.Since
+	if window == "" {
+		window = rule.Interval
+	}
+	duration, err := timeexpr.ParseDuration(window)
+	if err != nil {
+		w.record(rule, err.Error())
+		return
+	}
+
+	from := now.Add(-duration).UnixNano()
+	to := now.UnixNano()
+	result, qErr := w.manager.Query(ctx, &grpc_public_api_go.LogQueryRequest{
+		OrganizationId: rule.OrganizationId,
+		Query:          search.Query,
+		From:           from,
+		To:             to,
+		Limit:          1,
+	})
+	if qErr != nil {
+		w.record(rule, qErr.Error())
+		return
+	}
+	rule.LastCount = result.Matched
+	// A truncated query may miss matches, so only a count reaching the threshold is conclusive.
+	if result.Truncated && result.Matched < rule.Threshold {
+		w.record(rule, truncatedEvaluation)
+		return
+	}
+
+	state, event := rule.State, ""
+	switch {
+	case result.Matched >= rule.Threshold && rule.State != AlertStateFiring:
+		state, event = AlertStateFiring, AlertEventFiring
+	case result.Matched < rule.Threshold && rule.State == AlertStateFiring:
+		state, event = AlertStateOK, AlertEventResolved
+	}
+	if event != "" {
+		secret, sErr := w.manager.searches.webhookSecret(stored)
+		if sErr != nil {
+			w.record(rule, sErr.Error())
+			return
+		}
+		nErr := w.notifier.Notify(ctx, rule.WebhookUrl, secret, AlertEvent{
+			Event:          event,
+			OrganizationID: rule.OrganizationId,
+			AlertRuleID:    rule.AlertRuleId,
+			Name:           rule.Name,
+			SavedSearchID:  rule.SavedSearchId,
+			Query:          search.Query,
+			Count:          result.Matched,
+			Threshold:      rule.Threshold,
+			From:           from,
+			To:             to,
+			Timestamp:      now.Unix(),
+		})
+		if nErr != nil {
+			w.record(rule, nErr.Error())
+			return
+		}
+		rule.State = state
+		rule.LastNotified = now.Unix()
+	}
+	w.record(rule, "")
+}
+
+// record stores the result of an evaluation.
+func (w *AlertWorker) record(rule *grpc_public_api_go.AlertRule, lastError string) {
+	rule.LastError = lastError
+	if lastError != "" {
+		log.Warn().Str("organizationID", rule.OrganizationId).Str("alertRuleID", rule.AlertRuleId).
+			Str("err", lastError).Msg("alert rule evaluation failed")
+	}
+	if err := w.manager.searches.updateRule(rule); err != nil {
+		log.Error().Str("alertRuleID", rule.AlertRuleId).Str("err", err.DebugReport()).Msg("cannot record alert rule evaluation")
+	}
+}
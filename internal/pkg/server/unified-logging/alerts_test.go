@@ -0,0 +1,296 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// webhookStandIn records the alert events received by a local webhook.
+type webhookStandIn struct {
+	sync.Mutex
+	events []AlertEvent
+	// failures with the number of requests answered with an error before accepting them.
+	failures int
+}
+
+func (w *webhookStandIn) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	w.Lock()
+	defer w.Unlock()
+	if w.failures > 0 {
+		w.failures--
+		writer.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, err := ioutil.ReadAll(request.Body)
+	gomega.Expect(err).To(gomega.Succeed())
+	event := AlertEvent{}
+	gomega.Expect(json.Unmarshal(body, &event)).To(gomega.Succeed())
+	w.events = append(w.events, event)
+	gomega.Expect(request.Header.Get(SignatureHeader)).To(gomega.Equal(Signature("secret", body)))
+}
+
+func (w *webhookStandIn) received() []AlertEvent {
+	w.Lock()
+	defer w.Unlock()
+	return append([]AlertEvent{}, w.events...)
+}
+
+var _ = ginkgo.Describe("Alert worker", func() {
+
+	var platform *fakes.Platform
+	var webhook *webhookStandIn
+	var webhookServer *httptest.Server
+	var manager Manager
+	var worker *AlertWorker
+	var organizationID string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+		organizationID = ithelpers.GenerateUUID()
+		webhook = &webhookStandIn{}
+		webhookServer = httptest.NewServer(webhook)
+
+		// The local webhook is only reachable because the operator allows it.
+		guard := NewWebhookGuard([]string{"127.0.0.1"})
+		searches, err := NewSearchStore("", "secret")
+		gomega.Expect(err).To(gomega.Succeed())
+		manager = NewManager(grpc_application_manager_go.NewUnifiedLoggingClient(platform.Conn()),
+			grpc_log_download_manager_go.NewLogDownloadManagerClient(platform.Conn()),
+			grpc_application_manager_go.NewApplicationManagerClient(platform.Conn()),
+			grpc_organization_manager_go.NewOrganizationsClient(platform.Conn()),
+			searches, guard)
+		notifier := NewWebhookNotifier(guard)
+		notifier.retrySleep = time.Millisecond
+		worker = NewAlertWorker(manager, notifier, time.Hour)
+	})
+
+	ginkgo.AfterEach(func() {
+		webhookServer.Close()
+		platform.Stop()
+	})
+
+	addRule := func(threshold int64) *grpc_public_api_go.AlertRule {
+		search, err := manager.searches.AddSearch(&grpc_public_api_go.AddSavedSearchRequest{
+			OrganizationId: organizationID, Name: "errors", Query: "severity:error"})
+		gomega.Expect(err).To(gomega.Succeed())
+		rule, err := manager.searches.AddRule(&grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+			SavedSearchId: search.SavedSearchId, Name: "errors", Interval: "1m", Threshold: threshold,
+			WebhookUrl: webhookServer.URL, WebhookSecret: "secret"})
+		gomega.Expect(err).To(gomega.Succeed())
+		return rule
+	}
+
+	ginkgo.It("should notify when the threshold is crossed and when it recovers", func() {
+		rule := addRule(2)
+		now := time.Now()
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second * 2).UnixNano(), Msg: "ERROR: disk full"},
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second).UnixNano(), Msg: "level=error msg=timeout"})
+
+		worker.checkRules(now)
+		events := webhook.received()
+		gomega.Expect(len(events)).To(gomega.Equal(1))
+		gomega.Expect(events[0].Event).To(gomega.Equal(AlertEventFiring))
+		gomega.Expect(events[0].AlertRuleID).To(gomega.Equal(rule.AlertRuleId))
+		gomega.Expect(events[0].Count).To(gomega.Equal(int64(2)))
+		firing := manager.searches.ListRules(organizationID)[0]
+		gomega.Expect(firing.State).To(gomega.Equal(AlertStateFiring))
+		gomega.Expect(firing.LastNotified).To(gomega.Equal(now.Unix()))
+
+		// The rule is not due before its interval elapses, and a firing rule is not notified again.
+		worker.checkRules(now.Add(time.Second * 30))
+		gomega.Expect(manager.searches.ListRules(organizationID)[0].LastRun).To(gomega.Equal(now.Unix()))
+
+		worker.checkRules(now.Add(time.Minute * 2))
+		events = webhook.received()
+		gomega.Expect(len(events)).To(gomega.Equal(2))
+		gomega.Expect(events[1].Event).To(gomega.Equal(AlertEventResolved))
+		gomega.Expect(events[1].Count).To(gomega.BeZero())
+		gomega.Expect(manager.searches.ListRules(organizationID)[0].State).To(gomega.Equal(AlertStateOK))
+	})
+
+	ginkgo.It("should retry the notification while the webhook fails", func() {
+		addRule(1)
+		now := time.Now()
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second).UnixNano(), Msg: "ERROR: disk full"})
+		webhook.failures = DefaultWebhookRetries
+
+		worker.checkRules(now)
+		gomega.Expect(webhook.received()).To(gomega.BeEmpty())
+		failed := manager.searches.ListRules(organizationID)[0]
+		gomega.Expect(failed.State).To(gomega.Equal(AlertStateOK))
+		gomega.Expect(failed.LastError).NotTo(gomega.BeEmpty())
+		gomega.Expect(failed.LastCount).To(gomega.Equal(int64(1)))
+
+		// The next evaluation only covers the entries of the following interval.
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(time.Second * 30).UnixNano(), Msg: "ERROR: disk full"})
+		worker.checkRules(now.Add(time.Minute))
+		gomega.Expect(len(webhook.received())).To(gomega.Equal(1))
+		notified := manager.searches.ListRules(organizationID)[0]
+		gomega.Expect(notified.State).To(gomega.Equal(AlertStateFiring))
+		gomega.Expect(notified.LastError).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should not resolve a rule while its window is truncated", func() {
+		addRule(2)
+		now := time.Now()
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second * 3).UnixNano(), Msg: "ERROR: disk full"},
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second * 2).UnixNano(), Msg: "level=error msg=timeout"})
+		worker.checkRules(now)
+		gomega.Expect(manager.searches.ListRules(organizationID)[0].State).To(gomega.Equal(AlertStateFiring))
+
+		// Only one of the two entries of the next window can be scanned.
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(time.Second * 30).UnixNano(), Msg: "ERROR: disk full"},
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(time.Second * 40).UnixNano(), Msg: "INFO: started"})
+		worker.manager.queryMaxScan = 1
+		worker.checkRules(now.Add(time.Minute))
+		gomega.Expect(len(webhook.received())).To(gomega.Equal(1))
+		truncated := manager.searches.ListRules(organizationID)[0]
+		gomega.Expect(truncated.State).To(gomega.Equal(AlertStateFiring))
+		gomega.Expect(truncated.LastError).To(gomega.Equal(truncatedEvaluation))
+	})
+
+	ginkgo.It("should keep the saved searches and rules in a file", func() {
+		dir, err := ioutil.TempDir("", "searches")
+		gomega.Expect(err).To(gomega.Succeed())
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "searches.json")
+
+		store, dErr := NewSearchStore(path, "secret")
+		gomega.Expect(dErr).To(gomega.Succeed())
+		search, dErr := store.AddSearch(&grpc_public_api_go.AddSavedSearchRequest{OrganizationId: organizationID, Name: "errors", Query: "severity:error"})
+		gomega.Expect(dErr).To(gomega.Succeed())
+		_, dErr = store.AddRule(&grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID, SavedSearchId: search.SavedSearchId,
+			Name: "errors", Interval: "1m", Threshold: 1, WebhookUrl: webhookServer.URL, WebhookSecret: "webhook-signing-key"})
+		gomega.Expect(dErr).To(gomega.Succeed())
+		raw, err := ioutil.ReadFile(path)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(string(raw)).NotTo(gomega.ContainSubstring("webhook-signing-key"))
+
+		loaded, dErr := NewSearchStore(path, "secret")
+		gomega.Expect(dErr).To(gomega.Succeed())
+		gomega.Expect(len(loaded.ListSearches(organizationID))).To(gomega.Equal(1))
+		due := loaded.dueRules(time.Now())
+		gomega.Expect(len(due)).To(gomega.Equal(1))
+		secret, dErr := loaded.webhookSecret(due[0])
+		gomega.Expect(dErr).To(gomega.Succeed())
+		gomega.Expect(secret).To(gomega.Equal("webhook-signing-key"))
+
+		// The secrets cannot be read with a different key.
+		other, dErr := NewSearchStore(path, "other")
+		gomega.Expect(dErr).To(gomega.Succeed())
+		_, dErr = other.webhookSecret(other.dueRules(time.Now())[0])
+		gomega.Expect(dErr).NotTo(gomega.Succeed())
+		gomega.Expect(loaded.ListRules(organizationID)[0].LastError).To(gomega.BeEmpty())
+		gomega.Expect(other.ListRules(organizationID)[0].LastError).To(gomega.Equal(undecryptableSecret))
+	})
+
+	ginkgo.It("should bound each evaluation with a deadline", func() {
+		release := make(chan struct{})
+		slowServer := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			select {
+			case <-release:
+			case <-request.Context().Done():
+			}
+		}))
+		defer slowServer.Close()
+		defer close(release)
+		search, err := manager.searches.AddSearch(&grpc_public_api_go.AddSavedSearchRequest{
+			OrganizationId: organizationID, Name: "errors", Query: "severity:error"})
+		gomega.Expect(err).To(gomega.Succeed())
+		for _, name := range []string{"first", "second"} {
+			_, err = manager.searches.AddRule(&grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+				SavedSearchId: search.SavedSearchId, Name: name, Interval: "1m", Threshold: 1, WebhookUrl: slowServer.URL})
+			gomega.Expect(err).To(gomega.Succeed())
+		}
+		now := time.Now()
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{Timestamp: now.Add(-time.Second).UnixNano(), Msg: "ERROR: disk full"})
+
+		worker.timeout = time.Millisecond * 200
+		start := time.Now()
+		worker.checkRules(now)
+		// Both rules are evaluated at the same time.
+		gomega.Expect(time.Since(start)).To(gomega.BeNumerically("<", time.Millisecond*390))
+		for _, rule := range manager.searches.ListRules(organizationID) {
+			gomega.Expect(rule.State).To(gomega.Equal(AlertStateOK))
+			gomega.Expect(rule.LastError).NotTo(gomega.BeEmpty())
+		}
+	})
+})
+
+var _ = ginkgo.Describe("Webhook guard", func() {
+
+	ginkgo.It("should reject the internal addresses", func() {
+		guard := NewWebhookGuard(nil)
+		for _, webhookURL := range []string{"http://127.0.0.1/hook", "http://10.1.2.3/hook", "http://172.16.0.1/hook",
+			"http://192.168.1.1/hook", "http://169.254.169.254/latest", "http://100.64.0.1/hook", "http://0.0.0.0/hook",
+			"http://[::1]/hook", "http://[fd00::1]/hook", "http://[fe80::1]/hook", "http://[::ffff:127.0.0.1]/hook"} {
+			gomega.Expect(guard.CheckURL(webhookURL)).NotTo(gomega.Succeed(), webhookURL)
+		}
+		gomega.Expect(guard.CheckURL("https://203.0.113.10/hook")).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should only allow the hosts set by the operator", func() {
+		guard := NewWebhookGuard([]string{"alerts.example.com", ".hooks.example.org"})
+		gomega.Expect(guard.CheckURL("https://alerts.example.com/hook")).To(gomega.Succeed())
+		gomega.Expect(guard.CheckURL("https://team.hooks.example.org/hook")).To(gomega.Succeed())
+		gomega.Expect(guard.CheckURL("https://203.0.113.10/hook")).NotTo(gomega.Succeed())
+		gomega.Expect(guard.CheckURL("https://alerts.example.com.attacker.net/hook")).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should not connect to an internal address nor follow redirections", func() {
+		webhook := &webhookStandIn{}
+		server := httptest.NewServer(webhook)
+		defer server.Close()
+		event := AlertEvent{Event: AlertEventFiring}
+
+		notifier := NewWebhookNotifier(NewWebhookGuard(nil))
+		notifier.retrySleep = time.Millisecond
+		gomega.Expect(notifier.Notify(context.Background(), server.URL, "secret", event)).NotTo(gomega.Succeed())
+
+		redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusTemporaryRedirect))
+		defer redirect.Close()
+		notifier = NewWebhookNotifier(NewWebhookGuard([]string{"127.0.0.1"}))
+		notifier.retrySleep = time.Millisecond
+		gomega.Expect(notifier.Notify(context.Background(), redirect.URL, "secret", event)).NotTo(gomega.Succeed())
+		gomega.Expect(notifier.Notify(context.Background(), server.URL, "secret", event)).To(gomega.Succeed())
+		gomega.Expect(len(webhook.received())).To(gomega.Equal(1))
+	})
+})
@@ -0,0 +1,170 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDownloadRetention with the time the finished log downloads are kept if the organization does not set one.
+const DefaultDownloadRetention = time.Hour * 24 * 7
+
+// finishedDownload checks whether a download request has reached a final state.
+func finishedDownload(response *grpc_log_download_manager_go.DownloadLogResponse) bool {
+	switch response.State {
+	case grpc_log_download_manager_go.DownloadLogState_READY,
+		grpc_log_download_manager_go.DownloadLogState_ERROR,
+		grpc_log_download_manager_go.DownloadLogState_CANCELLED:
+		return true
+	}
+	return false
+}
+
+// expiredDownloads returns the finished download requests created before the retention. A retention of 0 keeps
+// all of them.
+func expiredDownloads(responses []*grpc_log_download_manager_go.DownloadLogResponse, retention time.Duration, now time.Time) []*grpc_log_download_manager_go.DownloadLogResponse {
+	expired := make([]*grpc_log_download_manager_go.DownloadLogResponse, 0)
+	if retention == 0 {
+		return expired
+	}
+	limit := now.Add(-retention).UnixNano()
+	for _, response := range responses {
+		if finishedDownload(response) && response.Created != 0 && response.Created < limit {
+			expired = append(expired, response)
+		}
+	}
+	return expired
+}
+
+// downloadRetention returns the retention of the finished downloads of an organization. A retention of 0 disables
+// the removal of the finished downloads.
+func (m *Manager) downloadRetention(ctx context.Context, organizationID string) (time.Duration, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	settings, err := m.settingsClient.ListSettings(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return 0, err
+	}
+	for _, setting := range settings.Settings {
+		if setting.Key != entities.LogDownloadRetentionSetting {
+			continue
+		}
+		retention, dErr := timeexpr.ParseDuration(setting.Value)
+		if dErr != nil || retention < 0 {
+			log.Warn().Str("organizationID", organizationID).Str("value", setting.Value).
+				Msg("invalid log download retention, using the default one")
+			return DefaultDownloadRetention, nil
+		}
+		return retention, nil
+	}
+	return DefaultDownloadRetention, nil
+}
+
+// prune removes the expired download requests of an organization, returning the removed ones. The requests that
+// cannot be removed are skipped.
+func (m *Manager) prune(ctx context.Context, organizationID string, userId string, dryRun bool) ([]*grpc_log_download_manager_go.DownloadLogResponse, error) {
+	retention, err := m.downloadRetention(ctx, organizationID)
+	if err != nil {
+		return nil, err
+	}
+	userCtx, cancel := common.GetContextWithUser(ctx, userId)
+	defer cancel()
+	responses, err := m.logDownloadClient.List(userCtx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	expired := expiredDownloads(responses.Responses, retention, time.Now())
+	if dryRun {
+		return expired, nil
+	}
+	removed := make([]*grpc_log_download_manager_go.DownloadLogResponse, 0, len(expired))
+	for _, response := range expired {
+		_, err := m.logDownloadClient.Delete(userCtx, &grpc_log_download_manager_go.DownloadRequestId{
+			OrganizationId: organizationID,
+			RequestId:      response.RequestId,
+		})
+		if err != nil {
+			log.Warn().Str("requestID", response.RequestId).Err(err).Msg("cannot remove an expired log download")
+			continue
+		}
+		removed = append(removed, response)
+	}
+	if len(removed) > 0 {
+		log.Info().Str("organizationID", organizationID).Int("removed", len(removed)).Msg("expired log downloads removed")
+	}
+	return removed, nil
+}
+
+// CancelDownload stops a download request in progress, returning its new state.
+func (m *Manager) CancelDownload(ctx context.Context, requestId *grpc_log_download_manager_go.DownloadRequestId, userId string) (*grpc_public_api_go.DownloadLogResponse, error) {
+	log.Debug().Interface("request", requestId).Msg("CancelDownload request")
+	ctx, cancel := common.GetContextWithUser(ctx, userId)
+	defer cancel()
+	if _, err := m.logDownloadClient.Cancel(ctx, requestId); err != nil {
+		return nil, err
+	}
+	response, err := m.logDownloadClient.Check(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	return entities.ToPublicAPIDownloadLogReponse(response), nil
+}
+
+// DeleteDownload removes a finished download request and its archive. The requests in progress must be cancelled
+// first.
+func (m *Manager) DeleteDownload(ctx context.Context, requestId *grpc_log_download_manager_go.DownloadRequestId, userId string) (*grpc_common_go.Success, error) {
+	log.Debug().Interface("request", requestId).Msg("DeleteDownload request")
+	ctx, cancel := common.GetContextWithUser(ctx, userId)
+	defer cancel()
+	response, err := m.logDownloadClient.Check(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	if !finishedDownload(response) {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError(
+			"the download request is in progress, cancel it with CancelDownload first").WithParams(requestId.RequestId))
+	}
+	return m.logDownloadClient.Delete(ctx, requestId)
+}
+
+// PruneDownloads removes the finished download requests older than the retention of the organization, returning
+// them. A dry run only returns them.
+func (m *Manager) PruneDownloads(ctx context.Context, request *grpc_public_api_go.PruneDownloadsRequest, userId string) (*grpc_public_api_go.DownloadLogResponseList, error) {
+	removed, err := m.prune(ctx, request.OrganizationId, userId, request.DryRun)
+	if err != nil {
+		return nil, err
+	}
+	responseList := make([]*grpc_public_api_go.DownloadLogResponse, len(removed))
+	for i, resp := range removed {
+		responseList[i] = entities.ToPublicAPIDownloadLogReponse(resp)
+	}
+	return &grpc_public_api_go.DownloadLogResponseList{
+		Responses: responseList,
+	}, nil
+}
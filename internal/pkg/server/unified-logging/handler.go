@@ -20,6 +20,7 @@ import (
 	"context"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-log-download-manager-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-public-api-go"
@@ -52,7 +53,39 @@ func (h *Handler) Search(ctx context.Context, request *grpc_public_api_go.Search
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Search(request)
+	return h.Manager.Search(ctx, request)
+}
+
+// Query evaluates a log query with field filters and aggregations.
+func (h *Handler) Query(ctx context.Context, request *grpc_public_api_go.LogQueryRequest) (*grpc_public_api_go.LogQueryResponse, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidLogQueryRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.Query(ctx, request)
+}
+
+// Tail streams the log entries matching a query as they arrive, sending heartbeats while there are none.
+func (h *Handler) Tail(request *grpc_public_api_go.SearchRequest, stream grpc_public_api_go.UnifiedLogging_TailServer) error {
+	rm, err := authhelper.GetRequestMetadata(stream.Context())
+	if err != nil {
+		return conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidTailRequest(request)
+	if err != nil {
+		return conversions.ToGRPCError(err)
+	}
+	return h.Manager.Tail(request, stream)
 }
 
 // Check checks the state of the download operation
@@ -68,7 +101,7 @@ func (h *Handler) Check(ctx context.Context, requestId *grpc_log_download_manage
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Check(requestId, rm.UserID)
+	return h.Manager.Check(ctx, requestId, rm.UserID)
 }
 
 // DownloadLog ask for log entries and store them into a zip file
@@ -84,7 +117,7 @@ func (h *Handler) DownloadLog(ctx context.Context, request *grpc_log_download_ma
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.DownloadLog(request, rm.UserID)
+	return h.Manager.DownloadLog(ctx, request, rm.UserID)
 }
 
 func (h *Handler) List(ctx context.Context, request *grpc_organization_go.OrganizationId) (*grpc_public_api_go.DownloadLogResponseList, error) {
@@ -100,5 +133,165 @@ func (h *Handler) List(ctx context.Context, request *grpc_organization_go.Organi
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.List(request, rm.UserID)
+	return h.Manager.List(ctx, request, rm.UserID)
+}
+
+// CancelDownload stops a download request in progress.
+func (h *Handler) CancelDownload(ctx context.Context, requestId *grpc_log_download_manager_go.DownloadRequestId) (*grpc_public_api_go.DownloadLogResponse, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if requestId.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidDownloadRequestId(requestId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.CancelDownload(ctx, requestId, rm.UserID)
+}
+
+// DeleteDownload removes a finished download request and its archive.
+func (h *Handler) DeleteDownload(ctx context.Context, requestId *grpc_log_download_manager_go.DownloadRequestId) (*grpc_common_go.Success, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if requestId.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidDownloadRequestId(requestId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.DeleteDownload(ctx, requestId, rm.UserID)
+}
+
+// PruneDownloads removes the finished download requests older than the retention of the organization.
+func (h *Handler) PruneDownloads(ctx context.Context, request *grpc_public_api_go.PruneDownloadsRequest) (*grpc_public_api_go.DownloadLogResponseList, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidPruneDownloadsRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.PruneDownloads(ctx, request, rm.UserID)
+}
+
+// AddSavedSearch stores a log query to run it later or to use it in alert rules.
+func (h *Handler) AddSavedSearch(ctx context.Context, request *grpc_public_api_go.AddSavedSearchRequest) (*grpc_public_api_go.SavedSearch, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidAddSavedSearchRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.AddSavedSearch(request)
+}
+
+// ListSavedSearches retrieves the saved searches of an organization.
+func (h *Handler) ListSavedSearches(ctx context.Context, request *grpc_organization_go.OrganizationId) (*grpc_public_api_go.SavedSearchList, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidOrganizationId(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.ListSavedSearches(request.OrganizationId)
+}
+
+// RunSavedSearch evaluates a saved search over its time window.
+func (h *Handler) RunSavedSearch(ctx context.Context, request *grpc_public_api_go.SavedSearchId) (*grpc_public_api_go.LogQueryResponse, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidSavedSearchId(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.RunSavedSearch(ctx, request)
+}
+
+// RemoveSavedSearch removes a saved search that is not used by any alert rule.
+func (h *Handler) RemoveSavedSearch(ctx context.Context, request *grpc_public_api_go.SavedSearchId) (*grpc_common_go.Success, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidSavedSearchId(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.RemoveSavedSearch(request)
+}
+
+// AddAlertRule creates a rule that notifies a webhook when a saved search matches too many entries.
+func (h *Handler) AddAlertRule(ctx context.Context, request *grpc_public_api_go.AddAlertRuleRequest) (*grpc_public_api_go.AlertRule, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidAddAlertRuleRequest(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.AddAlertRule(request)
+}
+
+// ListAlertRules retrieves the alert rules of an organization.
+func (h *Handler) ListAlertRules(ctx context.Context, request *grpc_organization_go.OrganizationId) (*grpc_public_api_go.AlertRuleList, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidOrganizationId(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.ListAlertRules(request.OrganizationId)
+}
+
+// RemoveAlertRule removes an alert rule.
+func (h *Handler) RemoveAlertRule(ctx context.Context, request *grpc_public_api_go.AlertRuleId) (*grpc_common_go.Success, error) {
+	rm, err := authhelper.GetRequestMetadata(ctx)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	if request.OrganizationId != rm.OrganizationID {
+		return nil, derrors.NewPermissionDeniedError("cannot access requested OrganizationID")
+	}
+	err = entities.ValidAlertRuleId(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return h.Manager.RemoveAlertRule(request)
 }
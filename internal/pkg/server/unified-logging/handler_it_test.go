@@ -19,6 +19,7 @@ RUN_INTEGRATION_TEST=true
 IT_SM_ADDRESS=localhost:8800
 IT_UL_COORD_ADDRESS=localhost:8323
 IT_ORGMGR_ADDRESS=localhost:8950
+IT_APPMGR_ADDRESS=localhost:8910
 */
 
 package unified_logging
@@ -55,9 +56,10 @@ var _ = ginkgo.Describe("Unified Logging", func() {
 		unifiedLoggingAddress = os.Getenv("IT_UL_COORD_ADDRESS")
 		logManagerAddress     = os.Getenv("LOG_DOWNLOAD_ADDRESS")
 		orgManagerAddress     = os.Getenv("IT_ORGMGR_ADDRESS")
+		appManagerAddress     = os.Getenv("IT_APPMGR_ADDRESS")
 	)
 
-	if systemModelAddress == "" || unifiedLoggingAddress == "" || logManagerAddress == "" || orgManagerAddress == "" {
+	if systemModelAddress == "" || unifiedLoggingAddress == "" || logManagerAddress == "" || orgManagerAddress == "" || appManagerAddress == "" {
 		ginkgo.Fail("missing environment variables")
 	}
 
@@ -75,6 +77,7 @@ var _ = ginkgo.Describe("Unified Logging", func() {
 	var client grpc_public_api_go.UnifiedLoggingClient
 	var lmClient grpc_log_download_manager_go.LogDownloadManagerClient
 	var lmConn *grpc.ClientConn
+	var appConn *grpc.ClientConn
 
 	var organization, appInstance, sgInstance string
 	var token string
@@ -99,10 +102,14 @@ var _ = ginkgo.Describe("Unified Logging", func() {
 		lmConn = utils.GetConnection(logManagerAddress)
 		lmClient = grpc_log_download_manager_go.NewLogDownloadManagerClient(lmConn)
 
+		appConn = utils.GetConnection(appManagerAddress)
+
 		conn, err := test.GetConn(*listener)
 		gomega.Expect(err).To(gomega.Succeed())
 
-		manager := NewManager(ulClient, lmClient)
+		searches, sErr := NewSearchStore("", "secret")
+		gomega.Expect(sErr).To(gomega.Succeed())
+		manager := NewManager(ulClient, lmClient, grpc_application_manager_go.NewApplicationManagerClient(appConn), orgClient, searches, NewWebhookGuard(nil))
 		handler := NewHandler(manager)
 		grpc_public_api_go.RegisterUnifiedLoggingServer(server, handler)
 		test.LaunchServer(server, listener)
@@ -139,6 +146,7 @@ var _ = ginkgo.Describe("Unified Logging", func() {
 		smConn.Close()
 		ulConn.Close()
 		orgConn.Close()
+		appConn.Close()
 	})
 
 	ginkgo.Context("search", func() {
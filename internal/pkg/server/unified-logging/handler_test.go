@@ -0,0 +1,490 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"bufio"
+	"context"
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-log-download-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-organization-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/nalej/public-api/internal/pkg/server/streamauth"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+)
+
+// nextEntries receives responses until one with entries arrives.
+func nextEntries(stream grpc_public_api_go.UnifiedLogging_TailClient) *grpc_public_api_go.TailResponse {
+	for {
+		response, err := stream.Recv()
+		gomega.Expect(err).To(gomega.Succeed())
+		if !response.Heartbeat {
+			return response
+		}
+	}
+}
+
+var _ = ginkgo.Describe("Unified logging handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.UnifiedLoggingClient
+
+	var organizationID string
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+		organizationID = ithelpers.GenerateUUID()
+
+		searches, sErr := NewSearchStore("", "secret")
+		gomega.Expect(sErr).To(gomega.Succeed())
+		authConfig := interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(
+			interceptor.WithServerAuthxInterceptor(authConfig),
+			grpc.StreamInterceptor(streamauth.StreamServerInterceptor(authConfig)))
+		manager := NewManager(grpc_application_manager_go.NewUnifiedLoggingClient(platform.Conn()),
+			grpc_log_download_manager_go.NewLogDownloadManagerClient(platform.Conn()),
+			grpc_application_manager_go.NewApplicationManagerClient(platform.Conn()),
+			grpc_organization_manager_go.NewOrganizationsClient(platform.Conn()),
+			searches, NewWebhookGuard([]string{"localhost"}))
+		manager.tail = tailConfig{pollInterval: time.Millisecond * 10, heartbeat: time.Millisecond * 50, maxDuration: time.Minute}
+		grpc_public_api_go.RegisterUnifiedLoggingServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewUnifiedLoggingClient(conn)
+
+		token = ithelpers.GenerateToken("dev@nalej.com", organizationID, "Developer", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_APPS})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	ginkgo.It("should tail the new log entries once", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		from := time.Now().UnixNano()
+		stream, err := client.Tail(ctx, &grpc_public_api_go.SearchRequest{OrganizationId: organizationID, From: from})
+		gomega.Expect(err).To(gomega.Succeed())
+		first, err := stream.Recv()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(first.Heartbeat).To(gomega.BeTrue())
+		gomega.Expect(first.Cursor).To(gomega.Equal(from))
+
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from - 1, Msg: "old"},
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from + 2, Msg: "second"},
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from + 1, Msg: "first"})
+		received := nextEntries(stream)
+		gomega.Expect(len(received.Entries)).To(gomega.Equal(2))
+		gomega.Expect(received.Entries[0].Msg).To(gomega.Equal("first"))
+		gomega.Expect(received.Entries[1].Msg).To(gomega.Equal("second"))
+		gomega.Expect(received.Cursor).To(gomega.Equal(from + 2))
+
+		// An entry with the timestamp of the cursor is only sent if it was not sent before.
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from + 2, Msg: "late"})
+		received = nextEntries(stream)
+		gomega.Expect(len(received.Entries)).To(gomega.Equal(1))
+		gomega.Expect(received.Entries[0].Msg).To(gomega.Equal("late"))
+
+		heartbeat, err := stream.Recv()
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(heartbeat.Heartbeat).To(gomega.BeTrue())
+		gomega.Expect(heartbeat.Cursor).To(gomega.Equal(from + 2))
+	})
+
+	ginkgo.It("should resume a tail after the cursor without repeating entries", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		from := time.Now().UnixNano()
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from, Msg: "first"},
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from, Msg: "second"})
+		stream, err := client.Tail(ctx, &grpc_public_api_go.SearchRequest{OrganizationId: organizationID, From: from - 1})
+		gomega.Expect(err).To(gomega.Succeed())
+		received := nextEntries(stream)
+		gomega.Expect(len(received.Entries)).To(gomega.Equal(2))
+		gomega.Expect(received.Cursor).To(gomega.Equal(from))
+		cancel()
+
+		resumeCtx, resumeCancel := ithelpers.GetContext(token)
+		defer resumeCancel()
+		resumed, err := client.Tail(resumeCtx, &grpc_public_api_go.SearchRequest{OrganizationId: organizationID, From: received.Cursor})
+		gomega.Expect(err).To(gomega.Succeed())
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", Timestamp: from + 1, Msg: "third"})
+		received = nextEntries(resumed)
+		gomega.Expect(len(received.Entries)).To(gomega.Equal(1))
+		gomega.Expect(received.Entries[0].Msg).To(gomega.Equal("third"))
+	})
+
+	ginkgo.It("should not tail the logs of another organization", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		stream, err := client.Tail(ctx, &grpc_public_api_go.SearchRequest{OrganizationId: ithelpers.GenerateUUID()})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = stream.Recv()
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject a stream without a valid token", func() {
+		ctx, cancel := ithelpers.GetContext("invalid")
+		defer cancel()
+		stream, err := client.Tail(ctx, &grpc_public_api_go.SearchRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = stream.Recv()
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.Unauthenticated))
+	})
+
+	ginkgo.It("should reject a tail with an end time", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		stream, err := client.Tail(ctx, &grpc_public_api_go.SearchRequest{OrganizationId: organizationID, To: time.Now().UnixNano()})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = stream.Recv()
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+
+	ginkgo.It("should evaluate a log query", func() {
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{ServiceName: "web", Timestamp: 1, Msg: "GET /index.php 200"},
+			&grpc_application_manager_go.LogEntryResponse{ServiceName: "web", Timestamp: 2, Msg: "level=error msg=timeout"},
+			&grpc_application_manager_go.LogEntryResponse{ServiceName: "db", Timestamp: 3, Msg: "ERROR: disk full"})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+
+		result, err := client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, Query: "severity:error service:web"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Scanned).To(gomega.Equal(int64(3)))
+		gomega.Expect(result.Matched).To(gomega.Equal(int64(1)))
+		gomega.Expect(result.Entries[0].Timestamp).To(gomega.Equal(int64(2)))
+
+		result, err = client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, Query: "severity>=warn | count by service every 1s"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.GroupBy).To(gomega.Equal("service"))
+		gomega.Expect(result.BucketSize).To(gomega.Equal(time.Second.Nanoseconds()))
+		gomega.Expect(len(result.Buckets)).To(gomega.Equal(2))
+		gomega.Expect(result.Buckets[0].Key).To(gomega.Equal("db"))
+		gomega.Expect(result.Buckets[1].Count).To(gomega.Equal(int64(1)))
+
+		result, err = client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, Query: "| top 1"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(result.TopMessages)).To(gomega.Equal(1))
+		gomega.Expect(result.TopMessages[0].Example).To(gomega.Equal("ERROR: disk full"))
+	})
+
+	ginkgo.It("should filter a log query by the labels of the instances", func() {
+		appClient := grpc_application_manager_go.NewApplicationManagerClient(platform.Conn())
+		descriptor := ithelpers.CreateAppDescriptor(organizationID, appClient)
+		instance, err := appClient.Deploy(context.Background(), ithelpers.GenerateDeploy(organizationID, descriptor.AppDescriptorId))
+		gomega.Expect(err).To(gomega.Succeed())
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: instance.AppInstanceId, Timestamp: 1, Msg: "labeled"},
+			&grpc_application_manager_go.LogEntryResponse{AppInstanceId: ithelpers.GenerateUUID(), Timestamp: 2, Msg: "other"})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+
+		result, err := client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, Query: "label.app:simple-app"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(result.Entries)).To(gomega.Equal(1))
+		gomega.Expect(result.Entries[0].Msg).To(gomega.Equal("labeled"))
+	})
+
+	ginkgo.It("should reject an invalid log query", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, Query: "(severity:error"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+		_, err = client.Query(ctx, &grpc_public_api_go.LogQueryRequest{OrganizationId: organizationID, From: 2, To: 1})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+
+	ginkgo.It("should cancel a download in progress", func() {
+		pending := platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_PENDING,
+		})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		requestID := &grpc_log_download_manager_go.DownloadRequestId{OrganizationId: organizationID, RequestId: pending.RequestId}
+
+		cancelled, err := client.CancelDownload(ctx, requestID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(cancelled.State).To(gomega.Equal(grpc_log_download_manager_go.DownloadLogState_CANCELLED))
+
+		_, err = client.CancelDownload(ctx, requestID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.FailedPrecondition))
+		_, err = client.CancelDownload(ctx, &grpc_log_download_manager_go.DownloadRequestId{OrganizationId: ithelpers.GenerateUUID(), RequestId: pending.RequestId})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should delete a finished download", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		added, err := client.DownloadLog(ctx, &grpc_log_download_manager_go.DownloadLogRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		requestID := &grpc_log_download_manager_go.DownloadRequestId{OrganizationId: organizationID, RequestId: added.RequestId}
+
+		_, err = client.DeleteDownload(ctx, requestID)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.Check(ctx, requestID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.NotFound))
+		_, err = client.DeleteDownload(ctx, &grpc_log_download_manager_go.DownloadRequestId{OrganizationId: organizationID})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+
+		// The downloads in progress must be cancelled first.
+		pending := platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_PENDING,
+		})
+		pendingID := &grpc_log_download_manager_go.DownloadRequestId{OrganizationId: organizationID, RequestId: pending.RequestId}
+		_, err = client.DeleteDownload(ctx, pendingID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.FailedPrecondition))
+		gomega.Expect(err.Error()).To(gomega.ContainSubstring("CancelDownload"))
+		_, err = client.Check(ctx, pendingID)
+		gomega.Expect(err).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should prune the downloads older than the retention", func() {
+		platform.Store.AddSetting(&grpc_organization_manager_go.Setting{
+			OrganizationId: organizationID,
+			Key:            entities.LogDownloadRetentionSetting,
+			Value:          "1d",
+		})
+		old := time.Now().Add(-time.Hour * 48).UnixNano()
+		expired := platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_READY,
+			Created:        old,
+		})
+		platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_PENDING,
+			Created:        old,
+		})
+		platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_READY,
+			Created:        time.Now().UnixNano(),
+		})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+
+		pruned, err := client.PruneDownloads(ctx, &grpc_public_api_go.PruneDownloadsRequest{OrganizationId: organizationID, DryRun: true})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(pruned.Responses)).To(gomega.Equal(1))
+		gomega.Expect(pruned.Responses[0].RequestId).To(gomega.Equal(expired.RequestId))
+
+		// Listing does not remove anything.
+		list, err := client.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Responses)).To(gomega.Equal(3))
+
+		pruned, err = client.PruneDownloads(ctx, &grpc_public_api_go.PruneDownloadsRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(pruned.Responses)).To(gomega.Equal(1))
+		list, err = client.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Responses)).To(gomega.Equal(2))
+
+		pruned, err = client.PruneDownloads(ctx, &grpc_public_api_go.PruneDownloadsRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(pruned.Responses).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should keep the finished downloads with a retention of 0", func() {
+		platform.Store.AddSetting(&grpc_organization_manager_go.Setting{
+			OrganizationId: organizationID,
+			Key:            entities.LogDownloadRetentionSetting,
+			Value:          "0",
+		})
+		platform.Store.AddDownload(&grpc_log_download_manager_go.DownloadLogResponse{
+			OrganizationId: organizationID,
+			State:          grpc_log_download_manager_go.DownloadLogState_READY,
+			Created:        time.Now().Add(-time.Hour * 24 * 365).UnixNano(),
+		})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		pruned, err := client.PruneDownloads(ctx, &grpc_public_api_go.PruneDownloadsRequest{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(pruned.Responses).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should save, run and remove a log search", func() {
+		platform.Store.AddLogEntries(organizationID,
+			&grpc_application_manager_go.LogEntryResponse{ServiceName: "web", Timestamp: time.Now().UnixNano(), Msg: "level=error msg=timeout"},
+			&grpc_application_manager_go.LogEntryResponse{ServiceName: "web", Timestamp: time.Now().UnixNano(), Msg: "GET /index.php 200"})
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+
+		_, err := client.AddSavedSearch(ctx, &grpc_public_api_go.AddSavedSearchRequest{OrganizationId: organizationID, Name: "broken", Query: "(severity:error"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+		search, err := client.AddSavedSearch(ctx, &grpc_public_api_go.AddSavedSearchRequest{
+			OrganizationId: organizationID, Name: "web errors", Query: "severity:error service:web", Since: "1h"})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.AddSavedSearch(ctx, &grpc_public_api_go.AddSavedSearchRequest{OrganizationId: organizationID, Name: "web errors", Query: "severity:error"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.AlreadyExists))
+
+		searchID := &grpc_public_api_go.SavedSearchId{OrganizationId: organizationID, SavedSearchId: search.SavedSearchId}
+		result, err := client.RunSavedSearch(ctx, searchID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Query).To(gomega.Equal(search.Query))
+		gomega.Expect(result.Matched).To(gomega.Equal(int64(1)))
+		gomega.Expect(result.From).NotTo(gomega.BeZero())
+
+		rule, err := client.AddAlertRule(ctx, &grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+			SavedSearchId: search.SavedSearchId, Name: "too many errors", Interval: "5m", Threshold: 10, WebhookUrl: "http://localhost/hook"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(rule.State).To(gomega.Equal(AlertStateOK))
+		rules, err := client.ListAlertRules(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(rules.AlertRules)).To(gomega.Equal(1))
+
+		// The searches used by a rule cannot be removed.
+		_, err = client.RemoveSavedSearch(ctx, searchID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.FailedPrecondition))
+		_, err = client.RemoveAlertRule(ctx, &grpc_public_api_go.AlertRuleId{OrganizationId: organizationID, AlertRuleId: rule.AlertRuleId})
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = client.RemoveSavedSearch(ctx, searchID)
+		gomega.Expect(err).To(gomega.Succeed())
+		searches, err := client.ListSavedSearches(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(searches.SavedSearches).To(gomega.BeEmpty())
+		_, err = client.RunSavedSearch(ctx, searchID)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.NotFound))
+	})
+
+	ginkgo.It("should reject an alert rule on a missing search or with an invalid webhook", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.AddAlertRule(ctx, &grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+			SavedSearchId: ithelpers.GenerateUUID(), Name: "rule", Interval: "5m", Threshold: 1, WebhookUrl: "http://localhost/hook"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.NotFound))
+		_, err = client.AddAlertRule(ctx, &grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+			SavedSearchId: ithelpers.GenerateUUID(), Name: "rule", Interval: "5m", Threshold: 1, WebhookUrl: "ftp://localhost"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+		_, err = client.AddAlertRule(ctx, &grpc_public_api_go.AddAlertRuleRequest{OrganizationId: organizationID,
+			SavedSearchId: ithelpers.GenerateUUID(), Name: "rule", Interval: "10s", Threshold: 1, WebhookUrl: "http://localhost/hook"})
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+	})
+
+	ginkgo.It("should serve the tail as server-sent events", func() {
+		events := httptest.NewServer(NewTailEventHandler(client, ithelpers.AuthHeader))
+		defer events.Close()
+
+		request, err := http.NewRequest(http.MethodGet, events.URL+TailEventsPath+"?organization_id="+organizationID, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+		request.Header.Set(ithelpers.AuthHeader, token)
+		request.Header.Set(LastEventIDHeader, "42")
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		response, err := http.DefaultClient.Do(request.WithContext(ctx))
+		gomega.Expect(err).To(gomega.Succeed())
+		defer response.Body.Close()
+		gomega.Expect(response.StatusCode).To(gomega.Equal(http.StatusOK))
+		gomega.Expect(response.Header.Get("Content-Type")).To(gomega.Equal("text/event-stream"))
+
+		reader := bufio.NewReader(response.Body)
+		lines := make([]string, 0)
+		for {
+			line, err := reader.ReadString('\n')
+			gomega.Expect(err).To(gomega.Succeed())
+			line = strings.TrimSpace(line)
+			if line == "" {
+				break
+			}
+			lines = append(lines, line)
+		}
+		gomega.Expect(lines[0]).To(gomega.Equal("id: 42"))
+		gomega.Expect(lines[1]).To(gomega.Equal("event: heartbeat"))
+		gomega.Expect(lines[2]).To(gomega.HavePrefix("data: "))
+	})
+
+	ginkgo.It("should read the token of the server-sent events from a cookie or the query", func() {
+		events := httptest.NewServer(NewTailEventHandler(client, ithelpers.AuthHeader))
+		defer events.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		withCookie, err := http.NewRequest(http.MethodGet, events.URL+TailEventsPath+"?organization_id="+organizationID, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+		withCookie.AddCookie(&http.Cookie{Name: TokenCookie, Value: token})
+		withQuery, err := http.NewRequest(http.MethodGet,
+			events.URL+TailEventsPath+"?organization_id="+organizationID+"&"+TokenQueryParam+"="+token, nil)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		for _, request := range []*http.Request{withCookie, withQuery} {
+			response, err := http.DefaultClient.Do(request.WithContext(ctx))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(response.StatusCode).To(gomega.Equal(http.StatusOK))
+			gomega.Expect(response.Header.Get("Referrer-Policy")).To(gomega.Equal("no-referrer"))
+			response.Body.Close()
+		}
+	})
+
+	ginkgo.It("should remove the token from the query of the server-sent events", func() {
+		handler := NewTailEventHandler(client, ithelpers.AuthHeader)
+		request := httptest.NewRequest(http.MethodGet, TailEventsPath+"?organization_id=org&"+TokenQueryParam+"=secret", nil)
+		gomega.Expect(handler.requestToken(request)).To(gomega.Equal("secret"))
+		gomega.Expect(request.URL.RawQuery).To(gomega.Equal("organization_id=org"))
+		gomega.Expect(request.RequestURI).NotTo(gomega.ContainSubstring("secret"))
+
+		request = httptest.NewRequest(http.MethodGet, TailEventsPath+"?"+TokenQueryParam+"=query", nil)
+		request.Header.Set(ithelpers.AuthHeader, "header")
+		request.AddCookie(&http.Cookie{Name: TokenCookie, Value: "cookie"})
+		gomega.Expect(handler.requestToken(request)).To(gomega.Equal("header"))
+	})
+
+	ginkgo.It("should refuse the server-sent events without a token", func() {
+		events := httptest.NewServer(NewTailEventHandler(client, ithelpers.AuthHeader))
+		defer events.Close()
+
+		response, err := http.Get(events.URL + TailEventsPath + "?organization_id=" + organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		defer response.Body.Close()
+		gomega.Expect(response.StatusCode).To(gomega.Equal(http.StatusUnauthorized))
+	})
+})
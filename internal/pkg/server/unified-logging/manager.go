@@ -17,9 +17,11 @@
 package unified_logging
 
 import (
+	"context"
 	"github.com/nalej/grpc-application-manager-go"
 	"github.com/nalej/grpc-log-download-manager-go"
 	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-organization-manager-go"
 	"github.com/nalej/grpc-public-api-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
 	"github.com/nalej/public-api/internal/pkg/entities"
@@ -31,16 +33,43 @@ import (
 type Manager struct {
 	unifiedLoggingClient grpc_application_manager_go.UnifiedLoggingClient
 	logDownloadClient    grpc_log_download_manager_go.LogDownloadManagerClient
+	// applicationClient retrieves the labels of the instances filtered in the log queries.
+	applicationClient grpc_application_manager_go.ApplicationManagerClient
+	// settingsClient retrieves the retention of the log downloads of an organization.
+	settingsClient grpc_organization_manager_go.OrganizationsClient
+	// searches contains the saved searches and the alert rules.
+	searches *SearchStore
+	// webhooks restricts the webhooks of the alert rules.
+	webhooks *WebhookGuard
+	tail     tailConfig
+	// queryMaxScan with the maximum number of entries retrieved to evaluate a log query.
+	queryMaxScan int
 }
 
 func NewManager(unifiedLoggingClient grpc_application_manager_go.UnifiedLoggingClient,
-	logDownloadClient grpc_log_download_manager_go.LogDownloadManagerClient) Manager {
-	return Manager{unifiedLoggingClient, logDownloadClient}
+	logDownloadClient grpc_log_download_manager_go.LogDownloadManagerClient,
+	applicationClient grpc_application_manager_go.ApplicationManagerClient,
+	settingsClient grpc_organization_manager_go.OrganizationsClient,
+	searches *SearchStore, webhooks *WebhookGuard) Manager {
+	return Manager{
+		unifiedLoggingClient: unifiedLoggingClient,
+		logDownloadClient:    logDownloadClient,
+		applicationClient:    applicationClient,
+		settingsClient:       settingsClient,
+		searches:             searches,
+		webhooks:             webhooks,
+		tail: tailConfig{
+			pollInterval: DefaultTailPollInterval,
+			heartbeat:    DefaultTailHeartbeat,
+			maxDuration:  DefaultTailMaxDuration,
+		},
+		queryMaxScan: DefaultQueryMaxScan,
+	}
 }
 
-func (m *Manager) Search(request *grpc_public_api_go.SearchRequest) (*grpc_application_manager_go.LogResponse, error) {
+func (m *Manager) Search(ctx context.Context, request *grpc_public_api_go.SearchRequest) (*grpc_application_manager_go.LogResponse, error) {
 	log.Debug().Interface("request", request).Msg("Search request")
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	convertedLog, err := m.unifiedLoggingClient.Search(ctx, entities.NewSearchRequest(request))
 
@@ -62,9 +91,9 @@ func (m *Manager) Search(request *grpc_public_api_go.SearchRequest) (*grpc_appli
 }
 
 // Check checks the state of the download operation
-func (m *Manager) Check(requestId *grpc_log_download_manager_go.DownloadRequestId, userId string) (*grpc_public_api_go.DownloadLogResponse, error) {
+func (m *Manager) Check(ctx context.Context, requestId *grpc_log_download_manager_go.DownloadRequestId, userId string) (*grpc_public_api_go.DownloadLogResponse, error) {
 	log.Debug().Interface("request", requestId).Msg("Check request")
-	ctx, cancel := common.GetContextWithUser(userId)
+	ctx, cancel := common.GetContextWithUser(ctx, userId)
 	defer cancel()
 	response, err := m.logDownloadClient.Check(ctx, requestId)
 	if err != nil {
@@ -75,9 +104,9 @@ func (m *Manager) Check(requestId *grpc_log_download_manager_go.DownloadRequestI
 }
 
 // DownloadLog ask for log entries and store them into a zip file
-func (m *Manager) DownloadLog(request *grpc_log_download_manager_go.DownloadLogRequest, userId string) (*grpc_public_api_go.DownloadLogResponse, error) {
+func (m *Manager) DownloadLog(ctx context.Context, request *grpc_log_download_manager_go.DownloadLogRequest, userId string) (*grpc_public_api_go.DownloadLogResponse, error) {
 	log.Debug().Interface("request", request).Msg("DownloadLog request")
-	ctx, cancel := common.GetContextWithUser(userId)
+	ctx, cancel := common.GetContextWithUser(ctx, userId)
 	defer cancel()
 	response, err := m.logDownloadClient.DownloadLog(ctx, request)
 	if err != nil {
@@ -86,8 +115,8 @@ func (m *Manager) DownloadLog(request *grpc_log_download_manager_go.DownloadLogR
 	return entities.ToPublicAPIDownloadLogReponse(response), nil
 }
 
-func (m *Manager) List(request *grpc_organization_go.OrganizationId, userId string) (*grpc_public_api_go.DownloadLogResponseList, error) {
-	ctx, cancel := common.GetContextWithUser(userId)
+func (m *Manager) List(ctx context.Context, request *grpc_organization_go.OrganizationId, userId string) (*grpc_public_api_go.DownloadLogResponseList, error) {
+	ctx, cancel := common.GetContextWithUser(ctx, userId)
 	defer cancel()
 	responses, err := m.logDownloadClient.List(ctx, request)
 	if err != nil {
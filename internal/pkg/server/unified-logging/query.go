@@ -0,0 +1,197 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/logquery"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/rs/zerolog/log"
+	"math"
+	"sort"
+)
+
+// DefaultQueryLimit with the number of entries returned by a query without aggregation if no limit is set.
+const DefaultQueryLimit = 100
+
+// DefaultQueryMaxScan with the maximum number of entries retrieved from the backend to evaluate a query. The
+// response of a query with more entries in its time range is flagged as truncated. A truncated query without
+// aggregation covers the newest entries of the time range, and one with an aggregation the oldest ones.
+const DefaultQueryMaxScan = 10000
+
+// Query evaluates a log query. The backend filters by instance, descriptor, message text and time range, so the
+// rest of the query and the aggregations are computed on the returned entries.
+func (m *Manager) Query(ctx context.Context, request *grpc_public_api_go.LogQueryRequest) (*grpc_public_api_go.LogQueryResponse, error) {
+	log.Debug().Interface("request", request).Msg("Query request")
+	query, pErr := logquery.Parse(request.Query)
+	if pErr != nil {
+		return nil, conversions.ToGRPCError(pErr)
+	}
+	pushdown := query.Pushdown()
+
+	// The queries without aggregation return the most recent entries, so they are read from the newest ones.
+	received, truncated, err := m.queryEntries(ctx, &grpc_application_manager_go.SearchRequest{
+		OrganizationId:  request.OrganizationId,
+		AppDescriptorId: pushdown.AppDescriptorId,
+		AppInstanceId:   pushdown.AppInstanceId,
+		MsgQueryFilter:  pushdown.MsgQueryFilter,
+		From:            request.From,
+		To:              request.To,
+		IncludeMetadata: true,
+		NFirst:          query.Aggregation != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := make(map[string]map[string]string, 0)
+	if query.UsesLabels() {
+		labels, err = m.instanceLabels(ctx, request.OrganizationId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	entries := make([]*logquery.Entry, 0, len(received))
+	for _, entry := range received {
+		entries = append(entries, logquery.NewEntry(entry, labels[entry.AppInstanceId]))
+	}
+	matched := query.Apply(entries)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].Timestamp < matched[j].Timestamp
+	})
+
+	result := &grpc_public_api_go.LogQueryResponse{
+		OrganizationId: request.OrganizationId,
+		Query:          request.Query,
+		From:           request.From,
+		To:             request.To,
+		Scanned:        int64(len(entries)),
+		Matched:        int64(len(matched)),
+		Truncated:      truncated,
+	}
+	switch {
+	case query.Aggregation == nil:
+		limit := int(request.Limit)
+		if limit == 0 {
+			limit = DefaultQueryLimit
+		}
+		// The most recent entries are returned.
+		if len(matched) > limit {
+			matched = matched[len(matched)-limit:]
+		}
+		result.Entries = make([]*grpc_application_manager_go.LogEntryResponse, 0, len(matched))
+		for _, entry := range matched {
+			result.Entries = append(result.Entries, entry.LogEntryResponse)
+		}
+	case query.Aggregation.Type == logquery.CountAggregation:
+		size := logquery.BucketSize(query.Aggregation, matched, request.From, request.To)
+		result.GroupBy = query.Aggregation.By
+		result.BucketSize = size.Nanoseconds()
+		result.Buckets = entities.ToLogCountBuckets(logquery.Count(matched, query.Aggregation.By, size))
+	case query.Aggregation.Type == logquery.TopAggregation:
+		result.TopMessages = entities.ToLogMessageCounts(logquery.Top(matched, query.Aggregation.Limit))
+	}
+	return result, nil
+}
+
+// queryEntries retrieves the entries of a search page by page. The backend returns the first entries of the time
+// range if NFirst is set, and the last ones otherwise, so each page starts at the timestamp of the last entry
+// received, skipping the entries already received at that timestamp. A page without new entries may be full of
+// entries at that timestamp, so the search continues after it before finishing. The entries are truncated once
+// the maximum number of entries to scan is reached.
+func (m *Manager) queryEntries(ctx context.Context, search *grpc_application_manager_go.SearchRequest) ([]*grpc_application_manager_go.LogEntryResponse, bool, error) {
+	result := make([]*grpc_application_manager_go.LogEntryResponse, 0)
+	// Unlike the tail streams, the query includes the entries at its initial timestamp.
+	cursor := &tailCursor{timestamp: search.From, seen: make(map[string]bool, 0)}
+	if !search.NFirst {
+		cursor.descending = true
+		cursor.timestamp = search.To
+		if search.To == 0 {
+			cursor.timestamp = math.MaxInt64
+		}
+	}
+	for {
+		page, err := m.querySearch(ctx, search)
+		if err != nil {
+			return nil, false, err
+		}
+		pending := cursor.advance(page)
+		if len(pending) == 0 {
+			if len(page) == 0 || !nextQueryPage(search, cursor) {
+				return result, false, nil
+			}
+			continue
+		}
+		result = append(result, pending...)
+		if len(result) >= m.queryMaxScan {
+			return result[:m.queryMaxScan], true, nil
+		}
+		if search.NFirst {
+			search.From = cursor.timestamp
+		} else {
+			search.To = cursor.timestamp
+		}
+	}
+}
+
+// nextQueryPage moves the search past the timestamp of the cursor after a page without new entries. It returns
+// false if the search already moved past it or the cursor reached the end of the time range.
+func nextQueryPage(search *grpc_application_manager_go.SearchRequest, cursor *tailCursor) bool {
+	if search.NFirst {
+		if search.From > cursor.timestamp || (search.To != 0 && cursor.timestamp >= search.To) {
+			return false
+		}
+		search.From = cursor.timestamp + 1
+		return true
+	}
+	if (search.To != 0 && search.To < cursor.timestamp) || cursor.timestamp <= search.From {
+		return false
+	}
+	search.To = cursor.timestamp - 1
+	return true
+}
+
+// querySearch retrieves a page of the entries of a query.
+func (m *Manager) querySearch(ctx context.Context, search *grpc_application_manager_go.SearchRequest) ([]*grpc_application_manager_go.LogEntryResponse, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	response, err := m.unifiedLoggingClient.Search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	return response.Entries, nil
+}
+
+// instanceLabels returns the labels of the application instances of an organization.
+func (m *Manager) instanceLabels(ctx context.Context, organizationID string) (map[string]map[string]string, error) {
+	ctx, cancel := common.GetContext(ctx)
+	defer cancel()
+	instances, err := m.applicationClient.ListAppInstances(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]map[string]string, len(instances.Instances))
+	for _, instance := range instances.Instances {
+		result[instance.AppInstanceId] = instance.Labels
+	}
+	return result, nil
+}
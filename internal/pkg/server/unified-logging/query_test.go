@@ -0,0 +1,122 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"sort"
+)
+
+// pagedSearch is an upstream component that returns the first entries of the requested time range, or the last
+// ones if NFirst is not set, up to its page size.
+type pagedSearch struct {
+	grpc_application_manager_go.UnifiedLoggingClient
+	entries  []*grpc_application_manager_go.LogEntryResponse
+	pageSize int
+	requests []*grpc_application_manager_go.SearchRequest
+}
+
+func (p *pagedSearch) Search(_ context.Context, request *grpc_application_manager_go.SearchRequest, _ ...grpc.CallOption) (*grpc_application_manager_go.LogResponse, error) {
+	p.requests = append(p.requests, &grpc_application_manager_go.SearchRequest{
+		MsgQueryFilter: request.MsgQueryFilter, From: request.From, To: request.To, NFirst: request.NFirst})
+	result := make([]*grpc_application_manager_go.LogEntryResponse, 0)
+	for _, entry := range p.entries {
+		if entry.Timestamp >= request.From && (request.To == 0 || entry.Timestamp <= request.To) {
+			result = append(result, entry)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp < result[j].Timestamp
+	})
+	if len(result) > p.pageSize {
+		if request.NFirst {
+			result = result[:p.pageSize]
+		} else {
+			result = result[len(result)-p.pageSize:]
+		}
+	}
+	return &grpc_application_manager_go.LogResponse{Entries: result}, nil
+}
+
+var _ = ginkgo.Describe("Query", func() {
+
+	entry := func(timestamp int64, msg string) *grpc_application_manager_go.LogEntryResponse {
+		return &grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", ServiceInstanceId: "service", Timestamp: timestamp, Msg: msg}
+	}
+
+	var search *pagedSearch
+
+	ginkgo.BeforeEach(func() {
+		search = &pagedSearch{
+			entries: []*grpc_application_manager_go.LogEntryResponse{
+				entry(1, "error: a"), entry(2, "error: b"), entry(2, "error: c"), entry(3, "error: d"), entry(5, "error: e")},
+			pageSize: 2,
+		}
+	})
+
+	ginkgo.It("should page through the entries of the time range", func() {
+		manager := Manager{unifiedLoggingClient: search, queryMaxScan: DefaultQueryMaxScan}
+		result, err := manager.Query(context.Background(), &grpc_public_api_go.LogQueryRequest{
+			OrganizationId: "org", Query: "error", From: 1, To: 4})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Scanned).To(gomega.Equal(int64(4)))
+		gomega.Expect(result.Truncated).To(gomega.BeFalse())
+		gomega.Expect(result.Entries[0].Msg).To(gomega.Equal("error: a"))
+		gomega.Expect(result.Entries[3].Msg).To(gomega.Equal("error: d"))
+
+		gomega.Expect(len(search.requests)).To(gomega.BeNumerically(">", 1))
+		for _, request := range search.requests {
+			gomega.Expect(request.MsgQueryFilter).To(gomega.Equal("error"))
+			gomega.Expect(request.From).To(gomega.Equal(int64(1)))
+			gomega.Expect(request.NFirst).To(gomega.BeFalse())
+		}
+		gomega.Expect(search.requests[0].To).To(gomega.Equal(int64(4)))
+	})
+
+	ginkgo.It("should return the newest entries of a truncated query", func() {
+		manager := Manager{unifiedLoggingClient: search, queryMaxScan: 3}
+		result, err := manager.Query(context.Background(), &grpc_public_api_go.LogQueryRequest{OrganizationId: "org", Query: "error"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Scanned).To(gomega.Equal(int64(3)))
+		gomega.Expect(result.Truncated).To(gomega.BeTrue())
+		messages := make([]string, 0)
+		for _, entry := range result.Entries {
+			messages = append(messages, entry.Msg)
+		}
+		gomega.Expect(messages).To(gomega.Equal([]string{"error: c", "error: d", "error: e"}))
+	})
+
+	ginkgo.It("should aggregate the oldest entries of the time range first", func() {
+		manager := Manager{unifiedLoggingClient: search, queryMaxScan: 3}
+		result, err := manager.Query(context.Background(), &grpc_public_api_go.LogQueryRequest{
+			OrganizationId: "org", Query: "error | count by service", From: 1, To: 5})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(result.Scanned).To(gomega.Equal(int64(3)))
+		gomega.Expect(result.Truncated).To(gomega.BeTrue())
+		for _, request := range search.requests {
+			gomega.Expect(request.NFirst).To(gomega.BeTrue())
+			gomega.Expect(request.To).To(gomega.Equal(int64(5)))
+		}
+		gomega.Expect(search.requests[0].From).To(gomega.Equal(int64(1)))
+	})
+
+})
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/public-api/internal/pkg/logquery"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
+	"github.com/rs/zerolog/log"
+)
+
+// AddSavedSearch stores a log query of an organization once it is parsed.
+func (m *Manager) AddSavedSearch(request *grpc_public_api_go.AddSavedSearchRequest) (*grpc_public_api_go.SavedSearch, error) {
+	log.Debug().Interface("request", request).Msg("AddSavedSearch request")
+	if _, err := logquery.Parse(request.Query); err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	search, err := m.searches.AddSearch(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return search, nil
+}
+
+// ListSavedSearches returns the saved searches of an organization.
+func (m *Manager) ListSavedSearches(organizationID string) (*grpc_public_api_go.SavedSearchList, error) {
+	return &grpc_public_api_go.SavedSearchList{SavedSearches: m.searches.ListSearches(organizationID)}, nil
+}
+
+// RunSavedSearch evaluates a saved search. The entries of the time window of the search up to now are queried, or
+// all of them if the search does not set one.
+func (m *Manager) RunSavedSearch(ctx context.Context, searchID *grpc_public_api_go.SavedSearchId) (*grpc_public_api_go.LogQueryResponse, error) {
+	search, err := m.searches.GetSearch(searchID)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	request := &grpc_public_api_go.LogQueryRequest{
+		OrganizationId: search.OrganizationId,
+		Query:          search.Query,
+	}
+	if search.Since != "" {
+		since, err := timeexpr.ParseDuration(search.Since)
+		if err != nil {
+			return nil, conversions.ToGRPCError(err)
+		}
+		now := time.Now()
+		request.From = now.Add(-since).UnixNano()
+		request.To = now.UnixNano()
+	}
+	return m.Query(ctx, request)
+}
+
+// RemoveSavedSearch removes a saved search that is not used by any alert rule.
+func (m *Manager) RemoveSavedSearch(searchID *grpc_public_api_go.SavedSearchId) (*grpc_common_go.Success, error) {
+	if err := m.searches.RemoveSearch(searchID); err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// AddAlertRule stores an alert rule on a saved search once its webhook is checked. The rule is evaluated by the
+// alert worker.
+func (m *Manager) AddAlertRule(request *grpc_public_api_go.AddAlertRuleRequest) (*grpc_public_api_go.AlertRule, error) {
+	log.Debug().Str("organizationID", request.OrganizationId).Str("savedSearchID", request.SavedSearchId).
+		Str("name", request.Name).Msg("AddAlertRule request")
+	if err := m.webhooks.CheckURL(request.WebhookUrl); err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	rule, err := m.searches.AddRule(request)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return rule, nil
+}
+
+// ListAlertRules returns the alert rules of an organization with the result of their last evaluation.
+func (m *Manager) ListAlertRules(organizationID string) (*grpc_public_api_go.AlertRuleList, error) {
+	return &grpc_public_api_go.AlertRuleList{AlertRules: m.searches.ListRules(organizationID)}, nil
+}
+
+// RemoveAlertRule removes an alert rule.
+func (m *Manager) RemoveAlertRule(ruleID *grpc_public_api_go.AlertRuleId) (*grpc_common_go.Success, error) {
+	if err := m.searches.RemoveRule(ruleID); err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/grpc-ecosystem/grpc-gateway/runtime"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// TailEventsPath with the HTTP path serving the tail stream as Server-Sent Events.
+const TailEventsPath = "/v1/unified-logging/tail"
+
+// LastEventIDHeader is sent by the browsers when an event source reconnects. It contains the last cursor.
+const LastEventIDHeader = "Last-Event-ID"
+
+// TokenCookie with the name of the cookie that may contain the token. Browsers cannot set headers on an event
+// source, so the web console sends the token as a cookie.
+const TokenCookie = "access_token"
+
+// TokenQueryParam with the query parameter that may contain the token when cookies cannot be used. The cookie is
+// preferred as proxies may log the query of the requests.
+const TokenQueryParam = "access_token"
+
+// TailEventHandler exposes the Tail stream as Server-Sent Events for the web console. Each response of the stream
+// is sent as an entries or heartbeat event whose identifier is the cursor, so reconnecting clients resume from
+// the last entry they received.
+type TailEventHandler struct {
+	client     grpc_public_api_go.UnifiedLoggingClient
+	authHeader string
+	marshaler  *jsonpb.Marshaler
+}
+
+// NewTailEventHandler creates the handler on top of a client of the gRPC server.
+func NewTailEventHandler(client grpc_public_api_go.UnifiedLoggingClient, authHeader string) *TailEventHandler {
+	return &TailEventHandler{client: client, authHeader: authHeader, marshaler: &jsonpb.Marshaler{OrigName: true}}
+}
+
+// requestToken returns the token of a request, taken from the authorization header, the cookie or the query
+// parameter, in that order. The query parameter is removed from the request so it is not logged or forwarded.
+func (h *TailEventHandler) requestToken(r *http.Request) string {
+	query := r.URL.Query()
+	fromQuery := query.Get(TokenQueryParam)
+	if _, exists := query[TokenQueryParam]; exists {
+		query.Del(TokenQueryParam)
+		r.URL.RawQuery = query.Encode()
+		r.RequestURI = r.URL.RequestURI()
+	}
+	if token := r.Header.Get(h.authHeader); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(TokenCookie); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	return fromQuery
+}
+
+// tailRequest builds the search request from the query parameters, which use the same names as the Search
+// endpoint of the gateway.
+func tailRequest(r *http.Request) (*grpc_public_api_go.SearchRequest, error) {
+	query := r.URL.Query()
+	request := &grpc_public_api_go.SearchRequest{
+		OrganizationId:         query.Get("organization_id"),
+		AppDescriptorId:        query.Get("app_descriptor_id"),
+		AppInstanceId:          query.Get("app_instance_id"),
+		ServiceGroupId:         query.Get("service_group_id"),
+		ServiceGroupInstanceId: query.Get("service_group_instance_id"),
+		ServiceId:              query.Get("service_id"),
+		ServiceInstanceId:      query.Get("service_instance_id"),
+		MsgQueryFilter:         query.Get("msg_query_filter"),
+	}
+	from := query.Get("from")
+	if lastEventID := r.Header.Get(LastEventIDHeader); lastEventID != "" {
+		from = lastEventID
+	}
+	if from != "" {
+		parsed, err := strconv.ParseInt(from, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from value %q", from)
+		}
+		request.From = parsed
+	}
+	return request, nil
+}
+
+// ServeHTTP opens a Tail stream and forwards its responses until the client or the stream ends.
+func (h *TailEventHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	token := h.requestToken(r)
+	// The URL of the page must not leak to other sites, as it may contain the token.
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	request, err := tailRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The stream is canceled when the HTTP client disconnects.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, h.authHeader, token)
+	stream, err := h.client.Tail(ctx, request)
+	if err != nil {
+		http.Error(w, err.Error(), runtime.HTTPStatusFromCode(status.Code(err)))
+		return
+	}
+
+	started := false
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !started {
+				// No event has been sent, so the error can still be reported with the HTTP status.
+				http.Error(w, status.Convert(err).Message(), runtime.HTTPStatusFromCode(status.Code(err)))
+				return
+			}
+			fmt.Fprintf(w, "event: error\ndata: %s\n\n", status.Convert(err).Message())
+			flusher.Flush()
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Accel-Buffering", "no")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if wErr := h.writeEvent(w, response); wErr != nil {
+			log.Debug().Err(wErr).Msg("cannot write tail event")
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a response of the stream as an event.
+func (h *TailEventHandler) writeEvent(w io.Writer, response *grpc_public_api_go.TailResponse) error {
+	var data bytes.Buffer
+	if err := h.marshaler.Marshal(&data, response); err != nil {
+		return err
+	}
+	event := "entries"
+	if response.Heartbeat {
+		event = "heartbeat"
+	}
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", response.Cursor, event, data.String())
+	return err
+}
@@ -0,0 +1,390 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/timeexpr"
+	"github.com/satori/go.uuid"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// States of the alert rules.
+const (
+	AlertStateOK     = "OK"
+	AlertStateFiring = "FIRING"
+)
+
+// undecryptableSecret with the error shown for the rules whose webhook secret cannot be decrypted with the current key.
+const undecryptableSecret = "the webhook secret cannot be decrypted, the webhook secret key changed since the rule was added"
+
+// storedRule contains an alert rule and the encrypted secret used to sign its notifications, which is never returned.
+type storedRule struct {
+	Rule         *grpc_public_api_go.AlertRule `json:"rule"`
+	SealedSecret string                        `json:"sealed_webhook_secret,omitempty"`
+}
+
+// secretBox encrypts the webhook secrets with AES-GCM so they are not kept in cleartext.
+type secretBox struct {
+	aead cipher.AEAD
+}
+
+// newSecretBox creates a box with a key derived from a secret of the component.
+func newSecretBox(secret string) (*secretBox, derrors.Error) {
+	if secret == "" {
+		return nil, derrors.NewInvalidArgumentError("the key of the webhook secrets must be set")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("public-api webhook secrets"))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, derrors.NewInternalError("cannot create webhook secrets cipher", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, derrors.NewInternalError("cannot create webhook secrets cipher", err)
+	}
+	return &secretBox{aead: aead}, nil
+}
+
+// seal encrypts a secret. The result contains the nonce followed by the ciphertext, base64 encoded.
+func (b *secretBox) seal(secret string) (string, derrors.Error) {
+	if secret == "" {
+		return "", nil
+	}
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", derrors.NewInternalError("cannot generate nonce", err)
+	}
+	return base64.StdEncoding.EncodeToString(b.aead.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+// open decrypts a secret sealed by the box.
+func (b *secretBox) open(sealed string) (string, derrors.Error) {
+	if sealed == "" {
+		return "", nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", derrors.NewInternalError("invalid sealed webhook secret", err)
+	}
+	nonceSize := b.aead.NonceSize()
+	if len(raw) < nonceSize {
+		return "", derrors.NewInternalError("invalid sealed webhook secret")
+	}
+	secret, err := b.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+	if err != nil {
+		return "", derrors.NewInternalError("cannot decrypt webhook secret", err)
+	}
+	return string(secret), nil
+}
+
+// storeContent with the content persisted by the store.
+type storeContent struct {
+	Searches []*grpc_public_api_go.SavedSearch `json:"saved_searches"`
+	Rules    []*storedRule                     `json:"alert_rules"`
+}
+
+// SearchStore contains the saved searches and the alert rules of all the organizations. They are kept in memory
+// and, if a path is set, written to a JSON file on every change so they survive a restart. The webhook secrets are
+// encrypted both in memory and in the file.
+type SearchStore struct {
+	sync.Mutex
+	path     string
+	secrets  *secretBox
+	searches map[string]*grpc_public_api_go.SavedSearch
+	rules    map[string]*storedRule
+}
+
+// NewSearchStore creates a store, loading the content of the file if the path is set and the file exists. The
+// webhook secrets are encrypted with a key derived from secretKey.
+func NewSearchStore(path string, secretKey string) (*SearchStore, derrors.Error) {
+	secrets, sErr := newSecretBox(secretKey)
+	if sErr != nil {
+		return nil, sErr
+	}
+	store := &SearchStore{
+		path:     path,
+		secrets:  secrets,
+		searches: make(map[string]*grpc_public_api_go.SavedSearch, 0),
+		rules:    make(map[string]*storedRule, 0),
+	}
+	if path == "" {
+		return store, nil
+	}
+	raw, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, derrors.NewInternalError("cannot read saved searches", err).WithParams(path)
+	}
+	content := storeContent{}
+	if err := json.Unmarshal(raw, &content); err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot parse saved searches", err).WithParams(path)
+	}
+	for _, search := range content.Searches {
+		store.searches[storeKey(search.OrganizationId, search.SavedSearchId)] = search
+	}
+	for _, rule := range content.Rules {
+		store.rules[storeKey(rule.Rule.OrganizationId, rule.Rule.AlertRuleId)] = rule
+	}
+	return store, nil
+}
+
+// storeKey returns the key of an element of an organization in the store.
+func storeKey(organizationID string, id string) string {
+	return organizationID + "/" + id
+}
+
+// save writes the content of the store to its file. The store must be locked by the caller.
+func (s *SearchStore) save() derrors.Error {
+	if s.path == "" {
+		return nil
+	}
+	content := storeContent{
+		Searches: make([]*grpc_public_api_go.SavedSearch, 0, len(s.searches)),
+		Rules:    make([]*storedRule, 0, len(s.rules)),
+	}
+	for _, search := range s.searches {
+		content.Searches = append(content.Searches, search)
+	}
+	for _, rule := range s.rules {
+		content.Rules = append(content.Rules, rule)
+	}
+	raw, err := json.MarshalIndent(content, "", "  ")
+	if err != nil {
+		return derrors.NewInternalError("cannot marshal saved searches", err)
+	}
+	// The file is replaced at once so a failure does not leave it truncated.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return derrors.NewInternalError("cannot write saved searches", err).WithParams(s.path)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(raw)
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		return derrors.NewInternalError("cannot write saved searches", err).WithParams(s.path)
+	}
+	return nil
+}
+
+// AddSearch stores a new saved search.
+func (s *SearchStore) AddSearch(request *grpc_public_api_go.AddSavedSearchRequest) (*grpc_public_api_go.SavedSearch, derrors.Error) {
+	s.Lock()
+	defer s.Unlock()
+	for _, search := range s.searches {
+		if search.OrganizationId == request.OrganizationId && search.Name == request.Name {
+			return nil, derrors.NewAlreadyExistsError("saved search").WithParams(request.Name)
+		}
+	}
+	search := &grpc_public_api_go.SavedSearch{
+		OrganizationId: request.OrganizationId,
+		SavedSearchId:  uuid.NewV4().String(),
+		Name:           request.Name,
+		Query:          request.Query,
+		Since:          request.Since,
+		Created:        time.Now().Unix(),
+	}
+	key := storeKey(search.OrganizationId, search.SavedSearchId)
+	s.searches[key] = search
+	if err := s.save(); err != nil {
+		delete(s.searches, key)
+		return nil, err
+	}
+	return proto.Clone(search).(*grpc_public_api_go.SavedSearch), nil
+}
+
+// GetSearch retrieves a saved search.
+func (s *SearchStore) GetSearch(searchID *grpc_public_api_go.SavedSearchId) (*grpc_public_api_go.SavedSearch, derrors.Error) {
+	s.Lock()
+	defer s.Unlock()
+	search, exists := s.searches[storeKey(searchID.OrganizationId, searchID.SavedSearchId)]
+	if !exists {
+		return nil, derrors.NewNotFoundError("saved search").WithParams(searchID.SavedSearchId)
+	}
+	return proto.Clone(search).(*grpc_public_api_go.SavedSearch), nil
+}
+
+// ListSearches returns the saved searches of an organization sorted by name.
+func (s *SearchStore) ListSearches(organizationID string) []*grpc_public_api_go.SavedSearch {
+	s.Lock()
+	defer s.Unlock()
+	result := make([]*grpc_public_api_go.SavedSearch, 0)
+	for _, search := range s.searches {
+		if search.OrganizationId == organizationID {
+			result = append(result, proto.Clone(search).(*grpc_public_api_go.SavedSearch))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// RemoveSearch removes a saved search. The searches used by an alert rule cannot be removed.
+func (s *SearchStore) RemoveSearch(searchID *grpc_public_api_go.SavedSearchId) derrors.Error {
+	s.Lock()
+	defer s.Unlock()
+	key := storeKey(searchID.OrganizationId, searchID.SavedSearchId)
+	search, exists := s.searches[key]
+	if !exists {
+		return derrors.NewNotFoundError("saved search").WithParams(searchID.SavedSearchId)
+	}
+	for _, rule := range s.rules {
+		if rule.Rule.OrganizationId == searchID.OrganizationId && rule.Rule.SavedSearchId == searchID.SavedSearchId {
+			return derrors.NewFailedPreconditionError("the saved search is used by an alert rule").
+				WithParams(searchID.SavedSearchId, rule.Rule.Name)
+		}
+	}
+	delete(s.searches, key)
+	if err := s.save(); err != nil {
+		s.searches[key] = search
+		return err
+	}
+	return nil
+}
+
+// AddRule stores a new alert rule on an existing saved search.
+func (s *SearchStore) AddRule(request *grpc_public_api_go.AddAlertRuleRequest) (*grpc_public_api_go.AlertRule, derrors.Error) {
+	s.Lock()
+	defer s.Unlock()
+	if _, exists := s.searches[storeKey(request.OrganizationId, request.SavedSearchId)]; !exists {
+		return nil, derrors.NewNotFoundError("saved search").WithParams(request.SavedSearchId)
+	}
+	sealed, err := s.secrets.seal(request.WebhookSecret)
+	if err != nil {
+		return nil, err
+	}
+	rule := &storedRule{
+		Rule: &grpc_public_api_go.AlertRule{
+			OrganizationId: request.OrganizationId,
+			AlertRuleId:    uuid.NewV4().String(),
+			SavedSearchId:  request.SavedSearchId,
+			Name:           request.Name,
+			Interval:       request.Interval,
+			Threshold:      request.Threshold,
+			WebhookUrl:     request.WebhookUrl,
+			State:          AlertStateOK,
+			Created:        time.Now().Unix(),
+		},
+		SealedSecret: sealed,
+	}
+	key := storeKey(rule.Rule.OrganizationId, rule.Rule.AlertRuleId)
+	s.rules[key] = rule
+	if err := s.save(); err != nil {
+		delete(s.rules, key)
+		return nil, err
+	}
+	return proto.Clone(rule.Rule).(*grpc_public_api_go.AlertRule), nil
+}
+
+// ListRules returns the alert rules of an organization sorted by name. The rules whose webhook secret cannot be
+// decrypted report it as their last error, as they cannot notify until they are added again.
+func (s *SearchStore) ListRules(organizationID string) []*grpc_public_api_go.AlertRule {
+	s.Lock()
+	defer s.Unlock()
+	result := make([]*grpc_public_api_go.AlertRule, 0)
+	for _, rule := range s.rules {
+		if rule.Rule.OrganizationId == organizationID {
+			listed := proto.Clone(rule.Rule).(*grpc_public_api_go.AlertRule)
+			if _, err := s.secrets.open(rule.SealedSecret); err != nil {
+				listed.LastError = undecryptableSecret
+			}
+			result = append(result, listed)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// RemoveRule removes an alert rule.
+func (s *SearchStore) RemoveRule(ruleID *grpc_public_api_go.AlertRuleId) derrors.Error {
+	s.Lock()
+	defer s.Unlock()
+	key := storeKey(ruleID.OrganizationId, ruleID.AlertRuleId)
+	rule, exists := s.rules[key]
+	if !exists {
+		return derrors.NewNotFoundError("alert rule").WithParams(ruleID.AlertRuleId)
+	}
+	delete(s.rules, key)
+	if err := s.save(); err != nil {
+		s.rules[key] = rule
+		return err
+	}
+	return nil
+}
+
+// dueRules returns a copy of the alert rules whose interval has elapsed since their last evaluation.
+func (s *SearchStore) dueRules(now time.Time) []storedRule {
+	s.Lock()
+	defer s.Unlock()
+	result := make([]storedRule, 0)
+	for _, rule := range s.rules {
+		interval, err := timeexpr.ParseDuration(rule.Rule.Interval)
+		if err != nil {
+			continue
+		}
+		if rule.Rule.LastRun == 0 || now.Sub(time.Unix(rule.Rule.LastRun, 0)) >= interval {
+			result = append(result, storedRule{
+				Rule:         proto.Clone(rule.Rule).(*grpc_public_api_go.AlertRule),
+				SealedSecret: rule.SealedSecret,
+			})
+		}
+	}
+	return result
+}
+
+// updateRule records the result of the evaluation of an alert rule. The rules removed in the meantime are ignored.
+func (s *SearchStore) updateRule(rule *grpc_public_api_go.AlertRule) derrors.Error {
+	s.Lock()
+	defer s.Unlock()
+	stored, exists := s.rules[storeKey(rule.OrganizationId, rule.AlertRuleId)]
+	if !exists {
+		return nil
+	}
+	stored.Rule = proto.Clone(rule).(*grpc_public_api_go.AlertRule)
+	return s.save()
+}
+
+// webhookSecret returns the secret of a rule returned by dueRules.
+func (s *SearchStore) webhookSecret(rule storedRule) (string, derrors.Error) {
+	return s.secrets.open(rule.SealedSecret)
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/public-api/internal/pkg/entities"
+	"github.com/nalej/public-api/internal/pkg/server/common"
+	"github.com/rs/zerolog/log"
+	"sort"
+	"time"
+)
+
+// DefaultTailPollInterval with the time between two searches of the upstream component while tailing.
+const DefaultTailPollInterval = time.Second
+
+// DefaultTailHeartbeat with the maximum time a tail stream stays silent before sending a heartbeat, so clients and
+// proxies can tell an idle stream from a broken connection.
+const DefaultTailHeartbeat = time.Second * 15
+
+// DefaultTailMaxDuration limits the life of a tail stream. Clients reconnect sending the last cursor as From.
+const DefaultTailMaxDuration = time.Hour
+
+// tailConfig contains the timing of the tail streams.
+type tailConfig struct {
+	pollInterval time.Duration
+	heartbeat    time.Duration
+	maxDuration  time.Duration
+}
+
+// tailCursor tracks the position of a tail stream. The upstream search is inclusive on From, so the identities
+// of the entries sharing the timestamp of the cursor are kept to skip them in the next search. A stream starts
+// after its initial timestamp, so a client resuming from the cursor of a previous stream does not receive again
+// the entries sharing that timestamp. A descending cursor moves back in time, as used by the queries that read
+// the newest entries first.
+type tailCursor struct {
+	timestamp int64
+	seen      map[string]bool
+	// initial is set until the cursor moves from its initial timestamp.
+	initial bool
+	// descending is set if the cursor moves from the newest entries to the oldest ones.
+	descending bool
+	// pageSize with the largest page returned by the upstream component. A shorter page is the last one.
+	pageSize int
+}
+
+func newTailCursor(from int64) *tailCursor {
+	return &tailCursor{timestamp: from, seen: make(map[string]bool, 0), initial: true}
+}
+
+// entryIdentity identifies a log entry regardless of the search that returned it.
+func entryIdentity(entry *grpc_application_manager_go.LogEntryResponse) string {
+	return fmt.Sprintf("%s/%s/%s/%d/%s", entry.AppInstanceId, entry.ServiceGroupInstanceId, entry.ServiceInstanceId,
+		entry.Timestamp, entry.Msg)
+}
+
+// after checks whether a timestamp comes after another one in the direction of the cursor.
+func (c *tailCursor) after(timestamp int64, other int64) bool {
+	if c.descending {
+		return timestamp < other
+	}
+	return timestamp > other
+}
+
+// advance returns the entries not sent yet, sorted in the direction of the cursor, and moves the cursor to the
+// last of them.
+func (c *tailCursor) advance(entries []*grpc_application_manager_go.LogEntryResponse) []*grpc_application_manager_go.LogEntryResponse {
+	pending := make([]*grpc_application_manager_go.LogEntryResponse, 0)
+	for _, entry := range entries {
+		if c.after(c.timestamp, entry.Timestamp) || (entry.Timestamp == c.timestamp && c.initial) {
+			continue
+		}
+		identity := entryIdentity(entry)
+		if entry.Timestamp == c.timestamp && c.seen[identity] {
+			continue
+		}
+		pending = append(pending, entry)
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		return c.after(pending[j].Timestamp, pending[i].Timestamp)
+	})
+	for _, entry := range pending {
+		if c.after(entry.Timestamp, c.timestamp) {
+			c.timestamp = entry.Timestamp
+			c.seen = make(map[string]bool, 0)
+			c.initial = false
+		}
+		c.seen[entryIdentity(entry)] = true
+	}
+	return pending
+}
+
+// Tail sends the log entries matching a query as they are received by the upstream component. The stream starts
+// after request.From, which is the cursor of a previous stream when resuming, or at the current time if not set,
+// and ends without error when the client disconnects or the maximum duration is reached.
+func (m *Manager) Tail(request *grpc_public_api_go.SearchRequest, stream grpc_public_api_go.UnifiedLogging_TailServer) error {
+	log.Debug().Interface("request", request).Msg("Tail request")
+	from := request.From
+	if from == 0 {
+		from = time.Now().UnixNano()
+	}
+	cursor := newTailCursor(from)
+	search := entities.NewSearchRequest(request)
+	search.To = 0
+	// The upstream component returns the first entries from the cursor, so a burst is retrieved page by page.
+	search.NFirst = true
+
+	// The first heartbeat confirms the stream to the client and contains the initial cursor.
+	if err := m.tailSend(request, stream, cursor, nil); err != nil {
+		return err
+	}
+
+	poll := time.NewTicker(m.tail.pollInterval)
+	defer poll.Stop()
+	deadline := time.NewTimer(m.tail.maxDuration)
+	defer deadline.Stop()
+	lastSent := time.Now()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Debug().Str("organizationID", request.OrganizationId).Msg("tail stream closed by the client")
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-poll.C:
+			sent, err := m.tailPoll(request, stream, cursor, search)
+			if err != nil {
+				if stream.Context().Err() != nil {
+					return nil
+				}
+				return err
+			}
+			if sent {
+				lastSent = time.Now()
+				continue
+			}
+			if time.Since(lastSent) < m.tail.heartbeat {
+				continue
+			}
+			if err := m.tailSend(request, stream, cursor, nil); err != nil {
+				return err
+			}
+			lastSent = time.Now()
+		}
+	}
+}
+
+// tailPoll sends the entries received after the cursor, a response per page, and returns whether any entry was
+// sent. Each page starts at the timestamp of the cursor and the search continues until a page comes back shorter
+// than the largest one received. A full page without new entries is full of entries at the timestamp of the
+// cursor, so the search continues after it.
+func (m *Manager) tailPoll(request *grpc_public_api_go.SearchRequest, stream grpc_public_api_go.UnifiedLogging_TailServer,
+	cursor *tailCursor, search *grpc_application_manager_go.SearchRequest) (bool, error) {
+	sent := false
+	search.From = cursor.timestamp
+	for {
+		page, err := m.tailSearch(stream.Context(), search)
+		if err != nil {
+			return sent, err
+		}
+		full := len(page) > 0 && len(page) >= cursor.pageSize
+		if len(page) > cursor.pageSize {
+			cursor.pageSize = len(page)
+		}
+		pending := cursor.advance(page)
+		if len(pending) > 0 {
+			if err := m.tailSend(request, stream, cursor, pending); err != nil {
+				return sent, err
+			}
+			sent = true
+		}
+		switch {
+		case !full:
+			return sent, nil
+		case len(pending) > 0:
+			search.From = cursor.timestamp
+		case search.From > cursor.timestamp:
+			return sent, nil
+		default:
+			search.From = cursor.timestamp + 1
+		}
+	}
+}
+
+// tailSend sends the pending entries of a stream, or a heartbeat if there are none. Errors caused by the client
+// disconnecting are ignored.
+func (m *Manager) tailSend(request *grpc_public_api_go.SearchRequest, stream grpc_public_api_go.UnifiedLogging_TailServer,
+	cursor *tailCursor, pending []*grpc_application_manager_go.LogEntryResponse) error {
+	err := stream.Send(&grpc_public_api_go.TailResponse{
+		OrganizationId: request.OrganizationId,
+		Entries:        pending,
+		Cursor:         cursor.timestamp,
+		Heartbeat:      len(pending) == 0,
+	})
+	if err != nil && stream.Context().Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// tailSearch retrieves the entries from the cursor of a tail stream. The context derives from the one of the
+// stream, so the search is canceled when the client disconnects.
+func (m *Manager) tailSearch(ctx context.Context, search *grpc_application_manager_go.SearchRequest) ([]*grpc_application_manager_go.LogEntryResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, common.DefaultTimeout)
+	defer cancel()
+	response, err := m.unifiedLoggingClient.Search(ctx, search)
+	if err != nil {
+		return nil, err
+	}
+	return response.Entries, nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"github.com/nalej/grpc-application-manager-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"sync"
+	"time"
+)
+
+// tailStream captures the responses of a tail stream.
+type tailStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	lock      sync.Mutex
+	responses []*grpc_public_api_go.TailResponse
+}
+
+func (s *tailStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *tailStream) Send(response *grpc_public_api_go.TailResponse) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.responses = append(s.responses, response)
+	return nil
+}
+
+func (s *tailStream) sent() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.responses)
+}
+
+// received returns the messages of the entries sent.
+func (s *tailStream) received() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	result := make([]string, 0)
+	for _, response := range s.responses {
+		for _, entry := range response.Entries {
+			result = append(result, entry.Msg)
+		}
+	}
+	return result
+}
+
+// emptySearch is an upstream component without log entries.
+type emptySearch struct {
+	grpc_application_manager_go.UnifiedLoggingClient
+}
+
+func (e *emptySearch) Search(_ context.Context, _ *grpc_application_manager_go.SearchRequest, _ ...grpc.CallOption) (*grpc_application_manager_go.LogResponse, error) {
+	return &grpc_application_manager_go.LogResponse{}, nil
+}
+
+var _ = ginkgo.Describe("Tail", func() {
+
+	entry := func(timestamp int64, msg string) *grpc_application_manager_go.LogEntryResponse {
+		return &grpc_application_manager_go.LogEntryResponse{AppInstanceId: "app", ServiceInstanceId: "service", Timestamp: timestamp, Msg: msg}
+	}
+
+	ginkgo.It("should only return the entries after the cursor", func() {
+		cursor := newTailCursor(10)
+		pending := cursor.advance([]*grpc_application_manager_go.LogEntryResponse{entry(12, "b"), entry(9, "old"), entry(11, "a")})
+		gomega.Expect(len(pending)).To(gomega.Equal(2))
+		gomega.Expect(pending[0].Msg).To(gomega.Equal("a"))
+		gomega.Expect(cursor.timestamp).To(gomega.Equal(int64(12)))
+
+		pending = cursor.advance([]*grpc_application_manager_go.LogEntryResponse{entry(12, "b"), entry(12, "c"), entry(13, "d")})
+		gomega.Expect(len(pending)).To(gomega.Equal(2))
+		gomega.Expect(pending[0].Msg).To(gomega.Equal("c"))
+		gomega.Expect(pending[1].Msg).To(gomega.Equal("d"))
+
+		gomega.Expect(cursor.advance([]*grpc_application_manager_go.LogEntryResponse{entry(13, "d")})).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should start after the initial cursor", func() {
+		cursor := newTailCursor(10)
+		pending := cursor.advance([]*grpc_application_manager_go.LogEntryResponse{entry(10, "sent"), entry(11, "a")})
+		gomega.Expect(len(pending)).To(gomega.Equal(1))
+		gomega.Expect(pending[0].Msg).To(gomega.Equal("a"))
+		pending = cursor.advance([]*grpc_application_manager_go.LogEntryResponse{entry(11, "a"), entry(11, "b")})
+		gomega.Expect(len(pending)).To(gomega.Equal(1))
+		gomega.Expect(pending[0].Msg).To(gomega.Equal("b"))
+	})
+
+	ginkgo.It("should send heartbeats and end when the client disconnects", func() {
+		manager := Manager{
+			unifiedLoggingClient: &emptySearch{},
+			tail:                 tailConfig{pollInterval: time.Millisecond * 5, heartbeat: time.Millisecond * 20, maxDuration: time.Minute},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &tailStream{ctx: ctx}
+		done := make(chan error)
+		go func() {
+			done <- manager.Tail(&grpc_public_api_go.SearchRequest{OrganizationId: "org"}, stream)
+		}()
+		gomega.Eventually(stream.sent, time.Second).Should(gomega.BeNumerically(">=", 3))
+		cancel()
+		gomega.Eventually(done, time.Second).Should(gomega.Receive(gomega.BeNil()))
+		for _, response := range stream.responses {
+			gomega.Expect(response.Heartbeat).To(gomega.BeTrue())
+		}
+	})
+
+	ginkgo.It("should send a burst larger than a page of the upstream component", func() {
+		search := &pagedSearch{
+			entries: []*grpc_application_manager_go.LogEntryResponse{
+				entry(1, "before"), entry(2, "a"), entry(3, "b"), entry(3, "c"), entry(4, "d"), entry(5, "e"),
+				entry(6, "f"), entry(7, "g"), entry(7, "h"), entry(8, "i")},
+			pageSize: 3,
+		}
+		manager := Manager{
+			unifiedLoggingClient: search,
+			tail:                 tailConfig{pollInterval: time.Millisecond * 5, heartbeat: time.Minute, maxDuration: time.Minute},
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		stream := &tailStream{ctx: ctx}
+		done := make(chan error)
+		go func() {
+			done <- manager.Tail(&grpc_public_api_go.SearchRequest{OrganizationId: "org", From: 1}, stream)
+		}()
+		expected := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+		gomega.Eventually(stream.received, time.Second).Should(gomega.Equal(expected))
+		cancel()
+		gomega.Eventually(done, time.Second).Should(gomega.Receive(gomega.BeNil()))
+		gomega.Expect(stream.received()).To(gomega.Equal(expected))
+		for _, request := range search.requests {
+			gomega.Expect(request.NFirst).To(gomega.BeTrue())
+		}
+	})
+})
@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/rs/zerolog/log"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Events sent to the webhook of an alert rule.
+const (
+	AlertEventFiring   = "FIRING"
+	AlertEventResolved = "RESOLVED"
+)
+
+// SignatureHeader with the header that contains the HMAC-SHA256 of the body, signed with the secret of the rule.
+const SignatureHeader = "X-Nalej-Signature"
+
+// DefaultWebhookTimeout with the deadline of each delivery attempt.
+const DefaultWebhookTimeout = time.Second * 10
+
+// DefaultWebhookRetries with the number of delivery attempts of a notification.
+const DefaultWebhookRetries = 3
+
+// DefaultWebhookRetrySleep with the time between two delivery attempts.
+const DefaultWebhookRetrySleep = time.Second * 2
+
+// AlertEvent with the notification sent to the webhook of an alert rule when its threshold is crossed.
+type AlertEvent struct {
+	Event          string `json:"event"`
+	OrganizationID string `json:"organization_id"`
+	AlertRuleID    string `json:"alert_rule_id"`
+	Name           string `json:"name"`
+	SavedSearchID  string `json:"saved_search_id"`
+	Query          string `json:"query"`
+	// Count with the number of entries matched in the window of the evaluation.
+	Count     int64 `json:"count"`
+	Threshold int64 `json:"threshold"`
+	// From and To with the window of the evaluation in nanoseconds.
+	From      int64 `json:"from"`
+	To        int64 `json:"to"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// WebhookNotifier delivers the alert events to the webhooks.
+type WebhookNotifier struct {
+	client     *http.Client
+	retries    int
+	retrySleep time.Duration
+}
+
+// NewWebhookNotifier creates a notifier with the default timeout and retries. The connections are opened through
+// the guard, without proxies, and the redirections are not followed so they cannot lead to an internal address.
+func NewWebhookNotifier(guard *WebhookGuard) *WebhookNotifier {
+	client := &http.Client{
+		Timeout: DefaultWebhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         guard.DialContext,
+			TLSHandshakeTimeout: DefaultWebhookTimeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     time.Second * 90,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &WebhookNotifier{
+		client:     client,
+		retries:    DefaultWebhookRetries,
+		retrySleep: DefaultWebhookRetrySleep,
+	}
+}
+
+// Signature returns the value of the signature header of a body.
+func Signature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Notify posts an event to a webhook. The body is signed if the secret is set. The server errors and the failed
+// connections are retried, the rest of the responses other than 2xx are reported at once.
+func (n *WebhookNotifier) Notify(ctx context.Context, webhookURL string, secret string, event AlertEvent) derrors.Error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return derrors.NewInternalError("cannot marshal alert event", err)
+	}
+	var lastErr derrors.Error
+	for attempt := 0; attempt < n.retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return derrors.NewCanceledError("webhook notification cancelled", ctx.Err())
+			case <-time.After(n.retrySleep):
+			}
+		}
+		retry, dErr := n.post(ctx, webhookURL, secret, body)
+		if dErr == nil {
+			return nil
+		}
+		lastErr = dErr
+		if !retry {
+			return dErr
+		}
+		log.Warn().Str("webhook", webhookURL).Int("attempt", attempt+1).Str("err", dErr.Error()).Msg("webhook delivery failed")
+	}
+	return lastErr
+}
+
+// post sends a single delivery attempt. It returns whether the attempt can be retried.
+func (n *WebhookNotifier) post(ctx context.Context, webhookURL string, secret string, body []byte) (bool, derrors.Error) {
+	request, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return false, derrors.NewInvalidArgumentError("invalid webhook", err).WithParams(webhookURL)
+	}
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		request.Header.Set(SignatureHeader, Signature(secret, body))
+	}
+	response, err := n.client.Do(request)
+	if err != nil {
+		return true, derrors.NewUnavailableError("cannot reach webhook", err).WithParams(webhookURL)
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	statusErr := derrors.NewUnavailableError(fmt.Sprintf("webhook answered %s", response.Status)).WithParams(webhookURL)
+	return response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests, statusErr
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package unified_logging
+
+import (
+	"context"
+	"fmt"
+	"github.com/nalej/derrors"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultWebhookResolveTimeout with the deadline to resolve the host of a webhook when a rule is added.
+const DefaultWebhookResolveTimeout = time.Second * 5
+
+// blockedNetworks with the address ranges the webhooks cannot reach: loopback, private, link-local, shared
+// address space used by some cluster networks, multicast and reserved ranges.
+var blockedNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "fc00::/7", "fe80::/10", "ff00::/8")
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	result := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, network)
+	}
+	return result
+}
+
+// publicIP checks whether an address is outside the blocked ranges. IPv4-mapped IPv6 addresses are checked as IPv4.
+func publicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// WebhookGuard restricts the destinations of the alert webhooks so the rules cannot be used to reach the
+// components of the platform. If the operator sets a list of allowed hosts only those are reachable, and they are
+// trusted even if they resolve to an internal address. Otherwise any host is reachable as long as all its
+// addresses are public. The addresses are checked when a rule is added and again on every connection, so a host
+// cannot change its records to an internal address after the rule is accepted.
+type WebhookGuard struct {
+	// allowedHosts with the hosts set by the operator. An entry starting with a dot matches the subdomains.
+	allowedHosts []string
+	resolver     *net.Resolver
+}
+
+// NewWebhookGuard creates a guard with the hosts allowed by the operator, if any.
+func NewWebhookGuard(allowedHosts []string) *WebhookGuard {
+	hosts := make([]string, 0, len(allowedHosts))
+	for _, host := range allowedHosts {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return &WebhookGuard{allowedHosts: hosts, resolver: net.DefaultResolver}
+}
+
+// allowed checks whether a host is in the list set by the operator.
+func (g *WebhookGuard) allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range g.allowedHosts {
+		if host == entry || (strings.HasPrefix(entry, ".") && strings.HasSuffix(host, entry)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost checks whether a host can be reached before resolving it. It returns whether the host is trusted.
+func (g *WebhookGuard) checkHost(host string) (bool, derrors.Error) {
+	if len(g.allowedHosts) == 0 {
+		return false, nil
+	}
+	if !g.allowed(host) {
+		return false, derrors.NewPermissionDeniedError("webhook host is not allowed").WithParams(host)
+	}
+	return true, nil
+}
+
+// CheckURL checks that a webhook is reachable resolving its host.
+func (g *WebhookGuard) CheckURL(webhookURL string) derrors.Error {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil {
+		return derrors.NewInvalidArgumentError("invalid webhook", err).WithParams(webhookURL)
+	}
+	host := parsed.Hostname()
+	trusted, dErr := g.checkHost(host)
+	if dErr != nil || trusted {
+		return dErr
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultWebhookResolveTimeout)
+	defer cancel()
+	addresses, err := g.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return derrors.NewInvalidArgumentError("cannot resolve webhook host", err).WithParams(host)
+	}
+	for _, address := range addresses {
+		if !publicIP(address.IP) {
+			return derrors.NewPermissionDeniedError("webhook host resolves to an internal address").WithParams(host, address.IP.String())
+		}
+	}
+	return nil
+}
+
+// control is set on the dialer of the untrusted hosts. It runs once the address is resolved, just before the
+// connection is opened.
+func control(network string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !publicIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// DialContext opens the connections of the webhook notifier applying the restrictions of the guard.
+func (g *WebhookGuard) DialContext(ctx context.Context, network string, address string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	trusted, dErr := g.checkHost(host)
+	if dErr != nil {
+		return nil, dErr
+	}
+	dialer := &net.Dialer{Timeout: DefaultWebhookTimeout, KeepAlive: time.Second * 30}
+	if !trusted {
+		dialer.Control = control
+	}
+	return dialer.DialContext(ctx, network, address)
+}
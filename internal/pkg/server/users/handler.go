@@ -52,7 +52,7 @@ func (h *Handler) Add(ctx context.Context, addUserRequest *grpc_public_api_go.Ad
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Add(addUserRequest)
+	return h.Manager.Add(ctx, addUserRequest)
 }
 
 func (h *Handler) Info(ctx context.Context, userID *grpc_user_go.UserId) (*grpc_public_api_go.User, error) {
@@ -70,7 +70,7 @@ func (h *Handler) Info(ctx context.Context, userID *grpc_user_go.UserId) (*grpc_
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Info(userID)
+	return h.Manager.Info(ctx, userID)
 }
 
 func (h *Handler) List(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.UserList, error) {
@@ -85,7 +85,7 @@ func (h *Handler) List(ctx context.Context, organizationID *grpc_organization_go
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.List(organizationID)
+	return h.Manager.List(ctx, organizationID)
 }
 
 func (h *Handler) Delete(ctx context.Context, userID *grpc_user_go.UserId) (*grpc_common_go.Success, error) {
@@ -101,7 +101,7 @@ func (h *Handler) Delete(ctx context.Context, userID *grpc_user_go.UserId) (*grp
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Delete(userID)
+	return h.Manager.Delete(ctx, userID)
 }
 
 func (h *Handler) ChangePassword(ctx context.Context, changePasswordRequest *grpc_user_manager_go.ChangePasswordRequest) (*grpc_common_go.Success, error) {
@@ -119,7 +119,7 @@ func (h *Handler) ChangePassword(ctx context.Context, changePasswordRequest *grp
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.ResetPassword(changePasswordRequest)
+	return h.Manager.ResetPassword(ctx, changePasswordRequest)
 }
 
 func (h *Handler) Update(ctx context.Context, updateUserRequest *grpc_user_go.UpdateUserRequest) (*grpc_common_go.Success, error) {
@@ -138,5 +138,5 @@ func (h *Handler) Update(ctx context.Context, updateUserRequest *grpc_user_go.Up
 	if err != nil {
 		return nil, conversions.ToGRPCError(err)
 	}
-	return h.Manager.Update(updateUserRequest)
+	return h.Manager.Update(ctx, updateUserRequest)
 }
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package users
+
+import (
+	"github.com/nalej/authx/pkg/interceptor"
+	"github.com/nalej/grpc-authx-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-public-api-go"
+	"github.com/nalej/grpc-user-go"
+	"github.com/nalej/grpc-user-manager-go"
+	"github.com/nalej/grpc-utils/pkg/test"
+	"github.com/nalej/public-api/internal/pkg/server/fakes"
+	"github.com/nalej/public-api/internal/pkg/server/ithelpers"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+var _ = ginkgo.Describe("Users handler", func() {
+
+	var platform *fakes.Platform
+	var server *grpc.Server
+	var listener *bufconn.Listener
+	var conn *grpc.ClientConn
+	var client grpc_public_api_go.UsersClient
+
+	var organizationID string
+	var token string
+
+	ginkgo.BeforeEach(func() {
+		platform = fakes.NewPlatform()
+		gomega.Expect(platform.Start()).To(gomega.Succeed())
+
+		organizationID = ithelpers.GenerateUUID()
+		platform.Store.AddRole(&grpc_authx_go.Role{OrganizationId: organizationID, Name: "Developer"})
+		platform.Store.AddUser(&grpc_user_manager_go.User{
+			OrganizationId: organizationID,
+			Email:          "internal@nalej.com",
+			InternalRole:   true,
+		})
+
+		listener = test.GetDefaultListener()
+		server = grpc.NewServer(interceptor.WithServerAuthxInterceptor(interceptor.NewConfig(ithelpers.GetAllAuthConfig(), "secret", ithelpers.AuthHeader)))
+		manager := NewManager(grpc_user_manager_go.NewUserManagerClient(platform.Conn()))
+		grpc_public_api_go.RegisterUsersServer(server, NewHandler(manager))
+		test.LaunchServer(server, listener)
+
+		var err error
+		conn, err = test.GetConn(*listener)
+		gomega.Expect(err).To(gomega.Succeed())
+		client = grpc_public_api_go.NewUsersClient(conn)
+
+		token = ithelpers.GenerateToken("email@nalej.com", organizationID, "Owner", "secret",
+			[]grpc_authx_go.AccessPrimitive{grpc_authx_go.AccessPrimitive_ORG})
+	})
+
+	ginkgo.AfterEach(func() {
+		conn.Close()
+		server.Stop()
+		listener.Close()
+		platform.Stop()
+	})
+
+	addRequest := func(roleName string) *grpc_public_api_go.AddUserRequest {
+		return &grpc_public_api_go.AddUserRequest{
+			OrganizationId: organizationID,
+			Email:          "dev@nalej.com",
+			Password:       "password",
+			Name:           "name",
+			LastName:       "last name",
+			Title:          "title",
+			RoleName:       roleName,
+		}
+	}
+
+	ginkgo.It("should add a user resolving the role by name", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		added, err := client.Add(ctx, addRequest("Developer"))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(added.RoleName).To(gomega.Equal("Developer"))
+
+		info, err := client.Info(ctx, &grpc_user_go.UserId{OrganizationId: organizationID, Email: "dev@nalej.com"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(info.Name).To(gomega.Equal("name"))
+	})
+
+	ginkgo.It("should not add a user with an unknown role", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.Add(ctx, addRequest("Unknown"))
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.InvalidArgument))
+		gomega.Expect(platform.Faults.Calls("UserManager/AddUser")).To(gomega.Equal(0))
+	})
+
+	ginkgo.It("should hide the users with internal roles", func() {
+		ctx, cancel := ithelpers.GetContext(token)
+		defer cancel()
+		_, err := client.Add(ctx, addRequest("Developer"))
+		gomega.Expect(err).To(gomega.Succeed())
+		list, err := client.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(len(list.Users)).To(gomega.Equal(1))
+		gomega.Expect(list.Users[0].Email).To(gomega.Equal("dev@nalej.com"))
+	})
+
+})
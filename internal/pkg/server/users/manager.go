@@ -17,6 +17,7 @@
 package users
 
 import (
+	"context"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-organization-go"
@@ -37,11 +38,11 @@ func NewManager(client grpc_user_manager_go.UserManagerClient) Manager {
 	return Manager{client}
 }
 
-func (m *Manager) Add(addUserRequest *grpc_public_api_go.AddUserRequest) (*grpc_public_api_go.User, error) {
+func (m *Manager) Add(ctx context.Context, addUserRequest *grpc_public_api_go.AddUserRequest) (*grpc_public_api_go.User, error) {
 	orgID := &grpc_organization_go.OrganizationId{
 		OrganizationId: addUserRequest.OrganizationId,
 	}
-	ctx, cancel := common.GetContext()
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	role, err := m.umClient.ListRoles(ctx, orgID)
 	if err != nil {
@@ -68,7 +69,7 @@ func (m *Manager) Add(addUserRequest *grpc_public_api_go.AddUserRequest) (*grpc_
 		Title:          addUserRequest.Title,
 		RoleId:         roleId,
 	}
-	ctx2, cancel2 := common.GetContext()
+	ctx2, cancel2 := common.GetContext(ctx)
 	defer cancel2()
 	added, err := m.umClient.AddUser(ctx2, toAdd)
 	if err != nil {
@@ -90,8 +91,8 @@ func (m *Manager) Add(addUserRequest *grpc_public_api_go.AddUserRequest) (*grpc_
 	}, nil
 }
 
-func (m *Manager) Info(userID *grpc_user_go.UserId) (*grpc_public_api_go.User, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Info(ctx context.Context, userID *grpc_user_go.UserId) (*grpc_public_api_go.User, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	retrieved, err := m.umClient.GetUser(ctx, userID)
 	if err != nil {
@@ -114,8 +115,8 @@ func (m *Manager) Info(userID *grpc_user_go.UserId) (*grpc_public_api_go.User, e
 	}, nil
 }
 
-func (m *Manager) List(organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.UserList, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) List(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) (*grpc_public_api_go.UserList, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	list, err := m.umClient.ListUsers(ctx, organizationID)
 	if err != nil {
@@ -146,20 +147,20 @@ func (m *Manager) List(organizationID *grpc_organization_go.OrganizationId) (*gr
 	}, nil
 }
 
-func (m *Manager) Delete(userID *grpc_user_go.UserId) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Delete(ctx context.Context, userID *grpc_user_go.UserId) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.umClient.RemoveUser(ctx, userID)
 }
 
-func (m *Manager) Update(updateUserRequest *grpc_user_go.UpdateUserRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) Update(ctx context.Context, updateUserRequest *grpc_user_go.UpdateUserRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.umClient.Update(ctx, updateUserRequest)
 }
 
-func (m *Manager) ResetPassword(changePasswordRequest *grpc_user_manager_go.ChangePasswordRequest) (*grpc_common_go.Success, error) {
-	ctx, cancel := common.GetContext()
+func (m *Manager) ResetPassword(ctx context.Context, changePasswordRequest *grpc_user_manager_go.ChangePasswordRequest) (*grpc_common_go.Success, error) {
+	ctx, cancel := common.GetContext(ctx)
 	defer cancel()
 	return m.umClient.ChangePassword(ctx, changePasswordRequest)
 }
@@ -0,0 +1,240 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package timeexpr contains the parser of the time expressions accepted by the time flags of the CLI. Besides the
+// absolute dates, such as 2020-05-04T10:00:00Z or "2020-05-04 10:00", it accepts the times relative to the current
+// one, such as -15m, now-2h, today+8h or yesterday. An IANA time zone at the end, as in "yesterday Europe/Madrid",
+// sets the location of the expression.
+package timeexpr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/araddon/dateparse"
+	"github.com/nalej/derrors"
+)
+
+const (
+	// Now is the current time.
+	Now = "now"
+	// Today is the start of the current day.
+	Today = "today"
+	// Yesterday is the start of the previous day.
+	Yesterday = "yesterday"
+)
+
+// dayUnits with the length of the units that time.ParseDuration does not support.
+var dayUnits = map[string]time.Duration{
+	"d": time.Hour * 24,
+	"w": time.Hour * 24 * 7,
+}
+
+// ParseDuration parses a duration as time.ParseDuration, also accepting days and weeks, as in 1d12h or 2w. As in
+// time.ParseDuration, 0 is accepted without unit.
+func ParseDuration(expr string) (time.Duration, derrors.Error) {
+	invalid := derrors.NewInvalidArgumentError("invalid duration").WithParams(expr)
+	raw := strings.TrimSpace(expr)
+	if raw == "0" {
+		return 0, nil
+	}
+	negative := strings.HasPrefix(raw, "-")
+	raw = strings.TrimLeft(raw, "+-")
+	if raw == "" {
+		return 0, invalid
+	}
+	var normalized strings.Builder
+	for raw != "" {
+		number := strings.IndexFunc(raw, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if number <= 0 {
+			return 0, invalid
+		}
+		unit := strings.IndexFunc(raw[number:], func(r rune) bool { return r >= '0' && r <= '9' })
+		if unit < 0 {
+			unit = len(raw) - number
+		}
+		value, symbol := raw[:number], raw[number:number+unit]
+		if length, found := dayUnits[symbol]; found {
+			days, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return 0, invalid
+			}
+			value, symbol = fmt.Sprintf("%g", days*length.Hours()), "h"
+		}
+		normalized.WriteString(value + symbol)
+		raw = raw[number+unit:]
+	}
+	duration, err := time.ParseDuration(normalized.String())
+	if err != nil {
+		return 0, invalid
+	}
+	if negative {
+		return -duration, nil
+	}
+	return duration, nil
+}
+
+// splitZone separates the time zone at the end of an expression, returning the location of the expression.
+func splitZone(expr string, local *time.Location) (string, *time.Location) {
+	separator := strings.LastIndex(expr, " ")
+	if separator < 0 {
+		return expr, local
+	}
+	loc, err := time.LoadLocation(expr[separator+1:])
+	if err != nil {
+		return expr, local
+	}
+	return strings.TrimSpace(expr[:separator]), loc
+}
+
+// anchor returns the time named by a keyword and the rest of the expression.
+func anchor(expr string, now time.Time) (time.Time, string, bool) {
+	lower := strings.ToLower(expr)
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch {
+	case strings.HasPrefix(lower, Yesterday):
+		return midnight.AddDate(0, 0, -1), expr[len(Yesterday):], true
+	case strings.HasPrefix(lower, Today):
+		return midnight, expr[len(Today):], true
+	case strings.HasPrefix(lower, Now):
+		return now, expr[len(Now):], true
+	case strings.HasPrefix(lower, "-") || strings.HasPrefix(lower, "+"):
+		return now, expr, true
+	}
+	return time.Time{}, expr, false
+}
+
+// Parse parses a time expression. The relative expressions and the dates without a time zone are resolved in the
+// location of now.
+func Parse(expr string, now time.Time) (time.Time, derrors.Error) {
+	invalid := derrors.NewInvalidArgumentError("invalid time expression").WithParams(expr)
+	raw, loc := splitZone(strings.TrimSpace(expr), now.Location())
+	if raw == "" {
+		return time.Time{}, invalid
+	}
+	now = now.In(loc)
+
+	if base, offset, found := anchor(raw, now); found {
+		offset = strings.Replace(offset, " ", "", -1)
+		if offset == "" {
+			return base, nil
+		}
+		if offset[0] != '-' && offset[0] != '+' {
+			return time.Time{}, invalid
+		}
+		duration, err := ParseDuration(offset)
+		if err != nil {
+			return time.Time{}, invalid
+		}
+		return base.Add(duration), nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+		return t, nil
+	}
+	t, err := dateparse.ParseIn(raw, loc)
+	if err != nil {
+		return time.Time{}, invalid
+	}
+	return t, nil
+}
+
+// Range of time. The zero times leave the range open.
+type Range struct {
+	From time.Time
+	To   time.Time
+}
+
+// ParseRange parses the limits of a range. Since is a duration that sets the start of the range before now, and
+// cannot be combined with from. The start must be before the end.
+func ParseRange(from string, to string, since string, now time.Time) (*Range, derrors.Error) {
+	result := &Range{}
+	if since != "" {
+		if from != "" {
+			return nil, derrors.NewInvalidArgumentError("since and from cannot be used together")
+		}
+		duration, err := ParseDuration(since)
+		if err != nil {
+			return nil, err
+		}
+		if duration <= 0 {
+			return nil, derrors.NewInvalidArgumentError("since must be a positive duration").WithParams(since)
+		}
+		result.From = now.Add(-duration)
+	}
+	if from != "" {
+		t, err := Parse(from, now)
+		if err != nil {
+			return nil, err
+		}
+		result.From = t
+	}
+	if to != "" {
+		t, err := Parse(to, now)
+		if err != nil {
+			return nil, err
+		}
+		result.To = t
+	}
+	if !result.From.IsZero() && !result.To.IsZero() && !result.From.Before(result.To) {
+		return nil, derrors.NewInvalidArgumentError("the start of the range must be before the end").
+			WithParams(result.From.Format(time.RFC3339), result.To.Format(time.RFC3339))
+	}
+	return result, nil
+}
+
+// IsZero checks whether the range is open on both sides.
+func (r *Range) IsZero() bool {
+	return r.From.IsZero() && r.To.IsZero()
+}
+
+// FromNano returns the start of the range in nanoseconds, as used by the unified logging.
+func (r *Range) FromNano() int64 {
+	return UnixNano(r.From)
+}
+
+// ToNano returns the end of the range in nanoseconds, as used by the unified logging.
+func (r *Range) ToNano() int64 {
+	return UnixNano(r.To)
+}
+
+// FromUnix returns the start of the range in seconds, as used by the monitoring.
+func (r *Range) FromUnix() int64 {
+	return Unix(r.From)
+}
+
+// ToUnix returns the end of the range in seconds, as used by the monitoring.
+func (r *Range) ToUnix() int64 {
+	return Unix(r.To)
+}
+
+// UnixNano returns a time in nanoseconds since the epoch, or 0 for the zero time.
+func UnixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// Unix returns a time in seconds since the epoch, or 0 for the zero time.
+func Unix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2020 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package timeexpr
+
+import (
+	"time"
+
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Time expressions", func() {
+
+	madrid, _ := time.LoadLocation("Europe/Madrid")
+	now := time.Date(2020, 5, 4, 10, 30, 0, 0, time.UTC)
+
+	ginkgo.It("should parse durations with days and weeks", func() {
+		expected := map[string]time.Duration{
+			"15m":   time.Minute * 15,
+			"1h30m": time.Minute * 90,
+			"1d12h": time.Hour * 36,
+			"1.5d":  time.Hour * 36,
+			"2w":    time.Hour * 24 * 14,
+			"-2h":   -time.Hour * 2,
+			"500ms": time.Millisecond * 500,
+			"0":     0,
+		}
+		for expr, duration := range expected {
+			parsed, err := ParseDuration(expr)
+			gomega.Expect(err).To(gomega.Succeed(), expr)
+			gomega.Expect(parsed).To(gomega.Equal(duration), expr)
+		}
+		for _, expr := range []string{"", "15", "m", "1x", "1d-2h"} {
+			_, err := ParseDuration(expr)
+			gomega.Expect(err).NotTo(gomega.Succeed(), expr)
+		}
+	})
+
+	ginkgo.It("should parse relative expressions", func() {
+		expected := map[string]time.Time{
+			"now":          now,
+			"NOW":          now,
+			"-15m":         now.Add(-time.Minute * 15),
+			"+1h":          now.Add(time.Hour),
+			"now-2h":       now.Add(-time.Hour * 2),
+			"now - 1d":     now.Add(-time.Hour * 24),
+			"today":        time.Date(2020, 5, 4, 0, 0, 0, 0, time.UTC),
+			"today+8h":     time.Date(2020, 5, 4, 8, 0, 0, 0, time.UTC),
+			"yesterday":    time.Date(2020, 5, 3, 0, 0, 0, 0, time.UTC),
+			"yesterday-1w": time.Date(2020, 4, 26, 0, 0, 0, 0, time.UTC),
+		}
+		for expr, t := range expected {
+			parsed, err := Parse(expr, now)
+			gomega.Expect(err).To(gomega.Succeed(), expr)
+			gomega.Expect(parsed.Equal(t)).To(gomega.BeTrue(), expr)
+		}
+	})
+
+	ginkgo.It("should parse absolute dates", func() {
+		expected := map[string]time.Time{
+			"2020-05-01T08:00:00Z":        time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC),
+			"2020-05-01T08:00:00.5+02:00": time.Date(2020, 5, 1, 6, 0, 0, 500000000, time.UTC),
+			"2020-05-01 08:00":            time.Date(2020, 5, 1, 8, 0, 0, 0, time.UTC),
+			"2020-05-01":                  time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC),
+		}
+		for expr, t := range expected {
+			parsed, err := Parse(expr, now)
+			gomega.Expect(err).To(gomega.Succeed(), expr)
+			gomega.Expect(parsed.Equal(t)).To(gomega.BeTrue(), expr)
+		}
+		for _, expr := range []string{"", "soon", "now*2", "today 2h", "2020-13-45"} {
+			_, err := Parse(expr, now)
+			gomega.Expect(err).NotTo(gomega.Succeed(), expr)
+		}
+	})
+
+	ginkgo.It("should apply explicit time zones", func() {
+		parsed, err := Parse("2020-05-01 08:00 Europe/Madrid", now)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Equal(time.Date(2020, 5, 1, 8, 0, 0, 0, madrid))).To(gomega.BeTrue())
+
+		parsed, err = Parse("today Europe/Madrid", now)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Equal(time.Date(2020, 5, 4, 0, 0, 0, 0, madrid))).To(gomega.BeTrue())
+
+		parsed, err = Parse("2020-05-01 08:00", now.In(madrid))
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Equal(time.Date(2020, 5, 1, 6, 0, 0, 0, time.UTC))).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should parse and validate ranges", func() {
+		r, err := ParseRange("", "", "1h", now)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(r.From.Equal(now.Add(-time.Hour))).To(gomega.BeTrue())
+		gomega.Expect(r.To.IsZero()).To(gomega.BeTrue())
+		gomega.Expect(r.ToNano()).To(gomega.BeZero())
+		gomega.Expect(r.FromNano()).To(gomega.Equal(now.Add(-time.Hour).UnixNano()))
+		gomega.Expect(r.FromUnix()).To(gomega.Equal(now.Add(-time.Hour).Unix()))
+
+		r, err = ParseRange("yesterday", "today", "", now)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(r.To.Sub(r.From)).To(gomega.Equal(time.Hour * 24))
+
+		r, err = ParseRange("", "", "", now)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(r.IsZero()).To(gomega.BeTrue())
+
+		_, err = ParseRange("today", "yesterday", "", now)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = ParseRange("now", "now", "", now)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = ParseRange("-1h", "", "2h", now)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = ParseRange("", "", "-2h", now)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = ParseRange("", "-3h", "2h", now)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+})